@@ -3,13 +3,14 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
-	"os/exec"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -18,6 +19,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 
+	"github.com/inamate/inamate/backend-go/internal/admin"
 	"github.com/inamate/inamate/backend-go/internal/asset"
 	"github.com/inamate/inamate/backend-go/internal/auth"
 	"github.com/inamate/inamate/backend-go/internal/collab"
@@ -26,8 +28,15 @@ import (
 	"github.com/inamate/inamate/backend-go/internal/db/dbgen"
 	"github.com/inamate/inamate/backend-go/internal/document"
 	"github.com/inamate/inamate/backend-go/internal/export"
+	"github.com/inamate/inamate/backend-go/internal/httperr"
 	mw "github.com/inamate/inamate/backend-go/internal/middleware"
+	"github.com/inamate/inamate/backend-go/internal/notification"
+	"github.com/inamate/inamate/backend-go/internal/oplog"
 	"github.com/inamate/inamate/backend-go/internal/project"
+	"github.com/inamate/inamate/backend-go/internal/raster"
+	"github.com/inamate/inamate/backend-go/internal/rendercache"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+	"github.com/inamate/inamate/backend-go/internal/webhook"
 )
 
 func main() {
@@ -38,68 +47,152 @@ func main() {
 		slog.Error("load config", "error", err)
 		os.Exit(1)
 	}
+	logger, err := cfg.Logger(os.Stdout)
+	if err != nil {
+		slog.Error("configure logging", "error", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	pool, err := db.NewPool(ctx, cfg.DatabaseURL)
+	pool, err := db.NewPool(ctx, cfg.DatabaseURL, db.PoolOptions{
+		MaxConns:        cfg.DBMaxConns,
+		MinConns:        cfg.DBMinConns,
+		MaxConnLifetime: cfg.DBMaxConnLifetime,
+		MaxConnIdleTime: cfg.DBMaxConnIdleTime,
+		ConnectTimeout:  cfg.DBConnectTimeout,
+	})
 	if err != nil {
 		slog.Error("connect to database", "error", err)
 		os.Exit(1)
 	}
 	defer pool.Close()
+	poolCfg := pool.Config()
+	slog.Info("database pool",
+		"maxConns", poolCfg.MaxConns,
+		"minConns", poolCfg.MinConns,
+		"maxConnLifetime", poolCfg.MaxConnLifetime,
+		"maxConnIdleTime", poolCfg.MaxConnIdleTime,
+		"connectTimeout", poolCfg.ConnConfig.ConnectTimeout)
 
 	queries := dbgen.New(pool)
 
 	authService := auth.NewService(queries, cfg.JWTSecret)
 	authHandler := auth.NewHandler(authService)
+	authHandler.SetRegistrationEnabled(cfg.Features.Registration)
+
+	// Seed the admin role from config. Users must register before they can be
+	// promoted, so this is re-applied on every start.
+	for _, raw := range strings.Split(cfg.AdminEmails, ",") {
+		email := strings.TrimSpace(raw)
+		if email == "" {
+			continue
+		}
+		found, err := authService.PromoteAdmin(ctx, email)
+		if err != nil {
+			slog.Error("promote admin", "email", email, "error", err)
+		} else if !found {
+			slog.Warn("admin email has no registered user", "email", email)
+		}
+	}
 
-	projectService := project.NewService(queries)
+	webhooks := webhook.NewDispatcher(queries, cfg.WebhookAllowPrivate)
+	go webhooks.Run(ctx)
+	webhookHandler := webhook.NewHandler(webhook.NewService(queries, cfg.WebhookAllowPrivate))
+
+	notifications := notification.NewService(queries)
+	go notifications.Run(ctx)
+	notificationHandler := notification.NewHandler(notifications)
+
+	projectService := project.NewService(queries, webhooks)
+	projectService.SetNotifications(notifications)
+	projectService.SetMaxMembers(cfg.Features.MaxProjectMembers)
+	sceneDefaults := document.SceneSettings{
+		Width:      cfg.DefaultSceneWidth,
+		Height:     cfg.DefaultSceneHeight,
+		Background: cfg.DefaultBackground,
+		FPS:        cfg.DefaultFPS,
+	}
+	if err := sceneDefaults.Validate(); err != nil {
+		slog.Error("invalid default scene settings", "error", err)
+		os.Exit(1)
+	}
+	projectService.SetSceneDefaults(sceneDefaults)
 	projectHandler := project.NewHandler(projectService)
 
 	// Document loader for the collaboration hub
-	docLoader := func(projectID string) (*document.InDocument, error) {
-		// Use a background context since this runs in the hub goroutine
-		snap, err := queries.GetLatestSnapshot(context.Background(), projectID)
+	docLoader := func(ctx context.Context, projectID string) (*document.InDocument, int64, error) {
+		snap, err := queries.GetLatestSnapshot(ctx, projectID)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		var doc document.InDocument
 		if err := json.Unmarshal(snap.Document, &doc); err != nil {
-			return nil, err
+			return nil, 0, err
+		}
+		if document.MigrateIDs(&doc) {
+			slog.Info("migrated legacy document ids", "project", projectID)
 		}
-		return &doc, nil
+		return &doc, snap.Seq, nil
 	}
 
 	// Document saver for the collaboration hub
-	docSaver := func(projectID string, doc *document.InDocument) error {
-		docJSON, err := json.Marshal(doc)
+	docSaver := func(ctx context.Context, projectID string, doc *document.InDocument, seq int64, label string) error {
+		docJSON, err := document.MarshalCanonical(doc)
 		if err != nil {
 			return fmt.Errorf("marshal document: %w", err)
 		}
 
 		// Get current version to increment
-		currentSnap, err := queries.GetLatestSnapshot(context.Background(), projectID)
+		currentSnap, err := queries.GetLatestSnapshot(ctx, projectID)
 		nextVersion := int32(1)
 		if err == nil {
 			nextVersion = currentSnap.Version + 1
 		}
 
-		_, err = queries.CreateSnapshot(context.Background(), dbgen.CreateSnapshotParams{
+		snap, err := queries.CreateSnapshot(ctx, dbgen.CreateSnapshotParams{
 			ID:        fmt.Sprintf("snap_%s", uuid.New().String()[:8]),
 			ProjectID: projectID,
 			Version:   nextVersion,
 			Document:  docJSON,
+			Seq:       seq,
+			Label:     label,
 		})
 		if err != nil {
 			return fmt.Errorf("create snapshot: %w", err)
 		}
 
+		webhooks.Dispatch(projectID, webhook.EventSnapshotSaved, map[string]interface{}{
+			"snapshotId": snap.ID,
+			"version":    snap.Version,
+			"label":      snap.Label,
+		})
+
 		return nil
 	}
 
+	opStore := oplog.NewStore(queries)
+	projectService.SetOpStore(opStore)
+
+	if err := document.SetPrecision(cfg.DocumentPrecision); err != nil {
+		slog.Error("invalid document precision", "error", err)
+		os.Exit(1)
+	}
+
 	hub := collab.NewHub(docLoader, docSaver)
+	hub.SetOpStore(opStore)
+	hub.SetWebhooks(webhooks)
+	hub.SetDocumentTimeout(cfg.DocumentTimeout)
+	hub.SetAutosaveInterval(cfg.AutosaveInterval)
+	hub.SetBackupInterval(cfg.BackupInterval)
+	hub.SetMessageLimits(cfg.WSReadLimit, cfg.WSMaxOpSize)
+	hub.SetOpPolicy(collab.ParseOpPolicy(cfg.OpAllow, cfg.OpDeny))
+	hub.SetPlaygroundEnabled(cfg.Features.Playground)
 	go hub.Run()
+	projectService.SetHub(hub)
+	notifications.SetHub(hub)
 
 	// Parse allowed origins into a set for CORS and WebSocket patterns
 	allowedOrigins := make(map[string]bool)
@@ -118,21 +211,70 @@ func main() {
 	slog.Info("allowed origins", "origins", cfg.AllowedOrigins)
 
 	assetHandler := asset.NewHandler(cfg.AssetDir)
-	exportHandler := export.NewHandler(cfg.FfmpegPath)
-	if _, err := exec.LookPath(cfg.FfmpegPath); err != nil {
-		slog.Warn("ffmpeg not found — video export (MP4/GIF/WebM) will be unavailable", "path", cfg.FfmpegPath)
+	assetHandler.SetFormatPolicy(asset.FormatPolicy{KeepJPEG: cfg.AssetKeepJPEG, JPEGQuality: cfg.AssetJPEGQuality})
+	if cfg.LinkedAssets {
+		projectService.SetAssetStore(assetHandler)
+	}
+	exportLoader := func(ctx context.Context, projectID string) (*document.InDocument, error) {
+		doc, _, err := docLoader(ctx, projectID)
+		return doc, err
 	}
+	exportHandler := export.NewHandler(cfg.FfmpegPath, exportLoader, webhooks)
+	exportHandler.SetDocumentTimeout(cfg.DocumentTimeout)
+	exportHandler.SetMaxFrames(cfg.ExportMaxFrames)
+	exportHandler.SetNotifications(notifications)
+	exportHandler.SetMemberCheck(func(ctx context.Context, projectID, userID string) (bool, error) {
+		err := projectService.CheckMember(ctx, projectID, userID)
+		if errors.Is(err, project.ErrNotMember) {
+			return false, nil
+		}
+		return err == nil, err
+	})
+	exportHandler.SetEnabled(cfg.Features.Exports)
+	if cfg.ExportSweepInterval > 0 {
+		go exportHandler.RunSweeper(ctx, cfg.ExportSweepInterval, cfg.ExportTempMaxAge)
+	}
+	if version, err := export.Probe(ctx, cfg.FfmpegPath); err != nil {
+		if cfg.FfmpegRequired {
+			slog.Error("ffmpeg is required but cannot be executed", "path", cfg.FfmpegPath, "error", err)
+			os.Exit(1)
+		}
+		slog.Warn("ffmpeg cannot be executed — video export (MP4/GIF/WebM) will be unavailable", "path", cfg.FfmpegPath, "error", err)
+	} else {
+		slog.Info("ffmpeg available", "path", cfg.FfmpegPath, "version", version)
+	}
+
+	idempotency := mw.NewIdempotencyStore(cfg.IdempotencyTTL)
+
+	// Rendered frames keyed by document content hash, shared by every
+	// server-side rendering path
+	renderCache, err := rendercache.New(cfg.RenderCacheBytes, cfg.RenderCacheDir, cfg.RenderCacheDiskBytes)
+	if err != nil {
+		slog.Error("create render cache", "error", err)
+		os.Exit(1)
+	}
+
+	projectService.SetRenderer(renderCache, raster.AssetDir(cfg.AssetDir))
+	exportHandler.SetImageLoader(raster.AssetDir(cfg.AssetDir))
+
+	adminHandler := admin.NewHandler(admin.NewService(queries, hub, exportHandler, webhooks, renderCache))
 
 	r := mux.NewRouter()
 
 	// Global middleware
+	r.Use(mw.RequestID)
 	r.Use(mw.Recovery)
 	r.Use(mw.Logger)
 	r.Use(mw.CORSWithOrigins(allowedOrigins))
 
+	// Bounds DB-bound request handling so a stuck connection fails the request
+	// instead of hanging it. Not applied to uploads and exports, which are
+	// legitimately slow.
+	dbTimeout := mw.Timeout(cfg.DBTimeout)
+
 	// Auth routes (public)
-	r.HandleFunc("/auth/register", authHandler.Register).Methods("POST")
-	r.HandleFunc("/auth/login", authHandler.Login).Methods("POST")
+	r.Handle("/auth/register", dbTimeout(http.HandlerFunc(authHandler.Register))).Methods("POST")
+	r.Handle("/auth/login", dbTimeout(http.HandlerFunc(authHandler.Login))).Methods("POST")
 
 	// Health check
 	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -140,29 +282,103 @@ func main() {
 		w.Write([]byte(`{"status":"ok"}`))
 	}).Methods("GET")
 
+	// Feature flags, so clients can hide what the deployment turned off
+	features := cfg.Features.Client()
+	r.HandleFunc("/config/features", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(features)
+	}).Methods("GET")
+
 	// Asset endpoints (public — used by playground and authenticated users)
-	r.HandleFunc("/assets/upload", assetHandler.Upload).Methods("POST", "OPTIONS")
+	r.Handle("/assets/upload", idempotency.Middleware(http.HandlerFunc(assetHandler.Upload))).Methods("POST", "OPTIONS")
+	r.Handle("/assets/upload/batch", idempotency.Middleware(http.HandlerFunc(assetHandler.UploadBatch))).Methods("POST", "OPTIONS")
+	r.Handle("/assets/upload-batch", idempotency.Middleware(http.HandlerFunc(assetHandler.UploadBatch))).Methods("POST", "OPTIONS") // Alias of /assets/upload/batch
+	r.Handle("/assets/upload/sequence", idempotency.Middleware(http.HandlerFunc(assetHandler.UploadSequence))).Methods("POST", "OPTIONS")
 	r.PathPrefix("/assets/").Handler(assetHandler.Serve()).Methods("GET")
 
-	// Export endpoint (public — used by playground and authenticated users)
+	// Exports of a stored project, for its members. Registered ahead of /api
+	// so the API's DB timeout doesn't cut off long encodes.
+	projectExport := r.PathPrefix("/api/projects/{projectId}/export").Subrouter()
+	projectExport.Use(authService.AuthMiddleware)
+	projectExport.Use(mw.PathIDs(map[string]string{"projectId": typeid.PrefixProject}))
+	projectExport.HandleFunc("/video", exportHandler.ExportVideo).Methods("POST")
+	projectExport.HandleFunc("/render", exportHandler.RenderVideo).Methods("POST")
+	projectExport.HandleFunc("/jobs", exportHandler.CreateJob).Methods("POST")
+	projectExport.HandleFunc("/jobs/{jobId}", exportHandler.GetJob).Methods("GET")
+	projectExport.HandleFunc("/jobs/{jobId}", exportHandler.CancelJob).Methods("DELETE")
+	projectExport.HandleFunc("/jobs/{jobId}/download", exportHandler.DownloadJob).Methods("GET")
+
+	// Export endpoint (public — used by playground and authenticated users).
+	// Exports here carry their own frames or document; a projectId is refused,
+	// and a project's jobs are only reachable under its route above.
 	r.HandleFunc("/export/video", exportHandler.ExportVideo).Methods("POST", "OPTIONS")
+	r.HandleFunc("/export/render", exportHandler.RenderVideo).Methods("POST", "OPTIONS")
+	r.HandleFunc("/export/jobs", exportHandler.CreateJob).Methods("POST", "OPTIONS")
+	r.HandleFunc("/export/jobs/{jobId}", exportHandler.GetJob).Methods("GET")
+	r.HandleFunc("/export/jobs/{jobId}", exportHandler.CancelJob).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/export/jobs/{jobId}/download", exportHandler.DownloadJob).Methods("GET")
 
 	// Protected API routes
 	api := r.PathPrefix("/api").Subrouter()
+	api.Use(dbTimeout)
 	api.Use(authService.AuthMiddleware)
+	api.Use(mw.PathIDs(map[string]string{
+		"projectId": typeid.PrefixProject,
+		"userId":    typeid.PrefixUser,
+		"webhookId": typeid.PrefixWebhook,
+		"assetId":   typeid.PrefixAsset,
+	}))
 
 	api.HandleFunc("/projects", projectHandler.List).Methods("GET")
-	api.HandleFunc("/projects", projectHandler.Create).Methods("POST")
+	api.Handle("/projects", idempotency.Middleware(http.HandlerFunc(projectHandler.Create))).Methods("POST")
+	api.HandleFunc("/projects/from-playground", projectHandler.CreateFromPlayground).Methods("POST")
+	api.HandleFunc("/projects/import/lottie", projectHandler.ImportLottie).Methods("POST")
 	api.HandleFunc("/projects/{projectId}", projectHandler.Get).Methods("GET")
 	api.HandleFunc("/projects/{projectId}", projectHandler.Delete).Methods("DELETE")
 	api.HandleFunc("/projects/{projectId}/invite", projectHandler.Invite).Methods("POST")
 	api.HandleFunc("/projects/{projectId}/members", projectHandler.ListMembers).Methods("GET")
 	api.HandleFunc("/projects/{projectId}/members/{userId}", projectHandler.RemoveMember).Methods("DELETE")
+	api.HandleFunc("/projects/{projectId}/snapshots", projectHandler.ListSnapshots).Methods("GET")
 	api.HandleFunc("/projects/{projectId}/snapshots/latest", projectHandler.GetLatestSnapshot).Methods("GET")
+	api.HandleFunc("/projects/{projectId}/snapshots/{version:[0-9]+}", projectHandler.GetSnapshot).Methods("GET")
+	api.HandleFunc("/projects/{projectId}/recording", projectHandler.GetRecording).Methods("GET")
+	api.HandleFunc("/projects/{projectId}/contactsheet.png", projectHandler.ContactSheet).Methods("GET")
+	api.HandleFunc("/projects/{projectId}/frames/{frame}.png", projectHandler.RenderFrame).Methods("GET")
+	api.HandleFunc("/projects/{projectId}/search", projectHandler.Search).Methods("GET")
+	api.HandleFunc("/projects/{projectId}/operations", projectHandler.ApplyOperations).Methods("POST")
+	api.HandleFunc("/projects/{projectId}/operations/validate", projectHandler.ValidateOperations).Methods("POST")
+	if cfg.LinkedAssets {
+		api.HandleFunc("/projects/{projectId}/assets/{assetId}/content", projectHandler.ReplaceAssetContent).Methods("PUT")
+	}
+	api.HandleFunc("/projects/{projectId}/webhooks", webhookHandler.List).Methods("GET")
+	api.HandleFunc("/projects/{projectId}/webhooks", webhookHandler.Create).Methods("POST")
+	api.HandleFunc("/projects/{projectId}/webhooks/{webhookId}", webhookHandler.Delete).Methods("DELETE")
+	api.HandleFunc("/projects/{projectId}/webhooks/{webhookId}/deliveries", webhookHandler.ListDeliveries).Methods("GET")
+
+	api.HandleFunc("/me/notifications", notificationHandler.List).Methods("GET")
+	api.HandleFunc("/me/notifications/read", notificationHandler.MarkRead).Methods("POST")
+	api.HandleFunc("/me/notifications/preferences", notificationHandler.GetPreferences).Methods("GET")
+	api.HandleFunc("/me/notifications/preferences", notificationHandler.SetPreferences).Methods("PUT")
+
+	// Operator routes (admin role required)
+	adminAPI := r.PathPrefix("/admin").Subrouter()
+	adminAPI.Use(dbTimeout)
+	adminAPI.Use(authService.AuthMiddleware)
+	adminAPI.Use(auth.RequireAdmin)
+
+	adminAPI.HandleFunc("/users", adminHandler.ListUsers).Methods("GET")
+	adminAPI.HandleFunc("/users/{userId}/deactivate", adminHandler.DeactivateUser).Methods("POST")
+	adminAPI.HandleFunc("/users/{userId}/reactivate", adminHandler.ReactivateUser).Methods("POST")
+	adminAPI.HandleFunc("/users/{userId}/promote", adminHandler.PromoteUser).Methods("POST")
+	adminAPI.HandleFunc("/users/{userId}/demote", adminHandler.DemoteUser).Methods("POST")
+	adminAPI.HandleFunc("/projects", adminHandler.ListProjects).Methods("GET")
+	adminAPI.HandleFunc("/rooms/{projectId}/save", adminHandler.SaveRoom).Methods("POST")
+	adminAPI.HandleFunc("/rooms/{projectId}/evict", adminHandler.EvictRoom).Methods("POST")
+	adminAPI.HandleFunc("/stats", adminHandler.Stats).Methods("GET")
 
 	// WebSocket endpoint
 	r.HandleFunc("/ws/project/{projectId}", func(w http.ResponseWriter, r *http.Request) {
-		handleWebSocket(w, r, hub, authService, queries, wsOriginPatterns)
+		handleWebSocket(w, r, hub, authService, queries, wsOriginPatterns, cfg.DBTimeout)
 	})
 
 	addr := fmt.Sprintf(":%d", cfg.Port)
@@ -198,16 +414,20 @@ func main() {
 	}
 }
 
-func handleWebSocket(w http.ResponseWriter, r *http.Request, hub *collab.Hub, authSvc *auth.Service, queries *dbgen.Queries, wsOriginPatterns []string) {
+func handleWebSocket(w http.ResponseWriter, r *http.Request, hub *collab.Hub, authSvc *auth.Service, queries *dbgen.Queries, wsOriginPatterns []string, dbTimeout time.Duration) {
 	vars := mux.Vars(r)
 	projectID := vars["projectId"]
 
 	var userID string
 	var displayName string
+	role := collab.RoleEditor
 
-	// Playground project allows anonymous access
-	const playgroundProjectID = "proj_playground"
-	if projectID == playgroundProjectID {
+	// Playground project allows anonymous access, unless it's turned off
+	if projectID == collab.PlaygroundProjectID {
+		if !hub.PlaygroundEnabled() {
+			httperr.Write(w, http.StatusNotFound, httperr.CodeRoomNotFound, "room not found")
+			return
+		}
 		// Anonymous user for playground
 		userID = "anon-" + uuid.New().String()[:8]
 		displayName = "Anonymous"
@@ -215,34 +435,44 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request, hub *collab.Hub, au
 		// Auth via query param for real projects
 		token := r.URL.Query().Get("token")
 		if token == "" {
-			http.Error(w, "missing token", http.StatusUnauthorized)
+			httperr.Write(w, http.StatusUnauthorized, httperr.CodeUnauthorized, "missing token")
 			return
 		}
 
 		var err error
 		userID, err = authSvc.ValidateToken(token)
 		if err != nil {
-			http.Error(w, "invalid token", http.StatusUnauthorized)
+			httperr.Write(w, http.StatusUnauthorized, httperr.CodeInvalidToken, "invalid token")
 			return
 		}
 
-		// Check membership
-		_, err = queries.GetProjectMember(r.Context(), dbgen.GetProjectMemberParams{
-			ProjectID: projectID,
-			UserID:    userID,
-		})
+		// The request context lives as long as the socket, so bound the
+		// lookups separately
+		lookupCtx, cancel := context.WithTimeout(r.Context(), dbTimeout)
+		defer cancel()
+
+		// Get user display name
+		user, err := authSvc.GetUser(lookupCtx, userID)
 		if err != nil {
-			http.Error(w, "not a project member", http.StatusForbidden)
+			httperr.Internal(w, "load websocket user", err)
 			return
 		}
+		if user.Deactivated {
+			httperr.Write(w, http.StatusForbidden, httperr.CodeAccountDeactivated, "account deactivated")
+			return
+		}
+		displayName = user.DisplayName
 
-		// Get user display name
-		user, err := authSvc.GetUser(r.Context(), userID)
+		// Check membership
+		member, err := queries.GetProjectMember(lookupCtx, dbgen.GetProjectMemberParams{
+			ProjectID: projectID,
+			UserID:    userID,
+		})
 		if err != nil {
-			http.Error(w, "user not found", http.StatusInternalServerError)
+			httperr.Write(w, http.StatusForbidden, httperr.CodeNotAMember, "not a project member")
 			return
 		}
-		displayName = user.DisplayName
+		role = string(member.Role)
 	}
 
 	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
@@ -254,7 +484,9 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request, hub *collab.Hub, au
 	}
 
 	clientID := uuid.New().String()
-	client := collab.NewClient(hub, conn, userID, displayName, projectID, clientID)
+	client := collab.NewClient(hub, conn, userID, displayName, projectID, clientID, role)
+	// A reconnecting client passes the last server sequence it saw
+	client.ResumeSeq, _ = strconv.ParseInt(r.URL.Query().Get("seq"), 10, 64)
 
 	hub.Register(client)
 
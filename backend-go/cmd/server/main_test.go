@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/inamate/inamate/backend-go/internal/auth"
+	"github.com/inamate/inamate/backend-go/internal/collab"
+	"github.com/inamate/inamate/backend-go/internal/httperr"
+)
+
+func TestWebSocketUpgradeErrorsAreEnveloped(t *testing.T) {
+	hub := collab.NewHub(nil, nil)
+	hub.SetPlaygroundEnabled(false)
+	authSvc := auth.NewService(nil, "test-secret")
+
+	tests := []struct {
+		name      string
+		projectID string
+		query     string
+		status    int
+		code      string
+	}{
+		{"playground disabled", collab.PlaygroundProjectID, "", http.StatusNotFound, httperr.CodeRoomNotFound},
+		{"missing token", "proj_x", "", http.StatusUnauthorized, httperr.CodeUnauthorized},
+		{"invalid token", "proj_x", "?token=garbage", http.StatusUnauthorized, httperr.CodeInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/ws/project/"+tt.projectID+tt.query, nil)
+			r = mux.SetURLVars(r, map[string]string{"projectId": tt.projectID})
+			rec := httptest.NewRecorder()
+			handleWebSocket(rec, r, hub, authSvc, nil, nil, time.Second)
+
+			if rec.Code != tt.status {
+				t.Errorf("status %d, want %d", rec.Code, tt.status)
+			}
+			var e httperr.Error
+			if err := json.Unmarshal(rec.Body.Bytes(), &e); err != nil {
+				t.Fatalf("body %q is not an error envelope: %v", rec.Body, err)
+			}
+			if e.Code != tt.code {
+				t.Errorf("code %q, want %s", e.Code, tt.code)
+			}
+		})
+	}
+}
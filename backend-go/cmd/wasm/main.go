@@ -6,12 +6,18 @@ import (
 	"encoding/json"
 	"syscall/js"
 
+	"time"
+
+	"github.com/inamate/inamate/backend-go/internal/collab"
 	"github.com/inamate/inamate/backend-go/internal/document"
 	"github.com/inamate/inamate/backend-go/internal/engine"
 )
 
 var eng *engine.Engine
 
+// player replays a loaded session recording into eng
+var player *collab.Player
+
 func main() {
 	eng = engine.NewEngine()
 
@@ -26,26 +32,53 @@ func main() {
 	inamateEngine.Set("play", js.FuncOf(play))
 	inamateEngine.Set("pause", js.FuncOf(pause))
 	inamateEngine.Set("togglePlay", js.FuncOf(togglePlay))
+	inamateEngine.Set("setAnimationPreview", js.FuncOf(setAnimationPreview))
 	inamateEngine.Set("setScene", js.FuncOf(setScene))
+	inamateEngine.Set("setSafeAreaGuides", js.FuncOf(setSafeAreaGuides))
+	inamateEngine.Set("setSafeAreaPercent", js.FuncOf(setSafeAreaPercent))
+	inamateEngine.Set("setCullToViewport", js.FuncOf(setCullToViewport))
+	inamateEngine.Set("setRenderPrecision", js.FuncOf(setRenderPrecision))
 	inamateEngine.Set("setSelection", js.FuncOf(setSelection))
 	inamateEngine.Set("setDragOverlay", js.FuncOf(setDragOverlay))
 	inamateEngine.Set("updateDragOverlay", js.FuncOf(updateDragOverlay))
 	inamateEngine.Set("clearDragOverlay", js.FuncOf(clearDragOverlay))
 	inamateEngine.Set("tick", js.FuncOf(tick))
+	inamateEngine.Set("loadRecording", js.FuncOf(loadRecording))
+	inamateEngine.Set("recordingAdvance", js.FuncOf(recordingAdvance))
+	inamateEngine.Set("recordingStep", js.FuncOf(recordingStep))
+	inamateEngine.Set("recordingSeek", js.FuncOf(recordingSeek))
+	inamateEngine.Set("recordingSetSpeed", js.FuncOf(recordingSetSpeed))
+	inamateEngine.Set("prepareOperation", js.FuncOf(prepareOperation))
 
 	// --- Queries (frontend ← backend) ---
 	inamateEngine.Set("render", js.FuncOf(render))
+	inamateEngine.Set("renderAtTime", js.FuncOf(renderAtTime))
+	inamateEngine.Set("renderAtFrame", js.FuncOf(renderAtFrame))
+	inamateEngine.Set("renderOverlay", js.FuncOf(renderOverlay))
+	inamateEngine.Set("getSafeAreas", js.FuncOf(getSafeAreas))
 	inamateEngine.Set("hitTest", js.FuncOf(hitTest))
+	inamateEngine.Set("getVisibleObjects", js.FuncOf(getVisibleObjects))
+	inamateEngine.Set("findObjects", js.FuncOf(findObjects))
 	inamateEngine.Set("getSelectionBounds", js.FuncOf(getSelectionBounds))
 	inamateEngine.Set("getScene", js.FuncOf(getScene))
+	inamateEngine.Set("getLayerTree", js.FuncOf(getLayerTree))
 	inamateEngine.Set("getPlaybackState", js.FuncOf(getPlaybackState))
 	inamateEngine.Set("getAnimatedTransform", js.FuncOf(getAnimatedTransform))
 	inamateEngine.Set("getDocument", js.FuncOf(getDocument))
+	inamateEngine.Set("getTimelines", js.FuncOf(getTimelines))
+	inamateEngine.Set("getKeyframesInRange", js.FuncOf(getKeyframesInRange))
+	inamateEngine.Set("getKeyframeFrames", js.FuncOf(getKeyframeFrames))
+	inamateEngine.Set("getMotionPath", js.FuncOf(getMotionPath))
+	inamateEngine.Set("getEasingPresets", js.FuncOf(getEasingPresets))
 	inamateEngine.Set("getSelection", js.FuncOf(getSelection))
 	inamateEngine.Set("getFrame", js.FuncOf(getFrame))
 	inamateEngine.Set("isPlaying", js.FuncOf(isPlaying))
 	inamateEngine.Set("getFPS", js.FuncOf(getFPS))
 	inamateEngine.Set("getTotalFrames", js.FuncOf(getTotalFrames))
+	inamateEngine.Set("measureText", js.FuncOf(measureText))
+	inamateEngine.Set("createObjectJSON", js.FuncOf(createObjectJSON))
+	inamateEngine.Set("getRenderStats", js.FuncOf(getRenderStats))
+	inamateEngine.Set("getDiagnostics", js.FuncOf(getDiagnostics))
 
 	// Register on global scope
 	js.Global().Set("inamateEngine", inamateEngine)
@@ -119,6 +152,51 @@ func togglePlay(this js.Value, args []js.Value) interface{} {
 	return nil
 }
 
+func setAnimationPreview(this js.Value, args []js.Value) interface{} {
+	if len(args) > 0 {
+		eng.SetAnimationPreview(args[0].Truthy())
+	}
+	return nil
+}
+
+func setSafeAreaGuides(this js.Value, args []js.Value) interface{} {
+	if len(args) > 0 {
+		eng.SetSafeAreaGuides(args[0].Truthy())
+	}
+	return nil
+}
+
+func setCullToViewport(this js.Value, args []js.Value) interface{} {
+	if len(args) > 0 && !args[0].Truthy() {
+		eng.SetCullToViewport(false, engine.Rect{})
+	} else if len(args) >= 5 {
+		eng.SetCullToViewport(true, engine.Rect{
+			X:      args[1].Float(),
+			Y:      args[2].Float(),
+			Width:  args[3].Float(),
+			Height: args[4].Float(),
+		})
+	}
+	return nil
+}
+
+func setRenderPrecision(this js.Value, args []js.Value) interface{} {
+	if len(args) > 0 {
+		eng.SetRenderPrecision(args[0].Int())
+	}
+	return nil
+}
+
+func setSafeAreaPercent(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return js.ValueOf(map[string]interface{}{"error": "missing action or title percentage"})
+	}
+	if err := eng.SetSafeAreaPercent(args[0].Float(), args[1].Float()); err != nil {
+		return js.ValueOf(map[string]interface{}{"error": err.Error()})
+	}
+	return js.ValueOf(map[string]interface{}{"ok": true})
+}
+
 func setScene(this js.Value, args []js.Value) interface{} {
 	if len(args) < 1 {
 		return nil
@@ -181,12 +259,138 @@ func tick(this js.Value, args []js.Value) interface{} {
 	return js.ValueOf(eng.Tick())
 }
 
+// --- Recording Playback ---
+
+func loadRecording(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return js.ValueOf(map[string]interface{}{"error": "missing recording JSON"})
+	}
+
+	var rec collab.Recording
+	if err := json.Unmarshal([]byte(args[0].String()), &rec); err != nil {
+		return js.ValueOf(map[string]interface{}{"error": err.Error()})
+	}
+	p, err := collab.NewPlayer(&rec)
+	if err != nil {
+		return js.ValueOf(map[string]interface{}{"error": err.Error()})
+	}
+
+	player = p
+	eng.ReplaceDocument(player.Document())
+	return playbackStatus(nil)
+}
+
+func recordingAdvance(this js.Value, args []js.Value) interface{} {
+	if player == nil || len(args) < 1 {
+		return playbackStatus(nil)
+	}
+	elapsed := time.Duration(args[0].Float() * float64(time.Millisecond))
+	_, err := player.Advance(elapsed)
+	return playbackStatus(err)
+}
+
+func recordingStep(this js.Value, args []js.Value) interface{} {
+	if player == nil {
+		return playbackStatus(nil)
+	}
+	return playbackStatus(player.Step())
+}
+
+func recordingSeek(this js.Value, args []js.Value) interface{} {
+	if player == nil || len(args) < 1 {
+		return playbackStatus(nil)
+	}
+	return playbackStatus(player.Seek(args[0].Int()))
+}
+
+func recordingSetSpeed(this js.Value, args []js.Value) interface{} {
+	if player != nil && len(args) > 0 {
+		player.SetSpeed(args[0].Float())
+	}
+	return nil
+}
+
+// playbackStatus pushes the player's document into the engine so the next
+// render reflects it, and reports the playback position.
+func playbackStatus(err error) interface{} {
+	if player == nil {
+		return js.ValueOf(map[string]interface{}{"error": "no recording loaded"})
+	}
+	eng.ReplaceDocument(player.Document())
+
+	status := map[string]interface{}{
+		"position": player.Position(),
+		"total":    player.Len(),
+		"done":     player.Done(),
+	}
+	if err != nil {
+		status["error"] = err.Error()
+	}
+	return js.ValueOf(status)
+}
+
+// --- Operations ---
+
+// prepareOperation fills in the server-assigned fields of an operation against
+// a document, so the client can apply it optimistically. Takes the document
+// and operation JSON; returns the prepared operation JSON.
+func prepareOperation(this js.Value, args []js.Value) interface{} {
+	if len(args) < 2 {
+		return js.ValueOf(map[string]interface{}{"error": "missing document or operation JSON"})
+	}
+
+	var doc document.InDocument
+	if err := json.Unmarshal([]byte(args[0].String()), &doc); err != nil {
+		return js.ValueOf(map[string]interface{}{"error": err.Error()})
+	}
+	var op collab.Operation
+	if err := json.Unmarshal([]byte(args[1].String()), &op); err != nil {
+		return js.ValueOf(map[string]interface{}{"error": err.Error()})
+	}
+	if err := collab.PrepareOperation(&doc, &op); err != nil {
+		return js.ValueOf(map[string]interface{}{"error": err.Error()})
+	}
+
+	out, err := json.Marshal(op)
+	if err != nil {
+		return js.ValueOf(map[string]interface{}{"error": err.Error()})
+	}
+	return js.ValueOf(map[string]interface{}{"op": string(out)})
+}
+
 // --- Query Handlers ---
 
 func render(this js.Value, args []js.Value) interface{} {
 	return js.ValueOf(eng.Render())
 }
 
+func renderAtTime(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return js.ValueOf("[]")
+	}
+	return js.ValueOf(eng.RenderAtTime(args[0].Float()))
+}
+
+func renderAtFrame(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return js.ValueOf("[]")
+	}
+	playing := len(args) > 1 && args[1].Truthy()
+	return js.ValueOf(eng.RenderAtFrame(args[0].Int(), playing))
+}
+
+func renderOverlay(this js.Value, args []js.Value) interface{} {
+	return js.ValueOf(eng.RenderOverlay())
+}
+
+func getSafeAreas(this js.Value, args []js.Value) interface{} {
+	sceneID := ""
+	if len(args) > 0 && args[0].Type() == js.TypeString {
+		sceneID = args[0].String()
+	}
+	return js.ValueOf(eng.GetSafeAreas(sceneID))
+}
+
 func hitTest(this js.Value, args []js.Value) interface{} {
 	if len(args) < 2 {
 		return js.ValueOf("")
@@ -196,6 +400,25 @@ func hitTest(this js.Value, args []js.Value) interface{} {
 	return js.ValueOf(eng.HitTest(x, y))
 }
 
+func getVisibleObjects(this js.Value, args []js.Value) interface{} {
+	if len(args) < 4 {
+		return js.ValueOf("[]")
+	}
+	return js.ValueOf(eng.GetVisibleObjects(engine.Rect{
+		X:      args[0].Float(),
+		Y:      args[1].Float(),
+		Width:  args[2].Float(),
+		Height: args[3].Float(),
+	}))
+}
+
+func findObjects(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return js.ValueOf("[]")
+	}
+	return js.ValueOf(eng.FindObjects(args[0].String()))
+}
+
 func getSelectionBounds(this js.Value, args []js.Value) interface{} {
 	return js.ValueOf(eng.GetSelectionBounds())
 }
@@ -204,6 +427,14 @@ func getScene(this js.Value, args []js.Value) interface{} {
 	return js.ValueOf(eng.GetScene())
 }
 
+func getLayerTree(this js.Value, args []js.Value) interface{} {
+	sceneID := ""
+	if len(args) > 0 && args[0].Type() == js.TypeString {
+		sceneID = args[0].String()
+	}
+	return js.ValueOf(eng.GetLayerTree(sceneID))
+}
+
 func getPlaybackState(this js.Value, args []js.Value) interface{} {
 	return js.ValueOf(eng.GetPlaybackState())
 }
@@ -219,6 +450,53 @@ func getDocument(this js.Value, args []js.Value) interface{} {
 	return js.ValueOf(eng.GetDocument())
 }
 
+func getTimelines(this js.Value, args []js.Value) interface{} {
+	return js.ValueOf(eng.GetTimelines())
+}
+
+func getKeyframesInRange(this js.Value, args []js.Value) interface{} {
+	if len(args) < 3 {
+		return js.ValueOf("[]")
+	}
+	return js.ValueOf(eng.GetKeyframesInRange(args[0].String(), args[1].Int(), args[2].Int()))
+}
+
+func getKeyframeFrames(this js.Value, args []js.Value) interface{} {
+	var ids []string
+	if len(args) > 0 && args[0].Type() == js.TypeObject {
+		arr := args[0]
+		ids = make([]string, arr.Length())
+		for i := range ids {
+			ids[i] = arr.Index(i).String()
+		}
+	}
+	frames := eng.GetKeyframeFrames(ids)
+	result := make([]interface{}, len(frames))
+	for i, f := range frames {
+		result[i] = f
+	}
+	return js.ValueOf(result)
+}
+
+func getMotionPath(this js.Value, args []js.Value) interface{} {
+	if len(args) < 5 {
+		return js.ValueOf("[]")
+	}
+	return js.ValueOf(eng.GetMotionPath(args[0].String(), args[1].String(), args[2].Int(), args[3].Int(), args[4].Int()))
+}
+
+func getEasingPresets(this js.Value, args []js.Value) interface{} {
+	return js.ValueOf(eng.GetEasingPresets())
+}
+
+func getRenderStats(this js.Value, args []js.Value) interface{} {
+	return js.ValueOf(eng.GetRenderStats())
+}
+
+func getDiagnostics(this js.Value, args []js.Value) interface{} {
+	return js.ValueOf(eng.GetDiagnostics())
+}
+
 func getSelection(this js.Value, args []js.Value) interface{} {
 	return js.ValueOf(eng.GetSelection())
 }
@@ -238,3 +516,25 @@ func getFPS(this js.Value, args []js.Value) interface{} {
 func getTotalFrames(this js.Value, args []js.Value) interface{} {
 	return js.ValueOf(eng.GetTotalFrames())
 }
+
+func measureText(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return js.ValueOf("{}")
+	}
+	return js.ValueOf(eng.MeasureText(json.RawMessage(args[0].String())))
+}
+
+func createObjectJSON(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 {
+		return js.ValueOf(map[string]interface{}{"error": "missing object type"})
+	}
+	overrides := ""
+	if len(args) > 1 && args[1].Type() == js.TypeString {
+		overrides = args[1].String()
+	}
+	obj, err := engine.CreateObjectJSON(args[0].String(), overrides)
+	if err != nil {
+		return js.ValueOf(map[string]interface{}{"error": err.Error()})
+	}
+	return js.ValueOf(map[string]interface{}{"object": obj})
+}
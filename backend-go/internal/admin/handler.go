@@ -0,0 +1,136 @@
+package admin
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/inamate/inamate/backend-go/internal/auth"
+	"github.com/inamate/inamate/backend-go/internal/httperr"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	users, err := h.service.SearchUsers(r.Context(), r.URL.Query().Get("q"), limit)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, users)
+}
+
+func (h *Handler) DeactivateUser(w http.ResponseWriter, r *http.Request) {
+	actorID := auth.UserIDFromContext(r.Context())
+	userID := mux.Vars(r)["userId"]
+
+	disconnected, err := h.service.DeactivateUser(r.Context(), actorID, userID)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	slog.Info("user deactivated", "user", userID, "by", actorID, "disconnected", disconnected)
+	writeJSON(w, http.StatusOK, map[string]int{"disconnected": disconnected})
+}
+
+func (h *Handler) ReactivateUser(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["userId"]
+
+	if err := h.service.ReactivateUser(r.Context(), userID); err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	slog.Info("user reactivated", "user", userID, "by", auth.UserIDFromContext(r.Context()))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) PromoteUser(w http.ResponseWriter, r *http.Request) {
+	h.setAdmin(w, r, true)
+}
+
+func (h *Handler) DemoteUser(w http.ResponseWriter, r *http.Request) {
+	h.setAdmin(w, r, false)
+}
+
+func (h *Handler) setAdmin(w http.ResponseWriter, r *http.Request, isAdmin bool) {
+	actorID := auth.UserIDFromContext(r.Context())
+	userID := mux.Vars(r)["userId"]
+
+	if err := h.service.SetAdmin(r.Context(), actorID, userID, isAdmin); err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	slog.Info("user admin role changed", "user", userID, "isAdmin", isAdmin, "by", actorID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) ListProjects(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+	projects, err := h.service.ListProjects(r.Context(), limit, offset)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, projects)
+}
+
+func (h *Handler) SaveRoom(w http.ResponseWriter, r *http.Request) {
+	projectID := mux.Vars(r)["projectId"]
+
+	if err := h.service.SaveRoom(projectID); err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) EvictRoom(w http.ResponseWriter, r *http.Request) {
+	projectID := mux.Vars(r)["projectId"]
+
+	if err := h.service.EvictRoom(projectID); err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) Stats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.service.Stats())
+}
+
+var serviceErrors = []httperr.Mapping{
+	{Err: ErrUserNotFound, Status: http.StatusNotFound, Code: httperr.CodeUserNotFound},
+	{Err: ErrRoomNotFound, Status: http.StatusNotFound, Code: httperr.CodeRoomNotFound},
+	{Err: ErrSelfDeactivate, Status: http.StatusBadRequest, Code: httperr.CodeValidationFailed},
+	{Err: ErrSelfDemote, Status: http.StatusBadRequest, Code: httperr.CodeValidationFailed},
+	{Err: ErrCorruptDocument, Status: http.StatusInternalServerError, Code: httperr.CodeCorruptDocument},
+}
+
+func handleServiceError(w http.ResponseWriter, err error) {
+	httperr.FromError(w, err, serviceErrors)
+}
+
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
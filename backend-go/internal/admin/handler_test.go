@@ -0,0 +1,347 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/inamate/inamate/backend-go/internal/auth"
+	"github.com/inamate/inamate/backend-go/internal/collab/collabtest"
+	"github.com/inamate/inamate/backend-go/internal/db/dbgen"
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/export"
+	"github.com/inamate/inamate/backend-go/internal/httperr"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// fakeStore keeps users in memory, and fails every query once err is set.
+type fakeStore struct {
+	users    map[string]*dbgen.GetUserByIDRow
+	projects []dbgen.ListProjectsWithStatsRow
+	search   dbgen.SearchUsersParams
+	page     dbgen.ListProjectsWithStatsParams
+	err      error
+}
+
+func (s *fakeStore) GetUserByID(ctx context.Context, id string) (dbgen.GetUserByIDRow, error) {
+	if s.err != nil {
+		return dbgen.GetUserByIDRow{}, s.err
+	}
+	u, ok := s.users[id]
+	if !ok {
+		return dbgen.GetUserByIDRow{}, pgx.ErrNoRows
+	}
+	return *u, nil
+}
+
+func (s *fakeStore) SearchUsers(ctx context.Context, arg dbgen.SearchUsersParams) ([]dbgen.SearchUsersRow, error) {
+	s.search = arg
+	if s.err != nil {
+		return nil, s.err
+	}
+	var rows []dbgen.SearchUsersRow
+	for _, u := range s.users {
+		if strings.Contains(u.Email, arg.Query) || strings.Contains(u.DisplayName, arg.Query) {
+			rows = append(rows, dbgen.SearchUsersRow(*u))
+		}
+	}
+	return rows, nil
+}
+
+func (s *fakeStore) DeactivateUser(ctx context.Context, id string) (int64, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	s.users[id].DeactivatedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	return 1, nil
+}
+
+func (s *fakeStore) ReactivateUser(ctx context.Context, id string) (int64, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	s.users[id].DeactivatedAt = pgtype.Timestamptz{}
+	return 1, nil
+}
+
+func (s *fakeStore) SetUserAdmin(ctx context.Context, arg dbgen.SetUserAdminParams) (int64, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	s.users[arg.ID].IsAdmin = arg.IsAdmin
+	return 1, nil
+}
+
+func (s *fakeStore) ListProjectsWithStats(ctx context.Context, arg dbgen.ListProjectsWithStatsParams) ([]dbgen.ListProjectsWithStatsRow, error) {
+	s.page = arg
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.projects, nil
+}
+
+// adminFixture is an admin handler over a fake store holding an admin and
+// a regular user, with a live room for liveProject.
+type adminFixture struct {
+	handler     *Handler
+	store       *fakeStore
+	server      *collabtest.Server
+	adminID     string
+	userID      string
+	liveProject string
+}
+
+func newAdminFixture(t *testing.T) *adminFixture {
+	t.Helper()
+	f := &adminFixture{
+		adminID:     typeid.NewUserID(),
+		userID:      typeid.NewUserID(),
+		liveProject: typeid.NewProjectID(),
+	}
+	created := pgtype.Timestamptz{Time: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), Valid: true}
+	f.store = &fakeStore{users: map[string]*dbgen.GetUserByIDRow{
+		f.adminID: {ID: f.adminID, Email: "ops@example.com", DisplayName: "Ops", IsAdmin: true, CreatedAt: created},
+		f.userID:  {ID: f.userID, Email: "ana@example.com", DisplayName: "Ana", CreatedAt: created},
+	}}
+
+	docs := collabtest.NewStore()
+	doc := document.NewEmptyDocument(f.liveProject, "Live", typeid.NewSceneID(), typeid.NewObjectID(), typeid.NewTimelineID())
+	if err := docs.Put(f.liveProject, doc); err != nil {
+		t.Fatal(err)
+	}
+	f.server = collabtest.NewServer(docs)
+	t.Cleanup(f.server.Close)
+
+	f.handler = NewHandler(&Service{
+		queries: f.store,
+		hub:     f.server.Hub,
+		exports: export.NewHandler("", nil, nil),
+		started: time.Now(),
+	})
+	return f
+}
+
+// join connects userID to the live room and waits until it is synced.
+func (f *adminFixture) join(t *testing.T, userID string) *collabtest.Client {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, err := f.server.Connect(ctx, f.liveProject, userID, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	if _, _, err := c.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// serve sends a request from the admin to handler, with the route's vars.
+func (f *adminFixture) serve(handler http.HandlerFunc, method, target string, vars map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	r = r.WithContext(context.WithValue(r.Context(), auth.UserIDKey, f.adminID))
+	r = mux.SetURLVars(r, vars)
+	rec := httptest.NewRecorder()
+	handler(rec, r)
+	return rec
+}
+
+func decodeJSON(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("body %q: %v", rec.Body, err)
+	}
+}
+
+// wantError fails unless rec is an error envelope with status and code.
+func wantError(t *testing.T, rec *httptest.ResponseRecorder, status int, code string) {
+	t.Helper()
+	var e httperr.Error
+	decodeJSON(t, rec, &e)
+	if rec.Code != status || e.Code != code {
+		t.Errorf("got %d %s, want %d %s", rec.Code, e.Code, status, code)
+	}
+}
+
+func TestListUsers(t *testing.T) {
+	f := newAdminFixture(t)
+
+	rec := f.serve(f.handler.ListUsers, http.MethodGet, "/admin/users?q=ana&limit=1000", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var users []User
+	decodeJSON(t, rec, &users)
+	if len(users) != 1 || users[0].ID != f.userID || users[0].CreatedAt != "2026-01-02T03:04:05Z" {
+		t.Errorf("users %+v, want only ana", users)
+	}
+	if f.store.search.Query != "ana" || f.store.search.MaxResults != maxPageSize {
+		t.Errorf("searched %+v, want ana clamped to %d", f.store.search, maxPageSize)
+	}
+
+	f.serve(f.handler.ListUsers, http.MethodGet, "/admin/users", nil)
+	if f.store.search.MaxResults != defaultPageSize {
+		t.Errorf("default page %d, want %d", f.store.search.MaxResults, defaultPageSize)
+	}
+}
+
+func TestDeactivateUser(t *testing.T) {
+	f := newAdminFixture(t)
+	f.join(t, f.userID)
+
+	rec := f.serve(f.handler.DeactivateUser, http.MethodPost, "/", map[string]string{"userId": f.userID})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var resp map[string]int
+	decodeJSON(t, rec, &resp)
+	if resp["disconnected"] != 1 {
+		t.Errorf("disconnected %d sockets, want 1", resp["disconnected"])
+	}
+	if !f.store.users[f.userID].DeactivatedAt.Valid {
+		t.Error("user not deactivated")
+	}
+
+	rec = f.serve(f.handler.ReactivateUser, http.MethodPost, "/", map[string]string{"userId": f.userID})
+	if rec.Code != http.StatusNoContent || f.store.users[f.userID].DeactivatedAt.Valid {
+		t.Errorf("reactivate: status %d, deactivated %v", rec.Code, f.store.users[f.userID].DeactivatedAt.Valid)
+	}
+}
+
+func TestDeactivateUserErrors(t *testing.T) {
+	f := newAdminFixture(t)
+
+	rec := f.serve(f.handler.DeactivateUser, http.MethodPost, "/", map[string]string{"userId": f.adminID})
+	wantError(t, rec, http.StatusBadRequest, httperr.CodeValidationFailed)
+	if f.store.users[f.adminID].DeactivatedAt.Valid {
+		t.Error("admin deactivated themselves")
+	}
+
+	rec = f.serve(f.handler.DeactivateUser, http.MethodPost, "/", map[string]string{"userId": typeid.NewUserID()})
+	wantError(t, rec, http.StatusNotFound, httperr.CodeUserNotFound)
+
+	rec = f.serve(f.handler.ReactivateUser, http.MethodPost, "/", map[string]string{"userId": typeid.NewUserID()})
+	wantError(t, rec, http.StatusNotFound, httperr.CodeUserNotFound)
+
+	f.store.err = errors.New("connection refused")
+	rec = f.serve(f.handler.DeactivateUser, http.MethodPost, "/", map[string]string{"userId": f.userID})
+	wantError(t, rec, http.StatusInternalServerError, httperr.CodeInternal)
+}
+
+func TestPromoteAndDemote(t *testing.T) {
+	f := newAdminFixture(t)
+	vars := map[string]string{"userId": f.userID}
+
+	if rec := f.serve(f.handler.PromoteUser, http.MethodPost, "/", vars); rec.Code != http.StatusNoContent {
+		t.Fatalf("promote: status %d: %s", rec.Code, rec.Body)
+	}
+	if !f.store.users[f.userID].IsAdmin {
+		t.Fatal("user not promoted")
+	}
+
+	if rec := f.serve(f.handler.DemoteUser, http.MethodPost, "/", vars); rec.Code != http.StatusNoContent {
+		t.Fatalf("demote: status %d: %s", rec.Code, rec.Body)
+	}
+	if f.store.users[f.userID].IsAdmin {
+		t.Error("user still admin after demotion")
+	}
+}
+
+func TestDemoteErrors(t *testing.T) {
+	f := newAdminFixture(t)
+
+	rec := f.serve(f.handler.DemoteUser, http.MethodPost, "/", map[string]string{"userId": f.adminID})
+	wantError(t, rec, http.StatusBadRequest, httperr.CodeValidationFailed)
+	if !f.store.users[f.adminID].IsAdmin {
+		t.Error("admin demoted themselves")
+	}
+
+	// Promoting yourself is a no-op, not an error
+	if rec := f.serve(f.handler.PromoteUser, http.MethodPost, "/", map[string]string{"userId": f.adminID}); rec.Code != http.StatusNoContent {
+		t.Errorf("self-promote: status %d", rec.Code)
+	}
+
+	rec = f.serve(f.handler.DemoteUser, http.MethodPost, "/", map[string]string{"userId": typeid.NewUserID()})
+	wantError(t, rec, http.StatusNotFound, httperr.CodeUserNotFound)
+}
+
+func TestListProjects(t *testing.T) {
+	f := newAdminFixture(t)
+	idle := typeid.NewProjectID()
+	active := pgtype.Timestamptz{Time: time.Date(2026, 5, 6, 7, 8, 9, 0, time.UTC), Valid: true}
+	f.store.projects = []dbgen.ListProjectsWithStatsRow{
+		{ID: f.liveProject, Name: "Live", OwnerID: f.adminID, SnapshotVersion: 3, DocumentBytes: 2048, LastActivity: active, CreatedAt: active},
+		{ID: idle, Name: "Idle", OwnerID: f.userID, LastActivity: active, CreatedAt: active},
+	}
+	f.join(t, f.userID)
+
+	rec := f.serve(f.handler.ListProjects, http.MethodGet, "/admin/projects?limit=10&offset=-5", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var projects []Project
+	decodeJSON(t, rec, &projects)
+	if len(projects) != 2 {
+		t.Fatalf("%d projects, want 2", len(projects))
+	}
+	if p := projects[0]; !p.Live || p.Clients != 1 || p.DocumentBytes != 2048 || p.LastActivity != "2026-05-06T07:08:09Z" {
+		t.Errorf("live project %+v", p)
+	}
+	if p := projects[1]; p.Live || p.Clients != 0 {
+		t.Errorf("idle project %+v", p)
+	}
+	if f.store.page.Limit != 10 || f.store.page.Offset != 0 {
+		t.Errorf("page %+v, want limit 10 offset 0", f.store.page)
+	}
+}
+
+func TestSaveAndEvictRoom(t *testing.T) {
+	f := newAdminFixture(t)
+	f.join(t, f.userID)
+	live := map[string]string{"projectId": f.liveProject}
+	missing := map[string]string{"projectId": typeid.NewProjectID()}
+
+	if rec := f.serve(f.handler.SaveRoom, http.MethodPost, "/", live); rec.Code != http.StatusNoContent {
+		t.Errorf("save: status %d: %s", rec.Code, rec.Body)
+	}
+	wantError(t, f.serve(f.handler.SaveRoom, http.MethodPost, "/", missing), http.StatusNotFound, httperr.CodeRoomNotFound)
+
+	if rec := f.serve(f.handler.EvictRoom, http.MethodPost, "/", live); rec.Code != http.StatusNoContent {
+		t.Errorf("evict: status %d: %s", rec.Code, rec.Body)
+	}
+
+	// Evicted clients disconnect asynchronously; the room closes after them
+	deadline := time.Now().Add(5 * time.Second)
+	for len(f.server.Hub.Stats().Rooms) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("room still open after eviction")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	wantError(t, f.serve(f.handler.EvictRoom, http.MethodPost, "/", live), http.StatusNotFound, httperr.CodeRoomNotFound)
+}
+
+func TestStats(t *testing.T) {
+	f := newAdminFixture(t)
+	f.join(t, f.userID)
+
+	rec := f.serve(f.handler.Stats, http.MethodGet, "/admin/stats", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var stats Stats
+	decodeJSON(t, rec, &stats)
+	if len(stats.Hub.Rooms) != 1 || stats.Hub.Rooms[0].ProjectID != f.liveProject {
+		t.Errorf("hub stats %+v, want the live room", stats.Hub)
+	}
+}
@@ -0,0 +1,247 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/inamate/inamate/backend-go/internal/collab"
+	"github.com/inamate/inamate/backend-go/internal/db/dbgen"
+	"github.com/inamate/inamate/backend-go/internal/export"
+	"github.com/inamate/inamate/backend-go/internal/rendercache"
+	"github.com/inamate/inamate/backend-go/internal/webhook"
+)
+
+var (
+	ErrUserNotFound   = errors.New("user not found")
+	ErrRoomNotFound   = errors.New("project has no live room")
+	ErrSelfDeactivate = errors.New("cannot deactivate your own account")
+	ErrSelfDemote     = errors.New("cannot remove your own admin role")
+
+	// ErrCorruptDocument is returned, wrapping the problem found, when a
+	// room's document fails its integrity check and is not saved
+	ErrCorruptDocument = collab.ErrCorruptDocument
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// store is the subset of dbgen.Queries the service uses.
+type store interface {
+	GetUserByID(ctx context.Context, id string) (dbgen.GetUserByIDRow, error)
+	SearchUsers(ctx context.Context, arg dbgen.SearchUsersParams) ([]dbgen.SearchUsersRow, error)
+	DeactivateUser(ctx context.Context, id string) (int64, error)
+	ReactivateUser(ctx context.Context, id string) (int64, error)
+	SetUserAdmin(ctx context.Context, arg dbgen.SetUserAdminParams) (int64, error)
+	ListProjectsWithStats(ctx context.Context, arg dbgen.ListProjectsWithStatsParams) ([]dbgen.ListProjectsWithStatsRow, error)
+}
+
+type Service struct {
+	queries  store
+	hub      *collab.Hub
+	exports  *export.Handler
+	webhooks *webhook.Dispatcher
+	renders  *rendercache.Cache
+	started  time.Time
+}
+
+func NewService(queries *dbgen.Queries, hub *collab.Hub, exports *export.Handler, webhooks *webhook.Dispatcher, renders *rendercache.Cache) *Service {
+	return &Service{
+		queries:  queries,
+		hub:      hub,
+		exports:  exports,
+		webhooks: webhooks,
+		renders:  renders,
+		started:  time.Now(),
+	}
+}
+
+type User struct {
+	ID            string `json:"id"`
+	Email         string `json:"email"`
+	DisplayName   string `json:"displayName"`
+	IsAdmin       bool   `json:"isAdmin"`
+	DeactivatedAt string `json:"deactivatedAt,omitempty"`
+	CreatedAt     string `json:"createdAt"`
+}
+
+type Project struct {
+	ID              string `json:"id"`
+	Name            string `json:"name"`
+	OwnerID         string `json:"ownerId"`
+	SnapshotVersion int    `json:"snapshotVersion"`
+	DocumentBytes   int    `json:"documentBytes"`
+	LastActivity    string `json:"lastActivity"`
+	Live            bool   `json:"live"`
+	Clients         int    `json:"clients"`
+	CreatedAt       string `json:"createdAt"`
+}
+
+type Stats struct {
+	UptimeSeconds int64             `json:"uptimeSeconds"`
+	Hub           collab.HubStats   `json:"hub"`
+	Exports       export.Stats      `json:"exports"`
+	WebhookQueue  int               `json:"webhookQueue"`
+	RenderCache   rendercache.Stats `json:"renderCache"`
+}
+
+// SearchUsers lists users whose email or display name contains query. An
+// empty query matches everyone.
+func (s *Service) SearchUsers(ctx context.Context, query string, limit int) ([]User, error) {
+	dbUsers, err := s.queries.SearchUsers(ctx, dbgen.SearchUsersParams{
+		Query:      query,
+		MaxResults: int32(clampPageSize(limit)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("search users: %w", err)
+	}
+
+	users := make([]User, len(dbUsers))
+	for i, u := range dbUsers {
+		users[i] = User{
+			ID:          u.ID,
+			Email:       u.Email,
+			DisplayName: u.DisplayName,
+			IsAdmin:     u.IsAdmin,
+			CreatedAt:   u.CreatedAt.Time.Format("2006-01-02T15:04:05Z"),
+		}
+		if u.DeactivatedAt.Valid {
+			users[i].DeactivatedAt = u.DeactivatedAt.Time.Format("2006-01-02T15:04:05Z")
+		}
+	}
+	return users, nil
+}
+
+// DeactivateUser blocks a user from logging in or using existing tokens and
+// closes any collaboration sockets they hold. It returns the number of
+// sockets closed.
+func (s *Service) DeactivateUser(ctx context.Context, actorID, userID string) (int, error) {
+	if actorID == userID {
+		return 0, ErrSelfDeactivate
+	}
+	if err := s.checkUser(ctx, userID); err != nil {
+		return 0, err
+	}
+	if _, err := s.queries.DeactivateUser(ctx, userID); err != nil {
+		return 0, fmt.Errorf("deactivate user: %w", err)
+	}
+	return s.hub.DisconnectUser(userID), nil
+}
+
+func (s *Service) ReactivateUser(ctx context.Context, userID string) error {
+	if err := s.checkUser(ctx, userID); err != nil {
+		return err
+	}
+	if _, err := s.queries.ReactivateUser(ctx, userID); err != nil {
+		return fmt.Errorf("reactivate user: %w", err)
+	}
+	return nil
+}
+
+// SetAdmin grants or removes a user's admin role. Admins can't remove their
+// own, so there is always one left. Admins seeded from config get the role
+// back on the next start unless they are removed from it.
+func (s *Service) SetAdmin(ctx context.Context, actorID, userID string, isAdmin bool) error {
+	if actorID == userID && !isAdmin {
+		return ErrSelfDemote
+	}
+	if err := s.checkUser(ctx, userID); err != nil {
+		return err
+	}
+	if _, err := s.queries.SetUserAdmin(ctx, dbgen.SetUserAdminParams{ID: userID, IsAdmin: isAdmin}); err != nil {
+		return fmt.Errorf("set admin: %w", err)
+	}
+	return nil
+}
+
+// ListProjects lists all projects by most recent activity, with the size of
+// their latest snapshot and whether they currently have a live room.
+func (s *Service) ListProjects(ctx context.Context, limit, offset int) ([]Project, error) {
+	if offset < 0 {
+		offset = 0
+	}
+	dbProjects, err := s.queries.ListProjectsWithStats(ctx, dbgen.ListProjectsWithStatsParams{
+		Limit:  int32(clampPageSize(limit)),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list projects: %w", err)
+	}
+
+	live := make(map[string]int)
+	for _, room := range s.hub.Stats().Rooms {
+		live[room.ProjectID] = room.Clients
+	}
+
+	projects := make([]Project, len(dbProjects))
+	for i, p := range dbProjects {
+		clients, isLive := live[p.ID]
+		projects[i] = Project{
+			ID:              p.ID,
+			Name:            p.Name,
+			OwnerID:         p.OwnerID,
+			SnapshotVersion: int(p.SnapshotVersion),
+			DocumentBytes:   int(p.DocumentBytes),
+			LastActivity:    p.LastActivity.Time.Format("2006-01-02T15:04:05Z"),
+			Live:            isLive,
+			Clients:         clients,
+			CreatedAt:       p.CreatedAt.Time.Format("2006-01-02T15:04:05Z"),
+		}
+	}
+	return projects, nil
+}
+
+func (s *Service) SaveRoom(projectID string) error {
+	if err := s.hub.ForceSave(projectID); err != nil {
+		if errors.Is(err, collab.ErrRoomNotFound) {
+			return ErrRoomNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *Service) EvictRoom(projectID string) error {
+	if err := s.hub.EvictRoom(projectID); err != nil {
+		if errors.Is(err, collab.ErrRoomNotFound) {
+			return ErrRoomNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *Service) Stats() Stats {
+	return Stats{
+		UptimeSeconds: int64(time.Since(s.started).Seconds()),
+		Hub:           s.hub.Stats(),
+		Exports:       s.exports.Stats(),
+		WebhookQueue:  s.webhooks.QueueLength(),
+		RenderCache:   s.renders.Stats(),
+	}
+}
+
+func (s *Service) checkUser(ctx context.Context, userID string) error {
+	if _, err := s.queries.GetUserByID(ctx, userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("get user: %w", err)
+	}
+	return nil
+}
+
+func clampPageSize(limit int) int {
+	if limit <= 0 {
+		return defaultPageSize
+	}
+	if limit > maxPageSize {
+		return maxPageSize
+	}
+	return limit
+}
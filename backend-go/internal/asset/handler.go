@@ -1,36 +1,102 @@
 package asset
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"image"
-	_ "image/jpeg"
+	"image/jpeg"
 	"image/png"
 	"io"
 	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 
+	"github.com/inamate/inamate/backend-go/internal/httperr"
 	"github.com/inamate/inamate/backend-go/internal/typeid"
 )
 
-const maxUploadSize = 10 << 20 // 10MB
+const (
+	maxUploadSize = 10 << 20 // 10MB per file
+	maxBatchSize  = 50 << 20 // 50MB per batch request
+	maxBatchFiles = 50
+)
+
+var (
+	ErrUnsupportedType = errors.New("only PNG and JPEG images are supported")
+	ErrInvalidImage    = errors.New("invalid image")
+	ErrFileTooLarge    = errors.New("file too large (max 10MB)")
+	ErrAssetExists     = errors.New("asset already exists")
+)
+
+// assetExts are the extensions asset files are stored with: each asset is
+// one file named <asset ID><ext> in the format its extension names.
+var assetExts = []string{".png", ".jpg"}
 
-// UploadResponse is returned from the upload endpoint.
+// FormatPolicy decides which format uploaded images are stored in. Uploads
+// are decoded and re-encoded whatever the format, which validates them and
+// drops any metadata they carried, such as EXIF location.
+type FormatPolicy struct {
+	KeepJPEG    bool // Store JPEGs as JPEG rather than converting them to PNG
+	JPEGQuality int  // Quality JPEGs are re-encoded at, 1-100
+}
+
+// DefaultFormatPolicy keeps JPEGs as JPEG, at a quality that doesn't visibly
+// degrade photos.
+var DefaultFormatPolicy = FormatPolicy{KeepJPEG: true, JPEGQuality: 90}
+
+var uploadErrors = []httperr.Mapping{
+	{Err: ErrUnsupportedType, Status: http.StatusBadRequest, Code: httperr.CodeUnsupportedMediaType},
+	{Err: ErrInvalidImage, Status: http.StatusBadRequest, Code: httperr.CodeValidationFailed},
+	{Err: ErrFileTooLarge, Status: http.StatusBadRequest, Code: httperr.CodePayloadTooLarge},
+	{Err: ErrAssetExists, Status: http.StatusConflict, Code: httperr.CodeAssetExists},
+	{Err: ErrAssetNotFound, Status: http.StatusNotFound, Code: httperr.CodeAssetNotFound},
+	{Err: errInvalidProjectID, Status: http.StatusBadRequest, Code: httperr.CodeInvalidID},
+}
+
+// errInvalidProjectID rejects an upload naming a malformed projectId.
+var errInvalidProjectID = errors.New("invalid projectId")
+
+// uploadProject returns the projectId form value of an upload, which records
+// the project it is for so a linked asset can only be replaced through that
+// project (see Replace).
+func uploadProject(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	if err := typeid.Validate(value, typeid.PrefixProject); err != nil {
+		return "", errInvalidProjectID
+	}
+	return value, nil
+}
+
+// UploadResponse is returned from the upload endpoints. In a batch, a file
+// that could not be stored has only Name and Error set.
 type UploadResponse struct {
-	ID     string `json:"id"`
-	URL    string `json:"url"`
-	Width  int    `json:"width"`
-	Height int    `json:"height"`
-	Type   string `json:"type"`
-	Name   string `json:"name"`
+	ID     string         `json:"id"`
+	URL    string         `json:"url"`
+	Width  int            `json:"width"`
+	Height int            `json:"height"`
+	Type   string         `json:"type"`
+	Name   string         `json:"name"`
+	Error  *httperr.Error `json:"error,omitempty"`
+
+	// Revision is set when the content of an existing asset was replaced
+	Revision int `json:"revision,omitempty"`
 }
 
 // Handler serves asset upload and retrieval endpoints.
 type Handler struct {
-	dir string // directory to store asset files
+	dir    string // directory to store asset files
+	policy FormatPolicy
+
+	mu sync.Mutex // Serializes revision bumps
 }
 
 // NewHandler creates a new asset handler that stores files in dir.
@@ -39,10 +105,22 @@ func NewHandler(dir string) *Handler {
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		slog.Error("create asset dir", "error", err, "dir", dir)
 	}
-	return &Handler{dir: dir}
+	return &Handler{dir: dir, policy: DefaultFormatPolicy}
+}
+
+// SetFormatPolicy sets which format uploads are stored in. A JPEG quality
+// out of range keeps the current one.
+func (h *Handler) SetFormatPolicy(policy FormatPolicy) {
+	if policy.JPEGQuality < 1 || policy.JPEGQuality > 100 {
+		policy.JPEGQuality = h.policy.JPEGQuality
+	}
+	h.policy = policy
 }
 
-// Upload handles POST /assets/upload (multipart form with "file" field).
+// Upload handles POST /assets/upload (multipart form with "file" field). An
+// optional "assetId" field stores the file under an ID the client already
+// refers to, such as an image in an imported animation; it must not exist yet.
+// An optional "projectId" field records the project the asset is for.
 func (h *Handler) Upload(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodOptions {
 		w.WriteHeader(http.StatusOK)
@@ -52,89 +130,330 @@ func (h *Handler) Upload(w http.ResponseWriter, r *http.Request) {
 	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
 
 	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
-		http.Error(w, "file too large (max 10MB)", http.StatusBadRequest)
+		httperr.Write(w, http.StatusBadRequest, httperr.CodePayloadTooLarge, "file too large (max 10MB)")
 		return
 	}
 
 	file, header, err := r.FormFile("file")
 	if err != nil {
-		http.Error(w, "missing file field", http.StatusBadRequest)
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed, "missing file field")
 		return
 	}
 	defer file.Close()
 
-	// Validate content type
-	contentType := header.Header.Get("Content-Type")
-	if !strings.HasPrefix(contentType, "image/png") && !strings.HasPrefix(contentType, "image/jpeg") {
-		http.Error(w, "only PNG and JPEG images are supported", http.StatusBadRequest)
+	assetID := r.FormValue("assetId")
+	if assetID == "" {
+		assetID = typeid.NewAssetID()
+	} else if err := typeid.Validate(assetID, typeid.PrefixAsset); err != nil {
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeInvalidID, "invalid assetId")
 		return
 	}
 
-	// Decode image to get dimensions (and to re-encode as PNG if JPEG)
-	img, _, err := image.Decode(file)
+	projectID, err := uploadProject(r.FormValue("projectId"))
 	if err != nil {
-		http.Error(w, "invalid image: "+err.Error(), http.StatusBadRequest)
+		httperr.FromError(w, err, uploadErrors)
 		return
 	}
 
-	bounds := img.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
+	resp, err := h.saveImage(assetID, projectID, header.Filename, header.Header.Get("Content-Type"), file)
+	if err != nil {
+		httperr.FromError(w, err, uploadErrors)
+		return
+	}
 
-	// Generate asset ID and save as PNG
-	assetID := typeid.NewAssetID()
-	filename := assetID + ".png"
-	filePath := filepath.Join(h.dir, filename)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
 
-	out, err := os.Create(filePath)
+// UploadBatch handles POST /assets/upload/batch (multipart form with any
+// number of "file" parts). Each file is processed independently and the
+// response lists one UploadResponse per file, in request order; a file that
+// fails carries an error instead of failing the whole batch. Files over the
+// per-file limit are rejected individually, while a body over the aggregate
+// limit aborts the request. An optional "projectId" part, sent before the
+// files, records the project they are for.
+func (h *Handler) UploadBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxBatchSize)
+
+	reader, err := r.MultipartReader()
 	if err != nil {
-		slog.Error("create asset file", "error", err)
-		http.Error(w, "failed to save file", http.StatusInternalServerError)
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeInvalidBody, "expected multipart form")
+		return
+	}
+
+	results := []UploadResponse{}
+	var projectID string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			h.abortBatch(w, results, err)
+			return
+		}
+		if part.FormName() == "projectId" {
+			b, _ := io.ReadAll(io.LimitReader(part, 64))
+			part.Close()
+			if projectID, err = uploadProject(string(b)); err != nil {
+				h.abortBatch(w, results, err)
+				return
+			}
+			continue
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+		if len(results) == maxBatchFiles {
+			part.Close()
+			h.abortBatch(w, results, nil)
+			return
+		}
+
+		name := part.FileName()
+		src := &limitedReader{r: part, n: maxUploadSize}
+		resp, err := h.saveImage(typeid.NewAssetID(), projectID, name, part.Header.Get("Content-Type"), src)
+		if err != nil {
+			if src.n < 0 {
+				err = ErrFileTooLarge
+			}
+			resp = UploadResponse{Name: name, Error: batchError(err)}
+		}
+		results = append(results, resp)
+		part.Close()
+	}
+
+	if len(results) == 0 {
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed, "missing file field")
 		return
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(results)
+}
+
+// abortBatch rejects a batch that exceeded an aggregate limit or could not be
+// read. Files already stored are removed so a failed request leaves nothing
+// behind.
+func (h *Handler) abortBatch(w http.ResponseWriter, stored []UploadResponse, err error) {
+	for _, resp := range stored {
+		if resp.Error == nil {
+			h.Delete(resp.ID)
+		}
+	}
+
+	var mbe *http.MaxBytesError
+	switch {
+	case err == nil:
+		httperr.Write(w, http.StatusRequestEntityTooLarge, httperr.CodePayloadTooLarge,
+			fmt.Sprintf("too many files (max %d per batch)", maxBatchFiles))
+	case errors.As(err, &mbe):
+		httperr.Write(w, http.StatusRequestEntityTooLarge, httperr.CodePayloadTooLarge, "batch too large (max 50MB)")
+	case errors.Is(err, errInvalidProjectID):
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeInvalidID, err.Error())
+	default:
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeInvalidBody, "invalid multipart body")
+	}
+}
+
+// batchError converts a saveImage error into the per-file error envelope.
+func batchError(err error) *httperr.Error {
+	for _, m := range uploadErrors {
+		if errors.Is(err, m.Err) {
+			msg := m.Message
+			if msg == "" {
+				msg = err.Error()
+			}
+			return &httperr.Error{Code: m.Code, Message: msg}
+		}
+	}
+	slog.Error("batch upload", "error", err)
+	return &httperr.Error{Code: httperr.CodeInternal, Message: "internal error"}
+}
+
+// saveImage validates and decodes a PNG or JPEG and stores it under assetID
+// in the format the policy picks for it.
+func (h *Handler) saveImage(assetID, projectID, name, contentType string, src io.Reader) (UploadResponse, error) {
+	img, format, err := decodeImage(contentType, src)
+	if err != nil {
+		return UploadResponse{}, err
+	}
+	return h.store(assetID, projectID, name, img, h.storedType(format))
+}
+
+// decodeImage validates and decodes a PNG or JPEG, returning the format it
+// was in ("png" or "jpeg"). A JPEG is turned upright according to its EXIF
+// orientation, since the metadata saying how to display it is not kept.
+func decodeImage(contentType string, src io.Reader) (image.Image, string, error) {
+	if !strings.HasPrefix(contentType, "image/png") && !strings.HasPrefix(contentType, "image/jpeg") {
+		return nil, "", ErrUnsupportedType
+	}
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if format == "jpeg" {
+		img = orient(img, jpegOrientation(data))
+	}
+	return img, format, nil
+}
+
+// storedType returns the asset type, "png" or "jpg", that an image uploaded
+// in format is stored as.
+func (h *Handler) storedType(format string) string {
+	if format == "jpeg" && h.policy.KeepJPEG {
+		return "jpg"
+	}
+	return "png"
+}
+
+// encodeImage writes img to w as an asset of type typ.
+func (h *Handler) encodeImage(w io.Writer, img image.Image, typ string) error {
+	if typ == "jpg" {
+		if err := jpeg.Encode(w, img, &jpeg.Options{Quality: h.policy.JPEGQuality}); err != nil {
+			return fmt.Errorf("encode jpeg: %w", err)
+		}
+		return nil
+	}
+	if err := png.Encode(w, img); err != nil {
+		return fmt.Errorf("encode png: %w", err)
+	}
+	return nil
+}
+
+// store encodes img as an asset of type typ under assetID, never replacing
+// an existing asset, and records projectID, if any, as the project it was
+// uploaded to.
+func (h *Handler) store(assetID, projectID, name string, img image.Image, typ string) (UploadResponse, error) {
+	if _, err := h.assetFile(assetID); err == nil {
+		return UploadResponse{}, ErrAssetExists
+	}
+
+	bounds := img.Bounds()
+	filename := assetID + "." + typ
+	filePath := filepath.Join(h.dir, filename)
+
+	out, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o666)
+	if errors.Is(err, os.ErrExist) {
+		return UploadResponse{}, ErrAssetExists
+	}
+	if err != nil {
+		return UploadResponse{}, fmt.Errorf("create asset file: %w", err)
+	}
 	defer out.Close()
 
-	if err := png.Encode(out, img); err != nil {
-		slog.Error("encode png", "error", err)
+	if err := h.encodeImage(out, img, typ); err != nil {
 		os.Remove(filePath)
-		http.Error(w, "failed to encode image", http.StatusInternalServerError)
-		return
+		return UploadResponse{}, err
+	}
+	if projectID != "" {
+		if err := os.WriteFile(h.ownerPath(assetID), []byte(projectID), 0o666); err != nil {
+			os.Remove(filePath)
+			return UploadResponse{}, fmt.Errorf("write asset owner: %w", err)
+		}
 	}
 
-	resp := UploadResponse{
+	return UploadResponse{
 		ID:     assetID,
 		URL:    fmt.Sprintf("/assets/%s", filename),
-		Width:  width,
-		Height: height,
-		Type:   "png",
-		Name:   header.Filename,
+		Width:  bounds.Dx(),
+		Height: bounds.Dy(),
+		Type:   typ,
+		Name:   name,
+	}, nil
+}
+
+// assetFile returns the path of an asset's file, whichever format it is
+// stored in.
+func (h *Handler) assetFile(assetID string) (string, error) {
+	for _, ext := range assetExts {
+		path := filepath.Join(h.dir, assetID+ext)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
 	}
+	return "", ErrAssetNotFound
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(resp)
+// limitedReader is like io.LimitReader but fails with ErrFileTooLarge instead
+// of silently truncating, so an oversized file is not decoded from a prefix.
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, ErrFileTooLarge
+	}
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n, ErrFileTooLarge
+	}
+	return n, err
 }
 
-// Serve returns an http.Handler that serves stored asset files with caching headers.
+// Serve returns an http.Handler that serves stored asset files with caching
+// headers. Files are immutable unless their content has been replaced, in
+// which case only the URL naming the current revision may be cached. An
+// asset is served in the format it is stored in whichever of the asset
+// extensions its URL has, so URLs stay valid when a replacement changes the
+// format.
 func (h *Handler) Serve() http.Handler {
 	fs := http.FileServer(http.Dir(h.dir))
 	return http.StripPrefix("/assets/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Asset IDs are unique, so files are immutable
-		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		// Files are named <asset ID>.<ext>
+		name := r.URL.Path
+		assetID := strings.TrimSuffix(name, filepath.Ext(name))
+		if err := typeid.Validate(assetID, typeid.PrefixAsset); err != nil || !slices.Contains(assetExts, filepath.Ext(name)) {
+			httperr.Write(w, http.StatusBadRequest, httperr.CodeInvalidID, "not an asset file")
+			return
+		}
+		if path, err := h.assetFile(assetID); err == nil {
+			r.URL.Path = filepath.Base(path)
+		}
+
+		revision := h.revision(assetID)
+		if revision == 0 || r.URL.Query().Get("rev") == strconv.Itoa(revision) {
+			// Asset IDs and revisions are unique, so files are immutable
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		} else {
+			w.Header().Set("Cache-Control", "no-cache")
+		}
 		fs.ServeHTTP(w, r)
 	}))
 }
 
 // Delete removes an asset file from disk (for cleanup).
 func (h *Handler) Delete(assetID string) error {
-	// Try common extensions
-	for _, ext := range []string{".png"} {
-		path := filepath.Join(h.dir, assetID+ext)
-		if err := os.Remove(path); err == nil {
-			return nil
+	found := false
+	for _, ext := range assetExts {
+		if err := os.Remove(filepath.Join(h.dir, assetID+ext)); err == nil {
+			found = true
 		}
 	}
-	return fmt.Errorf("asset not found: %s", assetID)
+	if !found {
+		return fmt.Errorf("asset not found: %s", assetID)
+	}
+	os.Remove(h.revisionPath(assetID))
+	os.Remove(h.ownerPath(assetID))
+	return nil
 }
 
 // copyFile copies src reader to a file at dst path.
@@ -0,0 +1,322 @@
+package asset
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math/rand/v2"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/httperr"
+)
+
+// writePart adds a "file" part with the given content type and body to mw.
+func writePart(t *testing.T, mw *multipart.Writer, name, contentType string, body []byte) {
+	t.Helper()
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, name))
+	header.Set("Content-Type", contentType)
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(body)
+}
+
+func TestUploadBatchMixedFiles(t *testing.T) {
+	dir := t.TempDir()
+	h := NewHandler(dir)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	writePNG(t, mw, "first.png", 4, 4)
+	writePart(t, mw, "corrupt.png", "image/png", []byte("not a png"))
+	writePart(t, mw, "notes.txt", "text/plain", []byte("hello"))
+	writePNG(t, mw, "last.png", 2, 3)
+	mw.Close()
+	r := httptest.NewRequest(http.MethodPost, "/assets/upload/batch", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+
+	h.UploadBatch(rec, r)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	results := decodeBatch(t, rec)
+	want := []struct {
+		name string
+		code string // Empty for a stored file
+	}{
+		{"first.png", ""},
+		{"corrupt.png", httperr.CodeValidationFailed},
+		{"notes.txt", httperr.CodeUnsupportedMediaType},
+		{"last.png", ""},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("%d results, want %d", len(results), len(want))
+	}
+	for i, w := range want {
+		got := results[i]
+		if got.Name != w.name {
+			t.Errorf("result %d is for %q, want %q", i, got.Name, w.name)
+		}
+		switch {
+		case w.code == "" && got.Error != nil:
+			t.Errorf("%s: failed with %s", w.name, got.Error.Code)
+		case w.code == "" && got.ID == "":
+			t.Errorf("%s: stored without an ID", w.name)
+		case w.code != "" && (got.Error == nil || got.Error.Code != w.code):
+			t.Errorf("%s: error %+v, want %s", w.name, got.Error, w.code)
+		}
+	}
+	if results[3].Width != 2 || results[3].Height != 3 {
+		t.Errorf("last.png stored as %dx%d, want 2x3", results[3].Width, results[3].Height)
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Errorf("%d files stored, want 2", len(files))
+	}
+}
+
+func TestUploadBatchTooManyFilesStoresNothing(t *testing.T) {
+	dir := t.TempDir()
+	h := NewHandler(dir)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for i := range maxBatchFiles + 1 {
+		writePNG(t, mw, fmt.Sprintf("%d.png", i), 1, 1)
+	}
+	mw.Close()
+	r := httptest.NewRequest(http.MethodPost, "/assets/upload/batch", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+
+	h.UploadBatch(rec, r)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status %d, want 413", rec.Code)
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Errorf("%d files left behind", len(files))
+	}
+}
+
+// TestUploadBatchFileLimits checks a file over the per-file limit fails on
+// its own while the rest of the batch is stored.
+func TestUploadBatchFileLimits(t *testing.T) {
+	dir := t.TempDir()
+	h := NewHandler(dir)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	writePNG(t, mw, "small.png", 4, 4)
+	writePart(t, mw, "huge.png", "image/png", make([]byte, maxUploadSize+1))
+	writePNG(t, mw, "after.png", 4, 4)
+	mw.Close()
+	r := httptest.NewRequest(http.MethodPost, "/assets/upload/batch", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+
+	h.UploadBatch(rec, r)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	results := decodeBatch(t, rec)
+	if len(results) != 3 {
+		t.Fatalf("%d results, want 3", len(results))
+	}
+	if results[1].Error == nil || results[1].Error.Code != httperr.CodePayloadTooLarge {
+		t.Errorf("huge.png: error %+v, want %s", results[1].Error, httperr.CodePayloadTooLarge)
+	}
+	if results[0].Error != nil || results[2].Error != nil {
+		t.Errorf("small files failed: %+v, %+v", results[0].Error, results[2].Error)
+	}
+}
+
+// TestUploadBatchAggregateLimit checks a body over the batch limit fails the
+// whole request and removes the files stored before it was reached.
+func TestUploadBatchAggregateLimit(t *testing.T) {
+	dir := t.TempDir()
+	h := NewHandler(dir)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	writePNG(t, mw, "first.png", 4, 4)
+	for i := range maxBatchSize/maxUploadSize + 1 {
+		writePart(t, mw, fmt.Sprintf("%d.png", i), "image/png", make([]byte, maxUploadSize-1))
+	}
+	mw.Close()
+	r := httptest.NewRequest(http.MethodPost, "/assets/upload/batch", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+
+	h.UploadBatch(rec, r)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status %d, want 413: %s", rec.Code, rec.Body)
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Errorf("%d files left behind", len(files))
+	}
+}
+
+// photoJPEG returns a JPEG of a noisy gradient, which compresses like a
+// photo: well as JPEG and badly as PNG.
+func photoJPEG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	rng := rand.New(rand.NewPCG(1, 2))
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			noise := uint8(rng.IntN(24))
+			img.SetRGBA(x, y, color.RGBA{R: uint8(x) + noise, G: uint8(y) + noise, B: 128 + noise, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// uploadFile uploads one file to h and returns the stored asset.
+func uploadFile(t *testing.T, h *Handler, name, contentType string, data []byte) UploadResponse {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	writePart(t, mw, name, contentType, data)
+	mw.Close()
+	r := httptest.NewRequest(http.MethodPost, "/assets/upload", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	h.Upload(rec, r)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("upload %s: status %d: %s", name, rec.Code, rec.Body)
+	}
+	var resp UploadResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+// storedFormat decodes an asset's file and returns its format and size.
+func storedFormat(t *testing.T, dir, url string) (string, int64) {
+	t.Helper()
+	path := filepath.Join(dir, strings.TrimPrefix(url, "/assets/"))
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	_, format, err := image.DecodeConfig(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return format, info.Size()
+}
+
+func TestUploadKeepsJPEG(t *testing.T) {
+	photo := photoJPEG(t, 256, 192)
+
+	dir := t.TempDir()
+	h := NewHandler(dir)
+	jpg := uploadFile(t, h, "photo.jpg", "image/jpeg", photo)
+	if jpg.Type != "jpg" || jpg.URL != "/assets/"+jpg.ID+".jpg" || jpg.Width != 256 || jpg.Height != 192 {
+		t.Errorf("stored %+v, want a 256x192 jpg", jpg)
+	}
+	format, jpgSize := storedFormat(t, dir, jpg.URL)
+	if format != "jpeg" {
+		t.Errorf("stored file is %s, want jpeg", format)
+	}
+
+	// PNGs stay PNG
+	if png := uploadFile(t, h, "flat.png", "image/png", pngBytes(t, 4, 4)); png.Type != "png" || !strings.HasSuffix(png.URL, ".png") {
+		t.Errorf("stored PNG as %+v", png)
+	}
+
+	// The same photo converted to PNG is several times the size
+	converting := NewHandler(t.TempDir())
+	converting.SetFormatPolicy(FormatPolicy{KeepJPEG: false})
+	converted := uploadFile(t, converting, "photo.jpg", "image/jpeg", photo)
+	format, pngSize := storedFormat(t, converting.dir, converted.URL)
+	if converted.Type != "png" || format != "png" {
+		t.Errorf("converting policy stored %s as %s", converted.Type, format)
+	}
+	if jpgSize*2 > pngSize {
+		t.Errorf("JPEG stored in %d bytes, PNG in %d; want the JPEG well under half", jpgSize, pngSize)
+	}
+}
+
+func TestSetFormatPolicyQuality(t *testing.T) {
+	photo := photoJPEG(t, 128, 128)
+	sizes := make(map[int]int64)
+	for _, quality := range []int{30, 95} {
+		dir := t.TempDir()
+		h := NewHandler(dir)
+		h.SetFormatPolicy(FormatPolicy{KeepJPEG: true, JPEGQuality: quality})
+		_, sizes[quality] = storedFormat(t, dir, uploadFile(t, h, "photo.jpg", "image/jpeg", photo).URL)
+	}
+	if sizes[30] >= sizes[95] {
+		t.Errorf("quality 30 stored %d bytes, quality 95 %d; want fewer", sizes[30], sizes[95])
+	}
+
+	// An out of range quality keeps the current one
+	h := NewHandler(t.TempDir())
+	h.SetFormatPolicy(FormatPolicy{KeepJPEG: true, JPEGQuality: 101})
+	if h.policy.JPEGQuality != DefaultFormatPolicy.JPEGQuality {
+		t.Errorf("quality %d, want the default kept", h.policy.JPEGQuality)
+	}
+}
+
+func TestServeAndDeleteJPEG(t *testing.T) {
+	h := NewHandler(t.TempDir())
+	jpg := uploadFile(t, h, "photo.jpg", "image/jpeg", photoJPEG(t, 16, 16))
+	serve := h.Serve()
+
+	// The asset is served as stored whichever asset extension is asked for
+	for _, url := range []string{jpg.URL, "/assets/" + jpg.ID + ".png"} {
+		rec := httptest.NewRecorder()
+		serve.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/jpeg" {
+			t.Errorf("GET %s: status %d, type %q; want the JPEG", url, rec.Code, rec.Header().Get("Content-Type"))
+		}
+	}
+	rec := httptest.NewRecorder()
+	serve.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/assets/"+jpg.ID+".gif", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("GET .gif: status %d, want 400", rec.Code)
+	}
+
+	if err := h.Delete(jpg.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.assetFile(jpg.ID); !errors.Is(err, ErrAssetNotFound) {
+		t.Errorf("after delete: %v, want ErrAssetNotFound", err)
+	}
+	if err := h.Delete(jpg.ID); err == nil {
+		t.Error("deleted a missing asset")
+	}
+}
@@ -0,0 +1,113 @@
+package asset
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// revisionExt names the file beside a replaced asset that holds its revision.
+// Assets that were never replaced have none.
+const revisionExt = ".rev"
+
+// ownerExt names the file beside an asset that holds the ID of the project
+// it was uploaded to. Assets uploaded without a project have none.
+const ownerExt = ".owner"
+
+var (
+	ErrAssetNotFound = errors.New("asset not found")
+	ErrAssetNotOwned = errors.New("asset was not uploaded to this project")
+)
+
+// Replace stores new PNG or JPEG content for an existing asset, keeping its
+// ID, and returns it with the asset's new revision. Asset files are shared by
+// every project that refers to them, so only an asset uploaded to projectID
+// may be replaced through it; others fail with ErrAssetNotOwned. The content
+// is stored in the format the policy picks for it, which may differ from the
+// old content's. The URL names the revision, so it can still be cached as
+// immutable; the bare URL is served uncached from then on.
+func (h *Handler) Replace(projectID, assetID, contentType string, src io.Reader) (UploadResponse, error) {
+	oldPath, err := h.assetFile(assetID)
+	if err != nil {
+		return UploadResponse{}, err
+	}
+	if projectID == "" || h.owner(assetID) != projectID {
+		return UploadResponse{}, ErrAssetNotOwned
+	}
+
+	img, format, err := decodeImage(contentType, &limitedReader{r: src, n: maxUploadSize})
+	if err != nil {
+		return UploadResponse{}, err
+	}
+	typ := h.storedType(format)
+	filePath := filepath.Join(h.dir, assetID+"."+typ)
+
+	// Encode beside the asset and rename over it, so it's never served
+	// half-written
+	tmp, err := os.CreateTemp(h.dir, assetID+".*.tmp")
+	if err != nil {
+		return UploadResponse{}, fmt.Errorf("create asset file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := h.encodeImage(tmp, img, typ); err != nil {
+		tmp.Close()
+		return UploadResponse{}, err
+	}
+	if err := tmp.Close(); err != nil {
+		return UploadResponse{}, fmt.Errorf("write asset file: %w", err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	revision := h.revision(assetID) + 1
+	if err := os.Rename(tmp.Name(), filePath); err != nil {
+		return UploadResponse{}, fmt.Errorf("replace asset file: %w", err)
+	}
+	if oldPath != filePath {
+		os.Remove(oldPath)
+	}
+	if err := os.WriteFile(h.revisionPath(assetID), []byte(strconv.Itoa(revision)), 0o666); err != nil {
+		return UploadResponse{}, fmt.Errorf("write asset revision: %w", err)
+	}
+
+	bounds := img.Bounds()
+	return UploadResponse{
+		ID:       assetID,
+		URL:      fmt.Sprintf("/assets/%s.%s?rev=%d", assetID, typ, revision),
+		Width:    bounds.Dx(),
+		Height:   bounds.Dy(),
+		Type:     typ,
+		Revision: revision,
+	}, nil
+}
+
+// revision returns how many times an asset's content has been replaced.
+func (h *Handler) revision(assetID string) int {
+	b, err := os.ReadFile(h.revisionPath(assetID))
+	if err != nil {
+		return 0
+	}
+	revision, _ := strconv.Atoi(strings.TrimSpace(string(b)))
+	return revision
+}
+
+func (h *Handler) revisionPath(assetID string) string {
+	return filepath.Join(h.dir, assetID+revisionExt)
+}
+
+// owner returns the project an asset was uploaded to, or "" for none.
+func (h *Handler) owner(assetID string) string {
+	b, err := os.ReadFile(h.ownerPath(assetID))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func (h *Handler) ownerPath(assetID string) string {
+	return filepath.Join(h.dir, assetID+ownerExt)
+}
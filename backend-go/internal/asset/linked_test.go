@@ -0,0 +1,228 @@
+package asset
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+func pngBytes(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// writePNG adds a "file" part holding a PNG to mw.
+func writePNG(t *testing.T, mw *multipart.Writer, name string, width, height int) {
+	t.Helper()
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, name))
+	header.Set("Content-Type", "image/png")
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(pngBytes(t, width, height))
+}
+
+// storeAsset uploads a PNG to h for projectID, or for no project when it is
+// empty, and returns the asset's ID.
+func storeAsset(t *testing.T, h *Handler, projectID string) string {
+	t.Helper()
+	resp, err := h.saveImage(typeid.NewAssetID(), projectID, "test.png", "image/png", bytes.NewReader(pngBytes(t, 4, 4)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.ID
+}
+
+// storedID returns the ID of the asset a single upload stored.
+func storedID(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var resp UploadResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp.ID
+}
+
+// decodeBatch returns the per-file results of a batch upload.
+func decodeBatch(t *testing.T, rec *httptest.ResponseRecorder) []UploadResponse {
+	t.Helper()
+	var results []UploadResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	}
+	return results
+}
+
+func TestReplaceBumpsRevision(t *testing.T) {
+	h := NewHandler(t.TempDir())
+	projectID := typeid.NewProjectID()
+	assetID := storeAsset(t, h, projectID)
+
+	for want := 1; want <= 2; want++ {
+		resp, err := h.Replace(projectID, assetID, "image/png", bytes.NewReader(pngBytes(t, 8, 6)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.ID != assetID || resp.Revision != want {
+			t.Errorf("replace %d: id %s revision %d, want %s revision %d", want, resp.ID, resp.Revision, assetID, want)
+		}
+		if wantURL := fmt.Sprintf("/assets/%s.png?rev=%d", assetID, want); resp.URL != wantURL {
+			t.Errorf("replace %d: url %s, want %s", want, resp.URL, wantURL)
+		}
+		if resp.Width != 8 || resp.Height != 6 {
+			t.Errorf("replace %d: size %dx%d, want 8x6", want, resp.Width, resp.Height)
+		}
+	}
+	if got := h.revision(assetID); got != 2 {
+		t.Errorf("stored revision %d, want 2", got)
+	}
+}
+
+func TestReplaceRequiresOwningProject(t *testing.T) {
+	h := NewHandler(t.TempDir())
+	owner := typeid.NewProjectID()
+	owned := storeAsset(t, h, owner)
+	unowned := storeAsset(t, h, "")
+
+	tests := []struct {
+		name      string
+		projectID string
+		assetID   string
+		want      error
+	}{
+		{"other project", typeid.NewProjectID(), owned, ErrAssetNotOwned},
+		{"no project", "", owned, ErrAssetNotOwned},
+		{"uploaded without a project", owner, unowned, ErrAssetNotOwned},
+		{"missing asset", owner, typeid.NewAssetID(), ErrAssetNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := h.Replace(tt.projectID, tt.assetID, "image/png", bytes.NewReader(pngBytes(t, 2, 2)))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+	if got := h.revision(owned); got != 0 {
+		t.Errorf("refused replacements bumped the revision to %d", got)
+	}
+}
+
+func TestServeCacheHeaders(t *testing.T) {
+	h := NewHandler(t.TempDir())
+	projectID := typeid.NewProjectID()
+	assetID := storeAsset(t, h, projectID)
+	serve := h.Serve()
+
+	cacheControl := func(target string) string {
+		rec := httptest.NewRecorder()
+		serve.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: status %d", target, rec.Code)
+		}
+		return rec.Header().Get("Cache-Control")
+	}
+	const immutable = "public, max-age=31536000, immutable"
+
+	if got := cacheControl("/assets/" + assetID + ".png"); got != immutable {
+		t.Errorf("never replaced: Cache-Control %q, want immutable", got)
+	}
+
+	for range 2 {
+		if _, err := h.Replace(projectID, assetID, "image/png", bytes.NewReader(pngBytes(t, 2, 2))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"/assets/" + assetID + ".png", "no-cache"},
+		{"/assets/" + assetID + ".png?rev=1", "no-cache"},
+		{"/assets/" + assetID + ".png?rev=2", immutable},
+		{"/assets/" + assetID + ".jpg?rev=2", immutable},
+	}
+	for _, tt := range tests {
+		if got := cacheControl(tt.target); got != tt.want {
+			t.Errorf("replaced, GET %s: Cache-Control %q, want %q", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestUploadRecordsProject(t *testing.T) {
+	upload := func(h *Handler, projectID string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		mw.WriteField("projectId", projectID)
+		writePNG(t, mw, "test.png", 4, 4)
+		mw.Close()
+		r := httptest.NewRequest(http.MethodPost, "/assets/upload", &body)
+		r.Header.Set("Content-Type", mw.FormDataContentType())
+		rec := httptest.NewRecorder()
+		h.Upload(rec, r)
+		return rec
+	}
+
+	h := NewHandler(t.TempDir())
+	projectID := typeid.NewProjectID()
+	rec := upload(h, projectID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("upload: status %d: %s", rec.Code, rec.Body)
+	}
+	if got := h.owner(storedID(t, rec)); got != projectID {
+		t.Errorf("owner %q, want %q", got, projectID)
+	}
+
+	if rec := upload(h, "not-a-project"); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid projectId: status %d, want 400", rec.Code)
+	}
+}
+
+func TestUploadBatchRecordsProject(t *testing.T) {
+	h := NewHandler(t.TempDir())
+	projectID := typeid.NewProjectID()
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("projectId", projectID)
+	for range 2 {
+		writePNG(t, mw, "test.png", 4, 4)
+	}
+	mw.Close()
+	r := httptest.NewRequest(http.MethodPost, "/assets/upload/batch", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	h.UploadBatch(rec, r)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	results := decodeBatch(t, rec)
+	if len(results) != 2 {
+		t.Fatalf("%d results, want 2", len(results))
+	}
+	for _, resp := range results {
+		if resp.Error != nil {
+			t.Fatalf("%s: %s", resp.Name, resp.Error.Message)
+		}
+		if got := h.owner(resp.ID); got != projectID {
+			t.Errorf("%s: owner %q, want %q", resp.ID, got, projectID)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package asset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// quadrants is a 32x16 image whose quarters are red, green (top), blue and
+// white (bottom), left to right.
+func quadrants() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 32, 16))
+	for y := range 16 {
+		for x := range 32 {
+			c := []color.RGBA{{255, 0, 0, 255}, {0, 255, 0, 255}, {0, 0, 255, 255}, {255, 255, 255, 255}}[y/8*2+x/16]
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// exifSegment returns an APP1 segment declaring orientation, and a GPS IFD
+// pointer standing in for private metadata, in the given byte order.
+func exifSegment(order binary.ByteOrder, orientation uint16) []byte {
+	tiff := make([]byte, 8+2+2*12+4)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 2)
+	// GPSInfo, a LONG offset
+	order.PutUint16(tiff[10:], 0x8825)
+	order.PutUint16(tiff[12:], 4)
+	order.PutUint32(tiff[14:], 1)
+	// Orientation, a SHORT
+	order.PutUint16(tiff[22:], exifOrientationTag)
+	order.PutUint16(tiff[24:], 3)
+	order.PutUint32(tiff[26:], 1)
+	order.PutUint16(tiff[30:], orientation)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(2+len(payload)))
+	return append(segment, payload...)
+}
+
+// withEXIF returns a JPEG of img with an EXIF segment right after its SOI.
+func withEXIF(t *testing.T, img image.Image, segment []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	return append(append(append([]byte{}, data[:2]...), segment...), data[2:]...)
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	img := quadrants()
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"big-endian", withEXIF(t, img, exifSegment(binary.BigEndian, 6)), 6},
+		{"little-endian", withEXIF(t, img, exifSegment(binary.LittleEndian, 8)), 8},
+		{"out of range", withEXIF(t, img, exifSegment(binary.BigEndian, 9)), 1},
+		{"no EXIF", withEXIF(t, img, nil), 1},
+		{"truncated EXIF", withEXIF(t, img, exifSegment(binary.BigEndian, 6)[:20]), 1},
+		{"not a JPEG", []byte("\x89PNG\r\n\x1a\n"), 1},
+	}
+	for _, tt := range tests {
+		if got := jpegOrientation(tt.data); got != tt.want {
+			t.Errorf("%s: orientation %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// The corner each orientation moves the red top-left quarter to: 0
+	// top-left, 1 top-right, 2 bottom-left, 3 bottom-right
+	src := quadrants()
+	red := []int{1: 0, 2: 1, 3: 3, 4: 2, 5: 0, 6: 1, 7: 3, 8: 2}
+	for orientation := 1; orientation <= 8; orientation++ {
+		got := orient(src, orientation)
+		b := got.Bounds()
+		if orientation >= 5 && (b.Dx() != 16 || b.Dy() != 32) || orientation < 5 && (b.Dx() != 32 || b.Dy() != 16) {
+			t.Errorf("orientation %d: %dx%d", orientation, b.Dx(), b.Dy())
+			continue
+		}
+		corner := red[orientation]
+		x, y := corner%2*(b.Dx()-1), corner/2*(b.Dy()-1)
+		if r, g, bl, _ := got.At(x, y).RGBA(); r>>8 != 255 || g != 0 || bl != 0 {
+			t.Errorf("orientation %d: pixel (%d, %d) is %d,%d,%d, want red", orientation, x, y, r>>8, g>>8, bl>>8)
+		}
+	}
+}
+
+func TestUploadOrientsJPEG(t *testing.T) {
+	dir := t.TempDir()
+	h := NewHandler(dir)
+	// Stored sideways, to be turned 90° clockwise
+	resp := uploadFile(t, h, "phone.jpg", "image/jpeg", withEXIF(t, quadrants(), exifSegment(binary.BigEndian, 6)))
+	if resp.Width != 16 || resp.Height != 32 {
+		t.Errorf("stored %dx%d, want 16x32", resp.Width, resp.Height)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, strings.TrimPrefix(resp.URL, "/assets/")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("Exif")) {
+		t.Error("stored file kept its EXIF")
+	}
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Turned clockwise: blue and red on top, white and green below
+	want := map[image.Point][3]uint32{{4, 4}: {0, 0, 255}, {12, 4}: {255, 0, 0}, {4, 28}: {255, 255, 255}, {12, 28}: {0, 255, 0}}
+	for p, rgb := range want {
+		r, g, b, _ := img.At(p.X, p.Y).RGBA()
+		for i, v := range []uint32{r >> 8, g >> 8, b >> 8} {
+			if v+16 < rgb[i] || v > rgb[i]+16 {
+				t.Errorf("pixel %v is %d,%d,%d, want %v", p, r>>8, g>>8, b>>8, rgb)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package asset
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"math"
+	"net/http"
+
+	"github.com/inamate/inamate/backend-go/internal/httperr"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// maxSheetSide bounds each side of a stitched sprite sheet, in pixels.
+// Browsers won't reliably draw images larger than this.
+const maxSheetSide = 16384
+
+var (
+	ErrFrameSizeMismatch = errors.New("sequence frames must all be the same size")
+	ErrSheetTooLarge     = fmt.Errorf("sequence doesn't fit a %dpx sprite sheet", maxSheetSide)
+)
+
+var sequenceErrors = append([]httperr.Mapping{
+	{Err: ErrFrameSizeMismatch, Status: http.StatusBadRequest, Code: httperr.CodeValidationFailed},
+	{Err: ErrSheetTooLarge, Status: http.StatusBadRequest, Code: httperr.CodeValidationFailed},
+}, uploadErrors...)
+
+// SequenceResponse is returned from the sequence upload endpoint. The embedded
+// UploadResponse describes the sprite sheet the frames were stitched into;
+// frame i is the cell at column i % Columns, row i / Columns.
+type SequenceResponse struct {
+	UploadResponse
+	FrameCount  int `json:"frameCount"`
+	Columns     int `json:"columns"`
+	FrameWidth  int `json:"frameWidth"`
+	FrameHeight int `json:"frameHeight"`
+}
+
+// UploadSequence handles POST /assets/upload/sequence (multipart form with
+// one "file" part per frame, in playback order, and optional "name" and
+// "projectId" fields). The frames, which must all be the same size, are
+// stitched into a single sprite sheet asset for a SpriteSequence object.
+// Unlike a batch upload, any bad frame fails the whole request.
+func (h *Handler) UploadSequence(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxBatchSize)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeInvalidBody, "expected multipart form")
+		return
+	}
+
+	var frames []image.Image
+	var name, projectID string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var mbe *http.MaxBytesError
+			if errors.As(err, &mbe) {
+				httperr.Write(w, http.StatusRequestEntityTooLarge, httperr.CodePayloadTooLarge, "sequence too large (max 50MB)")
+				return
+			}
+			httperr.Write(w, http.StatusBadRequest, httperr.CodeInvalidBody, "invalid multipart body")
+			return
+		}
+
+		switch part.FormName() {
+		case "name":
+			b, _ := io.ReadAll(io.LimitReader(part, 256))
+			name = string(b)
+		case "projectId":
+			b, _ := io.ReadAll(io.LimitReader(part, 64))
+			if projectID, err = uploadProject(string(b)); err != nil {
+				part.Close()
+				httperr.FromError(w, err, sequenceErrors)
+				return
+			}
+		case "file":
+			if len(frames) == maxBatchFiles {
+				part.Close()
+				httperr.Write(w, http.StatusRequestEntityTooLarge, httperr.CodePayloadTooLarge,
+					fmt.Sprintf("too many frames (max %d per sequence)", maxBatchFiles))
+				return
+			}
+			if name == "" {
+				name = part.FileName()
+			}
+			src := &limitedReader{r: part, n: maxUploadSize}
+			img, _, err := decodeImage(part.Header.Get("Content-Type"), src)
+			if err == nil && len(frames) > 0 && img.Bounds().Size() != frames[0].Bounds().Size() {
+				err = ErrFrameSizeMismatch
+			}
+			if err != nil {
+				if src.n < 0 {
+					err = ErrFileTooLarge
+				}
+				part.Close()
+				httperr.FromError(w, fmt.Errorf("frame %d (%s): %w", len(frames)+1, part.FileName(), err), sequenceErrors)
+				return
+			}
+			frames = append(frames, img)
+		}
+		part.Close()
+	}
+
+	if len(frames) == 0 {
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed, "missing file field")
+		return
+	}
+
+	sheet, columns, err := stitchSheet(frames)
+	if err != nil {
+		httperr.FromError(w, err, sequenceErrors)
+		return
+	}
+	stored, err := h.store(typeid.NewAssetID(), projectID, name, sheet, "png")
+	if err != nil {
+		httperr.FromError(w, err, sequenceErrors)
+		return
+	}
+
+	size := frames[0].Bounds().Size()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(SequenceResponse{
+		UploadResponse: stored,
+		FrameCount:     len(frames),
+		Columns:        columns,
+		FrameWidth:     size.X,
+		FrameHeight:    size.Y,
+	})
+}
+
+// stitchSheet lays same-sized frames out left to right, top to bottom on a
+// sheet as close to square as fits within maxSheetSide, and returns it with
+// its number of columns.
+func stitchSheet(frames []image.Image) (*image.NRGBA, int, error) {
+	size := frames[0].Bounds().Size()
+	columns := min(int(math.Ceil(math.Sqrt(float64(len(frames))))), maxSheetSide/size.X)
+	if columns < 1 {
+		return nil, 0, ErrSheetTooLarge
+	}
+	rows := (len(frames) + columns - 1) / columns
+	if rows*size.Y > maxSheetSide {
+		return nil, 0, ErrSheetTooLarge
+	}
+
+	sheet := image.NewNRGBA(image.Rect(0, 0, columns*size.X, rows*size.Y))
+	for i, frame := range frames {
+		at := image.Pt(i%columns*size.X, i/columns*size.Y)
+		draw.Draw(sheet, image.Rectangle{Min: at, Max: at.Add(size)}, frame, frame.Bounds().Min, draw.Src)
+	}
+	return sheet, columns, nil
+}
@@ -0,0 +1,115 @@
+package asset
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/httperr"
+)
+
+// solidFrame returns a width × height frame filled with c.
+func solidFrame(width, height int, c color.NRGBA) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	return img
+}
+
+func uploadSequence(t *testing.T, h *Handler, frames ...image.Image) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("name", "walk")
+	for _, frame := range frames {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, frame); err != nil {
+			t.Fatal(err)
+		}
+		writePart(t, mw, "frame.png", "image/png", buf.Bytes())
+	}
+	mw.Close()
+	r := httptest.NewRequest(http.MethodPost, "/assets/upload/sequence", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	h.UploadSequence(rec, r)
+	return rec
+}
+
+func TestUploadSequenceStitchesSheet(t *testing.T) {
+	dir := t.TempDir()
+	h := NewHandler(dir)
+	colors := []color.NRGBA{{255, 0, 0, 255}, {0, 255, 0, 255}, {0, 0, 255, 255}}
+	var frames []image.Image
+	for _, c := range colors {
+		frames = append(frames, solidFrame(4, 3, c))
+	}
+
+	rec := uploadSequence(t, h, frames...)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var resp SequenceResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	// Three frames fit two columns and two rows
+	if resp.FrameCount != 3 || resp.Columns != 2 || resp.FrameWidth != 4 || resp.FrameHeight != 3 {
+		t.Errorf("sequence %+v, want 3 frames of 4x3 in 2 columns", resp)
+	}
+	if resp.Width != 8 || resp.Height != 6 || resp.Name != "walk" {
+		t.Errorf("sheet %q %dx%d, want walk 8x6", resp.Name, resp.Width, resp.Height)
+	}
+
+	f, err := os.Open(filepath.Join(dir, resp.ID+".png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	sheet, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, c := range colors {
+		x, y := i%2*4+1, i/2*3+1
+		if got := color.NRGBAModel.Convert(sheet.At(x, y)); got != c {
+			t.Errorf("frame %d cell at %d,%d is %v, want %v", i, x, y, got, c)
+		}
+	}
+}
+
+func TestUploadSequenceRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		frames []image.Image
+	}{
+		{"no frames", nil},
+		{"mismatched sizes", []image.Image{solidFrame(4, 3, color.NRGBA{}), solidFrame(3, 4, color.NRGBA{})}},
+		{"too wide for a sheet", []image.Image{solidFrame(maxSheetSide+1, 1, color.NRGBA{})}},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		rec := uploadSequence(t, NewHandler(dir), tt.frames...)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d %s, want 400", tt.name, rec.Code, rec.Body)
+			continue
+		}
+		var body httperr.Error
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		if body.Code != httperr.CodeValidationFailed {
+			t.Errorf("%s: code %s, want %s", tt.name, body.Code, httperr.CodeValidationFailed)
+		}
+		if files, _ := os.ReadDir(dir); len(files) != 0 {
+			t.Errorf("%s: stored %d files", tt.name, len(files))
+		}
+	}
+}
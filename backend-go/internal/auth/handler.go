@@ -3,18 +3,26 @@ package auth
 import (
 	"encoding/json"
 	"errors"
-	"log/slog"
 	"net/http"
+
+	"github.com/inamate/inamate/backend-go/internal/httperr"
 )
 
 type Handler struct {
-	service *Service
+	service            *Service
+	registrationClosed bool
 }
 
 func NewHandler(service *Service) *Handler {
 	return &Handler{service: service}
 }
 
+// SetRegistrationEnabled sets whether new accounts may register. It is
+// enabled by default.
+func (h *Handler) SetRegistrationEnabled(enabled bool) {
+	h.registrationClosed = !enabled
+}
+
 type registerRequest struct {
 	Email       string `json:"email"`
 	Password    string `json:"password"`
@@ -27,30 +35,34 @@ type loginRequest struct {
 }
 
 func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
+	if h.registrationClosed {
+		httperr.Write(w, http.StatusForbidden, httperr.CodeFeatureDisabled, "registration is disabled")
+		return
+	}
+
 	var req registerRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeInvalidBody, "invalid request body")
 		return
 	}
 
 	if req.Email == "" || req.Password == "" || req.DisplayName == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "email, password, and displayName are required"})
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed, "email, password, and displayName are required")
 		return
 	}
 
 	if len(req.Password) < 8 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "password must be at least 8 characters"})
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed, "password must be at least 8 characters")
 		return
 	}
 
 	result, err := h.service.Register(r.Context(), req.Email, req.Password, req.DisplayName)
 	if err != nil {
 		if errors.Is(err, ErrEmailTaken) {
-			writeJSON(w, http.StatusConflict, map[string]string{"error": "email already registered"})
+			httperr.Write(w, http.StatusConflict, httperr.CodeEmailTaken, "email already registered")
 			return
 		}
-		slog.Error("register failed", "error", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		httperr.Internal(w, "register failed", err)
 		return
 	}
 
@@ -60,23 +72,26 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	var req loginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeInvalidBody, "invalid request body")
 		return
 	}
 
 	if req.Email == "" || req.Password == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "email and password are required"})
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed, "email and password are required")
 		return
 	}
 
 	result, err := h.service.Login(r.Context(), req.Email, req.Password)
 	if err != nil {
 		if errors.Is(err, ErrInvalidCredentials) {
-			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid credentials"})
+			httperr.Write(w, http.StatusUnauthorized, httperr.CodeInvalidCredentials, "invalid credentials")
+			return
+		}
+		if errors.Is(err, ErrAccountDeactivated) {
+			httperr.Write(w, http.StatusForbidden, httperr.CodeAccountDeactivated, "account deactivated")
 			return
 		}
-		slog.Error("login failed", "error", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		httperr.Internal(w, "login failed", err)
 		return
 	}
 
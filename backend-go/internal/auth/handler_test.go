@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/httperr"
+)
+
+func TestRegistrationDisabled(t *testing.T) {
+	register := func(h *Handler, body string) (int, string) {
+		rec := httptest.NewRecorder()
+		h.Register(rec, httptest.NewRequest(http.MethodPost, "/auth/register", strings.NewReader(body)))
+		var e httperr.Error
+		json.Unmarshal(rec.Body.Bytes(), &e)
+		return rec.Code, e.Code
+	}
+
+	h := NewHandler(nil)
+	h.SetRegistrationEnabled(false)
+	if status, code := register(h, `{"email":"a@example.com","password":"password1","displayName":"A"}`); status != http.StatusForbidden || code != httperr.CodeFeatureDisabled {
+		t.Errorf("disabled: status %d code %q, want 403 %s", status, code, httperr.CodeFeatureDisabled)
+	}
+
+	// Enabled again, requests reach validation
+	h.SetRegistrationEnabled(true)
+	if status, code := register(h, `{"email":"a@example.com"}`); status != http.StatusBadRequest || code != httperr.CodeValidationFailed {
+		t.Errorf("enabled: status %d code %q, want 400 %s", status, code, httperr.CodeValidationFailed)
+	}
+}
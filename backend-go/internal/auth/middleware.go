@@ -2,40 +2,79 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strings"
+
+	"github.com/inamate/inamate/backend-go/internal/httperr"
 )
 
 type contextKey string
 
-const UserIDKey contextKey = "userID"
+const (
+	UserIDKey  contextKey = "userID"
+	IsAdminKey contextKey = "isAdmin"
+)
 
 func (s *Service) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "missing authorization header"})
+			httperr.Write(w, http.StatusUnauthorized, httperr.CodeUnauthorized, "missing authorization header")
 			return
 		}
 
 		parts := strings.SplitN(authHeader, " ", 2)
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid authorization format"})
+			httperr.Write(w, http.StatusUnauthorized, httperr.CodeUnauthorized, "invalid authorization format")
 			return
 		}
 
 		userID, err := s.ValidateToken(parts[1])
 		if err != nil {
-			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+			httperr.Write(w, http.StatusUnauthorized, httperr.CodeInvalidToken, "invalid token")
+			return
+		}
+
+		// Tokens outlive account changes, so check the account is still active.
+		user, err := s.GetUser(r.Context(), userID)
+		if errors.Is(err, context.DeadlineExceeded) {
+			httperr.Internal(w, "auth user lookup", err)
+			return
+		}
+		if err != nil {
+			httperr.Write(w, http.StatusUnauthorized, httperr.CodeInvalidToken, "invalid token")
+			return
+		}
+		if user.Deactivated {
+			httperr.Write(w, http.StatusForbidden, httperr.CodeAccountDeactivated, "account deactivated")
 			return
 		}
 
 		ctx := context.WithValue(r.Context(), UserIDKey, userID)
+		ctx = context.WithValue(ctx, IsAdminKey, user.IsAdmin)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// RequireAdmin rejects requests from non-admin users. It must run after
+// AuthMiddleware.
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !IsAdminFromContext(r.Context()) {
+			httperr.Write(w, http.StatusForbidden, httperr.CodeAdminRequired, "admin access required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func UserIDFromContext(ctx context.Context) string {
 	userID, _ := ctx.Value(UserIDKey).(string)
 	return userID
 }
+
+func IsAdminFromContext(ctx context.Context) bool {
+	isAdmin, _ := ctx.Value(IsAdminKey).(bool)
+	return isAdmin
+}
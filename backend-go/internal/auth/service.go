@@ -18,6 +18,7 @@ import (
 var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrEmailTaken         = errors.New("email already registered")
+	ErrAccountDeactivated = errors.New("account deactivated")
 )
 
 type Service struct {
@@ -41,6 +42,8 @@ type User struct {
 	ID          string `json:"id"`
 	Email       string `json:"email"`
 	DisplayName string `json:"displayName"`
+	IsAdmin     bool   `json:"isAdmin,omitempty"`
+	Deactivated bool   `json:"-"`
 }
 
 func (s *Service) Register(ctx context.Context, email, password, displayName string) (*AuthResult, error) {
@@ -93,6 +96,10 @@ func (s *Service) Login(ctx context.Context, email, password string) (*AuthResul
 		return nil, ErrInvalidCredentials
 	}
 
+	if dbUser.DeactivatedAt.Valid {
+		return nil, ErrAccountDeactivated
+	}
+
 	token, err := s.issueToken(dbUser.ID)
 	if err != nil {
 		return nil, err
@@ -104,6 +111,7 @@ func (s *Service) Login(ctx context.Context, email, password string) (*AuthResul
 			ID:          dbUser.ID,
 			Email:       dbUser.Email,
 			DisplayName: dbUser.DisplayName,
+			IsAdmin:     dbUser.IsAdmin,
 		},
 	}, nil
 }
@@ -144,9 +152,21 @@ func (s *Service) GetUser(ctx context.Context, userID string) (*User, error) {
 		ID:          dbUser.ID,
 		Email:       dbUser.Email,
 		DisplayName: dbUser.DisplayName,
+		IsAdmin:     dbUser.IsAdmin,
+		Deactivated: dbUser.DeactivatedAt.Valid,
 	}, nil
 }
 
+// PromoteAdmin grants the admin role to the user with the given email. It
+// reports whether a matching user exists.
+func (s *Service) PromoteAdmin(ctx context.Context, email string) (bool, error) {
+	n, err := s.queries.SetUserAdminByEmail(ctx, email)
+	if err != nil {
+		return false, fmt.Errorf("promote admin: %w", err)
+	}
+	return n > 0, nil
+}
+
 func (s *Service) issueToken(userID string) (string, error) {
 	claims := jwt.MapClaims{
 		"sub": userID,
@@ -0,0 +1,216 @@
+package collab
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+)
+
+// bounce is a rect with x and y tracks on the root timeline, and targets to
+// paste them onto.
+type bounce struct {
+	ds             *DocumentState
+	source         string
+	x              document.Track
+	targets        []string
+	root, timeline string
+}
+
+func newBounce(t *testing.T, targets int) bounce {
+	ds, source := rectState(t)
+	b := bounce{ds: ds, source: source, root: *ds.doc.Objects[source].Parent, timeline: ds.doc.Project.RootTimeline}
+	b.x = addTrack(ds, source, "transform.x", []document.Keyframe{
+		{Frame: 0, Value: json.RawMessage(`0`), Easing: document.EasingEaseOut},
+		{Frame: 10, Value: json.RawMessage(`100`), Easing: document.EasingLinear},
+	})
+	addTrack(ds, source, "transform.y", []document.Keyframe{
+		{Frame: 5, Value: json.RawMessage(`50`), Easing: document.EasingLinear},
+	})
+	for range targets {
+		b.targets = append(b.targets, addRect(ds, b.root, false))
+	}
+	return b
+}
+
+func (b bounce) copyOp(targets ...string) *Operation {
+	return &Operation{Type: "animation.copy", SourceObjectID: b.source, ObjectIDs: targets, TimelineID: b.timeline}
+}
+
+// tracksOf returns objectID's tracks on the root timeline by property.
+func (b bounce) tracksOf(objectID string) map[string]document.Track {
+	tracks := make(map[string]document.Track)
+	for _, id := range b.ds.doc.Timelines[b.timeline].Tracks {
+		if track := b.ds.doc.Tracks[id]; track.ObjectID == objectID {
+			tracks[track.Property] = track
+		}
+	}
+	return tracks
+}
+
+// frames returns the frames of a track's keyframes.
+func (b bounce) frames(track document.Track) []int {
+	var frames []int
+	for _, kf := range trackState(b.ds, track.ID) {
+		frames = append(frames, kf.Frame)
+	}
+	return frames
+}
+
+func TestAnimationCopyStaggers(t *testing.T) {
+	b := newBounce(t, 3)
+	op := b.copyOp(b.targets...)
+	op.StartOffset, op.FrameOffset = 2, 4
+	apply(t, b.ds, op, "user")
+
+	for i, target := range b.targets {
+		tracks := b.tracksOf(target)
+		if len(tracks) != 2 {
+			t.Fatalf("target %d has %d tracks, want 2", i, len(tracks))
+		}
+		shift := 2 + 4*i
+		if got := b.frames(tracks["transform.x"]); len(got) != 2 || got[0] != shift || got[1] != 10+shift {
+			t.Errorf("target %d x keyframes at %v, want [%d %d]", i, got, shift, 10+shift)
+		}
+		if got := b.frames(tracks["transform.y"]); len(got) != 1 || got[0] != 5+shift {
+			t.Errorf("target %d y keyframes at %v, want [%d]", i, got, 5+shift)
+		}
+		if kf := trackState(b.ds, tracks["transform.x"].ID)[0]; kf.Easing != document.EasingEaseOut || string(kf.Value) != "0" {
+			t.Errorf("target %d first key %s %s, want the source's value and easing", i, kf.Value, kf.Easing)
+		}
+	}
+	if got := b.frames(b.tracksOf(b.source)["transform.x"]); got[0] != 0 || got[1] != 10 {
+		t.Errorf("source keyframes moved to %v", got)
+	}
+}
+
+func TestAnimationCopyIDMap(t *testing.T) {
+	b := newBounce(t, 2)
+	op := b.copyOp(b.targets...)
+	apply(t, b.ds, op, "user")
+
+	seen := map[string]bool{}
+	for _, target := range b.targets {
+		ids := op.IDMap[target]
+		// Two tracks and three keyframes
+		if len(ids) != 5 {
+			t.Fatalf("target %s mapped %d IDs, want 5", target, len(ids))
+		}
+		for sourceID, newID := range ids {
+			if newID == sourceID || seen[newID] {
+				t.Errorf("%s mapped to reused ID %s", sourceID, newID)
+			}
+			seen[newID] = true
+		}
+		if track := b.tracksOf(target)["transform.x"]; track.ID != ids[b.x.ID] || track.Keys[0] != ids[b.x.Keys[0]] {
+			t.Errorf("target track %s keys %v do not match the ID map", track.ID, track.Keys)
+		}
+	}
+}
+
+// TestAnimationCopyReplacesTracks checks a target's track for a copied
+// property is replaced, and its other tracks are kept.
+func TestAnimationCopyReplacesTracks(t *testing.T) {
+	b := newBounce(t, 1)
+	target := b.targets[0]
+	oldX := addTrack(b.ds, target, "transform.x", []document.Keyframe{{Frame: 30, Value: json.RawMessage(`7`)}})
+	rotation := addTrack(b.ds, target, "transform.r", []document.Keyframe{{Frame: 0, Value: json.RawMessage(`90`)}})
+
+	apply(t, b.ds, b.copyOp(target), "user")
+	tracks := b.tracksOf(target)
+	if len(tracks) != 3 {
+		t.Fatalf("target tracks %v, want x, y and r", tracks)
+	}
+	if tracks["transform.x"].ID == oldX.ID || tracks["transform.r"].ID != rotation.ID {
+		t.Error("copied x track did not replace the target's, or r was touched")
+	}
+	if _, ok := b.ds.doc.Keyframes[oldX.Keys[0]]; ok {
+		t.Error("replaced track's keyframe left in the document")
+	}
+}
+
+func TestAnimationCopyUndo(t *testing.T) {
+	b := newBounce(t, 2)
+	existing := addTrack(b.ds, b.targets[0], "transform.x", []document.Keyframe{{Frame: 30, Value: json.RawMessage(`7`)}})
+	tracks, keyframes := len(b.ds.doc.Tracks), len(b.ds.doc.Keyframes)
+
+	apply(t, b.ds, b.copyOp(b.targets...), "user")
+	undo(t, b.ds, "user")
+	if len(b.ds.doc.Tracks) != tracks || len(b.ds.doc.Keyframes) != keyframes {
+		t.Errorf("%d tracks, %d keyframes after undo; want %d, %d", len(b.ds.doc.Tracks), len(b.ds.doc.Keyframes), tracks, keyframes)
+	}
+	if got := b.tracksOf(b.targets[0]); len(got) != 1 || got["transform.x"].ID != existing.ID {
+		t.Errorf("first target tracks %v after undo, want its own x track back", got)
+	}
+	if got := b.tracksOf(b.targets[1]); len(got) != 0 {
+		t.Errorf("second target tracks %v after undo, want none", got)
+	}
+
+	redo(t, b.ds, "user")
+	for _, target := range b.targets {
+		if got := b.tracksOf(target); len(got) != 2 {
+			t.Errorf("target tracks %v after redo, want the copied x and y", got)
+		}
+	}
+}
+
+func TestAnimationCopyRejected(t *testing.T) {
+	b := newBounce(t, 1)
+	unanimated := addRect(b.ds, b.root, false)
+
+	tests := []struct {
+		name string
+		op   *Operation
+	}{
+		{"no targets", b.copyOp()},
+		{"onto itself", b.copyOp(b.source)},
+		{"missing target", b.copyOp("obj_missing")},
+		{"missing timeline", &Operation{Type: "animation.copy", SourceObjectID: b.source, ObjectIDs: b.targets, TimelineID: "tl_missing"}},
+		{"unanimated source", &Operation{Type: "animation.copy", SourceObjectID: unanimated, ObjectIDs: b.targets, TimelineID: b.timeline}},
+		{"foreign track", &Operation{Type: "animation.copy", SourceObjectID: b.source, ObjectIDs: b.targets, TimelineID: b.timeline, TrackIDs: []string{"trk_missing"}}},
+	}
+	tracks := len(b.ds.doc.Tracks)
+	for _, tt := range tests {
+		tt.op.ID = "op_" + tt.name
+		if _, err := b.ds.ApplyOperation(tt.op, "user"); err == nil {
+			t.Errorf("%s: applied", tt.name)
+		}
+	}
+	if len(b.ds.doc.Tracks) != tracks {
+		t.Errorf("%d tracks after rejected copies, want %d", len(b.ds.doc.Tracks), tracks)
+	}
+}
+
+// TestAnimationCopySelectedTracks copies only a spinner's rotation onto
+// another rect, later in time, and checks the copy is independent of it.
+func TestAnimationCopySelectedTracks(t *testing.T) {
+	b := newBounce(t, 1)
+	spin := addTrack(b.ds, b.source, "transform.r", []document.Keyframe{
+		{Frame: 0, Value: json.RawMessage(`0`), Easing: document.EasingLinear},
+		{Frame: 24, Value: json.RawMessage(`360`), Easing: document.EasingLinear},
+	})
+	target := b.targets[0]
+
+	op := b.copyOp(target)
+	op.TrackIDs = []string{spin.ID}
+	op.StartOffset = 12
+	apply(t, b.ds, op, "user")
+
+	tracks := b.tracksOf(target)
+	if len(tracks) != 1 {
+		t.Fatalf("target tracks %v, want only the rotation", tracks)
+	}
+	copied := tracks["transform.r"]
+	if copied.ID == spin.ID {
+		t.Fatal("target shares the source's track")
+	}
+	keys := trackState(b.ds, copied.ID)
+	if len(keys) != 2 || keys[0].Frame != 12 || keys[1].Frame != 36 || string(keys[1].Value) != "360" {
+		t.Fatalf("copied keys %+v, want 0 and 360 at frames 12 and 36", keys)
+	}
+
+	apply(t, b.ds, &Operation{Type: "keyframe.update", KeyframeID: keys[1].ID, Changes: json.RawMessage(`{"value":720}`)}, "user")
+	if got := string(trackState(b.ds, spin.ID)[1].Value); got != "360" {
+		t.Errorf("editing the copy changed the source's key to %s", got)
+	}
+}
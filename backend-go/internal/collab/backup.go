@@ -0,0 +1,71 @@
+//go:build !(js && wasm)
+
+package collab
+
+import (
+	"log/slog"
+	"time"
+)
+
+// BackupLabelPrefix starts the label of every safety snapshot, which is
+// followed by what the snapshot guards against: "pre: scene.delete by Ada".
+const BackupLabelPrefix = "pre: "
+
+// defaultBackupInterval is the least time between a room's safety snapshots,
+// so a run of deletions doesn't write one per operation.
+const defaultBackupInterval = time.Minute
+
+// destructiveOps are the operation types that can wreck a lot of work at
+// once. Before one is applied, the room's document is saved as a safety
+// snapshot, since the last autosave may be minutes old.
+var destructiveOps = map[string]bool{
+	"scene.delete":        true,
+	"object.delete":       true,
+	"timeline.removeTime": true,
+}
+
+// SetBackupInterval sets the least time between a room's safety snapshots.
+func (h *Hub) SetBackupInterval(d time.Duration) {
+	if d > 0 {
+		h.backupInterval = d
+	}
+}
+
+// backupBefore saves the room's document as a safety snapshot before op is
+// applied on behalf of author, if op is a destructive type the hub permits,
+// the document has changes its last snapshot lacks, and the room hasn't been
+// backed up within the backup interval. The operation goes ahead even if
+// the save fails.
+func (h *Hub) backupBefore(room *Room, op *Operation, author string) {
+	if !destructiveOps[op.Type] || !h.opPolicy.Permits(op.Type) {
+		return
+	}
+	if !room.docState.IsDirty() || !room.claimBackup(time.Now(), h.backupInterval) {
+		return
+	}
+	label := BackupLabelPrefix + op.Type + " by " + author
+	if err := h.saveRoom(room.projectID, room, label); err != nil {
+		slog.Warn("safety snapshot failed", "project", room.projectID, "label", label, "error", err)
+	}
+}
+
+// claimBackup reports whether the room may take a safety snapshot at now,
+// interval after its last one, and if so records now as its last.
+func (r *Room) claimBackup(now time.Time, interval time.Duration) bool {
+	r.saveMu.Lock()
+	defer r.saveMu.Unlock()
+	if !r.lastBackup.IsZero() && now.Sub(r.lastBackup) < interval {
+		return false
+	}
+	r.lastBackup = now
+	return true
+}
+
+// backupAuthor is how a client is named in the labels of the safety
+// snapshots its operations trigger.
+func backupAuthor(c *Client) string {
+	if c.DisplayName != "" {
+		return c.DisplayName
+	}
+	return c.UserID
+}
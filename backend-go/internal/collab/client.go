@@ -1,8 +1,11 @@
+//go:build !(js && wasm)
+
 package collab
 
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"time"
 
@@ -13,7 +16,17 @@ const (
 	writeWait  = 10 * time.Second
 	pongWait   = 60 * time.Second
 	pingPeriod = 30 * time.Second
-	maxMsgSize = 64 * 1024
+
+	// Minimum time between doc.requestSync messages a client may send
+	syncRequestInterval = 2 * time.Second
+)
+
+// Project member roles, as stored. A viewer receives the document and shares
+// presence but can't submit or undo operations.
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
 )
 
 type Client struct {
@@ -24,9 +37,18 @@ type Client struct {
 	DisplayName string
 	ProjectID   string
 	ClientID    string
+	Role        string
+
+	// ResumeSeq is the last server sequence a reconnecting client saw, so it
+	// is sent only the operations it missed on joining; zero for a client
+	// that needs the whole document. Set before Register.
+	ResumeSeq int64
+
+	room            *Room     // Set by Register; nil if the room failed to load
+	lastSyncRequest time.Time // Only touched from the room's goroutine
 }
 
-func NewClient(hub *Hub, conn *websocket.Conn, userID, displayName, projectID, clientID string) *Client {
+func NewClient(hub *Hub, conn *websocket.Conn, userID, displayName, projectID, clientID, role string) *Client {
 	return &Client{
 		hub:         hub,
 		conn:        conn,
@@ -35,16 +57,17 @@ func NewClient(hub *Hub, conn *websocket.Conn, userID, displayName, projectID, c
 		DisplayName: displayName,
 		ProjectID:   projectID,
 		ClientID:    clientID,
+		Role:        role,
 	}
 }
 
 func (c *Client) ReadPump(ctx context.Context) {
 	defer func() {
-		c.hub.unregister <- c
+		c.hub.removeClient(c)
 		c.conn.Close(websocket.StatusNormalClosure, "")
 	}()
 
-	c.conn.SetReadLimit(maxMsgSize)
+	c.conn.SetReadLimit(c.hub.readLimit)
 
 	for {
 		_, data, err := c.conn.Read(ctx)
@@ -53,6 +76,10 @@ func (c *Client) ReadPump(ctx context.Context) {
 				websocket.CloseStatus(err) == websocket.StatusGoingAway {
 				return
 			}
+			if errors.Is(err, websocket.ErrMessageTooBig) {
+				slog.Warn("message exceeds read limit", "limit", c.hub.readLimit, "user", c.UserID)
+				return
+			}
 			slog.Debug("read error", "error", err, "user", c.UserID)
 			return
 		}
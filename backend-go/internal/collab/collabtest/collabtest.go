@@ -0,0 +1,482 @@
+//go:build !(js && wasm)
+
+// Package collabtest runs the collaboration hub behind a real WebSocket
+// server, with in-memory document storage, so multi-client sessions can be
+// scripted end to end without Postgres: clients join, submit operations,
+// wait for their acks and broadcasts, and compare the documents they end up
+// with.
+package collabtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/coder/websocket"
+
+	"github.com/inamate/inamate/backend-go/internal/collab"
+	"github.com/inamate/inamate/backend-go/internal/document"
+)
+
+// Store is an in-memory document store standing in for the snapshot table.
+// Its Load and Save methods are the hub's DocumentLoader and DocumentSaver.
+type Store struct {
+	mu     sync.Mutex
+	docs   map[string][]byte // projectID -> encoded document
+	seqs   map[string]int64
+	labels map[string][]string // projectID -> labels of the labeled saves
+	saves  int
+}
+
+// NewStore returns an empty store.
+func NewStore() *Store {
+	return &Store{
+		docs:   make(map[string][]byte),
+		seqs:   make(map[string]int64),
+		labels: make(map[string][]string),
+	}
+}
+
+// Put stores doc as the project's saved document at sequence zero.
+func (s *Store) Put(projectID string, doc *document.InDocument) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.docs[projectID] = data
+	s.seqs[projectID] = 0
+	return nil
+}
+
+// Load returns a copy of the project's saved document.
+func (s *Store) Load(ctx context.Context, projectID string) (*document.InDocument, int64, error) {
+	s.mu.Lock()
+	data, ok := s.docs[projectID]
+	seq := s.seqs[projectID]
+	s.mu.Unlock()
+	if !ok {
+		return nil, 0, fmt.Errorf("project not found: %s", projectID)
+	}
+	var doc document.InDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, 0, err
+	}
+	return &doc, seq, nil
+}
+
+// Save records doc as the project's saved document at seq.
+func (s *Store) Save(ctx context.Context, projectID string, doc *document.InDocument, seq int64, label string) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.docs[projectID] = data
+	s.seqs[projectID] = seq
+	if label != "" {
+		s.labels[projectID] = append(s.labels[projectID], label)
+	}
+	s.saves++
+	return nil
+}
+
+// Saved returns the project's saved document and the sequence it was saved
+// at.
+func (s *Store) Saved(projectID string) (*document.InDocument, int64, error) {
+	return s.Load(context.Background(), projectID)
+}
+
+// Saves returns how many times the hub has saved a document.
+func (s *Store) Saves() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.saves
+}
+
+// Labels returns the labels of the project's labeled saves, such as safety
+// snapshots, oldest first.
+func (s *Store) Labels(projectID string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.labels[projectID])
+}
+
+// Server is a hub served over WebSocket by an httptest server. Clients
+// connect to /ws/{projectID}?user={userID}&client={clientID}&role={role}; no
+// authentication is done.
+type Server struct {
+	Hub   *collab.Hub
+	Store *Store
+	http  *httptest.Server
+}
+
+// NewServer starts a hub backed by store and serves it.
+func NewServer(store *Store) *Server {
+	hub := collab.NewHub(store.Load, store.Save)
+	go hub.Run()
+
+	s := &Server{Hub: hub, Store: store}
+	s.http = httptest.NewServer(http.HandlerFunc(s.serveWS))
+	return s
+}
+
+// Close disconnects every client, then stops the hub, saving dirty rooms.
+func (s *Server) Close() {
+	s.http.CloseClientConnections()
+	s.http.Close()
+	s.Hub.Stop()
+}
+
+func (s *Server) serveWS(w http.ResponseWriter, r *http.Request) {
+	projectID := strings.TrimPrefix(r.URL.Path, "/ws/")
+	userID := r.URL.Query().Get("user")
+	if projectID == "" || userID == "" {
+		http.Error(w, "project and user are required", http.StatusBadRequest)
+		return
+	}
+
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		return
+	}
+	clientID := r.URL.Query().Get("client")
+	if clientID == "" {
+		clientID = userID
+	}
+	role := r.URL.Query().Get("role")
+	if role == "" {
+		role = collab.RoleEditor
+	}
+	client := collab.NewClient(s.Hub, conn, userID, userID, projectID, clientID, role)
+	client.ResumeSeq, _ = strconv.ParseInt(r.URL.Query().Get("seq"), 10, 64)
+	s.Hub.Register(client)
+
+	ctx := r.Context()
+	go client.WritePump(ctx)
+	client.ReadPump(ctx)
+}
+
+// Client is a scripted WebSocket connection to a Server.
+type Client struct {
+	UserID string
+
+	conn     *websocket.Conn
+	incoming chan *collab.Message
+	done     chan struct{}
+	err      error // Why the read loop stopped; set before done closes
+
+	mu      sync.Mutex
+	pending []*collab.Message // Received but not yet waited for
+	seq     int64             // Latest server sequence seen in an ack, broadcast or sync
+}
+
+// ErrClosed is returned when waiting on a client whose connection has closed.
+var ErrClosed = errors.New("collabtest: connection closed")
+
+// Connect joins projectID as userID, an editor, with a connection of its own.
+// Two clients with the same userID and clientID replace each other in the
+// room, as a reconnect does.
+func (s *Server) Connect(ctx context.Context, projectID, userID, clientID string) (*Client, error) {
+	return s.ConnectAs(ctx, projectID, userID, clientID, collab.RoleEditor)
+}
+
+// ConnectAs is Connect with the member role the client joins with.
+func (s *Server) ConnectAs(ctx context.Context, projectID, userID, clientID, role string) (*Client, error) {
+	return s.dial(ctx, projectID, userID, clientID, role, 0)
+}
+
+// Reconnect joins projectID as an editor that last saw server sequence
+// lastSeq, so the server answers with doc.resyncOps rather than doc.sync
+// when it can.
+func (s *Server) Reconnect(ctx context.Context, projectID, userID, clientID string, lastSeq int64) (*Client, error) {
+	return s.dial(ctx, projectID, userID, clientID, collab.RoleEditor, lastSeq)
+}
+
+func (s *Server) dial(ctx context.Context, projectID, userID, clientID, role string, lastSeq int64) (*Client, error) {
+	url := "ws" + strings.TrimPrefix(s.http.URL, "http") + "/ws/" + projectID + "?user=" + userID + "&client=" + clientID + "&role=" + role
+	if lastSeq > 0 {
+		url += "&seq=" + strconv.FormatInt(lastSeq, 10)
+	}
+	conn, _, err := websocket.Dial(ctx, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetReadLimit(-1)
+
+	c := &Client{
+		UserID:   userID,
+		conn:     conn,
+		incoming: make(chan *collab.Message, 256),
+		done:     make(chan struct{}),
+	}
+	go c.readLoop()
+	return c, nil
+}
+
+func (c *Client) readLoop() {
+	defer close(c.done)
+	for {
+		_, data, err := c.conn.Read(context.Background())
+		if err != nil {
+			c.err = err
+			return
+		}
+		var msg collab.Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.err = fmt.Errorf("invalid message: %w", err)
+			return
+		}
+		c.incoming <- &msg
+	}
+}
+
+// Close closes the connection, which the hub treats as the client leaving.
+func (c *Client) Close() error {
+	return c.conn.Close(websocket.StatusNormalClosure, "")
+}
+
+// Send sends a message of the given type with payload encoded as JSON.
+func (c *Client) Send(ctx context.Context, msgType string, payload interface{}) error {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(collab.Message{Type: msgType, Payload: raw})
+	if err != nil {
+		return err
+	}
+	return c.conn.Write(ctx, websocket.MessageText, data)
+}
+
+// Submit sends op as an op.submit.
+func (c *Client) Submit(ctx context.Context, op collab.Operation) error {
+	return c.Send(ctx, collab.TypeOpSubmit, op)
+}
+
+// SubmitAndWait submits op and waits for the server's answer to it.
+func (c *Client) SubmitAndWait(ctx context.Context, op collab.Operation) (*collab.OperationAckPayload, *collab.OperationNackPayload, error) {
+	if err := c.Submit(ctx, op); err != nil {
+		return nil, nil, err
+	}
+	return c.Result(ctx, op.ID)
+}
+
+// Undo sends an op.undo of the client's most recent operation still in
+// effect, applied as an operation with the given ID, and waits for the
+// server's answer to it.
+func (c *Client) Undo(ctx context.Context, id string) (*collab.OperationAckPayload, *collab.OperationNackPayload, error) {
+	if err := c.Send(ctx, collab.TypeOpUndo, collab.OperationUndoPayload{ID: id}); err != nil {
+		return nil, nil, err
+	}
+	return c.Result(ctx, id)
+}
+
+// Redo sends an op.redo, applied as an operation with the given ID, and
+// waits for the server's answer to it.
+func (c *Client) Redo(ctx context.Context, id string) (*collab.OperationAckPayload, *collab.OperationNackPayload, error) {
+	if err := c.Send(ctx, collab.TypeOpRedo, collab.OperationRedoPayload{ID: id}); err != nil {
+		return nil, nil, err
+	}
+	return c.Result(ctx, id)
+}
+
+// Result waits for the op.ack or op.nack answering the operation with the
+// given ID.
+func (c *Client) Result(ctx context.Context, operationID string) (*collab.OperationAckPayload, *collab.OperationNackPayload, error) {
+	msg, err := c.WaitFor(ctx, func(msg *collab.Message) bool {
+		if msg.Type != collab.TypeOpAck && msg.Type != collab.TypeOpNack {
+			return false
+		}
+		var answer struct {
+			OperationID string `json:"operationId"`
+		}
+		return json.Unmarshal(msg.Payload, &answer) == nil && answer.OperationID == operationID
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if msg.Type == collab.TypeOpNack {
+		var nack collab.OperationNackPayload
+		return nil, &nack, json.Unmarshal(msg.Payload, &nack)
+	}
+	var ack collab.OperationAckPayload
+	return &ack, nil, json.Unmarshal(msg.Payload, &ack)
+}
+
+// SubmitBatch sends ops as one op.batch with the given ID and waits for the
+// server's answer to it.
+func (c *Client) SubmitBatch(ctx context.Context, batchID string, ops []collab.Operation) (*collab.OperationBatchAckPayload, *collab.OperationBatchNackPayload, error) {
+	if err := c.Send(ctx, collab.TypeOpBatch, collab.OperationBatchPayload{ID: batchID, Operations: ops}); err != nil {
+		return nil, nil, err
+	}
+	msg, err := c.WaitFor(ctx, func(msg *collab.Message) bool {
+		if msg.Type != collab.TypeOpBatchAck && msg.Type != collab.TypeOpBatchNack {
+			return false
+		}
+		var answer struct {
+			BatchID string `json:"batchId"`
+		}
+		return json.Unmarshal(msg.Payload, &answer) == nil && answer.BatchID == batchID
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if msg.Type == collab.TypeOpBatchNack {
+		var nack collab.OperationBatchNackPayload
+		return nil, &nack, json.Unmarshal(msg.Payload, &nack)
+	}
+	var ack collab.OperationBatchAckPayload
+	return &ack, nil, json.Unmarshal(msg.Payload, &ack)
+}
+
+// BatchBroadcast waits for the op.batchBroadcast of the batch with the given
+// ID.
+func (c *Client) BatchBroadcast(ctx context.Context, batchID string) (*collab.OperationBatchBroadcastPayload, error) {
+	var broadcast collab.OperationBatchBroadcastPayload
+	_, err := c.WaitFor(ctx, func(msg *collab.Message) bool {
+		if msg.Type != collab.TypeOpBatchBroadcast {
+			return false
+		}
+		return json.Unmarshal(msg.Payload, &broadcast) == nil && broadcast.BatchID == batchID
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &broadcast, nil
+}
+
+// Broadcast waits for the op.broadcast of the operation with the given ID.
+func (c *Client) Broadcast(ctx context.Context, operationID string) (*collab.OperationBroadcastPayload, error) {
+	var broadcast collab.OperationBroadcastPayload
+	_, err := c.WaitFor(ctx, func(msg *collab.Message) bool {
+		if msg.Type != collab.TypeOpBroadcast {
+			return false
+		}
+		return json.Unmarshal(msg.Payload, &broadcast) == nil && broadcast.Operation.ID == operationID
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &broadcast, nil
+}
+
+// Sync waits for the next doc.sync and returns its document and sequence.
+// The first follows the welcome on joining; RequestSync asks for another.
+func (c *Client) Sync(ctx context.Context) (*document.InDocument, int64, error) {
+	msg, err := c.Expect(ctx, collab.TypeDocSync)
+	if err != nil {
+		return nil, 0, err
+	}
+	var doc document.InDocument
+	if err := json.Unmarshal(msg.Payload, &doc); err != nil {
+		return nil, 0, err
+	}
+	return &doc, msg.Seq, nil
+}
+
+// RequestSync asks for the document again and waits for it. The hub rate
+// limits these per connection, answering a second request within its
+// interval with an error instead.
+func (c *Client) RequestSync(ctx context.Context) (*document.InDocument, int64, error) {
+	if err := c.Send(ctx, collab.TypeDocRequestSync, struct{}{}); err != nil {
+		return nil, 0, err
+	}
+	return c.Sync(ctx)
+}
+
+// Expect waits for the next message of the given type.
+func (c *Client) Expect(ctx context.Context, msgType string) (*collab.Message, error) {
+	return c.WaitFor(ctx, func(msg *collab.Message) bool { return msg.Type == msgType })
+}
+
+// WaitFor waits for a message match accepts and returns it. Messages it
+// rejects stay queued, in order, for later waits.
+func (c *Client) WaitFor(ctx context.Context, match func(*collab.Message) bool) (*collab.Message, error) {
+	c.mu.Lock()
+	for i, msg := range c.pending {
+		if match(msg) {
+			c.pending = append(c.pending[:i], c.pending[i+1:]...)
+			c.mu.Unlock()
+			return msg, nil
+		}
+	}
+	c.mu.Unlock()
+
+	for {
+		select {
+		case msg := <-c.incoming:
+			c.observe(msg)
+			if match(msg) {
+				return msg, nil
+			}
+			c.mu.Lock()
+			c.pending = append(c.pending, msg)
+			c.mu.Unlock()
+		case <-c.done:
+			if c.err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrClosed, c.err)
+			}
+			return nil, ErrClosed
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// observe tracks the latest server sequence the client has seen.
+func (c *Client) observe(msg *collab.Message) {
+	var seq int64
+	switch msg.Type {
+	case collab.TypeDocSync, collab.TypeDocResyncOps:
+		seq = msg.Seq
+	case collab.TypeOpAck, collab.TypeOpBroadcast, collab.TypeOpBatchAck, collab.TypeOpBatchBroadcast:
+		var p struct {
+			ServerSeq int64 `json:"serverSeq"`
+		}
+		if json.Unmarshal(msg.Payload, &p) == nil {
+			seq = p.ServerSeq
+		}
+	}
+	c.mu.Lock()
+	c.seq = max(c.seq, seq)
+	c.mu.Unlock()
+}
+
+// Seq returns the latest server sequence the client has received.
+func (c *Client) Seq() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.seq
+}
+
+// Converged reports whether every document is identical to the first,
+// comparing their JSON encodings, and describes the first difference.
+func Converged(docs ...*document.InDocument) (bool, string) {
+	if len(docs) < 2 {
+		return true, ""
+	}
+	want, err := json.Marshal(docs[0])
+	if err != nil {
+		return false, err.Error()
+	}
+	for i, doc := range docs[1:] {
+		got, err := json.Marshal(doc)
+		if err != nil {
+			return false, err.Error()
+		}
+		if string(got) != string(want) {
+			return false, fmt.Sprintf("document %d differs from document 0", i+1)
+		}
+	}
+	return true, ""
+}
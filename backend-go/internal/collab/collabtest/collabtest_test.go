@@ -0,0 +1,550 @@
+//go:build !(js && wasm)
+
+package collabtest_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/inamate/inamate/backend-go/internal/collab"
+	"github.com/inamate/inamate/backend-go/internal/collab/collabtest"
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// session is a project with one rect under its scene's root, served by a
+// collabtest server.
+type session struct {
+	t         *testing.T
+	ctx       context.Context
+	server    *collabtest.Server
+	projectID string
+	rootID    string
+	rectID    string
+}
+
+func newSession(t *testing.T) *session {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	s := &session{
+		t:         t,
+		ctx:       ctx,
+		projectID: typeid.NewProjectID(),
+		rootID:    typeid.NewObjectID(),
+		rectID:    typeid.NewObjectID(),
+	}
+	doc := document.NewEmptyDocument(s.projectID, "Test", typeid.NewSceneID(), s.rootID, typeid.NewTimelineID())
+	root := doc.Objects[s.rootID]
+	root.Children = append(root.Children, s.rectID)
+	doc.Objects[s.rootID] = root
+	doc.Objects[s.rectID] = rect(s.rectID, s.rootID)
+
+	store := collabtest.NewStore()
+	if err := store.Put(s.projectID, doc); err != nil {
+		t.Fatal(err)
+	}
+	s.server = collabtest.NewServer(store)
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func rect(id, parentID string) document.ObjectNode {
+	return document.ObjectNode{
+		ID:        id,
+		Type:      document.ObjectTypeShapeRect,
+		Parent:    &parentID,
+		Children:  []string{},
+		Transform: document.Transform{SX: 1, SY: 1},
+		Style:     document.Style{Fill: "#ff0000", Opacity: 1},
+		Visible:   true,
+		Data:      json.RawMessage(`{"width":40,"height":30}`),
+	}
+}
+
+// join connects userID as an editor and waits for its first doc.sync,
+// returning the sequence it was synced at.
+func (s *session) join(userID string) (*collabtest.Client, int64) {
+	s.t.Helper()
+	c, err := s.server.Connect(s.ctx, s.projectID, userID, userID)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	s.t.Cleanup(func() { c.Close() })
+	_, seq, err := c.Sync(s.ctx)
+	if err != nil {
+		s.t.Fatalf("%s: sync: %v", userID, err)
+	}
+	return c, seq
+}
+
+// converge fetches every client's document and fails unless they match.
+func (s *session) converge(clients ...*collabtest.Client) *document.InDocument {
+	s.t.Helper()
+	docs := make([]*document.InDocument, len(clients))
+	for i, c := range clients {
+		doc, _, err := c.RequestSync(s.ctx)
+		if err != nil {
+			s.t.Fatalf("%s: request sync: %v", c.UserID, err)
+		}
+		docs[i] = doc
+	}
+	if ok, diff := collabtest.Converged(docs...); !ok {
+		s.t.Fatalf("documents diverged: %s", diff)
+	}
+	return docs[0]
+}
+
+func newOpID() string {
+	return typeid.New("op")
+}
+
+func moveOp(objectID string, x float64) collab.Operation {
+	return collab.Operation{
+		ID:        newOpID(),
+		Type:      "object.transform",
+		ObjectID:  objectID,
+		Transform: json.RawMessage(fmt.Sprintf(`{"x":%g}`, x)),
+	}
+}
+
+func createOp(t *testing.T, obj document.ObjectNode) collab.Operation {
+	t.Helper()
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return collab.Operation{
+		ID:       newOpID(),
+		Type:     "object.create",
+		Object:   raw,
+		ParentID: *obj.Parent,
+	}
+}
+
+func deleteOp(objectID string) collab.Operation {
+	return collab.Operation{ID: newOpID(), Type: "object.delete", ObjectID: objectID}
+}
+
+func TestConcurrentTransformsConverge(t *testing.T) {
+	s := newSession(t)
+	const perClient = 20
+	clients := make([]*collabtest.Client, 3)
+	for i := range clients {
+		clients[i], _ = s.join(fmt.Sprintf("user%d", i))
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(clients)*perClient)
+	for i, c := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range perClient {
+				ack, nack, err := c.SubmitAndWait(s.ctx, moveOp(s.rectID, float64(i*1000+n)))
+				switch {
+				case err != nil:
+					errs <- err
+				case ack == nil:
+					errs <- fmt.Errorf("%s: nack: %s", c.UserID, nack.Reason)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	doc := s.converge(clients...)
+	// Every client saw every operation, and the last one sequenced won
+	for _, c := range clients {
+		if c.Seq() != int64(len(clients)*perClient) {
+			t.Errorf("%s saw up to seq %d, want %d", c.UserID, c.Seq(), len(clients)*perClient)
+		}
+	}
+	if x := doc.Objects[s.rectID].Transform.X; int(x)%1000 != perClient-1 {
+		t.Errorf("final x = %v, want some client's last move", x)
+	}
+}
+
+func TestCreateDeleteRacesConverge(t *testing.T) {
+	s := newSession(t)
+	creator, _ := s.join("creator")
+	deleter, _ := s.join("deleter")
+	mover, _ := s.join("mover")
+
+	for range 10 {
+		id := typeid.NewObjectID()
+		ops := map[*collabtest.Client]collab.Operation{
+			creator: createOp(t, rect(id, s.rootID)),
+			deleter: deleteOp(id),
+			mover:   moveOp(id, 50),
+		}
+		var wg sync.WaitGroup
+		for c, op := range ops {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				// Each operation is answered, acked or nacked by where it
+				// landed relative to the create
+				if _, _, err := c.SubmitAndWait(s.ctx, op); err != nil {
+					t.Errorf("%s: %v", c.UserID, err)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+
+	doc := s.converge(creator, deleter, mover)
+	if err := doc.Validate(); err != nil {
+		t.Fatalf("converged document is invalid: %v", err)
+	}
+}
+
+func TestJoinDuringEdit(t *testing.T) {
+	s := newSession(t)
+	editor, _ := s.join("editor")
+
+	type edit struct {
+		id  string
+		seq int64
+	}
+	const edits = 40
+	started := make(chan struct{})
+	done := make(chan []edit)
+	go func() {
+		var applied []edit
+		defer func() { done <- applied }()
+		for n := range edits {
+			op := moveOp(s.rectID, float64(n))
+			ack, _, err := editor.SubmitAndWait(s.ctx, op)
+			if err != nil || ack == nil {
+				t.Errorf("edit %d not acked: %v", n, err)
+				return
+			}
+			applied = append(applied, edit{op.ID, ack.ServerSeq})
+			if n == 0 {
+				close(started)
+			}
+		}
+	}()
+
+	<-started
+	late, syncedAt := s.join("late")
+	applied := <-done
+	if len(applied) != edits {
+		t.FailNow()
+	}
+
+	// Every edit the document it was sent didn't include is broadcast to
+	// the late joiner, so it misses nothing
+	for _, e := range applied {
+		if e.seq <= syncedAt {
+			continue
+		}
+		if _, err := late.Broadcast(s.ctx, e.id); err != nil {
+			t.Fatalf("late joiner (synced at %d) missed seq %d: %v", syncedAt, e.seq, err)
+		}
+	}
+	if last := applied[len(applied)-1].seq; late.Seq() != last {
+		t.Errorf("late joiner reached seq %d, want %d", late.Seq(), last)
+	}
+	s.converge(editor, late)
+}
+
+func TestReconnectResyncsMissedOps(t *testing.T) {
+	s := newSession(t)
+	editor, _ := s.join("editor")
+	roamer, _ := s.join("roamer")
+
+	first := moveOp(s.rectID, 1)
+	if _, _, err := editor.SubmitAndWait(s.ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := roamer.Broadcast(s.ctx, first.ID); err != nil {
+		t.Fatal(err)
+	}
+	lastSeq := roamer.Seq()
+
+	roamer.Close()
+	if _, err := editor.WaitFor(s.ctx, func(msg *collab.Message) bool {
+		return msg.Type == collab.TypePresenceLeave && msg.UserID == "roamer"
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var missed []string
+	var serverSeq int64
+	for n := range 3 {
+		op := moveOp(s.rectID, float64(10+n))
+		ack, _, err := editor.SubmitAndWait(s.ctx, op)
+		if err != nil || ack == nil {
+			t.Fatalf("edit %d not acked: %v", n, err)
+		}
+		missed = append(missed, op.ID)
+		serverSeq = ack.ServerSeq
+	}
+
+	back, err := s.server.Reconnect(s.ctx, s.projectID, "roamer", "roamer", lastSeq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { back.Close() })
+	msg, err := back.Expect(s.ctx, collab.TypeDocResyncOps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resync collab.ResyncOpsPayload
+	if err := json.Unmarshal(msg.Payload, &resync); err != nil {
+		t.Fatal(err)
+	}
+	if resync.LastSeq != lastSeq || resync.ServerSeq != serverSeq {
+		t.Errorf("resync from %d to %d, want %d to %d", resync.LastSeq, resync.ServerSeq, lastSeq, serverSeq)
+	}
+	if len(resync.Operations) != len(missed) {
+		t.Fatalf("resync sent %d operations, want %d", len(resync.Operations), len(missed))
+	}
+	for i, op := range resync.Operations {
+		if op.Operation.ID != missed[i] {
+			t.Errorf("resync operation %d is %s, want %s", i, op.Operation.ID, missed[i])
+		}
+	}
+
+	// The reconnected client edits as before
+	op := moveOp(s.rectID, 99)
+	if ack, nack, err := back.SubmitAndWait(s.ctx, op); err != nil || ack == nil {
+		t.Fatalf("edit after reconnecting: %v %+v", err, nack)
+	}
+	if _, err := editor.Broadcast(s.ctx, op.ID); err != nil {
+		t.Fatal(err)
+	}
+	s.converge(editor, back)
+}
+
+func nudgeOp(objectID, dx string) collab.Operation {
+	return collab.Operation{
+		ID:        newOpID(),
+		Type:      "object.nudge",
+		ObjectIDs: []string{objectID},
+		Deltas:    map[string]json.RawMessage{"x": json.RawMessage(`"` + dx + `"`)},
+	}
+}
+
+func TestConcurrentNudgesCompose(t *testing.T) {
+	s := newSession(t)
+	const perClient = 15
+	nudgers := make([]*collabtest.Client, 3)
+	for i := range nudgers {
+		nudgers[i], _ = s.join(fmt.Sprintf("nudger%d", i))
+	}
+	mover, _ := s.join("mover")
+
+	// Each nudger moves the rect right by 1px at a time while the mover
+	// sets it absolutely once, somewhere in between
+	var mu sync.Mutex
+	var nudged []int64
+	var wg sync.WaitGroup
+	for _, c := range nudgers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range perClient {
+				ack, nack, err := c.SubmitAndWait(s.ctx, nudgeOp(s.rectID, "1px"))
+				if err != nil || ack == nil {
+					t.Errorf("%s: nudge not acked: %v %+v", c.UserID, err, nack)
+					return
+				}
+				mu.Lock()
+				nudged = append(nudged, ack.ServerSeq)
+				mu.Unlock()
+			}
+		}()
+	}
+	ack, nack, err := mover.SubmitAndWait(s.ctx, moveOp(s.rectID, 1000))
+	if err != nil || ack == nil {
+		t.Fatalf("move not acked: %v %+v", err, nack)
+	}
+	wg.Wait()
+	if t.Failed() {
+		t.FailNow()
+	}
+
+	// No nudge is lost to another: the rect sits at the move plus every
+	// nudge sequenced after it
+	after := 0
+	for _, seq := range nudged {
+		if seq > ack.ServerSeq {
+			after++
+		}
+	}
+	doc := s.converge(append(nudgers, mover)...)
+	if x := doc.Objects[s.rectID].Transform.X; x != float64(1000+after) {
+		t.Errorf("x = %v, want 1000 plus the %d nudges after the move", x, after)
+	}
+
+	// A nudge naming a property that isn't a number is nacked
+	bad := nudgeOp(s.rectID, "1px")
+	bad.Deltas = map[string]json.RawMessage{"style.fill": json.RawMessage(`"#000"`)}
+	if ack, nack, err := mover.SubmitAndWait(s.ctx, bad); err != nil || ack != nil || nack.Field != "deltas.style.fill" {
+		t.Errorf("non-numeric nudge answered %+v %+v %v, want a nack naming deltas.style.fill", ack, nack, err)
+	}
+}
+
+// dragOp moves objectID from previous to transform as seen at baseSeq.
+func dragOp(objectID string, baseSeq int64, previous, transform string) collab.Operation {
+	return collab.Operation{
+		ID:        newOpID(),
+		Type:      "object.transform",
+		ObjectID:  objectID,
+		BaseSeq:   &baseSeq,
+		Previous:  json.RawMessage(previous),
+		Transform: json.RawMessage(transform),
+	}
+}
+
+func TestConcurrentDragsRebase(t *testing.T) {
+	s := newSession(t)
+	alice, base := s.join("alice")
+	bob, _ := s.join("bob")
+
+	// Both drag the rect's x from 0 at the same sequence; the later one is
+	// rebased so neither drag is lost
+	ack, nack, err := alice.SubmitAndWait(s.ctx, dragOp(s.rectID, base, `{"x":0}`, `{"x":10}`))
+	if err != nil || ack == nil {
+		t.Fatalf("alice's drag not acked: %v %+v", err, nack)
+	}
+	ack, nack, err = bob.SubmitAndWait(s.ctx, dragOp(s.rectID, base, `{"x":0}`, `{"x":-4}`))
+	if err != nil || ack == nil {
+		t.Fatalf("bob's drag not acked: %v %+v", err, nack)
+	}
+	if string(ack.Transform) != `{"x":6}` {
+		t.Errorf("bob's ack transform %s, want the rebased {\"x\":6}", ack.Transform)
+	}
+	if x := s.converge(alice, bob).Objects[s.rectID].Transform.X; x != 6 {
+		t.Errorf("x = %v, want both drags applied", x)
+	}
+
+	// Once the rect is deleted a drag from before can't be rebased
+	if ack, nack, err := alice.SubmitAndWait(s.ctx, deleteOp(s.rectID)); err != nil || ack == nil {
+		t.Fatalf("delete not acked: %v %+v", err, nack)
+	}
+	ack, nack, err = bob.SubmitAndWait(s.ctx, dragOp(s.rectID, base, `{"x":0}`, `{"x":1}`))
+	if err != nil || ack != nil || nack.Reason != "conflict" || nack.Conflict == nil || nack.Conflict.Type != "object.delete" {
+		t.Errorf("drag of a deleted rect answered %+v %+v %v, want a conflict with the delete", ack, nack, err)
+	}
+}
+
+func TestRequestSyncResends(t *testing.T) {
+	s := newSession(t)
+	stale, syncedAt := s.join("stale")
+	editor, _ := s.join("editor")
+
+	// The editor's moves happen while stale isn't looking at them
+	var last *collab.OperationAckPayload
+	for n := range 3 {
+		ack, nack, err := editor.SubmitAndWait(s.ctx, moveOp(s.rectID, float64(100+n)))
+		if err != nil || ack == nil {
+			t.Fatalf("move %d not acked: %v %+v", n, err, nack)
+		}
+		last = ack
+	}
+
+	doc, seq, err := stale.RequestSync(s.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seq != last.ServerSeq || seq <= syncedAt {
+		t.Errorf("resynced at seq %d, want the latest %d", seq, last.ServerSeq)
+	}
+	if x := doc.Objects[s.rectID].Transform.X; x != 102 {
+		t.Errorf("resynced x = %v, want the editor's last move to 102", x)
+	}
+
+	// Asking again straight away is refused, and changes nothing
+	if err := stale.Send(s.ctx, collab.TypeDocRequestSync, struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := stale.Expect(s.ctx, collab.TypeError)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var refusal struct{ Code string }
+	if err := json.Unmarshal(msg.Payload, &refusal); err != nil || refusal.Code != "sync_rate_limited" {
+		t.Errorf("second request answered %s, want sync_rate_limited", msg.Payload)
+	}
+
+	// The connection and its presence survived: stale edits as before, and
+	// the editor never saw it leave
+	op := moveOp(s.rectID, 7)
+	if ack, nack, err := stale.SubmitAndWait(s.ctx, op); err != nil || ack == nil {
+		t.Fatalf("edit after resync: %v %+v", err, nack)
+	}
+	if _, err := editor.Broadcast(s.ctx, op.ID); err != nil {
+		t.Fatal(err)
+	}
+	quick, cancel := context.WithTimeout(s.ctx, 100*time.Millisecond)
+	defer cancel()
+	if _, err := editor.WaitFor(quick, func(msg *collab.Message) bool {
+		return msg.Type == collab.TypePresenceLeave && msg.UserID == "stale"
+	}); err == nil {
+		t.Error("resyncing client was seen leaving")
+	}
+}
+
+// paddedOp is a setMeta on objectID whose op.submit payload is exactly size
+// bytes.
+func paddedOp(t *testing.T, objectID string, size int) collab.Operation {
+	t.Helper()
+	op := collab.Operation{ID: newOpID(), Type: "object.setMeta", ObjectID: objectID}
+	op.Meta = json.RawMessage(`{"pad":""}`)
+	raw, err := json.Marshal(op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(raw) > size {
+		t.Fatalf("smallest op is %d bytes, over %d", len(raw), size)
+	}
+	op.Meta = json.RawMessage(`{"pad":"` + strings.Repeat("x", size-len(raw)) + `"}`)
+	if raw, _ := json.Marshal(op); len(raw) != size {
+		t.Fatalf("padded op is %d bytes, want %d", len(raw), size)
+	}
+	return op
+}
+
+func TestOversizedOps(t *testing.T) {
+	const readLimit, maxOpSize = 8 << 10, 4 << 10
+	s := newSession(t)
+	s.server.Hub.SetMessageLimits(readLimit, maxOpSize)
+	c, _ := s.join("sender")
+
+	if ack, nack, err := c.SubmitAndWait(s.ctx, paddedOp(t, s.rectID, maxOpSize)); err != nil || ack == nil {
+		t.Fatalf("op at the limit: %v %+v", err, nack)
+	}
+
+	// A byte over is nacked, and the connection stays up
+	op := paddedOp(t, s.rectID, maxOpSize+1)
+	ack, nack, err := c.SubmitAndWait(s.ctx, op)
+	if err != nil || ack != nil || nack.Reason != "payload_too_large" || nack.OperationID != op.ID {
+		t.Fatalf("op over the limit answered %+v %+v %v, want a payload_too_large nack", ack, nack, err)
+	}
+	if ack, nack, err := c.SubmitAndWait(s.ctx, moveOp(s.rectID, 5)); err != nil || ack == nil {
+		t.Fatalf("edit after an oversized op: %v %+v", err, nack)
+	}
+
+	// A message over the read limit still closes the connection
+	if err := c.Submit(s.ctx, paddedOp(t, s.rectID, readLimit+1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Expect(s.ctx, collab.TypeOpAck); !errors.Is(err, collabtest.ErrClosed) {
+		t.Errorf("message over the read limit: %v, want the connection closed", err)
+	}
+}
@@ -0,0 +1,83 @@
+package collab
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/color"
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+func TestStyleColorsNormalized(t *testing.T) {
+	ds, rectID := rectState(t)
+
+	op := &Operation{Type: "object.style", ObjectID: rectID, Style: json.RawMessage(`{"fill":"rgb(255 0 0 / 50%)","stroke":"HSL(240, 100%, 50%)","strokeWidth":3}`)}
+	apply(t, ds, op, "user")
+	style := ds.doc.Objects[rectID].Style
+	if style.Fill != "#ff000080" || style.Stroke != "#0000ffff" || style.StrokeWidth != 3 {
+		t.Errorf("style %+v, want canonical fill and stroke and the width kept", style)
+	}
+	// The logged and broadcast op carries the canonical values too
+	var sent struct{ Fill string }
+	if err := json.Unmarshal(op.Style, &sent); err != nil {
+		t.Fatal(err)
+	}
+	if sent.Fill != "#ff000080" {
+		t.Errorf("broadcast fill %q, want #ff000080", sent.Fill)
+	}
+
+	apply(t, ds, &Operation{Type: "object.style", ObjectID: rectID, Style: json.RawMessage(`{"stroke":"none"}`)}, "user")
+	if got := ds.doc.Objects[rectID].Style.Stroke; got != "none" {
+		t.Errorf("stroke %q, want none kept", got)
+	}
+}
+
+func TestInvalidColorRejected(t *testing.T) {
+	ds, rectID := rectState(t)
+	seq := ds.ServerSeq()
+
+	op := &Operation{ID: "op_bad", Type: "object.style", ObjectID: rectID, Style: json.RawMessage(`{"fill":"rgb(1, 2)"}`)}
+	result, applied := applySubmitted(ds, op, "user", OpPolicy{})
+	if applied || result.Nack == nil {
+		t.Fatalf("answered %+v, want a nack", result)
+	}
+	if _, err := ds.ApplyOperation(&Operation{ID: "op_bad2", Type: "object.style", ObjectID: rectID, Style: json.RawMessage(`{"stroke":"bogus"}`)}, "user"); !errors.Is(err, color.ErrInvalid) {
+		t.Errorf("err %v, want color.ErrInvalid", err)
+	}
+	if ds.ServerSeq() != seq || ds.doc.Objects[rectID].Style.Fill != "#ff0000" {
+		t.Error("rejected color changed the document")
+	}
+}
+
+func TestKeyframeColorsNormalized(t *testing.T) {
+	ds, rectID := rectState(t)
+	fill := addTrack(ds, rectID, "style.fill", nil)
+	x := addTrack(ds, rectID, "transform.x", nil)
+
+	keyID := typeid.NewKeyframeID()
+	apply(t, ds, &Operation{Type: "keyframe.add", TrackID: fill.ID,
+		Keyframe: json.RawMessage(`{"id":"` + keyID + `","frame":0,"value":"hsla(120, 100%, 50%, 0.5)","easing":"linear"}`)}, "user")
+	if got := string(ds.doc.Keyframes[keyID].Value); got != `"#00ff0080"` {
+		t.Errorf("added fill keyframe %s, want \"#00ff0080\"", got)
+	}
+
+	apply(t, ds, &Operation{Type: "keyframe.update", KeyframeID: keyID, Changes: json.RawMessage(`{"value":"#ABC"}`)}, "user")
+	if got := string(ds.doc.Keyframes[keyID].Value); got != `"#aabbccff"` {
+		t.Errorf("updated fill keyframe %s, want \"#aabbccff\"", got)
+	}
+
+	op := &Operation{ID: "op_bad", Type: "keyframe.update", TrackID: fill.ID, KeyframeID: keyID, Changes: json.RawMessage(`{"value":"bogus"}`)}
+	if _, err := ds.ApplyOperation(op, "user"); !errors.Is(err, color.ErrInvalid) {
+		t.Errorf("invalid keyframe color: %v, want color.ErrInvalid", err)
+	}
+
+	// Values on other properties are not colors
+	xKey := typeid.NewKeyframeID()
+	apply(t, ds, &Operation{Type: "keyframe.add", TrackID: x.ID,
+		Keyframe: json.RawMessage(`{"id":"` + xKey + `","frame":0,"value":12,"easing":"linear"}`)}, "user")
+	if got := ds.doc.Keyframes[xKey]; string(got.Value) != "12" || got.Easing != document.EasingLinear {
+		t.Errorf("x keyframe %s %s, want 12 linear", got.Value, got.Easing)
+	}
+}
@@ -0,0 +1,87 @@
+package collab
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func int64Ptr(n int64) *int64 { return &n }
+
+// TestStaleBaseTransformNacked checks a transform made against a base the
+// server has since moved past is nacked with the object's current transform.
+func TestStaleBaseTransformNacked(t *testing.T) {
+	ds, rectID := rectState(t)
+	base := ds.ServerSeq()
+	moveRect(t, ds, rectID, 3)
+
+	op := &Operation{
+		ID:        "op_stale",
+		Type:      "object.transform",
+		ObjectID:  rectID,
+		Transform: json.RawMessage(`{"x":50}`),
+		BaseSeq:   int64Ptr(base),
+	}
+	result, applied := applySubmitted(ds, op, "user", OpPolicy{})
+	if applied || result.Nack == nil {
+		t.Fatalf("stale op answered %+v, want a nack", result)
+	}
+	if result.Nack.Reason != "conflict" || result.Nack.Conflict == nil {
+		t.Fatalf("nack %+v, want a conflict", result.Nack)
+	}
+	conflict := result.Nack.Conflict
+	if conflict.ObjectID != rectID || conflict.BaseSeq == nil || *conflict.BaseSeq != ds.ServerSeq() {
+		t.Errorf("conflict %+v, want %s as of seq %d", conflict, rectID, ds.ServerSeq())
+	}
+	var current struct{ X float64 }
+	if err := json.Unmarshal(conflict.Transform, &current); err != nil || current.X != 3 {
+		t.Errorf("conflict transform %s, want the server's x of 3", conflict.Transform)
+	}
+	if x := ds.doc.Objects[rectID].Transform.X; x != 3 {
+		t.Errorf("x %v after a nacked op, want 3", x)
+	}
+}
+
+func TestBaseChecks(t *testing.T) {
+	tests := []struct {
+		name  string
+		op    func(ds *DocumentState, rectID string) *Operation
+		stale bool
+	}{
+		{"current base seq", func(ds *DocumentState, rectID string) *Operation {
+			return &Operation{Type: "object.transform", ObjectID: rectID, Transform: json.RawMessage(`{"x":9}`), BaseSeq: int64Ptr(ds.ServerSeq())}
+		}, false},
+		{"matching base value", func(ds *DocumentState, rectID string) *Operation {
+			return &Operation{Type: "object.transform", ObjectID: rectID, Transform: json.RawMessage(`{"x":9}`), BaseValue: json.RawMessage(`{"x":3}`)}
+		}, false},
+		{"stale base value", func(ds *DocumentState, rectID string) *Operation {
+			return &Operation{Type: "object.transform", ObjectID: rectID, Transform: json.RawMessage(`{"x":9}`), BaseValue: json.RawMessage(`{"x":1}`)}
+		}, true},
+		{"stale style", func(ds *DocumentState, rectID string) *Operation {
+			return &Operation{Type: "object.style", ObjectID: rectID, Style: json.RawMessage(`{"fill":"#00ff00"}`), BaseValue: json.RawMessage(`{"fill":"#0000ff"}`)}
+		}, true},
+		{"no base", func(ds *DocumentState, rectID string) *Operation {
+			return &Operation{Type: "object.transform", ObjectID: rectID, Transform: json.RawMessage(`{"x":9}`)}
+		}, false},
+		// Types without a comparable target ignore the base
+		{"unsupported type", func(ds *DocumentState, rectID string) *Operation {
+			return &Operation{Type: "object.visibility", ObjectIDs: []string{rectID}, Visible: boolPtr(false), BaseSeq: int64Ptr(0)}
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds, rectID := rectState(t)
+			moveRect(t, ds, rectID, 3)
+			op := tt.op(ds, rectID)
+			op.ID = "op_base"
+
+			result, _ := applySubmitted(ds, op, "user", OpPolicy{})
+			conflicted := result.Nack != nil && result.Nack.Reason == "conflict"
+			if conflicted != tt.stale {
+				t.Errorf("answered %+v %+v, want conflict %v", result.Ack, result.Nack, tt.stale)
+			}
+			if !tt.stale && result.Ack == nil {
+				t.Errorf("nacked: %+v", result.Nack)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package collab
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+)
+
+// DryRunResult is the outcome of one operation in a dry run: what a live
+// room would have acknowledged or rejected it with.
+type DryRunResult struct {
+	OperationID string `json:"operationId"`
+	Type        string `json:"type"`
+	OK          bool   `json:"ok"`
+	NoChange    bool   `json:"noChange,omitempty"` // Acknowledged, but the document was left as it was
+	Reason      string `json:"reason,omitempty"`   // Nack reason
+	Field       string `json:"field,omitempty"`    // Offending field when an ID or value failed validation
+
+	// Conflict is the server's current value when the op's base was stale
+	Conflict *Operation `json:"conflictingOp,omitempty"`
+
+	// ObjectIDs are the objects the operation created, and IDMap the IDs
+	// the server assigned, as in an op.ack
+	ObjectIDs []string                     `json:"objectIds,omitempty"`
+	IDMap     map[string]map[string]string `json:"idMap,omitempty"`
+}
+
+// DryRun applies ops in order to doc, which it takes ownership of, and
+// reports how a live room would have answered each one. Later operations see
+// the effects of earlier ones that succeeded, as they would in a session.
+// Nothing is recorded or broadcast; doc holds the trial result afterwards.
+func DryRun(doc *document.InDocument, ops []Operation, policy OpPolicy) []DryRunResult {
+	ds := NewDocumentState(doc)
+	results := make([]DryRunResult, len(ops))
+	for i := range ops {
+		op := &ops[i]
+		result := DryRunResult{OperationID: op.ID, Type: op.Type}
+		if !policy.Permits(op.Type) {
+			result.Reason = fmt.Sprintf("operation type %q is not permitted", op.Type)
+			results[i] = result
+			continue
+		}
+
+		before := objectIDSet(ds.doc)
+		_, err := ds.ApplyOperation(op, "")
+
+		var conflict *ConflictError
+		var invalidID *InvalidIDError
+		var invalidValue *InvalidValueError
+		switch {
+		case err == nil:
+			result.OK = true
+			result.ObjectIDs = createdObjects(ds.doc, before)
+			result.IDMap = op.IDMap
+		case errors.Is(err, ErrNoChange):
+			result.OK = true
+			result.NoChange = true
+		case errors.As(err, &conflict):
+			result.Reason = "conflict"
+			result.Conflict = &conflict.Current
+		case errors.As(err, &invalidID):
+			result.Reason = err.Error()
+			result.Field = invalidID.Field
+		case errors.As(err, &invalidValue):
+			result.Reason = err.Error()
+			result.Field = invalidValue.Field
+		default:
+			result.Reason = err.Error()
+		}
+		results[i] = result
+	}
+	return results
+}
+
+func objectIDSet(doc *document.InDocument) map[string]bool {
+	ids := make(map[string]bool, len(doc.Objects))
+	for id := range doc.Objects {
+		ids[id] = true
+	}
+	return ids
+}
+
+// createdObjects returns the sorted IDs of objects in doc that aren't in
+// before.
+func createdObjects(doc *document.InDocument, before map[string]bool) []string {
+	var created []string
+	for id := range doc.Objects {
+		if !before[id] {
+			created = append(created, id)
+		}
+	}
+	sort.Strings(created)
+	return created
+}
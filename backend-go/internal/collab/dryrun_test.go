@@ -0,0 +1,75 @@
+package collab
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+func TestDryRun(t *testing.T) {
+	ds, rectID := rectState(t)
+	rootID := *ds.doc.Objects[rectID].Parent
+	live, _, err := ds.SnapshotDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	createdID := typeid.NewObjectID()
+	created, _ := json.Marshal(document.ObjectNode{
+		ID: createdID, Type: document.ObjectTypeShapeEllipse, Parent: &rootID, Children: []string{},
+		Transform: document.Transform{SX: 1, SY: 1}, Visible: true, Data: json.RawMessage(`{"rx":5,"ry":5}`),
+	})
+	ops := []Operation{
+		{ID: "op_create", Type: "object.create", Object: created, ParentID: rootID},
+		// Sees the object the first op created
+		{ID: "op_move", Type: "object.transform", ObjectID: createdID, Transform: json.RawMessage(`{"x":5}`)},
+		{ID: "op_missing", Type: "object.transform", ObjectID: typeid.NewObjectID(), Transform: json.RawMessage(`{"x":5}`)},
+		{ID: "op_stale", Type: "object.transform", ObjectID: rectID, Transform: json.RawMessage(`{"x":5}`), BaseValue: json.RawMessage(`{"x":99}`)},
+		{ID: "op_same", Type: "object.visibility", ObjectIDs: []string{rectID}, Visible: boolPtr(true)},
+		{ID: "op_bad_id", Type: "object.transform", ObjectID: "rect", Transform: json.RawMessage(`{"x":5}`)},
+		{ID: "op_denied", Type: "project.rename", Name: "Renamed"},
+	}
+	results := DryRun(live, ops, OpPolicy{Deny: []string{"project.*"}})
+	if len(results) != len(ops) {
+		t.Fatalf("%d results for %d ops", len(results), len(ops))
+	}
+
+	want := []struct {
+		ok, noChange bool
+		reason       string
+		field        string
+	}{
+		{ok: true},
+		{ok: true},
+		{},
+		{reason: "conflict"},
+		{ok: true, noChange: true},
+		{field: "objectId"},
+		{reason: `operation type "project.rename" is not permitted`},
+	}
+	for i, w := range want {
+		r := results[i]
+		if r.OperationID != ops[i].ID || r.Type != ops[i].Type {
+			t.Errorf("result %d is for %s %s", i, r.OperationID, r.Type)
+		}
+		if r.OK != w.ok || r.NoChange != w.noChange || (w.reason != "" && r.Reason != w.reason) || r.Field != w.field {
+			t.Errorf("%s: %+v, want %+v", ops[i].ID, r, w)
+		}
+		if !r.OK && r.Reason == "" {
+			t.Errorf("%s rejected without a reason", ops[i].ID)
+		}
+	}
+	if ids := results[0].ObjectIDs; len(ids) != 1 || ids[0] != createdID {
+		t.Errorf("create reported objects %v, want [%s]", ids, createdID)
+	}
+	if results[3].Conflict == nil || string(results[3].Conflict.Transform) == "" {
+		t.Errorf("conflict %+v, want the current transform", results[3].Conflict)
+	}
+
+	// The room's own document and log are untouched
+	if _, ok := ds.doc.Objects[createdID]; ok || ds.ServerSeq() != 0 {
+		t.Errorf("dry run reached the live state: seq %d", ds.ServerSeq())
+	}
+}
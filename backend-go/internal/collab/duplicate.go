@@ -0,0 +1,193 @@
+package collab
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// DuplicateSnapshot is everything an object.duplicate creates: the copied
+// subtree and, per timeline, the copies of the tracks animating it.
+type DuplicateSnapshot struct {
+	Objects   []document.ObjectNode        `json:"objects"`             // The copied root first, then its descendants
+	Animation map[string]AnimationSnapshot `json:"animation,omitempty"` // Timeline ID → copied tracks and keyframes
+}
+
+// prepareDuplicateLocked assigns IDs for the copies of an object, its
+// descendants and the tracks and keyframes animating them (keeping any the
+// client chose), resolves where the copy goes, and builds the copies.
+func (ds *DocumentState) prepareDuplicateLocked(op *Operation) error {
+	source, ok := ds.doc.Objects[op.ObjectID]
+	if !ok {
+		return fmt.Errorf("object not found: %s", op.ObjectID)
+	}
+	if source.Parent == nil {
+		return fmt.Errorf("object has no parent: %s", op.ObjectID)
+	}
+
+	subtree := ds.subtreeLocked(op.ObjectID)
+	inSubtree := make(map[string]bool, len(subtree))
+	for _, id := range subtree {
+		inSubtree[id] = true
+	}
+
+	if op.ParentID == "" {
+		op.ParentID = *source.Parent
+	}
+	parent, ok := ds.doc.Objects[op.ParentID]
+	if !ok {
+		return &InvalidValueError{Field: "parentId", Reason: "parent does not exist"}
+	}
+	if inSubtree[op.ParentID] {
+		return &InvalidValueError{Field: "parentId", Reason: "would make the copy its own descendant"}
+	}
+
+	// Tracks animating the subtree, timeline by timeline in a stable order
+	timelineIDs := make([]string, 0, len(ds.doc.Timelines))
+	for id := range ds.doc.Timelines {
+		timelineIDs = append(timelineIDs, id)
+	}
+	slices.Sort(timelineIDs)
+	sources := make(map[string][]document.Track)
+	for _, tlID := range timelineIDs {
+		for _, trackID := range ds.doc.Timelines[tlID].Tracks {
+			if track, ok := ds.doc.Tracks[trackID]; ok && inSubtree[track.ObjectID] {
+				sources[tlID] = append(sources[tlID], track)
+			}
+		}
+	}
+
+	ids := op.IDMap[op.ObjectID]
+	if ids == nil {
+		ids = make(map[string]string, len(subtree))
+	}
+	for _, id := range subtree {
+		if ids[id] == "" {
+			ids[id] = typeid.NewObjectID()
+		}
+	}
+	for _, tracks := range sources {
+		for _, track := range tracks {
+			if ids[track.ID] == "" {
+				ids[track.ID] = typeid.NewTrackID()
+			}
+			for _, keyID := range track.Keys {
+				if ids[keyID] == "" {
+					ids[keyID] = typeid.NewKeyframeID()
+				}
+			}
+		}
+	}
+	used := make(map[string]bool, len(ids))
+	for _, newID := range ids {
+		_, objectExists := ds.doc.Objects[newID]
+		_, trackExists := ds.doc.Tracks[newID]
+		_, keyExists := ds.doc.Keyframes[newID]
+		if objectExists || trackExists || keyExists || used[newID] {
+			return fmt.Errorf("id already in use: %s", newID)
+		}
+		used[newID] = true
+	}
+	op.IDMap = map[string]map[string]string{op.ObjectID: ids}
+
+	// The copy lands right after the original unless told otherwise
+	index := len(parent.Children)
+	if op.Index != nil {
+		index = max(0, min(*op.Index, len(parent.Children)))
+	} else if op.ParentID == *source.Parent {
+		if i := slices.Index(parent.Children, op.ObjectID); i >= 0 {
+			index = i + 1
+		}
+	}
+	op.Index = &index
+
+	dup := &DuplicateSnapshot{Objects: make([]document.ObjectNode, 0, len(subtree))}
+	for _, id := range subtree {
+		obj := ds.doc.Objects[id]
+		obj.ID = ids[id]
+		newParent := op.ParentID
+		if id != op.ObjectID {
+			newParent = ids[*obj.Parent]
+		}
+		obj.Parent = &newParent
+		obj.Children = make([]string, len(obj.Children))
+		for i, childID := range ds.doc.Objects[id].Children {
+			obj.Children[i] = ids[childID]
+		}
+		dup.Objects = append(dup.Objects, obj)
+	}
+	for _, tlID := range timelineIDs {
+		tracks := sources[tlID]
+		if len(tracks) == 0 {
+			continue
+		}
+		snap := AnimationSnapshot{Tracks: []document.Track{}, Keyframes: []document.Keyframe{}}
+		for _, track := range tracks {
+			copied := track
+			copied.ID = ids[track.ID]
+			copied.ObjectID = ids[track.ObjectID]
+			copied.Keys = make([]string, 0, len(track.Keys))
+			for _, keyID := range track.Keys {
+				kf, ok := ds.doc.Keyframes[keyID]
+				if !ok {
+					continue
+				}
+				kf.ID = ids[keyID]
+				copied.Keys = append(copied.Keys, kf.ID)
+				snap.Keyframes = append(snap.Keyframes, kf)
+			}
+			if !slices.Contains(snap.ObjectIDs, copied.ObjectID) {
+				snap.ObjectIDs = append(snap.ObjectIDs, copied.ObjectID)
+			}
+			snap.Tracks = append(snap.Tracks, copied)
+		}
+		if dup.Animation == nil {
+			dup.Animation = make(map[string]AnimationSnapshot)
+		}
+		dup.Animation[tlID] = snap
+	}
+	op.Duplicate = dup
+	return nil
+}
+
+// applyDuplicate inserts the copies prepareDuplicateLocked built under
+// ParentID at Index and adds their tracks to the same timelines as the
+// originals'.
+func (ds *DocumentState) applyDuplicate(op Operation) error {
+	dup := op.Duplicate
+	if dup == nil || len(dup.Objects) == 0 {
+		return fmt.Errorf("duplicate is missing prepared objects")
+	}
+	parent, ok := ds.doc.Objects[op.ParentID]
+	if !ok {
+		return fmt.Errorf("parent not found: %s", op.ParentID)
+	}
+
+	for _, obj := range dup.Objects {
+		ds.doc.Objects[obj.ID] = obj
+	}
+	index := len(parent.Children)
+	if op.Index != nil {
+		index = max(0, min(*op.Index, len(parent.Children)))
+	}
+	parent.Children = slices.Insert(slices.Clone(parent.Children), index, dup.Objects[0].ID)
+	ds.doc.Objects[op.ParentID] = parent
+
+	for tlID, snap := range dup.Animation {
+		timeline, ok := ds.doc.Timelines[tlID]
+		if !ok {
+			continue
+		}
+		for _, track := range snap.Tracks {
+			ds.doc.Tracks[track.ID] = track
+			timeline.Tracks = append(timeline.Tracks, track.ID)
+		}
+		for _, kf := range snap.Keyframes {
+			ds.doc.Keyframes[kf.ID] = kf
+		}
+		ds.doc.Timelines[tlID] = timeline
+	}
+	return nil
+}
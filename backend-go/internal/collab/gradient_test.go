@@ -0,0 +1,68 @@
+package collab
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/engine"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+func TestGradientStyleRoundTrip(t *testing.T) {
+	ds, rectID := rectState(t)
+	apply(t, ds, &Operation{Type: "object.style", ObjectID: rectID, Style: json.RawMessage(
+		`{"fillGradient":{"type":"linear","x2":40,"stops":[{"offset":0,"color":"#f00"},{"offset":1,"color":"rgb(0 0 255 / 50%)"}]}}`)}, "user")
+
+	// Stop colors are normalized and the solid fill stays as the fallback
+	want := &document.Gradient{Type: document.GradientLinear, X2: 40, Stops: []document.GradientStop{
+		{Offset: 0, Color: "#ff0000ff"},
+		{Offset: 1, Color: "#0000ff80"},
+	}}
+	style := ds.doc.Objects[rectID].Style
+	if !reflect.DeepEqual(style.FillGradient, want) || style.Fill != "#ff0000" {
+		t.Fatalf("style %+v with gradient %+v, want %+v over fill #ff0000", style, style.FillGradient, want)
+	}
+
+	// The gradient survives saving and loading
+	data, err := json.Marshal(ds.doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded document.InDocument
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded.Objects[rectID].Style.FillGradient; !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded gradient %+v, want %+v", got, want)
+	}
+
+	// And reaches the rect's draw command
+	sg := engine.BuildSceneGraph(&loaded, loaded.Project.Scenes[0], 0, loaded.Project.RootTimeline, false, nil)
+	commands := engine.CompileDrawCommands(sg)
+	i := slices.IndexFunc(commands, func(cmd engine.DrawCommand) bool { return cmd.ObjectID == rectID })
+	if i < 0 {
+		t.Fatal("no draw command for the rect")
+	}
+	cmd := commands[i]
+	paint := cmd.FillGradient
+	if paint == nil || paint.Type != "linear" || !slices.Equal(paint.Coords, []float64{0, 0, 40, 0}) || !slices.Equal(paint.Stops, want.Stops) {
+		t.Errorf("gradient paint %+v, want the linear gradient from (0, 0) to (40, 0)", paint)
+	}
+	if cmd.Fill != "#ff0000" {
+		t.Errorf("command fill %q, want the #ff0000 fallback", cmd.Fill)
+	}
+
+	// A malformed gradient is nacked, and null clears it back to the fill
+	bad := &Operation{ID: typeid.NewOpID(), Type: "object.style", ObjectID: rectID, Style: json.RawMessage(
+		`{"fillGradient":{"type":"linear","stops":[{"offset":0,"color":"#f00"}]}}`)}
+	if result, applied := applySubmitted(ds, bad, "user", OpPolicy{}); applied || result.Nack == nil || result.Nack.Field != "style.fillGradient" {
+		t.Errorf("one-stop gradient answered %+v, want a nack naming style.fillGradient", result)
+	}
+	apply(t, ds, &Operation{Type: "object.style", ObjectID: rectID, Style: json.RawMessage(`{"fillGradient":null}`)}, "user")
+	if got := ds.doc.Objects[rectID].Style.FillGradient; got != nil {
+		t.Errorf("gradient %+v after clearing, want none", got)
+	}
+}
@@ -1,80 +1,216 @@
+//go:build !(js && wasm)
+
 package collab
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/coder/websocket"
+
 	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+	"github.com/inamate/inamate/backend-go/internal/webhook"
 )
 
-type Room struct {
-	projectID string
-	clients   map[string]*Client // clientID -> client
-	presence  *PresenceManager
-	docState  *DocumentState // Authoritative document state
-}
+// PlaygroundProjectID is the shared project anonymous users edit.
+const PlaygroundProjectID = "proj_playground"
 
-func NewRoom(projectID string, initialDoc *document.InDocument) *Room {
-	return &Room{
-		projectID: projectID,
-		clients:   make(map[string]*Client),
-		presence:  NewPresenceManager(),
-		docState:  NewDocumentState(initialDoc),
-	}
+var (
+	ErrRoomNotFound = errors.New("room not found")
+
+	// ErrCorruptDocument wraps the integrity problem that stopped a room's
+	// document being saved.
+	ErrCorruptDocument = errors.New("document failed its integrity check")
+
+	errNoLoader = errors.New("no document loader configured")
+)
+
+// DocumentLoader loads a project's latest document and the server sequence it
+// was saved at
+type DocumentLoader func(ctx context.Context, projectID string) (*document.InDocument, int64, error)
+
+// DocumentSaver saves a project's document as it stood at server sequence
+// seq. The label says why a save was made outside the autosave cycle, such
+// as a safety snapshot (see BackupLabelPrefix); it is empty for routine saves.
+type DocumentSaver func(ctx context.Context, projectID string, doc *document.InDocument, seq int64, label string) error
+
+// OpStore persists the operations applied between snapshots. A room is
+// restored by loading its latest snapshot and replaying the ops after it.
+type OpStore interface {
+	Append(ctx context.Context, projectID string, ops []RecordedOperation) error
+	Since(ctx context.Context, projectID string, seq int64) ([]RecordedOperation, error)
+	Compact(ctx context.Context, projectID string, throughSeq int64) error
+	Discard(ctx context.Context, projectID string, afterSeq int64) error
 }
 
-// DocumentLoader loads a document for a project
-type DocumentLoader func(projectID string) (*document.InDocument, error)
+const (
+	defaultDocTimeout = 10 * time.Second
+
+	// Dirty rooms are saved this often, autosaveWorkers at a time. After a
+	// failed save a room waits twice as long for each consecutive failure,
+	// up to maxSaveBackoff.
+	defaultAutosaveInterval = 30 * time.Second
+	autosaveWorkers         = 4
+	maxSaveBackoff          = 10 * time.Minute
+
+	// Applied ops are written to the op store this often, or sooner once a
+	// room has opFlushBatch of them pending
+	opFlushInterval = 2 * time.Second
+	opFlushBatch    = 64
 
-// DocumentSaver saves a document for a project
-type DocumentSaver func(projectID string, doc *document.InDocument) error
+	// A WebSocket message larger than defaultReadLimit closes the connection.
+	// Below that, an op.submit or op.batch whose payload exceeds
+	// defaultMaxOpSize is nacked with reason "payload_too_large" and the
+	// connection kept.
+	defaultReadLimit = 16 << 20
+	defaultMaxOpSize = 4 << 20
+
+	// An op.batch may hold at most maxBatchOps operations
+	maxBatchOps = 1000
+)
 
 type Hub struct {
-	mu         sync.RWMutex
-	rooms      map[string]*Room // projectID -> room
-	register   chan *Client
-	unregister chan *Client
-	loadDoc    DocumentLoader // Function to load documents
-	saveDoc    DocumentSaver  // Function to save documents
-	stopSaver  chan struct{}  // Signal to stop periodic saver
+	mu             sync.RWMutex     // Guards rooms, closing and each room's members
+	rooms          map[string]*Room // projectID -> live room
+	closing        map[string]*Room // projectID -> room saving on its way out
+	loadDoc        DocumentLoader   // Function to load documents
+	saveDoc        DocumentSaver    // Function to save documents
+	docTimeout     time.Duration    // Bound on each load/save call
+	autosave       time.Duration    // How often dirty rooms are saved
+	backupInterval time.Duration    // Least time between a room's safety snapshots
+	stopSaver      chan struct{}    // Signal to stop periodic saver and op flusher
+	opStore        OpStore          // Where applied ops are logged between saves
+	flushNow       chan struct{}    // Asks the op flusher to run early
+	webhooks       *webhook.Dispatcher
+	opPolicy       OpPolicy // Operation types this deployment accepts
+	readLimit      int64    // Largest WebSocket message read
+	maxOpSize      int64    // Largest op.submit payload applied
+
+	playgroundClosed bool // The deployment has turned the playground off
 }
 
 func NewHub(loadDoc DocumentLoader, saveDoc DocumentSaver) *Hub {
 	return &Hub{
-		rooms:      make(map[string]*Room),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
-		loadDoc:    loadDoc,
-		saveDoc:    saveDoc,
-		stopSaver:  make(chan struct{}),
+		rooms:          make(map[string]*Room),
+		closing:        make(map[string]*Room),
+		loadDoc:        loadDoc,
+		saveDoc:        saveDoc,
+		docTimeout:     defaultDocTimeout,
+		autosave:       defaultAutosaveInterval,
+		backupInterval: defaultBackupInterval,
+		readLimit:      defaultReadLimit,
+		maxOpSize:      defaultMaxOpSize,
+		stopSaver:      make(chan struct{}),
+		flushNow:       make(chan struct{}, 1),
 	}
 }
 
-func (h *Hub) Run() {
-	// Start periodic saver
-	go h.periodicSaver()
+// SetOpStore configures where applied operations are logged between saves.
+// It must be called before Run.
+func (h *Hub) SetOpStore(s OpStore) {
+	h.opStore = s
+}
 
-	for {
-		select {
-		case client := <-h.register:
-			h.addClient(client)
-		case client := <-h.unregister:
-			h.removeClient(client)
-		}
+// SetDocumentTimeout bounds how long a single document load or save may take.
+func (h *Hub) SetDocumentTimeout(d time.Duration) {
+	if d > 0 {
+		h.docTimeout = d
+	}
+}
+
+// SetAutosaveInterval sets how often rooms with unsaved changes are saved. It
+// must be called before Run.
+func (h *Hub) SetAutosaveInterval(d time.Duration) {
+	if d > 0 {
+		h.autosave = d
 	}
 }
 
-// Stop gracefully shuts down the hub, saving all dirty documents
+// SetMessageLimits sets the largest WebSocket message a client may send
+// before its connection is closed, and the largest op.submit payload applied
+// rather than nacked. The op limit is capped at the read limit. It must be
+// called before clients connect.
+func (h *Hub) SetMessageLimits(readLimit, maxOpSize int64) {
+	if readLimit > 0 {
+		h.readLimit = readLimit
+	}
+	if maxOpSize > 0 {
+		h.maxOpSize = maxOpSize
+	}
+	h.maxOpSize = min(h.maxOpSize, h.readLimit)
+}
+
+// SetWebhooks configures the dispatcher notified of document-level events.
+func (h *Hub) SetWebhooks(d *webhook.Dispatcher) {
+	h.webhooks = d
+}
+
+// SetOpPolicy restricts which operation types clients may submit.
+func (h *Hub) SetOpPolicy(p OpPolicy) {
+	h.opPolicy = p
+}
+
+// SetPlaygroundEnabled sets whether anonymous users may join the playground
+// room. It is enabled by default. It must be called before Run.
+func (h *Hub) SetPlaygroundEnabled(enabled bool) {
+	h.playgroundClosed = !enabled
+}
+
+// PlaygroundEnabled reports whether anonymous users may join the playground
+// room.
+func (h *Hub) PlaygroundEnabled() bool {
+	return !h.playgroundClosed
+}
+
+// OpPolicy returns the operation types this hub accepts.
+func (h *Hub) OpPolicy() OpPolicy {
+	return h.opPolicy
+}
+
+// Run saves dirty rooms every autosave interval, and logs their operations,
+// until Stop.
+func (h *Hub) Run() {
+	if h.opStore != nil {
+		go h.opFlusher()
+	}
+	h.periodicSaver()
+}
+
+// Stop gracefully shuts down the hub: it tells every room to finish the work
+// it has queued and save, and waits for them all.
 func (h *Hub) Stop() {
 	close(h.stopSaver)
-	h.saveAllDirtyRooms()
+
+	h.mu.Lock()
+	rooms := make([]*Room, 0, len(h.rooms)+len(h.closing))
+	for projectID, room := range h.rooms {
+		delete(h.rooms, projectID)
+		h.closing[projectID] = room
+	}
+	for _, room := range h.closing {
+		rooms = append(rooms, room)
+	}
+	h.mu.Unlock()
+
+	for _, room := range rooms {
+		room.shutdown()
+	}
+	for _, room := range rooms {
+		<-room.done
+	}
 }
 
-// periodicSaver saves dirty documents every 30 seconds
+// periodicSaver saves dirty documents every autosave interval
 func (h *Hub) periodicSaver() {
-	ticker := time.NewTicker(30 * time.Second)
+	ticker := time.NewTicker(h.autosave)
 	defer ticker.Stop()
 
 	for {
@@ -87,93 +223,453 @@ func (h *Hub) periodicSaver() {
 	}
 }
 
-// saveAllDirtyRooms saves all rooms with unsaved changes
+// saveAllDirtyRooms saves all rooms with unsaved changes, autosaveWorkers at
+// a time. Rooms backing off after a failed save are skipped.
 func (h *Hub) saveAllDirtyRooms() {
+	now := time.Now()
 	h.mu.RLock()
 	roomsToSave := make(map[string]*Room)
 	for projectID, room := range h.rooms {
-		if room.docState.IsDirty() {
+		if room.docState.IsDirty() && room.saveDue(now) {
 			roomsToSave[projectID] = room
 		}
 	}
 	h.mu.RUnlock()
 
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, autosaveWorkers)
 	for projectID, room := range roomsToSave {
-		h.saveRoom(projectID, room)
+		workers <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-workers }()
+			h.saveRoom(projectID, room, "")
+		}()
 	}
+	wg.Wait()
+}
+
+// saveDue reports whether autosave may try the room at now.
+func (r *Room) saveDue(now time.Time) bool {
+	r.saveMu.Lock()
+	defer r.saveMu.Unlock()
+	return !now.Before(r.retryAt)
 }
 
-// saveRoom saves a single room's document
-func (h *Hub) saveRoom(projectID string, room *Room) {
+// saveRoom saves a single room's document with the given snapshot label, then
+// compacts away the logged ops the new snapshot covers. A document failing
+// its integrity check (see document.InDocument.CheckIntegrity) is not saved,
+// so the last good snapshot stays the one loaded, with the logged ops
+// replaying on top of it; non-finite numbers already fail to encode. A failed
+// save leaves the room dirty and backs off its autosave.
+func (h *Hub) saveRoom(projectID string, room *Room, label string) error {
 	if h.saveDoc == nil {
 		slog.Warn("no document saver configured, skipping save", "project", projectID)
-		return
+		return errors.New("no document saver configured")
+	}
+
+	room.saveMu.Lock()
+	defer room.saveMu.Unlock()
+
+	doc, seq, err := room.docState.SnapshotDocument()
+	if err != nil {
+		slog.Error("failed to copy document", "project", projectID, "error", err)
+		return err
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), h.docTimeout)
+	defer cancel()
+	if err := doc.CheckIntegrity(); err != nil {
+		backoff := room.backOffSave(h.autosave)
+		slog.Error("refusing to save corrupt document; keeping the last good snapshot",
+			"project", projectID, "seq", seq, "label", label, "failures", room.saveFailures, "retryIn", backoff, "error", err)
+		return fmt.Errorf("%w: %v", ErrCorruptDocument, err)
+	}
+	if err := h.saveDoc(ctx, projectID, doc, seq, label); err != nil {
+		backoff := room.backOffSave(h.autosave)
+		slog.Error("failed to save document", "project", projectID, "failures", room.saveFailures, "retryIn", backoff, "error", err)
+		return err
+	}
+
+	room.docState.MarkSaved(seq)
+	if room.saveFailures > 0 {
+		slog.Info("document saved after failures", "project", projectID, "failures", room.saveFailures)
+	}
+	room.saveFailures = 0
+	room.retryAt = time.Time{}
+	slog.Info("document saved", "project", projectID, "seq", seq, "label", label)
+
+	if h.opStore != nil {
+		// A failed compaction only leaves ops that replay skips
+		if err := h.opStore.Compact(ctx, projectID, seq); err != nil {
+			slog.Warn("failed to compact op log", "project", projectID, "error", err)
+		}
+	}
+	return nil
+}
+
+// backOffSave counts a failed save and delays the room's next autosave,
+// twice as long for each consecutive failure, returning the delay. The caller
+// holds saveMu.
+func (r *Room) backOffSave(interval time.Duration) time.Duration {
+	r.saveFailures++
+	backoff := maxSaveBackoff
+	if r.saveFailures < 16 {
+		backoff = min(maxSaveBackoff, interval<<r.saveFailures)
+	}
+	r.retryAt = time.Now().Add(backoff)
+	return backoff
+}
 
-	doc := room.docState.GetDocument()
-	if err := h.saveDoc(projectID, doc); err != nil {
-		slog.Error("failed to save document", "project", projectID, "error", err)
+// opFlusher writes applied operations to the op store every opFlushInterval,
+// or early when a room's backlog reaches opFlushBatch
+func (h *Hub) opFlusher() {
+	ticker := time.NewTicker(opFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-h.flushNow:
+		case <-h.stopSaver:
+			return
+		}
+		h.flushAllRooms()
+	}
+}
+
+// flushAllRooms writes every room's pending operations to the op store
+func (h *Hub) flushAllRooms() {
+	if h.opStore == nil {
 		return
 	}
 
-	room.docState.MarkClean()
-	slog.Info("document saved", "project", projectID)
+	h.mu.RLock()
+	rooms := make([]*Room, 0, len(h.rooms))
+	for _, room := range h.rooms {
+		if room.docState.unpersistedCount() > 0 {
+			rooms = append(rooms, room)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, room := range rooms {
+		h.flushRoom(room)
+	}
 }
 
+// flushRoom writes a room's pending operations to the op store. Ops that
+// fail to write stay pending and are retried on the next flush.
+func (h *Hub) flushRoom(room *Room) error {
+	if h.opStore == nil {
+		return nil
+	}
+	ops := room.docState.UnpersistedOps()
+	if len(ops) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.docTimeout)
+	defer cancel()
+	if err := h.opStore.Append(ctx, room.projectID, ops); err != nil {
+		slog.Error("failed to persist ops", "project", room.projectID, "count", len(ops), "error", err)
+		return err
+	}
+	room.docState.MarkPersisted(ops[len(ops)-1].Seq)
+	return nil
+}
+
+// RoomStats describes a live room for operators.
+type RoomStats struct {
+	ProjectID string   `json:"projectId"`
+	Clients   int      `json:"clients"`
+	UserIDs   []string `json:"userIds"`
+	ServerSeq int64    `json:"serverSeq"`
+	Dirty     bool     `json:"dirty"`
+}
+
+// HubStats is a snapshot of all live rooms.
+type HubStats struct {
+	Rooms   []RoomStats `json:"rooms"`
+	Clients int         `json:"clients"`
+}
+
+// Stats returns a snapshot of the live rooms and their connected clients.
+func (h *Hub) Stats() HubStats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	stats := HubStats{Rooms: make([]RoomStats, 0, len(h.rooms))}
+	for projectID, room := range h.rooms {
+		rs := RoomStats{
+			ProjectID: projectID,
+			ServerSeq: room.docState.ServerSeq(),
+			Dirty:     room.docState.IsDirty(),
+		}
+		room.mu.RLock()
+		rs.Clients = len(room.clients)
+		rs.UserIDs = make([]string, 0, len(room.clients))
+		for _, c := range room.clients {
+			rs.UserIDs = append(rs.UserIDs, c.UserID)
+		}
+		room.mu.RUnlock()
+		stats.Clients += rs.Clients
+		stats.Rooms = append(stats.Rooms, rs)
+	}
+	return stats
+}
+
+// Recording returns a span of a live room's history (see
+// DocumentState.Recording). Only the current session is recorded.
+func (h *Hub) Recording(projectID string, fromSeq, toSeq int64) (*Recording, error) {
+	h.mu.RLock()
+	room, ok := h.rooms[projectID]
+	h.mu.RUnlock()
+	if !ok {
+		return nil, ErrRoomNotFound
+	}
+	return room.docState.Recording(fromSeq, toSeq)
+}
+
+// Document returns a copy of a live room's current document.
+func (h *Hub) Document(projectID string) (*document.InDocument, error) {
+	h.mu.RLock()
+	room, ok := h.rooms[projectID]
+	h.mu.RUnlock()
+	if !ok {
+		return nil, ErrRoomNotFound
+	}
+	return room.docState.CopyDocument()
+}
+
+// ForceSave saves a live room's document immediately, even if it is clean.
+func (h *Hub) ForceSave(projectID string) error {
+	h.mu.RLock()
+	room, ok := h.rooms[projectID]
+	h.mu.RUnlock()
+	if !ok {
+		return ErrRoomNotFound
+	}
+	return h.saveRoom(projectID, room, "")
+}
+
+// EvictRoom saves a live room if it has unsaved changes and disconnects all of
+// its clients. The room is torn down as the clients unregister.
+func (h *Hub) EvictRoom(projectID string) error {
+	h.mu.RLock()
+	room, ok := h.rooms[projectID]
+	h.mu.RUnlock()
+	if !ok {
+		return ErrRoomNotFound
+	}
+	room.mu.RLock()
+	clients := make([]*Client, 0, len(room.clients))
+	for _, c := range room.clients {
+		clients = append(clients, c)
+	}
+	room.mu.RUnlock()
+
+	if room.docState.IsDirty() {
+		if err := h.saveRoom(projectID, room, ""); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range clients {
+		go c.conn.Close(websocket.StatusGoingAway, "room evicted")
+	}
+	slog.Info("room evicted", "project", projectID, "clients", len(clients))
+	return nil
+}
+
+// DisconnectUser closes every connection held by a user across all rooms and
+// returns how many were closed.
+func (h *Hub) DisconnectUser(userID string) int {
+	clients := h.userClients(userID, nil)
+	for _, c := range clients {
+		go c.conn.Close(websocket.StatusPolicyViolation, "account deactivated")
+	}
+	return len(clients)
+}
+
+// SendToUser sends msg to every connection a user holds, in whichever rooms,
+// and returns how many it was sent to.
+func (h *Hub) SendToUser(userID string, msg *Message) int {
+	return len(h.userClients(userID, msg))
+}
+
+// userClients returns every connection a user holds, in whichever rooms,
+// sending each msg if it isn't nil. Sending while the room's clients are
+// locked means no client leaves, closing its send buffer, meanwhile.
+func (h *Hub) userClients(userID string, msg *Message) []*Client {
+	h.mu.RLock()
+	rooms := make([]*Room, 0, len(h.rooms))
+	for _, room := range h.rooms {
+		rooms = append(rooms, room)
+	}
+	h.mu.RUnlock()
+
+	var clients []*Client
+	for _, room := range rooms {
+		room.mu.RLock()
+		for _, c := range room.clients {
+			if c.UserID == userID {
+				clients = append(clients, c)
+				if msg != nil {
+					c.Send(msg)
+				}
+			}
+		}
+		room.mu.RUnlock()
+	}
+	return clients
+}
+
+// Register adds a client to its project's room, creating the room if needed.
+// It runs on the caller's goroutine so a slow document load never blocks the
+// hub or other rooms, and it returns before the client's read pump starts, so
+// the client cannot be unregistered before it is added.
 func (h *Hub) Register(client *Client) {
-	h.register <- client
+	h.addClient(client)
+}
+
+// loadRoomDocument loads the document state for a new room: the latest
+// snapshot with the logged operations after it replayed. The playground gets
+// a fresh document when none has been saved yet.
+func (h *Hub) loadRoomDocument(projectID string) (*DocumentState, error) {
+	if h.loadDoc == nil {
+		return nil, errNoLoader
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.docTimeout)
+	defer cancel()
+	doc, seq, err := h.loadDoc(ctx, projectID)
+	if err != nil && projectID == PlaygroundProjectID && h.PlaygroundEnabled() && !errors.Is(err, context.DeadlineExceeded) {
+		slog.Info("creating fresh playground document", "project", projectID)
+		doc, seq, err = document.NewEmptyDocument(
+			projectID,
+			"Playground",
+			typeid.NewSceneID(),
+			typeid.NewObjectID(),
+			typeid.NewTimelineID(),
+		), 0, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Kept as they are and drawn as placeholders, but worth knowing about
+	if unknown := doc.UnknownObjects(); len(unknown) > 0 {
+		slog.Warn("document has objects of unknown types", "project", projectID, "version", doc.Project.Version, "objects", len(unknown), "first_type", unknown[0].Type)
+	}
+
+	ds := NewDocumentStateAt(doc, seq)
+	if err := h.replayOps(ctx, projectID, ds); err != nil {
+		return nil, err
+	}
+	return ds, nil
+}
+
+// replayOps applies the operations logged after a room's snapshot. If the
+// log can't be replayed in full, the rest is discarded so the room can reuse
+// those sequences, and the room is saved as far as replay got.
+func (h *Hub) replayOps(ctx context.Context, projectID string, ds *DocumentState) error {
+	if h.opStore == nil {
+		return nil
+	}
+
+	from := ds.ServerSeq()
+	ops, err := h.opStore.Since(ctx, projectID, from)
+	if err != nil {
+		return fmt.Errorf("load op log: %w", err)
+	}
+	if len(ops) == 0 {
+		return nil
+	}
+
+	reached, err := ds.Replay(ops)
+	if err != nil {
+		slog.Warn("op log replay stopped early", "project", projectID, "seq", reached, "error", err)
+		if err := h.opStore.Discard(ctx, projectID, reached); err != nil {
+			return fmt.Errorf("discard unreplayable ops: %w", err)
+		}
+	}
+	slog.Info("op log replayed", "project", projectID, "from", from, "to", reached)
+	return nil
 }
 
 func (h *Hub) addClient(client *Client) {
-	h.mu.Lock()
-	room, ok := h.rooms[client.ProjectID]
-	if !ok {
-		// Load document for new room
-		if h.loadDoc == nil {
-			slog.Error("no document loader configured", "project", client.ProjectID)
+	room, err := h.attach(client)
+	if err != nil {
+		slog.Error("failed to load document", "project", client.ProjectID, "error", err)
+		client.Send(loadErrorMessage(err))
+		return
+	}
+	client.room = room
+	if !room.do(func() { h.join(room, client) }) {
+		slog.Warn("client joined a room that is shutting down", "user", client.UserID, "project", client.ProjectID)
+	}
+}
+
+// attach registers client as a member of its project's room, loading the
+// room if none is live, and returns it. A room stays live while it has
+// members. If the project's last room is still saving on its way out, that
+// save is waited for so the new room loads it.
+func (h *Hub) attach(client *Client) (*Room, error) {
+	projectID := client.ProjectID
+	for {
+		h.mu.Lock()
+		if room, ok := h.rooms[projectID]; ok {
+			room.members++
 			h.mu.Unlock()
-			// Send error to client
-			errPayload, _ := json.Marshal(map[string]string{
-				"code":    "no_loader",
-				"message": "Document loader not configured",
-			})
-			client.Send(&Message{Type: TypeError, Payload: errPayload})
-			return
+			return room, nil
+		}
+		closing := h.closing[projectID]
+		h.mu.Unlock()
+		if closing != nil {
+			<-closing.done
+			continue
 		}
-		doc, err := h.loadDoc(client.ProjectID)
+
+		// Load without holding the lock. Two clients joining a cold room at
+		// once may both load; the first to insert wins and the other's copy
+		// is discarded.
+		docState, err := h.loadRoomDocument(projectID)
 		if err != nil {
-			// For the playground project, create a fresh empty document instead of erroring
-			if client.ProjectID == "proj_playground" {
-				slog.Info("creating fresh playground document", "project", client.ProjectID)
-				doc = document.NewEmptyDocument(
-					client.ProjectID,
-					"Playground",
-					"scene_playground",
-					"root_playground",
-					"timeline_playground",
-				)
-			} else {
-				slog.Error("failed to load document", "project", client.ProjectID, "error", err)
+			return nil, err
+		}
+
+		h.mu.Lock()
+		room, ok := h.rooms[projectID]
+		if !ok {
+			if h.closing[projectID] != nil {
+				// A room came and went while this one loaded; what it saved
+				// is newer
 				h.mu.Unlock()
-				// Send error to client
-				errPayload, _ := json.Marshal(map[string]string{
-					"code":    "load_failed",
-					"message": "Failed to load project. The project may not exist or has no document.",
-				})
-				client.Send(&Message{Type: TypeError, Payload: errPayload})
-				return
+				continue
 			}
+			room = NewRoom(projectID, docState)
+			h.rooms[projectID] = room
+			go h.runRoom(room)
 		}
-		room = NewRoom(client.ProjectID, doc)
-		h.rooms[client.ProjectID] = room
+		room.members++
+		h.mu.Unlock()
+		return room, nil
 	}
+}
+
+// join adds a client to the room it is attached to and brings it up to
+// date. It runs on the room's goroutine.
+func (h *Hub) join(room *Room, client *Client) {
+	room.mu.Lock()
 	room.clients[client.ClientID] = client
-	h.mu.Unlock()
+	room.mu.Unlock()
 
 	// Send welcome message with user's identity
 	welcomePayload, _ := json.Marshal(map[string]string{
 		"userId":      client.UserID,
 		"displayName": client.DisplayName,
+		"role":        client.Role,
 	})
 	welcomeMsg := &Message{
 		Type:    TypeWelcome,
@@ -181,13 +677,13 @@ func (h *Hub) addClient(client *Client) {
 	}
 	client.Send(welcomeMsg)
 
-	// Send current document state to new client
-	docPayload, _ := json.Marshal(room.docState.GetDocument())
-	docMsg := &Message{
-		Type:    TypeDocSync,
-		Payload: docPayload,
+	// Send current document state to new client, or only what it missed if
+	// it is reconnecting
+	if client.ResumeSeq > 0 {
+		client.Send(resyncMessage(room, client.ResumeSeq))
+	} else {
+		client.Send(docSyncMessage(room))
 	}
-	client.Send(docMsg)
 
 	// Send current presence state to new client
 	stateMsg := room.presence.StateMessage()
@@ -205,34 +701,111 @@ func (h *Hub) addClient(client *Client) {
 		UserID:  client.UserID,
 		Payload: joinPayload,
 	}
-	h.broadcastToRoom(client.ProjectID, joinMsg, client.ClientID)
+	room.broadcast(joinMsg, client.ClientID)
 
 	slog.Info("client joined", "user", client.UserID, "project", client.ProjectID)
 }
 
-func (h *Hub) removeClient(client *Client) {
-	h.mu.Lock()
-	room, ok := h.rooms[client.ProjectID]
+// docSyncMessage carries the room's current document and the server sequence
+// it is at.
+func docSyncMessage(room *Room) *Message {
+	payload, seq, err := room.docState.EncodeDocument()
+	if err != nil {
+		slog.Error("failed to encode document", "project", room.projectID, "error", err)
+	}
+	return &Message{
+		Type:    TypeDocSync,
+		Seq:     seq,
+		Payload: payload,
+	}
+}
+
+// maxResyncOps bounds how many missed operations a resync sends; a client
+// further behind is sent the whole document instead.
+const maxResyncOps = 1000
+
+// resyncMessage brings a client that last saw lastSeq up to date, with the
+// operations it missed when the room's log holds them all, otherwise with
+// the whole document.
+func resyncMessage(room *Room, lastSeq int64) *Message {
+	ops, serverSeq, ok := room.docState.OpsAfter(lastSeq, maxResyncOps)
 	if !ok {
-		h.mu.Unlock()
-		return
+		return docSyncMessage(room)
+	}
+	resync := ResyncOpsPayload{
+		LastSeq:    lastSeq,
+		ServerSeq:  serverSeq,
+		Operations: make([]OperationBroadcastPayload, len(ops)),
+	}
+	for i, rec := range ops {
+		resync.Operations[i] = OperationBroadcastPayload{Operation: rec.Operation, UserID: rec.UserID, ServerSeq: rec.Seq}
+	}
+	payload, err := json.Marshal(resync)
+	if err != nil {
+		slog.Error("failed to encode resync", "project", room.projectID, "error", err)
+		return docSyncMessage(room)
 	}
+	return &Message{
+		Type:    TypeDocResyncOps,
+		Seq:     serverSeq,
+		Payload: payload,
+	}
+}
 
-	delete(room.clients, client.ClientID)
-	close(client.send)
-	room.presence.Remove(client.UserID)
+// loadErrorMessage describes a failed room load to the joining client.
+func loadErrorMessage(err error) *Message {
+	code, message := "load_failed", "Failed to load project. The project may not exist or has no document."
+	switch {
+	case errors.Is(err, errNoLoader):
+		code, message = "no_loader", "Document loader not configured"
+	case errors.Is(err, context.DeadlineExceeded):
+		code, message = "load_timeout", "Timed out loading project. Please try again."
+	}
+	payload, _ := json.Marshal(map[string]string{
+		"code":    code,
+		"message": message,
+	})
+	return &Message{Type: TypeError, Payload: payload}
+}
 
-	// Save and close room when last client leaves
-	shouldSave := len(room.clients) == 0
-	if shouldSave {
+// removeClient detaches a client whose connection has closed from its room.
+// When the last client leaves, the room shuts down, saving its document.
+func (h *Hub) removeClient(client *Client) {
+	room := client.room
+	if room == nil {
+		// Its room never loaded
+		return
+	}
+
+	h.mu.Lock()
+	room.members--
+	last := room.members == 0 && h.rooms[client.ProjectID] == room
+	if last {
 		delete(h.rooms, client.ProjectID)
+		h.closing[client.ProjectID] = room
 	}
 	h.mu.Unlock()
 
-	// Save outside the lock to avoid blocking other operations
-	if shouldSave && room.docState.IsDirty() {
-		h.saveRoom(client.ProjectID, room)
+	if !room.enqueue(func() { h.leave(room, client) }) {
+		// The hub stopped the room; finish up once it's done with the client
+		<-room.done
+		h.leave(room, client)
+	}
+	if last {
+		room.shutdown()
 	}
+}
+
+// leave removes a client from its room and tells the others it left.
+func (h *Hub) leave(room *Room, client *Client) {
+	room.mu.Lock()
+	if room.clients[client.ClientID] == client {
+		delete(room.clients, client.ClientID)
+	}
+	close(client.send)
+	room.mu.Unlock()
+	room.presence.Remove(client.UserID)
+	room.locks.Release(client.ClientID)
 
 	// Broadcast leave to remaining clients
 	leavePayload, _ := json.Marshal(PresenceLeavePayload{
@@ -243,23 +816,85 @@ func (h *Hub) removeClient(client *Client) {
 		UserID:  client.UserID,
 		Payload: leavePayload,
 	}
-	h.broadcastToRoom(client.ProjectID, leaveMsg, "")
+	room.broadcast(leaveMsg, "")
 
 	slog.Info("client left", "user", client.UserID, "project", client.ProjectID)
 }
 
+// handleMessage queues a client's message for its room's goroutine. Messages
+// arriving once the room is shutting down are dropped.
 func (h *Hub) handleMessage(sender *Client, msg *Message) {
+	room := sender.room
+	if room == nil || !room.enqueue(func() { h.dispatch(room, sender, msg) }) {
+		slog.Debug("message for closed room dropped", "type", msg.Type, "user", sender.UserID)
+	}
+}
+
+// dispatch handles a client's message on its room's goroutine.
+func (h *Hub) dispatch(room *Room, sender *Client, msg *Message) {
 	switch msg.Type {
 	case TypePresenceUpdate:
-		h.handlePresenceUpdate(sender, msg)
+		h.handlePresenceUpdate(room, sender, msg)
 	case TypeOpSubmit:
-		h.handleOperationSubmit(sender, msg)
+		h.handleOperationSubmit(room, sender, msg)
+	case TypeOpUndo, TypeOpRedo:
+		h.handleOperationUndo(room, sender, msg)
+	case TypeOpBatch:
+		h.handleOperationBatch(room, sender, msg)
+	case TypeDocRequestSync:
+		h.handleRequestSync(room, sender)
+	case TypeDocResync:
+		h.handleResync(room, sender, msg)
 	default:
 		slog.Warn("unknown message type", "type", msg.Type, "user", sender.UserID)
 	}
 }
 
-func (h *Hub) handlePresenceUpdate(sender *Client, msg *Message) {
+// handleRequestSync sends the client the room's document again, for clients
+// that have lost track of it. Requests closer together than
+// syncRequestInterval are refused.
+func (h *Hub) handleRequestSync(room *Room, sender *Client) {
+	if syncRateLimited(sender) {
+		return
+	}
+	sender.Send(docSyncMessage(room))
+	slog.Debug("document resynced", "user", sender.UserID, "project", sender.ProjectID)
+}
+
+// handleResync sends the client the operations it missed since the server
+// sequence it last saw, or the whole document if they can't all be sent.
+// It shares doc.requestSync's rate limit.
+func (h *Hub) handleResync(room *Room, sender *Client, msg *Message) {
+	var req ResyncRequestPayload
+	if err := json.Unmarshal(msg.Payload, &req); err != nil {
+		slog.Warn("invalid resync payload", "error", err, "user", sender.UserID)
+		return
+	}
+	if syncRateLimited(sender) {
+		return
+	}
+	reply := resyncMessage(room, req.LastSeq)
+	sender.Send(reply)
+	slog.Debug("client resynced", "user", sender.UserID, "project", sender.ProjectID, "lastSeq", req.LastSeq, "reply", reply.Type)
+}
+
+// syncRateLimited refuses a sync request that came sooner than
+// syncRequestInterval after the client's last one, telling the client so.
+func syncRateLimited(sender *Client) bool {
+	now := time.Now()
+	if now.Sub(sender.lastSyncRequest) < syncRequestInterval {
+		payload, _ := json.Marshal(map[string]string{
+			"code":    "sync_rate_limited",
+			"message": "Document sync requested too often. Please try again shortly.",
+		})
+		sender.Send(&Message{Type: TypeError, Payload: payload})
+		return true
+	}
+	sender.lastSyncRequest = now
+	return false
+}
+
+func (h *Hub) handlePresenceUpdate(room *Room, sender *Client, msg *Message) {
 	var presence PresencePayload
 	if err := json.Unmarshal(msg.Payload, &presence); err != nil {
 		slog.Warn("invalid presence payload", "error", err)
@@ -267,14 +902,17 @@ func (h *Hub) handlePresenceUpdate(sender *Client, msg *Message) {
 	}
 
 	presence.DisplayName = sender.DisplayName
-
-	h.mu.RLock()
-	room, ok := h.rooms[sender.ProjectID]
-	h.mu.RUnlock()
-	if !ok {
-		return
+	if !normalizeCursor(presence.Cursor) {
+		slog.Warn("presence cursor in unknown space dropped", "space", presence.Cursor.Space, "user", sender.UserID)
+		presence.Cursor = nil
 	}
 
+	h.resolveSelection(room, sender.UserID, &presence)
+	if sender.Role == RoleViewer {
+		// Viewers can't edit, so they mustn't hold editors off either
+		presence.EditingObjects = nil
+	}
+	presence.EditingObjects = room.locks.Claim(sender.ClientID, sender.DisplayName, presence.EditingObjects, time.Now())
 	room.presence.Update(sender.UserID, &presence)
 
 	// Broadcast to other clients in room
@@ -284,31 +922,96 @@ func (h *Hub) handlePresenceUpdate(sender *Client, msg *Message) {
 		UserID:  sender.UserID,
 		Payload: outPayload,
 	}
-	h.broadcastToRoom(sender.ProjectID, outMsg, sender.ClientID)
+	room.broadcast(outMsg, sender.ClientID)
 }
 
-func (h *Hub) broadcastToRoom(projectID string, msg *Message, excludeClientID string) {
-	h.mu.RLock()
-	room, ok := h.rooms[projectID]
-	if !ok {
-		h.mu.RUnlock()
+// normalizeCursor marks a cursor sent without a space as legacy, and reports
+// whether the cursor is usable: absent, or in a known space.
+func normalizeCursor(cursor *CursorPos) bool {
+	if cursor == nil {
+		return true
+	}
+	switch cursor.Space {
+	case "":
+		cursor.Space = CursorSpaceLegacy
+	case CursorSpaceScene, CursorSpaceLegacy:
+	default:
+		return false
+	}
+	return true
+}
+
+// resolveSelection fills in presence's selection bounds. Cursor-only updates
+// repeat the selection, so bounds resolved at the current server sequence are
+// reused rather than rebuilding the scene graph.
+func (h *Hub) resolveSelection(room *Room, userID string, presence *PresencePayload) {
+	presence.SelectionBounds = nil
+	if len(presence.Selection) == 0 {
 		return
 	}
+	seq := room.docState.ServerSeq()
+	if prev := room.presence.Get(userID); prev != nil && prev.boundsSeq == seq &&
+		prev.Frame == presence.Frame && slices.Equal(prev.Selection, presence.Selection) {
+		presence.SelectionBounds = prev.SelectionBounds
+		presence.boundsSeq = seq
+		return
+	}
+	bounds := room.docState.selectionBounds(map[string]selectionQuery{
+		userID: {objectIDs: presence.Selection, frame: presence.Frame},
+	})
+	presence.SelectionBounds = bounds[userID]
+	presence.boundsSeq = seq
+}
 
-	clients := make([]*Client, 0, len(room.clients))
-	for _, c := range room.clients {
-		if c.ClientID != excludeClientID {
-			clients = append(clients, c)
+// refreshSelections re-resolves every selection in the room after an edit
+// and broadcasts the presences whose bounds changed, so remote selection
+// boxes follow objects whoever moves them.
+func (h *Hub) refreshSelections(room *Room) {
+	seq := room.docState.ServerSeq()
+	presences := room.presence.GetAll()
+	queries := make(map[string]selectionQuery)
+	for userID, p := range presences {
+		if len(p.Selection) > 0 && p.boundsSeq != seq {
+			queries[userID] = selectionQuery{objectIDs: p.Selection, frame: p.Frame}
 		}
 	}
-	h.mu.RUnlock()
+	if len(queries) == 0 {
+		return
+	}
 
-	for _, c := range clients {
-		c.Send(msg)
+	for userID, bounds := range room.docState.selectionBounds(queries) {
+		prev := presences[userID]
+		next := *prev
+		next.SelectionBounds = bounds
+		next.boundsSeq = seq
+		if !room.presence.ReplaceIf(userID, prev, &next) || sameBounds(prev.SelectionBounds, bounds) {
+			continue
+		}
+		payload, _ := json.Marshal(next)
+		room.broadcast(&Message{
+			Type:    TypePresenceUpdate,
+			UserID:  userID,
+			Payload: payload,
+		}, "")
 	}
 }
 
-func (h *Hub) handleOperationSubmit(sender *Client, msg *Message) {
+func (h *Hub) handleOperationSubmit(room *Room, sender *Client, msg *Message) {
+	if sender.Role == RoleViewer {
+		h.rejectViewer(sender, msg)
+		return
+	}
+	if int64(len(msg.Payload)) > h.maxOpSize {
+		// Only the ID is needed to answer it
+		var op struct {
+			ID string `json:"id"`
+		}
+		json.Unmarshal(msg.Payload, &op)
+		slog.Warn("operation too large", "size", len(msg.Payload), "limit", h.maxOpSize, "opId", op.ID, "user", sender.UserID)
+		h.sendNack(sender, op.ID, "payload_too_large")
+		return
+	}
+
 	// Parse the operation from the message payload
 	var op Operation
 	if err := json.Unmarshal(msg.Payload, &op); err != nil {
@@ -317,51 +1020,266 @@ func (h *Hub) handleOperationSubmit(sender *Client, msg *Message) {
 		return
 	}
 
-	h.mu.RLock()
-	room, ok := h.rooms[sender.ProjectID]
-	h.mu.RUnlock()
-	if !ok {
-		h.sendNack(sender, op.ID, "room not found")
+	if nack := checkEditLocks(room, sender.ClientID, sender.UserID, &op); nack != nil {
+		h.sendResult(sender, OpResult{Nack: nack})
 		return
 	}
 
 	// Apply the operation to the authoritative document
-	serverSeq, err := room.docState.ApplyOperation(op)
-	if err != nil {
-		slog.Warn("operation failed", "error", err, "opType", op.Type, "user", sender.UserID)
-		h.sendNack(sender, op.ID, err.Error())
+	h.backupBefore(room, &op, backupAuthor(sender))
+	result, applied := applySubmitted(room.docState, &op, sender.UserID, h.opPolicy)
+	h.sendResult(sender, result)
+	if applied {
+		h.publishOperation(room, &op, sender.UserID, result.Ack.ServerSeq, sender.ClientID)
+	}
+}
+
+// handleOperationUndo applies an op.undo or op.redo and broadcasts the
+// inverse it applied.
+func (h *Hub) handleOperationUndo(room *Room, sender *Client, msg *Message) {
+	if sender.Role == RoleViewer {
+		h.rejectViewer(sender, msg)
+		return
+	}
+	var req OperationUndoPayload
+	if err := json.Unmarshal(msg.Payload, &req); err != nil {
+		slog.Warn("invalid undo payload", "error", err, "type", msg.Type, "user", sender.UserID)
+		h.sendNack(sender, "", "invalid "+strings.TrimPrefix(msg.Type, "op.")+" payload")
 		return
 	}
 
-	// Send ACK to the sender
-	h.sendAck(sender, op.ID, serverSeq)
+	var inverse *Operation
+	var result OpResult
+	var applied bool
+	if msg.Type == TypeOpRedo {
+		inverse, result, applied = redoSubmitted(room.docState, OperationRedoPayload{ID: req.ID}, sender.UserID, h.opPolicy)
+	} else {
+		inverse, result, applied = undoSubmitted(room.docState, req, sender.UserID, h.opPolicy)
+	}
+	h.sendResult(sender, result)
+	if applied {
+		// The sender hasn't applied the inverse either, so it's included
+		h.publishOperation(room, inverse, sender.UserID, result.Ack.ServerSeq, "")
+	}
+}
+
+// handleOperationBatch applies an op.batch, all or nothing, and broadcasts
+// the operations that changed the document in one message.
+func (h *Hub) handleOperationBatch(room *Room, sender *Client, msg *Message) {
+	if sender.Role == RoleViewer {
+		h.rejectViewer(sender, msg)
+		return
+	}
+	if int64(len(msg.Payload)) > h.maxOpSize {
+		var batch struct {
+			ID string `json:"id"`
+		}
+		json.Unmarshal(msg.Payload, &batch)
+		slog.Warn("operation batch too large", "size", len(msg.Payload), "limit", h.maxOpSize, "batchId", batch.ID, "user", sender.UserID)
+		h.sendBatchResult(sender, batchNack(batch.ID, 0, OperationNackPayload{Reason: "payload_too_large"}))
+		return
+	}
+
+	var batch OperationBatchPayload
+	if err := json.Unmarshal(msg.Payload, &batch); err != nil {
+		slog.Warn("invalid operation batch payload", "error", err, "user", sender.UserID)
+		h.sendBatchResult(sender, batchNack("", 0, OperationNackPayload{Reason: "invalid operation batch payload"}))
+		return
+	}
+	if len(batch.Operations) > maxBatchOps {
+		h.sendBatchResult(sender, batchNack(batch.ID, maxBatchOps, OperationNackPayload{
+			Reason: fmt.Sprintf("a batch may hold at most %d operations", maxBatchOps),
+		}))
+		return
+	}
+
+	for i := range batch.Operations {
+		if nack := checkEditLocks(room, sender.ClientID, sender.UserID, &batch.Operations[i]); nack != nil {
+			h.sendBatchResult(sender, batchNack(batch.ID, i, *nack))
+			return
+		}
+	}
+
+	for i := range batch.Operations {
+		h.backupBefore(room, &batch.Operations[i], backupAuthor(sender))
+	}
+	result, applied := applySubmittedBatch(room.docState, &batch, sender.UserID, h.opPolicy)
+	h.sendBatchResult(sender, result)
+	if len(applied) > 0 {
+		h.publishBatch(room, batch.ID, applied, sender.UserID, result.Ack.FirstSeq, sender.ClientID)
+	}
+}
+
+// checkEditLocks returns the nack for op if a client in the room other than
+// clientID holds an edit lock on an object it moves or restyles.
+func checkEditLocks(room *Room, clientID, userID string, op *Operation) *OperationNackPayload {
+	objectID, holder, locked := room.locks.Check(op, clientID, time.Now())
+	if !locked {
+		return nil
+	}
+	slog.Debug("operation on locked object rejected", "opId", op.ID, "objectId", objectID, "holder", holder, "user", userID)
+	return &OperationNackPayload{OperationID: op.ID, Reason: "locked by " + holder}
+}
 
-	// Broadcast to other clients in the room
+// rejectViewer nacks an op.submit, op.undo, op.redo or op.batch from a viewer.
+func (h *Hub) rejectViewer(sender *Client, msg *Message) {
+	var op struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal(msg.Payload, &op)
+	slog.Warn("operation from viewer rejected", "opId", op.ID, "user", sender.UserID)
+	if msg.Type == TypeOpBatch {
+		h.sendBatchResult(sender, batchNack(op.ID, 0, OperationNackPayload{Reason: "insufficient permissions"}))
+		return
+	}
+	h.sendNack(sender, op.ID, "insufficient permissions")
+}
+
+// SubmitOperations applies ops in order to a live room on behalf of userID,
+// as if they had been submitted over the room's WebSocket, and returns the
+// answer to each. Like op.submit, an operation larger than the hub accepts or
+// on an object a client holds an edit lock on is refused. Applied operations
+// are broadcast to every connected client. It returns ErrRoomNotFound if no
+// one is editing the project.
+func (h *Hub) SubmitOperations(projectID, userID string, ops []Operation) ([]OpResult, error) {
+	h.mu.RLock()
+	room, ok := h.rooms[projectID]
+	closing := h.closing[projectID]
+	h.mu.RUnlock()
+	if !ok {
+		if closing != nil {
+			// Let the caller see what the room saves on its way out
+			<-closing.done
+		}
+		return nil, ErrRoomNotFound
+	}
+
+	results := make([]OpResult, len(ops))
+	ran := room.do(func() {
+		for i := range ops {
+			if data, _ := json.Marshal(&ops[i]); int64(len(data)) > h.maxOpSize {
+				slog.Warn("operation too large", "size", len(data), "limit", h.maxOpSize, "opId", ops[i].ID, "user", userID)
+				results[i] = nackResult(ops[i].ID, "payload_too_large")
+				continue
+			}
+			// A REST caller holds no locks, so any client's lock refuses it
+			if nack := checkEditLocks(room, "", userID, &ops[i]); nack != nil {
+				results[i] = OpResult{Nack: nack}
+				continue
+			}
+			var applied bool
+			h.backupBefore(room, &ops[i], userID)
+			results[i], applied = applySubmitted(room.docState, &ops[i], userID, h.opPolicy)
+			if applied {
+				h.publishOperation(room, &ops[i], userID, results[i].Ack.ServerSeq, "")
+			}
+		}
+	})
+	if !ran {
+		// The last client left first
+		<-room.done
+		return nil, ErrRoomNotFound
+	}
+	return results, nil
+}
+
+// publishOperation broadcasts an applied operation to the room's clients
+// other than excludeClientID, and follows up on its effects.
+func (h *Hub) publishOperation(room *Room, op *Operation, userID string, serverSeq int64, excludeClientID string) {
 	broadcastPayload, _ := json.Marshal(OperationBroadcastPayload{
-		Operation: op,
-		UserID:    sender.UserID,
+		Operation: *op,
+		UserID:    userID,
 		ServerSeq: serverSeq,
 	})
 	broadcastMsg := &Message{
 		Type:    TypeOpBroadcast,
-		UserID:  sender.UserID,
+		UserID:  userID,
 		Payload: broadcastPayload,
 	}
-	h.broadcastToRoom(sender.ProjectID, broadcastMsg, sender.ClientID)
-
-	slog.Debug("operation applied", "opType", op.Type, "opId", op.ID, "serverSeq", serverSeq, "user", sender.UserID)
+	room.broadcast(broadcastMsg, excludeClientID)
+	h.afterPublish(room)
+	h.followUpOperation(room, op, userID, serverSeq)
 }
 
-func (h *Hub) sendAck(client *Client, operationID string, serverSeq int64) {
-	payload, _ := json.Marshal(OperationAckPayload{
-		OperationID:     operationID,
-		ServerSeq:       serverSeq,
-		ServerTimestamp: GetServerTimestamp(),
+// publishBatch broadcasts the applied operations of a batch, sequenced from
+// firstSeq, to the room's clients other than excludeClientID in one message,
+// and follows up on their effects.
+func (h *Hub) publishBatch(room *Room, batchID string, ops []Operation, userID string, firstSeq int64, excludeClientID string) {
+	lastSeq := firstSeq + int64(len(ops)) - 1
+	payload, _ := json.Marshal(OperationBatchBroadcastPayload{
+		BatchID:    batchID,
+		Operations: ops,
+		UserID:     userID,
+		FirstSeq:   firstSeq,
+		ServerSeq:  lastSeq,
 	})
-	client.Send(&Message{
-		Type:    TypeOpAck,
+	room.broadcast(&Message{
+		Type:    TypeOpBatchBroadcast,
+		UserID:  userID,
 		Payload: payload,
-	})
+	}, excludeClientID)
+	h.afterPublish(room)
+	for i := range ops {
+		h.followUpOperation(room, &ops[i], userID, firstSeq+int64(i))
+	}
+}
+
+// afterPublish refreshes the room's selections after an edit, and has its
+// operations flushed early if enough are waiting.
+func (h *Hub) afterPublish(room *Room) {
+	h.refreshSelections(room)
+
+	if h.opStore != nil && room.docState.unpersistedCount() >= opFlushBatch {
+		select {
+		case h.flushNow <- struct{}{}:
+		default: // A flush is already pending
+		}
+	}
+}
+
+// followUpOperation sends what an applied operation announces beyond its
+// broadcast: reloaded assets and webhook events.
+func (h *Hub) followUpOperation(room *Room, op *Operation, userID string, serverSeq int64) {
+	if op.Type == "asset.update" {
+		// Everyone, the submitter included, has the old image cached
+		var asset document.Asset
+		if json.Unmarshal(op.Asset, &asset) == nil {
+			payload, _ := json.Marshal(AssetUpdatedPayload{Asset: asset, UserID: userID, ServerSeq: serverSeq})
+			room.broadcast(&Message{Type: TypeAssetUpdated, UserID: userID, Payload: payload}, "")
+		}
+	}
+
+	if op.Type == "project.rename" {
+		h.webhooks.Dispatch(room.projectID, webhook.EventProjectRenamed, map[string]string{
+			"name":         op.Name,
+			"previousName": op.PreviousName,
+			"userId":       userID,
+		})
+	}
+
+	slog.Debug("operation applied", "opType", op.Type, "opId", op.ID, "serverSeq", serverSeq, "user", userID)
+}
+
+// sendBatchResult answers an op.batch with an op.batchAck or op.batchNack.
+func (h *Hub) sendBatchResult(client *Client, result BatchResult) {
+	msg := &Message{Type: TypeOpBatchAck}
+	msg.Payload, _ = json.Marshal(result.Ack)
+	if result.Nack != nil {
+		msg.Type = TypeOpBatchNack
+		msg.Payload, _ = json.Marshal(result.Nack)
+	}
+	client.Send(msg)
+}
+
+// sendResult answers a submitted operation with an op.ack or op.nack.
+func (h *Hub) sendResult(client *Client, result OpResult) {
+	msg := &Message{Type: TypeOpAck}
+	msg.Payload, _ = json.Marshal(result.Ack)
+	if result.Nack != nil {
+		msg.Type = TypeOpNack
+		msg.Payload, _ = json.Marshal(result.Nack)
+	}
+	client.Send(msg)
 }
 
 func (h *Hub) sendNack(client *Client, operationID string, reason string) {
@@ -0,0 +1,430 @@
+//go:build !(js && wasm)
+
+package collab_test
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/inamate/inamate/backend-go/internal/collab"
+	"github.com/inamate/inamate/backend-go/internal/collab/collabtest"
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// fixture is a project holding one rect under its scene's root, served by a
+// collabtest server.
+type fixture struct {
+	server    *collabtest.Server
+	projectID string
+	rootID    string
+	rectID    string
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	f := &fixture{
+		projectID: typeid.NewProjectID(),
+		rootID:    typeid.NewObjectID(),
+		rectID:    typeid.NewObjectID(),
+	}
+	doc := document.NewEmptyDocument(f.projectID, "Test", typeid.NewSceneID(), f.rootID, typeid.NewTimelineID())
+	root := doc.Objects[f.rootID]
+	root.Children = append(root.Children, f.rectID)
+	doc.Objects[f.rootID] = root
+	doc.Objects[f.rectID] = document.ObjectNode{
+		ID:        f.rectID,
+		Type:      document.ObjectTypeShapeRect,
+		Parent:    &f.rootID,
+		Children:  []string{},
+		Transform: document.Transform{SX: 1, SY: 1},
+		Style:     document.Style{Fill: "#ff0000", Opacity: 1},
+		Visible:   true,
+		Data:      json.RawMessage(`{"width":40,"height":30}`),
+	}
+
+	store := collabtest.NewStore()
+	if err := store.Put(f.projectID, doc); err != nil {
+		t.Fatal(err)
+	}
+	f.server = collabtest.NewServer(store)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// join connects userID with role and waits for its first doc.sync.
+func (f *fixture) join(t *testing.T, ctx context.Context, userID, role string) *collabtest.Client {
+	t.Helper()
+	c, err := f.server.ConnectAs(ctx, f.projectID, userID, userID, role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	if _, _, err := c.Sync(ctx); err != nil {
+		t.Fatalf("%s: sync: %v", userID, err)
+	}
+	return c
+}
+
+func testContext(t *testing.T) context.Context {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func transformOp(objectID, transform string) collab.Operation {
+	return collab.Operation{
+		ID:        typeid.New("op"),
+		Type:      "object.transform",
+		ObjectID:  objectID,
+		Transform: json.RawMessage(transform),
+	}
+}
+
+// editPresence sends a presence.update from c declaring it edits objectIDs,
+// and waits until watcher sees it, so the hub has claimed the locks.
+func editPresence(t *testing.T, ctx context.Context, c, watcher *collabtest.Client, objectIDs ...string) *collab.PresencePayload {
+	t.Helper()
+	if err := c.Send(ctx, collab.TypePresenceUpdate, collab.PresencePayload{EditingObjects: objectIDs}); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := watcher.WaitFor(ctx, func(msg *collab.Message) bool {
+		return msg.Type == collab.TypePresenceUpdate && msg.UserID == c.UserID
+	})
+	if err != nil {
+		t.Fatalf("waiting for presence: %v", err)
+	}
+	var presence collab.PresencePayload
+	if err := json.Unmarshal(msg.Payload, &presence); err != nil {
+		t.Fatal(err)
+	}
+	return &presence
+}
+
+func TestViewerCannotClaimEditLocks(t *testing.T) {
+	f := newFixture(t)
+	ctx := testContext(t)
+	editor := f.join(t, ctx, "editor", collab.RoleEditor)
+	viewer := f.join(t, ctx, "viewer", collab.RoleViewer)
+
+	presence := editPresence(t, ctx, viewer, editor, f.rectID)
+	if len(presence.EditingObjects) != 0 {
+		t.Errorf("viewer holds %v, want no locks", presence.EditingObjects)
+	}
+
+	ack, nack, err := editor.SubmitAndWait(ctx, transformOp(f.rectID, `{"x":10}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ack == nil {
+		t.Fatalf("editor's transform nacked: %s", nack.Reason)
+	}
+}
+
+func TestEditorLockRefusesOthers(t *testing.T) {
+	f := newFixture(t)
+	ctx := testContext(t)
+	holder := f.join(t, ctx, "holder", collab.RoleEditor)
+	other := f.join(t, ctx, "other", collab.RoleEditor)
+
+	presence := editPresence(t, ctx, holder, other, f.rectID)
+	if len(presence.EditingObjects) != 1 || presence.EditingObjects[0] != f.rectID {
+		t.Fatalf("holder holds %v, want [%s]", presence.EditingObjects, f.rectID)
+	}
+
+	_, nack, err := other.SubmitAndWait(ctx, transformOp(f.rectID, `{"x":10}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nack == nil || !strings.HasPrefix(nack.Reason, "locked by ") {
+		t.Fatalf("nack = %+v, want locked", nack)
+	}
+}
+
+func TestSubmitOperationsChecksEditLocks(t *testing.T) {
+	f := newFixture(t)
+	ctx := testContext(t)
+	holder := f.join(t, ctx, "holder", collab.RoleEditor)
+	watcher := f.join(t, ctx, "watcher", collab.RoleEditor)
+	editPresence(t, ctx, holder, watcher, f.rectID)
+
+	results, err := f.server.Hub.SubmitOperations(f.projectID, "rest", []collab.Operation{
+		transformOp(f.rectID, `{"x":10}`),
+		transformOp(f.rootID, `{"x":10}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Nack == nil || !strings.HasPrefix(results[0].Nack.Reason, "locked by ") {
+		t.Errorf("locked object: %+v, want locked nack", results[0])
+	}
+	if results[1].Ack == nil {
+		t.Errorf("unlocked object: %+v, want ack", results[1].Nack)
+	}
+}
+
+func TestSubmitOperationsChecksSize(t *testing.T) {
+	f := newFixture(t)
+	f.server.Hub.SetMessageLimits(0, 1024)
+	ctx := testContext(t)
+	f.join(t, ctx, "editor", collab.RoleEditor)
+
+	big := transformOp(f.rectID, `{"x":10}`)
+	big.Meta = json.RawMessage(`{"note":"` + strings.Repeat("a", 2048) + `"}`)
+	results, err := f.server.Hub.SubmitOperations(f.projectID, "rest", []collab.Operation{
+		big,
+		transformOp(f.rectID, `{"x":20}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Nack == nil || results[0].Nack.Reason != "payload_too_large" {
+		t.Errorf("large operation: %+v, want payload_too_large", results[0])
+	}
+	if results[1].Ack == nil {
+		t.Errorf("small operation: %+v, want ack", results[1].Nack)
+	}
+}
+
+// waitPresence waits for watcher to see a presence.update from userID
+// matching match.
+func waitPresence(t *testing.T, ctx context.Context, watcher *collabtest.Client, userID string, match func(*collab.PresencePayload) bool) *collab.PresencePayload {
+	t.Helper()
+	var presence collab.PresencePayload
+	_, err := watcher.WaitFor(ctx, func(msg *collab.Message) bool {
+		if msg.Type != collab.TypePresenceUpdate || msg.UserID != userID {
+			return false
+		}
+		presence = collab.PresencePayload{}
+		return json.Unmarshal(msg.Payload, &presence) == nil && match(&presence)
+	})
+	if err != nil {
+		t.Fatalf("waiting for %s's presence: %v", userID, err)
+	}
+	return &presence
+}
+
+func TestPresenceSelectionBounds(t *testing.T) {
+	f := newFixture(t)
+	ctx := testContext(t)
+	selector := f.join(t, ctx, "selector", collab.RoleEditor)
+	watcher := f.join(t, ctx, "watcher", collab.RoleEditor)
+
+	// A second 40x30 rect at 100,50
+	otherID := typeid.NewObjectID()
+	raw, _ := json.Marshal(document.ObjectNode{
+		ID:        otherID,
+		Type:      document.ObjectTypeShapeRect,
+		Parent:    &f.rootID,
+		Children:  []string{},
+		Transform: document.Transform{X: 100, Y: 50, SX: 1, SY: 1},
+		Style:     document.Style{Fill: "#0000ff", Opacity: 1},
+		Visible:   true,
+		Data:      json.RawMessage(`{"width":40,"height":30}`),
+	})
+	if ack, nack, err := watcher.SubmitAndWait(ctx, collab.Operation{ID: typeid.New("op"), Type: "object.create", Object: raw, ParentID: f.rootID}); err != nil || ack == nil {
+		t.Fatalf("create: %+v, %v", nack, err)
+	}
+
+	// The client's own bounds are ignored
+	bogus := &collab.Bounds{X: -1, Y: -1, Width: 1, Height: 1}
+	if err := selector.Send(ctx, collab.TypePresenceUpdate, collab.PresencePayload{Selection: []string{f.rectID, otherID}, SelectionBounds: bogus}); err != nil {
+		t.Fatal(err)
+	}
+	presence := waitPresence(t, ctx, watcher, "selector", func(p *collab.PresencePayload) bool { return len(p.Selection) == 2 })
+	want := collab.Bounds{X: 0, Y: 0, Width: 140, Height: 80}
+	if presence.SelectionBounds == nil || *presence.SelectionBounds != want {
+		t.Fatalf("selection bounds %+v, want the union %+v", presence.SelectionBounds, want)
+	}
+
+	// Another user moving a selected object re-sends the selector's bounds
+	if ack, nack, err := watcher.SubmitAndWait(ctx, transformOp(f.rectID, `{"x":-20}`)); err != nil || ack == nil {
+		t.Fatalf("move: %+v, %v", nack, err)
+	}
+	want = collab.Bounds{X: -20, Y: 0, Width: 160, Height: 80}
+	waitPresence(t, ctx, watcher, "selector", func(p *collab.PresencePayload) bool {
+		return p.SelectionBounds != nil && *p.SelectionBounds == want
+	})
+}
+
+// TestPresenceCursorSpaces has a client from before cursor spaces and one
+// sending scene-space cursors with its scene share a room.
+func TestPresenceCursorSpaces(t *testing.T) {
+	f := newFixture(t)
+	ctx := testContext(t)
+	legacy := f.join(t, ctx, "legacy", collab.RoleEditor)
+	current := f.join(t, ctx, "current", collab.RoleEditor)
+	watcher := f.join(t, ctx, "watcher", collab.RoleEditor)
+
+	// A cursor sent without a space is marked legacy
+	if err := legacy.Send(ctx, collab.TypePresenceUpdate, json.RawMessage(`{"cursor":{"x":10,"y":20}}`)); err != nil {
+		t.Fatal(err)
+	}
+	presence := waitPresence(t, ctx, watcher, "legacy", func(p *collab.PresencePayload) bool { return p.Cursor != nil })
+	if *presence.Cursor != (collab.CursorPos{X: 10, Y: 20, Space: collab.CursorSpaceLegacy}) || presence.SceneID != "" {
+		t.Errorf("legacy presence cursor %+v in scene %q, want legacy space and no scene", presence.Cursor, presence.SceneID)
+	}
+
+	// Scene-space cursors pass through with the sender's scene
+	sceneID := "scene_edited"
+	cursor := collab.CursorPos{X: 300, Y: 150, Space: collab.CursorSpaceScene}
+	if err := current.Send(ctx, collab.TypePresenceUpdate, collab.PresencePayload{Cursor: &cursor, SceneID: sceneID}); err != nil {
+		t.Fatal(err)
+	}
+	presence = waitPresence(t, ctx, watcher, "current", func(p *collab.PresencePayload) bool { return p.Cursor != nil })
+	if *presence.Cursor != cursor || presence.SceneID != sceneID {
+		t.Errorf("presence cursor %+v in scene %q, want %+v in %s", presence.Cursor, presence.SceneID, cursor, sceneID)
+	}
+
+	// A cursor in an unknown space is dropped, the rest of the update kept
+	unknown := collab.CursorPos{X: 1, Y: 2, Space: "screen"}
+	if err := current.Send(ctx, collab.TypePresenceUpdate, collab.PresencePayload{Cursor: &unknown, SceneID: sceneID, Selection: []string{f.rectID}}); err != nil {
+		t.Fatal(err)
+	}
+	presence = waitPresence(t, ctx, watcher, "current", func(p *collab.PresencePayload) bool { return len(p.Selection) == 1 })
+	if presence.Cursor != nil || presence.SceneID != sceneID {
+		t.Errorf("cursor %+v in scene %q, want no cursor in %s", presence.Cursor, presence.SceneID, sceneID)
+	}
+
+	// A late joiner's presence state holds both, as normalized
+	late, err := f.server.ConnectAs(ctx, f.projectID, "late", "late", collab.RoleEditor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { late.Close() })
+	msg, err := late.Expect(ctx, collab.TypePresenceState)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state collab.PresenceStatePayload
+	if err := json.Unmarshal(msg.Payload, &state); err != nil {
+		t.Fatal(err)
+	}
+	if p := state.Presences["legacy"]; p == nil || p.Cursor == nil || p.Cursor.Space != collab.CursorSpaceLegacy {
+		t.Errorf("legacy user's state %+v, want a legacy cursor", p)
+	}
+	if p := state.Presences["current"]; p == nil || p.SceneID != sceneID || len(p.Selection) != 1 {
+		t.Errorf("current user's state %+v, want its scene and selection", p)
+	}
+}
+
+func TestPlaygroundFlag(t *testing.T) {
+	ctx := testContext(t)
+	// join connects anonymously to the playground and returns the first
+	// message the hub answers with
+	join := func(enabled bool) *collab.Message {
+		server := collabtest.NewServer(collabtest.NewStore())
+		t.Cleanup(server.Close)
+		// Before anyone connects, so before the hub reads it
+		server.Hub.SetPlaygroundEnabled(enabled)
+		if got := server.Hub.PlaygroundEnabled(); got != enabled {
+			t.Errorf("PlaygroundEnabled() = %v after setting %v", got, enabled)
+		}
+		c, err := server.ConnectAs(ctx, collab.PlaygroundProjectID, "anon", "Anonymous", collab.RoleEditor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { c.Close() })
+		msg, err := c.WaitFor(ctx, func(msg *collab.Message) bool {
+			return msg.Type == collab.TypeDocSync || msg.Type == collab.TypeError
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	}
+
+	// With nothing stored, the enabled playground starts a fresh document
+	if msg := join(true); msg.Type != collab.TypeDocSync {
+		t.Errorf("enabled playground answered %s %s, want doc.sync", msg.Type, msg.Payload)
+	}
+	// and the disabled one fails to load rather than making one
+	if msg := join(false); msg.Type != collab.TypeError || !strings.Contains(string(msg.Payload), "load_failed") {
+		t.Errorf("disabled playground answered %s %s, want a load_failed error", msg.Type, msg.Payload)
+	}
+}
+
+// submit applies op as c and fails the test unless it is acked.
+func submit(t *testing.T, ctx context.Context, c *collabtest.Client, op collab.Operation) *collab.OperationAckPayload {
+	t.Helper()
+	ack, nack, err := c.SubmitAndWait(ctx, op)
+	if err != nil || ack == nil {
+		t.Fatalf("%s: %+v, %v", op.Type, nack, err)
+	}
+	return ack
+}
+
+// removeFrameOp removes the first frame of timelineID.
+func removeFrameOp(timelineID string) collab.Operation {
+	at := 0
+	return collab.Operation{ID: typeid.New("op"), Type: "timeline.removeTime", TimelineID: timelineID, AtFrame: &at, Frames: 1}
+}
+
+func TestSafetySnapshot(t *testing.T) {
+	f := newFixture(t)
+	ctx := testContext(t)
+	stored, _, err := f.server.Store.Load(ctx, f.projectID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	timelineID := stored.Project.RootTimeline
+	editor := f.join(t, ctx, "editor", collab.RoleEditor)
+
+	// Nothing to back up in a document just loaded
+	submit(t, ctx, editor, removeFrameOp(timelineID))
+	if labels := f.server.Store.Labels(f.projectID); len(labels) != 0 {
+		t.Fatalf("safety snapshots %v of an unchanged document", labels)
+	}
+
+	// Once there are changes to lose, a delete is backed up first
+	submit(t, ctx, editor, transformOp(f.rectID, `{"x":10}`))
+	ack := submit(t, ctx, editor, collab.Operation{ID: typeid.New("op"), Type: "object.delete", ObjectID: f.rectID})
+	if labels := f.server.Store.Labels(f.projectID); len(labels) != 1 || labels[0] != "pre: object.delete by editor" {
+		t.Fatalf("safety snapshots %v, want one before the delete", labels)
+	}
+	// It was saved before the delete applied, with the edit before it
+	saved, seq, err := f.server.Store.Load(ctx, f.projectID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seq != ack.ServerSeq-1 {
+		t.Errorf("safety snapshot at seq %d, want %d", seq, ack.ServerSeq-1)
+	}
+	if rect, ok := saved.Objects[f.rectID]; !ok || rect.Transform.X != 10 {
+		t.Errorf("safety snapshot rect %+v, want it moved and not yet deleted", rect)
+	}
+
+	// Another destructive op within the interval isn't backed up again
+	submit(t, ctx, editor, removeFrameOp(timelineID))
+	if labels := f.server.Store.Labels(f.projectID); len(labels) != 1 {
+		t.Errorf("safety snapshots %v, want the second throttled", labels)
+	}
+}
+
+func TestSafetySnapshotInterval(t *testing.T) {
+	f := newFixture(t)
+	ctx := testContext(t)
+	// Before anyone connects, so before the hub reads it
+	f.server.Hub.SetBackupInterval(time.Nanosecond)
+	stored, _, err := f.server.Store.Load(ctx, f.projectID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	editor := f.join(t, ctx, "editor", collab.RoleEditor)
+
+	submit(t, ctx, editor, transformOp(f.rectID, `{"x":10}`))
+	submit(t, ctx, editor, collab.Operation{ID: typeid.New("op"), Type: "object.delete", ObjectID: f.rectID})
+	submit(t, ctx, editor, removeFrameOp(stored.Project.RootTimeline))
+	// Non-destructive ops are never backed up
+	submit(t, ctx, editor, collab.Operation{ID: typeid.New("op"), Type: "project.rename", Name: "Renamed"})
+
+	want := []string{"pre: object.delete by editor", "pre: timeline.removeTime by editor"}
+	if labels := f.server.Store.Labels(f.projectID); !slices.Equal(labels, want) {
+		t.Errorf("safety snapshots %v, want %v", labels, want)
+	}
+}
@@ -0,0 +1,81 @@
+package collab
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+)
+
+func TestSceneUpdateSafeAreaInsets(t *testing.T) {
+	ds, _ := rectState(t)
+	sceneID := ds.doc.Project.Scenes[0]
+	update := func(changes string) error {
+		_, err := ds.ApplyOperation(&Operation{ID: "op_scene", Type: "scene.update", SceneID: sceneID, Changes: json.RawMessage(changes)}, "user")
+		return err
+	}
+
+	custom := `{"safeAreaInsets":{"action":{"top":10,"right":10,"bottom":10,"left":10},"title":{"top":20,"right":20,"bottom":20,"left":20}}}`
+	if err := update(custom); err != nil {
+		t.Fatal(err)
+	}
+	want := document.SafeAreaInsets{Action: document.Insets{Top: 10, Right: 10, Bottom: 10, Left: 10}, Title: document.Insets{Top: 20, Right: 20, Bottom: 20, Left: 20}}
+	if got := ds.doc.Scenes[sceneID].SafeAreaInsets; got == nil || *got != want {
+		t.Fatalf("safe area %+v, want %+v", got, want)
+	}
+
+	rejected := []struct {
+		name, changes string
+	}{
+		{"title outside action", `{"safeAreaInsets":{"action":{"top":30},"title":{"top":20}}}`},
+		{"negative", `{"safeAreaInsets":{"action":{"left":-5},"title":{}}}`},
+		{"covering the scene", `{"safeAreaInsets":{"action":{"left":640,"right":640},"title":{"left":640,"right":640}}}`},
+		{"not an object", `{"safeAreaInsets":"wide"}`},
+		// The current insets no longer fit a 30px high scene
+		{"shrunk under the insets", `{"height":30}`},
+	}
+	for _, tt := range rejected {
+		if err := update(tt.changes); err == nil {
+			t.Errorf("%s: applied", tt.name)
+		}
+	}
+	scene := ds.doc.Scenes[sceneID]
+	if scene.Height != 720 || scene.SafeAreaInsets == nil || *scene.SafeAreaInsets != want {
+		t.Errorf("rejected updates changed the scene to %dpx high with %+v", scene.Height, scene.SafeAreaInsets)
+	}
+
+	// null goes back to the standard safe areas
+	if err := update(`{"safeAreaInsets":null}`); err != nil {
+		t.Fatal(err)
+	}
+	if scene := ds.doc.Scenes[sceneID]; scene.SafeAreaInsets != nil || scene.SafeArea() != document.DefaultSafeAreaInsets(1280, 720) {
+		t.Errorf("after reset safe area %+v", scene.SafeAreaInsets)
+	}
+}
+
+func TestProjectUpdate(t *testing.T) {
+	ds, _ := rectState(t)
+	update := func(changes string) error {
+		_, err := ds.ApplyOperation(&Operation{ID: "op_project", Type: "project.update", Changes: json.RawMessage(changes)}, "user")
+		return err
+	}
+
+	if err := update(`{"units":"mm","direction":"rtl"}`); err != nil {
+		t.Fatal(err)
+	}
+	if p := ds.doc.Project; p.Units != "mm" || p.Direction != "rtl" {
+		t.Fatalf("units %q direction %q, want mm rtl", p.Units, p.Direction)
+	}
+	// A field left out is unchanged
+	if err := update(`{"units":"pt"}`); err != nil || ds.doc.Project.Direction != "rtl" {
+		t.Errorf("units-only update: direction %q, err %v", ds.doc.Project.Direction, err)
+	}
+	for _, changes := range []string{`{"units":"furlongs"}`, `{"units":"px","direction":"up"}`} {
+		if err := update(changes); err == nil {
+			t.Errorf("%s applied", changes)
+		}
+	}
+	if p := ds.doc.Project; p.Units != "pt" || p.Direction != "rtl" {
+		t.Errorf("rejected updates left units %q direction %q", p.Units, p.Direction)
+	}
+}
@@ -0,0 +1,182 @@
+//go:build !(js && wasm)
+
+package collab
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// gatedLoader loads empty documents, but loads of projects in gated wait
+// until the gate is closed or their context ends.
+type gatedLoader struct {
+	mu      sync.Mutex
+	gated   map[string]chan struct{}
+	entered chan string // Receives each gated project as its load starts
+	loads   atomic.Int32
+}
+
+func newGatedLoader(gated ...string) *gatedLoader {
+	l := &gatedLoader{gated: make(map[string]chan struct{}), entered: make(chan string, 16)}
+	for _, projectID := range gated {
+		l.gated[projectID] = make(chan struct{})
+	}
+	return l
+}
+
+func (l *gatedLoader) release(projectID string) {
+	close(l.gated[projectID])
+}
+
+func (l *gatedLoader) load(ctx context.Context, projectID string) (*document.InDocument, int64, error) {
+	l.loads.Add(1)
+	l.mu.Lock()
+	gate := l.gated[projectID]
+	l.mu.Unlock()
+	if gate != nil {
+		l.entered <- projectID
+		select {
+		case <-gate:
+		case <-ctx.Done():
+			return nil, 0, ctx.Err()
+		}
+	}
+	doc := document.NewEmptyDocument(projectID, "Test", typeid.NewSceneID(), typeid.NewObjectID(), typeid.NewTimelineID())
+	return doc, 0, nil
+}
+
+func newLoadHub(t *testing.T, loader *gatedLoader) *Hub {
+	t.Helper()
+	h := NewHub(loader.load, nil)
+	t.Cleanup(h.Stop)
+	return h
+}
+
+// unconnected returns a client without a connection; what the hub sends it
+// is left on its send channel.
+func unconnected(h *Hub, projectID, userID string) *Client {
+	return NewClient(h, nil, userID, userID, projectID, userID, RoleEditor)
+}
+
+// nextMessage waits for the next message the hub sends c.
+func nextMessage(t *testing.T, c *Client) *Message {
+	t.Helper()
+	select {
+	case data := <-c.send:
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatal(err)
+		}
+		return &msg
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s: no message", c.UserID)
+		return nil
+	}
+}
+
+// expectSynced checks c was welcomed and sent the document.
+func expectSynced(t *testing.T, c *Client) {
+	t.Helper()
+	if msg := nextMessage(t, c); msg.Type != TypeWelcome {
+		t.Fatalf("%s: first message %s, want %s", c.UserID, msg.Type, TypeWelcome)
+	}
+	if msg := nextMessage(t, c); msg.Type != TypeDocSync {
+		t.Fatalf("%s: second message %s, want %s", c.UserID, msg.Type, TypeDocSync)
+	}
+}
+
+// TestSlowLoadKeepsHubResponsive checks a project whose document is slow to
+// load holds up neither other projects' clients nor the hub's lock.
+func TestSlowLoadKeepsHubResponsive(t *testing.T) {
+	slow, fast := typeid.NewProjectID(), typeid.NewProjectID()
+	loader := newGatedLoader(slow)
+	h := newLoadHub(t, loader)
+
+	waiting := unconnected(h, slow, "waiting")
+	registered := make(chan struct{})
+	go func() {
+		h.Register(waiting)
+		close(registered)
+	}()
+	<-loader.entered
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.Register(unconnected(h, fast, "other"))
+		h.Stats()
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("hub blocked behind a slow document load")
+	}
+
+	loader.release(slow)
+	<-registered
+	expectSynced(t, waiting)
+	if stats := h.Stats(); len(stats.Rooms) != 2 {
+		t.Errorf("%d rooms, want 2", len(stats.Rooms))
+	}
+}
+
+func TestLoadTimeoutReported(t *testing.T) {
+	stuck := typeid.NewProjectID()
+	h := newLoadHub(t, newGatedLoader(stuck))
+	h.SetDocumentTimeout(20 * time.Millisecond)
+
+	c := unconnected(h, stuck, "user")
+	h.Register(c)
+
+	msg := nextMessage(t, c)
+	var payload struct{ Code string }
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Type != TypeError || payload.Code != "load_timeout" {
+		t.Errorf("got %s %s, want %s load_timeout", msg.Type, payload.Code, TypeError)
+	}
+	if stats := h.Stats(); len(stats.Rooms) != 0 {
+		t.Errorf("%d rooms after a failed load, want none", len(stats.Rooms))
+	}
+}
+
+// TestSimultaneousJoinersShareRoom checks two clients joining a cold room
+// while its document loads end up in the same room.
+func TestSimultaneousJoinersShareRoom(t *testing.T) {
+	projectID := typeid.NewProjectID()
+	loader := newGatedLoader(projectID)
+	h := newLoadHub(t, loader)
+
+	a, b := unconnected(h, projectID, "a"), unconnected(h, projectID, "b")
+	var wg sync.WaitGroup
+	for _, c := range []*Client{a, b} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.Register(c)
+		}()
+	}
+	// Both load before either room is inserted
+	<-loader.entered
+	<-loader.entered
+	loader.release(projectID)
+	wg.Wait()
+
+	expectSynced(t, a)
+	expectSynced(t, b)
+	if a.room != b.room {
+		t.Fatal("joiners were given different rooms")
+	}
+	stats := h.Stats()
+	if len(stats.Rooms) != 1 || stats.Rooms[0].Clients != 2 {
+		t.Errorf("stats %+v, want one room with both clients", stats)
+	}
+}
@@ -0,0 +1,102 @@
+package collab
+
+import (
+	"sync"
+	"time"
+)
+
+// editLockTTL is how long an edit lock lasts unless the client holding it
+// renews it by listing the object in another presence update, so a stuck
+// client can't hold an object forever.
+const editLockTTL = 15 * time.Second
+
+// maxEditLocks bounds how many objects one client may hold edit locks on.
+// Objects listed beyond it are left unlocked.
+const maxEditLocks = 64
+
+// editLock is a client's soft lock on an object it is editing.
+type editLock struct {
+	clientID    string
+	displayName string
+	expires     time.Time
+}
+
+// EditLocks are the soft locks a room's clients hold on the objects they
+// are editing, such as during a drag. The first client to declare it is
+// editing an object holds it; other clients' transform and style operations
+// on it are refused until it is released or expires.
+type EditLocks struct {
+	mu    sync.Mutex
+	locks map[string]editLock // objectID -> lock
+}
+
+func NewEditLocks() *EditLocks {
+	return &EditLocks{locks: make(map[string]editLock)}
+}
+
+// Claim sets the objects a client is editing to objectIDs as of now: it
+// takes those that are free or expired, renews those it holds, and releases
+// those it holds but no longer lists. Only the first maxEditLocks objects
+// listed are considered. It returns the objects the client holds afterwards,
+// in the order listed.
+func (l *EditLocks) Claim(clientID, displayName string, objectIDs []string, now time.Time) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	listed := make(map[string]bool, min(len(objectIDs), maxEditLocks))
+	var held []string
+	for _, id := range objectIDs {
+		if listed[id] {
+			continue
+		}
+		if len(listed) == maxEditLocks {
+			break
+		}
+		listed[id] = true
+		if lock, ok := l.locks[id]; ok && lock.clientID != clientID && now.Before(lock.expires) {
+			continue
+		}
+		l.locks[id] = editLock{clientID: clientID, displayName: displayName, expires: now.Add(editLockTTL)}
+		held = append(held, id)
+	}
+	for id, lock := range l.locks {
+		if (lock.clientID == clientID && !listed[id]) || !now.Before(lock.expires) {
+			delete(l.locks, id)
+		}
+	}
+	return held
+}
+
+// Release releases every lock a client holds.
+func (l *EditLocks) Release(clientID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for id, lock := range l.locks {
+		if lock.clientID == clientID {
+			delete(l.locks, id)
+		}
+	}
+}
+
+// Check returns the first of op's targets locked by a client other than
+// clientID as of now, and the display name of the client holding it. Only
+// operations that move or restyle objects are checked.
+func (l *EditLocks) Check(op *Operation, clientID string, now time.Time) (objectID, holder string, locked bool) {
+	switch op.Type {
+	case "object.transform", "object.style", "object.nudge", "object.resetTransform":
+	default:
+		return "", "", false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.locks) == 0 {
+		return "", "", false
+	}
+	for _, id := range modifiedIDs(op) {
+		if lock, ok := l.locks[id]; ok && lock.clientID != clientID && now.Before(lock.expires) {
+			return id, lock.displayName, true
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,44 @@
+package collab
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestClaimBoundsLocks(t *testing.T) {
+	locks := NewEditLocks()
+	ids := make([]string, maxEditLocks+10)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("obj_%d", i)
+	}
+
+	held := locks.Claim("a", "A", ids, time.Now())
+	if len(held) != maxEditLocks {
+		t.Fatalf("holds %d locks, want %d", len(held), maxEditLocks)
+	}
+	if len(locks.locks) != maxEditLocks {
+		t.Errorf("%d locks recorded, want %d", len(locks.locks), maxEditLocks)
+	}
+	if held := locks.Claim("b", "B", ids[maxEditLocks:], time.Now()); len(held) != 10 {
+		t.Errorf("objects past the bound: b holds %d, want 10", len(held))
+	}
+}
+
+func TestClaimExpiresAndReleases(t *testing.T) {
+	locks := NewEditLocks()
+	now := time.Now()
+	locks.Claim("a", "A", []string{"obj_1", "obj_2"}, now)
+
+	if held := locks.Claim("b", "B", []string{"obj_1"}, now); len(held) != 0 {
+		t.Errorf("b took a's lock: %v", held)
+	}
+	// a lets go of obj_2 by no longer listing it
+	locks.Claim("a", "A", []string{"obj_1"}, now)
+	if held := locks.Claim("b", "B", []string{"obj_2"}, now); len(held) != 1 {
+		t.Errorf("b couldn't take a released lock")
+	}
+	if held := locks.Claim("b", "B", []string{"obj_1", "obj_2"}, now.Add(editLockTTL)); len(held) != 2 {
+		t.Errorf("b couldn't take an expired lock: %v", held)
+	}
+}
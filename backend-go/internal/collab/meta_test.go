@@ -0,0 +1,104 @@
+package collab
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/engine"
+)
+
+// drawn compiles the draw commands of ds's first scene at frame 0.
+func drawn(ds *DocumentState) []engine.DrawCommand {
+	sg := engine.BuildSceneGraph(ds.doc, ds.doc.Project.Scenes[0], 0, ds.doc.Project.RootTimeline, false, nil)
+	return engine.CompileDrawCommands(sg)
+}
+
+func setMeta(rectID, meta string) *Operation {
+	return &Operation{Type: "object.setMeta", ObjectID: rectID, Meta: json.RawMessage(meta)}
+}
+
+func TestSetMetaMerges(t *testing.T) {
+	ds, rectID := rectState(t)
+	before := drawn(ds)
+	data := string(ds.doc.Objects[rectID].Data)
+
+	apply(t, ds, setMeta(rectID, `{"l10nKey":"hero.title","cms":{"entry":42}}`), "user")
+	apply(t, ds, setMeta(rectID, `{"cms":{"entry":43},"owner":"marketing"}`), "user")
+	var meta map[string]interface{}
+	if err := json.Unmarshal(ds.doc.Objects[rectID].Meta, &meta); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"l10nKey": "hero.title", "cms": map[string]interface{}{"entry": 43.0}, "owner": "marketing"}
+	if !reflect.DeepEqual(meta, want) {
+		t.Errorf("meta %v, want %v", meta, want)
+	}
+
+	// Meta is its own namespace and never reaches the renderer
+	if got := string(ds.doc.Objects[rectID].Data); got != data {
+		t.Errorf("data %s, want %s untouched", got, data)
+	}
+	if after := drawn(ds); !reflect.DeepEqual(after, before) {
+		t.Errorf("draw commands changed by meta:\n%+v\nwant\n%+v", after, before)
+	}
+
+	// Undo restores only the keys the last merge touched
+	undo(t, ds, "user")
+	if got := string(ds.doc.Objects[rectID].Meta); got != `{"cms":{"entry":42},"l10nKey":"hero.title"}` {
+		t.Errorf("meta after undo %s", got)
+	}
+
+	// Null removes a key, and no keys means no meta
+	apply(t, ds, setMeta(rectID, `{"cms":null,"l10nKey":null,"missing":null}`), "user")
+	if got := ds.doc.Objects[rectID].Meta; got != nil {
+		t.Errorf("meta %s after removing every key, want none", got)
+	}
+
+	for _, meta := range []string{`null`, `[1]`, `"key"`, `{`} {
+		if _, err := ds.ApplyOperation(setMeta(rectID, meta), "user"); err == nil {
+			t.Errorf("meta %s applied", meta)
+		}
+	}
+	if _, err := ds.ApplyOperation(setMeta("obj_missing", `{"a":1}`), "user"); err == nil {
+		t.Error("meta set on a missing object")
+	}
+}
+
+func TestMetaSurvivesRoundTripAndDuplicate(t *testing.T) {
+	ds, rectID := rectState(t)
+	apply(t, ds, setMeta(rectID, `{"l10nKey":"hero.title"}`), "user")
+
+	saved, _, err := ds.SnapshotDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded document.InDocument
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(loaded.Objects[rectID].Meta); got != `{"l10nKey":"hero.title"}` {
+		t.Errorf("loaded meta %s", got)
+	}
+	reloaded := NewDocumentState(&loaded)
+	if !reflect.DeepEqual(drawn(reloaded), drawn(ds)) {
+		t.Error("reloaded document draws differently")
+	}
+
+	op := &Operation{Type: "object.duplicate", ObjectID: rectID}
+	apply(t, ds, op, "user")
+	copyID := op.IDMap[rectID][rectID]
+	if copyID == "" || copyID == rectID {
+		t.Fatalf("duplicate mapped %s to %q", rectID, copyID)
+	}
+	if got := string(ds.doc.Objects[copyID].Meta); got != `{"l10nKey":"hero.title"}` {
+		t.Errorf("copy's meta %s, want the original's", got)
+	}
+}
@@ -0,0 +1,200 @@
+package collab
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+)
+
+// nudgeUnit is the kind of quantity a nudgeable property holds, which decides
+// the units its deltas may be written in.
+type nudgeUnit int
+
+const (
+	unitLength nudgeUnit = iota // px
+	unitAngle                   // deg, °, rad
+	unitScale                   // %, as a factor of the current value
+)
+
+// nudgeProperties are the properties object.nudge can move, each a number on
+// the object's base transform.
+var nudgeProperties = map[string]nudgeUnit{
+	"x":     unitLength,
+	"y":     unitLength,
+	"ax":    unitLength,
+	"ay":    unitLength,
+	"r":     unitAngle,
+	"skewX": unitAngle,
+	"skewY": unitAngle,
+	"sx":    unitScale,
+	"sy":    unitScale,
+}
+
+// nudgeDelta is a parsed delta: the current value is multiplied by factor,
+// then add is added.
+type nudgeDelta struct {
+	add, factor float64
+}
+
+// parseNudgeDeltas parses an object.nudge's deltas, keyed by transform field
+// ("x") or property path ("transform.x"). A delta is a number or a string
+// with a unit: "10px" for positions, "-15°", "-15deg" or "0.5rad" for angles,
+// and "200%" for scales, which multiplies the current scale. Unitless strings
+// are taken as they are.
+func parseNudgeDeltas(raw map[string]json.RawMessage) (map[string]nudgeDelta, error) {
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("deltas are required")
+	}
+	deltas := make(map[string]nudgeDelta, len(raw))
+	for _, key := range slices.Sorted(maps.Keys(raw)) {
+		field := "deltas." + key
+		name := strings.TrimPrefix(key, "transform.")
+		unit, ok := nudgeProperties[name]
+		if !ok {
+			return nil, &InvalidValueError{Field: field, Reason: "not a numeric transform property"}
+		}
+		if _, dup := deltas[name]; dup {
+			return nil, &InvalidValueError{Field: field, Reason: "property given twice"}
+		}
+
+		var s string
+		if len(raw[key]) == 0 || raw[key][0] != '"' || json.Unmarshal(raw[key], &s) != nil {
+			v, err := checkNumber(field, raw[key])
+			if err != nil {
+				return nil, err
+			}
+			deltas[name] = nudgeDelta{add: v, factor: 1}
+			continue
+		}
+		d, err := parseUnitDelta(strings.TrimSpace(s), unit)
+		if err != nil {
+			return nil, &InvalidValueError{Field: field, Reason: err.Error()}
+		}
+		deltas[name] = d
+	}
+	return deltas, nil
+}
+
+// parseUnitDelta parses a delta written with an optional unit suffix.
+func parseUnitDelta(s string, unit nudgeUnit) (nudgeDelta, error) {
+	number, scale, isFactor := s, 1.0, false
+	switch {
+	case strings.HasSuffix(s, "px"):
+		if unit != unitLength {
+			return nudgeDelta{}, fmt.Errorf("px only applies to positions")
+		}
+		number = strings.TrimSuffix(s, "px")
+	case strings.HasSuffix(s, "°"), strings.HasSuffix(s, "deg"):
+		if unit != unitAngle {
+			return nudgeDelta{}, fmt.Errorf("degrees only apply to angles")
+		}
+		number = strings.TrimSuffix(strings.TrimSuffix(s, "°"), "deg")
+	case strings.HasSuffix(s, "rad"):
+		if unit != unitAngle {
+			return nudgeDelta{}, fmt.Errorf("radians only apply to angles")
+		}
+		number, scale = strings.TrimSuffix(s, "rad"), 180/math.Pi
+	case strings.HasSuffix(s, "%"):
+		if unit != unitScale {
+			return nudgeDelta{}, fmt.Errorf("percentages only apply to scales")
+		}
+		number, scale, isFactor = strings.TrimSuffix(s, "%"), 0.01, true
+	}
+
+	v, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil {
+		return nudgeDelta{}, fmt.Errorf("%q is not a number", s)
+	}
+	v *= scale
+	if !finite(v) {
+		return nudgeDelta{}, fmt.Errorf("must be a finite number")
+	}
+	if isFactor {
+		return nudgeDelta{factor: v}, nil
+	}
+	return nudgeDelta{add: v, factor: 1}, nil
+}
+
+// nudgeField returns a pointer to a transform's field by name.
+func nudgeField(t *document.Transform, name string) *float64 {
+	switch name {
+	case "x":
+		return &t.X
+	case "y":
+		return &t.Y
+	case "ax":
+		return &t.AX
+	case "ay":
+		return &t.AY
+	case "r":
+		return &t.R
+	case "skewX":
+		return &t.SkewX
+	case "skewY":
+		return &t.SkewY
+	case "sx":
+		return &t.SX
+	case "sy":
+		return &t.SY
+	}
+	return nil
+}
+
+// prepareNudgeLocked resolves an object.nudge against the current transforms
+// of its targets, ObjectID and ObjectIDs: Transforms is set to where each
+// ends up and PreviousTransforms to where it was. Because the deltas are
+// applied to whatever the server holds when the nudge arrives, a nudge
+// composes with concurrent absolute edits instead of overwriting them, and
+// the resolved transforms replay identically from the log. Results are
+// clamped to the validation bounds; a nudge that would zero a scale is
+// rejected as a whole.
+func (ds *DocumentState) prepareNudgeLocked(op *Operation) error {
+	deltas, err := parseNudgeDeltas(op.Deltas)
+	if err != nil {
+		return err
+	}
+	ids := op.ObjectIDs
+	if op.ObjectID != "" {
+		ids = append([]string{op.ObjectID}, ids...)
+	}
+	if len(ids) == 0 {
+		return fmt.Errorf("objectId or objectIds is required")
+	}
+
+	transforms := make(map[string]document.Transform, len(ids))
+	previous := make(map[string]document.Transform, len(ids))
+	for _, id := range ids {
+		obj, ok := ds.doc.Objects[id]
+		if !ok {
+			return fmt.Errorf("object not found: %s", id)
+		}
+		t := obj.Transform
+		for _, name := range slices.Sorted(maps.Keys(deltas)) {
+			d := deltas[name]
+			v := nudgeField(&t, name)
+			*v = math.Max(-maxValueMagnitude, math.Min(maxValueMagnitude, *v*d.factor+d.add))
+			if (name == "sx" || name == "sy") && *v == 0 {
+				return &InvalidValueError{Field: "deltas." + name, Reason: "scale must not be zero"}
+			}
+		}
+		transforms[id] = t.Rounded(document.Precision())
+		previous[id] = obj.Transform
+	}
+	op.Transforms = transforms
+	op.PreviousTransforms = previous
+	return nil
+}
+
+// applyNudge sets the transforms a prepared object.nudge resolved to.
+func (ds *DocumentState) applyNudge(op Operation) error {
+	if op.Transforms == nil {
+		return fmt.Errorf("object.nudge has not been prepared")
+	}
+	return ds.applyResetTransform(op)
+}
@@ -0,0 +1,194 @@
+package collab
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// nudge is an object.nudge of objectIDs by deltas, given as JSON.
+func nudge(deltas string, objectIDs ...string) *Operation {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(deltas), &raw); err != nil {
+		panic(err)
+	}
+	return &Operation{Type: "object.nudge", ObjectIDs: objectIDs, Deltas: raw}
+}
+
+func TestNudgeUnits(t *testing.T) {
+	tests := []struct {
+		deltas string
+		want   document.Transform
+	}{
+		{`{"x":10}`, document.Transform{X: 10, SX: 1, SY: 1}},
+		{`{"x":"10px","transform.y":"-4.5px"}`, document.Transform{X: 10, Y: -4.5, SX: 1, SY: 1}},
+		{`{"r":"-15°"}`, document.Transform{R: -15, SX: 1, SY: 1}},
+		{`{"r":"30deg","skewX":" 5 deg "}`, document.Transform{R: 30, SkewX: 5, SX: 1, SY: 1}},
+		{`{"r":"0.5rad"}`, document.Transform{R: document.Round(0.5*180/math.Pi, document.Precision()), SX: 1, SY: 1}},
+		{`{"sx":"200%","sy":"50%"}`, document.Transform{SX: 2, SY: 0.5}},
+		{`{"sx":0.25,"ax":"3"}`, document.Transform{SX: 1.25, SY: 1, AX: 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.deltas, func(t *testing.T) {
+			ds, rectID := rectState(t)
+			apply(t, ds, nudge(tt.deltas, rectID), "user")
+			if got := ds.doc.Objects[rectID].Transform; got != tt.want {
+				t.Errorf("transform %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNudgeRejected(t *testing.T) {
+	tests := []struct {
+		deltas string
+		field  string // Empty when the error isn't about one field
+	}{
+		{`{"opacity":0.5}`, "deltas.opacity"},
+		{`{"style.fill":"#fff"}`, "deltas.style.fill"},
+		{`{"visible":1}`, "deltas.visible"},
+		{`{"x":"10%"}`, "deltas.x"},
+		{`{"sx":"10px"}`, "deltas.sx"},
+		{`{"r":"1rad","x":"far"}`, "deltas.x"},
+		{`{"x":null}`, "deltas.x"},
+		{`{"x":1,"transform.x":2}`, "deltas.x"},
+		{`{"sx":"0%"}`, "deltas.sx"},
+		{`{"sy":-1}`, "deltas.sy"},
+		{`{}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.deltas, func(t *testing.T) {
+			ds, rectID := rectState(t)
+			op := nudge(tt.deltas, rectID)
+			op.ID = typeid.NewOpID()
+			result, applied := applySubmitted(ds, op, "user", OpPolicy{})
+			if applied || result.Nack == nil {
+				t.Fatalf("answered %+v, want a nack", result)
+			}
+			if result.Nack.Field != tt.field {
+				t.Errorf("nack field %q, want %q", result.Nack.Field, tt.field)
+			}
+			if got := ds.doc.Objects[rectID].Transform; got != (document.Transform{SX: 1, SY: 1}) {
+				t.Errorf("transform %+v after a rejected nudge", got)
+			}
+		})
+	}
+
+	ds, rectID := rectState(t)
+	if _, err := ds.ApplyOperation(nudge(`{"x":1}`, rectID, typeid.NewObjectID()), "user"); err == nil {
+		t.Error("nudging a missing object applied")
+	}
+	if got := ds.doc.Objects[rectID].Transform.X; got != 0 {
+		t.Errorf("x %v after a failed nudge, want the selection untouched", got)
+	}
+}
+
+func TestNudgeSelectionUndo(t *testing.T) {
+	ds, rectID := rectState(t)
+	rootID := *ds.doc.Objects[rectID].Parent
+	otherID := addRect(ds, rootID, false)
+	apply(t, ds, &Operation{Type: "object.transform", ObjectID: otherID, Transform: json.RawMessage(`{"x":100,"sx":2}`)}, "user")
+
+	apply(t, ds, nudge(`{"x":"10px","sx":"150%"}`, rectID, otherID), "user")
+	if got := ds.doc.Objects[rectID].Transform; got.X != 10 || got.SX != 1.5 {
+		t.Errorf("rect %+v, want x 10 and sx 1.5", got)
+	}
+	if got := ds.doc.Objects[otherID].Transform; got.X != 110 || got.SX != 3 {
+		t.Errorf("other %+v, want x 110 and sx 3", got)
+	}
+
+	// Undo puts every nudged object back in one step
+	undo(t, ds, "user")
+	if got := ds.doc.Objects[rectID].Transform; got != (document.Transform{SX: 1, SY: 1}) {
+		t.Errorf("rect %+v after undo", got)
+	}
+	if got := ds.doc.Objects[otherID].Transform; got.X != 100 || got.SX != 2 {
+		t.Errorf("other %+v after undo, want x 100 and sx 2", got)
+	}
+
+	// Results clamp to the validation bounds
+	apply(t, ds, nudge(`{"x":9e8}`, rectID), "user")
+	apply(t, ds, nudge(`{"x":9e8}`, rectID), "user")
+	if got := ds.doc.Objects[rectID].Transform.X; got != maxValueMagnitude {
+		t.Errorf("x %v, want clamped to %v", got, maxValueMagnitude)
+	}
+}
+
+// TestNudgeComposesWithConcurrentEdits checks a nudge sent against a stale
+// view applies to whatever the server holds when it arrives, where an
+// absolute edit from the same view clobbers the other user's change.
+func TestNudgeComposesWithConcurrentEdits(t *testing.T) {
+	// Both users see x = 0. Alice moves the rect to 100; Bob, not yet
+	// seeing it, nudges it right by 10.
+	ds, rectID := rectState(t)
+	apply(t, ds, &Operation{Type: "object.transform", ObjectID: rectID, Transform: json.RawMessage(`{"x":100}`)}, "alice")
+	apply(t, ds, nudge(`{"x":10}`, rectID), "bob")
+	if got := ds.doc.Objects[rectID].Transform.X; got != 110 {
+		t.Errorf("x = %v, want Alice's move plus Bob's nudge", got)
+	}
+
+	// The absolute edit Bob might have sent instead, 0 + 10, loses Alice's
+	ds, rectID = rectState(t)
+	apply(t, ds, &Operation{Type: "object.transform", ObjectID: rectID, Transform: json.RawMessage(`{"x":100}`)}, "alice")
+	apply(t, ds, &Operation{Type: "object.transform", ObjectID: rectID, Transform: json.RawMessage(`{"x":10}`)}, "bob")
+	if got := ds.doc.Objects[rectID].Transform.X; got != 10 {
+		t.Errorf("x = %v, want Bob's absolute edit to overwrite", got)
+	}
+
+	// In the other order the absolute edit lands on top of the nudge
+	ds, rectID = rectState(t)
+	apply(t, ds, nudge(`{"x":10}`, rectID), "bob")
+	apply(t, ds, &Operation{Type: "object.transform", ObjectID: rectID, Transform: json.RawMessage(`{"x":100}`)}, "alice")
+	if got := ds.doc.Objects[rectID].Transform.X; got != 100 {
+		t.Errorf("x = %v, want Alice's later move", got)
+	}
+
+	// Nudges of different properties and of the same one both compose
+	ds, rectID = rectState(t)
+	apply(t, ds, &Operation{Type: "object.transform", ObjectID: rectID, Transform: json.RawMessage(`{"y":40,"r":90}`)}, "alice")
+	apply(t, ds, nudge(`{"x":5}`, rectID), "bob")
+	apply(t, ds, nudge(`{"x":-2,"r":"-15°"}`, rectID), "carol")
+	if got := ds.doc.Objects[rectID].Transform; got.X != 3 || got.Y != 40 || got.R != 75 {
+		t.Errorf("transform %+v, want x 3, y 40 and r 75", got)
+	}
+
+	// Undoing Bob's nudge restores what it replaced, as resetTransform
+	// undo does
+	ds, rectID = rectState(t)
+	apply(t, ds, nudge(`{"x":10}`, rectID), "bob")
+	if inverse := undo(t, ds, "bob"); inverse.Type != "object.resetTransform" {
+		t.Errorf("nudge undone by %s", inverse.Type)
+	}
+	if got := ds.doc.Objects[rectID].Transform.X; got != 0 {
+		t.Errorf("x = %v after undo, want 0", got)
+	}
+}
+
+func TestNudgeReplays(t *testing.T) {
+	ds, rectID := rectState(t)
+	before, _, err := ds.SnapshotDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+	op := nudge(`{"x":"12px","r":"45deg"}`, rectID)
+	apply(t, ds, op, "user")
+	if got := op.Transforms[rectID]; got != ds.doc.Objects[rectID].Transform {
+		t.Fatalf("nudge resolved to %+v, want the rect's new transform", op.Transforms)
+	}
+
+	// The logged op carries what it resolved to, so replaying it gives the
+	// same transform
+	replayed := NewDocumentState(before)
+	if err := replayed.applyNudge(*op); err != nil {
+		t.Fatal(err)
+	}
+	if got := replayed.doc.Objects[rectID].Transform; got != ds.doc.Objects[rectID].Transform {
+		t.Errorf("replayed %+v, want %+v", got, ds.doc.Objects[rectID].Transform)
+	}
+	if err := replayed.applyNudge(Operation{Type: "object.nudge", ObjectID: rectID}); err == nil {
+		t.Error("unprepared nudge applied")
+	}
+}
@@ -2,29 +2,73 @@ package collab
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"reflect"
+	"slices"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/inamate/inamate/backend-go/internal/color"
 	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
 )
 
+// ErrNoChange is returned when an operation would leave the document as it is.
+// Such operations are acknowledged but not sequenced or broadcast.
+var ErrNoChange = errors.New("operation has no effect")
+
+// ConflictError is returned when an operation's base no longer matches the
+// server state. Current carries the server's value so the client can rebase.
+type ConflictError struct {
+	Current Operation
+}
+
+func (e *ConflictError) Error() string {
+	return "conflict: base is stale"
+}
+
+// defaultLogWindow is how many operations a room's log keeps once they are
+// saved. It covers maxResyncOps, so a client the log can't bring up to date
+// would have been sent the whole document anyway.
+const defaultLogWindow = 1000
+
 // DocumentState holds the authoritative document state for a room
 type DocumentState struct {
-	mu        sync.RWMutex
-	doc       *document.InDocument
-	serverSeq int64
-	opLog     []Operation // Operation history for persistence
-	dirty     bool        // Has unsaved changes
+	mu           sync.RWMutex
+	doc          *document.InDocument
+	base         json.RawMessage // Document before the first op in opLog
+	baseSeq      int64           // Server sequence base is at
+	serverSeq    int64
+	opLog        []RecordedOperation // Recent operation history for persistence, resync, undo and recordings
+	logWindow    int                 // Persisted operations opLog keeps
+	persistedSeq int64               // Last sequence written to the op store
+	dirty        bool                // Has unsaved changes
+	modifiedSeq  map[string]int64    // Last server sequence that touched each object/keyframe
 }
 
 // NewDocumentState creates a new document state from an initial document
 func NewDocumentState(doc *document.InDocument) *DocumentState {
+	return NewDocumentStateAt(doc, 0)
+}
+
+// NewDocumentStateAt creates a document state from a document saved at server
+// sequence seq, so the next operation applied is seq+1.
+func NewDocumentStateAt(doc *document.InDocument, seq int64) *DocumentState {
+	// Recordings replay opLog over base; if it can't be encoded (nil) they
+	// are unavailable, which shouldn't block editing
+	base, _ := json.Marshal(doc)
 	return &DocumentState{
-		doc:       doc,
-		serverSeq: 0,
-		opLog:     make([]Operation, 0),
-		dirty:     false,
+		doc:          doc,
+		base:         base,
+		baseSeq:      seq,
+		serverSeq:    seq,
+		opLog:        make([]RecordedOperation, 0),
+		logWindow:    defaultLogWindow,
+		persistedSeq: seq,
+		dirty:        false,
+		modifiedSeq:  make(map[string]int64),
 	}
 }
 
@@ -35,11 +79,113 @@ func (ds *DocumentState) IsDirty() bool {
 	return ds.dirty
 }
 
-// MarkClean marks the document as saved
-func (ds *DocumentState) MarkClean() {
+// MarkSaved records that the document as it stood at seq was saved. It stays
+// dirty if operations were applied since.
+func (ds *DocumentState) MarkSaved(seq int64) {
 	ds.mu.Lock()
 	defer ds.mu.Unlock()
-	ds.dirty = false
+	ds.dirty = ds.serverSeq > seq
+	ds.persistedSeq = max(ds.persistedSeq, seq)
+	ds.trimLogLocked()
+}
+
+// SnapshotDocument returns a deep copy of the current document along with the
+// server sequence it is at.
+func (ds *DocumentState) SnapshotDocument() (*document.InDocument, int64, error) {
+	ds.mu.RLock()
+	raw, err := json.Marshal(ds.doc)
+	seq := ds.serverSeq
+	ds.mu.RUnlock()
+	if err != nil {
+		return nil, 0, fmt.Errorf("encode document: %w", err)
+	}
+	var doc document.InDocument
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, 0, fmt.Errorf("decode document: %w", err)
+	}
+	return &doc, seq, nil
+}
+
+// EncodeDocument returns the current document as JSON along with the server
+// sequence it is at.
+func (ds *DocumentState) EncodeDocument() (json.RawMessage, int64, error) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	raw, err := json.Marshal(ds.doc)
+	if err != nil {
+		return nil, 0, fmt.Errorf("encode document: %w", err)
+	}
+	return raw, ds.serverSeq, nil
+}
+
+// UnpersistedOps returns the logged operations not yet written to the op
+// store, oldest first.
+func (ds *DocumentState) UnpersistedOps() []RecordedOperation {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	return append([]RecordedOperation(nil), ds.opLog[ds.persistedSeq-ds.baseSeq:]...)
+}
+
+// unpersistedCount returns how many operations are waiting to be persisted.
+func (ds *DocumentState) unpersistedCount() int64 {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	return ds.serverSeq - ds.persistedSeq
+}
+
+// MarkPersisted records that the operations through seq were written to the
+// op store.
+func (ds *DocumentState) MarkPersisted(seq int64) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.persistedSeq = max(ds.persistedSeq, seq)
+	ds.trimLogLocked()
+}
+
+// trimLogLocked drops persisted operations from the front of the log once it
+// holds twice logWindow of them, keeping the latest logWindow, and moves base
+// forward past what was dropped. Operations not yet persisted are always
+// kept. Clients further behind than the log reaches resync with the whole
+// document, and the dropped operations can no longer be undone or recorded.
+func (ds *DocumentState) trimLogLocked() {
+	persisted := int(ds.persistedSeq - ds.baseSeq)
+	if persisted <= 2*ds.logWindow {
+		return
+	}
+	drop := persisted - ds.logWindow
+	ds.base = replayBase(ds.base, ds.opLog[:drop])
+	ds.baseSeq += int64(drop)
+	ds.opLog = append([]RecordedOperation(nil), ds.opLog[drop:]...)
+}
+
+// replayBase returns base with ops applied, or nil if base is unavailable
+// or they don't replay over it, which makes recordings unavailable.
+func replayBase(base json.RawMessage, ops []RecordedOperation) json.RawMessage {
+	if base == nil {
+		return nil
+	}
+	var doc document.InDocument
+	if err := json.Unmarshal(base, &doc); err != nil {
+		return nil
+	}
+	replay := NewDocumentState(&doc)
+	for _, rec := range ops {
+		if err := replay.ApplyRecorded(rec); err != nil {
+			return nil
+		}
+	}
+	raw, _, err := replay.EncodeDocument()
+	if err != nil {
+		return nil
+	}
+	return raw
+}
+
+// ServerSeq returns the sequence number of the last applied operation
+func (ds *DocumentState) ServerSeq() int64 {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	return ds.serverSeq
 }
 
 // GetDocument returns a copy of the current document
@@ -50,20 +196,509 @@ func (ds *DocumentState) GetDocument() *document.InDocument {
 	return ds.doc
 }
 
-// ApplyOperation applies an operation to the document and returns the server sequence
-func (ds *DocumentState) ApplyOperation(op Operation) (int64, error) {
+// CopyDocument returns a deep copy of the current document, which the caller
+// may mutate.
+func (ds *DocumentState) CopyDocument() (*document.InDocument, error) {
+	ds.mu.RLock()
+	raw, err := json.Marshal(ds.doc)
+	ds.mu.RUnlock()
+	if err != nil {
+		return nil, fmt.Errorf("encode document: %w", err)
+	}
+	var doc document.InDocument
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("decode document: %w", err)
+	}
+	return &doc, nil
+}
+
+// ApplyOperation applies an operation submitted by userID to the document and
+// returns the server sequence. Fields the server assigns (such as generated
+// IDs) are filled in on op, so the caller can broadcast exactly what was
+// applied.
+func (ds *DocumentState) ApplyOperation(op *Operation, userID string) (int64, error) {
+	if err := ValidateOperation(op); err != nil {
+		return 0, err
+	}
+	if err := ValidateValues(op); err != nil {
+		return 0, err
+	}
+	roundValues(op, document.Precision())
+
 	ds.mu.Lock()
 	defer ds.mu.Unlock()
 
-	if err := ds.applyOperationLocked(op); err != nil {
+	if err := ds.applyCheckedLocked(op); err != nil {
 		return 0, err
 	}
+	return ds.recordLocked(op, userID), nil
+}
+
+// ApplyBatch applies ops submitted together by userID in order, under one
+// lock, all or nothing, and returns the server sequence of each; zero for
+// operations that changed nothing (ErrNoChange). If an operation fails, the
+// document is restored to how it was before the batch and failed is its
+// index. As with ApplyOperation, server-assigned fields are filled in on ops.
+func (ds *DocumentState) ApplyBatch(ops []Operation, userID string) (seqs []int64, failed int, err error) {
+	for i := range ops {
+		if err := ValidateOperation(&ops[i]); err != nil {
+			return nil, i, err
+		}
+		if err := ValidateValues(&ops[i]); err != nil {
+			return nil, i, err
+		}
+		roundValues(&ops[i], document.Precision())
+	}
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	// Later operations may depend on earlier ones, so the batch can only be
+	// checked by applying it; keep what's needed to undo that
+	saved, err := json.Marshal(ds.doc)
+	if err != nil {
+		return nil, 0, fmt.Errorf("encode document: %w", err)
+	}
+	savedSeq, savedLog, savedDirty := ds.serverSeq, len(ds.opLog), ds.dirty
+	savedModified := make(map[string]int64)
+
+	seqs = make([]int64, len(ops))
+	for i := range ops {
+		err := ds.applyCheckedLocked(&ops[i])
+		if errors.Is(err, ErrNoChange) {
+			continue
+		}
+		if err != nil {
+			var doc document.InDocument
+			if rerr := json.Unmarshal(saved, &doc); rerr != nil {
+				return nil, i, fmt.Errorf("restore document: %w", rerr)
+			}
+			ds.doc = &doc
+			ds.serverSeq, ds.opLog, ds.dirty = savedSeq, ds.opLog[:savedLog], savedDirty
+			for id, seq := range savedModified {
+				if seq == 0 {
+					delete(ds.modifiedSeq, id)
+				} else {
+					ds.modifiedSeq[id] = seq
+				}
+			}
+			return nil, i, err
+		}
+		for _, id := range modifiedIDs(&ops[i]) {
+			if _, ok := savedModified[id]; !ok {
+				savedModified[id] = ds.modifiedSeq[id]
+			}
+		}
+		seqs[i] = ds.recordLocked(&ops[i], userID)
+	}
+	return seqs, 0, nil
+}
 
+// applyCheckedLocked checks a validated operation against the document,
+// fills in its server-assigned fields, checks it leaves the document well
+// formed and applies it.
+func (ds *DocumentState) applyCheckedLocked(op *Operation) error {
+	if err := ds.checkBaseLocked(op); err != nil {
+		return err
+	}
+	if err := ds.checkUndoLocked(op); err != nil {
+		return err
+	}
+	if err := ds.prepareOperationLocked(op); err != nil {
+		return err
+	}
+	if err := ds.validateStructureLocked(op); err != nil {
+		return err
+	}
+	ds.captureUndoLocked(op)
+	return ds.applyOperationLocked(*op)
+}
+
+// recordLocked sequences an applied operation and logs it, returning its
+// server sequence.
+func (ds *DocumentState) recordLocked(op *Operation, userID string) int64 {
 	ds.serverSeq++
-	ds.opLog = append(ds.opLog, op)
+	ds.opLog = append(ds.opLog, RecordedOperation{
+		Seq:       ds.serverSeq,
+		UserID:    userID,
+		Timestamp: GetServerTimestamp(),
+		Operation: *op,
+	})
 	ds.dirty = true
+	for _, id := range modifiedIDs(op) {
+		ds.modifiedSeq[id] = ds.serverSeq
+	}
+	return ds.serverSeq
+}
+
+// modifiedIDs returns the IDs of the objects and keyframes op touches, as
+// tracked for BaseSeq checks.
+func modifiedIDs(op *Operation) []string {
+	var ids []string
+	for _, id := range append([]string{op.ObjectID, op.KeyframeID}, op.ObjectIDs...) {
+		if id != "" {
+			ids = append(ids, id)
+		}
+	}
+	for id := range op.Transforms {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// checkBaseLocked rejects an operation whose BaseSeq or BaseValue no longer
+// matches the server state. Op types without a single comparable target
+// ignore both fields.
+func (ds *DocumentState) checkBaseLocked(op *Operation) error {
+	if op.BaseSeq == nil && op.BaseValue == nil {
+		return nil
+	}
+	if handled, err := ds.transformTransformOp(op); handled {
+		return err
+	}
+
+	target, current, ok := ds.currentValueLocked(op)
+	if !ok {
+		// Unsupported type or missing target; applying reports the latter
+		return nil
+	}
+
+	stale := op.BaseSeq != nil && ds.modifiedSeq[target] > *op.BaseSeq
+	if op.BaseValue != nil && !baseMatches(op.BaseValue, current) {
+		stale = true
+	}
+	if !stale {
+		return nil
+	}
+
+	seq := ds.modifiedSeq[target]
+	conflict := Operation{
+		ID:         op.ID,
+		Type:       op.Type,
+		ObjectID:   op.ObjectID,
+		KeyframeID: op.KeyframeID,
+		BaseSeq:    &seq,
+	}
+	switch op.Type {
+	case "object.transform":
+		conflict.Transform = current
+	case "object.style":
+		conflict.Style = current
+	case "object.data":
+		conflict.Data = current
+	case "keyframe.update":
+		conflict.Value = current
+	}
+	return &ConflictError{Current: conflict}
+}
+
+// transformTransformOp rebases an object.transform made against the document
+// as of op.BaseSeq onto the operations applied to its object since, rather
+// than letting it overwrite them. Each field is read as a delta, its value in
+// Transform less its value in Previous, and added to the object's current
+// value, so when two users drag the same object both drags move it. Fields
+// missing from Previous are set as sent.
+//
+// It reports whether op is one it handles: an object.transform with BaseSeq
+// and Previous but no BaseValue, which asks for an exact match instead. If
+// the object was deleted since BaseSeq, the op can't be rebased and the
+// ConflictError carries the object.delete.
+func (ds *DocumentState) transformTransformOp(op *Operation) (bool, error) {
+	if op.Type != "object.transform" || op.BaseSeq == nil || op.BaseValue != nil || op.Previous == nil {
+		return false, nil
+	}
+	obj, ok := ds.doc.Objects[op.ObjectID]
+	if !ok {
+		if deletion := ds.deletionSinceLocked(op.ObjectID, *op.BaseSeq); deletion != nil {
+			return true, &ConflictError{Current: *deletion}
+		}
+		// Applying reports the missing object
+		return true, nil
+	}
+	if ds.modifiedSeq[op.ObjectID] <= *op.BaseSeq {
+		return true, nil
+	}
+
+	var changes, previous map[string]float64
+	if err := json.Unmarshal(op.Transform, &changes); err != nil {
+		return true, fmt.Errorf("invalid transform: %w", err)
+	}
+	if err := json.Unmarshal(op.Previous, &previous); err != nil {
+		return true, fmt.Errorf("invalid previous transform: %w", err)
+	}
+	current := transformFields(obj.Transform)
+	for field, value := range changes {
+		base, hasBase := previous[field]
+		now, known := current[field]
+		if hasBase && known {
+			changes[field] = document.RoundField(field, now+value-base, document.Precision())
+		}
+	}
+	rebased, err := json.Marshal(changes)
+	if err != nil {
+		return true, fmt.Errorf("encode transform: %w", err)
+	}
 
-	return ds.serverSeq, nil
+	// Logged and broadcast as made against the latest state, so replaying it
+	// doesn't rebase it again
+	seq := ds.serverSeq
+	op.Transform = rebased
+	op.BaseSeq = &seq
+	return true, nil
+}
+
+// deletionSinceLocked returns the logged object.delete that removed objectID
+// after server sequence seq, or nil if there is none.
+func (ds *DocumentState) deletionSinceLocked(objectID string, seq int64) *Operation {
+	for i := len(ds.opLog) - 1; i >= 0 && ds.opLog[i].Seq > seq; i-- {
+		if op := ds.opLog[i].Operation; op.Type == "object.delete" && op.ObjectID == objectID {
+			return &op
+		}
+	}
+	return nil
+}
+
+// transformFields returns t keyed by its JSON field names.
+func transformFields(t document.Transform) map[string]float64 {
+	return map[string]float64{
+		"x":     t.X,
+		"y":     t.Y,
+		"sx":    t.SX,
+		"sy":    t.SY,
+		"r":     t.R,
+		"ax":    t.AX,
+		"ay":    t.AY,
+		"skewX": t.SkewX,
+		"skewY": t.SkewY,
+	}
+}
+
+// currentValueLocked returns the target ID and current server value an
+// operation's base is compared against.
+func (ds *DocumentState) currentValueLocked(op *Operation) (string, json.RawMessage, bool) {
+	var value interface{}
+	switch op.Type {
+	case "object.transform", "object.style", "object.data":
+		obj, ok := ds.doc.Objects[op.ObjectID]
+		if !ok {
+			return "", nil, false
+		}
+		switch op.Type {
+		case "object.transform":
+			value = obj.Transform
+		case "object.style":
+			value = obj.Style
+		default:
+			return op.ObjectID, obj.Data, true
+		}
+	case "keyframe.update":
+		kf, ok := ds.doc.Keyframes[op.KeyframeID]
+		if !ok {
+			return "", nil, false
+		}
+		return op.KeyframeID, kf.Value, true
+	default:
+		return "", nil, false
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", nil, false
+	}
+	return op.ObjectID, data, true
+}
+
+// baseMatches reports whether base agrees with current. When both are JSON
+// objects only the fields present in base are compared.
+func baseMatches(base, current json.RawMessage) bool {
+	var b, c interface{}
+	if json.Unmarshal(base, &b) != nil || json.Unmarshal(current, &c) != nil {
+		return false
+	}
+	bm, bIsMap := b.(map[string]interface{})
+	cm, cIsMap := c.(map[string]interface{})
+	if !bIsMap || !cIsMap {
+		return reflect.DeepEqual(b, c)
+	}
+	for k, v := range bm {
+		if !reflect.DeepEqual(v, cm[k]) {
+			return false
+		}
+	}
+	return true
+}
+
+// prepareOperationLocked fills in server-assigned fields before an operation
+// is applied, so the logged and broadcast operation replays identically.
+func (ds *DocumentState) prepareOperationLocked(op *Operation) error {
+	switch op.Type {
+	case "animation.copy":
+		if err := ds.assignAnimationCopyIDs(op); err != nil {
+			return err
+		}
+		op.PreviousAnimation = ds.snapshotAnimation(op.TimelineID, op.ObjectIDs)
+	case "animation.restore":
+		if op.Animation != nil {
+			op.PreviousAnimation = ds.snapshotAnimation(op.TimelineID, op.Animation.ObjectIDs)
+		}
+	case "path.movePoint", "path.deletePoint", "path.setClosed":
+		return ds.preparePathEditLocked(op)
+	case "object.detachSymbol":
+		return ds.prepareDetachSymbolLocked(op)
+	case "object.duplicate":
+		return ds.prepareDuplicateLocked(op)
+	case "object.nudge":
+		return ds.prepareNudgeLocked(op)
+	case "timeline.removeTime":
+		return ds.prepareRemoveTimeLocked(op)
+	case "object.resetTransform":
+		targets, err := ds.resetTargetsLocked(*op)
+		if err != nil {
+			return err
+		}
+		op.PreviousTransforms = make(map[string]document.Transform, len(targets))
+		for id := range targets {
+			op.PreviousTransforms[id] = ds.doc.Objects[id].Transform
+		}
+	case "object.create":
+		// Broadcast the filled-in object so every client stores the same one
+		object, err := document.FillObjectDefaults(op.Object)
+		if err != nil {
+			return err
+		}
+		op.Object = object
+	case "object.style", "keyframe.add", "keyframe.update":
+		return ds.normalizeColorsLocked(op)
+	}
+	return nil
+}
+
+// colorProperties are the style properties whose values are colors.
+var colorProperties = map[string]bool{
+	"style.fill":   true,
+	"style.stroke": true,
+}
+
+// normalizeColorsLocked rewrites fill/stroke values on op to canonical
+// "#rrggbbaa" so every client stores and interpolates the same value.
+// Unparseable colors reject the operation.
+func (ds *DocumentState) normalizeColorsLocked(op *Operation) error {
+	var err error
+	switch op.Type {
+	case "object.style":
+		if op.Style, err = normalizeFields(op.Style, "fill", "stroke"); err != nil {
+			return err
+		}
+		op.Style, err = normalizeGradientField(op.Style, "fillGradient")
+		return err
+	case "keyframe.add":
+		if !colorProperties[ds.doc.Tracks[op.TrackID].Property] {
+			return nil
+		}
+		if op.Keyframe != nil {
+			op.Keyframe, err = normalizeFields(op.Keyframe, "value")
+			return err
+		}
+		op.Value, err = normalizeColorValue("value", op.Value)
+		return err
+	case "keyframe.update":
+		if !colorProperties[ds.keyframeTrackLocked(op.TrackID, op.KeyframeID).Property] {
+			return nil
+		}
+		if op.Changes != nil {
+			op.Changes, err = normalizeFields(op.Changes, "value")
+			return err
+		}
+		op.Value, err = normalizeColorValue("value", op.Value)
+		return err
+	}
+	return nil
+}
+
+// keyframeTrackLocked returns the track owning keyframeID, preferring trackID
+// when the client supplied it.
+func (ds *DocumentState) keyframeTrackLocked(trackID, keyframeID string) document.Track {
+	if track, ok := ds.doc.Tracks[trackID]; ok {
+		return track
+	}
+	for _, track := range ds.doc.Tracks {
+		for _, id := range track.Keys {
+			if id == keyframeID {
+				return track
+			}
+		}
+	}
+	return document.Track{}
+}
+
+// normalizeFields normalizes the named color fields of a JSON object,
+// leaving every other field untouched.
+func normalizeFields(raw json.RawMessage, names ...string) (json.RawMessage, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		// Left for the apply step to report
+		return raw, nil
+	}
+	changed := false
+	for _, name := range names {
+		v, ok := fields[name]
+		if !ok {
+			continue
+		}
+		normalized, err := normalizeColorValue(name, v)
+		if err != nil {
+			return nil, err
+		}
+		fields[name] = normalized
+		changed = true
+	}
+	if !changed {
+		return raw, nil
+	}
+	return json.Marshal(fields)
+}
+
+// normalizeGradientField normalizes the stop colors of the named gradient
+// field of a JSON object, leaving every other field untouched. Malformed
+// gradients and colors are left for validation to report against the field.
+func normalizeGradientField(raw json.RawMessage, name string) (json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if raw == nil || json.Unmarshal(raw, &fields) != nil {
+		return raw, nil
+	}
+	var g *document.Gradient
+	if v, ok := fields[name]; !ok || json.Unmarshal(v, &g) != nil || g == nil {
+		return raw, nil
+	}
+	for i, stop := range g.Stops {
+		normalized, err := color.Normalize(stop.Color)
+		if err != nil {
+			return raw, nil
+		}
+		g.Stops[i].Color = normalized
+	}
+	var err error
+	if fields[name], err = json.Marshal(g); err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
+
+// normalizeColorValue normalizes a JSON string color. Non-string values are
+// returned unchanged.
+func normalizeColorValue(name string, raw json.RawMessage) (json.RawMessage, error) {
+	var s string
+	if raw == nil || json.Unmarshal(raw, &s) != nil {
+		return raw, nil
+	}
+	normalized, err := color.Normalize(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return json.Marshal(normalized)
 }
 
 // applyOperationLocked applies the operation without locking (caller must hold lock)
@@ -79,14 +714,26 @@ func (ds *DocumentState) applyOperationLocked(op Operation) error {
 		return ds.applyCreate(op)
 	case "object.reparent":
 		return ds.applyReparent(op)
+	case "object.reorder":
+		return ds.applyReorder(op)
 	case "object.visibility":
 		return ds.applyVisibility(op)
 	case "object.locked":
 		return ds.applyLocked(op)
+	case "object.solo":
+		return ds.applySolo(op)
 	case "object.data":
 		return ds.applyData(op)
+	case "object.setMeta":
+		return ds.applyMeta(op)
+	case "object.resetTransform":
+		return ds.applyResetTransform(op)
+	case "object.nudge":
+		return ds.applyNudge(op)
 	case "timeline.update":
 		return ds.applyTimelineUpdate(op)
+	case "timeline.insertTime", "timeline.removeTime":
+		return ds.applyTimeShift(op)
 	case "scene.update":
 		return ds.applySceneUpdate(op)
 	case "scene.create":
@@ -95,16 +742,44 @@ func (ds *DocumentState) applyOperationLocked(op Operation) error {
 		return ds.applySceneDelete(op)
 	case "project.rename":
 		return ds.applyProjectRename(op)
+	case "project.update":
+		return ds.applyProjectUpdate(op)
+	case "project.setRootTimeline":
+		return ds.applySetRootTimeline(op)
+	case "asset.update":
+		return ds.applyAssetUpdate(op)
 	case "track.create":
 		return ds.applyTrackCreate(op)
 	case "track.delete":
 		return ds.applyTrackDelete(op)
+	case "track.reverse":
+		return ds.applyTrackReverse(op)
 	case "keyframe.add":
 		return ds.applyKeyframeAdd(op)
 	case "keyframe.update":
 		return ds.applyKeyframeUpdate(op)
 	case "keyframe.delete":
 		return ds.applyKeyframeDelete(op)
+	case "keyframe.convertEasingToBezier":
+		return ds.applyKeyframeConvertEasing(op)
+	case "animation.copy":
+		return ds.applyAnimationCopy(op)
+	case "animation.restore":
+		return ds.applyAnimationRestore(op)
+	case "easingPreset.create":
+		return ds.applyEasingPresetCreate(op)
+	case "easingPreset.update":
+		return ds.applyEasingPresetUpdate(op)
+	case "easingPreset.delete":
+		return ds.applyEasingPresetDelete(op)
+	case "path.insertPoint", "path.movePoint", "path.deletePoint", "path.setClosed":
+		return ds.applyPathEdit(op)
+	case "object.detachSymbol":
+		return ds.applyDetachSymbol(op)
+	case "object.restoreSymbol":
+		return ds.applyRestoreSymbol(op)
+	case "object.duplicate":
+		return ds.applyDuplicate(op)
 	default:
 		return fmt.Errorf("unknown operation type: %s", op.Type)
 	}
@@ -180,6 +855,16 @@ func (ds *DocumentState) applyStyle(op Operation) error {
 	if v, ok := changes["opacity"].(float64); ok {
 		obj.Style.Opacity = v
 	}
+	if v, ok := changes["fillGradient"]; ok {
+		// null clears the gradient, leaving the solid fill
+		var fields struct {
+			FillGradient *document.Gradient `json:"fillGradient"`
+		}
+		if err := json.Unmarshal(op.Style, &fields); err != nil && v != nil {
+			return fmt.Errorf("invalid fillGradient: %w", err)
+		}
+		obj.Style.FillGradient = fields.FillGradient
+	}
 
 	ds.doc.Objects[op.ObjectID] = obj
 	return nil
@@ -302,7 +987,48 @@ func (ds *DocumentState) applyReparent(op Operation) error {
 	return nil
 }
 
+// applyReorder moves an object so it ends up at NewIndex among its parent's
+// children. The object is taken out before it is put back, so moving it down
+// inserts it at NewIndex of the shortened list, past the siblings that shift
+// up into its old place.
+func (ds *DocumentState) applyReorder(op Operation) error {
+	obj, ok := ds.doc.Objects[op.ObjectID]
+	if !ok {
+		return fmt.Errorf("object not found: %s", op.ObjectID)
+	}
+	if obj.Parent == nil {
+		return fmt.Errorf("object has no parent: %s", op.ObjectID)
+	}
+	parent, ok := ds.doc.Objects[*obj.Parent]
+	if !ok {
+		return fmt.Errorf("parent not found: %s", *obj.Parent)
+	}
+	from := slices.Index(parent.Children, op.ObjectID)
+	if from < 0 {
+		return fmt.Errorf("object %s is not among its parent's children", op.ObjectID)
+	}
+	if op.NewIndex < 0 || op.NewIndex >= len(parent.Children) {
+		return fmt.Errorf("index %d out of range for %d children", op.NewIndex, len(parent.Children))
+	}
+	if from == op.NewIndex {
+		return ErrNoChange
+	}
+
+	children := slices.Delete(slices.Clone(parent.Children), from, from+1)
+	parent.Children = slices.Insert(children, op.NewIndex, op.ObjectID)
+	ds.doc.Objects[*obj.Parent] = parent
+	return nil
+}
+
 func (ds *DocumentState) applyVisibility(op Operation) error {
+	if op.States != nil || len(op.ObjectIDs) > 0 {
+		targets, err := bulkTargets(op, op.Visible)
+		if err != nil {
+			return err
+		}
+		return ds.applyBoolStates(targets, func(obj *document.ObjectNode) *bool { return &obj.Visible })
+	}
+
 	obj, ok := ds.doc.Objects[op.ObjectID]
 	if !ok {
 		return fmt.Errorf("object not found: %s", op.ObjectID)
@@ -317,6 +1043,14 @@ func (ds *DocumentState) applyVisibility(op Operation) error {
 }
 
 func (ds *DocumentState) applyLocked(op Operation) error {
+	if op.States != nil || len(op.ObjectIDs) > 0 {
+		targets, err := bulkTargets(op, op.Locked)
+		if err != nil {
+			return err
+		}
+		return ds.applyBoolStates(targets, func(obj *document.ObjectNode) *bool { return &obj.Locked })
+	}
+
 	obj, ok := ds.doc.Objects[op.ObjectID]
 	if !ok {
 		return fmt.Errorf("object not found: %s", op.ObjectID)
@@ -330,6 +1064,158 @@ func (ds *DocumentState) applyLocked(op Operation) error {
 	return nil
 }
 
+// applySolo shows the given objects and hides every sibling of each. Siblings
+// are resolved against the authoritative document at apply time, so objects
+// added or moved concurrently are still covered.
+func (ds *DocumentState) applySolo(op Operation) error {
+	targets, err := ds.soloTargetsLocked(op)
+	if err != nil {
+		return err
+	}
+	return ds.applyBoolStates(targets, func(obj *document.ObjectNode) *bool { return &obj.Visible })
+}
+
+// soloTargetsLocked returns the visibility each object an object.solo
+// touches ends up with.
+func (ds *DocumentState) soloTargetsLocked(op Operation) (map[string]bool, error) {
+	if len(op.ObjectIDs) == 0 {
+		return nil, fmt.Errorf("objectIds is required")
+	}
+
+	solo := make(map[string]bool, len(op.ObjectIDs))
+	for _, id := range op.ObjectIDs {
+		solo[id] = true
+	}
+
+	targets := make(map[string]bool)
+	for _, id := range op.ObjectIDs {
+		obj, ok := ds.doc.Objects[id]
+		if !ok {
+			return nil, fmt.Errorf("object not found: %s", id)
+		}
+		targets[id] = true
+		if obj.Parent == nil {
+			continue
+		}
+		parent, ok := ds.doc.Objects[*obj.Parent]
+		if !ok {
+			continue
+		}
+		for _, siblingID := range parent.Children {
+			if !solo[siblingID] {
+				targets[siblingID] = false
+			}
+		}
+	}
+	return targets, nil
+}
+
+// bulkTargets resolves the per-object values for the bulk form of
+// object.visibility and object.locked: either an explicit states map, or a
+// list of objectIds that all receive the same value.
+func bulkTargets(op Operation, value *bool) (map[string]bool, error) {
+	if op.States != nil {
+		return op.States, nil
+	}
+	if value == nil {
+		return nil, fmt.Errorf("value is required with objectIds")
+	}
+	targets := make(map[string]bool, len(op.ObjectIDs))
+	for _, id := range op.ObjectIDs {
+		targets[id] = *value
+	}
+	return targets, nil
+}
+
+// applyBoolStates sets a boolean field on several objects atomically. Every
+// object is checked before any is modified, and ErrNoChange is returned when
+// all of them already hold their target value.
+func (ds *DocumentState) applyBoolStates(targets map[string]bool, field func(*document.ObjectNode) *bool) error {
+	changed := false
+	for id, value := range targets {
+		obj, ok := ds.doc.Objects[id]
+		if !ok {
+			return fmt.Errorf("object not found: %s", id)
+		}
+		if *field(&obj) != value {
+			changed = true
+		}
+	}
+	if !changed {
+		return ErrNoChange
+	}
+
+	for id, value := range targets {
+		obj := ds.doc.Objects[id]
+		*field(&obj) = value
+		ds.doc.Objects[id] = obj
+	}
+	return nil
+}
+
+// resetTransform returns the identity transform with t's anchor point, so
+// the object's pivot stays where it was.
+func resetTransform(t document.Transform) document.Transform {
+	return document.Transform{SX: 1, SY: 1, AX: t.AX, AY: t.AY}
+}
+
+// resetTargetsLocked returns the transform each object of an
+// object.resetTransform ends up with.
+func (ds *DocumentState) resetTargetsLocked(op Operation) (map[string]document.Transform, error) {
+	if op.Transforms != nil {
+		for id := range op.Transforms {
+			if _, ok := ds.doc.Objects[id]; !ok {
+				return nil, fmt.Errorf("object not found: %s", id)
+			}
+		}
+		return op.Transforms, nil
+	}
+
+	ids := op.ObjectIDs
+	if op.ObjectID != "" {
+		ids = append([]string{op.ObjectID}, ids...)
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("objectId or objectIds is required")
+	}
+	targets := make(map[string]document.Transform, len(ids))
+	for _, id := range ids {
+		obj, ok := ds.doc.Objects[id]
+		if !ok {
+			return nil, fmt.Errorf("object not found: %s", id)
+		}
+		targets[id] = resetTransform(obj.Transform)
+	}
+	return targets, nil
+}
+
+// applyResetTransform resets objects' base transforms atomically. Tracks are
+// left alone, so animated properties still override the base in playback.
+// ErrNoChange is returned when every object already has its target
+// transform.
+func (ds *DocumentState) applyResetTransform(op Operation) error {
+	targets, err := ds.resetTargetsLocked(op)
+	if err != nil {
+		return err
+	}
+	changed := false
+	for id, t := range targets {
+		if ds.doc.Objects[id].Transform != t {
+			changed = true
+		}
+	}
+	if !changed {
+		return ErrNoChange
+	}
+
+	for id, t := range targets {
+		obj := ds.doc.Objects[id]
+		obj.Transform = t
+		ds.doc.Objects[id] = obj
+	}
+	return nil
+}
+
 func (ds *DocumentState) applyData(op Operation) error {
 	obj, ok := ds.doc.Objects[op.ObjectID]
 	if !ok {
@@ -346,20 +1232,60 @@ func (ds *DocumentState) applyData(op Operation) error {
 		existing = make(map[string]interface{})
 	}
 
-	var changes map[string]interface{}
-	if err := json.Unmarshal(op.Data, &changes); err != nil {
-		return fmt.Errorf("invalid data: %w", err)
+	var changes map[string]interface{}
+	if err := json.Unmarshal(op.Data, &changes); err != nil {
+		return fmt.Errorf("invalid data: %w", err)
+	}
+
+	for k, v := range changes {
+		existing[k] = v
+	}
+
+	merged, err := json.Marshal(existing)
+	if err != nil {
+		return fmt.Errorf("failed to marshal data: %w", err)
+	}
+	obj.Data = merged
+	ds.doc.Objects[op.ObjectID] = obj
+	return nil
+}
+
+// applyMeta merges op.Meta into an object's integration metadata. Unlike
+// object.data it never affects rendering; a null value removes its key, and
+// an object left without keys has no Meta at all.
+func (ds *DocumentState) applyMeta(op Operation) error {
+	obj, ok := ds.doc.Objects[op.ObjectID]
+	if !ok {
+		return fmt.Errorf("object not found: %s", op.ObjectID)
+	}
+
+	var changes map[string]json.RawMessage
+	if err := json.Unmarshal(op.Meta, &changes); err != nil || changes == nil {
+		return fmt.Errorf("invalid meta: must be an object")
 	}
 
+	meta := make(map[string]json.RawMessage)
+	if len(obj.Meta) > 0 {
+		if err := json.Unmarshal(obj.Meta, &meta); err != nil || meta == nil {
+			meta = make(map[string]json.RawMessage)
+		}
+	}
 	for k, v := range changes {
-		existing[k] = v
+		if string(v) == "null" {
+			delete(meta, k)
+		} else {
+			meta[k] = v
+		}
 	}
 
-	merged, err := json.Marshal(existing)
-	if err != nil {
-		return fmt.Errorf("failed to marshal data: %w", err)
+	obj.Meta = nil
+	if len(meta) > 0 {
+		merged, err := json.Marshal(meta)
+		if err != nil {
+			return fmt.Errorf("failed to marshal meta: %w", err)
+		}
+		obj.Meta = merged
 	}
-	obj.Data = merged
 	ds.doc.Objects[op.ObjectID] = obj
 	return nil
 }
@@ -387,6 +1313,29 @@ func (ds *DocumentState) applySceneUpdate(op Operation) error {
 	if v, ok := changes["background"].(string); ok {
 		scene.Background = v
 	}
+	if v, ok := changes["safeAreaInsets"]; ok {
+		// null restores the standard safe areas
+		scene.SafeAreaInsets = nil
+		if v != nil {
+			raw, _ := json.Marshal(v)
+			var insets document.SafeAreaInsets
+			if err := json.Unmarshal(raw, &insets); err != nil {
+				return fmt.Errorf("invalid safeAreaInsets: %w", err)
+			}
+			scene.SafeAreaInsets = &insets
+		}
+	}
+
+	if scene.Width < 1 || scene.Width > document.MaxSceneSize || scene.Height < 1 || scene.Height > document.MaxSceneSize {
+		return fmt.Errorf("scene size must be between 1 and %d", document.MaxSceneSize)
+	}
+	// Checked against the final size, so a resize that would leave custom
+	// insets overlapping must send insets that fit
+	if scene.SafeAreaInsets != nil {
+		if err := scene.SafeAreaInsets.Validate(scene.Width, scene.Height); err != nil {
+			return fmt.Errorf("invalid safeAreaInsets: %w", err)
+		}
+	}
 
 	ds.doc.Scenes[op.SceneID] = scene
 	return nil
@@ -478,6 +1427,76 @@ func (ds *DocumentState) applyProjectRename(op Operation) error {
 	return nil
 }
 
+// applyProjectUpdate changes project-level display settings: the units
+// lengths are shown in and the layout direction.
+func (ds *DocumentState) applyProjectUpdate(op Operation) error {
+	var changes struct {
+		Units        *string `json:"units"`
+		Direction    *string `json:"direction"`
+		LinkedAssets *bool   `json:"linkedAssets"`
+	}
+	if err := json.Unmarshal(op.Changes, &changes); err != nil {
+		return fmt.Errorf("invalid project changes: %w", err)
+	}
+	if changes.Units != nil && !document.ValidUnits(*changes.Units) {
+		return fmt.Errorf("unknown units: %s", *changes.Units)
+	}
+	if changes.Direction != nil && !document.ValidDirection(*changes.Direction) {
+		return fmt.Errorf("unknown direction: %s", *changes.Direction)
+	}
+
+	if changes.Units != nil {
+		ds.doc.Project.Units = *changes.Units
+	}
+	if changes.Direction != nil {
+		ds.doc.Project.Direction = *changes.Direction
+	}
+	if changes.LinkedAssets != nil {
+		ds.doc.Project.LinkedAssets = *changes.LinkedAssets
+	}
+	return nil
+}
+
+// applyAssetUpdate replaces the record of an existing asset, as when its
+// content is replaced in place. An update older than the current revision is
+// ignored, so a late update never undoes a newer one.
+func (ds *DocumentState) applyAssetUpdate(op Operation) error {
+	if op.Asset == nil {
+		return fmt.Errorf("asset is required")
+	}
+	var asset document.Asset
+	if err := json.Unmarshal(op.Asset, &asset); err != nil {
+		return fmt.Errorf("invalid asset: %w", err)
+	}
+	current, ok := ds.doc.Assets[asset.ID]
+	if !ok {
+		return fmt.Errorf("asset not found: %s", asset.ID)
+	}
+	if asset.Revision < current.Revision {
+		return ErrNoChange
+	}
+
+	ds.doc.Assets[asset.ID] = asset
+	return nil
+}
+
+// applySetRootTimeline promotes a timeline to be the project's root. The
+// root timeline's length is the project's total frame count.
+func (ds *DocumentState) applySetRootTimeline(op Operation) error {
+	if op.TimelineID == "" {
+		return fmt.Errorf("timelineId is required")
+	}
+	if _, ok := ds.doc.Timelines[op.TimelineID]; !ok {
+		return fmt.Errorf("timeline not found: %s", op.TimelineID)
+	}
+	if ds.doc.Project.RootTimeline == op.TimelineID {
+		return ErrNoChange
+	}
+
+	ds.doc.Project.RootTimeline = op.TimelineID
+	return nil
+}
+
 func (ds *DocumentState) applyTrackCreate(op Operation) error {
 	if op.TimelineID == "" {
 		return fmt.Errorf("timelineId is required")
@@ -488,10 +1507,11 @@ func (ds *DocumentState) applyTrackCreate(op Operation) error {
 
 	// Parse the track data
 	var trackData struct {
-		ID       string   `json:"id"`
-		ObjectID string   `json:"objectId"`
-		Property string   `json:"property"`
-		Keys     []string `json:"keys"`
+		ID         string              `json:"id"`
+		ObjectID   string              `json:"objectId"`
+		Property   string              `json:"property"`
+		Keys       []string            `json:"keys"`
+		EasingMode document.EasingMode `json:"easingMode"`
 	}
 	if err := json.Unmarshal(op.Track, &trackData); err != nil {
 		return fmt.Errorf("invalid track data: %w", err)
@@ -503,12 +1523,19 @@ func (ds *DocumentState) applyTrackCreate(op Operation) error {
 		return fmt.Errorf("timeline not found: %s", op.TimelineID)
 	}
 
+	switch trackData.EasingMode {
+	case "", document.EasingOutgoing, document.EasingIncoming:
+	default:
+		return fmt.Errorf("invalid easingMode: %q", trackData.EasingMode)
+	}
+
 	// Create the track
 	track := document.Track{
-		ID:       trackData.ID,
-		ObjectID: trackData.ObjectID,
-		Property: trackData.Property,
-		Keys:     trackData.Keys,
+		ID:         trackData.ID,
+		ObjectID:   trackData.ObjectID,
+		Property:   trackData.Property,
+		Keys:       trackData.Keys,
+		EasingMode: trackData.EasingMode,
 	}
 	if track.Keys == nil {
 		track.Keys = []string{}
@@ -554,6 +1581,71 @@ func (ds *DocumentState) applyTrackDelete(op Operation) error {
 	return nil
 }
 
+// applyTrackReverse mirrors a track's keyframes around the midpoint of its
+// frame range. Each segment keeps its easing, which moves to the keyframe that
+// now starts it. Applying the operation twice restores the original track.
+func (ds *DocumentState) applyTrackReverse(op Operation) error {
+	if op.TrackID == "" {
+		return fmt.Errorf("trackId is required")
+	}
+
+	track, ok := ds.doc.Tracks[op.TrackID]
+	if !ok {
+		return fmt.Errorf("track not found: %s", op.TrackID)
+	}
+	if len(track.Keys) < 2 {
+		return nil
+	}
+
+	keys := make([]document.Keyframe, len(track.Keys))
+	for i, keyID := range track.Keys {
+		kf, ok := ds.doc.Keyframes[keyID]
+		if !ok {
+			return fmt.Errorf("keyframe not found: %s", keyID)
+		}
+		keys[i] = kf
+	}
+	sort.SliceStable(keys, func(i, j int) bool { return keys[i].Frame < keys[j].Frame })
+
+	span := keys[0].Frame + keys[len(keys)-1].Frame
+	n := len(keys)
+	newKeys := make([]string, n)
+	for i, kf := range keys {
+		// The segment starting at keys[i-1] now starts at keys[i]; the first
+		// keyframe becomes the last and takes the (unused) trailing easing.
+		// Incoming easings likewise move from keys[i+1] to keys[i].
+		src := keys[(i+n-1)%n]
+		if track.EasingMode == document.EasingIncoming {
+			src = keys[(i+1)%n]
+		}
+		easing := src.Easing
+		if op.MirrorEasing {
+			easing = easing.Mirrored()
+		}
+		kf.Frame = span - kf.Frame
+		kf.Easing = easing
+		kf.EasingParams = src.EasingParams
+		kf.Bezier = src.Bezier
+		if op.MirrorEasing {
+			kf.EasingParams = document.MirroredEasingParams(easing, kf.EasingParams)
+		}
+		if op.MirrorEasing && kf.Bezier != nil {
+			// Rounded, so that mirroring twice gives back the same points
+			mirrored := document.MirroredBezier(*kf.Bezier)
+			for j := range mirrored {
+				mirrored[j] = document.Round(mirrored[j], document.Precision())
+			}
+			kf.Bezier = &mirrored
+		}
+		ds.doc.Keyframes[kf.ID] = kf
+		newKeys[n-1-i] = kf.ID
+	}
+
+	track.Keys = newKeys
+	ds.doc.Tracks[op.TrackID] = track
+	return nil
+}
+
 func (ds *DocumentState) applyKeyframeAdd(op Operation) error {
 	if op.TrackID == "" {
 		return fmt.Errorf("trackId is required")
@@ -561,10 +1653,12 @@ func (ds *DocumentState) applyKeyframeAdd(op Operation) error {
 
 	// Parse keyframe from nested object
 	var kfData struct {
-		ID     string          `json:"id"`
-		Frame  int             `json:"frame"`
-		Value  json.RawMessage `json:"value"`
-		Easing string          `json:"easing"`
+		ID           string          `json:"id"`
+		Frame        int             `json:"frame"`
+		Value        json.RawMessage `json:"value"`
+		Easing       string          `json:"easing"`
+		EasingParams json.RawMessage `json:"easingParams"`
+		Bezier       json.RawMessage `json:"bezier"`
 	}
 	if op.Keyframe != nil {
 		if err := json.Unmarshal(op.Keyframe, &kfData); err != nil {
@@ -590,6 +1684,15 @@ func (ds *DocumentState) applyKeyframeAdd(op Operation) error {
 		return fmt.Errorf("track not found: %s", op.TrackID)
 	}
 
+	params, err := parseEasingParams(kfData.EasingParams)
+	if err != nil {
+		return err
+	}
+	bezier, err := parseBezier(kfData.Bezier)
+	if err != nil {
+		return err
+	}
+
 	// Create the keyframe
 	easing := document.EasingLinear
 	if kfData.Easing != "" {
@@ -597,10 +1700,12 @@ func (ds *DocumentState) applyKeyframeAdd(op Operation) error {
 	}
 
 	keyframe := document.Keyframe{
-		ID:     kfData.ID,
-		Frame:  kfData.Frame,
-		Value:  kfData.Value,
-		Easing: easing,
+		ID:           kfData.ID,
+		Frame:        kfData.Frame,
+		Value:        kfData.Value,
+		Easing:       easing,
+		EasingParams: params,
+		Bezier:       bezier,
 	}
 
 	// Add to keyframes map
@@ -640,9 +1745,11 @@ func (ds *DocumentState) applyKeyframeUpdate(op Operation) error {
 	var newFrame *int
 	if op.Changes != nil {
 		var changes struct {
-			Frame  *int            `json:"frame,omitempty"`
-			Value  json.RawMessage `json:"value,omitempty"`
-			Easing string          `json:"easing,omitempty"`
+			Frame        *int            `json:"frame,omitempty"`
+			Value        json.RawMessage `json:"value,omitempty"`
+			Easing       string          `json:"easing,omitempty"`
+			EasingParams json.RawMessage `json:"easingParams,omitempty"` // null clears them
+			Bezier       json.RawMessage `json:"bezier,omitempty"`       // null clears it
 		}
 		if err := json.Unmarshal(op.Changes, &changes); err != nil {
 			return fmt.Errorf("invalid changes data: %w", err)
@@ -657,6 +1764,20 @@ func (ds *DocumentState) applyKeyframeUpdate(op Operation) error {
 		if changes.Easing != "" {
 			keyframe.Easing = document.EasingType(changes.Easing)
 		}
+		if changes.EasingParams != nil {
+			params, err := parseEasingParams(changes.EasingParams)
+			if err != nil {
+				return err
+			}
+			keyframe.EasingParams = params
+		}
+		if changes.Bezier != nil {
+			bezier, err := parseBezier(changes.Bezier)
+			if err != nil {
+				return err
+			}
+			keyframe.Bezier = bezier
+		}
 	} else {
 		// Fallback to flat fields for backwards compatibility
 		if op.Frame != nil {
@@ -734,6 +1855,460 @@ func (ds *DocumentState) applyKeyframeDelete(op Operation) error {
 	return nil
 }
 
+// applyKeyframeConvertEasing replaces a keyframe's named easing with a
+// cubicBezier easing drawing the same curve, so its handles can be edited.
+// A preset reference takes the preset's points, and a missing preset, which
+// evaluates as linear, linear ones. Easings no single curve can draw, such
+// as springs, are refused.
+func (ds *DocumentState) applyKeyframeConvertEasing(op Operation) error {
+	if op.KeyframeID == "" {
+		return fmt.Errorf("keyframeId is required")
+	}
+	keyframe, ok := ds.doc.Keyframes[op.KeyframeID]
+	if !ok {
+		return fmt.Errorf("keyframe not found: %s", op.KeyframeID)
+	}
+	if keyframe.Easing == document.EasingCubicBezier {
+		return ErrNoChange
+	}
+
+	var bezier [4]float64
+	if name, isPreset := keyframe.Easing.PresetName(); isPreset {
+		bezier, _ = document.EasingLinear.Bezier()
+		if p, ok := ds.doc.EasingPresets[name]; ok {
+			bezier = [4]float64{p.X1, p.Y1, p.X2, p.Y2}
+		}
+	} else if bezier, ok = keyframe.Easing.Bezier(); !ok {
+		return fmt.Errorf("easing %q has no bezier equivalent", keyframe.Easing)
+	}
+
+	keyframe.Easing = document.EasingCubicBezier
+	keyframe.Bezier = &bezier
+	ds.doc.Keyframes[op.KeyframeID] = keyframe
+	return nil
+}
+
+// sourceTracks returns the tracks on a timeline that animate objectID, in
+// timeline order.
+func (ds *DocumentState) sourceTracks(timelineID, objectID string) []document.Track {
+	var tracks []document.Track
+	for _, trackID := range ds.doc.Timelines[timelineID].Tracks {
+		if track, ok := ds.doc.Tracks[trackID]; ok && track.ObjectID == objectID {
+			tracks = append(tracks, track)
+		}
+	}
+	return tracks
+}
+
+// copySources returns the source tracks an animation.copy clones: every
+// track of the source object, or only op.TrackIDs when given.
+func (ds *DocumentState) copySources(op *Operation) ([]document.Track, error) {
+	all := ds.sourceTracks(op.TimelineID, op.SourceObjectID)
+	if len(op.TrackIDs) == 0 {
+		return all, nil
+	}
+	byID := make(map[string]document.Track, len(all))
+	for _, track := range all {
+		byID[track.ID] = track
+	}
+	tracks := make([]document.Track, 0, len(op.TrackIDs))
+	for _, id := range op.TrackIDs {
+		track, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("track %s does not animate %s on timeline %s", id, op.SourceObjectID, op.TimelineID)
+		}
+		tracks = append(tracks, track)
+	}
+	return tracks, nil
+}
+
+// assignAnimationCopyIDs generates IDs for every track and keyframe an
+// animation.copy will create, keeping any the client already chose.
+func (ds *DocumentState) assignAnimationCopyIDs(op *Operation) error {
+	if op.IDMap == nil {
+		op.IDMap = make(map[string]map[string]string)
+	}
+	sources, err := ds.copySources(op)
+	if err != nil {
+		return err
+	}
+	used := make(map[string]bool)
+	for _, targetID := range op.ObjectIDs {
+		ids := op.IDMap[targetID]
+		if ids == nil {
+			ids = make(map[string]string)
+			op.IDMap[targetID] = ids
+		}
+		for _, track := range sources {
+			if ids[track.ID] == "" {
+				ids[track.ID] = typeid.NewTrackID()
+			}
+			for _, keyID := range track.Keys {
+				if ids[keyID] == "" {
+					ids[keyID] = typeid.NewKeyframeID()
+				}
+			}
+		}
+		for _, newID := range ids {
+			_, trackExists := ds.doc.Tracks[newID]
+			_, keyExists := ds.doc.Keyframes[newID]
+			if trackExists || keyExists || used[newID] {
+				return fmt.Errorf("id already in use: %s", newID)
+			}
+			used[newID] = true
+		}
+	}
+	return nil
+}
+
+// snapshotAnimation captures the tracks and keyframes animating objectIDs on a timeline.
+func (ds *DocumentState) snapshotAnimation(timelineID string, objectIDs []string) *AnimationSnapshot {
+	snap := &AnimationSnapshot{
+		ObjectIDs: objectIDs,
+		Tracks:    []document.Track{},
+		Keyframes: []document.Keyframe{},
+	}
+	targets := make(map[string]bool, len(objectIDs))
+	for _, id := range objectIDs {
+		targets[id] = true
+	}
+	for _, trackID := range ds.doc.Timelines[timelineID].Tracks {
+		track, ok := ds.doc.Tracks[trackID]
+		if !ok || !targets[track.ObjectID] {
+			continue
+		}
+		snap.Tracks = append(snap.Tracks, track)
+		for _, keyID := range track.Keys {
+			if kf, ok := ds.doc.Keyframes[keyID]; ok {
+				snap.Keyframes = append(snap.Keyframes, kf)
+			}
+		}
+	}
+	return snap
+}
+
+// removeTracksLocked deletes tracks (and their keyframes) from a timeline
+// wherever match returns true.
+func (ds *DocumentState) removeTracksLocked(timelineID string, match func(document.Track) bool) {
+	timeline := ds.doc.Timelines[timelineID]
+	kept := make([]string, 0, len(timeline.Tracks))
+	for _, trackID := range timeline.Tracks {
+		track, ok := ds.doc.Tracks[trackID]
+		if ok && match(track) {
+			for _, keyID := range track.Keys {
+				delete(ds.doc.Keyframes, keyID)
+			}
+			delete(ds.doc.Tracks, trackID)
+			continue
+		}
+		kept = append(kept, trackID)
+	}
+	timeline.Tracks = kept
+	ds.doc.Timelines[timelineID] = timeline
+}
+
+// applyAnimationCopy clones every track animating the source object (or only
+// TrackIDs) onto each target, shifting target i by StartOffset+i*FrameOffset
+// frames. A target's existing track for the same property is replaced.
+func (ds *DocumentState) applyAnimationCopy(op Operation) error {
+	if op.SourceObjectID == "" {
+		return fmt.Errorf("sourceObjectId is required")
+	}
+	if len(op.ObjectIDs) == 0 {
+		return fmt.Errorf("objectIds is required")
+	}
+	if _, ok := ds.doc.Timelines[op.TimelineID]; !ok {
+		return fmt.Errorf("timeline not found: %s", op.TimelineID)
+	}
+	if _, ok := ds.doc.Objects[op.SourceObjectID]; !ok {
+		return fmt.Errorf("object not found: %s", op.SourceObjectID)
+	}
+	for _, targetID := range op.ObjectIDs {
+		if targetID == op.SourceObjectID {
+			return fmt.Errorf("cannot copy animation onto its source: %s", targetID)
+		}
+		if _, ok := ds.doc.Objects[targetID]; !ok {
+			return fmt.Errorf("object not found: %s", targetID)
+		}
+	}
+
+	sources, err := ds.copySources(&op)
+	if err != nil {
+		return err
+	}
+	if len(sources) == 0 {
+		return fmt.Errorf("object has no animation on timeline: %s", op.SourceObjectID)
+	}
+	properties := make(map[string]bool, len(sources))
+	for _, track := range sources {
+		properties[track.Property] = true
+	}
+
+	for i, targetID := range op.ObjectIDs {
+		ids := op.IDMap[targetID]
+		offset := op.StartOffset + i*op.FrameOffset
+
+		ds.removeTracksLocked(op.TimelineID, func(t document.Track) bool {
+			return t.ObjectID == targetID && properties[t.Property]
+		})
+
+		timeline := ds.doc.Timelines[op.TimelineID]
+		for _, src := range sources {
+			track := document.Track{
+				ID:         ids[src.ID],
+				ObjectID:   targetID,
+				Property:   src.Property,
+				Keys:       make([]string, 0, len(src.Keys)),
+				EasingMode: src.EasingMode,
+			}
+			for _, keyID := range src.Keys {
+				kf, ok := ds.doc.Keyframes[keyID]
+				if !ok {
+					continue
+				}
+				kf.ID = ids[keyID]
+				kf.Frame += offset
+				ds.doc.Keyframes[kf.ID] = kf
+				track.Keys = append(track.Keys, kf.ID)
+			}
+			ds.doc.Tracks[track.ID] = track
+			timeline.Tracks = append(timeline.Tracks, track.ID)
+		}
+		ds.doc.Timelines[op.TimelineID] = timeline
+	}
+
+	return nil
+}
+
+// applyAnimationRestore replaces all animation of the snapshot's objects on a
+// timeline with the snapshot. It is the inverse of animation.copy and of itself.
+func (ds *DocumentState) applyAnimationRestore(op Operation) error {
+	if op.Animation == nil {
+		return fmt.Errorf("animation is required")
+	}
+	if _, ok := ds.doc.Timelines[op.TimelineID]; !ok {
+		return fmt.Errorf("timeline not found: %s", op.TimelineID)
+	}
+
+	targets := make(map[string]bool, len(op.Animation.ObjectIDs))
+	for _, id := range op.Animation.ObjectIDs {
+		targets[id] = true
+	}
+	ds.removeTracksLocked(op.TimelineID, func(t document.Track) bool {
+		return targets[t.ObjectID]
+	})
+
+	for _, kf := range op.Animation.Keyframes {
+		ds.doc.Keyframes[kf.ID] = kf
+	}
+	timeline := ds.doc.Timelines[op.TimelineID]
+	for _, track := range op.Animation.Tracks {
+		ds.doc.Tracks[track.ID] = track
+		timeline.Tracks = append(timeline.Tracks, track.ID)
+	}
+	ds.doc.Timelines[op.TimelineID] = timeline
+
+	return nil
+}
+
+// parseEasingParams checks a keyframe's easing parameters are an object. A
+// missing or null value is no parameters.
+func parseEasingParams(raw json.RawMessage) (json.RawMessage, error) {
+	if raw == nil || string(raw) == "null" {
+		return nil, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, fmt.Errorf("invalid easingParams: %w", err)
+	}
+	return raw, nil
+}
+
+// parseBezier decodes a keyframe's cubic-bezier control points, which must be
+// document.ValidBezier. A missing or null value is no curve.
+func parseBezier(raw json.RawMessage) (*[4]float64, error) {
+	if raw == nil || string(raw) == "null" {
+		return nil, nil
+	}
+	var points []float64
+	if err := json.Unmarshal(raw, &points); err != nil {
+		return nil, fmt.Errorf("invalid bezier: %w", err)
+	}
+	if len(points) != 4 {
+		return nil, fmt.Errorf("bezier must have 4 control values")
+	}
+	b := [4]float64(points)
+	if !document.ValidBezier(b) {
+		return nil, fmt.Errorf("bezier x1 and x2 must be between 0 and 1")
+	}
+	return &b, nil
+}
+
+// parseEasingPreset decodes and validates an easing preset. As with CSS
+// cubic-bezier, the x coordinates must lie in [0, 1] so the curve is a function
+// of time; y may overshoot.
+func parseEasingPreset(raw json.RawMessage) (document.EasingPreset, error) {
+	var p document.EasingPreset
+	if raw == nil {
+		return p, fmt.Errorf("preset is required")
+	}
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return p, fmt.Errorf("invalid preset data: %w", err)
+	}
+	if p.X1 < 0 || p.X1 > 1 || p.X2 < 0 || p.X2 > 1 {
+		return p, fmt.Errorf("preset x1 and x2 must be between 0 and 1")
+	}
+	return p, nil
+}
+
+func (ds *DocumentState) applyEasingPresetCreate(op Operation) error {
+	if op.PresetName == "" {
+		return fmt.Errorf("presetName is required")
+	}
+	preset, err := parseEasingPreset(op.Preset)
+	if err != nil {
+		return err
+	}
+	if _, exists := ds.doc.EasingPresets[op.PresetName]; exists {
+		return fmt.Errorf("easing preset already exists: %s", op.PresetName)
+	}
+
+	if ds.doc.EasingPresets == nil {
+		ds.doc.EasingPresets = make(map[string]document.EasingPreset)
+	}
+	ds.doc.EasingPresets[op.PresetName] = preset
+	return nil
+}
+
+// applyEasingPresetUpdate replaces a preset's curve. Keyframes reference
+// presets by name, so every keyframe using it picks up the change.
+func (ds *DocumentState) applyEasingPresetUpdate(op Operation) error {
+	if op.PresetName == "" {
+		return fmt.Errorf("presetName is required")
+	}
+	preset, err := parseEasingPreset(op.Preset)
+	if err != nil {
+		return err
+	}
+	existing, ok := ds.doc.EasingPresets[op.PresetName]
+	if !ok {
+		return fmt.Errorf("easing preset not found: %s", op.PresetName)
+	}
+	if existing == preset {
+		return ErrNoChange
+	}
+
+	ds.doc.EasingPresets[op.PresetName] = preset
+	return nil
+}
+
+// applyEasingPresetDelete removes a preset. Keyframes that reference it are
+// left untouched and evaluate as linear until a preset with the same name is
+// created again, which is also how the delete is undone.
+func (ds *DocumentState) applyEasingPresetDelete(op Operation) error {
+	if op.PresetName == "" {
+		return fmt.Errorf("presetName is required")
+	}
+	if _, ok := ds.doc.EasingPresets[op.PresetName]; !ok {
+		return fmt.Errorf("easing preset not found: %s", op.PresetName)
+	}
+
+	delete(ds.doc.EasingPresets, op.PresetName)
+	return nil
+}
+
+// pathCommandsLocked returns the commands of a VectorPath object.
+func (ds *DocumentState) pathCommandsLocked(op Operation) (document.ObjectNode, [][]interface{}, error) {
+	obj, ok := ds.doc.Objects[op.ObjectID]
+	if !ok {
+		return obj, nil, fmt.Errorf("object not found: %s", op.ObjectID)
+	}
+	if obj.Type != document.ObjectTypeVectorPath {
+		return obj, nil, fmt.Errorf("object %s is not a VectorPath", op.ObjectID)
+	}
+	if op.Index == nil {
+		return obj, nil, fmt.Errorf("index is required")
+	}
+	commands, err := document.PathCommands(obj.Data)
+	return obj, commands, err
+}
+
+// preparePathEditLocked records the state a path edit replaces so its
+// inverse can be built from the broadcast operation alone.
+func (ds *DocumentState) preparePathEditLocked(op *Operation) error {
+	_, commands, err := ds.pathCommandsLocked(*op)
+	if err != nil {
+		return err
+	}
+	switch op.Type {
+	case "path.movePoint":
+		prev, err := document.GetPathPoint(commands, *op.Index)
+		if err != nil {
+			return err
+		}
+		op.PreviousPoint = &prev
+	case "path.deletePoint":
+		vertices := document.PathVertexCommands(commands)
+		if *op.Index < 0 || *op.Index >= len(vertices) {
+			return document.ErrPathVertexRange
+		}
+		raw, err := json.Marshal(commands[vertices[*op.Index]])
+		if err != nil {
+			return err
+		}
+		op.PreviousCommand = raw
+	case "path.setClosed":
+		_, wasClosed, err := document.SetPathClosed(commands, *op.Index, false)
+		if err != nil {
+			return err
+		}
+		op.PreviousBool = &wasClosed
+	}
+	return nil
+}
+
+// applyPathEdit applies a vertex-level VectorPath edit. Only the addressed
+// vertex (and, for movePoint, its adjacent handle slots) changes, so edits of
+// different vertices by different users compose.
+func (ds *DocumentState) applyPathEdit(op Operation) error {
+	obj, commands, err := ds.pathCommandsLocked(op)
+	if err != nil {
+		return err
+	}
+
+	switch op.Type {
+	case "path.insertPoint":
+		var cmd []interface{}
+		if err := json.Unmarshal(op.Command, &cmd); err != nil {
+			return fmt.Errorf("invalid command: %w", err)
+		}
+		commands, err = document.InsertPathPoint(commands, *op.Index, cmd)
+	case "path.movePoint":
+		if op.Point == nil {
+			return fmt.Errorf("point is required")
+		}
+		err = document.MovePathPoint(commands, *op.Index, *op.Point)
+	case "path.deletePoint":
+		commands, _, err = document.DeletePathPoint(commands, *op.Index)
+	case "path.setClosed":
+		if op.Closed == nil {
+			return fmt.Errorf("closed is required")
+		}
+		commands, _, err = document.SetPathClosed(commands, *op.Index, *op.Closed)
+	}
+	if err != nil {
+		return err
+	}
+
+	data, err := document.WithPathCommands(obj.Data, commands)
+	if err != nil {
+		return err
+	}
+	obj.Data = data
+	ds.doc.Objects[op.ObjectID] = obj
+	return nil
+}
+
 // GetServerTimestamp returns the current server timestamp
 func GetServerTimestamp() int64 {
 	return time.Now().UnixMilli()
@@ -0,0 +1,316 @@
+package collab
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/engine"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// rectState returns a document state holding one rect under its scene's
+// root, and the rect's ID.
+func rectState(t *testing.T) (*DocumentState, string) {
+	t.Helper()
+	rootID, rectID := typeid.NewObjectID(), typeid.NewObjectID()
+	doc := document.NewEmptyDocument(typeid.NewProjectID(), "Test", typeid.NewSceneID(), rootID, typeid.NewTimelineID())
+	root := doc.Objects[rootID]
+	root.Children = append(root.Children, rectID)
+	doc.Objects[rootID] = root
+	doc.Objects[rectID] = document.ObjectNode{
+		ID:        rectID,
+		Type:      document.ObjectTypeShapeRect,
+		Parent:    &rootID,
+		Children:  []string{},
+		Transform: document.Transform{SX: 1, SY: 1},
+		Style:     document.Style{Fill: "#ff0000", Opacity: 1},
+		Visible:   true,
+		Data:      json.RawMessage(`{"width":40,"height":30}`),
+	}
+	return NewDocumentState(doc), rectID
+}
+
+// moveRect applies n transforms moving objectID to x = 1..n.
+func moveRect(t *testing.T, ds *DocumentState, objectID string, n int) {
+	t.Helper()
+	for x := 1; x <= n; x++ {
+		op := &Operation{
+			ID:        typeid.NewOpID(),
+			Type:      "object.transform",
+			ObjectID:  objectID,
+			Transform: json.RawMessage(fmt.Sprintf(`{"x":%d}`, x)),
+		}
+		if _, err := ds.ApplyOperation(op, "user"); err != nil {
+			t.Fatalf("move to %d: %v", x, err)
+		}
+	}
+}
+
+func TestOpLogTrimmedOnceSaved(t *testing.T) {
+	ds, rectID := rectState(t)
+	ds.logWindow = 5
+	moveRect(t, ds, rectID, 20)
+
+	// Unsaved operations are all kept
+	if len(ds.opLog) != 20 {
+		t.Fatalf("log holds %d operations before saving, want 20", len(ds.opLog))
+	}
+
+	ds.MarkSaved(18)
+	if ds.baseSeq != 13 || len(ds.opLog) != 7 || ds.opLog[0].Seq != 14 {
+		t.Fatalf("after saving at 18: base seq %d, %d logged from seq %d; want base 13, 7 from 14",
+			ds.baseSeq, len(ds.opLog), ds.opLog[0].Seq)
+	}
+	if ops := ds.UnpersistedOps(); len(ops) != 2 || ops[0].Seq != 19 {
+		t.Errorf("unpersisted = %d ops, want seqs 19 and 20", len(ops))
+	}
+
+	// Resync within the window still sends operations; further back falls
+	// back to a full sync
+	if ops, _, ok := ds.OpsAfter(13, 100); !ok || len(ops) != 7 {
+		t.Errorf("OpsAfter(13) = %d ops, ok %v; want 7 ops", len(ops), ok)
+	}
+	if _, _, ok := ds.OpsAfter(12, 100); ok {
+		t.Error("OpsAfter(12) ok from a trimmed log")
+	}
+
+	// The new base is the document as it stood at the trim point
+	rec, err := ds.Recording(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.FromSeq != 14 || len(rec.Operations) != 7 {
+		t.Errorf("recording from %d with %d ops, want from 14 with 7", rec.FromSeq, len(rec.Operations))
+	}
+	if x := rec.Document.Objects[rectID].Transform.X; x != 13 {
+		t.Errorf("recording base x = %v, want 13", x)
+	}
+}
+
+func TestOpLogKeptWithinTwiceWindow(t *testing.T) {
+	ds, rectID := rectState(t)
+	ds.logWindow = 5
+	moveRect(t, ds, rectID, 10)
+
+	ds.MarkPersisted(10)
+	if ds.baseSeq != 0 || len(ds.opLog) != 10 {
+		t.Errorf("base seq %d with %d logged, want the log untrimmed", ds.baseSeq, len(ds.opLog))
+	}
+}
+
+func TestTrackReverseSpinner(t *testing.T) {
+	ds, rectID := rectState(t)
+	track := addTrack(ds, rectID, "transform.r", []document.Keyframe{
+		{Frame: 0, Value: json.RawMessage(`0`), Easing: document.EasingLinear},
+		{Frame: 24, Value: json.RawMessage(`360`), Easing: document.EasingLinear},
+	})
+	before := trackState(ds, track.ID)
+
+	apply(t, ds, &Operation{Type: "track.reverse", TrackID: track.ID}, "user")
+	got := trackState(ds, track.ID)
+	if got[0].Frame != 0 || string(got[0].Value) != `360` || got[1].Frame != 24 || string(got[1].Value) != `0` {
+		t.Errorf("reversed spinner %+v, want 360 at 0 and 0 at 24", got)
+	}
+
+	apply(t, ds, &Operation{Type: "track.reverse", TrackID: track.ID}, "user")
+	if got := trackState(ds, track.ID); !reflect.DeepEqual(got, before) {
+		t.Errorf("reversed twice %+v, want %+v", got, before)
+	}
+}
+
+// TestTrackReverseMirrorsEasings checks a track reversed with mirrored
+// easings plays its animation backwards, whatever easings it uses.
+func TestTrackReverseMirrorsEasings(t *testing.T) {
+	bezier := [4]float64{0.1, 0.2, 0.3, 1.4}
+	for _, mode := range []document.EasingMode{document.EasingOutgoing, document.EasingIncoming} {
+		ds, rectID := rectState(t)
+		track := addTrack(ds, rectID, "transform.x", []document.Keyframe{
+			{Frame: 0, Value: json.RawMessage(`0`), Easing: document.EasingEaseIn},
+			{Frame: 10, Value: json.RawMessage(`100`), Easing: document.EasingCubicBezier, Bezier: &bezier},
+			{Frame: 20, Value: json.RawMessage(`-50`), Easing: document.EasingSpring, EasingParams: json.RawMessage(`{"stiffness":180}`)},
+			{Frame: 30, Value: json.RawMessage(`40`), Easing: document.EasingSpringSoft},
+			{Frame: 40, Value: json.RawMessage(`0`), Easing: document.EasingBackOut},
+		})
+		track.EasingMode = mode
+		ds.doc.Tracks[track.ID] = track
+		before := trackState(ds, track.ID)
+
+		timelineID := ds.doc.Project.RootTimeline
+		sample := func() []float64 {
+			var xs []float64
+			for f := 0.0; f <= 40; f += 0.5 {
+				xs = append(xs, engine.EvaluateTimeline(ds.doc, timelineID, f).Numeric[rectID]["transform.x"])
+			}
+			return xs
+		}
+		forwards := sample()
+
+		apply(t, ds, &Operation{Type: "track.reverse", TrackID: track.ID, MirrorEasing: true}, "user")
+		backwards := sample()
+		for i, x := range backwards {
+			if want := forwards[len(forwards)-1-i]; math.Abs(x-want) > 1e-6 {
+				t.Errorf("%s: reversed x at frame %v = %v, want %v", mode, float64(i)/2, x, want)
+			}
+		}
+
+		apply(t, ds, &Operation{Type: "track.reverse", TrackID: track.ID, MirrorEasing: true}, "user")
+		if got := trackState(ds, track.ID); !reflect.DeepEqual(got, before) {
+			t.Errorf("%s: reversed twice %+v, want %+v", mode, got, before)
+		}
+	}
+}
+
+// TestCreateFillsDefaults checks an object.create missing its scale,
+// opacity and visibility stores and broadcasts an object that renders.
+func TestCreateFillsDefaults(t *testing.T) {
+	ds, rectID := rectState(t)
+	rootID := *ds.doc.Objects[rectID].Parent
+	objectID := typeid.NewObjectID()
+	op := &Operation{
+		Type:     "object.create",
+		ParentID: rootID,
+		Object: json.RawMessage(`{"id":"` + objectID + `","type":"ShapeRect","parent":"` + rootID +
+			`","children":[],"transform":{"x":10},"data":{"width":10,"height":10}}`),
+	}
+	apply(t, ds, op, "user")
+
+	obj := ds.doc.Objects[objectID]
+	if obj.Transform.SX != 1 || obj.Transform.SY != 1 || obj.Transform.X != 10 || obj.Style.Opacity != 1 || !obj.Visible {
+		t.Errorf("stored %+v, want unit scale, full opacity and visible", obj)
+	}
+	// The broadcast operation carries the filled-in object
+	var sent document.ObjectNode
+	if err := json.Unmarshal(op.Object, &sent); err != nil || sent.Transform.SX != 1 || sent.Style.Opacity != 1 {
+		t.Errorf("broadcast object %s", op.Object)
+	}
+}
+
+func TestTrackCreateEasingMode(t *testing.T) {
+	ds, rectID := rectState(t)
+	timelineID := ds.doc.Project.RootTimeline
+	create := func(mode string) (string, error) {
+		id := typeid.NewTrackID()
+		_, err := ds.ApplyOperation(&Operation{ID: typeid.NewOpID(), Type: "track.create", TimelineID: timelineID,
+			Track: json.RawMessage(`{"id":"` + id + `","objectId":"` + rectID + `","property":"transform.x","keys":[],"easingMode":` + mode + `}`)}, "user")
+		return id, err
+	}
+
+	for mode, want := range map[string]document.EasingMode{`""`: "", `"outgoing"`: document.EasingOutgoing, `"incoming"`: document.EasingIncoming} {
+		id, err := create(mode)
+		if err != nil {
+			t.Fatalf("%s: %v", mode, err)
+		}
+		if got := ds.doc.Tracks[id].EasingMode; got != want {
+			t.Errorf("%s: track mode %q, want %q", mode, got, want)
+		}
+	}
+	if id, err := create(`"both"`); err == nil {
+		t.Errorf("track %s created with an unknown easing mode", id)
+	}
+
+	// A duplicated object's tracks keep their mode
+	incoming := addTrack(ds, rectID, "transform.y", nil)
+	incoming.EasingMode = document.EasingIncoming
+	ds.doc.Tracks[incoming.ID] = incoming
+	op := &Operation{Type: "object.duplicate", ObjectID: rectID}
+	apply(t, ds, op, "user")
+	copied := ds.doc.Tracks[op.IDMap[rectID][incoming.ID]]
+	if copied.ID == "" || copied.EasingMode != document.EasingIncoming {
+		t.Errorf("duplicated track %+v, want incoming easing", copied)
+	}
+}
+
+// dragOp moves objectID from previous to transform, as seen at baseSeq.
+func dragOp(objectID string, baseSeq int64, previous, transform string) *Operation {
+	return &Operation{
+		ID:        typeid.NewOpID(),
+		Type:      "object.transform",
+		ObjectID:  objectID,
+		Transform: json.RawMessage(transform),
+		Previous:  json.RawMessage(previous),
+		BaseSeq:   &baseSeq,
+	}
+}
+
+// TestTransformRebase has two clients drag the same rect's x from the same
+// baseline.
+func TestTransformRebase(t *testing.T) {
+	ds, rectID := rectState(t)
+	before, base, err := ds.SnapshotDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, applied := applySubmitted(ds, dragOp(rectID, base, `{"x":0}`, `{"x":10}`), "alice", OpPolicy{})
+	if !applied || first.Ack.Transform != nil {
+		t.Fatalf("first drag %+v, want acked as sent", first)
+	}
+
+	// The second drag is rebased onto the first, so both move the rect. r
+	// has no previous value and is set as sent.
+	second := dragOp(rectID, base, `{"x":0,"y":0}`, `{"x":5,"y":-3,"r":45}`)
+	result, applied := applySubmitted(ds, second, "bob", OpPolicy{})
+	if !applied || result.Ack == nil {
+		t.Fatalf("second drag %+v, want acked", result)
+	}
+	got := ds.doc.Objects[rectID].Transform
+	if got.X != 15 || got.Y != -3 || got.R != 45 {
+		t.Errorf("rect at x %v y %v r %v, want 15, -3, 45", got.X, got.Y, got.R)
+	}
+	// The sender learns what was set instead, and the log holds it as made
+	// against the latest state
+	var acked map[string]float64
+	if err := json.Unmarshal(result.Ack.Transform, &acked); err != nil || acked["x"] != 15 || acked["y"] != -3 || acked["r"] != 45 {
+		t.Errorf("ack transform %s, want x 15, y -3, r 45", result.Ack.Transform)
+	}
+	logged := ds.opLog[len(ds.opLog)-1].Operation
+	if string(logged.Transform) != string(result.Ack.Transform) || *logged.BaseSeq != first.Ack.ServerSeq {
+		t.Errorf("logged %s at base %d, want the rebased transform at base %d", logged.Transform, *logged.BaseSeq, first.Ack.ServerSeq)
+	}
+
+	// Replaying the log lands in the same place without rebasing again
+	replayed := NewDocumentState(before)
+	if _, err := replayed.Replay(ds.opLog); err != nil {
+		t.Fatal(err)
+	}
+	if got := replayed.doc.Objects[rectID].Transform; got != ds.doc.Objects[rectID].Transform {
+		t.Errorf("replayed %+v, want %+v", got, ds.doc.Objects[rectID].Transform)
+	}
+
+	// A drag made against the latest state applies as sent
+	latest := ds.ServerSeq()
+	result, _ = applySubmitted(ds, dragOp(rectID, latest, `{"x":15}`, `{"x":20}`), "alice", OpPolicy{})
+	if result.Ack == nil || result.Ack.Transform != nil || ds.doc.Objects[rectID].Transform.X != 20 {
+		t.Errorf("up-to-date drag %+v, want x 20 as sent", result)
+	}
+}
+
+func TestTransformRebaseConflicts(t *testing.T) {
+	ds, rectID := rectState(t)
+	base := ds.ServerSeq()
+
+	// An exact base value still asks for a match rather than a rebase
+	apply(t, ds, dragOp(rectID, base, `{"x":0}`, `{"x":10}`), "alice")
+	exact := dragOp(rectID, base, `{"x":0}`, `{"x":5}`)
+	exact.BaseValue = json.RawMessage(`{"x":0}`)
+	result, applied := applySubmitted(ds, exact, "bob", OpPolicy{})
+	if applied || result.Nack == nil || result.Nack.Reason != "conflict" {
+		t.Errorf("stale exact base: %+v, want a conflict", result)
+	}
+
+	// A drag of an object deleted since its base can't be rebased, and the
+	// nack carries the delete
+	deleteID := typeid.NewOpID()
+	apply(t, ds, &Operation{ID: deleteID, Type: "object.delete", ObjectID: rectID}, "alice")
+	result, applied = applySubmitted(ds, dragOp(rectID, base, `{"x":0}`, `{"x":5}`), "bob", OpPolicy{})
+	if applied || result.Nack == nil || result.Nack.Conflict == nil {
+		t.Fatalf("drag of a deleted object: %+v, want a conflict", result)
+	}
+	if c := result.Nack.Conflict; c.Type != "object.delete" || c.ObjectID != rectID || c.ID != deleteID {
+		t.Errorf("conflicting op %+v, want the delete", c)
+	}
+}
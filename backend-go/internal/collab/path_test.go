@@ -0,0 +1,140 @@
+package collab
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// pathState returns a document state holding a closed path with a curved
+// third vertex, and the path's ID.
+func pathState(t *testing.T) (*DocumentState, string) {
+	t.Helper()
+	ds, rectID := rectState(t)
+	pathID := typeid.NewObjectID()
+	root := *ds.doc.Objects[rectID].Parent
+	ds.doc.Objects[pathID] = document.ObjectNode{
+		ID:        pathID,
+		Type:      document.ObjectTypeVectorPath,
+		Parent:    &root,
+		Children:  []string{},
+		Transform: document.Transform{SX: 1, SY: 1},
+		Style:     document.Style{Stroke: "#000000", Opacity: 1},
+		Visible:   true,
+		Data:      json.RawMessage(`{"commands":[["M",0,0],["L",10,0],["C",15,0,20,5,20,10],["Z"]],"fillRule":"evenodd"}`),
+	}
+	parent := ds.doc.Objects[root]
+	parent.Children = append(parent.Children, pathID)
+	ds.doc.Objects[root] = parent
+	return ds, pathID
+}
+
+func intPtr(n int) *int { return &n }
+
+func pathCommands(t *testing.T, ds *DocumentState, pathID string) string {
+	t.Helper()
+	var data struct{ Commands json.RawMessage }
+	if err := json.Unmarshal(ds.doc.Objects[pathID].Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	return string(data.Commands)
+}
+
+// TestConcurrentVertexMoves checks two users moving different vertices of
+// the same path, each against the document before the other's move, both
+// keep their edit.
+func TestConcurrentVertexMoves(t *testing.T) {
+	ds, pathID := pathState(t)
+
+	apply(t, ds, &Operation{Type: "path.movePoint", ObjectID: pathID, Index: intPtr(1),
+		Point: &document.PathPoint{X: 10, Y: -5}}, "alice")
+	apply(t, ds, &Operation{Type: "path.movePoint", ObjectID: pathID, Index: intPtr(2),
+		Point: &document.PathPoint{X: 25, Y: 10, In: &document.PathHandle{X: 25, Y: 5}}}, "bob")
+
+	want := `[["M",0,0],["L",10,-5],["C",15,0,25,5,25,10],["Z"]]`
+	if got := pathCommands(t, ds, pathID); got != want {
+		t.Errorf("commands %s, want %s", got, want)
+	}
+	if fillRule(t, ds, pathID) != "evenodd" {
+		t.Error("path edit dropped the path's other data")
+	}
+}
+
+func fillRule(t *testing.T, ds *DocumentState, pathID string) string {
+	t.Helper()
+	var data struct{ FillRule string }
+	if err := json.Unmarshal(ds.doc.Objects[pathID].Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	return data.FillRule
+}
+
+func TestPathEditsUndo(t *testing.T) {
+	tests := []struct {
+		name string
+		op   func(pathID string) *Operation
+	}{
+		{"move", func(pathID string) *Operation {
+			return &Operation{Type: "path.movePoint", ObjectID: pathID, Index: intPtr(1),
+				Point: &document.PathPoint{X: 12, Y: 3, Out: &document.PathHandle{X: 16, Y: 2}}}
+		}},
+		{"insert", func(pathID string) *Operation {
+			return &Operation{Type: "path.insertPoint", ObjectID: pathID, Index: intPtr(3), Command: json.RawMessage(`["L",0,10]`)}
+		}},
+		{"delete", func(pathID string) *Operation {
+			return &Operation{Type: "path.deletePoint", ObjectID: pathID, Index: intPtr(2)}
+		}},
+		{"open", func(pathID string) *Operation {
+			return &Operation{Type: "path.setClosed", ObjectID: pathID, Index: intPtr(0), Closed: boolPtr(false)}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds, pathID := pathState(t)
+			before := pathCommands(t, ds, pathID)
+
+			apply(t, ds, tt.op(pathID), "user")
+			edited := pathCommands(t, ds, pathID)
+			if edited == before {
+				t.Fatal("edit left the path unchanged")
+			}
+			undo(t, ds, "user")
+			if got := pathCommands(t, ds, pathID); got != before {
+				t.Errorf("after undo %s, want %s", got, before)
+			}
+			redo(t, ds, "user")
+			if got := pathCommands(t, ds, pathID); got != edited {
+				t.Errorf("after redo %s, want %s", got, edited)
+			}
+		})
+	}
+}
+
+func TestPathEditsRejected(t *testing.T) {
+	ds, pathID := pathState(t)
+	rectID := ds.doc.Objects[*ds.doc.Objects[pathID].Parent].Children[0]
+	before := pathCommands(t, ds, pathID)
+
+	tests := []struct {
+		name string
+		op   *Operation
+	}{
+		{"not a path", &Operation{Type: "path.deletePoint", ObjectID: rectID, Index: intPtr(1)}},
+		{"no index", &Operation{Type: "path.deletePoint", ObjectID: pathID}},
+		{"index out of range", &Operation{Type: "path.movePoint", ObjectID: pathID, Index: intPtr(7), Point: &document.PathPoint{}}},
+		{"handle the vertex lacks", &Operation{Type: "path.movePoint", ObjectID: pathID, Index: intPtr(1), Point: &document.PathPoint{In: &document.PathHandle{}}}},
+		{"delete the start", &Operation{Type: "path.deletePoint", ObjectID: pathID, Index: intPtr(0)}},
+		{"insert a bad command", &Operation{Type: "path.insertPoint", ObjectID: pathID, Index: intPtr(1), Command: json.RawMessage(`["L",1]`)}},
+	}
+	for _, tt := range tests {
+		tt.op.ID = "op_" + tt.name
+		if _, err := ds.ApplyOperation(tt.op, "user"); err == nil {
+			t.Errorf("%s: applied", tt.name)
+		}
+	}
+	if got := pathCommands(t, ds, pathID); got != before {
+		t.Errorf("rejected edits changed the path to %s", got)
+	}
+}
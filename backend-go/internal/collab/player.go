@@ -0,0 +1,139 @@
+package collab
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+)
+
+// maxPlaybackGap caps how long playback waits between two recorded ops, so
+// idle stretches of a session don't stall the replay.
+const maxPlaybackGap = 2 * time.Second
+
+// Player steps through a Recording, applying each operation to a copy of the
+// recording's starting document. Playback time follows the server
+// timestamps, scaled by the speed. The editor runs it in the wasm engine,
+// which is why the hub and client files are excluded from wasm builds.
+type Player struct {
+	base  json.RawMessage
+	ops   []RecordedOperation
+	state *DocumentState
+	next  int     // Index of the next op to apply
+	clock float64 // Recording time reached, server Unix ms
+	speed float64
+}
+
+// NewPlayer creates a player positioned before the recording's first op.
+func NewPlayer(rec *Recording) (*Player, error) {
+	if rec.Document == nil {
+		return nil, errNoRecordingBase
+	}
+	base, err := json.Marshal(rec.Document)
+	if err != nil {
+		return nil, fmt.Errorf("encode recording base: %w", err)
+	}
+	p := &Player{base: base, ops: rec.Operations, speed: 1}
+	if err := p.reset(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// reset rewinds to the recording's starting document.
+func (p *Player) reset() error {
+	var doc document.InDocument
+	if err := json.Unmarshal(p.base, &doc); err != nil {
+		return fmt.Errorf("decode recording base: %w", err)
+	}
+	p.state = NewDocumentState(&doc)
+	p.next = 0
+	p.clock = 0
+	if len(p.ops) > 0 {
+		p.clock = float64(p.ops[0].Timestamp)
+	}
+	return nil
+}
+
+// Document returns the document as of the current position. It is replaced
+// on Seek backwards, so callers should re-fetch it after each call.
+func (p *Player) Document() *document.InDocument {
+	return p.state.GetDocument()
+}
+
+// Position returns how many ops have been applied.
+func (p *Player) Position() int {
+	return p.next
+}
+
+// Len returns the number of ops in the recording.
+func (p *Player) Len() int {
+	return len(p.ops)
+}
+
+// Done reports whether every op has been applied.
+func (p *Player) Done() bool {
+	return p.next >= len(p.ops)
+}
+
+// SetSpeed sets the playback rate (1 is real time). Non-positive values are
+// ignored.
+func (p *Player) SetSpeed(speed float64) {
+	if speed > 0 {
+		p.speed = speed
+	}
+}
+
+// Step applies the next op regardless of its timestamp.
+func (p *Player) Step() error {
+	if p.Done() {
+		return nil
+	}
+	rec := p.ops[p.next]
+	if err := p.state.ApplyRecorded(rec); err != nil {
+		return fmt.Errorf("apply seq %d: %w", rec.Seq, err)
+	}
+	p.next++
+	p.clock = max(p.clock, float64(rec.Timestamp))
+	return nil
+}
+
+// Advance moves playback forward by elapsed wall time and applies every op
+// that falls due, returning how many were applied.
+func (p *Player) Advance(elapsed time.Duration) (int, error) {
+	if p.Done() {
+		return 0, nil
+	}
+	// Skip idle stretches rather than replaying them
+	if gap := float64(p.ops[p.next].Timestamp) - p.clock; gap > float64(maxPlaybackGap.Milliseconds()) {
+		p.clock += gap - float64(maxPlaybackGap.Milliseconds())
+	}
+	p.clock += float64(elapsed) / float64(time.Millisecond) * p.speed
+
+	applied := 0
+	for !p.Done() && float64(p.ops[p.next].Timestamp) <= p.clock {
+		if err := p.Step(); err != nil {
+			return applied, err
+		}
+		applied++
+	}
+	return applied, nil
+}
+
+// Seek moves to just after the first index ops, rewinding to the starting
+// document when moving backwards.
+func (p *Player) Seek(index int) error {
+	index = max(0, min(index, len(p.ops)))
+	if index < p.next {
+		if err := p.reset(); err != nil {
+			return err
+		}
+	}
+	for p.next < index {
+		if err := p.Step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package collab
+
+import "strings"
+
+// OpPolicy restricts which operation types a deployment accepts, e.g. a
+// read-mostly embed that denies every "object.*" and "scene.*" mutation.
+// Patterns are exact types ("project.rename"), a namespace wildcard
+// ("object.*"), or "*" for everything.
+type OpPolicy struct {
+	Allow []string // When non-empty, only matching types are permitted
+	Deny  []string // Matching types are rejected even if allowed
+}
+
+// ParseOpPolicy builds a policy from comma-separated allow and deny lists.
+func ParseOpPolicy(allow, deny string) OpPolicy {
+	return OpPolicy{Allow: splitPatterns(allow), Deny: splitPatterns(deny)}
+}
+
+func splitPatterns(list string) []string {
+	var patterns []string
+	for _, raw := range strings.Split(list, ",") {
+		if p := strings.TrimSpace(raw); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// Permits reports whether operations of the given type may be applied.
+func (p OpPolicy) Permits(opType string) bool {
+	if len(p.Allow) > 0 && !matchesAny(p.Allow, opType) {
+		return false
+	}
+	return !matchesAny(p.Deny, opType)
+}
+
+func matchesAny(patterns []string, opType string) bool {
+	for _, p := range patterns {
+		if p == "*" || p == opType {
+			return true
+		}
+		if ns, ok := strings.CutSuffix(p, ".*"); ok && strings.HasPrefix(opType, ns+".") {
+			return true
+		}
+	}
+	return false
+}
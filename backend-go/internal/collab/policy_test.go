@@ -0,0 +1,58 @@
+package collab
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestOpPolicyPermits(t *testing.T) {
+	commentOnly := ParseOpPolicy("", "object.*, scene.*")
+	embed := ParseOpPolicy("presence.*,comment.add", "")
+	readOnly := ParseOpPolicy("*", "*")
+
+	tests := []struct {
+		policy OpPolicy
+		opType string
+		want   bool
+	}{
+		{OpPolicy{}, "object.transform", true},
+		{commentOnly, "object.transform", false},
+		{commentOnly, "scene.create", false},
+		{commentOnly, "comment.add", true},
+		{commentOnly, "objectx.transform", true},
+		{commentOnly, "object", true},
+		{embed, "presence.update", true},
+		{embed, "comment.add", true},
+		{embed, "comment.delete", false},
+		{embed, "object.transform", false},
+		{readOnly, "project.rename", false},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.Permits(tt.opType); got != tt.want {
+			t.Errorf("%+v permits %s = %v, want %v", tt.policy, tt.opType, got, tt.want)
+		}
+	}
+}
+
+func TestDeniedOpTypeNacked(t *testing.T) {
+	ds, rectID := rectState(t)
+	policy := ParseOpPolicy("", "object.transform")
+
+	denied := &Operation{ID: "op_denied", Type: "object.transform", ObjectID: rectID, Transform: json.RawMessage(`{"x":5}`)}
+	result, applied := applySubmitted(ds, denied, "user", policy)
+	if applied || result.Nack == nil || result.Nack.Reason == "" {
+		t.Fatalf("denied op answered %+v, want a nack with a reason", result)
+	}
+	if x := ds.doc.Objects[rectID].Transform.X; x != 0 {
+		t.Errorf("denied op moved the rect to %v", x)
+	}
+
+	allowed := &Operation{ID: "op_allowed", Type: "object.style", ObjectID: rectID, Style: json.RawMessage(`{"fill":"#00ff00"}`)}
+	result, applied = applySubmitted(ds, allowed, "user", policy)
+	if !applied || result.Ack == nil {
+		t.Fatalf("allowed op answered %+v %+v, want an ack", result.Ack, result.Nack)
+	}
+	if fill := ds.doc.Objects[rectID].Style.Fill; fill != "#00ff00ff" {
+		t.Errorf("fill %s after the allowed op", fill)
+	}
+}
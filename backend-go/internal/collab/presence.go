@@ -23,6 +23,25 @@ func (pm *PresenceManager) Update(userID string, p *PresencePayload) {
 	pm.presences[userID] = p
 }
 
+// Get returns a user's presence, or nil if they have none.
+func (pm *PresenceManager) Get(userID string) *PresencePayload {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.presences[userID]
+}
+
+// ReplaceIf stores next as a user's presence only if it is still prev, so a
+// presence derived from an older one doesn't overwrite a newer update.
+func (pm *PresenceManager) ReplaceIf(userID string, prev, next *PresencePayload) bool {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	if pm.presences[userID] != prev {
+		return false
+	}
+	pm.presences[userID] = next
+	return true
+}
+
 func (pm *PresenceManager) Remove(userID string) {
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
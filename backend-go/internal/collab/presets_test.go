@@ -0,0 +1,250 @@
+package collab
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/engine"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+func presetOp(opType, name, preset string) *Operation {
+	op := &Operation{Type: opType, PresetName: name}
+	if preset != "" {
+		op.Preset = json.RawMessage(preset)
+	}
+	return op
+}
+
+// presetTrack adds a track moving objectID's x from 0 to 100 over frames 0
+// to 10, eased by the named preset.
+func presetTrack(ds *DocumentState, objectID, name string) {
+	addTrack(ds, objectID, "transform.x", []document.Keyframe{
+		{Frame: 0, Value: json.RawMessage(`0`), Easing: document.EasingType(document.EasingPresetPrefix + name)},
+		{Frame: 10, Value: json.RawMessage(`100`), Easing: document.EasingLinear},
+	})
+}
+
+func midpointX(ds *DocumentState, objectID string) float64 {
+	return engine.EvaluateTimeline(ds.doc, ds.doc.Project.RootTimeline, 5).Numeric[objectID]["transform.x"]
+}
+
+func TestEasingPresetCreateAndUpdate(t *testing.T) {
+	ds, rectID := rectState(t)
+	presetTrack(ds, rectID, "snappy")
+
+	apply(t, ds, presetOp("easingPreset.create", "snappy", `{"x1":0.2,"y1":0.9,"x2":0.3,"y2":1}`), "user")
+	eased := midpointX(ds, rectID)
+	if eased <= 50 {
+		t.Errorf("midpoint x %v with an ease-out preset, want past 50", eased)
+	}
+
+	// Updating the preset retimes the keyframe without touching it
+	before := trackState(ds, ds.doc.Timelines[ds.doc.Project.RootTimeline].Tracks[0])
+	apply(t, ds, presetOp("easingPreset.update", "snappy", `{"x1":0.7,"y1":0,"x2":0.8,"y2":0.1}`), "user")
+	if x := midpointX(ds, rectID); x >= 50 {
+		t.Errorf("midpoint x %v after updating to an ease-in preset, want under 50", x)
+	}
+	after := trackState(ds, ds.doc.Timelines[ds.doc.Project.RootTimeline].Tracks[0])
+	if before[0].Easing != after[0].Easing {
+		t.Errorf("keyframe easing rewritten from %s to %s", before[0].Easing, after[0].Easing)
+	}
+
+	seq := ds.ServerSeq()
+	op := presetOp("easingPreset.update", "snappy", `{"x1":0.7,"y1":0,"x2":0.8,"y2":0.1}`)
+	op.ID = "op_same"
+	if _, err := ds.ApplyOperation(op, "user"); !errors.Is(err, ErrNoChange) || ds.ServerSeq() != seq {
+		t.Errorf("identical update: err %v, seq %d→%d; want ErrNoChange, unsequenced", err, seq, ds.ServerSeq())
+	}
+}
+
+// TestEasingPresetDeletePolicy checks deleting a preset leaves its keyframes
+// referring to it, evaluating as linear until it is created again.
+func TestEasingPresetDeletePolicy(t *testing.T) {
+	ds, rectID := rectState(t)
+	presetTrack(ds, rectID, "snappy")
+	apply(t, ds, presetOp("easingPreset.create", "snappy", `{"x1":0.2,"y1":0.9,"x2":0.3,"y2":1}`), "user")
+	eased := midpointX(ds, rectID)
+
+	apply(t, ds, presetOp("easingPreset.delete", "snappy", ""), "user")
+	if _, ok := ds.doc.EasingPresets["snappy"]; ok {
+		t.Fatal("preset still in the document")
+	}
+	keys := trackState(ds, ds.doc.Timelines[ds.doc.Project.RootTimeline].Tracks[0])
+	if keys[0].Easing != document.EasingPresetPrefix+"snappy" {
+		t.Errorf("keyframe easing %q after delete, want the preset reference kept", keys[0].Easing)
+	}
+	if x := midpointX(ds, rectID); x != 50 {
+		t.Errorf("midpoint x %v with the preset deleted, want linear 50", x)
+	}
+
+	apply(t, ds, presetOp("easingPreset.create", "snappy", `{"x1":0.2,"y1":0.9,"x2":0.3,"y2":1}`), "user")
+	if x := midpointX(ds, rectID); x != eased {
+		t.Errorf("midpoint x %v after recreating the preset, want %v", x, eased)
+	}
+}
+
+func TestEasingPresetErrors(t *testing.T) {
+	ds, _ := rectState(t)
+	apply(t, ds, presetOp("easingPreset.create", "snappy", `{"x1":0.2,"y1":0.9,"x2":0.3,"y2":1}`), "user")
+
+	tests := []struct {
+		name string
+		op   *Operation
+	}{
+		{"create existing", presetOp("easingPreset.create", "snappy", `{"x1":0,"y1":0,"x2":1,"y2":1}`)},
+		{"create without name", presetOp("easingPreset.create", "", `{"x1":0,"y1":0,"x2":1,"y2":1}`)},
+		{"create without curve", presetOp("easingPreset.create", "other", "")},
+		{"x outside 0..1", presetOp("easingPreset.create", "other", `{"x1":1.5,"y1":0,"x2":1,"y2":1}`)},
+		{"update missing", presetOp("easingPreset.update", "missing", `{"x1":0,"y1":0,"x2":1,"y2":1}`)},
+		{"delete missing", presetOp("easingPreset.delete", "missing", "")},
+	}
+	for _, tt := range tests {
+		tt.op.ID = "op_" + tt.name
+		if _, err := ds.ApplyOperation(tt.op, "user"); err == nil || errors.Is(err, ErrNoChange) {
+			t.Errorf("%s: err %v, want a rejection", tt.name, err)
+		}
+	}
+	if len(ds.doc.EasingPresets) != 1 {
+		t.Errorf("presets %v, want only snappy", ds.doc.EasingPresets)
+	}
+
+	// y may overshoot
+	apply(t, ds, presetOp("easingPreset.create", "bouncy", `{"x1":0.3,"y1":-0.5,"x2":0.6,"y2":1.6}`), "user")
+}
+
+func TestKeyframeEasingParams(t *testing.T) {
+	ds, rectID := rectState(t)
+	x := addTrack(ds, rectID, "transform.x", nil)
+
+	keyID := typeid.NewKeyframeID()
+	apply(t, ds, &Operation{Type: "keyframe.add", TrackID: x.ID,
+		Keyframe: json.RawMessage(`{"id":"` + keyID + `","frame":0,"value":0,"easing":"spring","easingParams":{"damping":4}}`)}, "user")
+	if got := ds.doc.Keyframes[keyID]; got.Easing != document.EasingSpring || string(got.EasingParams) != `{"damping":4}` {
+		t.Errorf("added keyframe %s %s, want a spring with damping 4", got.Easing, got.EasingParams)
+	}
+
+	apply(t, ds, &Operation{Type: "keyframe.update", KeyframeID: keyID, Changes: json.RawMessage(`{"easingParams":{"stiffness":250}}`)}, "user")
+	if got := string(ds.doc.Keyframes[keyID].EasingParams); got != `{"stiffness":250}` {
+		t.Errorf("updated params %s", got)
+	}
+	undo(t, ds, "user")
+	if got := string(ds.doc.Keyframes[keyID].EasingParams); got != `{"damping":4}` {
+		t.Errorf("params after undo %s, want the original", got)
+	}
+	apply(t, ds, &Operation{Type: "keyframe.update", KeyframeID: keyID, Changes: json.RawMessage(`{"easingParams":null}`)}, "user")
+	if got := ds.doc.Keyframes[keyID].EasingParams; got != nil {
+		t.Errorf("params after clearing %s, want none", got)
+	}
+
+	for _, op := range []*Operation{
+		{ID: "op_add", Type: "keyframe.add", TrackID: x.ID,
+			Keyframe: json.RawMessage(`{"id":"` + typeid.NewKeyframeID() + `","frame":5,"value":1,"easing":"spring","easingParams":[4]}`)},
+		{ID: "op_update", Type: "keyframe.update", KeyframeID: keyID, Changes: json.RawMessage(`{"easingParams":"stiff"}`)},
+	} {
+		if _, err := ds.ApplyOperation(op, "user"); err == nil {
+			t.Errorf("%s accepted easing params that aren't an object", op.Type)
+		}
+	}
+}
+
+func TestKeyframeBezier(t *testing.T) {
+	ds, rectID := rectState(t)
+	x := addTrack(ds, rectID, "transform.x", nil)
+
+	keyID := typeid.NewKeyframeID()
+	apply(t, ds, &Operation{Type: "keyframe.add", TrackID: x.ID,
+		Keyframe: json.RawMessage(`{"id":"` + keyID + `","frame":0,"value":0,"easing":"cubicBezier","bezier":[0.25,0.1,0.25,1]}`)}, "user")
+	if got := ds.doc.Keyframes[keyID]; got.Easing != document.EasingCubicBezier || got.Bezier == nil || *got.Bezier != [4]float64{0.25, 0.1, 0.25, 1} {
+		t.Errorf("added keyframe %s %v, want CSS ease", got.Easing, got.Bezier)
+	}
+
+	// y may overshoot; x may not leave [0, 1]
+	apply(t, ds, &Operation{Type: "keyframe.update", KeyframeID: keyID, Changes: json.RawMessage(`{"bezier":[0.68,-0.55,0.265,1.55]}`)}, "user")
+	if got := *ds.doc.Keyframes[keyID].Bezier; got != [4]float64{0.68, -0.55, 0.265, 1.55} {
+		t.Errorf("updated bezier %v", got)
+	}
+	for _, bezier := range []string{`[1.2,0,0.5,1]`, `[0.5,0,-0.5,1]`, `[0.5,0,0.5]`, `"ease"`} {
+		op := &Operation{ID: typeid.NewOpID(), Type: "keyframe.update", KeyframeID: keyID, Changes: json.RawMessage(`{"bezier":` + bezier + `}`)}
+		if _, err := ds.ApplyOperation(op, "user"); err == nil {
+			t.Errorf("bezier %s accepted", bezier)
+		}
+	}
+	if got := *ds.doc.Keyframes[keyID].Bezier; got != [4]float64{0.68, -0.55, 0.265, 1.55} {
+		t.Errorf("bezier %v after rejected updates", got)
+	}
+
+	apply(t, ds, &Operation{Type: "keyframe.update", KeyframeID: keyID, Changes: json.RawMessage(`{"bezier":null}`)}, "user")
+	if got := ds.doc.Keyframes[keyID].Bezier; got != nil {
+		t.Errorf("bezier %v after clearing, want none", *got)
+	}
+}
+
+func TestKeyframeConvertEasingToBezier(t *testing.T) {
+	tests := []struct {
+		easing    document.EasingType
+		tolerance float64 // Of the 100px change
+		undone    document.EasingType
+	}{
+		{document.EasingEaseInOut, 0.4, document.EasingEaseInOut}, // A least-squares fit
+		{document.EasingEaseIn, 1e-4, document.EasingEaseIn},      // Exact but for solving the curve
+		{document.EasingBackOut, 1e-4, document.EasingBackOut},
+		{"", 1e-4, document.EasingLinear}, // Undone as the linear easing it stands for
+	}
+	for _, tt := range tests {
+		t.Run(cmp.Or(string(tt.easing), "empty"), func(t *testing.T) {
+			ds, rectID := rectState(t)
+			track := addTrack(ds, rectID, "transform.x", []document.Keyframe{
+				{Frame: 0, Value: json.RawMessage(`0`), Easing: tt.easing},
+				{Frame: 24, Value: json.RawMessage(`100`), Easing: document.EasingLinear},
+			})
+			keyID := track.Keys[0]
+			sample := func() []float64 {
+				var xs []float64
+				for _, f := range []float64{2, 6, 9.5, 12, 15, 18, 22} {
+					xs = append(xs, engine.EvaluateTimeline(ds.doc, ds.doc.Project.RootTimeline, f).Numeric[rectID]["transform.x"])
+				}
+				return xs
+			}
+			before := sample()
+
+			apply(t, ds, &Operation{Type: "keyframe.convertEasingToBezier", TrackID: track.ID, KeyframeID: keyID}, "user")
+			if kf := ds.doc.Keyframes[keyID]; kf.Easing != document.EasingCubicBezier || kf.Bezier == nil {
+				t.Fatalf("keyframe %+v, want a cubicBezier easing with handles", kf)
+			}
+			for i, x := range sample() {
+				if math.Abs(x-before[i]) > tt.tolerance {
+					t.Errorf("sample %d: x %v with handles, %v with %q", i, x, before[i], tt.easing)
+				}
+			}
+
+			// Converting again changes nothing, and undo puts the name back
+			again := &Operation{ID: typeid.NewOpID(), Type: "keyframe.convertEasingToBezier", TrackID: track.ID, KeyframeID: keyID}
+			if _, err := ds.ApplyOperation(again, "user"); !errors.Is(err, ErrNoChange) {
+				t.Errorf("second conversion: %v, want ErrNoChange", err)
+			}
+			undo(t, ds, "user")
+			if kf := ds.doc.Keyframes[keyID]; kf.Easing != tt.undone || kf.Bezier != nil {
+				t.Errorf("after undo easing %q bezier %v, want %q without handles", kf.Easing, kf.Bezier, tt.undone)
+			}
+		})
+	}
+}
+
+func TestKeyframeConvertEasingRefused(t *testing.T) {
+	ds, rectID := rectState(t)
+	track := addTrack(ds, rectID, "transform.x", []document.Keyframe{
+		{Frame: 0, Value: json.RawMessage(`0`), Easing: document.EasingType("spring")},
+	})
+	op := &Operation{ID: typeid.NewOpID(), Type: "keyframe.convertEasingToBezier", TrackID: track.ID, KeyframeID: track.Keys[0]}
+	if _, err := ds.ApplyOperation(op, "user"); err == nil {
+		t.Error("converted a spring, which no single curve draws")
+	}
+	if kf := ds.doc.Keyframes[track.Keys[0]]; kf.Easing != "spring" || kf.Bezier != nil {
+		t.Errorf("refused conversion left %+v", kf)
+	}
+}
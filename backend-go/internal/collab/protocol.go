@@ -1,6 +1,10 @@
 package collab
 
-import "encoding/json"
+import (
+	"encoding/json"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+)
 
 type Message struct {
 	Type      string          `json:"type"`
@@ -14,12 +18,39 @@ type Message struct {
 type PresencePayload struct {
 	Cursor      *CursorPos `json:"cursor,omitempty"`
 	Selection   []string   `json:"selection,omitempty"`
+	Frame       int        `json:"frame,omitempty"` // Playhead the selection is seen at
 	DisplayName string     `json:"displayName,omitempty"`
+
+	// SceneID is the scene the user is editing, so others only draw their
+	// cursor over the same scene. Empty for clients that don't send it.
+	SceneID string `json:"sceneId,omitempty"`
+
+	// EditingObjects are the objects the client is editing, e.g. dragging,
+	// and wants to hold edit locks on (see EditLocks). The server replaces
+	// them with the ones it holds.
+	EditingObjects []string `json:"editingObjects,omitempty"`
+
+	// SelectionBounds is the union of the selected objects' world-space
+	// bounds, resolved by the server from its scene graph. Clients' values
+	// are ignored.
+	SelectionBounds *Bounds `json:"selectionBounds,omitempty"`
+
+	boundsSeq int64 // Server sequence SelectionBounds was resolved at
 }
 
+// Cursor coordinate spaces. Cursors are in scene space: the pixels of the
+// scene being edited, before the viewport's pan and zoom, so they land in
+// the same place on every collaborator's screen. Clients from before the
+// space was declared send none; the hub marks their cursors legacy.
+const (
+	CursorSpaceScene  = "scene"
+	CursorSpaceLegacy = "legacy"
+)
+
 type CursorPos struct {
-	X float64 `json:"x"`
-	Y float64 `json:"y"`
+	X     float64 `json:"x"`
+	Y     float64 `json:"y"`
+	Space string  `json:"space,omitempty"` // CursorSpaceScene or CursorSpaceLegacy
 }
 
 type PresenceStatePayload struct {
@@ -45,14 +76,42 @@ const (
 	// Connection
 	TypeWelcome = "welcome"
 
-	// Document sync
-	TypeDocSync = "doc.sync"
+	// Document sync. A client that suspects it has diverged sends
+	// doc.requestSync to be sent doc.sync again, with Seq set to the server
+	// sequence the document is at.
+	TypeDocSync        = "doc.sync"
+	TypeDocRequestSync = "doc.requestSync"
+
+	// Resync. A reconnecting client sends doc.resync with the last server
+	// sequence it saw, or passes it as the seq query parameter when it joins,
+	// and is sent doc.resyncOps with the operations it missed, or doc.sync
+	// when they can't all be sent.
+	TypeDocResync    = "doc.resync"
+	TypeDocResyncOps = "doc.resyncOps"
 
 	// Operation message types
 	TypeOpSubmit    = "op.submit"
 	TypeOpAck       = "op.ack"
 	TypeOpNack      = "op.nack"
 	TypeOpBroadcast = "op.broadcast"
+	TypeOpUndo      = "op.undo"
+	TypeOpRedo      = "op.redo"
+
+	// Batched operations, e.g. the transforms of a drag gesture. An op.batch
+	// is applied all or nothing and answered with one op.batchAck or
+	// op.batchNack; the operations it applied reach other clients in one
+	// op.batchBroadcast.
+	TypeOpBatch          = "op.batch"
+	TypeOpBatchAck       = "op.batchAck"
+	TypeOpBatchNack      = "op.batchNack"
+	TypeOpBatchBroadcast = "op.batchBroadcast"
+
+	// Sent after an asset.update broadcast, so clients reload the image
+	TypeAssetUpdated = "asset.updated"
+
+	// Sent to each of a user's connections, whatever the room, when a
+	// notification is added to their feed
+	TypeNotificationNew = "notification.new"
 )
 
 // --- Operation Types ---
@@ -66,7 +125,24 @@ type Operation struct {
 	ObjectID  string          `json:"objectId,omitempty"`
 	Payload   json.RawMessage `json:"payload,omitempty"` // Type-specific data
 
-	// For object.transform
+	// UndoOf is the ID of the operation this one undoes. Only the server sets
+	// it, on the inverse it applies for an op.undo.
+	UndoOf string `json:"undoOf,omitempty"`
+
+	// Optional optimistic concurrency for object.transform, object.style,
+	// object.data, and keyframe.update. BaseSeq is the server sequence the client
+	// last saw for the target; BaseValue is the value it believed it was editing
+	// (for object ops, only the listed fields are compared). A stale base is
+	// nacked with the server's current value in OperationNackPayload.Conflict,
+	// except that an object.transform with BaseSeq and Previous but no
+	// BaseValue is rebased onto the changes since BaseSeq as a delta (see
+	// transformTransformOp); it is nacked only if its object was deleted.
+	BaseSeq   *int64          `json:"baseSeq,omitempty"`
+	BaseValue json.RawMessage `json:"baseValue,omitempty"`
+
+	// For object.transform. The server fills in Previous, for this and the
+	// other ops that change some fields of their target, with the values they
+	// replace.
 	Transform json.RawMessage `json:"transform,omitempty"`
 	Previous  json.RawMessage `json:"previous,omitempty"`
 
@@ -77,13 +153,14 @@ type Operation struct {
 	Object   json.RawMessage `json:"object,omitempty"`
 	ParentID string          `json:"parentId,omitempty"`
 	Index    *int            `json:"index,omitempty"`
-	Asset    json.RawMessage `json:"asset,omitempty"` // Optional bundled asset (for RasterImage creates)
+	Asset    json.RawMessage `json:"asset,omitempty"` // Optional bundled asset (for RasterImage creates); the new record for asset.update
 
 	// For object.delete
 	PreviousObject         json.RawMessage `json:"previousObject,omitempty"`
 	PreviousParentChildren []string        `json:"previousParentChildren,omitempty"`
 
-	// For object.reparent
+	// For object.reparent, and object.reorder (NewIndex and PreviousIndex
+	// only), which moves an object to NewIndex among its current siblings
 	NewParentID      string `json:"newParentId,omitempty"`
 	NewIndex         int    `json:"newIndex,omitempty"`
 	PreviousParentID string `json:"previousParentId,omitempty"`
@@ -92,26 +169,63 @@ type Operation struct {
 	// For object.data
 	Data json.RawMessage `json:"data,omitempty"`
 
+	// For object.setMeta: keys merged into the object's Meta, a null value
+	// removing its key
+	Meta json.RawMessage `json:"meta,omitempty"`
+
+	// For object.resetTransform: ObjectIDs (or ObjectID) are reset to the
+	// identity, or each object in Transforms is set to its transform, which
+	// is how a reset is undone. The server fills in PreviousTransforms.
+	Transforms         map[string]document.Transform `json:"transforms,omitempty"`
+	PreviousTransforms map[string]document.Transform `json:"previousTransforms,omitempty"`
+
+	// For object.nudge: relative changes to the base transforms of ObjectIDs
+	// (or ObjectID), keyed by field, as numbers or strings with units such
+	// as "10px", "-15°" or "200%". The server resolves them into Transforms
+	// and PreviousTransforms.
+	Deltas map[string]json.RawMessage `json:"deltas,omitempty"`
+
 	// For object.visibility / object.locked
 	Visible      *bool `json:"visible,omitempty"`
 	Locked       *bool `json:"locked,omitempty"`
 	PreviousBool *bool `json:"previousBool,omitempty"`
 
+	// For bulk object.visibility / object.locked and object.solo
+	ObjectIDs      []string        `json:"objectIds,omitempty"`
+	States         map[string]bool `json:"states,omitempty"`
+	PreviousStates map[string]bool `json:"previousStates,omitempty"`
+
 	// For scene.update, scene.create, scene.delete, and keyframe.update
 	SceneID    string          `json:"sceneId,omitempty"`
-	Changes    json.RawMessage `json:"changes,omitempty"`    // Used by scene.update, timeline.update, and keyframe.update
+	Changes    json.RawMessage `json:"changes,omitempty"`    // Used by scene.update, project.update, timeline.update, and keyframe.update
 	Scene      json.RawMessage `json:"scene,omitempty"`      // For scene.create
 	RootObject json.RawMessage `json:"rootObject,omitempty"` // For scene.create
 
+	// For timeline.insertTime / timeline.removeTime (on TimelineID). Clamp
+	// makes removeTime move the keyframes inside the removed span to its
+	// start instead of deleting them. The server fills in RemovedKeyframes
+	// (track ID → keyframes as they were) with those a removeTime deletes or
+	// clamps; an insertTime carrying them puts them back.
+	AtFrame          *int                           `json:"atFrame,omitempty"`
+	Frames           int                            `json:"frames,omitempty"`
+	Clamp            bool                           `json:"clamp,omitempty"`
+	RemovedKeyframes map[string][]document.Keyframe `json:"removedKeyframes,omitempty"`
+
 	// For project.rename
 	Name         string `json:"name,omitempty"`
 	PreviousName string `json:"previousName,omitempty"`
 
+	// For project.setRootTimeline (the new root is TimelineID)
+	PreviousTimelineID string `json:"previousTimelineId,omitempty"`
+
 	// For track operations
 	Track         json.RawMessage `json:"track,omitempty"`
 	PreviousTrack json.RawMessage `json:"previousTrack,omitempty"`
+	MirrorEasing  bool            `json:"mirrorEasing,omitempty"` // For track.reverse
 
-	// For keyframe operations
+	// For keyframe operations. keyframe.convertEasingToBezier (KeyframeID)
+	// swaps the keyframe's named easing for the cubicBezier drawing the same
+	// curve; Previous holds the easing and bezier it replaced.
 	Keyframe          json.RawMessage `json:"keyframe,omitempty"` // For keyframe.add: { id, frame, value, easing }
 	KeyframeID        string          `json:"keyframeId,omitempty"`
 	TrackID           string          `json:"trackId,omitempty"`
@@ -124,6 +238,50 @@ type Operation struct {
 	PreviousEasing    string          `json:"previousEasing,omitempty"`
 	PreviousKeyframe  json.RawMessage `json:"previousKeyframe,omitempty"`
 	PreviousTrackKeys []string        `json:"previousTrackKeys,omitempty"`
+
+	// For easingPreset.create / easingPreset.update / easingPreset.delete
+	PresetName     string          `json:"presetName,omitempty"`
+	Preset         json.RawMessage `json:"preset,omitempty"`         // { x1, y1, x2, y2 }
+	PreviousPreset json.RawMessage `json:"previousPreset,omitempty"` // For undo of update / delete
+
+	// For path.insertPoint / path.movePoint / path.deletePoint / path.setClosed.
+	// The vertex is addressed by Index (see document.PathPoint); PreviousPoint,
+	// PreviousCommand and PreviousBool are filled in by the server for undo.
+	Point           *document.PathPoint `json:"point,omitempty"`
+	PreviousPoint   *document.PathPoint `json:"previousPoint,omitempty"`
+	Command         json.RawMessage     `json:"command,omitempty"`         // Inserted vertex, e.g. ["L", x, y]
+	PreviousCommand json.RawMessage     `json:"previousCommand,omitempty"` // Removed vertex
+	Closed          *bool               `json:"closed,omitempty"`
+
+	// For animation.copy (targets are ObjectIDs) and animation.restore
+	SourceObjectID    string                       `json:"sourceObjectId,omitempty"`
+	TrackIDs          []string                     `json:"trackIds,omitempty"`          // Copy only these source tracks (default: all)
+	StartOffset       int                          `json:"startOffset,omitempty"`       // Frames every target is shifted by
+	FrameOffset       int                          `json:"frameOffset,omitempty"`       // Stagger: target i is shifted by a further i*FrameOffset frames
+	IDMap             map[string]map[string]string `json:"idMap,omitempty"`             // Target ID → source track/keyframe ID → new ID
+	Animation         *AnimationSnapshot           `json:"animation,omitempty"`         // For animation.restore
+	PreviousAnimation *AnimationSnapshot           `json:"previousAnimation,omitempty"` // For undo
+
+	// For object.detachSymbol and its inverse object.restoreSymbol (the symbol
+	// is ObjectID). The server fills in DetachedObjects, the plain copies with
+	// the symbol's first frame baked in (the replacement group first), and
+	// PreviousSymbol; IDMap[ObjectID] maps each original to its copy's ID.
+	DetachedObjects []document.ObjectNode `json:"detachedObjects,omitempty"`
+	PreviousSymbol  *SymbolSnapshot       `json:"previousSymbol,omitempty"`
+
+	// For object.duplicate: the copy goes under ParentID (default: the
+	// original's parent) at Index (default: right after the original). The
+	// server fills in both, and Duplicate with the copies; IDMap[ObjectID]
+	// maps each original object, track and keyframe to its copy's ID.
+	Duplicate *DuplicateSnapshot `json:"duplicate,omitempty"`
+}
+
+// AnimationSnapshot captures every track, with its keyframes, that animates
+// a set of objects on one timeline. Tracks are in timeline order.
+type AnimationSnapshot struct {
+	ObjectIDs []string            `json:"objectIds"`
+	Tracks    []document.Track    `json:"tracks"`
+	Keyframes []document.Keyframe `json:"keyframes"`
 }
 
 // OperationSubmitPayload is the payload for op.submit messages
@@ -131,11 +289,30 @@ type OperationSubmitPayload struct {
 	Operation Operation `json:"operation"`
 }
 
+// OperationUndoPayload is the payload for op.undo messages. The inverse of
+// OperationID, or of the sender's most recent operation still in effect when
+// it is empty, is applied as a new operation with the given ID, which the
+// op.ack or op.nack answers.
+type OperationUndoPayload struct {
+	ID          string `json:"id"`
+	OperationID string `json:"operationId,omitempty"`
+}
+
+// OperationRedoPayload is the payload for op.redo messages. What the
+// sender's most recent undo still in effect undid is redone by a new
+// operation with the given ID, which the op.ack or op.nack answers.
+type OperationRedoPayload struct {
+	ID string `json:"id"`
+}
+
 // OperationAckPayload is the payload for op.ack messages
 type OperationAckPayload struct {
-	OperationID     string `json:"operationId"`
-	ServerSeq       int64  `json:"serverSeq"`
-	ServerTimestamp int64  `json:"serverTimestamp"`
+	OperationID     string                       `json:"operationId"`
+	ServerSeq       int64                        `json:"serverSeq"`
+	ServerTimestamp int64                        `json:"serverTimestamp"`
+	IDMap           map[string]map[string]string `json:"idMap,omitempty"`     // IDs the server assigned (animation.copy, object.detachSymbol, object.duplicate)
+	Transform       json.RawMessage              `json:"transform,omitempty"` // What a rebased or rounded object.transform set instead
+	ObjectID        string                       `json:"objectId,omitempty"`  // The copy an object.duplicate created
 }
 
 // OperationNackPayload is the payload for op.nack messages
@@ -143,6 +320,7 @@ type OperationNackPayload struct {
 	OperationID string     `json:"operationId"`
 	Reason      string     `json:"reason"`
 	Conflict    *Operation `json:"conflictingOp,omitempty"`
+	Field       string     `json:"field,omitempty"` // Offending field when an ID or value failed validation
 }
 
 // OperationBroadcastPayload is the payload for op.broadcast messages
@@ -151,3 +329,63 @@ type OperationBroadcastPayload struct {
 	UserID    string    `json:"userId"`
 	ServerSeq int64     `json:"serverSeq"`
 }
+
+// ResyncRequestPayload is the payload for doc.resync messages.
+type ResyncRequestPayload struct {
+	LastSeq int64 `json:"lastSeq"` // The last server sequence the client saw
+}
+
+// ResyncOpsPayload is the payload for doc.resyncOps messages: every
+// operation sequenced after LastSeq, in order, as each was broadcast, which
+// brings the client to ServerSeq.
+type ResyncOpsPayload struct {
+	LastSeq    int64                       `json:"lastSeq"`
+	ServerSeq  int64                       `json:"serverSeq"`
+	Operations []OperationBroadcastPayload `json:"operations"`
+}
+
+// OperationBatchPayload is the payload for op.batch messages: operations to
+// apply in order, atomically. ID identifies the batch in the answer.
+type OperationBatchPayload struct {
+	ID         string      `json:"id"`
+	Operations []Operation `json:"operations"`
+}
+
+// OperationBatchAckPayload is the payload for op.batchAck messages. The
+// operations that changed the document were sequenced FirstSeq through
+// ServerSeq, in batch order; FirstSeq is zero when none did.
+type OperationBatchAckPayload struct {
+	BatchID         string                                  `json:"batchId"`
+	OperationIDs    []string                                `json:"operationIds"`
+	FirstSeq        int64                                   `json:"firstSeq,omitempty"`
+	ServerSeq       int64                                   `json:"serverSeq"`
+	ServerTimestamp int64                                   `json:"serverTimestamp"`
+	IDMaps          map[string]map[string]map[string]string `json:"idMaps,omitempty"` // Operation ID → IDs the server assigned it
+}
+
+// OperationBatchNackPayload is the payload for op.batchNack messages. The
+// operation at Index failed, so none of the batch was applied.
+type OperationBatchNackPayload struct {
+	BatchID string `json:"batchId"`
+	Index   int    `json:"index"`
+	OperationNackPayload
+}
+
+// OperationBatchBroadcastPayload is the payload for op.batchBroadcast
+// messages: the operations of a batch that changed the document, sequenced
+// FirstSeq through ServerSeq.
+type OperationBatchBroadcastPayload struct {
+	BatchID    string      `json:"batchId"`
+	Operations []Operation `json:"operations"`
+	UserID     string      `json:"userId"`
+	FirstSeq   int64       `json:"firstSeq"`
+	ServerSeq  int64       `json:"serverSeq"`
+}
+
+// AssetUpdatedPayload announces that an asset's content was replaced. Asset
+// is its new record, whose URL names the new revision.
+type AssetUpdatedPayload struct {
+	Asset     document.Asset `json:"asset"`
+	UserID    string         `json:"userId"`
+	ServerSeq int64          `json:"serverSeq"`
+}
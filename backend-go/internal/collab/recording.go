@@ -0,0 +1,203 @@
+package collab
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+)
+
+// DefaultDownsampleWindow is the bucket size DownsampleTransforms uses for
+// recordings served over HTTP.
+const DefaultDownsampleWindow = 100 * time.Millisecond
+
+var errNoRecordingBase = errors.New("recording base document unavailable")
+
+// RecordedOperation is an applied operation as kept in a room's op log.
+type RecordedOperation struct {
+	Seq       int64     `json:"seq"`
+	UserID    string    `json:"userId"`
+	Timestamp int64     `json:"timestamp"` // Server time the op was applied (Unix ms)
+	Operation Operation `json:"operation"`
+}
+
+// Recording is a span of a room's history: the document as it stood before
+// FromSeq and the operations applied from FromSeq through ToSeq.
+type Recording struct {
+	FromSeq    int64                `json:"fromSeq"`
+	ToSeq      int64                `json:"toSeq"`
+	Document   *document.InDocument `json:"document"`
+	Operations []RecordedOperation  `json:"operations"`
+}
+
+// Recording returns the operations with fromSeq <= seq <= toSeq, along with
+// the document they apply to. A fromSeq before the oldest operation in the
+// room's log starts there; toSeq <= 0 runs through the latest.
+func (ds *DocumentState) Recording(fromSeq, toSeq int64) (*Recording, error) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	if ds.base == nil {
+		return nil, errNoRecordingBase
+	}
+	if fromSeq <= ds.baseSeq {
+		fromSeq = ds.baseSeq + 1
+	}
+	if toSeq <= 0 || toSeq > ds.serverSeq {
+		toSeq = ds.serverSeq
+	}
+	// The log holds every op applied since base, so seq n is at index
+	// n-baseSeq-1
+	start := min(fromSeq-ds.baseSeq-1, int64(len(ds.opLog)))
+	end := max(start, toSeq-ds.baseSeq)
+
+	var doc document.InDocument
+	if err := json.Unmarshal(ds.base, &doc); err != nil {
+		return nil, fmt.Errorf("decode recording base: %w", err)
+	}
+	replay := NewDocumentState(&doc)
+	for _, rec := range ds.opLog[:start] {
+		if err := replay.ApplyRecorded(rec); err != nil {
+			return nil, fmt.Errorf("replay seq %d: %w", rec.Seq, err)
+		}
+	}
+
+	return &Recording{
+		FromSeq:    fromSeq,
+		ToSeq:      toSeq,
+		Document:   &doc,
+		Operations: append([]RecordedOperation(nil), ds.opLog[start:end]...),
+	}, nil
+}
+
+// OpsAfter returns the logged operations sequenced after seq, oldest first,
+// and the sequence they bring a client at seq to. ok is false when the log
+// can't bring such a client up to date: seq is from before the log's oldest
+// operation or after the current sequence, or more than limit operations followed it.
+func (ds *DocumentState) OpsAfter(seq int64, limit int) (ops []RecordedOperation, serverSeq int64, ok bool) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	if seq < ds.baseSeq || seq > ds.serverSeq || ds.serverSeq-seq > int64(limit) {
+		return nil, ds.serverSeq, false
+	}
+	// Seq n is at index n-baseSeq-1, so the ops after seq start at seq-baseSeq
+	return append([]RecordedOperation(nil), ds.opLog[seq-ds.baseSeq:]...), ds.serverSeq, true
+}
+
+// ApplyRecorded applies an operation exactly as it was logged, skipping the
+// concurrency checks and server-assigned fields that were resolved when it
+// was first applied. Used to replay history.
+func (ds *DocumentState) ApplyRecorded(rec RecordedOperation) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if err := ds.applyOperationLocked(rec.Operation); err != nil {
+		return err
+	}
+	ds.serverSeq++
+	ds.opLog = append(ds.opLog, rec)
+	return nil
+}
+
+// Replay applies logged operations in order with ApplyRecorded, stopping at
+// the first that fails or doesn't follow on from the current sequence. It
+// returns the sequence reached, with an error saying why replay stopped
+// short. Replayed operations count as persisted but unsaved.
+func (ds *DocumentState) Replay(ops []RecordedOperation) (int64, error) {
+	for _, rec := range ops {
+		seq := ds.ServerSeq()
+		if rec.Seq != seq+1 {
+			return seq, fmt.Errorf("op log skips from seq %d to %d", seq, rec.Seq)
+		}
+		if err := ds.ApplyRecorded(rec); err != nil {
+			return seq, fmt.Errorf("replay seq %d: %w", rec.Seq, err)
+		}
+		ds.mu.Lock()
+		ds.persistedSeq = rec.Seq
+		ds.dirty = true
+		ds.mu.Unlock()
+	}
+	return ds.ServerSeq(), nil
+}
+
+// DownsampleTransforms thins out drag-style transform streams: consecutive
+// object.transform ops on the same object within the same window are merged
+// into one op carrying the last value, as long as no other kind of op was
+// applied in between. Other ops are returned unchanged and in order.
+func DownsampleTransforms(ops []RecordedOperation, window time.Duration) []RecordedOperation {
+	windowMs := window.Milliseconds()
+	if windowMs <= 0 {
+		return ops
+	}
+
+	out := make([]RecordedOperation, 0, len(ops))
+	pending := make(map[string]int) // objectID -> index in out of its mergeable transform
+	for _, rec := range ops {
+		if rec.Operation.Type != "object.transform" {
+			clear(pending)
+			out = append(out, rec)
+			continue
+		}
+
+		objectID := rec.Operation.ObjectID
+		if i, ok := pending[objectID]; ok && out[i].Timestamp/windowMs == rec.Timestamp/windowMs {
+			if merged, err := mergeTransforms(out[i], rec); err == nil {
+				out[i] = merged
+				continue
+			}
+		}
+		pending[objectID] = len(out)
+		out = append(out, rec)
+	}
+	return out
+}
+
+// mergeTransforms folds the later transform op into the earlier one: later
+// values win, and the earlier previous values are kept so the merged op still
+// inverts to the state before both.
+func mergeTransforms(earlier, later RecordedOperation) (RecordedOperation, error) {
+	transform, err := overlayFields(earlier.Operation.Transform, later.Operation.Transform, true)
+	if err != nil {
+		return earlier, err
+	}
+	previous, err := overlayFields(earlier.Operation.Previous, later.Operation.Previous, false)
+	if err != nil {
+		return earlier, err
+	}
+
+	merged := later
+	merged.Operation.Transform = transform
+	merged.Operation.Previous = previous
+	merged.Operation.BaseSeq = nil
+	merged.Operation.BaseValue = nil
+	return merged, nil
+}
+
+// overlayFields combines two partial JSON objects. With replace set, fields
+// in top overwrite those in base; otherwise they only fill in missing ones.
+func overlayFields(base, top json.RawMessage, replace bool) (json.RawMessage, error) {
+	fields := make(map[string]json.RawMessage)
+	if len(base) > 0 {
+		if err := json.Unmarshal(base, &fields); err != nil {
+			return nil, err
+		}
+	}
+	if len(top) > 0 {
+		var topFields map[string]json.RawMessage
+		if err := json.Unmarshal(top, &topFields); err != nil {
+			return nil, err
+		}
+		for k, v := range topFields {
+			if _, ok := fields[k]; replace || !ok {
+				fields[k] = v
+			}
+		}
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(fields)
+}
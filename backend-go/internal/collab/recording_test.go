@@ -0,0 +1,160 @@
+package collab
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRecordingRange(t *testing.T) {
+	ds, rectID := rectState(t)
+	moveRect(t, ds, rectID, 10)
+
+	rec, err := ds.Recording(4, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.FromSeq != 4 || rec.ToSeq != 6 || len(rec.Operations) != 3 {
+		t.Fatalf("recording %d..%d with %d ops, want 4..6 with 3", rec.FromSeq, rec.ToSeq, len(rec.Operations))
+	}
+	for i, op := range rec.Operations {
+		if op.Seq != int64(4+i) || op.UserID != "user" || op.Timestamp == 0 {
+			t.Errorf("op %d: seq %d by %q at %d", i, op.Seq, op.UserID, op.Timestamp)
+		}
+	}
+	// The document is as it stood before fromSeq
+	if x := rec.Document.Objects[rectID].Transform.X; x != 3 {
+		t.Errorf("recording base x = %v, want 3", x)
+	}
+	if x := ds.doc.Objects[rectID].Transform.X; x != 10 {
+		t.Errorf("building the recording changed the live document: x = %v", x)
+	}
+
+	// Out of range bounds are clamped to the log
+	rec, err = ds.Recording(0, 99)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.FromSeq != 1 || rec.ToSeq != 10 || len(rec.Operations) != 10 {
+		t.Errorf("recording %d..%d with %d ops, want 1..10 with 10", rec.FromSeq, rec.ToSeq, len(rec.Operations))
+	}
+	if rec, err = ds.Recording(11, 0); err != nil || len(rec.Operations) != 0 {
+		t.Errorf("recording past the latest seq: %v, %v", rec, err)
+	}
+}
+
+// transformAt is a recorded transform of objectID to x at timestamp ms.
+func transformAt(seq int64, objectID string, x, previous float64, ms int64) RecordedOperation {
+	return RecordedOperation{
+		Seq:       seq,
+		UserID:    "user",
+		Timestamp: ms,
+		Operation: Operation{
+			ID:        fmt.Sprintf("op_%d", seq),
+			Type:      "object.transform",
+			ObjectID:  objectID,
+			Transform: json.RawMessage(fmt.Sprintf(`{"x":%v}`, x)),
+			Previous:  json.RawMessage(fmt.Sprintf(`{"x":%v}`, previous)),
+		},
+	}
+}
+
+func TestDownsampleTransforms(t *testing.T) {
+	ops := []RecordedOperation{
+		transformAt(1, "a", 1, 0, 1000),
+		transformAt(2, "b", 5, 0, 1010),
+		transformAt(3, "a", 2, 1, 1020),
+		transformAt(4, "a", 3, 2, 1090),
+		// Next window
+		transformAt(5, "a", 4, 3, 1100),
+		{Seq: 6, Timestamp: 1110, Operation: Operation{Type: "object.style", ObjectID: "a"}},
+		// Not merged across the style op
+		transformAt(7, "a", 5, 4, 1120),
+	}
+	out := DownsampleTransforms(ops, 100*time.Millisecond)
+
+	var seqs []int64
+	for _, rec := range out {
+		seqs = append(seqs, rec.Seq)
+	}
+	if fmt.Sprint(seqs) != "[4 2 5 6 7]" {
+		t.Fatalf("kept seqs %v, want [4 2 5 6 7]", seqs)
+	}
+	// The merged op lands the last value and inverts to the first previous
+	merged := out[0].Operation
+	if string(merged.Transform) != `{"x":3}` || string(merged.Previous) != `{"x":0}` {
+		t.Errorf("merged transform %s previous %s, want x 3 from 0", merged.Transform, merged.Previous)
+	}
+
+	if got := DownsampleTransforms(ops, 0); len(got) != len(ops) {
+		t.Errorf("zero window kept %d ops, want all %d", len(got), len(ops))
+	}
+}
+
+// playerFor returns a player over n moves of a rect to x = 1..n, recorded
+// 100ms apart, and the rect's ID.
+func playerFor(t *testing.T, n int) (*Player, string) {
+	t.Helper()
+	ds, rectID := rectState(t)
+	moveRect(t, ds, rectID, n)
+	rec, err := ds.Recording(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range rec.Operations {
+		rec.Operations[i].Timestamp = 10_000 + int64(i)*100
+	}
+	p, err := NewPlayer(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, rectID
+}
+
+func TestPlayerSteps(t *testing.T) {
+	p, rectID := playerFor(t, 5)
+	x := func() float64 { return p.Document().Objects[rectID].Transform.X }
+
+	if p.Len() != 5 || p.Position() != 0 || x() != 0 {
+		t.Fatalf("new player at %d of %d with x %v", p.Position(), p.Len(), x())
+	}
+	if err := p.Step(); err != nil || x() != 1 {
+		t.Fatalf("after one step x = %v, err %v", x(), err)
+	}
+
+	// The first op is due at once, then one every 100ms
+	if n, err := p.Advance(150 * time.Millisecond); err != nil || n != 1 || x() != 2 {
+		t.Errorf("advance 150ms applied %d (x %v, err %v), want 1", n, x(), err)
+	}
+	p.SetSpeed(2)
+	if n, _ := p.Advance(100 * time.Millisecond); n != 2 || x() != 4 {
+		t.Errorf("advance 100ms at 2x applied %d (x %v), want 2", n, x())
+	}
+
+	if err := p.Seek(1); err != nil || x() != 1 || p.Position() != 1 {
+		t.Errorf("seek back to 1: x %v at %d, err %v", x(), p.Position(), err)
+	}
+	if err := p.Seek(99); err != nil || !p.Done() || x() != 5 {
+		t.Errorf("seek past the end: x %v, done %v, err %v", x(), p.Done(), err)
+	}
+	if n, _ := p.Advance(time.Hour); n != 0 {
+		t.Errorf("advance when done applied %d", n)
+	}
+}
+
+func TestPlayerSkipsIdleGaps(t *testing.T) {
+	p, rectID := playerFor(t, 2)
+	p.ops[1].Timestamp = p.ops[0].Timestamp + time.Hour.Milliseconds()
+
+	p.Advance(0)
+	if n, _ := p.Advance(maxPlaybackGap); n != 1 || p.Document().Objects[rectID].Transform.X != 2 {
+		t.Errorf("advance by the max gap over an hour's idle applied %d, want 1", n)
+	}
+}
+
+func TestNewPlayerNeedsDocument(t *testing.T) {
+	if _, err := NewPlayer(&Recording{}); err == nil {
+		t.Error("player created without a base document")
+	}
+}
@@ -0,0 +1,101 @@
+package collab
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/engine"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+func TestResetTransform(t *testing.T) {
+	ds, rectID := rectState(t)
+	rootID := *ds.doc.Objects[rectID].Parent
+	otherID := addRect(ds, rootID, false)
+
+	moved := document.Transform{X: 30, Y: 40, SX: 2, SY: 0.5, R: 45, SkewX: 10, AX: 20, AY: 15}
+	scaled := document.Transform{SX: 3, SY: 3}
+	for id, tr := range map[string]document.Transform{rectID: moved, otherID: scaled} {
+		obj := ds.doc.Objects[id]
+		obj.Transform = tr
+		ds.doc.Objects[id] = obj
+	}
+
+	apply(t, ds, &Operation{Type: "object.resetTransform", ObjectIDs: []string{rectID, otherID}}, "user")
+	// The anchor stays so the pivot doesn't move
+	want := map[string]document.Transform{
+		rectID:  {SX: 1, SY: 1, AX: 20, AY: 15},
+		otherID: {SX: 1, SY: 1},
+	}
+	for id, w := range want {
+		if got := ds.doc.Objects[id].Transform; got != w {
+			t.Errorf("%s reset to %+v, want %+v", id, got, w)
+		}
+	}
+
+	inverse := undo(t, ds, "user")
+	if inverse.Type != "object.resetTransform" || len(inverse.Transforms) != 2 {
+		t.Errorf("inverse %+v, want a reset to the previous transforms", inverse)
+	}
+	if got := ds.doc.Objects[rectID].Transform; got != moved {
+		t.Errorf("after undo %+v, want %+v", got, moved)
+	}
+	if got := ds.doc.Objects[otherID].Transform; got != scaled {
+		t.Errorf("after undo %+v, want %+v", got, scaled)
+	}
+	redo(t, ds, "user")
+	if got := ds.doc.Objects[rectID].Transform; got != want[rectID] {
+		t.Errorf("after redo %+v, want %+v", got, want[rectID])
+	}
+}
+
+func TestResetTransformKeepsTracks(t *testing.T) {
+	ds, rectID := rectState(t)
+	track := addTrack(ds, rectID, "transform.x", []document.Keyframe{
+		{Frame: 0, Value: json.RawMessage(`100`), Easing: "linear"},
+		{Frame: 10, Value: json.RawMessage(`200`), Easing: "linear"},
+	})
+	obj := ds.doc.Objects[rectID]
+	obj.Transform.X, obj.Transform.Y = 50, 60
+	ds.doc.Objects[rectID] = obj
+
+	apply(t, ds, &Operation{Type: "object.resetTransform", ObjectID: rectID}, "user")
+	if _, ok := ds.doc.Tracks[track.ID]; !ok {
+		t.Fatal("reset removed the object's track")
+	}
+
+	// The track still drives x in playback; y falls back to the reset base
+	sg := engine.BuildSceneGraph(ds.doc, ds.doc.Project.Scenes[0], 5, ds.doc.Project.RootTimeline, false, nil)
+	m := sg.NodesById[rectID].LocalTransform
+	if m[4] != 150 || m[5] != 0 {
+		t.Errorf("translation at frame 5 (%v, %v), want (150, 0)", m[4], m[5])
+	}
+}
+
+func TestResetTransformRejected(t *testing.T) {
+	ds, rectID := rectState(t)
+
+	// Already at the identity
+	_, err := ds.ApplyOperation(&Operation{ID: "op_same", Type: "object.resetTransform", ObjectID: rectID}, "user")
+	if !errors.Is(err, ErrNoChange) {
+		t.Errorf("reset of an identity transform: %v, want ErrNoChange", err)
+	}
+
+	// One missing object fails the whole batch
+	obj := ds.doc.Objects[rectID]
+	obj.Transform.X = 9
+	ds.doc.Objects[rectID] = obj
+	for _, op := range []*Operation{
+		{ID: "op_missing", Type: "object.resetTransform", ObjectIDs: []string{rectID, typeid.NewObjectID()}},
+		{ID: "op_empty", Type: "object.resetTransform"},
+	} {
+		if _, err := ds.ApplyOperation(op, "user"); err == nil {
+			t.Errorf("%s applied", op.ID)
+		}
+	}
+	if x := ds.doc.Objects[rectID].Transform.X; x != 9 {
+		t.Errorf("rejected batch reset x to %v", x)
+	}
+}
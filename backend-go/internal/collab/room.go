@@ -0,0 +1,147 @@
+//go:build !(js && wasm)
+
+package collab
+
+import (
+	"sync"
+	"time"
+)
+
+// roomQueueSize is how much work may wait for a room's goroutine before
+// whoever queues more blocks.
+const roomQueueSize = 256
+
+// Room is a live project: its authoritative document and connected clients.
+// Everything that changes the document, presence or membership runs on the
+// room's own goroutine, one piece of work at a time in the order queued, so
+// a slow room never holds up the others.
+type Room struct {
+	projectID string
+	presence  *PresenceManager
+	locks     *EditLocks
+	docState  *DocumentState // Authoritative document state
+
+	mu      sync.RWMutex       // Guards clients; held while sending to them
+	clients map[string]*Client // clientID -> client; changed only by the room's goroutine
+
+	members int // Clients registered to the room; guarded by Hub.mu
+
+	inbox    chan func()   // Work for the room's goroutine
+	queueMu  sync.RWMutex  // Guards sealed against work being queued
+	sealed   bool          // The room is shutting down and takes no more work
+	stop     chan struct{} // Closed to shut the room down
+	stopOnce sync.Once
+	done     chan struct{} // Closed once the room has saved and its goroutine exited
+
+	saveMu       sync.Mutex // Serializes saves; guards the fields below
+	saveFailures int        // Consecutive failed saves
+	retryAt      time.Time  // Autosave skips the room until then after a failure
+	lastBackup   time.Time  // When the last safety snapshot was taken
+}
+
+func NewRoom(projectID string, docState *DocumentState) *Room {
+	return &Room{
+		projectID: projectID,
+		clients:   make(map[string]*Client),
+		presence:  NewPresenceManager(),
+		locks:     NewEditLocks(),
+		docState:  docState,
+		inbox:     make(chan func(), roomQueueSize),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// runRoom is a room's goroutine. It runs the room's work in order until the
+// room is shut down, then runs whatever is still queued, saves the document
+// if it has unsaved changes, logs the operations the save didn't cover, and
+// exits.
+func (h *Hub) runRoom(room *Room) {
+	defer close(room.done)
+
+loop:
+	for {
+		select {
+		case work := <-room.inbox:
+			work()
+		case <-room.stop:
+			break loop
+		}
+	}
+
+	// Refuse new work. Sealing waits out anyone queueing work, who may be
+	// blocked on a full inbox, so keep running it meanwhile.
+	sealed := make(chan struct{})
+	go func() {
+		room.queueMu.Lock()
+		room.sealed = true
+		room.queueMu.Unlock()
+		close(sealed)
+	}()
+	for draining := true; draining; {
+		select {
+		case work := <-room.inbox:
+			work()
+		case <-sealed:
+			draining = false
+		}
+	}
+	for len(room.inbox) > 0 {
+		(<-room.inbox)()
+	}
+
+	if room.docState.IsDirty() {
+		h.saveRoom(room.projectID, room, "")
+	}
+	h.flushRoom(room)
+
+	h.mu.Lock()
+	if h.closing[room.projectID] == room {
+		delete(h.closing, room.projectID)
+	}
+	h.mu.Unlock()
+}
+
+// enqueue queues work for the room's goroutine, blocking while its inbox is
+// full. It reports false, dropping the work, once the room is shutting down.
+// The room's own goroutine must not call it.
+func (r *Room) enqueue(work func()) bool {
+	r.queueMu.RLock()
+	defer r.queueMu.RUnlock()
+	if r.sealed {
+		return false
+	}
+	r.inbox <- work
+	return true
+}
+
+// do runs work on the room's goroutine and waits for it to finish. It
+// reports false, without running work, once the room is shutting down.
+func (r *Room) do(work func()) bool {
+	finished := make(chan struct{})
+	if !r.enqueue(func() {
+		defer close(finished)
+		work()
+	}) {
+		return false
+	}
+	<-finished
+	return true
+}
+
+// shutdown tells the room's goroutine to finish up and exit. It is safe to
+// call more than once.
+func (r *Room) shutdown() {
+	r.stopOnce.Do(func() { close(r.stop) })
+}
+
+// broadcast sends msg to the room's clients other than excludeClientID.
+func (r *Room) broadcast(msg *Message, excludeClientID string) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, c := range r.clients {
+		if c.ClientID != excludeClientID {
+			c.Send(msg)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+//go:build !(js && wasm)
+
+package collab
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// TestCorruptDocumentNotSaved checks a room whose document fails its
+// integrity check keeps its last good snapshot rather than saving.
+func TestCorruptDocumentNotSaved(t *testing.T) {
+	projectID := typeid.NewProjectID()
+	var saved []*document.InDocument
+	h := NewHub(newGatedLoader().load, func(ctx context.Context, projectID string, doc *document.InDocument, seq int64, label string) error {
+		saved = append(saved, doc)
+		return nil
+	})
+	t.Cleanup(h.Stop)
+	c := unconnected(h, projectID, "alice")
+	h.Register(c)
+	expectSynced(t, c)
+
+	h.mu.RLock()
+	room := h.rooms[projectID]
+	h.mu.RUnlock()
+
+	// A bug leaves the scene root listing a child that doesn't exist
+	ds := room.docState
+	ds.mu.Lock()
+	rootID := ds.doc.Scenes[ds.doc.Project.Scenes[0]].Root
+	root := ds.doc.Objects[rootID]
+	root.Children = append(root.Children, typeid.NewObjectID())
+	ds.doc.Objects[rootID] = root
+	ds.dirty = true
+	ds.mu.Unlock()
+
+	err := h.ForceSave(projectID)
+	if !errors.Is(err, ErrCorruptDocument) {
+		t.Fatalf("save of a corrupt document: %v, want ErrCorruptDocument", err)
+	}
+	if len(saved) != 0 {
+		t.Fatal("corrupt document was saved")
+	}
+	room.saveMu.Lock()
+	failures, retryAt := room.saveFailures, room.retryAt
+	room.saveMu.Unlock()
+	if !ds.IsDirty() || failures != 1 || retryAt.IsZero() {
+		t.Errorf("dirty %v, failures %d, retry at %v; want the room left dirty with its autosave backed off", ds.IsDirty(), failures, retryAt)
+	}
+
+	// Once the document is sound again it saves
+	ds.mu.Lock()
+	root.Children = root.Children[:len(root.Children)-1]
+	ds.doc.Objects[rootID] = root
+	ds.mu.Unlock()
+	if err := h.ForceSave(projectID); err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != 1 || ds.IsDirty() {
+		t.Errorf("%d saves, dirty %v after repair; want one save", len(saved), ds.IsDirty())
+	}
+}
@@ -0,0 +1,91 @@
+package collab
+
+import (
+	"github.com/inamate/inamate/backend-go/internal/engine"
+)
+
+// Bounds is an axis-aligned rectangle in scene coordinates.
+type Bounds struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// selectionQuery is a user's object selection as seen at a frame.
+type selectionQuery struct {
+	objectIDs []string
+	frame     int
+}
+
+// graphKey identifies a scene graph built for resolving selections.
+type graphKey struct {
+	sceneID string
+	frame   int
+}
+
+// selectionBounds resolves each selection to the union of its objects'
+// world-space bounds, in the scene containing them. Scene graphs are built
+// once per scene and frame and shared between selections. A selection whose
+// objects are all missing or empty resolves to nil.
+func (ds *DocumentState) selectionBounds(queries map[string]selectionQuery) map[string]*Bounds {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	sceneByRoot := make(map[string]string, len(ds.doc.Scenes))
+	for id, scene := range ds.doc.Scenes {
+		sceneByRoot[scene.Root] = id
+	}
+
+	graphs := make(map[graphKey]*engine.SceneGraph)
+	result := make(map[string]*Bounds, len(queries))
+	for key, q := range queries {
+		sceneID := ds.sceneOfLocked(q.objectIDs, sceneByRoot)
+		if sceneID == "" {
+			result[key] = nil
+			continue
+		}
+		gk := graphKey{sceneID, q.frame}
+		sg, ok := graphs[gk]
+		if !ok {
+			sg = engine.BuildSceneGraph(ds.doc, sceneID, float64(q.frame), ds.doc.Project.RootTimeline, false, nil)
+			graphs[gk] = sg
+		}
+		r := engine.GetSelectionBounds(sg, q.objectIDs)
+		if r.IsEmpty() {
+			result[key] = nil
+			continue
+		}
+		result[key] = &Bounds{X: r.X, Y: r.Y, Width: r.Width, Height: r.Height}
+	}
+	return result
+}
+
+// sceneOfLocked returns the scene whose tree holds the first existing object
+// in objectIDs, or "" if there is none. A selection never spans scenes.
+func (ds *DocumentState) sceneOfLocked(objectIDs []string, sceneByRoot map[string]string) string {
+	for _, id := range objectIDs {
+		obj, ok := ds.doc.Objects[id]
+		if !ok {
+			continue
+		}
+		// Bounded in case of a parent cycle
+		for steps := 0; obj.Parent != nil && steps <= len(ds.doc.Objects); steps++ {
+			parent, ok := ds.doc.Objects[*obj.Parent]
+			if !ok {
+				break
+			}
+			obj = parent
+		}
+		return sceneByRoot[obj.ID]
+	}
+	return ""
+}
+
+// sameBounds reports whether two resolved selections are equal.
+func sameBounds(a, b *Bounds) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
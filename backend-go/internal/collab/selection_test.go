@@ -0,0 +1,49 @@
+package collab
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+)
+
+func TestSelectionBounds(t *testing.T) {
+	ds, rectID := rectState(t)
+	sceneID := ds.doc.Project.Scenes[0]
+	rootID := ds.doc.Scenes[sceneID].Root
+	// The rect slides from x 0 to 90 over the first ten frames
+	addTrack(ds, rectID, "transform.x", []document.Keyframe{
+		{Frame: 0, Value: json.RawMessage(`0`), Easing: "linear"},
+		{Frame: 9, Value: json.RawMessage(`90`), Easing: "linear"},
+	})
+	otherID := addRect(ds, rootID, false)
+	other := ds.doc.Objects[otherID]
+	other.Transform.X, other.Transform.Y = 100, 50
+	ds.doc.Objects[otherID] = other
+
+	got := ds.selectionBounds(map[string]selectionQuery{
+		"start":   {objectIDs: []string{rectID}},
+		"later":   {objectIDs: []string{rectID}, frame: 5},
+		"missing": {objectIDs: []string{"obj_gone"}},
+		"partly":  {objectIDs: []string{"obj_gone", rectID}},
+		"none":    {},
+	})
+	want := map[string]*Bounds{
+		"start":   {X: 0, Y: 0, Width: 40, Height: 30},
+		"later":   {X: 50, Y: 0, Width: 40, Height: 30},
+		"missing": nil,
+		"partly":  {X: 0, Y: 0, Width: 40, Height: 30},
+		"none":    nil,
+	}
+	for key, w := range want {
+		if !sameBounds(got[key], w) {
+			t.Errorf("%s: bounds %+v, want %+v", key, got[key], w)
+		}
+	}
+
+	// Objects are unioned in world space
+	both := ds.selectionBounds(map[string]selectionQuery{"both": {objectIDs: []string{rectID, otherID}}})["both"]
+	if want := (Bounds{X: 0, Y: 0, Width: 140, Height: 80}); both == nil || *both != want {
+		t.Errorf("union %+v, want %+v", both, want)
+	}
+}
@@ -0,0 +1,236 @@
+package collab
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+)
+
+// OpResult is the server's answer to a submitted operation. Exactly one of
+// Ack and Nack is set, holding the payload of the op.ack or op.nack a
+// WebSocket client would receive.
+type OpResult struct {
+	Ack  *OperationAckPayload  `json:"ack,omitempty"`
+	Nack *OperationNackPayload `json:"nack,omitempty"`
+}
+
+// nackResult rejects an operation with reason.
+func nackResult(operationID, reason string) OpResult {
+	return OpResult{Nack: &OperationNackPayload{OperationID: operationID, Reason: reason}}
+}
+
+// applySubmitted applies an operation userID submitted to ds, checking it
+// against policy first, and returns the answer to give them. applied reports
+// whether the document changed, so the operation must be broadcast.
+func applySubmitted(ds *DocumentState, op *Operation, userID string, policy OpPolicy) (result OpResult, applied bool) {
+	// Only the server marks an operation as an undo
+	op.UndoOf = ""
+	if err := checkInlineAsset(op); err != nil {
+		slog.Warn("operation rejected", "error", err, "opType", op.Type, "user", userID)
+		return OpResult{Nack: &OperationNackPayload{
+			OperationID: op.ID,
+			Reason:      err.Error(),
+			Field:       err.Field,
+		}}, false
+	}
+	return applyPermitted(ds, op, userID, policy)
+}
+
+// undoSubmitted applies the inverse of the operation an op.undo names, or of
+// the sender's most recent operation still in effect when it names none, as
+// a new operation with the ID the client chose, and returns it with the
+// answer to give them. applied reports whether the inverse must be
+// broadcast.
+func undoSubmitted(ds *DocumentState, req OperationUndoPayload, userID string, policy OpPolicy) (inverse *Operation, result OpResult, applied bool) {
+	if req.ID == "" {
+		return nil, nackResult(req.ID, "id is required"), false
+	}
+	var err error
+	if req.OperationID == "" {
+		inverse, err = ds.NextUndo(userID)
+	} else {
+		inverse, err = ds.Inverse(req.OperationID)
+	}
+	if err != nil {
+		slog.Warn("undo rejected", "error", err, "operationId", req.OperationID, "user", userID)
+		return nil, nackResult(req.ID, err.Error()), false
+	}
+	inverse.ID = req.ID
+	result, applied = applyPermitted(ds, inverse, userID, policy)
+	return inverse, result, applied
+}
+
+// redoSubmitted applies the inverse of the sender's most recent undo still
+// in effect, as undoSubmitted does for an undo.
+func redoSubmitted(ds *DocumentState, req OperationRedoPayload, userID string, policy OpPolicy) (inverse *Operation, result OpResult, applied bool) {
+	if req.ID == "" {
+		return nil, nackResult(req.ID, "id is required"), false
+	}
+	inverse, err := ds.NextRedo(userID)
+	if err != nil {
+		slog.Warn("redo rejected", "error", err, "user", userID)
+		return nil, nackResult(req.ID, err.Error()), false
+	}
+	inverse.ID = req.ID
+	result, applied = applyPermitted(ds, inverse, userID, policy)
+	return inverse, result, applied
+}
+
+// applyPermitted applies op to ds if policy permits its type.
+func applyPermitted(ds *DocumentState, op *Operation, userID string, policy OpPolicy) (result OpResult, applied bool) {
+	if !policy.Permits(op.Type) {
+		slog.Warn("operation type not permitted", "opType", op.Type, "user", userID)
+		return nackResult(op.ID, fmt.Sprintf("operation type %q is not permitted", op.Type)), false
+	}
+
+	submitted := op.Transform
+	serverSeq, err := ds.ApplyOperation(op, userID)
+	if errors.Is(err, ErrNoChange) {
+		// Nothing to broadcast; confirm against the current sequence
+		return OpResult{Ack: &OperationAckPayload{
+			OperationID:     op.ID,
+			ServerSeq:       ds.ServerSeq(),
+			ServerTimestamp: GetServerTimestamp(),
+		}}, false
+	}
+	if err != nil {
+		return OpResult{Nack: operationNack(op, err, userID)}, false
+	}
+
+	ack := &OperationAckPayload{
+		OperationID:     op.ID,
+		ServerSeq:       serverSeq,
+		ServerTimestamp: GetServerTimestamp(),
+		IDMap:           op.IDMap,
+	}
+	if op.Type == "object.transform" && !bytes.Equal(submitted, op.Transform) {
+		// Rebased or rounded; the sender has to apply what the server did
+		ack.Transform = op.Transform
+	}
+	if op.Type == "object.duplicate" {
+		ack.ObjectID = op.IDMap[op.ObjectID][op.ObjectID]
+	}
+	return OpResult{Ack: ack}, true
+}
+
+// operationNack is the op.nack answering op, which failed with err.
+func operationNack(op *Operation, err error, userID string) *OperationNackPayload {
+	var conflict *ConflictError
+	if errors.As(err, &conflict) {
+		// Carry the server's current value so the client can rebase and resubmit
+		return &OperationNackPayload{
+			OperationID: op.ID,
+			Reason:      "conflict",
+			Conflict:    &conflict.Current,
+		}
+	}
+	var invalidID *InvalidIDError
+	if errors.As(err, &invalidID) {
+		slog.Warn("operation rejected", "error", err, "opType", op.Type, "user", userID)
+		return &OperationNackPayload{
+			OperationID: op.ID,
+			Reason:      err.Error(),
+			Field:       invalidID.Field,
+		}
+	}
+	var invalidValue *InvalidValueError
+	if errors.As(err, &invalidValue) {
+		slog.Warn("operation rejected", "error", err, "opType", op.Type, "user", userID)
+		return &OperationNackPayload{
+			OperationID: op.ID,
+			Reason:      err.Error(),
+			Field:       invalidValue.Field,
+		}
+	}
+	slog.Warn("operation failed", "error", err, "opType", op.Type, "user", userID)
+	return &OperationNackPayload{OperationID: op.ID, Reason: err.Error()}
+}
+
+// BatchResult is the server's answer to an op.batch. Exactly one of Ack and
+// Nack is set.
+type BatchResult struct {
+	Ack  *OperationBatchAckPayload  `json:"ack,omitempty"`
+	Nack *OperationBatchNackPayload `json:"nack,omitempty"`
+}
+
+// batchNack rejects a whole batch because of the operation at index.
+func batchNack(batchID string, index int, nack OperationNackPayload) BatchResult {
+	return BatchResult{Nack: &OperationBatchNackPayload{BatchID: batchID, Index: index, OperationNackPayload: nack}}
+}
+
+// applySubmittedBatch applies an op.batch userID submitted to ds, all or
+// nothing, checking each operation as applySubmitted does, and returns the
+// answer to give them along with the operations that changed the document,
+// to broadcast.
+func applySubmittedBatch(ds *DocumentState, batch *OperationBatchPayload, userID string, policy OpPolicy) (result BatchResult, applied []Operation) {
+	if batch.ID == "" {
+		return batchNack(batch.ID, 0, OperationNackPayload{Reason: "id is required"}), nil
+	}
+	if len(batch.Operations) == 0 {
+		return batchNack(batch.ID, 0, OperationNackPayload{Reason: "batch has no operations"}), nil
+	}
+
+	ops := batch.Operations
+	for i := range ops {
+		ops[i].UndoOf = ""
+		if err := checkInlineAsset(&ops[i]); err != nil {
+			slog.Warn("operation rejected", "error", err, "opType", ops[i].Type, "user", userID)
+			return batchNack(batch.ID, i, OperationNackPayload{OperationID: ops[i].ID, Reason: err.Error(), Field: err.Field}), nil
+		}
+		if !policy.Permits(ops[i].Type) {
+			slog.Warn("operation type not permitted", "opType", ops[i].Type, "user", userID)
+			return batchNack(batch.ID, i, OperationNackPayload{
+				OperationID: ops[i].ID,
+				Reason:      fmt.Sprintf("operation type %q is not permitted", ops[i].Type),
+			}), nil
+		}
+	}
+
+	seqs, failed, err := ds.ApplyBatch(ops, userID)
+	if err != nil {
+		return batchNack(batch.ID, failed, *operationNack(&ops[failed], err, userID)), nil
+	}
+
+	ack := &OperationBatchAckPayload{
+		BatchID:         batch.ID,
+		OperationIDs:    make([]string, len(ops)),
+		ServerSeq:       ds.ServerSeq(),
+		ServerTimestamp: GetServerTimestamp(),
+	}
+	for i := range ops {
+		ack.OperationIDs[i] = ops[i].ID
+		if seqs[i] == 0 {
+			continue
+		}
+		if ack.FirstSeq == 0 {
+			ack.FirstSeq = seqs[i]
+		}
+		ack.ServerSeq = seqs[i]
+		applied = append(applied, ops[i])
+		if ops[i].IDMap != nil {
+			if ack.IDMaps == nil {
+				ack.IDMaps = make(map[string]map[string]map[string]string)
+			}
+			ack.IDMaps[ops[i].ID] = ops[i].IDMap
+		}
+	}
+	return BatchResult{Ack: ack}, applied
+}
+
+// ApplyOperations applies ops in order to doc, which it takes ownership of,
+// as a room would if userID submitted them, and returns the answer to each.
+// Server sequence numbers count from zero, as in a newly opened room.
+// changed reports whether any operation modified doc.
+func ApplyOperations(doc *document.InDocument, ops []Operation, userID string, policy OpPolicy) (results []OpResult, changed bool) {
+	ds := NewDocumentState(doc)
+	results = make([]OpResult, len(ops))
+	for i := range ops {
+		var applied bool
+		results[i], applied = applySubmitted(ds, &ops[i], userID, policy)
+		changed = changed || applied
+	}
+	return results, changed
+}
@@ -0,0 +1,286 @@
+package collab
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/engine"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// SymbolSnapshot captures a Symbol instance before object.detachSymbol: the
+// symbol and its descendants, its nested timeline if the detach removed it,
+// and every track and keyframe the detach removed or retargeted.
+type SymbolSnapshot struct {
+	Objects   []document.ObjectNode `json:"objects"` // The symbol first, then its descendants
+	Index     int                   `json:"index"`   // The symbol's position among its parent's children
+	Timeline  *document.Timeline    `json:"timeline,omitempty"`
+	Tracks    []document.Track      `json:"tracks"`
+	Keyframes []document.Keyframe   `json:"keyframes"`
+}
+
+// PrepareOperation fills in the fields the server would assign to op against
+// doc, without applying it. Clients use it (through the wasm engine) to apply
+// operations such as object.detachSymbol optimistically with the same result
+// the server will compute.
+func PrepareOperation(doc *document.InDocument, op *Operation) error {
+	ds := &DocumentState{doc: doc, modifiedSeq: make(map[string]int64)}
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return ds.prepareOperationLocked(op)
+}
+
+// subtreeLocked returns rootID and its descendants in preorder.
+func (ds *DocumentState) subtreeLocked(rootID string) []string {
+	ids := []string{rootID}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, ds.doc.Objects[ids[i]].Children...)
+	}
+	return ids
+}
+
+// prepareDetachSymbolLocked assigns IDs for the detached copies (keeping any
+// the client chose), bakes the symbol timeline's first frame into them, and
+// snapshots everything the detach removes for undo.
+func (ds *DocumentState) prepareDetachSymbolLocked(op *Operation) error {
+	symbol, ok := ds.doc.Objects[op.ObjectID]
+	if !ok {
+		return fmt.Errorf("object not found: %s", op.ObjectID)
+	}
+	if symbol.Type != document.ObjectTypeSymbol {
+		return fmt.Errorf("object is not a symbol: %s", op.ObjectID)
+	}
+	if symbol.Parent == nil {
+		return fmt.Errorf("symbol has no parent: %s", op.ObjectID)
+	}
+	parent, ok := ds.doc.Objects[*symbol.Parent]
+	if !ok {
+		return fmt.Errorf("parent not found: %s", *symbol.Parent)
+	}
+
+	subtree := ds.subtreeLocked(op.ObjectID)
+	inSubtree := make(map[string]bool, len(subtree))
+	for _, id := range subtree {
+		inSubtree[id] = true
+	}
+
+	ids := op.IDMap[op.ObjectID]
+	if ids == nil {
+		ids = make(map[string]string, len(subtree))
+	}
+	used := make(map[string]bool, len(subtree))
+	for _, id := range subtree {
+		if ids[id] == "" {
+			ids[id] = typeid.NewObjectID()
+		}
+		if _, exists := ds.doc.Objects[ids[id]]; exists || used[ids[id]] {
+			return fmt.Errorf("id already in use: %s", ids[id])
+		}
+		used[ids[id]] = true
+	}
+	op.IDMap = map[string]map[string]string{op.ObjectID: ids}
+
+	snap := &SymbolSnapshot{
+		Index:     slices.Index(parent.Children, op.ObjectID),
+		Tracks:    []document.Track{},
+		Keyframes: []document.Keyframe{},
+	}
+	for _, id := range subtree {
+		snap.Objects = append(snap.Objects, ds.doc.Objects[id])
+	}
+
+	// The nested timeline goes with the symbol unless another instance
+	// outside it still plays it
+	timelineID := engine.GetSymbolTimelineID(symbol.Data)
+	eval := engine.EvaluateTimeline(ds.doc, timelineID, 0)
+	if timeline, ok := ds.doc.Timelines[timelineID]; ok && !ds.timelineSharedLocked(timelineID, inSubtree) {
+		snap.Timeline = &timeline
+		for _, trackID := range timeline.Tracks {
+			if track, ok := ds.doc.Tracks[trackID]; ok {
+				snap.Tracks = append(snap.Tracks, track)
+				for _, keyID := range track.Keys {
+					if kf, ok := ds.doc.Keyframes[keyID]; ok {
+						snap.Keyframes = append(snap.Keyframes, kf)
+					}
+				}
+			}
+		}
+	}
+	// Tracks on other timelines follow their objects to the new IDs
+	for tlID, timeline := range ds.doc.Timelines {
+		if tlID == timelineID {
+			continue
+		}
+		for _, trackID := range timeline.Tracks {
+			if track, ok := ds.doc.Tracks[trackID]; ok && inSubtree[track.ObjectID] {
+				snap.Tracks = append(snap.Tracks, track)
+			}
+		}
+	}
+	op.PreviousSymbol = snap
+
+	op.DetachedObjects = make([]document.ObjectNode, 0, len(subtree))
+	for _, orig := range snap.Objects {
+		obj, err := bakeOverrides(orig, eval)
+		if err != nil {
+			return err
+		}
+		obj.ID = ids[orig.ID]
+		if orig.ID == op.ObjectID {
+			obj.Type = document.ObjectTypeGroup
+			obj.Data = json.RawMessage(`{}`)
+		} else {
+			newParent := ids[*orig.Parent]
+			obj.Parent = &newParent
+		}
+		obj.Children = make([]string, len(orig.Children))
+		for i, childID := range orig.Children {
+			obj.Children[i] = ids[childID]
+		}
+		op.DetachedObjects = append(op.DetachedObjects, obj)
+	}
+	return nil
+}
+
+// timelineSharedLocked reports whether a Symbol outside the given set of
+// objects plays timelineID.
+func (ds *DocumentState) timelineSharedLocked(timelineID string, except map[string]bool) bool {
+	for id, obj := range ds.doc.Objects {
+		if !except[id] && obj.Type == document.ObjectTypeSymbol && engine.GetSymbolTimelineID(obj.Data) == timelineID {
+			return true
+		}
+	}
+	return false
+}
+
+// bakeOverrides writes an object's evaluated keyframe values into its base
+// transform, style, visibility and data.
+func bakeOverrides(obj document.ObjectNode, eval engine.EvalResult) (document.ObjectNode, error) {
+	if numeric, ok := eval.Numeric[obj.ID]; ok {
+		obj.Transform = engine.ApplyOverridesToTransform(obj.Transform, numeric)
+		obj.Style = engine.ApplyOverridesToStyle(obj.Style, numeric)
+
+		data := make(map[string]interface{})
+		if len(obj.Data) > 0 {
+			if err := json.Unmarshal(obj.Data, &data); err != nil {
+				return obj, fmt.Errorf("invalid data on %s: %w", obj.ID, err)
+			}
+		}
+		changed := false
+		for property, v := range numeric {
+			if key, ok := strings.CutPrefix(property, "data."); ok {
+				data[key] = v
+				changed = true
+			}
+		}
+		if changed {
+			raw, err := json.Marshal(data)
+			if err != nil {
+				return obj, err
+			}
+			obj.Data = raw
+		}
+	}
+	if strs, ok := eval.Strings[obj.ID]; ok {
+		obj.Style = engine.ApplyStringOverridesToStyle(obj.Style, strs)
+	}
+	if v, ok := eval.Bools[obj.ID]["visible"]; ok {
+		obj.Visible = v
+	}
+	return obj, nil
+}
+
+// applyDetachSymbol swaps a Symbol's subtree for its prepared plain copies,
+// drops its nested timeline and retargets other tracks to the copies.
+func (ds *DocumentState) applyDetachSymbol(op Operation) error {
+	snap := op.PreviousSymbol
+	if snap == nil || len(snap.Objects) == 0 || len(op.DetachedObjects) == 0 {
+		return fmt.Errorf("detachSymbol is missing prepared objects")
+	}
+	symbol, ok := ds.doc.Objects[op.ObjectID]
+	if !ok {
+		return fmt.Errorf("object not found: %s", op.ObjectID)
+	}
+	if symbol.Parent == nil {
+		return fmt.Errorf("symbol has no parent: %s", op.ObjectID)
+	}
+	ids := op.IDMap[op.ObjectID]
+
+	if snap.Timeline != nil {
+		for _, trackID := range snap.Timeline.Tracks {
+			for _, keyID := range ds.doc.Tracks[trackID].Keys {
+				delete(ds.doc.Keyframes, keyID)
+			}
+			delete(ds.doc.Tracks, trackID)
+		}
+		delete(ds.doc.Timelines, snap.Timeline.ID)
+	}
+	for _, track := range snap.Tracks {
+		if current, ok := ds.doc.Tracks[track.ID]; ok && ids[current.ObjectID] != "" {
+			current.ObjectID = ids[current.ObjectID]
+			ds.doc.Tracks[track.ID] = current
+		}
+	}
+
+	ds.swapSubtreeLocked(*symbol.Parent, snap.Objects, op.DetachedObjects, snap.Index)
+	return nil
+}
+
+// applyRestoreSymbol is the inverse of object.detachSymbol: it swaps the
+// detached copies back for the snapshot and restores its timeline and tracks.
+func (ds *DocumentState) applyRestoreSymbol(op Operation) error {
+	snap := op.PreviousSymbol
+	if snap == nil || len(snap.Objects) == 0 || len(op.DetachedObjects) == 0 {
+		return fmt.Errorf("restoreSymbol is missing the symbol snapshot")
+	}
+	group, ok := ds.doc.Objects[op.DetachedObjects[0].ID]
+	if !ok {
+		return fmt.Errorf("object not found: %s", op.DetachedObjects[0].ID)
+	}
+	if group.Parent == nil {
+		return fmt.Errorf("group has no parent: %s", group.ID)
+	}
+
+	ds.swapSubtreeLocked(*group.Parent, op.DetachedObjects, snap.Objects, snap.Index)
+
+	if snap.Timeline != nil {
+		ds.doc.Timelines[snap.Timeline.ID] = *snap.Timeline
+	}
+	for _, track := range snap.Tracks {
+		ds.doc.Tracks[track.ID] = track
+	}
+	for _, kf := range snap.Keyframes {
+		ds.doc.Keyframes[kf.ID] = kf
+	}
+	return nil
+}
+
+// swapSubtreeLocked removes the objects in old and inserts those in new, whose
+// first entries are the subtree roots. The new root takes the old root's
+// place under parentID, or fallbackIndex if the old root isn't there.
+func (ds *DocumentState) swapSubtreeLocked(parentID string, old, new []document.ObjectNode, fallbackIndex int) {
+	for _, obj := range old {
+		delete(ds.doc.Objects, obj.ID)
+	}
+	for _, obj := range new {
+		ds.doc.Objects[obj.ID] = obj
+	}
+
+	parent := ds.doc.Objects[parentID]
+	children := make([]string, 0, len(parent.Children)+1)
+	for _, id := range parent.Children {
+		if id != old[0].ID {
+			children = append(children, id)
+		}
+	}
+	index := slices.Index(parent.Children, old[0].ID)
+	if index < 0 {
+		index = fallbackIndex
+	}
+	index = max(0, min(index, len(children)))
+	parent.Children = slices.Insert(children, index, new[0].ID)
+	ds.doc.Objects[parentID] = parent
+}
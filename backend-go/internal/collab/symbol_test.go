@@ -0,0 +1,141 @@
+package collab
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// spinnerState returns a document holding the sample spinner: a Symbol whose
+// nested timeline turns a rect, beside an ellipse, from 45 degrees at its
+// first frame. It returns the symbol's ID and its nested timeline's ID.
+func spinnerState(t *testing.T) (*DocumentState, string, string) {
+	t.Helper()
+	ds, rectID := rectState(t)
+	rootID := *ds.doc.Objects[rectID].Parent
+	symbolID := addRect(ds, rootID, true)
+	timelineID := addTimeline(ds, 24)
+
+	symbol := ds.doc.Objects[symbolID]
+	symbol.Type = document.ObjectTypeSymbol
+	symbol.Transform.X = 100
+	symbol.Data = json.RawMessage(fmt.Sprintf(`{"timelineId":%q,"loop":true}`, timelineID))
+	ds.doc.Objects[symbolID] = symbol
+
+	bar := addRect(ds, symbolID, false)
+	dot := addRect(ds, symbolID, false)
+	ellipse := ds.doc.Objects[dot]
+	ellipse.Type = document.ObjectTypeShapeEllipse
+	ellipse.Data = json.RawMessage(`{"rx":5,"ry":5}`)
+	ds.doc.Objects[dot] = ellipse
+
+	// Move the rotation track from the root timeline onto the nested one
+	track := addTrack(ds, bar, "transform.r", []document.Keyframe{
+		{Frame: 0, Value: json.RawMessage(`45`), Easing: "linear"},
+		{Frame: 24, Value: json.RawMessage(`405`), Easing: "linear"},
+	})
+	root := ds.doc.Timelines[ds.doc.Project.RootTimeline]
+	root.Tracks = root.Tracks[:len(root.Tracks)-1]
+	ds.doc.Timelines[root.ID] = root
+	nested := ds.doc.Timelines[timelineID]
+	nested.Tracks = append(nested.Tracks, track.ID)
+	ds.doc.Timelines[timelineID] = nested
+	return ds, symbolID, timelineID
+}
+
+func TestDetachSymbol(t *testing.T) {
+	ds, symbolID, timelineID := spinnerState(t)
+	rootID := *ds.doc.Objects[symbolID].Parent
+	before, _ := json.Marshal(ds.doc)
+
+	apply(t, ds, &Operation{Type: "object.detachSymbol", ObjectID: symbolID}, "user")
+
+	if _, ok := ds.doc.Objects[symbolID]; ok {
+		t.Fatal("symbol still in the document")
+	}
+	root := ds.doc.Objects[rootID]
+	groupID := root.Children[1]
+	group := ds.doc.Objects[groupID]
+	if group.Type != document.ObjectTypeGroup || group.Transform.X != 100 {
+		t.Fatalf("symbol's place holds %s at x %v, want a Group at 100", group.Type, group.Transform.X)
+	}
+	if string(group.Data) != `{}` {
+		t.Errorf("group data %s, want no timelineId", group.Data)
+	}
+	if len(group.Children) != 2 {
+		t.Fatalf("group has %d children, want the two shapes", len(group.Children))
+	}
+	bar, dot := ds.doc.Objects[group.Children[0]], ds.doc.Objects[group.Children[1]]
+	if bar.Type != document.ObjectTypeShapeRect || dot.Type != document.ObjectTypeShapeEllipse {
+		t.Errorf("children are %s and %s, want a rect and an ellipse", bar.Type, dot.Type)
+	}
+	for _, child := range []document.ObjectNode{bar, dot} {
+		if child.Parent == nil || *child.Parent != groupID {
+			t.Errorf("child %s not parented to the group", child.ID)
+		}
+	}
+	if bar.Transform.R != 45 {
+		t.Errorf("rect rotation %v, want the first frame's 45 baked in", bar.Transform.R)
+	}
+	if _, ok := ds.doc.Timelines[timelineID]; ok {
+		t.Error("nested timeline left behind")
+	}
+	if len(ds.doc.Tracks) != 0 || len(ds.doc.Keyframes) != 0 {
+		t.Errorf("%d tracks and %d keyframes left, want the nested ones removed", len(ds.doc.Tracks), len(ds.doc.Keyframes))
+	}
+
+	undo(t, ds, "user")
+	after, _ := json.Marshal(ds.doc)
+	var want, got map[string]interface{}
+	json.Unmarshal(before, &want)
+	json.Unmarshal(after, &got)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("undo did not restore the symbol:\n got %s\nwant %s", after, before)
+	}
+
+	redo(t, ds, "user")
+	if _, ok := ds.doc.Objects[groupID]; !ok {
+		t.Error("redo did not detach to the same group")
+	}
+}
+
+// TestDetachSymbolKeepsSharedTimeline checks a nested timeline another
+// instance still plays survives the detach.
+func TestDetachSymbolKeepsSharedTimeline(t *testing.T) {
+	ds, symbolID, timelineID := spinnerState(t)
+	rootID := *ds.doc.Objects[symbolID].Parent
+	other := addRect(ds, rootID, true)
+	instance := ds.doc.Objects[other]
+	instance.Type = document.ObjectTypeSymbol
+	instance.Data = ds.doc.Objects[symbolID].Data
+	ds.doc.Objects[other] = instance
+
+	apply(t, ds, &Operation{Type: "object.detachSymbol", ObjectID: symbolID}, "user")
+	if tl, ok := ds.doc.Timelines[timelineID]; !ok || len(tl.Tracks) != 1 {
+		t.Errorf("shared timeline %+v removed", tl)
+	}
+}
+
+func TestDetachSymbolRejected(t *testing.T) {
+	ds, symbolID, _ := spinnerState(t)
+	rootID := *ds.doc.Objects[symbolID].Parent
+	tests := []struct {
+		name string
+		op   *Operation
+	}{
+		{"not a symbol", &Operation{Type: "object.detachSymbol", ObjectID: rootID}},
+		{"missing", &Operation{Type: "object.detachSymbol", ObjectID: typeid.NewObjectID()}},
+		{"id in use", &Operation{Type: "object.detachSymbol", ObjectID: symbolID,
+			IDMap: map[string]map[string]string{symbolID: {symbolID: rootID}}}},
+	}
+	for _, tt := range tests {
+		tt.op.ID = typeid.NewOpID()
+		if _, err := ds.ApplyOperation(tt.op, "user"); err == nil {
+			t.Errorf("%s: applied", tt.name)
+		}
+	}
+}
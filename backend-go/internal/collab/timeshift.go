@@ -0,0 +1,198 @@
+package collab
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+)
+
+// timeRemoval is how a timeline.removeTime changes a timeline's keyframes.
+type timeRemoval struct {
+	frames  map[string]int                 // Keyframe ID → new frame, for those that move
+	deleted map[string]bool                // Keyframes deleted
+	removed map[string][]document.Keyframe // Track ID → keyframes deleted or clamped, as they were
+}
+
+// timeShiftTargetLocked checks a timeline.insertTime or timeline.removeTime
+// and returns the timeline it shifts.
+func (ds *DocumentState) timeShiftTargetLocked(op Operation) (document.Timeline, error) {
+	if op.TimelineID == "" {
+		return document.Timeline{}, fmt.Errorf("timelineId is required")
+	}
+	if op.AtFrame == nil {
+		return document.Timeline{}, fmt.Errorf("atFrame is required")
+	}
+	if *op.AtFrame < 0 {
+		return document.Timeline{}, &InvalidValueError{Field: "atFrame", Reason: "must not be negative"}
+	}
+	if op.Frames <= 0 || op.Frames > maxValueMagnitude {
+		return document.Timeline{}, &InvalidValueError{Field: "frames", Reason: "must be a positive number of frames"}
+	}
+
+	timeline, ok := ds.doc.Timelines[op.TimelineID]
+	if !ok {
+		return document.Timeline{}, fmt.Errorf("timeline not found: %s", op.TimelineID)
+	}
+	if op.Type == "timeline.removeTime" && timeline.Length-op.Frames < 1 {
+		return document.Timeline{}, &InvalidValueError{Field: "frames", Reason: fmt.Sprintf("can't remove %d of the timeline's %d frames", op.Frames, timeline.Length)}
+	}
+	return timeline, nil
+}
+
+// planTimeRemovalLocked works out a timeline.removeTime of the span
+// [AtFrame, AtFrame+Frames). Keyframes inside the span are deleted, or with
+// Clamp moved to AtFrame; keyframes after it move Frames earlier. Where
+// clamping lands several keyframes of a track on AtFrame, the one that was
+// latest is kept and the rest are deleted.
+func (ds *DocumentState) planTimeRemovalLocked(op Operation) (*timeRemoval, error) {
+	timeline, err := ds.timeShiftTargetLocked(op)
+	if err != nil {
+		return nil, err
+	}
+	at, end := *op.AtFrame, *op.AtFrame+op.Frames
+
+	plan := &timeRemoval{
+		frames:  make(map[string]int),
+		deleted: make(map[string]bool),
+		removed: make(map[string][]document.Keyframe),
+	}
+	for _, trackID := range timeline.Tracks {
+		var landing []document.Keyframe // Keyframes that end up on frame at, by original frame
+		for _, keyID := range ds.doc.Tracks[trackID].Keys {
+			kf, ok := ds.doc.Keyframes[keyID]
+			switch {
+			case !ok || kf.Frame < at:
+			case kf.Frame >= end:
+				plan.frames[keyID] = kf.Frame - op.Frames
+				if kf.Frame == end {
+					landing = append(landing, kf)
+				}
+			case op.Clamp:
+				plan.frames[keyID] = at
+				plan.removed[trackID] = append(plan.removed[trackID], kf)
+				landing = append(landing, kf)
+			default:
+				plan.deleted[keyID] = true
+				plan.removed[trackID] = append(plan.removed[trackID], kf)
+			}
+		}
+
+		// Keep the latest of the keyframes landing on at. Only clamped ones
+		// can lose, and they are already recorded.
+		sort.SliceStable(landing, func(i, j int) bool { return landing[i].Frame < landing[j].Frame })
+		for i := 0; i < len(landing)-1; i++ {
+			plan.deleted[landing[i].ID] = true
+		}
+	}
+	return plan, nil
+}
+
+// prepareRemoveTimeLocked records in RemovedKeyframes the keyframes a
+// timeline.removeTime deletes or clamps, so its inverse can put them back.
+func (ds *DocumentState) prepareRemoveTimeLocked(op *Operation) error {
+	plan, err := ds.planTimeRemovalLocked(*op)
+	if err != nil {
+		return err
+	}
+	op.RemovedKeyframes = nil
+	if len(plan.removed) > 0 {
+		op.RemovedKeyframes = plan.removed
+	}
+	return nil
+}
+
+// applyTimeShift inserts or removes time on a timeline.
+//
+// timeline.insertTime moves every keyframe on the timeline's tracks at or
+// after AtFrame Frames later and grows the timeline by as much, then puts
+// back the keyframes in RemovedKeyframes, as they were; that is how a
+// removal is undone. timeline.removeTime deletes the span [AtFrame,
+// AtFrame+Frames) as planTimeRemovalLocked describes and shrinks the
+// timeline.
+func (ds *DocumentState) applyTimeShift(op Operation) error {
+	if op.Type == "timeline.removeTime" {
+		return ds.applyRemoveTime(op)
+	}
+
+	timeline, err := ds.timeShiftTargetLocked(op)
+	if err != nil {
+		return err
+	}
+	for trackID := range op.RemovedKeyframes {
+		if !slices.Contains(timeline.Tracks, trackID) {
+			return fmt.Errorf("track not found on timeline: %s", trackID)
+		}
+		if _, ok := ds.doc.Tracks[trackID]; !ok {
+			return fmt.Errorf("track not found: %s", trackID)
+		}
+	}
+
+	for _, trackID := range timeline.Tracks {
+		for _, keyID := range ds.doc.Tracks[trackID].Keys {
+			if kf, ok := ds.doc.Keyframes[keyID]; ok && kf.Frame >= *op.AtFrame {
+				kf.Frame += op.Frames
+				ds.doc.Keyframes[keyID] = kf
+			}
+		}
+	}
+	for _, trackID := range slices.Sorted(maps.Keys(op.RemovedKeyframes)) {
+		track := ds.doc.Tracks[trackID]
+		for _, kf := range op.RemovedKeyframes[trackID] {
+			ds.doc.Keyframes[kf.ID] = kf
+			if !slices.Contains(track.Keys, kf.ID) {
+				track.Keys = append(track.Keys, kf.ID)
+			}
+		}
+		ds.sortTrackKeys(&track)
+		ds.doc.Tracks[trackID] = track
+	}
+
+	timeline.Length += op.Frames
+	ds.doc.Timelines[op.TimelineID] = timeline
+	return nil
+}
+
+// applyRemoveTime deletes a span of time from a timeline.
+func (ds *DocumentState) applyRemoveTime(op Operation) error {
+	plan, err := ds.planTimeRemovalLocked(op)
+	if err != nil {
+		return err
+	}
+
+	timeline := ds.doc.Timelines[op.TimelineID]
+	for _, trackID := range timeline.Tracks {
+		track, ok := ds.doc.Tracks[trackID]
+		if !ok {
+			continue
+		}
+		keys := make([]string, 0, len(track.Keys))
+		for _, keyID := range track.Keys {
+			if plan.deleted[keyID] {
+				delete(ds.doc.Keyframes, keyID)
+				continue
+			}
+			if frame, ok := plan.frames[keyID]; ok {
+				kf := ds.doc.Keyframes[keyID]
+				kf.Frame = frame
+				ds.doc.Keyframes[keyID] = kf
+			}
+			keys = append(keys, keyID)
+		}
+		track.Keys = keys
+		ds.doc.Tracks[trackID] = track
+	}
+
+	timeline.Length -= op.Frames
+	ds.doc.Timelines[op.TimelineID] = timeline
+	return nil
+}
+
+// sortTrackKeys orders a track's keys by frame.
+func (ds *DocumentState) sortTrackKeys(track *document.Track) {
+	sort.SliceStable(track.Keys, func(i, j int) bool {
+		return ds.doc.Keyframes[track.Keys[i]].Frame < ds.doc.Keyframes[track.Keys[j]].Frame
+	})
+}
@@ -0,0 +1,221 @@
+package collab
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// playgroundSpinner is spinnerState keyed as in the playground project: the
+// rotation runs from frame 0 to 23 of the symbol's 24-frame timeline. It
+// returns the timeline and the rotation's first and last keyframe IDs.
+func playgroundSpinner(t *testing.T) (ds *DocumentState, timelineID, startID, endID string) {
+	ds, _, timelineID = spinnerState(t)
+	track := ds.doc.Tracks[ds.doc.Timelines[timelineID].Tracks[0]]
+	startID, endID = track.Keys[0], track.Keys[1]
+	end := ds.doc.Keyframes[endID]
+	end.Frame = 23
+	ds.doc.Keyframes[endID] = end
+	return ds, timelineID, startID, endID
+}
+
+func insertTime(timelineID string, at, frames int) *Operation {
+	return &Operation{Type: "timeline.insertTime", TimelineID: timelineID, AtFrame: &at, Frames: frames}
+}
+
+func removeTime(timelineID string, at, frames int, clamp bool) *Operation {
+	return &Operation{Type: "timeline.removeTime", TimelineID: timelineID, AtFrame: &at, Frames: frames, Clamp: clamp}
+}
+
+func TestInsertTime(t *testing.T) {
+	ds, timelineID, startID, endID := playgroundSpinner(t)
+	rootKey := addTrack(ds, addRect(ds, ds.doc.Scenes[ds.doc.Project.Scenes[0]].Root, false), "transform.x",
+		[]document.Keyframe{{Frame: 20, Value: []byte(`5`), Easing: document.EasingLinear}}).Keys[0]
+
+	apply(t, ds, insertTime(timelineID, 12, 10), "user")
+	if got := ds.doc.Keyframes[endID].Frame; got != 33 {
+		t.Errorf("end keyframe at frame %d, want 33", got)
+	}
+	if got := ds.doc.Keyframes[startID].Frame; got != 0 {
+		t.Errorf("frame-0 keyframe moved to %d", got)
+	}
+	if got := ds.doc.Timelines[timelineID].Length; got != 34 {
+		t.Errorf("timeline length %d, want 34", got)
+	}
+	if got := ds.doc.Keyframes[rootKey].Frame; got != 20 {
+		t.Errorf("another timeline's keyframe moved to %d", got)
+	}
+
+	// A keyframe exactly at the insertion point moves too
+	apply(t, ds, insertTime(timelineID, 33, 2), "user")
+	if got := ds.doc.Keyframes[endID].Frame; got != 35 {
+		t.Errorf("keyframe at the insertion point at frame %d, want 35", got)
+	}
+
+	// Undo removes the time again, one insertion at a time
+	if inverse := undo(t, ds, "user"); inverse.Type != "timeline.removeTime" || *inverse.AtFrame != 33 || inverse.Frames != 2 {
+		t.Errorf("undone by %s of %d at %d", inverse.Type, inverse.Frames, *inverse.AtFrame)
+	}
+	undo(t, ds, "user")
+	if got := ds.doc.Keyframes[endID].Frame; got != 23 {
+		t.Errorf("end keyframe at frame %d after undo, want 23", got)
+	}
+	if got := ds.doc.Timelines[timelineID].Length; got != 24 {
+		t.Errorf("timeline length %d after undo, want 24", got)
+	}
+	redo(t, ds, "user")
+	if got := ds.doc.Keyframes[endID].Frame; got != 33 {
+		t.Errorf("end keyframe at frame %d after redo, want 33", got)
+	}
+}
+
+func TestRemoveTime(t *testing.T) {
+	ds, timelineID, startID, endID := playgroundSpinner(t)
+	track := ds.doc.Tracks[ds.doc.Timelines[timelineID].Tracks[0]]
+	mid := document.Keyframe{ID: typeid.NewKeyframeID(), Frame: 12, Value: []byte(`180`), Easing: document.EasingLinear}
+	ds.doc.Keyframes[mid.ID] = mid
+	track.Keys = []string{startID, mid.ID, endID}
+	ds.doc.Tracks[track.ID] = track
+	before := trackState(ds, track.ID)
+
+	// Frames 10 to 14 go, and the keyframe in them with them
+	op := removeTime(timelineID, 10, 5, false)
+	apply(t, ds, op, "user")
+	if _, ok := ds.doc.Keyframes[mid.ID]; ok {
+		t.Error("keyframe inside the removed span survived")
+	}
+	if got := ds.doc.Keyframes[endID].Frame; got != 18 {
+		t.Errorf("end keyframe at frame %d, want 18", got)
+	}
+	if got := ds.doc.Tracks[track.ID].Keys; !reflect.DeepEqual(got, []string{startID, endID}) {
+		t.Errorf("track keys %v", got)
+	}
+	if got := op.RemovedKeyframes[track.ID]; len(got) != 1 || !reflect.DeepEqual(got[0], mid) {
+		t.Errorf("recorded %+v as removed, want the middle keyframe", got)
+	}
+
+	// Undo inserts the time and puts the keyframe back as it was
+	undo(t, ds, "user")
+	if got := trackState(ds, track.ID); !reflect.DeepEqual(got, before) {
+		t.Errorf("after undo %+v, want %+v", got, before)
+	}
+	if got := ds.doc.Timelines[timelineID].Length; got != 24 {
+		t.Errorf("timeline length %d after undo, want 24", got)
+	}
+
+	// Clamping keeps the keyframe at the start of the span instead
+	apply(t, ds, removeTime(timelineID, 10, 5, true), "user")
+	if got, ok := ds.doc.Keyframes[mid.ID]; !ok || got.Frame != 10 {
+		t.Errorf("clamped keyframe %+v, want it at frame 10", got)
+	}
+	undo(t, ds, "user")
+	if got := trackState(ds, track.ID); !reflect.DeepEqual(got, before) {
+		t.Errorf("after undoing the clamp %+v, want %+v", got, before)
+	}
+}
+
+func TestTimeShiftRejected(t *testing.T) {
+	ds, timelineID, _, endID := playgroundSpinner(t)
+	tests := []struct {
+		op    *Operation
+		field string
+	}{
+		{insertTime(timelineID, -1, 10), "atFrame"},
+		{insertTime(timelineID, 12, 0), "frames"},
+		{insertTime(timelineID, 12, -3), "frames"},
+		{removeTime(timelineID, 0, 24, false), "frames"},
+	}
+	for _, tt := range tests {
+		var invalid *InvalidValueError
+		if _, err := ds.ApplyOperation(tt.op, "user"); !errors.As(err, &invalid) || invalid.Field != tt.field {
+			t.Errorf("%s of %d at %d: %v, want invalid %s", tt.op.Type, tt.op.Frames, *tt.op.AtFrame, err, tt.field)
+		}
+	}
+	if _, err := ds.ApplyOperation(&Operation{Type: "timeline.insertTime", TimelineID: timelineID, Frames: 5}, "user"); err == nil {
+		t.Error("insertTime without atFrame applied")
+	}
+	if _, err := ds.ApplyOperation(insertTime("tl_missing", 0, 5), "user"); err == nil {
+		t.Error("insertTime on a missing timeline applied")
+	}
+	if got := ds.doc.Keyframes[endID].Frame; got != 23 {
+		t.Errorf("end keyframe at frame %d after rejected shifts", got)
+	}
+}
+
+// rippleTrack adds a transform.x track to the root timeline keyed at frames,
+// each keyframe's value its original frame.
+func rippleTrack(t *testing.T, frames ...int) (*DocumentState, document.Track) {
+	ds, rectID := rectState(t)
+	var keys []document.Keyframe
+	for _, frame := range frames {
+		keys = append(keys, document.Keyframe{Frame: frame, Value: []byte(strconv.Itoa(frame)), Easing: document.EasingLinear})
+	}
+	return ds, addTrack(ds, rectID, "transform.x", keys)
+}
+
+// keyFrames lists a track's keyframes as "frame:value", in track order.
+func keyFrames(ds *DocumentState, trackID string) []string {
+	var got []string
+	for _, kf := range trackState(ds, trackID) {
+		got = append(got, fmt.Sprintf("%d:%s", kf.Frame, kf.Value))
+	}
+	return got
+}
+
+func TestRemoveTimeRipple(t *testing.T) {
+	ds, track := rippleTrack(t, 0, 4, 5, 7, 10, 11, 20)
+	timelineID := ds.doc.Project.RootTimeline
+	before := trackState(ds, track.ID)
+
+	// Removing frames 5 to 10 deletes the keyframes in them and brings the
+	// rest 6 frames earlier
+	apply(t, ds, removeTime(timelineID, 5, 6, false), "user")
+	if got, want := keyFrames(ds, track.ID), []string{"0:0", "4:4", "5:11", "14:20"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("keyframes %v, want %v", got, want)
+	}
+	if got := ds.doc.Timelines[timelineID].Length; got != 42 {
+		t.Errorf("timeline length %d, want 42", got)
+	}
+	if len(ds.doc.Keyframes) != 4 {
+		t.Errorf("%d keyframes in the document, want the 3 in the span deleted", len(ds.doc.Keyframes))
+	}
+
+	undo(t, ds, "user")
+	if got := trackState(ds, track.ID); !reflect.DeepEqual(got, before) {
+		t.Errorf("after undo %+v, want %+v", got, before)
+	}
+	if got := ds.doc.Timelines[timelineID].Length; got != 48 {
+		t.Errorf("timeline length %d after undo, want 48", got)
+	}
+}
+
+func TestRemoveTimeCollapse(t *testing.T) {
+	tests := []struct {
+		name   string
+		frames []int
+		want   []string
+	}{
+		// Clamped keyframes collapse onto frame 5 and the latest survives
+		{"clamped keyframes", []int{0, 5, 7, 20}, []string{"0:0", "5:7", "14:20"}},
+		// The keyframe rippling onto frame 5 is later than any clamped one
+		{"rippled onto clamped", []int{0, 7, 11, 20}, []string{"0:0", "5:11", "14:20"}},
+	}
+	for _, tt := range tests {
+		ds, track := rippleTrack(t, tt.frames...)
+		before := trackState(ds, track.ID)
+
+		apply(t, ds, removeTime(ds.doc.Project.RootTimeline, 5, 6, true), "user")
+		if got := keyFrames(ds, track.ID); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: keyframes %v, want %v", tt.name, got, tt.want)
+		}
+		undo(t, ds, "user")
+		if got := trackState(ds, track.ID); !reflect.DeepEqual(got, before) {
+			t.Errorf("%s: after undo %+v, want %+v", tt.name, got, before)
+		}
+	}
+}
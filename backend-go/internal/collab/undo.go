@@ -0,0 +1,537 @@
+package collab
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+)
+
+// captureUndoLocked records on op, before it is applied, the state it
+// replaces, so its inverse can be built from the log alone. The Previous*
+// fields a client sent are overwritten with the server's values. Operations
+// whose target is missing are left alone for the apply step to reject.
+func (ds *DocumentState) captureUndoLocked(op *Operation) {
+	switch op.Type {
+	case "object.transform":
+		if obj, ok := ds.doc.Objects[op.ObjectID]; ok {
+			op.Previous = previousFields(obj.Transform, op.Transform)
+		}
+	case "object.style":
+		if obj, ok := ds.doc.Objects[op.ObjectID]; ok {
+			op.Previous = previousFields(obj.Style, op.Style)
+		}
+	case "object.data":
+		if obj, ok := ds.doc.Objects[op.ObjectID]; ok {
+			op.Previous = previousFields(obj.Data, op.Data)
+		}
+	case "object.setMeta":
+		if obj, ok := ds.doc.Objects[op.ObjectID]; ok {
+			op.Previous = previousFields(obj.Meta, op.Meta)
+		}
+	case "object.visibility", "object.locked":
+		field := func(obj document.ObjectNode) bool { return obj.Visible }
+		value := op.Visible
+		if op.Type == "object.locked" {
+			field = func(obj document.ObjectNode) bool { return obj.Locked }
+			value = op.Locked
+		}
+		if op.States != nil || len(op.ObjectIDs) > 0 {
+			if targets, err := bulkTargets(*op, value); err == nil {
+				op.PreviousStates = ds.boolStatesLocked(targets, field)
+			}
+		} else if obj, ok := ds.doc.Objects[op.ObjectID]; ok {
+			previous := field(obj)
+			op.PreviousBool = &previous
+		}
+	case "object.solo":
+		if targets, err := ds.soloTargetsLocked(*op); err == nil {
+			op.PreviousStates = ds.boolStatesLocked(targets, func(obj document.ObjectNode) bool { return obj.Visible })
+		}
+	case "object.delete":
+		obj, ok := ds.doc.Objects[op.ObjectID]
+		if !ok {
+			return
+		}
+		op.PreviousObject, _ = json.Marshal(obj)
+		op.PreviousParentChildren = nil
+		if obj.Parent != nil {
+			op.PreviousParentChildren = slices.Clone(ds.doc.Objects[*obj.Parent].Children)
+		}
+	case "object.reparent":
+		op.PreviousParentID, op.PreviousIndex = "", nil
+		obj, ok := ds.doc.Objects[op.ObjectID]
+		if !ok || obj.Parent == nil {
+			return
+		}
+		op.PreviousParentID = *obj.Parent
+		if index := slices.Index(ds.doc.Objects[*obj.Parent].Children, op.ObjectID); index >= 0 {
+			op.PreviousIndex = &index
+		}
+	case "object.reorder":
+		op.PreviousIndex = nil
+		obj, ok := ds.doc.Objects[op.ObjectID]
+		if !ok || obj.Parent == nil {
+			return
+		}
+		if index := slices.Index(ds.doc.Objects[*obj.Parent].Children, op.ObjectID); index >= 0 {
+			op.PreviousIndex = &index
+		}
+	case "project.setRootTimeline":
+		op.PreviousTimelineID = ds.doc.Project.RootTimeline
+	case "keyframe.update":
+		if kf, ok := ds.doc.Keyframes[op.KeyframeID]; ok && op.Changes != nil {
+			op.Previous = previousFields(kf, op.Changes)
+		}
+	case "keyframe.convertEasingToBezier":
+		if kf, ok := ds.doc.Keyframes[op.KeyframeID]; ok {
+			// keyframe.update can't set an empty easing, so undo restores
+			// the linear one it stands for
+			if kf.Easing == "" {
+				kf.Easing = document.EasingLinear
+			}
+			op.Previous = previousFields(kf, json.RawMessage(`{"easing":null,"bezier":null}`))
+		}
+	case "keyframe.delete":
+		if kf, ok := ds.doc.Keyframes[op.KeyframeID]; ok {
+			op.PreviousKeyframe, _ = json.Marshal(kf)
+		}
+	}
+}
+
+// previousFields returns current's values for the fields changes sets, as a
+// JSON object. Fields current doesn't have are null.
+func previousFields(current interface{}, changes json.RawMessage) json.RawMessage {
+	var changed map[string]json.RawMessage
+	if json.Unmarshal(changes, &changed) != nil {
+		return nil
+	}
+	raw, ok := current.(json.RawMessage)
+	if !ok {
+		raw, _ = json.Marshal(current)
+	}
+	var fields map[string]json.RawMessage
+	json.Unmarshal(raw, &fields)
+
+	previous := make(map[string]json.RawMessage, len(changed))
+	for key := range changed {
+		if v, ok := fields[key]; ok {
+			previous[key] = v
+		} else {
+			previous[key] = json.RawMessage("null")
+		}
+	}
+	out, _ := json.Marshal(previous)
+	return out
+}
+
+// boolStatesLocked returns the current value of a boolean field for each
+// object in targets.
+func (ds *DocumentState) boolStatesLocked(targets map[string]bool, field func(document.ObjectNode) bool) map[string]bool {
+	states := make(map[string]bool, len(targets))
+	for id := range targets {
+		if obj, ok := ds.doc.Objects[id]; ok {
+			states[id] = field(obj)
+		}
+	}
+	return states
+}
+
+// Inverse builds the operation that undoes the logged operation with the
+// given ID, from the state it recorded when it was applied. The inverse is
+// marked as undoing it, and is itself logged once applied, so undoing the
+// inverse redoes the original. Only operations still in the room's log can
+// be undone, each once.
+func (ds *DocumentState) Inverse(operationID string) (*Operation, error) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	var original *Operation
+	for i := len(ds.opLog) - 1; i >= 0; i-- {
+		if ds.opLog[i].Operation.ID == operationID {
+			original = &ds.opLog[i].Operation
+			break
+		}
+	}
+	if original == nil {
+		return nil, fmt.Errorf("operation %s is not in this session's history", operationID)
+	}
+	if ds.undoneLocked(operationID) {
+		return nil, fmt.Errorf("operation %s has already been undone", operationID)
+	}
+	return markedInverse(*original)
+}
+
+// historyKind is the part an operation in the log plays in its user's
+// undo history.
+type historyKind int
+
+const (
+	historyEdit historyKind = iota // An operation submitted as itself
+	historyUndo                    // The inverse of an edit or a redo
+	historyRedo                    // The inverse of an undo
+)
+
+// historyLocked classifies every logged operation by ID, and returns the IDs
+// of those that have been undone.
+func (ds *DocumentState) historyLocked() (kinds map[string]historyKind, undone map[string]bool) {
+	kinds = make(map[string]historyKind, len(ds.opLog))
+	undone = make(map[string]bool)
+	for _, rec := range ds.opLog {
+		op := rec.Operation
+		switch {
+		case op.UndoOf == "":
+			kinds[op.ID] = historyEdit
+		case kinds[op.UndoOf] == historyUndo:
+			kinds[op.ID] = historyRedo
+		default:
+			kinds[op.ID] = historyUndo
+		}
+		if op.UndoOf != "" {
+			undone[op.UndoOf] = true
+		}
+	}
+	return kinds, undone
+}
+
+// NextUndo builds the inverse that undoes userID's most recent edit or redo
+// still in effect, so each user steps back through their own work however
+// other users' operations interleave with it. Only operations still in the
+// room's log can be undone, and an operation that can't be undone on
+// the server stops the user undoing any further back.
+func (ds *DocumentState) NextUndo(userID string) (*Operation, error) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	kinds, undone := ds.historyLocked()
+	for i := len(ds.opLog) - 1; i >= 0; i-- {
+		rec := ds.opLog[i]
+		if rec.UserID != userID || undone[rec.Operation.ID] || kinds[rec.Operation.ID] == historyUndo {
+			continue
+		}
+		return markedInverse(rec.Operation)
+	}
+	return nil, fmt.Errorf("nothing to undo")
+}
+
+// NextRedo builds the inverse that redoes what userID's most recent undo
+// still in effect undid. Once the user edits again, what they undid before
+// can no longer be redone.
+func (ds *DocumentState) NextRedo(userID string) (*Operation, error) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	kinds, undone := ds.historyLocked()
+	for i := len(ds.opLog) - 1; i >= 0; i-- {
+		rec := ds.opLog[i]
+		if rec.UserID != userID {
+			continue
+		}
+		kind := kinds[rec.Operation.ID]
+		if kind == historyEdit {
+			break
+		}
+		if kind == historyUndo && !undone[rec.Operation.ID] {
+			return markedInverse(rec.Operation)
+		}
+	}
+	return nil, fmt.Errorf("nothing to redo")
+}
+
+// markedInverse returns the inverse of a logged operation, marked as undoing
+// it.
+func markedInverse(original Operation) (*Operation, error) {
+	inverse, err := inverseOf(original)
+	if err != nil {
+		return nil, err
+	}
+	inverse.UndoOf = original.ID
+	inverse.Timestamp = GetServerTimestamp()
+	return inverse, nil
+}
+
+// undoneLocked reports whether a logged operation undoes operationID.
+func (ds *DocumentState) undoneLocked(operationID string) bool {
+	for _, rec := range ds.opLog {
+		if rec.Operation.UndoOf == operationID {
+			return true
+		}
+	}
+	return false
+}
+
+// inverseOf returns the operation that reverts op, given the state op
+// recorded before it was applied.
+func inverseOf(op Operation) (*Operation, error) {
+	missing := fmt.Errorf("operation %s has no recorded state to undo", op.ID)
+	inverse := &Operation{Type: op.Type, ObjectID: op.ObjectID}
+
+	switch op.Type {
+	case "object.transform":
+		if op.Previous == nil {
+			return nil, missing
+		}
+		inverse.Transform = op.Previous
+	case "object.style":
+		if op.Previous == nil {
+			return nil, missing
+		}
+		inverse.Style = op.Previous
+	case "object.data":
+		if op.Previous == nil {
+			return nil, missing
+		}
+		inverse.Data = op.Previous
+	case "object.setMeta":
+		if op.Previous == nil {
+			return nil, missing
+		}
+		inverse.Meta = op.Previous
+	case "object.visibility", "object.locked":
+		switch {
+		case op.PreviousStates != nil:
+			inverse.States = op.PreviousStates
+		case op.PreviousBool != nil && op.Type == "object.visibility":
+			inverse.Visible = op.PreviousBool
+		case op.PreviousBool != nil:
+			inverse.Locked = op.PreviousBool
+		default:
+			return nil, missing
+		}
+	case "object.solo":
+		if op.PreviousStates == nil {
+			return nil, missing
+		}
+		inverse.Type = "object.visibility"
+		inverse.States = op.PreviousStates
+	case "object.create":
+		var obj struct {
+			ID string `json:"id"`
+		}
+		if json.Unmarshal(op.Object, &obj) != nil || obj.ID == "" {
+			return nil, missing
+		}
+		inverse.Type = "object.delete"
+		inverse.ObjectID = obj.ID
+	case "object.duplicate":
+		copyID := op.IDMap[op.ObjectID][op.ObjectID]
+		if copyID == "" {
+			return nil, missing
+		}
+		inverse.Type = "object.delete"
+		inverse.ObjectID = copyID
+	case "object.delete":
+		var obj document.ObjectNode
+		if op.PreviousObject == nil || json.Unmarshal(op.PreviousObject, &obj) != nil {
+			return nil, missing
+		}
+		inverse.Type = "object.create"
+		inverse.ObjectID = ""
+		inverse.Object = op.PreviousObject
+		if obj.Parent != nil {
+			inverse.ParentID = *obj.Parent
+			if index := slices.Index(op.PreviousParentChildren, obj.ID); index >= 0 {
+				inverse.Index = &index
+			}
+		}
+	case "object.reparent":
+		// Without its index the object would go back to the wrong place
+		if op.PreviousParentID == "" || op.PreviousIndex == nil {
+			return nil, missing
+		}
+		inverse.NewParentID = op.PreviousParentID
+		inverse.NewIndex = *op.PreviousIndex
+	case "object.reorder":
+		if op.PreviousIndex == nil {
+			return nil, missing
+		}
+		inverse.NewIndex = *op.PreviousIndex
+	case "object.resetTransform", "object.nudge":
+		if op.PreviousTransforms == nil {
+			return nil, missing
+		}
+		inverse.Type = "object.resetTransform"
+		inverse.ObjectID = ""
+		inverse.Transforms = op.PreviousTransforms
+	case "object.detachSymbol", "object.restoreSymbol":
+		if op.DetachedObjects == nil || op.PreviousSymbol == nil {
+			return nil, missing
+		}
+		inverse.Type = "object.restoreSymbol"
+		if op.Type == "object.restoreSymbol" {
+			inverse.Type = "object.detachSymbol"
+		}
+		inverse.IDMap = op.IDMap
+		inverse.DetachedObjects = op.DetachedObjects
+		inverse.PreviousSymbol = op.PreviousSymbol
+	case "path.insertPoint":
+		inverse.Type = "path.deletePoint"
+		inverse.Index = op.Index
+	case "path.movePoint":
+		if op.PreviousPoint == nil {
+			return nil, missing
+		}
+		inverse.Index = op.Index
+		inverse.Point = op.PreviousPoint
+	case "path.deletePoint":
+		if op.PreviousCommand == nil {
+			return nil, missing
+		}
+		inverse.Type = "path.insertPoint"
+		inverse.Index = op.Index
+		inverse.Command = op.PreviousCommand
+	case "path.setClosed":
+		if op.PreviousBool == nil {
+			return nil, missing
+		}
+		inverse.Index = op.Index
+		inverse.Closed = op.PreviousBool
+	case "animation.copy", "animation.restore":
+		if op.PreviousAnimation == nil {
+			return nil, missing
+		}
+		inverse.Type = "animation.restore"
+		inverse.ObjectID = ""
+		inverse.TimelineID = op.TimelineID
+		inverse.Animation = op.PreviousAnimation
+	case "timeline.insertTime", "timeline.removeTime":
+		inverse.Type = "timeline.removeTime"
+		if op.Type == "timeline.removeTime" {
+			inverse.Type = "timeline.insertTime"
+		}
+		inverse.ObjectID = ""
+		inverse.TimelineID = op.TimelineID
+		inverse.AtFrame = op.AtFrame
+		inverse.Frames = op.Frames
+		inverse.Clamp = op.Clamp
+		if op.Type == "timeline.removeTime" {
+			inverse.RemovedKeyframes = op.RemovedKeyframes
+		}
+	case "project.setRootTimeline":
+		if op.PreviousTimelineID == "" {
+			return nil, missing
+		}
+		inverse.ObjectID = ""
+		inverse.TimelineID = op.PreviousTimelineID
+	case "track.reverse":
+		// Reversing a track twice restores it, easings included
+		inverse.ObjectID = ""
+		inverse.TrackID = op.TrackID
+		inverse.MirrorEasing = op.MirrorEasing
+	case "keyframe.add":
+		var kf struct {
+			ID string `json:"id"`
+		}
+		kf.ID = op.KeyframeID
+		if op.Keyframe != nil && (json.Unmarshal(op.Keyframe, &kf) != nil || kf.ID == "") {
+			return nil, missing
+		}
+		inverse.Type = "keyframe.delete"
+		inverse.ObjectID = ""
+		inverse.TrackID = op.TrackID
+		inverse.KeyframeID = kf.ID
+	case "keyframe.update", "keyframe.convertEasingToBezier":
+		// Only changes sent as Changes record what they replaced
+		if op.Previous == nil {
+			return nil, missing
+		}
+		inverse.Type = "keyframe.update"
+		inverse.ObjectID = ""
+		inverse.TrackID = op.TrackID
+		inverse.KeyframeID = op.KeyframeID
+		inverse.Changes = op.Previous
+	case "keyframe.delete":
+		if op.PreviousKeyframe == nil {
+			return nil, missing
+		}
+		inverse.Type = "keyframe.add"
+		inverse.ObjectID = ""
+		inverse.TrackID = op.TrackID
+		inverse.Keyframe = op.PreviousKeyframe
+	default:
+		return nil, fmt.Errorf("operation type %q can't be undone on the server", op.Type)
+	}
+	return inverse, nil
+}
+
+// checkUndoLocked rejects an inverse whose targets changed since the
+// operation it undoes, rather than applying it to the wrong state: the
+// original must not have been undone already, everything the inverse
+// addresses must still exist, and an object it recreates must not.
+func (ds *DocumentState) checkUndoLocked(op *Operation) error {
+	if op.UndoOf == "" {
+		return nil
+	}
+	if ds.undoneLocked(op.UndoOf) {
+		return fmt.Errorf("operation %s has already been undone", op.UndoOf)
+	}
+
+	objectGone := func(id string) error {
+		return fmt.Errorf("can't undo %s: object %s no longer exists", op.UndoOf, id)
+	}
+	switch op.Type {
+	case "object.create":
+		var obj document.ObjectNode
+		if json.Unmarshal(op.Object, &obj) == nil {
+			if _, ok := ds.doc.Objects[obj.ID]; ok {
+				return fmt.Errorf("can't undo %s: object %s already exists", op.UndoOf, obj.ID)
+			}
+		}
+		if op.ParentID != "" {
+			if _, ok := ds.doc.Objects[op.ParentID]; !ok {
+				return fmt.Errorf("can't undo %s: parent %s no longer exists", op.UndoOf, op.ParentID)
+			}
+		}
+		return nil
+	case "object.reparent":
+		if _, ok := ds.doc.Objects[op.NewParentID]; !ok {
+			return fmt.Errorf("can't undo %s: parent %s no longer exists", op.UndoOf, op.NewParentID)
+		}
+	case "keyframe.add", "keyframe.delete":
+		if _, ok := ds.doc.Tracks[op.TrackID]; !ok {
+			return fmt.Errorf("can't undo %s: track %s no longer exists", op.UndoOf, op.TrackID)
+		}
+		if op.Type == "keyframe.delete" {
+			if _, ok := ds.doc.Keyframes[op.KeyframeID]; !ok {
+				return fmt.Errorf("can't undo %s: keyframe %s no longer exists", op.UndoOf, op.KeyframeID)
+			}
+		}
+		return nil
+	case "keyframe.update":
+		if _, ok := ds.doc.Keyframes[op.KeyframeID]; !ok {
+			return fmt.Errorf("can't undo %s: keyframe %s no longer exists", op.UndoOf, op.KeyframeID)
+		}
+		return nil
+	case "project.setRootTimeline":
+		if _, ok := ds.doc.Timelines[op.TimelineID]; !ok {
+			return fmt.Errorf("can't undo %s: timeline %s no longer exists", op.UndoOf, op.TimelineID)
+		}
+		return nil
+	case "track.reverse":
+		if _, ok := ds.doc.Tracks[op.TrackID]; !ok {
+			return fmt.Errorf("can't undo %s: track %s no longer exists", op.UndoOf, op.TrackID)
+		}
+		return nil
+	case "animation.restore", "object.restoreSymbol", "object.detachSymbol":
+		// Checked when applied
+		return nil
+	}
+
+	if op.ObjectID != "" {
+		if _, ok := ds.doc.Objects[op.ObjectID]; !ok {
+			return objectGone(op.ObjectID)
+		}
+	}
+	for id := range op.States {
+		if _, ok := ds.doc.Objects[id]; !ok {
+			return objectGone(id)
+		}
+	}
+	for id := range op.Transforms {
+		if _, ok := ds.doc.Objects[id]; !ok {
+			return objectGone(id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,303 @@
+package collab
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/engine"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// apply applies op as userID, failing the test if it is rejected.
+func apply(t *testing.T, ds *DocumentState, op *Operation, userID string) {
+	t.Helper()
+	if op.ID == "" {
+		op.ID = typeid.NewOpID()
+	}
+	if _, err := ds.ApplyOperation(op, userID); err != nil {
+		t.Fatalf("%s: %v", op.Type, err)
+	}
+}
+
+// undo applies the inverse of userID's most recent edit.
+func undo(t *testing.T, ds *DocumentState, userID string) *Operation {
+	t.Helper()
+	inverse, err := ds.NextUndo(userID)
+	if err != nil {
+		t.Fatalf("undo for %s: %v", userID, err)
+	}
+	apply(t, ds, inverse, userID)
+	return inverse
+}
+
+// redo applies the inverse of userID's most recent undo.
+func redo(t *testing.T, ds *DocumentState, userID string) *Operation {
+	t.Helper()
+	inverse, err := ds.NextRedo(userID)
+	if err != nil {
+		t.Fatalf("redo for %s: %v", userID, err)
+	}
+	apply(t, ds, inverse, userID)
+	return inverse
+}
+
+// addTimeline adds an empty timeline of length frames to ds's document.
+func addTimeline(ds *DocumentState, length int) string {
+	id := typeid.NewTimelineID()
+	ds.doc.Timelines[id] = document.Timeline{ID: id, Length: length, Tracks: []string{}}
+	return id
+}
+
+// addTrack adds a track on objectID's property to the root timeline, with
+// keys at the given frames, and returns it.
+func addTrack(ds *DocumentState, objectID, property string, keys []document.Keyframe) document.Track {
+	track := document.Track{ID: typeid.NewTrackID(), ObjectID: objectID, Property: property}
+	for _, kf := range keys {
+		kf.ID = typeid.NewKeyframeID()
+		ds.doc.Keyframes[kf.ID] = kf
+		track.Keys = append(track.Keys, kf.ID)
+	}
+	ds.doc.Tracks[track.ID] = track
+	root := ds.doc.Timelines[ds.doc.Project.RootTimeline]
+	root.Tracks = append(root.Tracks, track.ID)
+	ds.doc.Timelines[root.ID] = root
+	return track
+}
+
+func TestUndoSetRootTimeline(t *testing.T) {
+	ds, _ := rectState(t)
+	original := ds.doc.Project.RootTimeline
+	promoted := addTimeline(ds, 96)
+
+	apply(t, ds, &Operation{Type: "project.setRootTimeline", TimelineID: promoted}, "user")
+	if got := ds.doc.Project.RootTimeline; got != promoted {
+		t.Fatalf("root timeline %s, want %s", got, promoted)
+	}
+
+	inverse := undo(t, ds, "user")
+	if inverse.TimelineID != original {
+		t.Errorf("inverse sets root to %s, want %s", inverse.TimelineID, original)
+	}
+	if got := ds.doc.Project.RootTimeline; got != original {
+		t.Errorf("after undo root timeline %s, want %s", got, original)
+	}
+
+	redo(t, ds, "user")
+	if got := ds.doc.Project.RootTimeline; got != promoted {
+		t.Errorf("after redo root timeline %s, want %s", got, promoted)
+	}
+}
+
+func TestSetRootTimelineUpdatesEngineFrames(t *testing.T) {
+	ds, _ := rectState(t)
+	promoted := addTimeline(ds, 96)
+	eng := engine.NewEngine()
+	eng.ReplaceDocument(ds.GetDocument())
+	if got := eng.GetTotalFrames(); got != 48 {
+		t.Fatalf("total frames %d before, want 48", got)
+	}
+
+	apply(t, ds, &Operation{Type: "project.setRootTimeline", TimelineID: promoted}, "user")
+	eng.ReplaceDocument(ds.GetDocument())
+	if got := eng.GetTotalFrames(); got != 96 {
+		t.Errorf("total frames %d after promoting a 96-frame timeline, want 96", got)
+	}
+
+	undo(t, ds, "user")
+	eng.ReplaceDocument(ds.GetDocument())
+	if got := eng.GetTotalFrames(); got != 48 {
+		t.Errorf("total frames %d after undo, want 48", got)
+	}
+}
+
+func TestUndoSetRootTimelineAfterDelete(t *testing.T) {
+	ds, _ := rectState(t)
+	original := ds.doc.Project.RootTimeline
+	promoted := addTimeline(ds, 96)
+	apply(t, ds, &Operation{Type: "project.setRootTimeline", TimelineID: promoted}, "user")
+
+	// Someone removed the timeline that was root before
+	delete(ds.doc.Timelines, original)
+
+	inverse, err := ds.NextUndo("user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inverse.ID = typeid.NewOpID()
+	if _, err := ds.ApplyOperation(inverse, "user"); err == nil {
+		t.Fatal("undo restored a deleted root timeline")
+	}
+	if got := ds.doc.Project.RootTimeline; got != promoted {
+		t.Errorf("refused undo changed the root timeline to %s", got)
+	}
+}
+
+func TestUndoTrackReverse(t *testing.T) {
+	bezier := [4]float64{0.25, 0.5, 0.75, 1.5}
+	for _, mirror := range []bool{false, true} {
+		ds, rectID := rectState(t)
+		track := addTrack(ds, rectID, "transform.r", []document.Keyframe{
+			{Frame: 0, Value: json.RawMessage(`0`), Easing: document.EasingEaseIn},
+			{Frame: 10, Value: json.RawMessage(`90`), Easing: document.EasingCubicBezier, Bezier: &bezier},
+			{Frame: 24, Value: json.RawMessage(`360`), Easing: document.EasingLinear},
+		})
+		before := trackState(ds, track.ID)
+
+		apply(t, ds, &Operation{Type: "track.reverse", TrackID: track.ID, MirrorEasing: mirror}, "user")
+		if reflect.DeepEqual(trackState(ds, track.ID), before) {
+			t.Fatalf("mirror %v: reverse changed nothing", mirror)
+		}
+
+		inverse := undo(t, ds, "user")
+		if inverse.Type != "track.reverse" || inverse.MirrorEasing != mirror {
+			t.Errorf("mirror %v: inverse %s with mirror %v", mirror, inverse.Type, inverse.MirrorEasing)
+		}
+		if got := trackState(ds, track.ID); !reflect.DeepEqual(got, before) {
+			t.Errorf("mirror %v: after undo track is %+v, want %+v", mirror, got, before)
+		}
+	}
+}
+
+// trackState returns a track's keyframes in key order.
+func trackState(ds *DocumentState, trackID string) []document.Keyframe {
+	var keys []document.Keyframe
+	for _, id := range ds.doc.Tracks[trackID].Keys {
+		keys = append(keys, ds.doc.Keyframes[id])
+	}
+	return keys
+}
+
+// addRect adds a rect under parentID, or a group when group is set, at the
+// end of its children, and returns its ID.
+func addRect(ds *DocumentState, parentID string, group bool) string {
+	id := typeid.NewObjectID()
+	obj := document.ObjectNode{
+		ID:        id,
+		Type:      document.ObjectTypeShapeRect,
+		Parent:    &parentID,
+		Children:  []string{},
+		Transform: document.Transform{SX: 1, SY: 1},
+		Style:     document.Style{Fill: "#ff0000", Opacity: 1},
+		Visible:   true,
+		Data:      json.RawMessage(`{"width":40,"height":30}`),
+	}
+	if group {
+		obj.Type = document.ObjectTypeGroup
+		obj.Data = json.RawMessage(`{}`)
+	}
+	ds.doc.Objects[id] = obj
+	parent := ds.doc.Objects[parentID]
+	parent.Children = append(parent.Children, id)
+	ds.doc.Objects[parentID] = parent
+	return id
+}
+
+func TestUndoReparentRestoresIndex(t *testing.T) {
+	ds, first := rectState(t)
+	rootID := *ds.doc.Objects[first].Parent
+	middle := addRect(ds, rootID, false)
+	addRect(ds, rootID, false)
+	group := addRect(ds, rootID, true)
+	want := slices.Clone(ds.doc.Objects[rootID].Children)
+
+	apply(t, ds, &Operation{Type: "object.reparent", ObjectID: middle, NewParentID: group}, "user")
+	undo(t, ds, "user")
+
+	if got := ds.doc.Objects[rootID].Children; !slices.Equal(got, want) {
+		t.Errorf("after undo root children %v, want %v", got, want)
+	}
+	if got := *ds.doc.Objects[middle].Parent; got != rootID {
+		t.Errorf("after undo parent %s, want %s", got, rootID)
+	}
+}
+
+func TestReparentWithoutIndexCannotBeUndone(t *testing.T) {
+	op := Operation{ID: typeid.NewOpID(), Type: "object.reparent", ObjectID: typeid.NewObjectID(), PreviousParentID: typeid.NewObjectID()}
+	if inverse, err := inverseOf(op); err == nil {
+		t.Errorf("inverse %+v built without a previous index", inverse)
+	}
+}
+
+func TestUndoRedoInterleavedUsers(t *testing.T) {
+	ds, alicesRect := rectState(t)
+	rootID := *ds.doc.Objects[alicesRect].Parent
+	bobsRect := addRect(ds, rootID, false)
+	group := addRect(ds, rootID, true)
+	children := slices.Clone(ds.doc.Objects[rootID].Children)
+	transformX := func(id string) float64 { return ds.doc.Objects[id].Transform.X }
+
+	// Alice and Bob each make two edits, interleaved
+	apply(t, ds, &Operation{Type: "object.transform", ObjectID: alicesRect, Transform: json.RawMessage(`{"x":10}`)}, "alice")
+	apply(t, ds, &Operation{Type: "object.transform", ObjectID: bobsRect, Transform: json.RawMessage(`{"x":20}`)}, "bob")
+	apply(t, ds, &Operation{Type: "object.reparent", ObjectID: alicesRect, NewParentID: group}, "alice")
+	apply(t, ds, &Operation{Type: "object.transform", ObjectID: bobsRect, Transform: json.RawMessage(`{"x":30}`)}, "bob")
+
+	// Each undo steps back through its own user's work only
+	undo(t, ds, "alice")
+	if got := ds.doc.Objects[rootID].Children; !slices.Equal(got, children) {
+		t.Errorf("alice's undo: root children %v, want %v", got, children)
+	}
+	if transformX(bobsRect) != 30 {
+		t.Errorf("alice's undo moved bob's rect to x %v", transformX(bobsRect))
+	}
+
+	undo(t, ds, "bob")
+	if transformX(bobsRect) != 20 || transformX(alicesRect) != 10 {
+		t.Errorf("bob's undo: x %v and %v, want bob's 20 and alice's 10", transformX(bobsRect), transformX(alicesRect))
+	}
+
+	undo(t, ds, "alice")
+	if transformX(alicesRect) != 0 {
+		t.Errorf("alice's second undo: x %v, want 0", transformX(alicesRect))
+	}
+
+	// Redo replays the most recent undo first
+	redo(t, ds, "alice")
+	if transformX(alicesRect) != 10 || *ds.doc.Objects[alicesRect].Parent != rootID {
+		t.Errorf("alice's redo: x %v under %s, want x 10 under root", transformX(alicesRect), *ds.doc.Objects[alicesRect].Parent)
+	}
+	redo(t, ds, "alice")
+	if *ds.doc.Objects[alicesRect].Parent != group {
+		t.Errorf("alice's second redo left her rect under %s, want the group", *ds.doc.Objects[alicesRect].Parent)
+	}
+	redo(t, ds, "bob")
+	if transformX(bobsRect) != 30 {
+		t.Errorf("bob's redo: x %v, want 30", transformX(bobsRect))
+	}
+
+	// Nothing is left to redo, and a new edit clears what was undone
+	if _, err := ds.NextRedo("alice"); err == nil {
+		t.Error("alice has more to redo")
+	}
+	undo(t, ds, "bob")
+	apply(t, ds, &Operation{Type: "object.transform", ObjectID: bobsRect, Transform: json.RawMessage(`{"x":40}`)}, "bob")
+	if _, err := ds.NextRedo("bob"); err == nil {
+		t.Error("bob can redo past a new edit")
+	}
+	if err := ds.doc.Validate(); err != nil {
+		t.Errorf("document invalid after undo and redo: %v", err)
+	}
+}
+
+func TestUndoAfterOtherUserDeletedTarget(t *testing.T) {
+	ds, rectID := rectState(t)
+	apply(t, ds, &Operation{Type: "object.transform", ObjectID: rectID, Transform: json.RawMessage(`{"x":10}`)}, "alice")
+	apply(t, ds, &Operation{Type: "object.delete", ObjectID: rectID}, "bob")
+
+	inverse, err := ds.NextUndo("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inverse.ID = typeid.NewOpID()
+	seq := ds.ServerSeq()
+	if _, err := ds.ApplyOperation(inverse, "alice"); err == nil || !strings.Contains(err.Error(), "no longer exists") {
+		t.Errorf("undo of an edit to a deleted object: %v, want no longer exists", err)
+	}
+	if ds.ServerSeq() != seq {
+		t.Error("refused undo was sequenced")
+	}
+}
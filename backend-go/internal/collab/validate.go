@@ -0,0 +1,565 @@
+package collab
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// InvalidIDError rejects an operation carrying an ID that isn't a TypeID with
+// the prefix its field expects.
+type InvalidIDError struct {
+	Field  string // JSON path of the offending field, e.g. "object.parent"
+	ID     string
+	Prefix string
+}
+
+func (e *InvalidIDError) Error() string {
+	return fmt.Sprintf("invalid %s: expected a %q id", e.Field, e.Prefix)
+}
+
+// idRef is an ID field to validate
+type idRef struct {
+	field, id, prefix string
+}
+
+// ValidateOperation checks every entity ID an operation introduces or
+// addresses against the TypeID prefix for its kind, so malformed IDs never
+// reach the document. Empty fields are left to the operation's own checks.
+func ValidateOperation(op *Operation) error {
+	refs := []idRef{
+		{"objectId", op.ObjectID, typeid.PrefixObject},
+		{"sourceObjectId", op.SourceObjectID, typeid.PrefixObject},
+		{"parentId", op.ParentID, typeid.PrefixObject},
+		{"newParentId", op.NewParentID, typeid.PrefixObject},
+		{"sceneId", op.SceneID, typeid.PrefixScene},
+		{"timelineId", op.TimelineID, typeid.PrefixTimeline},
+		{"trackId", op.TrackID, typeid.PrefixTrack},
+		{"keyframeId", op.KeyframeID, typeid.PrefixKeyframe},
+	}
+	for _, id := range op.ObjectIDs {
+		refs = append(refs, idRef{"objectIds", id, typeid.PrefixObject})
+	}
+	for id := range op.States {
+		refs = append(refs, idRef{"states", id, typeid.PrefixObject})
+	}
+	for id := range op.Transforms {
+		refs = append(refs, idRef{"transforms", id, typeid.PrefixObject})
+	}
+	for _, id := range op.TrackIDs {
+		refs = append(refs, idRef{"trackIds", id, typeid.PrefixTrack})
+	}
+	for _, ids := range op.IDMap {
+		for original, id := range ids {
+			// Detaching a symbol maps object IDs, and duplicating maps each
+			// ID to one of the same kind; otherwise new IDs name either a
+			// track or a keyframe
+			if op.Type == "object.detachSymbol" || op.Type == "object.restoreSymbol" {
+				refs = append(refs, idRef{"idMap", id, typeid.PrefixObject})
+			} else if op.Type == "object.duplicate" {
+				refs = append(refs, idRef{"idMap", id, duplicatePrefix(original)})
+			} else if typeid.Validate(id, typeid.PrefixTrack) != nil {
+				refs = append(refs, idRef{"idMap", id, typeid.PrefixKeyframe})
+			}
+		}
+	}
+
+	refs = append(refs, payloadIDs("object", op.Object, typeid.PrefixObject)...)
+	refs = append(refs, payloadIDs("rootObject", op.RootObject, typeid.PrefixObject)...)
+	refs = append(refs, payloadIDs("scene", op.Scene, typeid.PrefixScene)...)
+	refs = append(refs, payloadIDs("track", op.Track, typeid.PrefixTrack)...)
+	refs = append(refs, payloadIDs("keyframe", op.Keyframe, typeid.PrefixKeyframe)...)
+	refs = append(refs, payloadIDs("asset", op.Asset, typeid.PrefixAsset)...)
+	if op.Animation != nil {
+		for _, track := range op.Animation.Tracks {
+			refs = append(refs,
+				idRef{"animation.tracks.id", track.ID, typeid.PrefixTrack},
+				idRef{"animation.tracks.objectId", track.ObjectID, typeid.PrefixObject},
+			)
+		}
+		for _, kf := range op.Animation.Keyframes {
+			refs = append(refs, idRef{"animation.keyframes.id", kf.ID, typeid.PrefixKeyframe})
+		}
+	}
+
+	for trackID, kfs := range op.RemovedKeyframes {
+		refs = append(refs, idRef{"removedKeyframes", trackID, typeid.PrefixTrack})
+		for _, kf := range kfs {
+			refs = append(refs, idRef{"removedKeyframes.id", kf.ID, typeid.PrefixKeyframe})
+		}
+	}
+
+	for _, obj := range op.DetachedObjects {
+		refs = append(refs, objectIDs("detachedObjects", obj)...)
+	}
+	if snap := op.PreviousSymbol; snap != nil {
+		for _, obj := range snap.Objects {
+			refs = append(refs, objectIDs("previousSymbol.objects", obj)...)
+		}
+		if snap.Timeline != nil {
+			refs = append(refs, idRef{"previousSymbol.timeline.id", snap.Timeline.ID, typeid.PrefixTimeline})
+		}
+		for _, track := range snap.Tracks {
+			refs = append(refs,
+				idRef{"previousSymbol.tracks.id", track.ID, typeid.PrefixTrack},
+				idRef{"previousSymbol.tracks.objectId", track.ObjectID, typeid.PrefixObject},
+			)
+		}
+		for _, kf := range snap.Keyframes {
+			refs = append(refs, idRef{"previousSymbol.keyframes.id", kf.ID, typeid.PrefixKeyframe})
+		}
+	}
+
+	if dup := op.Duplicate; dup != nil {
+		for _, obj := range dup.Objects {
+			refs = append(refs, objectIDs("duplicate.objects", obj)...)
+		}
+		for tlID, snap := range dup.Animation {
+			refs = append(refs, idRef{"duplicate.animation", tlID, typeid.PrefixTimeline})
+			for _, track := range snap.Tracks {
+				refs = append(refs,
+					idRef{"duplicate.animation.tracks.id", track.ID, typeid.PrefixTrack},
+					idRef{"duplicate.animation.tracks.objectId", track.ObjectID, typeid.PrefixObject},
+				)
+			}
+			for _, kf := range snap.Keyframes {
+				refs = append(refs, idRef{"duplicate.animation.keyframes.id", kf.ID, typeid.PrefixKeyframe})
+			}
+		}
+	}
+
+	for _, ref := range refs {
+		if ref.id == "" {
+			continue
+		}
+		if typeid.Validate(ref.id, ref.prefix) != nil {
+			return &InvalidIDError{Field: ref.field, ID: ref.id, Prefix: ref.prefix}
+		}
+	}
+	return nil
+}
+
+// payloadIDs collects the IDs in an entity payload: its own id (with prefix)
+// and the object IDs it references.
+func payloadIDs(field string, payload json.RawMessage, prefix string) []idRef {
+	if len(payload) == 0 {
+		return nil
+	}
+	var entity struct {
+		ID       string   `json:"id"`
+		Parent   *string  `json:"parent"`
+		Children []string `json:"children"`
+		Root     string   `json:"root"`
+		ObjectID string   `json:"objectId"`
+	}
+	if json.Unmarshal(payload, &entity) != nil {
+		return nil // Malformed payloads are rejected when applied
+	}
+
+	refs := []idRef{
+		{field + ".id", entity.ID, prefix},
+		{field + ".root", entity.Root, typeid.PrefixObject},
+		{field + ".objectId", entity.ObjectID, typeid.PrefixObject},
+	}
+	if entity.Parent != nil {
+		refs = append(refs, idRef{field + ".parent", *entity.Parent, typeid.PrefixObject})
+	}
+	for _, id := range entity.Children {
+		refs = append(refs, idRef{field + ".children", id, typeid.PrefixObject})
+	}
+	return refs
+}
+
+// objectIDs collects an object's own, parent and child IDs.
+func objectIDs(field string, obj document.ObjectNode) []idRef {
+	refs := []idRef{{field + ".id", obj.ID, typeid.PrefixObject}}
+	if obj.Parent != nil {
+		refs = append(refs, idRef{field + ".parent", *obj.Parent, typeid.PrefixObject})
+	}
+	for _, id := range obj.Children {
+		refs = append(refs, idRef{field + ".children", id, typeid.PrefixObject})
+	}
+	return refs
+}
+
+// duplicatePrefix is the prefix an object.duplicate's copy of original must
+// have: a track's or keyframe's, or otherwise an object's.
+func duplicatePrefix(original string) string {
+	for _, prefix := range []string{typeid.PrefixTrack, typeid.PrefixKeyframe} {
+		if typeid.Validate(original, prefix) == nil {
+			return prefix
+		}
+	}
+	return typeid.PrefixObject
+}
+
+// InvalidValueError rejects an operation carrying a value the document
+// can't hold, such as the null a client's NaN is serialized as or a parent
+// that would put an object inside itself.
+type InvalidValueError struct {
+	Field  string // JSON path of the offending value, e.g. "transform.sx"
+	Reason string
+}
+
+func (e *InvalidValueError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Reason)
+}
+
+// maxInlineAssetSize bounds the data URL an asset bundled with an operation
+// may carry. Larger content belongs in the asset store.
+const maxInlineAssetSize = 256 << 10
+
+// checkInlineAsset rejects an operation whose bundled asset embeds more than
+// maxInlineAssetSize of data in its URL, pointing the client to the upload
+// endpoint instead. Assets logged before the limit still replay, since only
+// submitted operations are checked.
+func checkInlineAsset(op *Operation) *InvalidValueError {
+	if op.Asset == nil {
+		return nil
+	}
+	var asset struct {
+		URL string `json:"url"`
+	}
+	if json.Unmarshal(op.Asset, &asset) != nil {
+		return nil
+	}
+	if strings.HasPrefix(asset.URL, "data:") && len(asset.URL) > maxInlineAssetSize {
+		return &InvalidValueError{
+			Field:  "asset.url",
+			Reason: fmt.Sprintf("inline data over %d KB; upload the file to POST /assets/upload and reference the returned asset", maxInlineAssetSize>>10),
+		}
+	}
+	return nil
+}
+
+// maxValueMagnitude bounds transform and keyframe numbers: far beyond any real
+// scene, yet small enough that composing nested transforms stays finite.
+const maxValueMagnitude = 1e9
+
+// ValidateValues checks that the numbers an operation writes to transforms
+// and keyframes are finite, and that it doesn't set a scale to zero, which
+// would make the object's matrix singular. Malformed payloads are left to the
+// operation's own checks.
+func ValidateValues(op *Operation) error {
+	if op.Transform != nil {
+		var changes map[string]json.RawMessage
+		if json.Unmarshal(op.Transform, &changes) == nil {
+			for _, key := range slices.Sorted(maps.Keys(changes)) {
+				field := "transform." + key
+				v, err := checkNumber(field, changes[key])
+				if err != nil {
+					return err
+				}
+				if (key == "sx" || key == "sy") && v == 0 {
+					return &InvalidValueError{Field: field, Reason: "scale must not be zero"}
+				}
+			}
+		}
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(op.Transforms)) {
+		t := op.Transforms[id]
+		for _, v := range []float64{t.X, t.Y, t.SX, t.SY, t.R, t.AX, t.AY, t.SkewX, t.SkewY} {
+			if !finite(v) {
+				return &InvalidValueError{Field: "transforms." + id, Reason: "values must be finite numbers"}
+			}
+		}
+	}
+
+	switch op.Type {
+	case "keyframe.add":
+		if err := checkKeyframeValue("value", op.Value); err != nil {
+			return err
+		}
+		if err := checkNestedKeyframeValue("keyframe.value", op.Keyframe); err != nil {
+			return err
+		}
+	case "keyframe.update":
+		if err := checkKeyframeValue("value", op.Value); err != nil {
+			return err
+		}
+		if err := checkNestedKeyframeValue("changes.value", op.Changes); err != nil {
+			return err
+		}
+	}
+	if op.Animation != nil {
+		for _, kf := range op.Animation.Keyframes {
+			if err := checkKeyframeValue("animation.keyframes.value", kf.Value); err != nil {
+				return err
+			}
+		}
+	}
+	for _, kfs := range op.RemovedKeyframes {
+		for _, kf := range kfs {
+			if err := checkKeyframeValue("removedKeyframes.value", kf.Value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkNumber decodes a JSON number that must be finite and within
+// maxValueMagnitude.
+func checkNumber(field string, raw json.RawMessage) (float64, error) {
+	var v float64
+	if string(raw) == "null" || json.Unmarshal(raw, &v) != nil {
+		return 0, &InvalidValueError{Field: field, Reason: "must be a number"}
+	}
+	if !finite(v) {
+		return 0, &InvalidValueError{Field: field, Reason: "must be a finite number"}
+	}
+	return v, nil
+}
+
+func finite(v float64) bool {
+	return !math.IsNaN(v) && math.Abs(v) <= maxValueMagnitude
+}
+
+// checkKeyframeValue checks a keyframe value's numbers: the value itself, or
+// each component of an array. Strings and booleans are left alone.
+func checkKeyframeValue(field string, raw json.RawMessage) error {
+	if raw == nil {
+		return nil
+	}
+	var components []json.RawMessage
+	switch {
+	case string(raw) == "null":
+		return &InvalidValueError{Field: field, Reason: "must not be null"}
+	case json.Unmarshal(raw, &components) == nil:
+		for _, c := range components {
+			if _, err := checkNumber(field, c); err != nil {
+				return err
+			}
+		}
+	case raw[0] == '-' || (raw[0] >= '0' && raw[0] <= '9'):
+		_, err := checkNumber(field, raw)
+		return err
+	}
+	return nil
+}
+
+// checkNestedKeyframeValue checks the value field of a keyframe or changes
+// payload.
+func checkNestedKeyframeValue(field string, payload json.RawMessage) error {
+	var nested struct {
+		Value json.RawMessage `json:"value"`
+	}
+	if len(payload) == 0 || json.Unmarshal(payload, &nested) != nil {
+		return nil
+	}
+	return checkKeyframeValue(field, nested.Value)
+}
+
+// roundValues rounds the numbers an operation writes to transforms and
+// styles to decimals places, so a drag doesn't log and broadcast digits that
+// make no visible difference. Payloads are only re-encoded when a value
+// changes; malformed ones are left to the operation's own checks.
+func roundValues(op *Operation, decimals int) {
+	op.Transform = roundFields(op.Transform, decimals)
+	op.Previous = roundFields(op.Previous, decimals)
+	op.Style = roundFields(op.Style, decimals)
+	for id, t := range op.Transforms {
+		op.Transforms[id] = t.Rounded(decimals)
+	}
+}
+
+// roundFields rounds the top-level numbers of a JSON object.
+func roundFields(raw json.RawMessage, decimals int) json.RawMessage {
+	var fields map[string]json.RawMessage
+	if len(raw) == 0 || json.Unmarshal(raw, &fields) != nil {
+		return raw
+	}
+	changed := false
+	for name, v := range fields {
+		if len(v) == 0 || (v[0] != '-' && (v[0] < '0' || v[0] > '9')) {
+			continue
+		}
+		var f float64
+		if json.Unmarshal(v, &f) != nil {
+			continue
+		}
+		if rounded := document.RoundField(name, f, decimals); rounded != f {
+			fields[name], _ = json.Marshal(rounded)
+			changed = true
+		}
+	}
+	if !changed {
+		return raw
+	}
+	rounded, err := json.Marshal(fields)
+	if err != nil {
+		return raw
+	}
+	return rounded
+}
+
+// validateStructureLocked rejects an operation that would leave the document
+// malformed: a reparent that makes a cycle, a reorder past the object's
+// siblings, an object created under a parent that doesn't exist, a keyframe
+// outside its timeline, a scene or timeline with no size, an opacity outside
+// [0, 1] or a malformed fill gradient. Only submitted operations are checked,
+// so logged ones still replay.
+func (ds *DocumentState) validateStructureLocked(op *Operation) error {
+	switch op.Type {
+	case "object.reparent":
+		// Walk up from the new parent; meeting the object means it would
+		// end up inside itself
+		seen := make(map[string]bool)
+		for id := op.NewParentID; id != "" && !seen[id]; {
+			if id == op.ObjectID {
+				return &InvalidValueError{Field: "newParentId", Reason: "can't move an object into itself or one of its descendants"}
+			}
+			seen[id] = true
+			parent := ds.doc.Objects[id].Parent
+			if parent == nil {
+				break
+			}
+			id = *parent
+		}
+
+	case "object.create":
+		if op.ParentID != "" {
+			if _, ok := ds.doc.Objects[op.ParentID]; !ok {
+				return &InvalidValueError{Field: "parentId", Reason: "parent object not found: " + op.ParentID}
+			}
+		}
+		var obj struct {
+			Parent *string `json:"parent"`
+			Style  struct {
+				FillGradient json.RawMessage `json:"fillGradient"`
+			} `json:"style"`
+		}
+		if json.Unmarshal(op.Object, &obj) == nil && obj.Parent != nil && *obj.Parent != "" {
+			if _, ok := ds.doc.Objects[*obj.Parent]; !ok {
+				return &InvalidValueError{Field: "object.parent", Reason: "parent object not found: " + *obj.Parent}
+			}
+		}
+		if err := checkGradient("object.style.fillGradient", obj.Style.FillGradient); err != nil {
+			return err
+		}
+
+	case "object.reorder":
+		obj, ok := ds.doc.Objects[op.ObjectID]
+		if !ok {
+			break
+		}
+		if obj.Parent == nil {
+			return &InvalidValueError{Field: "objectId", Reason: "object has no parent to reorder within"}
+		}
+		parent, ok := ds.doc.Objects[*obj.Parent]
+		if !ok {
+			break
+		}
+		if op.NewIndex < 0 || op.NewIndex >= len(parent.Children) {
+			return &InvalidValueError{Field: "newIndex", Reason: fmt.Sprintf("must be between 0 and %d", len(parent.Children)-1)}
+		}
+
+	case "object.style":
+		var style struct {
+			Opacity      *float64        `json:"opacity"`
+			FillGradient json.RawMessage `json:"fillGradient"`
+		}
+		if json.Unmarshal(op.Style, &style) != nil {
+			break
+		}
+		if style.Opacity != nil && (*style.Opacity < 0 || *style.Opacity > 1) {
+			return &InvalidValueError{Field: "style.opacity", Reason: "must be between 0 and 1"}
+		}
+		if err := checkGradient("style.fillGradient", style.FillGradient); err != nil {
+			return err
+		}
+
+	case "scene.update":
+		var size struct {
+			Width  *float64 `json:"width"`
+			Height *float64 `json:"height"`
+		}
+		if json.Unmarshal(op.Changes, &size) == nil {
+			if size.Width != nil && *size.Width <= 0 {
+				return &InvalidValueError{Field: "changes.width", Reason: "must be positive"}
+			}
+			if size.Height != nil && *size.Height <= 0 {
+				return &InvalidValueError{Field: "changes.height", Reason: "must be positive"}
+			}
+		}
+
+	case "timeline.update":
+		var changes struct {
+			Length *float64 `json:"length"`
+		}
+		if json.Unmarshal(op.Changes, &changes) == nil && changes.Length != nil && *changes.Length < 1 {
+			return &InvalidValueError{Field: "changes.length", Reason: "must be at least 1 frame"}
+		}
+
+	case "keyframe.add":
+		field, frame := "frame", op.Frame
+		if op.Keyframe != nil {
+			var kf struct {
+				Frame *int `json:"frame"`
+			}
+			if json.Unmarshal(op.Keyframe, &kf) != nil {
+				return nil
+			}
+			field, frame = "keyframe.frame", kf.Frame
+		}
+		return ds.checkKeyframeFrameLocked(field, op.TrackID, frame)
+
+	case "keyframe.update":
+		field, frame := "frame", op.Frame
+		if op.Changes != nil {
+			var changes struct {
+				Frame *int `json:"frame"`
+			}
+			if json.Unmarshal(op.Changes, &changes) != nil {
+				return nil
+			}
+			field, frame = "changes.frame", changes.Frame
+		}
+		return ds.checkKeyframeFrameLocked(field, ds.keyframeTrackLocked(op.TrackID, op.KeyframeID).ID, frame)
+	}
+	return nil
+}
+
+// checkGradient checks a gradient being set is well-formed. Absent and null
+// values, which leave or clear the gradient, pass.
+func checkGradient(field string, raw json.RawMessage) error {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	var g document.Gradient
+	if err := json.Unmarshal(raw, &g); err != nil {
+		return &InvalidValueError{Field: field, Reason: "must be a gradient object"}
+	}
+	if err := g.Validate(); err != nil {
+		return &InvalidValueError{Field: field, Reason: err.Error()}
+	}
+	return nil
+}
+
+// checkKeyframeFrameLocked rejects a keyframe frame outside the timeline
+// holding trackID. Frames of tracks no timeline holds aren't checked.
+func (ds *DocumentState) checkKeyframeFrameLocked(field, trackID string, frame *int) error {
+	if frame == nil || trackID == "" {
+		return nil
+	}
+	for _, timeline := range ds.doc.Timelines {
+		if !slices.Contains(timeline.Tracks, trackID) {
+			continue
+		}
+		if *frame < 0 || *frame >= timeline.Length {
+			return &InvalidValueError{
+				Field:  field,
+				Reason: fmt.Sprintf("frame %d is outside the timeline's frames 0 to %d", *frame, timeline.Length-1),
+			}
+		}
+		return nil
+	}
+	return nil
+}
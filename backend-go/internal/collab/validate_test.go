@@ -0,0 +1,308 @@
+package collab
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+func TestRoundValuesGuardsOnlyScales(t *testing.T) {
+	op := &Operation{
+		Type:      "object.transform",
+		Transform: json.RawMessage(`{"x":417.2384756293847,"y":0.0002,"sx":0.0002,"sy":-0.0002}`),
+		Style:     json.RawMessage(`{"opacity":0.0002}`),
+		Transforms: map[string]document.Transform{
+			"obj": {X: 0.0002, SX: 0.0002, SY: 1},
+		},
+	}
+	roundValues(op, 3)
+
+	var transform map[string]float64
+	if err := json.Unmarshal(op.Transform, &transform); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]float64{"x": 417.238, "y": 0, "sx": 0.001, "sy": -0.001}
+	for field, v := range want {
+		if transform[field] != v {
+			t.Errorf("transform.%s = %v, want %v", field, transform[field], v)
+		}
+	}
+	if string(op.Style) != `{"opacity":0}` {
+		t.Errorf("style = %s, want opacity 0", op.Style)
+	}
+	if got := op.Transforms["obj"]; got.X != 0 || got.SX != 0.001 {
+		t.Errorf("transforms = %+v, want x 0 and sx 0.001", got)
+	}
+}
+
+func TestValidateOperationIDs(t *testing.T) {
+	objID, trackID, kfID := typeid.NewObjectID(), typeid.NewTrackID(), typeid.NewKeyframeID()
+	tests := []struct {
+		name  string
+		op    Operation
+		field string // Empty if valid
+	}{
+		{"valid ids", Operation{Type: "keyframe.update", ObjectID: objID, TrackID: trackID, KeyframeID: kfID}, ""},
+		{"empty ids", Operation{Type: "object.transform"}, ""},
+		{"legacy object", Operation{Type: "object.transform", ObjectID: "rect_1"}, "objectId"},
+		{"uuid object", Operation{Type: "object.transform", ObjectID: "3f2504e0-4f89-11d3-9a0c-0305e82c3301"}, "objectId"},
+		{"track as object", Operation{Type: "object.transform", ObjectID: trackID}, "objectId"},
+		{"object as parent", Operation{Type: "object.reparent", ObjectID: objID, NewParentID: kfID}, "newParentId"},
+		{"scene", Operation{Type: "scene.update", SceneID: typeid.NewObjectID()}, "sceneId"},
+		{"timeline", Operation{Type: "track.create", TimelineID: typeid.NewTrackID()}, "timelineId"},
+		{"track", Operation{Type: "keyframe.add", TrackID: objID}, "trackId"},
+		{"keyframe", Operation{Type: "keyframe.delete", TrackID: trackID, KeyframeID: trackID}, "keyframeId"},
+		{"object list", Operation{Type: "object.visibility", ObjectIDs: []string{objID, "obj_2"}}, "objectIds"},
+		{"track list", Operation{Type: "animation.copy", TrackIDs: []string{kfID}}, "trackIds"},
+		{"transforms", Operation{Type: "object.transformMany", Transforms: map[string]document.Transform{"bad": {}}}, "transforms"},
+		{"payload id", Operation{Type: "object.create", Object: json.RawMessage(`{"id":"rect_1"}`)}, "object.id"},
+		{"payload parent", Operation{Type: "object.create",
+			Object: json.RawMessage(`{"id":"` + objID + `","parent":"root_playground"}`)}, "object.parent"},
+		{"payload children", Operation{Type: "object.create",
+			Object: json.RawMessage(`{"id":"` + objID + `","children":["` + kfID + `"]}`)}, "object.children"},
+		{"scene payload root", Operation{Type: "scene.create",
+			Scene: json.RawMessage(`{"id":"` + typeid.NewSceneID() + `","root":"root_1"}`)}, "scene.root"},
+		{"track payload object", Operation{Type: "track.create",
+			Track: json.RawMessage(`{"id":"` + trackID + `","objectId":"` + trackID + `"}`)}, "track.objectId"},
+		{"keyframe payload", Operation{Type: "keyframe.add", Keyframe: json.RawMessage(`{"id":"kf_1"}`)}, "keyframe.id"},
+		{"asset payload", Operation{Type: "asset.add", Asset: json.RawMessage(`{"id":"logo"}`)}, "asset.id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateOperation(&tt.op)
+			if tt.field == "" {
+				if err != nil {
+					t.Errorf("rejected: %v", err)
+				}
+				return
+			}
+			var invalid *InvalidIDError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("error %v, want an InvalidIDError", err)
+			}
+			if invalid.Field != tt.field {
+				t.Errorf("field %q, want %q", invalid.Field, tt.field)
+			}
+		})
+	}
+}
+
+// TestInvalidIDNackNamesField checks a submitted op with a malformed ID is
+// nacked with the offending field and leaves the document alone.
+func TestInvalidIDNackNamesField(t *testing.T) {
+	ds, rectID := rectState(t)
+	op := &Operation{
+		ID:          "op_bad_id",
+		Type:        "object.reparent",
+		ObjectID:    rectID,
+		NewParentID: "root_playground",
+	}
+	result, applied := applySubmitted(ds, op, "user", OpPolicy{})
+	if applied || result.Nack == nil {
+		t.Fatalf("answered %+v, want a nack", result)
+	}
+	if result.Nack.Field != "newParentId" {
+		t.Errorf("nack field %q, want newParentId", result.Nack.Field)
+	}
+}
+
+func TestValidateValues(t *testing.T) {
+	ds, rectID := rectState(t)
+	x := addTrack(ds, rectID, "transform.x", []document.Keyframe{{Frame: 0, Value: json.RawMessage(`0`), Easing: document.EasingLinear}})
+	keyID := x.Keys[0]
+	move := func(transform string) *Operation {
+		return &Operation{Type: "object.transform", ObjectID: rectID, Transform: json.RawMessage(transform)}
+	}
+	addKey := func(value string) *Operation {
+		return &Operation{Type: "keyframe.add", TrackID: x.ID,
+			Keyframe: json.RawMessage(`{"id":"` + typeid.NewKeyframeID() + `","frame":5,"value":` + value + `,"easing":"linear"}`)}
+	}
+	updateKey := func(value string) *Operation {
+		return &Operation{Type: "keyframe.update", KeyframeID: keyID, Changes: json.RawMessage(`{"value":` + value + `}`)}
+	}
+
+	tests := []struct {
+		name  string
+		op    *Operation
+		field string // Empty if the op is valid
+	}{
+		// A client's NaN or Infinity reaches the server as null
+		{"NaN x", move(`{"x":null}`), "transform.x"},
+		{"huge y", move(`{"x":1,"y":1e300}`), "transform.y"},
+		{"overflowing r", move(`{"r":1e400}`), "transform.r"},
+		{"zero scale", move(`{"sx":0}`), "transform.sx"},
+		{"zero y scale", move(`{"sx":2,"sy":0}`), "transform.sy"},
+		{"negative scale", move(`{"sx":-1,"x":-40}`), ""},
+		{"NaN reset", &Operation{Type: "object.resetTransform", Transforms: map[string]document.Transform{rectID: {SX: 1, SY: 1, X: math.NaN()}}}, "transforms." + rectID},
+		{"infinite reset", &Operation{Type: "object.resetTransform", Transforms: map[string]document.Transform{rectID: {SX: math.Inf(1), SY: 1}}}, "transforms." + rectID},
+		{"null keyframe", addKey(`null`), "keyframe.value"},
+		{"huge keyframe", addKey(`-2e9`), "keyframe.value"},
+		{"null component", addKey(`[1,null]`), "keyframe.value"},
+		{"vector keyframe", addKey(`[1,2]`), ""},
+		{"color keyframe", addKey(`"#ff0000"`), ""},
+		{"null update", updateKey(`null`), "changes.value"},
+		{"top-level value", &Operation{Type: "keyframe.update", KeyframeID: keyID, Value: json.RawMessage(`null`)}, "value"},
+		{"finite update", updateKey(`12.5`), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateValues(tt.op)
+			var invalid *InvalidValueError
+			switch {
+			case tt.field == "" && err != nil:
+				t.Errorf("rejected: %v", err)
+			case tt.field != "" && !errors.As(err, &invalid):
+				t.Errorf("%v, want an InvalidValueError", err)
+			case tt.field != "" && invalid.Field != tt.field:
+				t.Errorf("field %q, want %q", invalid.Field, tt.field)
+			}
+		})
+	}
+}
+
+// TestInvalidValueNack checks a NaN x and a zero scale are nacked with the
+// offending field and leave the document alone.
+func TestInvalidValueNack(t *testing.T) {
+	ds, rectID := rectState(t)
+	before := ds.doc.Objects[rectID].Transform
+	for field, transform := range map[string]string{
+		"transform.x":  `{"x":null}`,
+		"transform.sy": `{"sy":0}`,
+	} {
+		op := &Operation{ID: typeid.NewOpID(), Type: "object.transform", ObjectID: rectID, Transform: json.RawMessage(transform)}
+		result, applied := applySubmitted(ds, op, "user", OpPolicy{})
+		if applied || result.Nack == nil {
+			t.Fatalf("%s: answered %+v, want a nack", transform, result)
+		}
+		if result.Nack.Field != field {
+			t.Errorf("%s: nack field %q, want %q", transform, result.Nack.Field, field)
+		}
+	}
+	if got := ds.doc.Objects[rectID].Transform; got != before {
+		t.Errorf("transform %+v after rejected ops, want %+v", got, before)
+	}
+
+	live, _, err := ds.SnapshotDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := DryRun(live, []Operation{{ID: "op_nan", Type: "object.transform", ObjectID: rectID, Transform: json.RawMessage(`{"x":null}`)}}, OpPolicy{})
+	if results[0].OK || results[0].Field != "transform.x" {
+		t.Errorf("dry run %+v, want rejected naming transform.x", results[0])
+	}
+}
+
+// TestInlineAssetLimit checks a create bundling an asset is accepted with a
+// data URL of exactly maxInlineAssetSize and nacked, pointing at the upload
+// endpoint, a byte over.
+func TestInlineAssetLimit(t *testing.T) {
+	create := func(ds *DocumentState, url string) (*Operation, string) {
+		rootID := ds.doc.Scenes[ds.doc.Project.Scenes[0]].Root
+		objectID, assetID := typeid.NewObjectID(), typeid.NewAssetID()
+		obj, _ := json.Marshal(document.ObjectNode{
+			ID: objectID, Type: document.ObjectTypeRasterImage, Parent: &rootID, Children: []string{},
+			Transform: document.Transform{SX: 1, SY: 1}, Style: document.Style{Opacity: 1}, Visible: true,
+			Data: json.RawMessage(`{"assetId":"` + assetID + `","width":4,"height":4}`),
+		})
+		asset, _ := json.Marshal(document.Asset{ID: assetID, Type: "image", Name: "pasted.png", URL: url})
+		return &Operation{ID: typeid.NewOpID(), Type: "object.create", ParentID: rootID, Object: obj, Asset: asset}, assetID
+	}
+	dataURL := func(size int) string {
+		const prefix = "data:image/png;base64,"
+		return prefix + strings.Repeat("A", size-len(prefix))
+	}
+
+	ds, _ := rectState(t)
+	op, assetID := create(ds, dataURL(maxInlineAssetSize))
+	if result, applied := applySubmitted(ds, op, "user", OpPolicy{}); !applied || result.Ack == nil {
+		t.Fatalf("asset at the limit answered %+v", result.Nack)
+	}
+	if _, ok := ds.doc.Assets[assetID]; !ok {
+		t.Error("asset at the limit wasn't added")
+	}
+
+	op, assetID = create(ds, dataURL(maxInlineAssetSize+1))
+	result, applied := applySubmitted(ds, op, "user", OpPolicy{})
+	if applied || result.Nack == nil {
+		t.Fatalf("asset over the limit answered %+v", result)
+	}
+	if result.Nack.Field != "asset.url" || !strings.Contains(result.Nack.Reason, "/assets/upload") {
+		t.Errorf("nack %+v, want asset.url pointing at the upload endpoint", result.Nack)
+	}
+	if _, ok := ds.doc.Assets[assetID]; ok {
+		t.Error("asset over the limit was added")
+	}
+
+	// Only inline data counts; a long URL to the asset store is fine
+	op, _ = create(ds, "https://assets.example.com/"+strings.Repeat("a", maxInlineAssetSize))
+	if result, applied := applySubmitted(ds, op, "user", OpPolicy{}); !applied {
+		t.Errorf("long remote URL answered %+v", result.Nack)
+	}
+}
+
+func TestValidateStructure(t *testing.T) {
+	ds, rectID := rectState(t)
+	rootID := *ds.doc.Objects[rectID].Parent
+	group := addRect(ds, rootID, true)
+	child := addRect(ds, group, false)
+	track := addTrack(ds, rectID, "transform.x", []document.Keyframe{{Frame: 0, Value: json.RawMessage(`0`)}})
+	sceneID, timelineID := ds.doc.Project.Scenes[0], ds.doc.Project.RootTimeline
+	missing := typeid.NewObjectID()
+
+	create := func(parentID, payloadParent string) Operation {
+		id := typeid.NewObjectID()
+		obj := fmt.Sprintf(`{"id":%q,"type":"ShapeRect","parent":%q,"children":[],"transform":{"sx":1,"sy":1},"style":{"opacity":1},"visible":true,"data":{"width":1,"height":1}}`, id, payloadParent)
+		return Operation{Type: "object.create", ParentID: parentID, Object: json.RawMessage(obj)}
+	}
+	frame := func(n int) *int { return &n }
+
+	tests := []struct {
+		name   string
+		op     Operation
+		field  string // Empty if valid
+		reason string
+	}{
+		{"reparent into descendant", Operation{Type: "object.reparent", ObjectID: group, NewParentID: child}, "newParentId", "into itself or one of its descendants"},
+		{"reparent into itself", Operation{Type: "object.reparent", ObjectID: group, NewParentID: group}, "newParentId", "into itself or one of its descendants"},
+		{"reparent into sibling", Operation{Type: "object.reparent", ObjectID: rectID, NewParentID: group}, "", ""},
+		{"create under missing parent", create(missing, missing), "parentId", "parent object not found"},
+		{"create with missing payload parent", create("", missing), "object.parent", "parent object not found"},
+		{"create", create(group, group), "", ""},
+		{"opacity above 1", Operation{Type: "object.style", ObjectID: rectID, Style: json.RawMessage(`{"opacity":1.5}`)}, "style.opacity", "between 0 and 1"},
+		{"opacity below 0", Operation{Type: "object.style", ObjectID: rectID, Style: json.RawMessage(`{"opacity":-0.1}`)}, "style.opacity", "between 0 and 1"},
+		{"opacity", Operation{Type: "object.style", ObjectID: rectID, Style: json.RawMessage(`{"opacity":0.5}`)}, "", ""},
+		{"zero scene width", Operation{Type: "scene.update", SceneID: sceneID, Changes: json.RawMessage(`{"width":0}`)}, "changes.width", "must be positive"},
+		{"negative scene height", Operation{Type: "scene.update", SceneID: sceneID, Changes: json.RawMessage(`{"height":-1}`)}, "changes.height", "must be positive"},
+		{"scene size", Operation{Type: "scene.update", SceneID: sceneID, Changes: json.RawMessage(`{"width":640,"height":480}`)}, "", ""},
+		{"zero timeline length", Operation{Type: "timeline.update", TimelineID: timelineID, Changes: json.RawMessage(`{"length":0}`)}, "changes.length", "at least 1 frame"},
+		{"keyframe past the end", Operation{Type: "keyframe.add", TrackID: track.ID, Keyframe: json.RawMessage(`{"id":"` + typeid.NewKeyframeID() + `","frame":48,"value":1}`)}, "keyframe.frame", "outside the timeline's frames 0 to 47"},
+		{"keyframe before the start", Operation{Type: "keyframe.add", TrackID: track.ID, Frame: frame(-1), Value: json.RawMessage(`1`)}, "frame", "outside the timeline's frames 0 to 47"},
+		{"keyframe moved past the end", Operation{Type: "keyframe.update", TrackID: track.ID, KeyframeID: track.Keys[0], Changes: json.RawMessage(`{"frame":60}`)}, "changes.frame", "outside the timeline's frames 0 to 47"},
+		{"keyframe on the last frame", Operation{Type: "keyframe.add", TrackID: track.ID, Keyframe: json.RawMessage(`{"id":"` + typeid.NewKeyframeID() + `","frame":47,"value":1}`)}, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op := tt.op
+			op.ID = typeid.NewOpID()
+			result, applied := applySubmitted(ds, &op, "user", OpPolicy{})
+			if tt.field == "" {
+				if !applied {
+					t.Fatalf("rejected: %+v", result.Nack)
+				}
+				return
+			}
+			if applied || result.Nack == nil {
+				t.Fatalf("answered %+v, want a nack", result)
+			}
+			if result.Nack.Field != tt.field || !strings.Contains(result.Nack.Reason, tt.reason) {
+				t.Errorf("nack %q on %q, want %q on %q", result.Nack.Reason, result.Nack.Field, tt.reason, tt.field)
+			}
+		})
+	}
+}
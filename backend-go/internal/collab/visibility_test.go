@@ -0,0 +1,154 @@
+package collab
+
+import (
+	"errors"
+	"testing"
+)
+
+// layers is a document with two groups under the scene root: a holding
+// rects a1, a2 and a3, and b holding b1.
+type layers struct {
+	ds                 *DocumentState
+	a, a1, a2, a3      string
+	b, b1, first, root string
+}
+
+func newLayers(t *testing.T) layers {
+	ds, first := rectState(t)
+	l := layers{ds: ds, first: first, root: *ds.doc.Objects[first].Parent}
+	l.a = addRect(ds, l.root, true)
+	l.a1, l.a2, l.a3 = addRect(ds, l.a, false), addRect(ds, l.a, false), addRect(ds, l.a, false)
+	l.b = addRect(ds, l.root, true)
+	l.b1 = addRect(ds, l.b, false)
+	return l
+}
+
+// visible reports the visibility of each of ids.
+func (l layers) visible(ids ...string) []bool {
+	out := make([]bool, len(ids))
+	for i, id := range ids {
+		out[i] = l.ds.doc.Objects[id].Visible
+	}
+	return out
+}
+
+func boolPtr(b bool) *bool { return &b }
+
+func TestBulkVisibilitySingleSeq(t *testing.T) {
+	l := newLayers(t)
+	seq := l.ds.ServerSeq()
+
+	apply(t, l.ds, &Operation{Type: "object.visibility", ObjectIDs: []string{l.a1, l.b1, l.b}, Visible: boolPtr(false)}, "user")
+	if got := l.ds.ServerSeq(); got != seq+1 {
+		t.Errorf("server seq %d, want %d", got, seq+1)
+	}
+	if got := l.visible(l.a1, l.a2, l.b, l.b1); got[0] || !got[1] || got[2] || got[3] {
+		t.Errorf("visibility a1 a2 b b1 = %v, want only a2 shown", got)
+	}
+}
+
+func TestBulkStatesMap(t *testing.T) {
+	l := newLayers(t)
+
+	apply(t, l.ds, &Operation{Type: "object.locked", States: map[string]bool{l.a1: true, l.a3: true, l.b1: false}}, "user")
+	for id, want := range map[string]bool{l.a1: true, l.a2: false, l.a3: true, l.b1: false} {
+		if got := l.ds.doc.Objects[id].Locked; got != want {
+			t.Errorf("%s locked %v, want %v", id, got, want)
+		}
+	}
+}
+
+func TestBulkNoChange(t *testing.T) {
+	l := newLayers(t)
+	apply(t, l.ds, &Operation{Type: "object.visibility", ObjectIDs: []string{l.a1, l.a2}, Visible: boolPtr(false)}, "user")
+	seq, logged := l.ds.ServerSeq(), len(l.ds.opLog)
+
+	tests := []struct {
+		name string
+		op   Operation
+	}{
+		{"already hidden", Operation{Type: "object.visibility", ObjectIDs: []string{l.a1, l.a2}, Visible: boolPtr(false)}},
+		{"already unlocked", Operation{Type: "object.locked", States: map[string]bool{l.a1: false, l.b1: false}}},
+		// a3's only siblings are a1 and a2, hidden above
+		{"siblings already hidden", Operation{Type: "object.solo", ObjectIDs: []string{l.a3}}},
+	}
+	for _, tt := range tests {
+		op := tt.op
+		op.ID = "op_" + tt.name
+		if _, err := l.ds.ApplyOperation(&op, "user"); !errors.Is(err, ErrNoChange) {
+			t.Errorf("%s: err %v, want ErrNoChange", tt.name, err)
+		}
+	}
+	if l.ds.ServerSeq() != seq || len(l.ds.opLog) != logged {
+		t.Errorf("no-ops sequenced: seq %d→%d, log %d→%d", seq, l.ds.ServerSeq(), logged, len(l.ds.opLog))
+	}
+}
+
+func TestBulkIsAtomic(t *testing.T) {
+	l := newLayers(t)
+
+	op := &Operation{ID: "op_missing", Type: "object.visibility", ObjectIDs: []string{l.a1, "obj_missing", l.a2}, Visible: boolPtr(false)}
+	if _, err := l.ds.ApplyOperation(op, "user"); err == nil {
+		t.Fatal("bulk visibility with a missing object applied")
+	}
+	if got := l.visible(l.a1, l.a2); !got[0] || !got[1] {
+		t.Errorf("visibility a1 a2 = %v after a rejected bulk op, want unchanged", got)
+	}
+}
+
+func TestSoloHidesOnlySiblings(t *testing.T) {
+	l := newLayers(t)
+
+	apply(t, l.ds, &Operation{Type: "object.solo", ObjectIDs: []string{l.a2}}, "user")
+	got := l.visible(l.a1, l.a2, l.a3, l.a, l.b, l.b1, l.first)
+	want := []bool{false, true, false, true, true, true, true}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("visibility a1 a2 a3 a b b1 first = %v, want %v", got, want)
+		}
+	}
+
+	// Soloing objects in different groups hides the siblings of each, and
+	// shows a soloed object that was hidden
+	apply(t, l.ds, &Operation{Type: "object.solo", ObjectIDs: []string{l.a1, l.b}}, "user")
+	got = l.visible(l.a1, l.a2, l.a3, l.a, l.b, l.b1, l.first)
+	want = []bool{true, false, false, false, true, true, false}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("visibility a1 a2 a3 a b b1 first = %v, want %v", got, want)
+		}
+	}
+}
+
+// TestSoloCoversConcurrentChildren checks siblings are resolved when the
+// solo is applied, so one added after the client sent it is hidden too.
+func TestSoloCoversConcurrentChildren(t *testing.T) {
+	l := newLayers(t)
+	late := addRect(l.ds, l.a, false)
+
+	apply(t, l.ds, &Operation{Type: "object.solo", ObjectIDs: []string{l.a1}}, "user")
+	if l.ds.doc.Objects[late].Visible {
+		t.Error("sibling added before the solo applied is still visible")
+	}
+}
+
+func TestUndoBulkRestoresEachState(t *testing.T) {
+	l := newLayers(t)
+	apply(t, l.ds, &Operation{Type: "object.visibility", ObjectIDs: []string{l.a1}, Visible: boolPtr(false)}, "other")
+
+	apply(t, l.ds, &Operation{Type: "object.solo", ObjectIDs: []string{l.a3}}, "user")
+	undo(t, l.ds, "user")
+	if got := l.visible(l.a1, l.a2, l.a3); got[0] || !got[1] || !got[2] {
+		t.Errorf("visibility a1 a2 a3 after undoing solo = %v, want a1 still hidden", got)
+	}
+
+	apply(t, l.ds, &Operation{Type: "object.locked", ObjectIDs: []string{l.a1, l.b1}, Locked: boolPtr(true)}, "user")
+	undo(t, l.ds, "user")
+	if l.ds.doc.Objects[l.a1].Locked || l.ds.doc.Objects[l.b1].Locked {
+		t.Error("bulk lock not undone")
+	}
+	redo(t, l.ds, "user")
+	if !l.ds.doc.Objects[l.a1].Locked || !l.ds.doc.Objects[l.b1].Locked {
+		t.Error("bulk lock not redone")
+	}
+}
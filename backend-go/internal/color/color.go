@@ -0,0 +1,259 @@
+// Package color parses the CSS color notations users paste into fills and
+// strokes and converts them to one canonical form.
+//
+// Supported inputs: #rgb, #rgba, #rrggbb, #rrggbbaa, rgb()/rgba() and
+// hsl()/hsla() in both the comma and space-separated syntaxes (with an
+// optional "/ alpha"), and "transparent". The canonical form is lowercase
+// 8-digit hex, "#rrggbbaa".
+package color
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalid is returned for values that are not a supported color.
+var ErrInvalid = errors.New("invalid color")
+
+// None is the paint value meaning "no fill/stroke". It and the empty string
+// are passed through Normalize unchanged.
+const None = "none"
+
+// RGBA is a color with 8-bit sRGB channels and alpha.
+type RGBA struct {
+	R, G, B, A uint8
+}
+
+// Hex returns the canonical "#rrggbbaa" form.
+func (c RGBA) Hex() string {
+	return fmt.Sprintf("#%02x%02x%02x%02x", c.R, c.G, c.B, c.A)
+}
+
+// RGBHex returns "#rrggbb", dropping alpha.
+func (c RGBA) RGBHex() string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// Alpha returns the alpha channel in [0, 1].
+func (c RGBA) Alpha() float64 {
+	return float64(c.A) / 255
+}
+
+// Normalize converts a paint value to canonical hex. "" and "none" are
+// returned as-is.
+func Normalize(s string) (string, error) {
+	if s == "" || s == None {
+		return s, nil
+	}
+	c, err := Parse(s)
+	if err != nil {
+		return "", err
+	}
+	return c.Hex(), nil
+}
+
+// Parse parses a color in any supported notation.
+func Parse(s string) (RGBA, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+	switch {
+	case v == "transparent":
+		return RGBA{}, nil
+	case strings.HasPrefix(v, "#"):
+		return parseHex(v[1:], s)
+	}
+
+	open := strings.IndexByte(v, '(')
+	if open < 0 || !strings.HasSuffix(v, ")") {
+		return RGBA{}, fmt.Errorf("%w: %q", ErrInvalid, s)
+	}
+	name := strings.TrimSpace(v[:open])
+	args, err := splitArgs(v[open+1 : len(v)-1])
+	if err != nil {
+		return RGBA{}, fmt.Errorf("%w: %q", ErrInvalid, s)
+	}
+
+	var c RGBA
+	switch name {
+	case "rgb", "rgba":
+		c, err = parseRGB(args)
+	case "hsl", "hsla":
+		c, err = parseHSL(args)
+	default:
+		err = fmt.Errorf("unknown function %q", name)
+	}
+	if err != nil {
+		return RGBA{}, fmt.Errorf("%w: %q: %v", ErrInvalid, s, err)
+	}
+	return c, nil
+}
+
+func parseHex(digits, original string) (RGBA, error) {
+	// Expand the short forms: #rgb → #rrggbb, #rgba → #rrggbbaa
+	if len(digits) == 3 || len(digits) == 4 {
+		var b strings.Builder
+		for _, d := range digits {
+			b.WriteRune(d)
+			b.WriteRune(d)
+		}
+		digits = b.String()
+	}
+	if len(digits) == 6 {
+		digits += "ff"
+	}
+	if len(digits) != 8 {
+		return RGBA{}, fmt.Errorf("%w: %q", ErrInvalid, original)
+	}
+
+	n, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil {
+		return RGBA{}, fmt.Errorf("%w: %q", ErrInvalid, original)
+	}
+	return RGBA{R: uint8(n >> 24), G: uint8(n >> 16), B: uint8(n >> 8), A: uint8(n)}, nil
+}
+
+// splitArgs splits "r, g, b, a", "r g b / a" and mixtures into 3 or 4 tokens.
+func splitArgs(s string) ([]string, error) {
+	var alpha string
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		alpha = strings.TrimSpace(s[i+1:])
+		s = s[:i]
+		if alpha == "" {
+			return nil, ErrInvalid
+		}
+	}
+	args := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+	if alpha != "" {
+		args = append(args, alpha)
+	}
+	if len(args) != 3 && len(args) != 4 {
+		return nil, ErrInvalid
+	}
+	return args, nil
+}
+
+func parseRGB(args []string) (RGBA, error) {
+	var channels [3]uint8
+	for i := 0; i < 3; i++ {
+		v, err := parseNumberOrPercent(args[i], 255)
+		if err != nil {
+			return RGBA{}, err
+		}
+		channels[i] = toByte(v / 255)
+	}
+	a, err := parseAlpha(args)
+	if err != nil {
+		return RGBA{}, err
+	}
+	return RGBA{R: channels[0], G: channels[1], B: channels[2], A: a}, nil
+}
+
+func parseHSL(args []string) (RGBA, error) {
+	h, err := parseHue(args[0])
+	if err != nil {
+		return RGBA{}, err
+	}
+	s, err := parseNumberOrPercent(args[1], 100)
+	if err != nil {
+		return RGBA{}, err
+	}
+	l, err := parseNumberOrPercent(args[2], 100)
+	if err != nil {
+		return RGBA{}, err
+	}
+	a, err := parseAlpha(args)
+	if err != nil {
+		return RGBA{}, err
+	}
+
+	r, g, b := hslToRGB(h, clamp01(s/100), clamp01(l/100))
+	return RGBA{R: toByte(r), G: toByte(g), B: toByte(b), A: a}, nil
+}
+
+// hslToRGB converts hue in degrees and saturation/lightness in [0, 1] to
+// RGB channels in [0, 1] (CSS Color 4 algorithm).
+func hslToRGB(h, s, l float64) (float64, float64, float64) {
+	h = math.Mod(h, 360)
+	if h < 0 {
+		h += 360
+	}
+	f := func(n float64) float64 {
+		k := math.Mod(n+h/30, 12)
+		a := s * math.Min(l, 1-l)
+		return l - a*math.Max(-1, math.Min(math.Min(k-3, 9-k), 1))
+	}
+	return f(0), f(8), f(4)
+}
+
+// parseAlpha reads the optional fourth argument as a number in [0, 1] or a
+// percentage.
+func parseAlpha(args []string) (uint8, error) {
+	if len(args) < 4 {
+		return 255, nil
+	}
+	v, err := parseNumberOrPercent(args[3], 1)
+	if err != nil {
+		return 0, err
+	}
+	return toByte(v), nil
+}
+
+// parseNumberOrPercent parses a plain number, or a percentage scaled so that
+// 100% equals full.
+func parseNumberOrPercent(s string, full float64) (float64, error) {
+	if p, ok := strings.CutSuffix(s, "%"); ok {
+		v, err := strconv.ParseFloat(p, 64)
+		if err != nil || !isFinite(v) {
+			return 0, fmt.Errorf("invalid percentage %q", s)
+		}
+		return v / 100 * full, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || !isFinite(v) {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return v, nil
+}
+
+// parseHue parses a hue in degrees, accepting deg, rad, grad and turn units.
+func parseHue(s string) (float64, error) {
+	units := []struct {
+		suffix string
+		scale  float64
+	}{
+		{"deg", 1},
+		{"grad", 0.9},
+		{"rad", 180 / math.Pi},
+		{"turn", 360},
+	}
+	scale := 1.0
+	for _, u := range units {
+		if v, ok := strings.CutSuffix(s, u.suffix); ok {
+			s, scale = v, u.scale
+			break
+		}
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || !isFinite(v) {
+		return 0, fmt.Errorf("invalid hue %q", s)
+	}
+	return v * scale, nil
+}
+
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+// toByte converts a [0, 1] channel to 0–255, clamping out-of-range values as
+// CSS does.
+func toByte(v float64) uint8 {
+	return uint8(math.Round(clamp01(v) * 255))
+}
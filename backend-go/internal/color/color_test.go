@@ -0,0 +1,142 @@
+package color
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		// Hex
+		{"#f00", "#ff0000ff"},
+		{"#F00", "#ff0000ff"},
+		{"#1234", "#11223344"},
+		{"#3366cc", "#3366ccff"},
+		{"#3366CC80", "#3366cc80"},
+		{"  #3366cc  ", "#3366ccff"},
+		{"transparent", "#00000000"},
+		{"Transparent", "#00000000"},
+
+		// rgb()/rgba()
+		{"rgb(255, 0, 0)", "#ff0000ff"},
+		{"rgba(255, 0, 0, 0.5)", "#ff000080"},
+		{"rgb(255 0 0)", "#ff0000ff"},
+		{"rgb(255 0 0 / 50%)", "#ff000080"},
+		{"rgba(255,0,0,.25)", "#ff000040"},
+		{"rgb(100%, 50%, 0%)", "#ff8000ff"},
+		{"RGB(0, 0, 255)", "#0000ffff"},
+		{"rgb(12.4, 12.6, 0)", "#0c0d00ff"},
+		// Out of range channels and alpha clamp, as in CSS
+		{"rgb(300, -10, 0)", "#ff0000ff"},
+		{"rgba(0, 0, 0, 2)", "#000000ff"},
+		{"rgba(0, 0, 0, -1)", "#00000000"},
+
+		// hsl()/hsla()
+		{"hsl(0, 100%, 50%)", "#ff0000ff"},
+		{"hsl(120, 100%, 50%)", "#00ff00ff"},
+		{"hsl(240 100% 50%)", "#0000ffff"},
+		{"hsl(60 100% 25%)", "#808000ff"},
+		{"hsl(0, 0%, 100%)", "#ffffffff"},
+		{"hsla(0, 100%, 50%, 0.5)", "#ff000080"},
+		{"hsl(0 100% 50% / 25%)", "#ff000040"},
+		// Hue units and wrapping
+		{"hsl(120deg, 100%, 50%)", "#00ff00ff"},
+		{"hsl(200grad, 100%, 50%)", "#00ffffff"},
+		{"hsl(0.5turn, 100%, 50%)", "#00ffffff"},
+		{"hsl(0rad, 100%, 50%)", "#ff0000ff"},
+		{"hsl(-120, 100%, 50%)", "#0000ffff"},
+		{"hsl(480, 100%, 50%)", "#00ff00ff"},
+	}
+	for _, tt := range tests {
+		c, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		if got := c.Hex(); got != tt.want {
+			t.Errorf("Parse(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"red",
+		"#",
+		"#12",
+		"#12345",
+		"#1234567",
+		"#123456789",
+		"#ggg",
+		"#+12345",
+		"rgb",
+		"rgb()",
+		"rgb(1, 2)",
+		"rgb(1, 2, 3, 4, 5)",
+		"rgb(1, 2, 3",
+		"rgb(a, b, c)",
+		"rgb(1 2 3 /)",
+		"rgb(nan, 0, 0)",
+		"rgb(inf, 0, 0)",
+		"rgb(1%%, 0, 0)",
+		"hsl(red, 100%, 50%)",
+		"hsl(0, x%, 50%)",
+		"hsl(0, 100%, 50%, y)",
+		"cmyk(0, 0, 0, 1)",
+		"url(#gradient)",
+	} {
+		if c, err := Parse(in); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Parse(%q) = %s, %v; want ErrInvalid", in, c.Hex(), err)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{None, None},
+		{"#ABC", "#aabbccff"},
+		{"rgba(0, 128, 255, 0.5)", "#0080ff80"},
+		{"#0080ff80", "#0080ff80"},
+	}
+	for _, tt := range tests {
+		got, err := Normalize(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("Normalize(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+	if _, err := Normalize("bogus"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Normalize(bogus): %v, want ErrInvalid", err)
+	}
+}
+
+func TestRGBAForms(t *testing.T) {
+	c := RGBA{R: 0x33, G: 0x66, B: 0xcc, A: 0x80}
+	if got := c.Hex(); got != "#3366cc80" {
+		t.Errorf("Hex = %s", got)
+	}
+	if got := c.RGBHex(); got != "#3366cc" {
+		t.Errorf("RGBHex = %s", got)
+	}
+	if got := c.Alpha(); got != 128.0/255 {
+		t.Errorf("Alpha = %v, want %v", got, 128.0/255)
+	}
+	if got := (RGBA{A: 255}).Alpha(); got != 1 {
+		t.Errorf("opaque Alpha = %v, want 1", got)
+	}
+}
+
+// TestParseRoundTrips checks every canonical value parses back to itself.
+func TestParseRoundTrips(t *testing.T) {
+	for _, c := range []RGBA{{}, {R: 1, G: 2, B: 3, A: 4}, {R: 255, G: 255, B: 255, A: 255}, {R: 0xab, G: 0xcd, B: 0xef, A: 0x12}} {
+		back, err := Parse(c.Hex())
+		if err != nil || back != c {
+			t.Errorf("Parse(%s) = %+v, %v; want %+v", c.Hex(), back, err, c)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONLogger(t *testing.T) {
+	t.Setenv("LOG_FORMAT", "json")
+	t.Setenv("LOG_LEVEL", "warn")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	logger, err := cfg.Logger(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Info("below the level")
+	logger.With("request_id", "req_1").Warn("op rejected",
+		slog.Group("op", "id", "op_1", "type", "object.transform", "seq", 7))
+	logger.Error("save failed", "error", "boom")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("logged %d lines, want the warning and the error:\n%s", len(lines), buf.String())
+	}
+	var entry struct {
+		Level     string `json:"level"`
+		Msg       string `json:"msg"`
+		RequestID string `json:"request_id"`
+		Op        struct {
+			ID   string `json:"id"`
+			Type string `json:"type"`
+			Seq  int64  `json:"seq"`
+		} `json:"op"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("line %q is not JSON: %v", lines[0], err)
+	}
+	if entry.Level != "WARN" || entry.Msg != "op rejected" || entry.RequestID != "req_1" {
+		t.Errorf("entry %+v", entry)
+	}
+	if entry.Op.ID != "op_1" || entry.Op.Type != "object.transform" || entry.Op.Seq != 7 {
+		t.Errorf("op context %+v", entry.Op)
+	}
+	if !json.Valid([]byte(lines[1])) {
+		t.Errorf("line %q is not JSON", lines[1])
+	}
+}
+
+func TestLoggerDefaults(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	logger, err := cfg.Logger(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Debug("hidden")
+	logger.Info("shown", "request_id", "req_1")
+	if got := buf.String(); !strings.HasPrefix(got, "time=") || !strings.Contains(got, "level=INFO msg=shown request_id=req_1") || strings.Contains(got, "hidden") {
+		t.Errorf("default log output %q, want text at info", got)
+	}
+}
+
+func TestLoggerRejected(t *testing.T) {
+	for _, cfg := range []Config{
+		{LogFormat: "xml", LogLevel: "info"},
+		{LogFormat: "json", LogLevel: "loud"},
+	} {
+		if _, err := cfg.Logger(&bytes.Buffer{}); err == nil {
+			t.Errorf("format %q level %q accepted", cfg.LogFormat, cfg.LogLevel)
+		}
+	}
+}
+
+func TestWebSocketLimits(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.WSReadLimit != 16<<20 || cfg.WSMaxOpSize != 4<<20 {
+		t.Errorf("default limits %d and %d, want 16MB and 4MB", cfg.WSReadLimit, cfg.WSMaxOpSize)
+	}
+
+	t.Setenv("WS_READ_LIMIT", "1048576")
+	t.Setenv("WS_MAX_OP_SIZE", "65536")
+	if cfg, err = Load(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.WSReadLimit != 1<<20 || cfg.WSMaxOpSize != 64<<10 {
+		t.Errorf("configured limits %d and %d, want 1MB and 64KB", cfg.WSReadLimit, cfg.WSMaxOpSize)
+	}
+}
+
+func TestDatabasePool(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DBMaxConns != 0 || cfg.DBMinConns != 0 || cfg.DBMaxConnLifetime != 0 || cfg.DBMaxConnIdleTime != 0 || cfg.DBConnectTimeout != 0 {
+		t.Errorf("default pool max %d min %d lifetime %v idle %v timeout %v, want none set so pgx's defaults apply",
+			cfg.DBMaxConns, cfg.DBMinConns, cfg.DBMaxConnLifetime, cfg.DBMaxConnIdleTime, cfg.DBConnectTimeout)
+	}
+
+	t.Setenv("DB_MAX_CONNS", "20")
+	t.Setenv("DB_MIN_CONNS", "4")
+	t.Setenv("DB_MAX_CONN_LIFETIME", "1h")
+	t.Setenv("DB_MAX_CONN_IDLE_TIME", "10m")
+	t.Setenv("DB_CONNECT_TIMEOUT", "5s")
+	if cfg, err = Load(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DBMaxConns != 20 || cfg.DBMinConns != 4 || cfg.DBMaxConnLifetime != time.Hour ||
+		cfg.DBMaxConnIdleTime != 10*time.Minute || cfg.DBConnectTimeout != 5*time.Second {
+		t.Errorf("configured pool max %d min %d lifetime %v idle %v timeout %v", cfg.DBMaxConns, cfg.DBMinConns,
+			cfg.DBMaxConnLifetime, cfg.DBMaxConnIdleTime, cfg.DBConnectTimeout)
+	}
+}
+
+func TestFeatureFlags(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ClientFeatures{PlaygroundEnabled: true, ExportsEnabled: true, RegistrationEnabled: true, Experimental: map[string]bool{}}
+	if got := cfg.Features.Client(); !reflect.DeepEqual(got, want) {
+		t.Errorf("default features %+v, want %+v", got, want)
+	}
+
+	t.Setenv("FEATURE_PLAYGROUND", "false")
+	t.Setenv("FEATURE_EXPORTS", "false")
+	t.Setenv("FEATURE_REGISTRATION", "false")
+	t.Setenv("FEATURE_MAX_PROJECT_MEMBERS", "5")
+	t.Setenv("FEATURE_EXPERIMENTAL", "motionTrails:true,onionSkin:false")
+	if cfg, err = Load(); err != nil {
+		t.Fatal(err)
+	}
+	want = ClientFeatures{MaxProjectMembers: 5, Experimental: map[string]bool{"motionTrails": true, "onionSkin": false}}
+	if got := cfg.Features.Client(); !reflect.DeepEqual(got, want) {
+		t.Errorf("configured features %+v, want %+v", got, want)
+	}
+
+	// A negative member limit is no limit
+	if got := (FeatureFlags{MaxProjectMembers: -1}).Client().MaxProjectMembers; got != 0 {
+		t.Errorf("member limit %d, want 0", got)
+	}
+}
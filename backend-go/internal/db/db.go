@@ -3,14 +3,25 @@ package db
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func NewPool(ctx context.Context, databaseURL string) (*pgxpool.Pool, error) {
-	config, err := pgxpool.ParseConfig(databaseURL)
+// PoolOptions tune the connection pool. Zero values keep what the database
+// URL sets (e.g. pool_max_conns), or else pgx's defaults.
+type PoolOptions struct {
+	MaxConns        int32
+	MinConns        int32
+	MaxConnLifetime time.Duration
+	MaxConnIdleTime time.Duration
+	ConnectTimeout  time.Duration
+}
+
+func NewPool(ctx context.Context, databaseURL string, opts PoolOptions) (*pgxpool.Pool, error) {
+	config, err := poolConfig(databaseURL, opts)
 	if err != nil {
-		return nil, fmt.Errorf("parse database url: %w", err)
+		return nil, err
 	}
 
 	pool, err := pgxpool.NewWithConfig(ctx, config)
@@ -25,3 +36,41 @@ func NewPool(ctx context.Context, databaseURL string) (*pgxpool.Pool, error) {
 
 	return pool, nil
 }
+
+// poolConfig parses the database URL and applies opts over it.
+func poolConfig(databaseURL string, opts PoolOptions) (*pgxpool.Config, error) {
+	config, err := pgxpool.ParseConfig(databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse database url: %w", err)
+	}
+	if err := opts.apply(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// apply overrides config with the options that are set.
+func (o PoolOptions) apply(config *pgxpool.Config) error {
+	if o.MaxConns < 0 || o.MinConns < 0 || o.MaxConnLifetime < 0 || o.MaxConnIdleTime < 0 || o.ConnectTimeout < 0 {
+		return fmt.Errorf("pool options must not be negative")
+	}
+	if o.MaxConns > 0 {
+		config.MaxConns = o.MaxConns
+	}
+	if o.MinConns > 0 {
+		config.MinConns = o.MinConns
+	}
+	if config.MinConns > config.MaxConns {
+		return fmt.Errorf("pool min conns (%d) exceeds max conns (%d)", config.MinConns, config.MaxConns)
+	}
+	if o.MaxConnLifetime > 0 {
+		config.MaxConnLifetime = o.MaxConnLifetime
+	}
+	if o.MaxConnIdleTime > 0 {
+		config.MaxConnIdleTime = o.MaxConnIdleTime
+	}
+	if o.ConnectTimeout > 0 {
+		config.ConnConfig.ConnectTimeout = o.ConnectTimeout
+	}
+	return nil
+}
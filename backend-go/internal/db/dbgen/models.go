@@ -54,6 +54,24 @@ func (ns NullProjectRole) Value() (driver.Value, error) {
 	return string(ns.ProjectRole), nil
 }
 
+type Notification struct {
+	ID        string             `json:"id"`
+	UserID    string             `json:"user_id"`
+	ProjectID string             `json:"project_id"`
+	Type      string             `json:"type"`
+	ActorID   string             `json:"actor_id"`
+	Data      []byte             `json:"data"`
+	ReadAt    pgtype.Timestamptz `json:"read_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type NotificationPreference struct {
+	UserID        string             `json:"user_id"`
+	MutedProjects []string           `json:"muted_projects"`
+	MutedTypes    []string           `json:"muted_types"`
+	UpdatedAt     pgtype.Timestamptz `json:"updated_at"`
+}
+
 type Project struct {
 	ID        string             `json:"id"`
 	Name      string             `json:"name"`
@@ -88,13 +106,40 @@ type ProjectSnapshot struct {
 	Version   int32              `json:"version"`
 	Document  []byte             `json:"document"`
 	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	Seq       int64              `json:"seq"`
+	Label     string             `json:"label"`
+}
+
+type ProjectWebhook struct {
+	ID        string             `json:"id"`
+	ProjectID string             `json:"project_id"`
+	Url       string             `json:"url"`
+	Secret    string             `json:"secret"`
+	Events    []string           `json:"events"`
+	CreatedBy string             `json:"created_by"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
 }
 
 type User struct {
-	ID          string             `json:"id"`
-	Email       string             `json:"email"`
-	Password    string             `json:"password"`
-	DisplayName string             `json:"display_name"`
-	CreatedAt   pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+	ID            string             `json:"id"`
+	Email         string             `json:"email"`
+	Password      string             `json:"password"`
+	DisplayName   string             `json:"display_name"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt     pgtype.Timestamptz `json:"updated_at"`
+	IsAdmin       bool               `json:"is_admin"`
+	DeactivatedAt pgtype.Timestamptz `json:"deactivated_at"`
+}
+
+type WebhookDelivery struct {
+	ID         string             `json:"id"`
+	WebhookID  string             `json:"webhook_id"`
+	Event      string             `json:"event"`
+	Payload    []byte             `json:"payload"`
+	Attempt    int32              `json:"attempt"`
+	StatusCode int32              `json:"status_code"`
+	Error      string             `json:"error"`
+	Success    bool               `json:"success"`
+	DurationMs int32              `json:"duration_ms"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
 }
@@ -28,8 +28,8 @@ func (q *Queries) AddProjectMember(ctx context.Context, arg AddProjectMemberPara
 }
 
 const createProject = `-- name: CreateProject :one
-INSERT INTO projects (id, name, owner_id)
-VALUES ($1, $2, $3)
+INSERT INTO projects (id, name, owner_id, fps, width, height)
+VALUES ($1, $2, $3, $4, $5, $6)
 RETURNING id, name, owner_id, fps, width, height, created_at, updated_at
 `
 
@@ -37,10 +37,20 @@ type CreateProjectParams struct {
 	ID      string `json:"id"`
 	Name    string `json:"name"`
 	OwnerID string `json:"owner_id"`
+	Fps     int32  `json:"fps"`
+	Width   int32  `json:"width"`
+	Height  int32  `json:"height"`
 }
 
 func (q *Queries) CreateProject(ctx context.Context, arg CreateProjectParams) (Project, error) {
-	row := q.db.QueryRow(ctx, createProject, arg.ID, arg.Name, arg.OwnerID)
+	row := q.db.QueryRow(ctx, createProject,
+		arg.ID,
+		arg.Name,
+		arg.OwnerID,
+		arg.Fps,
+		arg.Width,
+		arg.Height,
+	)
 	var i Project
 	err := row.Scan(
 		&i.ID,
@@ -56,9 +66,9 @@ func (q *Queries) CreateProject(ctx context.Context, arg CreateProjectParams) (P
 }
 
 const createSnapshot = `-- name: CreateSnapshot :one
-INSERT INTO project_snapshots (id, project_id, version, document)
-VALUES ($1, $2, $3, $4)
-RETURNING id, project_id, version, document, created_at
+INSERT INTO project_snapshots (id, project_id, version, document, seq, label)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, project_id, version, document, created_at, seq, label
 `
 
 type CreateSnapshotParams struct {
@@ -66,6 +76,8 @@ type CreateSnapshotParams struct {
 	ProjectID string `json:"project_id"`
 	Version   int32  `json:"version"`
 	Document  []byte `json:"document"`
+	Seq       int64  `json:"seq"`
+	Label     string `json:"label"`
 }
 
 func (q *Queries) CreateSnapshot(ctx context.Context, arg CreateSnapshotParams) (ProjectSnapshot, error) {
@@ -74,6 +86,8 @@ func (q *Queries) CreateSnapshot(ctx context.Context, arg CreateSnapshotParams)
 		arg.ProjectID,
 		arg.Version,
 		arg.Document,
+		arg.Seq,
+		arg.Label,
 	)
 	var i ProjectSnapshot
 	err := row.Scan(
@@ -82,6 +96,8 @@ func (q *Queries) CreateSnapshot(ctx context.Context, arg CreateSnapshotParams)
 		&i.Version,
 		&i.Document,
 		&i.CreatedAt,
+		&i.Seq,
+		&i.Label,
 	)
 	return i, err
 }
@@ -96,7 +112,7 @@ func (q *Queries) DeleteProject(ctx context.Context, id string) error {
 }
 
 const getLatestSnapshot = `-- name: GetLatestSnapshot :one
-SELECT id, project_id, version, document, created_at
+SELECT id, project_id, version, document, created_at, seq, label
 FROM project_snapshots
 WHERE project_id = $1
 ORDER BY version DESC
@@ -112,6 +128,8 @@ func (q *Queries) GetLatestSnapshot(ctx context.Context, projectID string) (Proj
 		&i.Version,
 		&i.Document,
 		&i.CreatedAt,
+		&i.Seq,
+		&i.Label,
 	)
 	return i, err
 }
@@ -161,6 +179,32 @@ func (q *Queries) GetProjectMember(ctx context.Context, arg GetProjectMemberPara
 	return i, err
 }
 
+const getSnapshotByVersion = `-- name: GetSnapshotByVersion :one
+SELECT id, project_id, version, document, created_at, seq, label
+FROM project_snapshots
+WHERE project_id = $1 AND version = $2
+`
+
+type GetSnapshotByVersionParams struct {
+	ProjectID string `json:"project_id"`
+	Version   int32  `json:"version"`
+}
+
+func (q *Queries) GetSnapshotByVersion(ctx context.Context, arg GetSnapshotByVersionParams) (ProjectSnapshot, error) {
+	row := q.db.QueryRow(ctx, getSnapshotByVersion, arg.ProjectID, arg.Version)
+	var i ProjectSnapshot
+	err := row.Scan(
+		&i.ID,
+		&i.ProjectID,
+		&i.Version,
+		&i.Document,
+		&i.CreatedAt,
+		&i.Seq,
+		&i.Label,
+	)
+	return i, err
+}
+
 const listProjectMembers = `-- name: ListProjectMembers :many
 SELECT pm.project_id, pm.user_id, pm.role, pm.invited_at, u.display_name, u.email
 FROM project_members pm
@@ -242,6 +286,118 @@ func (q *Queries) ListProjectsForUser(ctx context.Context, userID string) ([]Pro
 	return items, nil
 }
 
+const listProjectsWithStats = `-- name: ListProjectsWithStats :many
+SELECT p.id, p.name, p.owner_id, p.created_at, p.updated_at,
+       COALESCE(s.version, 0)::int AS snapshot_version,
+       COALESCE(octet_length(s.document::text), 0)::int AS document_bytes,
+       GREATEST(p.updated_at, COALESCE(s.created_at, p.updated_at))::timestamptz AS last_activity
+FROM projects p
+LEFT JOIN LATERAL (
+    SELECT version, document, created_at
+    FROM project_snapshots
+    WHERE project_id = p.id
+    ORDER BY version DESC
+    LIMIT 1
+) s ON true
+ORDER BY last_activity DESC
+LIMIT $1 OFFSET $2
+`
+
+type ListProjectsWithStatsParams struct {
+	Limit  int32 `json:"limit"`
+	Offset int32 `json:"offset"`
+}
+
+type ListProjectsWithStatsRow struct {
+	ID              string             `json:"id"`
+	Name            string             `json:"name"`
+	OwnerID         string             `json:"owner_id"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+	SnapshotVersion int32              `json:"snapshot_version"`
+	DocumentBytes   int32              `json:"document_bytes"`
+	LastActivity    pgtype.Timestamptz `json:"last_activity"`
+}
+
+func (q *Queries) ListProjectsWithStats(ctx context.Context, arg ListProjectsWithStatsParams) ([]ListProjectsWithStatsRow, error) {
+	rows, err := q.db.Query(ctx, listProjectsWithStats, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListProjectsWithStatsRow{}
+	for rows.Next() {
+		var i ListProjectsWithStatsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.OwnerID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.SnapshotVersion,
+			&i.DocumentBytes,
+			&i.LastActivity,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSnapshots = `-- name: ListSnapshots :many
+SELECT id, version, seq, label, octet_length(document::text)::int AS document_bytes, created_at
+FROM project_snapshots
+WHERE project_id = $1
+ORDER BY version DESC
+LIMIT $2 OFFSET $3
+`
+
+type ListSnapshotsParams struct {
+	ProjectID string `json:"project_id"`
+	Limit     int32  `json:"limit"`
+	Offset    int32  `json:"offset"`
+}
+
+type ListSnapshotsRow struct {
+	ID            string             `json:"id"`
+	Version       int32              `json:"version"`
+	Seq           int64              `json:"seq"`
+	Label         string             `json:"label"`
+	DocumentBytes int32              `json:"document_bytes"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+}
+
+func (q *Queries) ListSnapshots(ctx context.Context, arg ListSnapshotsParams) ([]ListSnapshotsRow, error) {
+	rows, err := q.db.Query(ctx, listSnapshots, arg.ProjectID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListSnapshotsRow{}
+	for rows.Next() {
+		var i ListSnapshotsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Version,
+			&i.Seq,
+			&i.Label,
+			&i.DocumentBytes,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const removeProjectMember = `-- name: RemoveProjectMember :exec
 DELETE FROM project_members WHERE project_id = $1 AND user_id = $2
 `
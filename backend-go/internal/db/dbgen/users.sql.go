@@ -50,8 +50,21 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateU
 	return i, err
 }
 
+const deactivateUser = `-- name: DeactivateUser :execrows
+UPDATE users SET deactivated_at = now(), updated_at = now()
+WHERE id = $1 AND deactivated_at IS NULL
+`
+
+func (q *Queries) DeactivateUser(ctx context.Context, id string) (int64, error) {
+	result, err := q.db.Exec(ctx, deactivateUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, email, password, display_name, created_at, updated_at
+SELECT id, email, password, display_name, created_at, updated_at, is_admin, deactivated_at
 FROM users
 WHERE email = $1
 `
@@ -66,22 +79,26 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.DisplayName,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.IsAdmin,
+		&i.DeactivatedAt,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, email, display_name, created_at, updated_at
+SELECT id, email, display_name, created_at, updated_at, is_admin, deactivated_at
 FROM users
 WHERE id = $1
 `
 
 type GetUserByIDRow struct {
-	ID          string             `json:"id"`
-	Email       string             `json:"email"`
-	DisplayName string             `json:"display_name"`
-	CreatedAt   pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+	ID            string             `json:"id"`
+	Email         string             `json:"email"`
+	DisplayName   string             `json:"display_name"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt     pgtype.Timestamptz `json:"updated_at"`
+	IsAdmin       bool               `json:"is_admin"`
+	DeactivatedAt pgtype.Timestamptz `json:"deactivated_at"`
 }
 
 func (q *Queries) GetUserByID(ctx context.Context, id string) (GetUserByIDRow, error) {
@@ -93,6 +110,104 @@ func (q *Queries) GetUserByID(ctx context.Context, id string) (GetUserByIDRow, e
 		&i.DisplayName,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.IsAdmin,
+		&i.DeactivatedAt,
 	)
 	return i, err
 }
+
+const reactivateUser = `-- name: ReactivateUser :execrows
+UPDATE users SET deactivated_at = NULL, updated_at = now()
+WHERE id = $1 AND deactivated_at IS NOT NULL
+`
+
+func (q *Queries) ReactivateUser(ctx context.Context, id string) (int64, error) {
+	result, err := q.db.Exec(ctx, reactivateUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const searchUsers = `-- name: SearchUsers :many
+SELECT id, email, display_name, created_at, updated_at, is_admin, deactivated_at
+FROM users
+WHERE email ILIKE '%' || $1::text || '%'
+   OR display_name ILIKE '%' || $1::text || '%'
+ORDER BY created_at
+LIMIT $2
+`
+
+type SearchUsersParams struct {
+	Query      string `json:"query"`
+	MaxResults int32  `json:"max_results"`
+}
+
+type SearchUsersRow struct {
+	ID            string             `json:"id"`
+	Email         string             `json:"email"`
+	DisplayName   string             `json:"display_name"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt     pgtype.Timestamptz `json:"updated_at"`
+	IsAdmin       bool               `json:"is_admin"`
+	DeactivatedAt pgtype.Timestamptz `json:"deactivated_at"`
+}
+
+func (q *Queries) SearchUsers(ctx context.Context, arg SearchUsersParams) ([]SearchUsersRow, error) {
+	rows, err := q.db.Query(ctx, searchUsers, arg.Query, arg.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SearchUsersRow{}
+	for rows.Next() {
+		var i SearchUsersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Email,
+			&i.DisplayName,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.IsAdmin,
+			&i.DeactivatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setUserAdmin = `-- name: SetUserAdmin :execrows
+UPDATE users SET is_admin = $2, updated_at = now()
+WHERE id = $1
+`
+
+type SetUserAdminParams struct {
+	ID      string `json:"id"`
+	IsAdmin bool   `json:"is_admin"`
+}
+
+func (q *Queries) SetUserAdmin(ctx context.Context, arg SetUserAdminParams) (int64, error) {
+	result, err := q.db.Exec(ctx, setUserAdmin, arg.ID, arg.IsAdmin)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const setUserAdminByEmail = `-- name: SetUserAdminByEmail :execrows
+UPDATE users SET is_admin = true, updated_at = now()
+WHERE email = $1
+`
+
+func (q *Queries) SetUserAdminByEmail(ctx context.Context, email string) (int64, error) {
+	result, err := q.db.Exec(ctx, setUserAdminByEmail, email)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
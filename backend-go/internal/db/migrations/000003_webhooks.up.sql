@@ -0,0 +1,25 @@
+CREATE TABLE project_webhooks (
+    id          TEXT PRIMARY KEY,
+    project_id  TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    url         TEXT NOT NULL,
+    secret      TEXT NOT NULL,
+    events      TEXT[] NOT NULL DEFAULT '{}',
+    created_by  TEXT NOT NULL REFERENCES users(id),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE webhook_deliveries (
+    id          TEXT PRIMARY KEY,
+    webhook_id  TEXT NOT NULL REFERENCES project_webhooks(id) ON DELETE CASCADE,
+    event       TEXT NOT NULL,
+    payload     JSONB NOT NULL,
+    attempt     INT NOT NULL,
+    status_code INT NOT NULL DEFAULT 0,
+    error       TEXT NOT NULL DEFAULT '',
+    success     BOOLEAN NOT NULL,
+    duration_ms INT NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_project_webhooks_project ON project_webhooks(project_id);
+CREATE INDEX idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, created_at DESC);
@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE users ADD COLUMN deactivated_at TIMESTAMPTZ;
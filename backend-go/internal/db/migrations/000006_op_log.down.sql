@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_project_ops_project_seq;
+CREATE INDEX idx_project_ops_project_seq ON project_ops(project_id, seq);
+
+DELETE FROM project_ops WHERE user_id NOT IN (SELECT id FROM users);
+ALTER TABLE project_ops ADD CONSTRAINT project_ops_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id);
+
+ALTER TABLE project_snapshots DROP COLUMN IF EXISTS seq;
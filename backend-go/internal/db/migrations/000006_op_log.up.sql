@@ -0,0 +1,9 @@
+-- The server sequence each snapshot was saved at. A room loads the latest
+-- snapshot and replays the logged ops after it.
+ALTER TABLE project_snapshots ADD COLUMN seq BIGINT NOT NULL DEFAULT 0;
+
+-- Playground users are anonymous, so ops can't reference users
+ALTER TABLE project_ops DROP CONSTRAINT project_ops_user_id_fkey;
+
+DROP INDEX idx_project_ops_project_seq;
+CREATE UNIQUE INDEX idx_project_ops_project_seq ON project_ops(project_id, seq);
@@ -0,0 +1 @@
+ALTER TABLE project_snapshots DROP COLUMN IF EXISTS label;
@@ -0,0 +1,3 @@
+-- Why a snapshot was saved outside the autosave cycle, e.g. the safety copy
+-- taken before a destructive operation. Empty for routine saves.
+ALTER TABLE project_snapshots ADD COLUMN label TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,41 @@
+-- name: CreateNotification :one
+INSERT INTO notifications (id, user_id, project_id, type, actor_id, data)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, user_id, project_id, type, actor_id, data, read_at, created_at;
+
+-- name: ListNotifications :many
+SELECT id, user_id, project_id, type, actor_id, data, read_at, created_at
+FROM notifications
+WHERE user_id = sqlc.arg(user_id)
+  AND (NOT sqlc.arg(unread_only)::bool OR read_at IS NULL)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(max_results) OFFSET sqlc.arg(skip);
+
+-- name: CountUnreadNotifications :one
+SELECT count(*) FROM notifications
+WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: MarkNotificationsRead :execrows
+UPDATE notifications SET read_at = now()
+WHERE user_id = sqlc.arg(user_id) AND id = ANY(sqlc.arg(ids)::text[]) AND read_at IS NULL;
+
+-- name: MarkAllNotificationsRead :execrows
+UPDATE notifications SET read_at = now()
+WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: GetNotificationPreferences :one
+SELECT user_id, muted_projects, muted_types, updated_at
+FROM notification_preferences
+WHERE user_id = $1;
+
+-- name: ListNotificationPreferences :many
+SELECT user_id, muted_projects, muted_types, updated_at
+FROM notification_preferences
+WHERE user_id = ANY(sqlc.arg(user_ids)::text[]);
+
+-- name: UpsertNotificationPreferences :one
+INSERT INTO notification_preferences (user_id, muted_projects, muted_types)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id) DO UPDATE
+SET muted_projects = EXCLUDED.muted_projects, muted_types = EXCLUDED.muted_types, updated_at = now()
+RETURNING user_id, muted_projects, muted_types, updated_at;
@@ -0,0 +1,21 @@
+-- name: AppendProjectOps :exec
+INSERT INTO project_ops (id, project_id, user_id, seq, op_type, payload)
+SELECT unnest(sqlc.arg(ids)::text[]),
+       sqlc.arg(project_id),
+       unnest(sqlc.arg(user_ids)::text[]),
+       unnest(sqlc.arg(seqs)::bigint[]),
+       unnest(sqlc.arg(op_types)::text[]),
+       unnest(sqlc.arg(payloads)::text[])::jsonb
+ON CONFLICT (project_id, seq) DO NOTHING;
+
+-- name: ListProjectOpsAfter :many
+SELECT id, project_id, user_id, seq, op_type, payload, created_at
+FROM project_ops
+WHERE project_id = $1 AND seq > $2
+ORDER BY seq;
+
+-- name: DeleteProjectOpsThrough :execrows
+DELETE FROM project_ops WHERE project_id = $1 AND seq <= $2;
+
+-- name: DeleteProjectOpsAfter :execrows
+DELETE FROM project_ops WHERE project_id = $1 AND seq > $2;
@@ -1,6 +1,6 @@
 -- name: CreateProject :one
-INSERT INTO projects (id, name, owner_id)
-VALUES ($1, $2, $3)
+INSERT INTO projects (id, name, owner_id, fps, width, height)
+VALUES ($1, $2, $3, $4, $5, $6)
 RETURNING id, name, owner_id, fps, width, height, created_at, updated_at;
 
 -- name: GetProject :one
@@ -38,13 +38,41 @@ ORDER BY pm.invited_at;
 DELETE FROM project_members WHERE project_id = $1 AND user_id = $2;
 
 -- name: CreateSnapshot :one
-INSERT INTO project_snapshots (id, project_id, version, document)
-VALUES ($1, $2, $3, $4)
-RETURNING id, project_id, version, document, created_at;
+INSERT INTO project_snapshots (id, project_id, version, document, seq, label)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, project_id, version, document, created_at, seq, label;
 
 -- name: GetLatestSnapshot :one
-SELECT id, project_id, version, document, created_at
+SELECT id, project_id, version, document, created_at, seq, label
 FROM project_snapshots
 WHERE project_id = $1
 ORDER BY version DESC
 LIMIT 1;
+
+-- name: GetSnapshotByVersion :one
+SELECT id, project_id, version, document, created_at, seq, label
+FROM project_snapshots
+WHERE project_id = $1 AND version = $2;
+
+-- name: ListSnapshots :many
+SELECT id, version, seq, label, octet_length(document::text)::int AS document_bytes, created_at
+FROM project_snapshots
+WHERE project_id = $1
+ORDER BY version DESC
+LIMIT $2 OFFSET $3;
+
+-- name: ListProjectsWithStats :many
+SELECT p.id, p.name, p.owner_id, p.created_at, p.updated_at,
+       COALESCE(s.version, 0)::int AS snapshot_version,
+       COALESCE(octet_length(s.document::text), 0)::int AS document_bytes,
+       GREATEST(p.updated_at, COALESCE(s.created_at, p.updated_at))::timestamptz AS last_activity
+FROM projects p
+LEFT JOIN LATERAL (
+    SELECT version, document, created_at
+    FROM project_snapshots
+    WHERE project_id = p.id
+    ORDER BY version DESC
+    LIMIT 1
+) s ON true
+ORDER BY last_activity DESC
+LIMIT $1 OFFSET $2;
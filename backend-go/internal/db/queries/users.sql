@@ -4,11 +4,35 @@ VALUES ($1, $2, $3, $4)
 RETURNING id, email, display_name, created_at, updated_at;
 
 -- name: GetUserByEmail :one
-SELECT id, email, password, display_name, created_at, updated_at
+SELECT id, email, password, display_name, created_at, updated_at, is_admin, deactivated_at
 FROM users
 WHERE email = $1;
 
 -- name: GetUserByID :one
-SELECT id, email, display_name, created_at, updated_at
+SELECT id, email, display_name, created_at, updated_at, is_admin, deactivated_at
 FROM users
 WHERE id = $1;
+
+-- name: SearchUsers :many
+SELECT id, email, display_name, created_at, updated_at, is_admin, deactivated_at
+FROM users
+WHERE email ILIKE '%' || sqlc.arg(query)::text || '%'
+   OR display_name ILIKE '%' || sqlc.arg(query)::text || '%'
+ORDER BY created_at
+LIMIT sqlc.arg(max_results);
+
+-- name: SetUserAdmin :execrows
+UPDATE users SET is_admin = $2, updated_at = now()
+WHERE id = $1;
+
+-- name: SetUserAdminByEmail :execrows
+UPDATE users SET is_admin = true, updated_at = now()
+WHERE email = $1;
+
+-- name: DeactivateUser :execrows
+UPDATE users SET deactivated_at = now(), updated_at = now()
+WHERE id = $1 AND deactivated_at IS NULL;
+
+-- name: ReactivateUser :execrows
+UPDATE users SET deactivated_at = NULL, updated_at = now()
+WHERE id = $1 AND deactivated_at IS NOT NULL;
@@ -0,0 +1,29 @@
+-- name: CreateWebhook :one
+INSERT INTO project_webhooks (id, project_id, url, secret, events, created_by)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, project_id, url, secret, events, created_by, created_at;
+
+-- name: GetWebhook :one
+SELECT id, project_id, url, secret, events, created_by, created_at
+FROM project_webhooks
+WHERE id = $1 AND project_id = $2;
+
+-- name: ListProjectWebhooks :many
+SELECT id, project_id, url, secret, events, created_by, created_at
+FROM project_webhooks
+WHERE project_id = $1
+ORDER BY created_at;
+
+-- name: DeleteWebhook :exec
+DELETE FROM project_webhooks WHERE id = $1 AND project_id = $2;
+
+-- name: CreateWebhookDelivery :exec
+INSERT INTO webhook_deliveries (id, webhook_id, event, payload, attempt, status_code, error, success, duration_ms)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);
+
+-- name: ListWebhookDeliveries :many
+SELECT id, webhook_id, event, payload, attempt, status_code, error, success, duration_ms, created_at
+FROM webhook_deliveries
+WHERE webhook_id = $1
+ORDER BY created_at DESC
+LIMIT $2;
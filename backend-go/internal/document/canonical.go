@@ -0,0 +1,56 @@
+package document
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// MarshalCanonical encodes a document as deterministic JSON, so equal
+// documents produce identical bytes for diffing and content hashing.
+//
+// encoding/json already sorts map keys, but the raw JSON embedded in object
+// data, keyframe values and asset metadata is copied through as received: the
+// same object arrives with different key order or spacing from a client, a
+// JSONB snapshot, or an importer. The output is re-encoded with every object's
+// keys sorted and numbers kept exactly as written, except that fractions in
+// objects' transforms and styles are rounded to the document precision, as
+// the operations writing them are.
+func MarshalCanonical(doc *InDocument) ([]byte, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var tree map[string]interface{}
+	if err := dec.Decode(&tree); err != nil {
+		return nil, err
+	}
+	objects, _ := tree["objects"].(map[string]interface{})
+	for _, obj := range objects {
+		if obj, ok := obj.(map[string]interface{}); ok {
+			roundFields(obj["transform"], Precision())
+			roundFields(obj["style"], Precision())
+		}
+	}
+	return json.Marshal(tree)
+}
+
+// roundFields rounds the fractional numbers among the fields of a decoded
+// JSON object to decimals places, in place. Nested values are left alone.
+func roundFields(v interface{}, decimals int) {
+	fields, ok := v.(map[string]interface{})
+	if !ok {
+		return
+	}
+	for name, field := range fields {
+		n, ok := field.(json.Number)
+		if !ok || !strings.ContainsAny(string(n), ".eE") {
+			continue
+		}
+		if f, err := n.Float64(); err == nil {
+			fields[name] = RoundField(name, f, decimals)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package document
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestMarshalCanonicalIsStable(t *testing.T) {
+	doc := legacyDoc(t)
+	first, err := MarshalCanonical(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Map iteration order changes run to run, so encode several times
+	for range 20 {
+		again, err := MarshalCanonical(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(again, first) {
+			t.Fatalf("encodings differ:\n%s\n%s", first, again)
+		}
+	}
+
+	// Decoding the output loses nothing: it encodes back to the same bytes
+	var decoded InDocument
+	if err := json.Unmarshal(first, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Objects) != len(doc.Objects) || len(decoded.Keyframes) != len(doc.Keyframes) {
+		t.Errorf("decoded %d objects and %d keyframes, want %d and %d",
+			len(decoded.Objects), len(decoded.Keyframes), len(doc.Objects), len(doc.Keyframes))
+	}
+	if again, _ := MarshalCanonical(&decoded); !bytes.Equal(again, first) {
+		t.Errorf("re-encoding the decoded document changed it:\n%s\n%s", first, again)
+	}
+}
+
+// TestMarshalCanonicalRawJSON checks embedded raw JSON differing only in key
+// order and spacing encodes identically, with its numbers as written.
+func TestMarshalCanonicalRawJSON(t *testing.T) {
+	const rectID = "3f2504e0-4f89-11d3-9a0c-0305e82c3301"
+	encode := func(data string) []byte {
+		t.Helper()
+		doc := legacyDoc(t)
+		rect := doc.Objects[rectID]
+		rect.Data = json.RawMessage(data)
+		doc.Objects[rectID] = rect
+		out, err := MarshalCanonical(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	a := encode(`{"width": 10, "height": 10.50, "id": 12345678901234567890}`)
+	b := encode(`{ "id":12345678901234567890,"height":10.50,"width":10 }`)
+	if !bytes.Equal(a, b) {
+		t.Errorf("reordered data encodes differently:\n%s\n%s", a, b)
+	}
+	if !bytes.Contains(a, []byte(`"data":{"height":10.50,"id":12345678901234567890,"width":10}`)) {
+		t.Errorf("data not sorted with its numbers as written:\n%s", a)
+	}
+}
+
+func TestMarshalCanonicalRoundsTransforms(t *testing.T) {
+	doc := legacyDoc(t)
+	const rectID = "3f2504e0-4f89-11d3-9a0c-0305e82c3301"
+	rect := doc.Objects[rectID]
+	rect.Transform.X = 417.2384756293847
+	doc.Objects[rectID] = rect
+
+	out, err := MarshalCanonical(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded InDocument
+	if err := json.Unmarshal(out, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if x, want := decoded.Objects[rectID].Transform.X, Round(417.2384756293847, Precision()); x != want {
+		t.Errorf("x encoded as %v, want %v", x, want)
+	}
+	if doc.Objects[rectID].Transform.X != 417.2384756293847 {
+		t.Error("encoding rounded the document itself")
+	}
+}
@@ -0,0 +1,49 @@
+package document
+
+import (
+	"slices"
+	"strings"
+)
+
+// CurrentVersion is the document format version this build reads and writes.
+// Documents saved by newer clients carry a higher version and may hold object
+// types this build doesn't know; those are kept as they are and shown as
+// placeholders rather than rejected.
+const CurrentVersion = 1
+
+// Known reports whether t is an object type this build understands.
+func (t ObjectType) Known() bool {
+	switch t {
+	case ObjectTypeGroup, ObjectTypeShapeRect, ObjectTypeShapeEllipse, ObjectTypeVectorPath,
+		ObjectTypeRasterImage, ObjectTypeSymbol, ObjectTypeText, ObjectTypeSpriteSequence:
+		return true
+	}
+	return false
+}
+
+// Newer reports whether the document was written by a newer format version
+// than this build's.
+func (doc *InDocument) Newer() bool {
+	return doc.Project.Version > CurrentVersion
+}
+
+// UnknownObject is an object whose type this build doesn't understand.
+type UnknownObject struct {
+	ID   string     `json:"id"`
+	Type ObjectType `json:"type"`
+}
+
+// UnknownObjects lists the objects of types this build doesn't understand,
+// ordered by ID.
+func (doc *InDocument) UnknownObjects() []UnknownObject {
+	unknown := make([]UnknownObject, 0)
+	for id, obj := range doc.Objects {
+		if !obj.Type.Known() {
+			unknown = append(unknown, UnknownObject{ID: id, Type: obj.Type})
+		}
+	}
+	slices.SortFunc(unknown, func(a, b UnknownObject) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return unknown
+}
@@ -0,0 +1,67 @@
+package document
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// FillObjectDefaults fills in the fields of a new object's payload that it
+// can't sensibly go without: a scale of 1 on each axis where the scale is
+// missing or zero, and an opacity of 1 and visibility where they are missing.
+// Decoded without them the object would be degenerate or hidden and never
+// render. An explicit zero opacity is kept, since objects often fade in from
+// it.
+func FillObjectDefaults(raw json.RawMessage) (json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, fmt.Errorf("invalid object: %w", err)
+	}
+
+	filled := false
+	fill := func(parent map[string]json.RawMessage, key string, value interface{}) {
+		if _, ok := parent[key]; !ok {
+			parent[key], _ = json.Marshal(value)
+			filled = true
+		}
+	}
+	fillZero := func(parent map[string]json.RawMessage, key string, value float64) {
+		var v float64
+		if json.Unmarshal(parent[key], &v) != nil || v == 0 {
+			parent[key], _ = json.Marshal(value)
+			filled = true
+		}
+	}
+	nested := func(key string) (map[string]json.RawMessage, error) {
+		m := make(map[string]json.RawMessage)
+		if v, ok := fields[key]; ok && string(v) != "null" {
+			if err := json.Unmarshal(v, &m); err != nil {
+				return nil, fmt.Errorf("invalid object %s: %w", key, err)
+			}
+		}
+		return m, nil
+	}
+
+	transform, err := nested("transform")
+	if err != nil {
+		return nil, err
+	}
+	style, err := nested("style")
+	if err != nil {
+		return nil, err
+	}
+	fillZero(transform, "sx", 1)
+	fillZero(transform, "sy", 1)
+	fill(style, "opacity", 1)
+	fill(fields, "visible", true)
+	if !filled {
+		return raw, nil
+	}
+
+	fields["transform"], _ = json.Marshal(transform)
+	fields["style"], _ = json.Marshal(style)
+	out, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,47 @@
+package document
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFillObjectDefaults(t *testing.T) {
+	tests := []struct {
+		name, in         string
+		sx, sy, x, alpha float64
+		visible          bool
+	}{
+		{"bare", `{"id":"a"}`, 1, 1, 0, 1, true},
+		{"zero scale", `{"transform":{"x":5,"sx":0,"sy":2},"style":{"opacity":0.5},"visible":false}`, 1, 2, 5, 0.5, false},
+		// An explicit zero opacity is a fade-in start, not a mistake
+		{"zero opacity", `{"transform":{"sx":3,"sy":3},"style":{"opacity":0},"visible":true}`, 3, 3, 0, 0, true},
+		{"null nested", `{"transform":null,"style":null}`, 1, 1, 0, 1, true},
+	}
+	for _, tt := range tests {
+		raw, err := FillObjectDefaults(json.RawMessage(tt.in))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var obj ObjectNode
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			t.Fatal(err)
+		}
+		if obj.Transform.SX != tt.sx || obj.Transform.SY != tt.sy || obj.Transform.X != tt.x ||
+			obj.Style.Opacity != tt.alpha || obj.Visible != tt.visible {
+			t.Errorf("%s: filled to %s", tt.name, raw)
+		}
+	}
+
+	// Nothing missing leaves the payload as sent
+	complete := `{"transform":{"sx":1,"sy":1},"style":{"opacity":1},"visible":true}`
+	if raw, _ := FillObjectDefaults(json.RawMessage(complete)); string(raw) != complete {
+		t.Errorf("complete object rewritten to %s", raw)
+	}
+
+	for _, in := range []string{`[]`, `{"transform":[1]}`, `{"style":"red"}`} {
+		if _, err := FillObjectDefaults(json.RawMessage(in)); err == nil {
+			t.Errorf("%s filled", in)
+		}
+	}
+}
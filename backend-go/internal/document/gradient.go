@@ -0,0 +1,70 @@
+package document
+
+import (
+	"fmt"
+
+	"github.com/inamate/inamate/backend-go/internal/color"
+)
+
+// GradientType is how a gradient's colors spread
+type GradientType string
+
+const (
+	GradientLinear GradientType = "linear"
+	GradientRadial GradientType = "radial"
+)
+
+// GradientStop is a color at an offset along a gradient, from 0 at its start
+// to 1 at its end.
+type GradientStop struct {
+	Offset float64 `json:"offset"`
+	Color  string  `json:"color"`
+}
+
+// Gradient is a fill that blends between color stops. Coordinates are in the
+// object's local space: a linear gradient runs from (X1, Y1) to (X2, Y2), a
+// radial one from its center (CX, CY) out to radius R. Past either end the
+// nearest stop's color continues.
+type Gradient struct {
+	Type  GradientType   `json:"type"`
+	Stops []GradientStop `json:"stops"`
+
+	X1 float64 `json:"x1,omitempty"`
+	Y1 float64 `json:"y1,omitempty"`
+	X2 float64 `json:"x2,omitempty"`
+	Y2 float64 `json:"y2,omitempty"`
+
+	CX float64 `json:"cx,omitempty"`
+	CY float64 `json:"cy,omitempty"`
+	R  float64 `json:"r,omitempty"`
+}
+
+// Validate checks the gradient has a known type, at least two stops with
+// valid colors at offsets in [0, 1] in ascending order, and, if radial, a
+// positive radius.
+func (g Gradient) Validate() error {
+	switch g.Type {
+	case GradientLinear:
+	case GradientRadial:
+		if g.R <= 0 {
+			return fmt.Errorf("radial gradient needs a positive r")
+		}
+	default:
+		return fmt.Errorf("unknown gradient type %q", g.Type)
+	}
+	if len(g.Stops) < 2 {
+		return fmt.Errorf("gradient needs at least two stops")
+	}
+	for i, stop := range g.Stops {
+		if stop.Offset < 0 || stop.Offset > 1 {
+			return fmt.Errorf("stop %d: offset must be between 0 and 1", i)
+		}
+		if i > 0 && stop.Offset < g.Stops[i-1].Offset {
+			return fmt.Errorf("stop %d: offsets must be in ascending order", i)
+		}
+		if _, err := color.Parse(stop.Color); err != nil {
+			return fmt.Errorf("stop %d: %w", i, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package document
+
+import "fmt"
+
+// Units a project displays lengths in. Document values are always pixels;
+// the units only change how editors present and accept them.
+const (
+	UnitsPixels      = "px"
+	UnitsPoints      = "pt"
+	UnitsInches      = "in"
+	UnitsCentimeters = "cm"
+	UnitsMillimeters = "mm"
+)
+
+// Text and layout directions. An empty direction is left to right.
+const (
+	DirectionLTR = "ltr"
+	DirectionRTL = "rtl"
+)
+
+// ValidUnits reports whether u is a known unit. Empty means pixels.
+func ValidUnits(u string) bool {
+	switch u {
+	case "", UnitsPixels, UnitsPoints, UnitsInches, UnitsCentimeters, UnitsMillimeters:
+		return true
+	}
+	return false
+}
+
+// ValidDirection reports whether d is a known direction. Empty means ltr.
+func ValidDirection(d string) bool {
+	return d == "" || d == DirectionLTR || d == DirectionRTL
+}
+
+// Insets are distances in from each edge of a scene, in pixels.
+type Insets struct {
+	Top    float64 `json:"top"`
+	Right  float64 `json:"right"`
+	Bottom float64 `json:"bottom"`
+	Left   float64 `json:"left"`
+}
+
+// Validate checks that the insets are non-negative and leave some of a
+// width × height scene uncovered.
+func (in Insets) Validate(width, height int) error {
+	if in.Top < 0 || in.Right < 0 || in.Bottom < 0 || in.Left < 0 {
+		return fmt.Errorf("insets must not be negative")
+	}
+	if in.Left+in.Right >= float64(width) {
+		return fmt.Errorf("left and right insets must total less than the scene width %d", width)
+	}
+	if in.Top+in.Bottom >= float64(height) {
+		return fmt.Errorf("top and bottom insets must total less than the scene height %d", height)
+	}
+	return nil
+}
+
+// within reports whether every edge of in lies at or inside the same edge of
+// outer.
+func (in Insets) within(outer Insets) bool {
+	return in.Top >= outer.Top && in.Right >= outer.Right &&
+		in.Bottom >= outer.Bottom && in.Left >= outer.Left
+}
+
+// SafeAreaInsets mark a scene's broadcast safe areas. Content outside the
+// action-safe area may be cropped by overscan; titles belong inside the
+// title-safe area, which lies within it.
+type SafeAreaInsets struct {
+	Action Insets `json:"action"`
+	Title  Insets `json:"title"`
+}
+
+// SafeAreaPercent sizes safe areas by the percentage of the scene's width
+// and height each one keeps, centred on the scene.
+type SafeAreaPercent struct {
+	Action float64 `json:"action"`
+	Title  float64 `json:"title"`
+}
+
+// StandardSafeAreaPercent is the broadcast standard: action-safe keeps the
+// inner 93% and title-safe the inner 90%.
+var StandardSafeAreaPercent = SafeAreaPercent{Action: 93, Title: 90}
+
+// Validate checks both percentages are in (0, 100] and title-safe is no
+// larger than action-safe.
+func (p SafeAreaPercent) Validate() error {
+	if p.Title <= 0 || p.Action > 100 {
+		return fmt.Errorf("safe-area percentages must be between 0 and 100")
+	}
+	if p.Title > p.Action {
+		return fmt.Errorf("title-safe percentage must not exceed action-safe")
+	}
+	return nil
+}
+
+// Insets returns the safe areas for a scene size.
+func (p SafeAreaPercent) Insets(width, height int) SafeAreaInsets {
+	inset := func(percent float64) Insets {
+		margin := (100 - percent) / 200
+		x, y := margin*float64(width), margin*float64(height)
+		return Insets{Top: y, Right: x, Bottom: y, Left: x}
+	}
+	return SafeAreaInsets{Action: inset(p.Action), Title: inset(p.Title)}
+}
+
+// DefaultSafeAreaInsets returns the standard safe areas for a scene size.
+func DefaultSafeAreaInsets(width, height int) SafeAreaInsets {
+	return StandardSafeAreaPercent.Insets(width, height)
+}
+
+// Validate checks both areas fit a width × height scene and title-safe lies
+// within action-safe.
+func (s SafeAreaInsets) Validate(width, height int) error {
+	if err := s.Action.Validate(width, height); err != nil {
+		return fmt.Errorf("action: %w", err)
+	}
+	if err := s.Title.Validate(width, height); err != nil {
+		return fmt.Errorf("title: %w", err)
+	}
+	if !s.Title.within(s.Action) {
+		return fmt.Errorf("title-safe area must lie within the action-safe area")
+	}
+	return nil
+}
+
+// SafeArea returns the scene's safe-area insets, or the standard ones for its
+// size if it has none.
+func (s Scene) SafeArea() SafeAreaInsets {
+	return s.SafeAreaWith(StandardSafeAreaPercent)
+}
+
+// SafeAreaWith returns the scene's safe-area insets, or ones sized by p if
+// it has none.
+func (s Scene) SafeAreaWith(p SafeAreaPercent) SafeAreaInsets {
+	if s.SafeAreaInsets != nil {
+		return *s.SafeAreaInsets
+	}
+	return p.Insets(s.Width, s.Height)
+}
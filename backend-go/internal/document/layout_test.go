@@ -0,0 +1,93 @@
+package document
+
+import "testing"
+
+func TestDefaultSafeAreaInsets(t *testing.T) {
+	safe := DefaultSafeAreaInsets(1920, 1080)
+	want := SafeAreaInsets{
+		Action: Insets{Top: 37.8, Right: 67.2, Bottom: 37.8, Left: 67.2},
+		Title:  Insets{Top: 54, Right: 96, Bottom: 54, Left: 96},
+	}
+	near := func(a, b Insets) bool {
+		for _, d := range []float64{a.Top - b.Top, a.Right - b.Right, a.Bottom - b.Bottom, a.Left - b.Left} {
+			if d < -1e-9 || d > 1e-9 {
+				return false
+			}
+		}
+		return true
+	}
+	if !near(safe.Action, want.Action) || !near(safe.Title, want.Title) {
+		t.Errorf("1920x1080 safe areas %+v, want %+v", safe, want)
+	}
+	if err := safe.Validate(1920, 1080); err != nil {
+		t.Errorf("standard safe areas invalid: %v", err)
+	}
+
+	// A scene without insets of its own uses the standard ones
+	scene := Scene{Width: 1920, Height: 1080}
+	if scene.SafeArea() != safe {
+		t.Errorf("scene safe area %+v, want the standard %+v", scene.SafeArea(), safe)
+	}
+	custom := SafeAreaInsets{Action: Insets{Top: 10}, Title: Insets{Top: 20}}
+	scene.SafeAreaInsets = &custom
+	if scene.SafeArea() != custom {
+		t.Errorf("scene safe area %+v, want its own %+v", scene.SafeArea(), custom)
+	}
+}
+
+func TestSafeAreaInsetsValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		safe  SafeAreaInsets
+		valid bool
+	}{
+		{"zero", SafeAreaInsets{}, true},
+		{"nested", SafeAreaInsets{Action: Insets{10, 10, 10, 10}, Title: Insets{20, 20, 20, 20}}, true},
+		{"title on action", SafeAreaInsets{Action: Insets{10, 10, 10, 10}, Title: Insets{10, 10, 10, 10}}, true},
+		{"negative", SafeAreaInsets{Action: Insets{Left: -1}}, false},
+		{"title outside action", SafeAreaInsets{Action: Insets{10, 10, 10, 10}, Title: Insets{20, 5, 20, 20}}, false},
+		// Across a 100x50 scene
+		{"covers width", SafeAreaInsets{Action: Insets{Left: 50, Right: 50}, Title: Insets{Left: 50, Right: 50}}, false},
+		{"covers height", SafeAreaInsets{Action: Insets{Top: 20, Bottom: 30}, Title: Insets{Top: 20, Bottom: 30}}, false},
+		{"just fits", SafeAreaInsets{Action: Insets{Left: 49.5, Right: 50}, Title: Insets{Left: 49.5, Right: 50}}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.safe.Validate(100, 50); (err == nil) != tt.valid {
+			t.Errorf("%s: err %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
+
+func TestValidUnitsAndDirection(t *testing.T) {
+	for _, u := range []string{"", "px", "pt", "in", "cm", "mm"} {
+		if !ValidUnits(u) {
+			t.Errorf("units %q rejected", u)
+		}
+	}
+	for _, u := range []string{"PX", "em", "%"} {
+		if ValidUnits(u) {
+			t.Errorf("units %q accepted", u)
+		}
+	}
+	for d, want := range map[string]bool{"": true, "ltr": true, "rtl": true, "RTL": false, "ttb": false} {
+		if ValidDirection(d) != want {
+			t.Errorf("ValidDirection(%q) = %v", d, !want)
+		}
+	}
+}
+
+func TestSafeAreaPercentInsets(t *testing.T) {
+	safe := SafeAreaPercent{Action: 90, Title: 80}.Insets(1280, 720)
+	if safe.Title != (Insets{Top: 72, Right: 128, Bottom: 72, Left: 128}) {
+		t.Errorf("80%% title insets %+v", safe.Title)
+	}
+	if safe.Action != (Insets{Top: 36, Right: 64, Bottom: 36, Left: 64}) {
+		t.Errorf("90%% action insets %+v", safe.Action)
+	}
+	if err := safe.Validate(1280, 720); err != nil {
+		t.Errorf("percent insets invalid: %v", err)
+	}
+	if full := (SafeAreaPercent{Action: 100, Title: 100}).Insets(1280, 720); full != (SafeAreaInsets{}) {
+		t.Errorf("100%% insets %+v, want none", full)
+	}
+}
@@ -0,0 +1,189 @@
+package document
+
+import (
+	"encoding/json"
+
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// MigrateIDs rewrites scene, object, timeline, track, keyframe and asset IDs
+// that aren't TypeIDs with the expected prefix, updating every reference.
+// Older documents (the seeded playground, and objects created before clients
+// generated TypeIDs) use IDs like "scene_playground" or bare UUIDs, which
+// operations would otherwise be unable to address. The rewrite is
+// deterministic, so every load of the same document agrees. Reports whether
+// anything changed.
+func MigrateIDs(doc *InDocument) bool {
+	m := idMigration{
+		scenes:    legacyIDs(doc.Scenes, typeid.PrefixScene),
+		objects:   legacyIDs(doc.Objects, typeid.PrefixObject),
+		timelines: legacyIDs(doc.Timelines, typeid.PrefixTimeline),
+		tracks:    legacyIDs(doc.Tracks, typeid.PrefixTrack),
+		keyframes: legacyIDs(doc.Keyframes, typeid.PrefixKeyframe),
+		assets:    legacyIDs(doc.Assets, typeid.PrefixAsset),
+	}
+	if m.empty() {
+		return false
+	}
+	m.apply(doc)
+	return true
+}
+
+// ReassignIDs gives every scene, object, timeline, track and keyframe a new
+// TypeID, updating every reference, so a copy of a document can live beside
+// the original. Asset IDs are kept since they name the stored files.
+func ReassignIDs(doc *InDocument) {
+	idMigration{
+		scenes:    freshIDs(doc.Scenes, typeid.NewSceneID),
+		objects:   freshIDs(doc.Objects, typeid.NewObjectID),
+		timelines: freshIDs(doc.Timelines, typeid.NewTimelineID),
+		tracks:    freshIDs(doc.Tracks, typeid.NewTrackID),
+		keyframes: freshIDs(doc.Keyframes, typeid.NewKeyframeID),
+		assets:    map[string]string{},
+	}.apply(doc)
+}
+
+// apply rewrites the mapped IDs and every reference to them.
+func (m idMigration) apply(doc *InDocument) {
+	doc.Project.Scenes = rename(doc.Project.Scenes, m.scenes)
+	doc.Project.Assets = rename(doc.Project.Assets, m.assets)
+	doc.Project.RootTimeline = renameOne(doc.Project.RootTimeline, m.timelines)
+
+	scenes := make(map[string]Scene, len(doc.Scenes))
+	for _, scene := range doc.Scenes {
+		scene.ID = renameOne(scene.ID, m.scenes)
+		scene.Root = renameOne(scene.Root, m.objects)
+		scenes[scene.ID] = scene
+	}
+	doc.Scenes = scenes
+
+	objects := make(map[string]ObjectNode, len(doc.Objects))
+	for _, obj := range doc.Objects {
+		obj.ID = renameOne(obj.ID, m.objects)
+		if obj.Parent != nil {
+			parent := renameOne(*obj.Parent, m.objects)
+			obj.Parent = &parent
+		}
+		obj.Children = rename(obj.Children, m.objects)
+		obj.Data = m.renameData(obj.Data)
+		objects[obj.ID] = obj
+	}
+	doc.Objects = objects
+
+	timelines := make(map[string]Timeline, len(doc.Timelines))
+	for _, tl := range doc.Timelines {
+		tl.ID = renameOne(tl.ID, m.timelines)
+		tl.Tracks = rename(tl.Tracks, m.tracks)
+		timelines[tl.ID] = tl
+	}
+	doc.Timelines = timelines
+
+	tracks := make(map[string]Track, len(doc.Tracks))
+	for _, track := range doc.Tracks {
+		track.ID = renameOne(track.ID, m.tracks)
+		track.ObjectID = renameOne(track.ObjectID, m.objects)
+		track.Keys = rename(track.Keys, m.keyframes)
+		tracks[track.ID] = track
+	}
+	doc.Tracks = tracks
+
+	keyframes := make(map[string]Keyframe, len(doc.Keyframes))
+	for _, kf := range doc.Keyframes {
+		kf.ID = renameOne(kf.ID, m.keyframes)
+		keyframes[kf.ID] = kf
+	}
+	doc.Keyframes = keyframes
+
+	assets := make(map[string]Asset, len(doc.Assets))
+	for _, asset := range doc.Assets {
+		asset.ID = renameOne(asset.ID, m.assets)
+		assets[asset.ID] = asset
+	}
+	doc.Assets = assets
+}
+
+// idMigration maps legacy IDs to their replacements, per entity kind.
+type idMigration struct {
+	scenes, objects, timelines, tracks, keyframes, assets map[string]string
+}
+
+func (m idMigration) empty() bool {
+	return len(m.scenes)+len(m.objects)+len(m.timelines)+len(m.tracks)+len(m.keyframes)+len(m.assets) == 0
+}
+
+// renameData rewrites the IDs object data refers to: a Symbol's timelineId,
+// a RasterImage's assetId, and a SpriteSequence's assetIds or sheetAssetId.
+func (m idMigration) renameData(data json.RawMessage) json.RawMessage {
+	var fields map[string]json.RawMessage
+	if len(data) == 0 || json.Unmarshal(data, &fields) != nil {
+		return data
+	}
+	changed := false
+	for key, ids := range map[string]map[string]string{"timelineId": m.timelines, "assetId": m.assets, "sheetAssetId": m.assets} {
+		var id string
+		if json.Unmarshal(fields[key], &id) != nil {
+			continue
+		}
+		if newID, ok := ids[id]; ok {
+			fields[key], _ = json.Marshal(newID)
+			changed = true
+		}
+	}
+	var assetIDs []string
+	if json.Unmarshal(fields["assetIds"], &assetIDs) == nil {
+		renamed := false
+		for i, id := range assetIDs {
+			if newID, ok := m.assets[id]; ok {
+				assetIDs[i] = newID
+				renamed = true
+			}
+		}
+		if renamed {
+			fields["assetIds"], _ = json.Marshal(assetIDs)
+			changed = true
+		}
+	}
+	if !changed {
+		return data
+	}
+	out, err := json.Marshal(fields)
+	if err != nil {
+		return data
+	}
+	return out
+}
+
+// legacyIDs maps each key of entities that isn't a valid TypeID with prefix
+// to a stable replacement.
+func legacyIDs[T any](entities map[string]T, prefix string) map[string]string {
+	ids := make(map[string]string)
+	for id := range entities {
+		if typeid.Validate(id, prefix) != nil {
+			ids[id] = typeid.FromName(prefix, id)
+		}
+	}
+	return ids
+}
+
+// freshIDs maps each key of entities to a new ID from newID.
+func freshIDs[T any](entities map[string]T, newID func() string) map[string]string {
+	ids := make(map[string]string, len(entities))
+	for id := range entities {
+		ids[id] = newID()
+	}
+	return ids
+}
+
+func renameOne(id string, ids map[string]string) string {
+	if newID, ok := ids[id]; ok {
+		return newID
+	}
+	return id
+}
+
+func rename(list []string, ids map[string]string) []string {
+	for i, id := range list {
+		list[i] = renameOne(id, ids)
+	}
+	return list
+}
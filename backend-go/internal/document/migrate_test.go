@@ -0,0 +1,139 @@
+package document
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// legacySnapshot is a document saved before IDs were TypeIDs: the seeded
+// playground's named IDs, and bare UUIDs from older clients.
+const legacySnapshot = `{
+	"project": {"id": "proj_01h455vb4pex5vsknk084sn02q", "name": "Playground", "version": 1, "fps": 24,
+		"scenes": ["scene_playground"], "assets": ["logo"], "rootTimeline": "timeline_main"},
+	"scenes": {"scene_playground": {"id": "scene_playground", "name": "Scene 1", "width": 1280, "height": 720,
+		"background": "#ffffff", "root": "root_playground"}},
+	"objects": {
+		"root_playground": {"id": "root_playground", "type": "Group", "parent": null,
+			"children": ["3f2504e0-4f89-11d3-9a0c-0305e82c3301", "symbol_1", "image_1"], "visible": true, "data": {}},
+		"3f2504e0-4f89-11d3-9a0c-0305e82c3301": {"id": "3f2504e0-4f89-11d3-9a0c-0305e82c3301", "type": "ShapeRect",
+			"parent": "root_playground", "children": [], "visible": true, "data": {"width": 10, "height": 10}},
+		"symbol_1": {"id": "symbol_1", "type": "Symbol", "parent": "root_playground", "children": [],
+			"visible": true, "data": {"timelineId": "timeline_symbol"}},
+		"image_1": {"id": "image_1", "type": "RasterImage", "parent": "root_playground", "children": [],
+			"visible": true, "data": {"assetId": "logo"}}
+	},
+	"timelines": {
+		"timeline_main": {"id": "timeline_main", "length": 48, "tracks": ["track_x"]},
+		"timeline_symbol": {"id": "timeline_symbol", "length": 12, "tracks": []}
+	},
+	"tracks": {"track_x": {"id": "track_x", "objectId": "3f2504e0-4f89-11d3-9a0c-0305e82c3301",
+		"property": "transform.x", "keys": ["kf_0", "kf_1"]}},
+	"keyframes": {
+		"kf_0": {"id": "kf_0", "frame": 0, "value": 0, "easing": "linear"},
+		"kf_1": {"id": "kf_1", "frame": 24, "value": 100, "easing": "linear"}
+	},
+	"assets": {"logo": {"id": "logo", "type": "image", "name": "logo.png", "url": "/assets/logo.png"}}
+}`
+
+func legacyDoc(t *testing.T) *InDocument {
+	t.Helper()
+	var doc InDocument
+	if err := json.Unmarshal([]byte(legacySnapshot), &doc); err != nil {
+		t.Fatal(err)
+	}
+	return &doc
+}
+
+func TestMigrateIDs(t *testing.T) {
+	doc := legacyDoc(t)
+	if !MigrateIDs(doc) {
+		t.Fatal("legacy document reported unchanged")
+	}
+
+	// Every key is now a TypeID of its kind, and matches its entity's ID
+	check := func(kind, prefix string, ids map[string]string) {
+		for key, id := range ids {
+			if err := typeid.Validate(key, prefix); err != nil {
+				t.Errorf("%s %q: %v", kind, key, err)
+			}
+			if key != id {
+				t.Errorf("%s keyed %q has id %q", kind, key, id)
+			}
+		}
+	}
+	check("scene", typeid.PrefixScene, keys(doc.Scenes, func(s Scene) string { return s.ID }))
+	check("object", typeid.PrefixObject, keys(doc.Objects, func(o ObjectNode) string { return o.ID }))
+	check("timeline", typeid.PrefixTimeline, keys(doc.Timelines, func(tl Timeline) string { return tl.ID }))
+	check("track", typeid.PrefixTrack, keys(doc.Tracks, func(tr Track) string { return tr.ID }))
+	check("keyframe", typeid.PrefixKeyframe, keys(doc.Keyframes, func(kf Keyframe) string { return kf.ID }))
+	check("asset", typeid.PrefixAsset, keys(doc.Assets, func(a Asset) string { return a.ID }))
+
+	// References follow the renamed IDs
+	sceneID := doc.Project.Scenes[0]
+	scene, ok := doc.Scenes[sceneID]
+	if !ok {
+		t.Fatalf("project scene %q missing", sceneID)
+	}
+	root, ok := doc.Objects[scene.Root]
+	if !ok || len(root.Children) != 3 {
+		t.Fatalf("scene root %q: %+v", scene.Root, root)
+	}
+	for _, childID := range root.Children {
+		child, ok := doc.Objects[childID]
+		if !ok || child.Parent == nil || *child.Parent != scene.Root {
+			t.Errorf("child %q missing or not parented to the root", childID)
+		}
+	}
+	main, ok := doc.Timelines[doc.Project.RootTimeline]
+	if !ok || len(main.Tracks) != 1 {
+		t.Fatalf("root timeline %q: %+v", doc.Project.RootTimeline, main)
+	}
+	track := doc.Tracks[main.Tracks[0]]
+	if track.ObjectID != root.Children[0] {
+		t.Errorf("track animates %q, want the rect %q", track.ObjectID, root.Children[0])
+	}
+	for _, kfID := range track.Keys {
+		if _, ok := doc.Keyframes[kfID]; !ok {
+			t.Errorf("track key %q missing", kfID)
+		}
+	}
+
+	var symbol struct{ TimelineID string }
+	json.Unmarshal(doc.Objects[root.Children[1]].Data, &symbol)
+	if _, ok := doc.Timelines[symbol.TimelineID]; !ok {
+		t.Errorf("symbol timeline %q missing", symbol.TimelineID)
+	}
+	var image struct{ AssetID string }
+	json.Unmarshal(doc.Objects[root.Children[2]].Data, &image)
+	if _, ok := doc.Assets[image.AssetID]; !ok || doc.Project.Assets[0] != image.AssetID {
+		t.Errorf("image asset %q not the project's asset %v", image.AssetID, doc.Project.Assets)
+	}
+
+	// Migrating again is a no-op
+	if MigrateIDs(doc) {
+		t.Error("migrated document reported changed")
+	}
+}
+
+func TestMigrateIDsIsDeterministic(t *testing.T) {
+	a, b := legacyDoc(t), legacyDoc(t)
+	MigrateIDs(a)
+	MigrateIDs(b)
+	if !reflect.DeepEqual(a, b) {
+		t.Error("two loads of the same snapshot migrated differently")
+	}
+	if a.Project.Scenes[0] != typeid.FromName(typeid.PrefixScene, "scene_playground") {
+		t.Errorf("scene migrated to %q", a.Project.Scenes[0])
+	}
+}
+
+func keys[T any](entities map[string]T, id func(T) string) map[string]string {
+	out := make(map[string]string, len(entities))
+	for key, entity := range entities {
+		out[key] = id(entity)
+	}
+	return out
+}
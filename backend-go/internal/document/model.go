@@ -1,15 +1,23 @@
 package document
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/inamate/inamate/backend-go/internal/color"
+)
 
 type InDocument struct {
-	Project   Project               `json:"project"`
-	Scenes    map[string]Scene      `json:"scenes"`
-	Objects   map[string]ObjectNode `json:"objects"`
-	Timelines map[string]Timeline   `json:"timelines"`
-	Tracks    map[string]Track      `json:"tracks"`
-	Keyframes map[string]Keyframe   `json:"keyframes"`
-	Assets    map[string]Asset      `json:"assets"`
+	Project       Project                 `json:"project"`
+	Scenes        map[string]Scene        `json:"scenes"`
+	Objects       map[string]ObjectNode   `json:"objects"`
+	Timelines     map[string]Timeline     `json:"timelines"`
+	Tracks        map[string]Track        `json:"tracks"`
+	Keyframes     map[string]Keyframe     `json:"keyframes"`
+	Assets        map[string]Asset        `json:"assets"`
+	EasingPresets map[string]EasingPreset `json:"easingPresets,omitempty"`
 }
 
 type Project struct {
@@ -22,6 +30,12 @@ type Project struct {
 	Scenes       []string `json:"scenes"`
 	Assets       []string `json:"assets"`
 	RootTimeline string   `json:"rootTimeline"`
+	Units        string   `json:"units,omitempty"`     // Display units; see UnitsPixels
+	Direction    string   `json:"direction,omitempty"` // "ltr" or "rtl"; empty is ltr
+
+	// LinkedAssets lets editors replace the content of the project's assets
+	// in place, keeping their IDs, so a designer can re-export over them
+	LinkedAssets bool `json:"linkedAssets,omitempty"`
 }
 
 type Scene struct {
@@ -31,18 +45,22 @@ type Scene struct {
 	Height     int    `json:"height"`
 	Background string `json:"background"`
 	Root       string `json:"root"`
+
+	// SafeAreaInsets override the standard broadcast safe areas
+	SafeAreaInsets *SafeAreaInsets `json:"safeAreaInsets,omitempty"`
 }
 
 type ObjectType string
 
 const (
-	ObjectTypeGroup        ObjectType = "Group"
-	ObjectTypeShapeRect    ObjectType = "ShapeRect"
-	ObjectTypeShapeEllipse ObjectType = "ShapeEllipse"
-	ObjectTypeVectorPath   ObjectType = "VectorPath"
-	ObjectTypeRasterImage  ObjectType = "RasterImage"
-	ObjectTypeSymbol       ObjectType = "Symbol"
-	ObjectTypeText         ObjectType = "Text"
+	ObjectTypeGroup          ObjectType = "Group"
+	ObjectTypeShapeRect      ObjectType = "ShapeRect"
+	ObjectTypeShapeEllipse   ObjectType = "ShapeEllipse"
+	ObjectTypeVectorPath     ObjectType = "VectorPath"
+	ObjectTypeRasterImage    ObjectType = "RasterImage"
+	ObjectTypeSymbol         ObjectType = "Symbol"
+	ObjectTypeText           ObjectType = "Text"
+	ObjectTypeSpriteSequence ObjectType = "SpriteSequence"
 )
 
 type Transform struct {
@@ -62,6 +80,10 @@ type Style struct {
 	Stroke      string  `json:"stroke"`
 	StrokeWidth float64 `json:"strokeWidth"`
 	Opacity     float64 `json:"opacity"`
+
+	// FillGradient, when set, fills in place of Fill, which stays as the
+	// solid color for renderers that can't draw it
+	FillGradient *Gradient `json:"fillGradient,omitempty"`
 }
 
 type ObjectNode struct {
@@ -74,6 +96,22 @@ type ObjectNode struct {
 	Visible   bool            `json:"visible"`
 	Locked    bool            `json:"locked"`
 	Data      json.RawMessage `json:"data"`
+
+	// Meta is free-form key-value metadata for integrations, such as a
+	// localization key or a CMS binding. It is never rendered.
+	Meta json.RawMessage `json:"meta,omitempty"`
+}
+
+// Name returns the name the object was given, kept in its meta under "name"
+// until objects have a name of their own, or "" when it has none.
+func (o ObjectNode) Name() string {
+	var meta struct {
+		Name string `json:"name"`
+	}
+	if len(o.Meta) == 0 || json.Unmarshal(o.Meta, &meta) != nil {
+		return ""
+	}
+	return meta.Name
 }
 
 type Timeline struct {
@@ -87,6 +125,28 @@ type Track struct {
 	ObjectID string   `json:"objectId"`
 	Property string   `json:"property"`
 	Keys     []string `json:"keys"`
+
+	// EasingMode picks which keyframe's easing shapes each segment. Empty is
+	// EasingOutgoing.
+	EasingMode EasingMode `json:"easingMode,omitempty"`
+}
+
+// EasingMode says whether a segment between two keyframes is eased by the
+// keyframe it leaves or the one it arrives at.
+type EasingMode string
+
+const (
+	EasingOutgoing EasingMode = "outgoing" // The earlier keyframe's easing
+	EasingIncoming EasingMode = "incoming" // The later keyframe's easing
+)
+
+// SegmentEasing returns the keyframe whose easing drives the segment from
+// prev to next.
+func (t Track) SegmentEasing(prev, next Keyframe) Keyframe {
+	if t.EasingMode == EasingIncoming {
+		return next
+	}
+	return prev
 }
 
 type EasingType string
@@ -104,13 +164,197 @@ const (
 	EasingBackInOut  EasingType = "backInOut"
 	EasingElasticOut EasingType = "elasticOut"
 	EasingBounceOut  EasingType = "bounceOut"
+
+	// Springs overshoot the next value and settle back onto it. Their
+	// stiffness, damping and mass come from Keyframe.EasingParams, falling
+	// back to SpringDefaults.
+	EasingSpring      EasingType = "spring"
+	EasingSpringSoft  EasingType = "springSoft"
+	EasingSpringStiff EasingType = "springStiff"
+
+	// A custom curve with the control points of CSS cubic-bezier(), taken
+	// from Keyframe.Bezier. Without valid points it is linear.
+	EasingCubicBezier EasingType = "cubicBezier"
 )
 
+// EasingPresetPrefix marks an easing that refers to a named entry in
+// InDocument.EasingPresets, e.g. "preset:bouncy-soft".
+const EasingPresetPrefix = "preset:"
+
+// PresetName returns the preset an easing refers to, if it is a preset reference.
+func (e EasingType) PresetName() (string, bool) {
+	if !strings.HasPrefix(string(e), EasingPresetPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(string(e), EasingPresetPrefix), true
+}
+
+// EasingPreset is a reusable cubic-bezier curve with the same control points
+// as CSS cubic-bezier(x1, y1, x2, y2). Keyframes reference presets by name and
+// resolve them at evaluation time, so editing a preset updates every keyframe
+// that uses it.
+type EasingPreset struct {
+	X1 float64 `json:"x1"`
+	Y1 float64 `json:"y1"`
+	X2 float64 `json:"x2"`
+	Y2 float64 `json:"y2"`
+}
+
+// Mirrored returns the easing that plays this one backwards in time. Symmetric
+// easings and those without an "in" counterpart are returned unchanged.
+func (e EasingType) Mirrored() EasingType {
+	switch e {
+	case EasingEaseIn:
+		return EasingEaseOut
+	case EasingEaseOut:
+		return EasingEaseIn
+	case EasingCubicIn:
+		return EasingCubicOut
+	case EasingCubicOut:
+		return EasingCubicIn
+	case EasingBackIn:
+		return EasingBackOut
+	case EasingBackOut:
+		return EasingBackIn
+	default:
+		return e
+	}
+}
+
+// easingBeziers are the cubic-bezier control points of the named easings a
+// single curve can draw. The polynomial ones are exact: their x handles sit
+// at 1/3 and 2/3, so x runs linearly in time and y is the easing's own
+// polynomial. The piecewise in-out easings are least-squares fits, off by at
+// most 0.004 (0.023 for backInOut) of the change.
+var easingBeziers = map[EasingType][4]float64{
+	EasingLinear:     {0, 0, 1, 1},
+	EasingEaseIn:     {1.0 / 3, 0, 2.0 / 3, 1.0 / 3},
+	EasingEaseOut:    {1.0 / 3, 2.0 / 3, 2.0 / 3, 1},
+	EasingEaseInOut:  {0.485, 0.045, 0.515, 0.955},
+	EasingCubicIn:    {1.0 / 3, 0, 2.0 / 3, 0},
+	EasingCubicOut:   {1.0 / 3, 1, 2.0 / 3, 1},
+	EasingCubicInOut: {0.625, -0.04, 0.375, 1.04},
+	EasingBackIn:     {1.0 / 3, 0, 2.0 / 3, -1.70158 / 3},
+	EasingBackOut:    {1.0 / 3, 1 + 1.70158/3, 2.0 / 3, 1},
+	EasingBackInOut:  {0.74, -0.515, 0.26, 1.515},
+}
+
+// Bezier returns the cubic-bezier control points x1, y1, x2, y2 that draw
+// the easing's curve. An empty easing is linear. It reports false for
+// easings no single curve can draw, such as springs and bounces, and for
+// preset references, whose points are the preset's.
+func (e EasingType) Bezier() ([4]float64, bool) {
+	if e == "" {
+		e = EasingLinear
+	}
+	b, ok := easingBeziers[e]
+	return b, ok
+}
+
+// SpringParams configure a spring easing: a unit mass on a damped spring
+// released from the segment's start value towards its end value.
+type SpringParams struct {
+	Stiffness float64 `json:"stiffness,omitempty"`
+	Damping   float64 `json:"damping,omitempty"`
+	Mass      float64 `json:"mass,omitempty"`
+
+	// Reversed plays the spring backwards in time: it winds up around the
+	// start value and leaves it cleanly. track.reverse sets it when mirroring
+	// easings, since no spring draws that curve forwards.
+	Reversed bool `json:"reversed,omitempty"`
+}
+
+// SpringDefaults are the parameters of each spring easing. Soft springs wobble
+// longer, stiff ones barely overshoot.
+var SpringDefaults = map[EasingType]SpringParams{
+	EasingSpring:      {Stiffness: 100, Damping: 10, Mass: 1},
+	EasingSpringSoft:  {Stiffness: 100, Damping: 6, Mass: 1},
+	EasingSpringStiff: {Stiffness: 100, Damping: 16, Mass: 1},
+}
+
+// Spring returns the parameters of a spring easing, with fields that raw
+// leaves out or sets out of range taken from SpringDefaults. It reports false
+// if the easing isn't a spring.
+func (e EasingType) Spring(raw json.RawMessage) (SpringParams, bool) {
+	p, ok := SpringDefaults[e]
+	if !ok {
+		return SpringParams{}, false
+	}
+	var custom SpringParams
+	if len(raw) > 0 && json.Unmarshal(raw, &custom) == nil {
+		if custom.Stiffness > 0 {
+			p.Stiffness = custom.Stiffness
+		}
+		if custom.Damping > 0 {
+			p.Damping = custom.Damping
+		}
+		if custom.Mass > 0 {
+			p.Mass = custom.Mass
+		}
+		p.Reversed = custom.Reversed
+	}
+	return p, true
+}
+
 type Keyframe struct {
 	ID     string          `json:"id"`
 	Frame  int             `json:"frame"`
 	Value  json.RawMessage `json:"value"`
 	Easing EasingType      `json:"easing"`
+
+	// EasingParams tune easings that take parameters, such as SpringParams
+	// for springs. Other easings ignore them.
+	EasingParams json.RawMessage `json:"easingParams,omitempty"`
+
+	// Bezier holds the control points x1, y1, x2, y2 of a cubicBezier
+	// easing. Other easings ignore it.
+	Bezier *[4]float64 `json:"bezier,omitempty"`
+}
+
+// ValidBezier reports whether b are usable cubic-bezier control points: as
+// with CSS, the x coordinates must lie in [0, 1] so the curve is a function
+// of time, while y may overshoot.
+func ValidBezier(b [4]float64) bool {
+	for _, v := range b {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return false
+		}
+	}
+	return b[0] >= 0 && b[0] <= 1 && b[2] >= 0 && b[2] <= 1
+}
+
+// MirroredBezier returns the control points of the curve b played backwards
+// in time.
+func MirroredBezier(b [4]float64) [4]float64 {
+	return [4]float64{1 - b[2], 1 - b[3], 1 - b[0], 1 - b[1]}
+}
+
+// MirroredEasingParams returns the parameters that play easing e with params
+// backwards in time: a spring's Reversed flag is flipped, keeping any other
+// fields. Other easings' parameters are returned unchanged.
+func MirroredEasingParams(e EasingType, params json.RawMessage) json.RawMessage {
+	if _, ok := SpringDefaults[e]; !ok {
+		return params
+	}
+	fields := make(map[string]json.RawMessage)
+	if len(params) > 0 && json.Unmarshal(params, &fields) != nil {
+		return params
+	}
+	var reversed bool
+	json.Unmarshal(fields["reversed"], &reversed)
+	if reversed {
+		delete(fields, "reversed")
+	} else {
+		fields["reversed"] = json.RawMessage("true")
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	out, err := json.Marshal(fields)
+	if err != nil {
+		return params
+	}
+	return out
 }
 
 type Asset struct {
@@ -119,6 +363,10 @@ type Asset struct {
 	Name string          `json:"name"`
 	URL  string          `json:"url"`
 	Meta json.RawMessage `json:"meta"`
+
+	// Revision counts replacements of the asset's content; zero for an
+	// asset as uploaded
+	Revision int `json:"revision,omitempty"`
 }
 
 // NewSampleDocument creates a sample document for testing/WASM
@@ -132,14 +380,56 @@ func NewSampleDocument(projectID string) *InDocument {
 	)
 }
 
-// NewEmptyDocument creates an empty document for a new project
+// SceneSettings are the canvas settings a new document starts with.
+type SceneSettings struct {
+	Width      int
+	Height     int
+	Background string
+	FPS        int
+}
+
+// Limits on SceneSettings
+const (
+	MaxSceneSize = 8192
+	MaxFPS       = 120
+)
+
+// DefaultSceneSettings are used when no other settings are configured.
+var DefaultSceneSettings = SceneSettings{
+	Width:      1280,
+	Height:     720,
+	Background: "#ffffff",
+	FPS:        24,
+}
+
+// Validate reports the first setting that is out of range.
+func (s SceneSettings) Validate() error {
+	if s.Width < 1 || s.Width > MaxSceneSize || s.Height < 1 || s.Height > MaxSceneSize {
+		return fmt.Errorf("scene size must be between 1 and %d", MaxSceneSize)
+	}
+	if s.FPS < 1 || s.FPS > MaxFPS {
+		return fmt.Errorf("fps must be between 1 and %d", MaxFPS)
+	}
+	if _, err := color.Parse(s.Background); err != nil {
+		return fmt.Errorf("background: %w", err)
+	}
+	return nil
+}
+
+// NewEmptyDocument creates an empty document for a new project with the
+// default scene settings
 func NewEmptyDocument(projectID, projectName, sceneID, rootID, timelineID string) *InDocument {
+	return NewDocument(projectID, projectName, sceneID, rootID, timelineID, DefaultSceneSettings)
+}
+
+// NewDocument creates an empty document whose scene uses settings
+func NewDocument(projectID, projectName, sceneID, rootID, timelineID string, settings SceneSettings) *InDocument {
 	return &InDocument{
 		Project: Project{
 			ID:           projectID,
 			Name:         projectName,
-			Version:      1,
-			FPS:          24,
+			Version:      CurrentVersion,
+			FPS:          settings.FPS,
 			CreatedAt:    "", // Will be set by caller
 			UpdatedAt:    "",
 			Scenes:       []string{sceneID},
@@ -150,9 +440,9 @@ func NewEmptyDocument(projectID, projectName, sceneID, rootID, timelineID string
 			sceneID: {
 				ID:         sceneID,
 				Name:       "Scene 1",
-				Width:      1280,
-				Height:     720,
-				Background: "#ffffff",
+				Width:      settings.Width,
+				Height:     settings.Height,
+				Background: settings.Background,
 				Root:       rootID,
 			},
 		},
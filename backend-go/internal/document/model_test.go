@@ -0,0 +1,47 @@
+package document
+
+import "testing"
+
+func TestNewDocumentUsesSceneSettings(t *testing.T) {
+	settings := SceneSettings{Width: 1080, Height: 1080, Background: "#1a1a2e", FPS: 30}
+	doc := NewDocument("proj_1", "Square", "scene_1", "root_1", "timeline_1", settings)
+
+	scene := doc.Scenes[doc.Project.Scenes[0]]
+	if scene.Width != 1080 || scene.Height != 1080 || scene.Background != "#1a1a2e" {
+		t.Errorf("first scene %dx%d %s, want 1080x1080 #1a1a2e", scene.Width, scene.Height, scene.Background)
+	}
+	if doc.Project.FPS != 30 {
+		t.Errorf("fps %d, want 30", doc.Project.FPS)
+	}
+
+	empty := NewEmptyDocument("proj_1", "Default", "scene_1", "root_1", "timeline_1")
+	scene = empty.Scenes["scene_1"]
+	if scene.Width != DefaultSceneSettings.Width || scene.Height != DefaultSceneSettings.Height || empty.Project.FPS != DefaultSceneSettings.FPS {
+		t.Errorf("empty document scene %dx%d at %d fps, want the defaults", scene.Width, scene.Height, empty.Project.FPS)
+	}
+}
+
+func TestSceneSettingsValidate(t *testing.T) {
+	valid := DefaultSceneSettings
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("defaults invalid: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*SceneSettings)
+	}{
+		{"zero width", func(s *SceneSettings) { s.Width = 0 }},
+		{"huge height", func(s *SceneSettings) { s.Height = MaxSceneSize + 1 }},
+		{"zero fps", func(s *SceneSettings) { s.FPS = 0 }},
+		{"fps over max", func(s *SceneSettings) { s.FPS = MaxFPS + 1 }},
+		{"bad background", func(s *SceneSettings) { s.Background = "dark" }},
+	}
+	for _, tt := range tests {
+		s := DefaultSceneSettings
+		tt.modify(&s)
+		if err := s.Validate(); err == nil {
+			t.Errorf("%s: %+v accepted", tt.name, s)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package document
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// VectorPath commands are stored as JSON arrays such as ["M", x, y],
+// ["L", x, y], ["C", c1x, c1y, c2x, c2y, x, y], ["Q", cx, cy, x, y] and ["Z"].
+//
+// Vertex-level edits address a path by vertex index rather than command index:
+// vertex i is the anchor that ends the i-th non-"Z" command. A vertex's In
+// handle is the last control point of its own command (C or Q) and its Out
+// handle is the first control point of the following C command. Editing a
+// vertex only touches those slots, so concurrent edits of different vertices
+// never overwrite each other.
+
+// PathPoint is the anchor+handles view of one vertex.
+type PathPoint struct {
+	X   float64     `json:"x"`
+	Y   float64     `json:"y"`
+	In  *PathHandle `json:"in,omitempty"`
+	Out *PathHandle `json:"out,omitempty"`
+}
+
+// PathHandle is an absolute control point position.
+type PathHandle struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+var (
+	ErrPathVertexRange = errors.New("path vertex index out of range")
+	ErrPathCommand     = errors.New("invalid path command")
+)
+
+// pathArity is the length of each command array, including the letter.
+var pathArity = map[string]int{"M": 3, "L": 3, "C": 7, "Q": 5, "Z": 1}
+
+// PathCommands reads the commands of a VectorPath's data.
+func PathCommands(data json.RawMessage) ([][]interface{}, error) {
+	var pathData struct {
+		Commands [][]interface{} `json:"commands"`
+	}
+	if err := json.Unmarshal(data, &pathData); err != nil {
+		return nil, fmt.Errorf("invalid path data: %w", err)
+	}
+	for i, cmd := range pathData.Commands {
+		if err := ValidatePathCommand(cmd); err != nil {
+			return nil, fmt.Errorf("command %d: %w", i, err)
+		}
+	}
+	return pathData.Commands, nil
+}
+
+// WithPathCommands returns data with its commands replaced, keeping every
+// other field.
+func WithPathCommands(data json.RawMessage, commands [][]interface{}) (json.RawMessage, error) {
+	fields := map[string]json.RawMessage{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, fmt.Errorf("invalid path data: %w", err)
+		}
+	}
+	raw, err := json.Marshal(commands)
+	if err != nil {
+		return nil, err
+	}
+	fields["commands"] = raw
+	return json.Marshal(fields)
+}
+
+// ValidatePathCommand checks a command's letter and argument count.
+func ValidatePathCommand(cmd []interface{}) error {
+	if len(cmd) == 0 {
+		return ErrPathCommand
+	}
+	letter, ok := cmd[0].(string)
+	if !ok || pathArity[letter] != len(cmd) {
+		return fmt.Errorf("%w: %v", ErrPathCommand, cmd)
+	}
+	for _, arg := range cmd[1:] {
+		if _, ok := arg.(float64); !ok {
+			return fmt.Errorf("%w: non-numeric argument in %v", ErrPathCommand, cmd)
+		}
+	}
+	return nil
+}
+
+// PathVertexCommands returns the command index of each vertex.
+func PathVertexCommands(commands [][]interface{}) []int {
+	indices := make([]int, 0, len(commands))
+	for i, cmd := range commands {
+		if cmd[0] != "Z" {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// GetPathPoint returns vertex i as an anchor with handles.
+func GetPathPoint(commands [][]interface{}, i int) (PathPoint, error) {
+	vertices := PathVertexCommands(commands)
+	if i < 0 || i >= len(vertices) {
+		return PathPoint{}, ErrPathVertexRange
+	}
+	ci := vertices[i]
+	cmd := commands[ci]
+	n := len(cmd)
+	p := PathPoint{X: cmd[n-2].(float64), Y: cmd[n-1].(float64)}
+	if cmd[0] == "C" || cmd[0] == "Q" {
+		p.In = &PathHandle{X: cmd[n-4].(float64), Y: cmd[n-3].(float64)}
+	}
+	if ci+1 < len(commands) && commands[ci+1][0] == "C" {
+		p.Out = &PathHandle{X: commands[ci+1][1].(float64), Y: commands[ci+1][2].(float64)}
+	}
+	return p, nil
+}
+
+// MovePathPoint moves vertex i and, when given, its handles. Setting a handle
+// the vertex does not have (e.g. In on an "L") is an error rather than an
+// implicit conversion, so the command structure only changes through
+// insert/delete.
+func MovePathPoint(commands [][]interface{}, i int, p PathPoint) error {
+	vertices := PathVertexCommands(commands)
+	if i < 0 || i >= len(vertices) {
+		return ErrPathVertexRange
+	}
+	ci := vertices[i]
+	cmd := commands[ci]
+	n := len(cmd)
+
+	if p.In != nil && cmd[0] != "C" && cmd[0] != "Q" {
+		return fmt.Errorf("%w: vertex %d has no incoming handle", ErrPathCommand, i)
+	}
+	var next []interface{}
+	if p.Out != nil {
+		if ci+1 >= len(commands) || commands[ci+1][0] != "C" {
+			return fmt.Errorf("%w: vertex %d has no outgoing handle", ErrPathCommand, i)
+		}
+		next = commands[ci+1]
+	}
+
+	cmd[n-2], cmd[n-1] = p.X, p.Y
+	if p.In != nil {
+		cmd[n-4], cmd[n-3] = p.In.X, p.In.Y
+	}
+	if next != nil {
+		next[1], next[2] = p.Out.X, p.Out.Y
+	}
+	return nil
+}
+
+// InsertPathPoint inserts cmd so that it becomes vertex i. Vertex 0 is a
+// subpath's "M" and cannot be displaced; i may equal the vertex count to
+// append. A vertex inserted at the end of a closed subpath goes before its
+// "Z".
+func InsertPathPoint(commands [][]interface{}, i int, cmd []interface{}) ([][]interface{}, error) {
+	if err := ValidatePathCommand(cmd); err != nil {
+		return nil, err
+	}
+	if cmd[0] == "M" || cmd[0] == "Z" {
+		return nil, fmt.Errorf("%w: inserted vertex must be L, C or Q", ErrPathCommand)
+	}
+	vertices := PathVertexCommands(commands)
+	if i < 1 || i > len(vertices) {
+		return nil, ErrPathVertexRange
+	}
+
+	pos := len(commands)
+	if i < len(vertices) {
+		pos = vertices[i]
+	}
+	if pos > 0 && commands[pos-1][0] == "Z" {
+		pos--
+	}
+
+	result := make([][]interface{}, 0, len(commands)+1)
+	result = append(result, commands[:pos]...)
+	result = append(result, cmd)
+	return append(result, commands[pos:]...), nil
+}
+
+// DeletePathPoint removes vertex i and returns the removed command. A
+// subpath's "M" cannot be deleted.
+func DeletePathPoint(commands [][]interface{}, i int) ([][]interface{}, []interface{}, error) {
+	vertices := PathVertexCommands(commands)
+	if i < 0 || i >= len(vertices) {
+		return nil, nil, ErrPathVertexRange
+	}
+	ci := vertices[i]
+	if commands[ci][0] == "M" {
+		return nil, nil, fmt.Errorf("%w: cannot delete a subpath's start point", ErrPathCommand)
+	}
+
+	removed := commands[ci]
+	result := make([][]interface{}, 0, len(commands)-1)
+	result = append(result, commands[:ci]...)
+	return append(result, commands[ci+1:]...), removed, nil
+}
+
+// SetPathClosed opens or closes the subpath containing vertex i and reports
+// whether it was closed before.
+func SetPathClosed(commands [][]interface{}, i int, closed bool) ([][]interface{}, bool, error) {
+	vertices := PathVertexCommands(commands)
+	if i < 0 || i >= len(vertices) {
+		return nil, false, ErrPathVertexRange
+	}
+
+	// The subpath ends before the next "M" after vertex i
+	end := len(commands)
+	for ci := vertices[i] + 1; ci < len(commands); ci++ {
+		if commands[ci][0] == "M" {
+			end = ci
+			break
+		}
+	}
+	wasClosed := commands[end-1][0] == "Z"
+	if wasClosed == closed {
+		return commands, wasClosed, nil
+	}
+
+	result := make([][]interface{}, 0, len(commands)+1)
+	if closed {
+		result = append(result, commands[:end]...)
+		result = append(result, []interface{}{"Z"})
+	} else {
+		result = append(result, commands[:end-1]...)
+	}
+	return append(result, commands[end:]...), wasClosed, nil
+}
@@ -0,0 +1,131 @@
+package document
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// square is a closed path whose third vertex is a curve, followed by a
+// second, open subpath.
+const square = `[["M",0,0],["L",10,0],["C",15,0,20,5,20,10],["Z"],["M",50,50],["L",60,50]]`
+
+func commands(t *testing.T, raw string) [][]interface{} {
+	t.Helper()
+	cmds, err := PathCommands(json.RawMessage(`{"commands":` + raw + `}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cmds
+}
+
+func TestGetPathPoint(t *testing.T) {
+	cmds := commands(t, square)
+	tests := []struct {
+		i    int
+		want PathPoint
+	}{
+		{0, PathPoint{X: 0, Y: 0}},
+		{1, PathPoint{X: 10, Y: 0, Out: &PathHandle{X: 15, Y: 0}}},
+		{2, PathPoint{X: 20, Y: 10, In: &PathHandle{X: 20, Y: 5}}},
+		{3, PathPoint{X: 50, Y: 50}},
+	}
+	for _, tt := range tests {
+		got, err := GetPathPoint(cmds, tt.i)
+		if err != nil {
+			t.Fatalf("vertex %d: %v", tt.i, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("vertex %d = %+v, want %+v", tt.i, got, tt.want)
+		}
+	}
+	if _, err := GetPathPoint(cmds, 5); !errors.Is(err, ErrPathVertexRange) {
+		t.Errorf("vertex 5: %v, want ErrPathVertexRange", err)
+	}
+}
+
+func TestMovePathPointTouchesOnlyItsSlots(t *testing.T) {
+	cmds := commands(t, square)
+	err := MovePathPoint(cmds, 1, PathPoint{X: 11, Y: 1, Out: &PathHandle{X: 16, Y: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := commands(t, `[["M",0,0],["L",11,1],["C",16,1,20,5,20,10],["Z"],["M",50,50],["L",60,50]]`)
+	if !reflect.DeepEqual(cmds, want) {
+		t.Errorf("commands %v, want %v", cmds, want)
+	}
+
+	// Handles are not created by a move
+	if err := MovePathPoint(cmds, 1, PathPoint{X: 11, Y: 1, In: &PathHandle{}}); !errors.Is(err, ErrPathCommand) {
+		t.Errorf("in handle on an L: %v, want ErrPathCommand", err)
+	}
+	if err := MovePathPoint(cmds, 2, PathPoint{X: 20, Y: 10, Out: &PathHandle{}}); !errors.Is(err, ErrPathCommand) {
+		t.Errorf("out handle before a Z: %v, want ErrPathCommand", err)
+	}
+}
+
+func TestInsertAndDeletePathPoint(t *testing.T) {
+	cmds := commands(t, square)
+
+	// Appending to a closed subpath goes before its Z
+	inserted, err := InsertPathPoint(cmds, 3, []interface{}{"L", 0.0, 10.0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := commands(t, `[["M",0,0],["L",10,0],["C",15,0,20,5,20,10],["L",0,10],["Z"],["M",50,50],["L",60,50]]`)
+	if !reflect.DeepEqual(inserted, want) {
+		t.Errorf("after insert %v, want %v", inserted, want)
+	}
+
+	deleted, removed, err := DeletePathPoint(inserted, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(deleted, cmds) || !reflect.DeepEqual(removed, []interface{}{"L", 0.0, 10.0}) {
+		t.Errorf("delete undid insert as %v removing %v", deleted, removed)
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		fn   func() error
+	}{
+		{"insert before M", ErrPathVertexRange, func() error { _, err := InsertPathPoint(cmds, 0, []interface{}{"L", 1.0, 1.0}); return err }},
+		{"insert M", ErrPathCommand, func() error { _, err := InsertPathPoint(cmds, 1, []interface{}{"M", 1.0, 1.0}); return err }},
+		{"insert short C", ErrPathCommand, func() error { _, err := InsertPathPoint(cmds, 1, []interface{}{"C", 1.0, 1.0}); return err }},
+		{"delete M", ErrPathCommand, func() error { _, _, err := DeletePathPoint(cmds, 3); return err }},
+		{"delete out of range", ErrPathVertexRange, func() error { _, _, err := DeletePathPoint(cmds, 9); return err }},
+	}
+	for _, tt := range tests {
+		if err := tt.fn(); !errors.Is(err, tt.err) {
+			t.Errorf("%s: %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestSetPathClosed(t *testing.T) {
+	cmds := commands(t, square)
+
+	opened, wasClosed, err := SetPathClosed(cmds, 1, false)
+	if err != nil || !wasClosed {
+		t.Fatalf("open first subpath: closed before %v, err %v", wasClosed, err)
+	}
+	want := commands(t, `[["M",0,0],["L",10,0],["C",15,0,20,5,20,10],["M",50,50],["L",60,50]]`)
+	if !reflect.DeepEqual(opened, want) {
+		t.Errorf("opened %v, want %v", opened, want)
+	}
+
+	closed, wasClosed, err := SetPathClosed(cmds, 4, true)
+	if err != nil || wasClosed {
+		t.Fatalf("close second subpath: closed before %v, err %v", wasClosed, err)
+	}
+	if last := closed[len(closed)-1]; last[0] != "Z" {
+		t.Errorf("second subpath ends with %v, want Z", last)
+	}
+
+	same, _, _ := SetPathClosed(cmds, 0, true)
+	if !reflect.DeepEqual(same, cmds) {
+		t.Errorf("closing a closed subpath changed it to %v", same)
+	}
+}
@@ -0,0 +1,85 @@
+package document
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+)
+
+// DefaultPrecision is how many decimals the numbers written to documents keep
+// unless SetPrecision says otherwise. Drags produce values like
+// 417.2384756293847; past a thousandth of a pixel or degree the extra digits
+// only bloat snapshots and broadcasts.
+const DefaultPrecision = 3
+
+var precision atomic.Int32
+
+func init() {
+	precision.Store(DefaultPrecision)
+}
+
+// SetPrecision sets how many decimals operation values and canonical
+// documents are rounded to. At least one decimal is kept: rounding drags to
+// whole pixels and degrees would be visible. It should be called at startup,
+// before any documents are handled.
+func SetPrecision(decimals int) error {
+	if decimals < 1 {
+		return fmt.Errorf("document precision must be at least 1, got %d", decimals)
+	}
+	precision.Store(int32(decimals))
+	return nil
+}
+
+// Precision returns how many decimals document numbers are rounded to.
+func Precision() int {
+	return int(precision.Load())
+}
+
+// Round rounds v to decimals places. Values too large to round are returned
+// unchanged.
+func Round(v float64, decimals int) float64 {
+	if v == 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+		return v
+	}
+	rounded := math.Round(v*math.Pow10(decimals)) / math.Pow10(decimals)
+	if math.IsInf(rounded, 0) || math.IsNaN(rounded) {
+		return v
+	}
+	return rounded
+}
+
+// RoundScale is Round for a scale factor. A non-zero scale never rounds to
+// zero, so a tiny one can't become singular: it keeps the smallest step of
+// its sign instead.
+func RoundScale(v float64, decimals int) float64 {
+	rounded := Round(v, decimals)
+	if rounded == 0 && v != 0 {
+		return math.Copysign(1/math.Pow10(decimals), v)
+	}
+	return rounded
+}
+
+// RoundField rounds the value of a transform or style field, by its JSON
+// name, to decimals places, guarding the scale fields as RoundScale does.
+func RoundField(name string, v float64, decimals int) float64 {
+	if name == "sx" || name == "sy" {
+		return RoundScale(v, decimals)
+	}
+	return Round(v, decimals)
+}
+
+// Rounded returns t with every field rounded to decimals places, the scales
+// by RoundScale.
+func (t Transform) Rounded(decimals int) Transform {
+	return Transform{
+		X:     Round(t.X, decimals),
+		Y:     Round(t.Y, decimals),
+		SX:    RoundScale(t.SX, decimals),
+		SY:    RoundScale(t.SY, decimals),
+		R:     Round(t.R, decimals),
+		AX:    Round(t.AX, decimals),
+		AY:    Round(t.AY, decimals),
+		SkewX: Round(t.SkewX, decimals),
+		SkewY: Round(t.SkewY, decimals),
+	}
+}
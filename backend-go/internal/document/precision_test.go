@@ -0,0 +1,119 @@
+package document
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		v, want float64
+	}{
+		{417.2384756293847, 417.238},
+		{-0.0004, 0},
+		{0.0004, 0},
+		{0.0005, 0.001},
+		{0, 0},
+		{1e300, 1e300},
+	}
+	for _, tt := range tests {
+		if got := Round(tt.v, 3); got != tt.want {
+			t.Errorf("Round(%v, 3) = %v, want %v", tt.v, got, tt.want)
+		}
+	}
+	if got := Round(math.NaN(), 3); !math.IsNaN(got) {
+		t.Errorf("Round(NaN) = %v", got)
+	}
+}
+
+func TestRoundScaleKeepsNonZero(t *testing.T) {
+	tests := []struct {
+		v, want float64
+	}{
+		{0.0004, 0.001},
+		{-0.0004, -0.001},
+		{0, 0},
+		{1.23456, 1.235},
+	}
+	for _, tt := range tests {
+		if got := RoundScale(tt.v, 3); got != tt.want {
+			t.Errorf("RoundScale(%v, 3) = %v, want %v", tt.v, got, tt.want)
+		}
+	}
+
+	rounded := Transform{X: 0.0004, SX: 0.0004, SY: -0.0002, R: 0.0001}.Rounded(3)
+	if rounded.SX != 0.001 || rounded.SY != -0.001 {
+		t.Errorf("scales rounded to %v, %v; want ±0.001", rounded.SX, rounded.SY)
+	}
+	if rounded.X != 0 || rounded.R != 0 {
+		t.Errorf("position and rotation rounded to %v, %v; want 0", rounded.X, rounded.R)
+	}
+}
+
+func TestSetPrecisionRequiresOneDecimal(t *testing.T) {
+	t.Cleanup(func() { SetPrecision(DefaultPrecision) })
+
+	for _, decimals := range []int{0, -1} {
+		if err := SetPrecision(decimals); err == nil {
+			t.Errorf("SetPrecision(%d) accepted", decimals)
+		}
+	}
+	if Precision() != DefaultPrecision {
+		t.Errorf("precision changed to %d by a refused value", Precision())
+	}
+	if err := SetPrecision(2); err != nil || Precision() != 2 {
+		t.Errorf("SetPrecision(2): %v, precision %d", err, Precision())
+	}
+}
+
+func TestMarshalCanonicalRoundsOnlyTransformAndStyle(t *testing.T) {
+	doc := NewEmptyDocument("proj_test", "Test", "scene_test", "root_test", "timeline_test")
+	root := doc.Objects["root_test"]
+	root.Transform = Transform{X: 10.123456, SX: 0.0001, SY: 1}
+	root.Style.Opacity = 0.987654
+	root.Data = json.RawMessage(`{"width":40.123456}`)
+	doc.Objects["root_test"] = root
+	doc.Keyframes["key_test"] = Keyframe{ID: "key_test", Value: json.RawMessage(`1.123456`)}
+
+	data, err := MarshalCanonical(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got InDocument
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	obj := got.Objects["root_test"]
+	if obj.Transform.X != 10.123 || obj.Transform.SX != 0.001 || obj.Style.Opacity != 0.988 {
+		t.Errorf("transform and style not rounded: %+v, opacity %v", obj.Transform, obj.Style.Opacity)
+	}
+	if string(obj.Data) != `{"width":40.123456}` {
+		t.Errorf("object data rounded: %s", obj.Data)
+	}
+	if string(got.Keyframes["key_test"].Value) != `1.123456` {
+		t.Errorf("keyframe value rounded: %s", got.Keyframes["key_test"].Value)
+	}
+}
+
+// TestCanonicalSnapshotSize measures how much smaller rounding makes a
+// snapshot whose objects were placed by drags.
+func TestCanonicalSnapshotSize(t *testing.T) {
+	doc := NewSynthDocument(SynthOptions{Objects: 500, Seed: 1})
+
+	plain, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	canonical, err := MarshalCanonical(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reduction := 1 - float64(len(canonical))/float64(len(plain))
+	t.Logf("snapshot of 500 objects: %d bytes -> %d bytes canonical (%.1f%% smaller)", len(plain), len(canonical), reduction*100)
+	if reduction < 0.05 {
+		t.Errorf("canonical snapshot only %.1f%% smaller, want at least 5%%", reduction*100)
+	}
+}
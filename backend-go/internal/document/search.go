@@ -0,0 +1,139 @@
+package document
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// searchContext is how many characters of context a match snippet keeps on
+// each side of the match.
+const searchContext = 30
+
+// SearchMatch is a place in a document where a search query was found.
+type SearchMatch struct {
+	SceneID  string `json:"sceneId"`
+	ObjectID string `json:"objectId,omitempty"` // Empty for a scene name
+	Field    string `json:"field"`              // "name", "content" or "meta.<key>"
+	Snippet  string `json:"snippet"`            // The match with some context
+}
+
+// Search finds query, ignoring case, in the document's scene names, the
+// content of its text objects and the string values of its objects' meta,
+// where editors keep object names and comments. Matches are in scene order,
+// then in painter's order within each scene; objects not under any scene are
+// not searched. At most limit matches are returned, and truncated reports
+// whether there were more.
+func Search(doc *InDocument, query string, limit int) (matches []SearchMatch, truncated bool) {
+	matches = make([]SearchMatch, 0)
+	needle := foldRunes(query)
+	if len(needle) == 0 || limit <= 0 {
+		return matches, false
+	}
+
+	add := func(m SearchMatch, text string) bool {
+		snippet, ok := matchSnippet(text, needle)
+		if !ok {
+			return true
+		}
+		if len(matches) == limit {
+			truncated = true
+			return false
+		}
+		m.Snippet = snippet
+		matches = append(matches, m)
+		return true
+	}
+
+	visited := make(map[string]bool)
+	var walk func(sceneID, objectID string) bool
+	walk = func(sceneID, objectID string) bool {
+		obj, ok := doc.Objects[objectID]
+		if !ok || visited[objectID] {
+			return true
+		}
+		visited[objectID] = true
+
+		if obj.Type == ObjectTypeText {
+			var data struct {
+				Content string `json:"content"`
+			}
+			if json.Unmarshal(obj.Data, &data) == nil &&
+				!add(SearchMatch{SceneID: sceneID, ObjectID: objectID, Field: "content"}, data.Content) {
+				return false
+			}
+		}
+		var meta map[string]interface{}
+		if len(obj.Meta) > 0 && json.Unmarshal(obj.Meta, &meta) == nil {
+			keys := make([]string, 0, len(meta))
+			for key := range meta {
+				keys = append(keys, key)
+			}
+			slices.Sort(keys)
+			for _, key := range keys {
+				value, ok := meta[key].(string)
+				if ok && !add(SearchMatch{SceneID: sceneID, ObjectID: objectID, Field: "meta." + key}, value) {
+					return false
+				}
+			}
+		}
+
+		for _, childID := range obj.Children {
+			if !walk(sceneID, childID) {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, sceneID := range doc.Project.Scenes {
+		scene, ok := doc.Scenes[sceneID]
+		if !ok {
+			continue
+		}
+		if !add(SearchMatch{SceneID: sceneID, Field: "name"}, scene.Name) || !walk(sceneID, scene.Root) {
+			break
+		}
+	}
+	return matches, truncated
+}
+
+// foldRunes lowercases s rune by rune, so indices into the result are indices
+// into []rune(s).
+func foldRunes(s string) []rune {
+	runes := []rune(s)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	return runes
+}
+
+// matchSnippet finds needle, already folded, in text ignoring case and
+// returns the match with up to searchContext characters either side, on one
+// line, with ellipses where text was cut.
+func matchSnippet(text string, needle []rune) (string, bool) {
+	folded := foldRunes(text)
+	at := -1
+	for i := 0; i+len(needle) <= len(folded); i++ {
+		if slices.Equal(folded[i:i+len(needle)], needle) {
+			at = i
+			break
+		}
+	}
+	if at < 0 {
+		return "", false
+	}
+
+	runes := []rune(text)
+	start := max(0, at-searchContext)
+	end := min(len(runes), at+len(needle)+searchContext)
+	snippet := strings.Join(strings.Fields(string(runes[start:end])), " ")
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(runes) {
+		snippet += "…"
+	}
+	return snippet, true
+}
@@ -0,0 +1,94 @@
+package document
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// searchDoc is a two-scene document with a match for "cta" in a scene name,
+// a text object's content, an object's name and another's comment, and one
+// in an object no scene reaches.
+func searchDoc() *InDocument {
+	doc := NewEmptyDocument("proj_1", "Search", "scene_1", "root_1", "timeline_1")
+	main := doc.Scenes["scene_1"]
+	main.Name = "Main"
+	doc.Scenes[main.ID] = main
+	doc.Scenes["scene_2"] = Scene{ID: "scene_2", Name: "End card CTA", Root: "root_2"}
+	doc.Project.Scenes = append(doc.Project.Scenes, "scene_2")
+
+	add := func(id, parent string, typ ObjectType, data, meta string) {
+		obj := ObjectNode{ID: id, Type: typ, Children: []string{}, Visible: true, Data: json.RawMessage(data)}
+		if parent != "" {
+			p := parent
+			obj.Parent = &p
+			parentObj := doc.Objects[parent]
+			parentObj.Children = append(parentObj.Children, id)
+			doc.Objects[parent] = parentObj
+		}
+		if meta != "" {
+			obj.Meta = json.RawMessage(meta)
+		}
+		doc.Objects[id] = obj
+	}
+	add("root_2", "", ObjectTypeGroup, `{}`, "")
+	add("button", "root_1", ObjectTypeShapeRect, `{"width":10,"height":10}`,
+		`{"name":"CTA button","comment":"Make the cta pop","order":3}`)
+	add("label", "button", ObjectTypeText, `{"content":"Sign up now"}`, "")
+	add("tagline", "root_2", ObjectTypeText, `{"content":"Tap the   Cta\nbelow"}`, `{"name":"Tagline"}`)
+	add("orphan", "", ObjectTypeText, `{"content":"CTA nobody sees"}`, "")
+	return doc
+}
+
+func TestSearch(t *testing.T) {
+	matches, truncated := Search(searchDoc(), "cta", 10)
+	want := []SearchMatch{
+		{SceneID: "scene_1", ObjectID: "button", Field: "meta.comment", Snippet: "Make the cta pop"},
+		{SceneID: "scene_1", ObjectID: "button", Field: "meta.name", Snippet: "CTA button"},
+		{SceneID: "scene_2", Field: "name", Snippet: "End card CTA"},
+		{SceneID: "scene_2", ObjectID: "tagline", Field: "content", Snippet: "Tap the Cta below"},
+	}
+	if !reflect.DeepEqual(matches, want) || truncated {
+		t.Errorf("matches %+v (truncated %v), want %+v", matches, truncated, want)
+	}
+
+	// Text content, and meta values that aren't strings are skipped
+	if matches, _ := Search(searchDoc(), "SIGN UP", 10); len(matches) != 1 || matches[0].ObjectID != "label" || matches[0].Field != "content" {
+		t.Errorf("text search matched %+v, want the label's content", matches)
+	}
+	if matches, _ := Search(searchDoc(), "3", 10); len(matches) != 0 {
+		t.Errorf("number meta matched %+v", matches)
+	}
+}
+
+func TestSearchLimit(t *testing.T) {
+	matches, truncated := Search(searchDoc(), "cta", 2)
+	if len(matches) != 2 || !truncated {
+		t.Errorf("%d matches (truncated %v), want 2 and truncated", len(matches), truncated)
+	}
+	if matches, truncated := Search(searchDoc(), "cta", 4); len(matches) != 4 || truncated {
+		t.Errorf("limit of exactly the matches: %d (truncated %v)", len(matches), truncated)
+	}
+	for _, query := range []string{"", "missing"} {
+		if matches, truncated := Search(searchDoc(), query, 10); matches == nil || len(matches) != 0 || truncated {
+			t.Errorf("%q matched %v, want an empty list", query, matches)
+		}
+	}
+}
+
+func TestSearchSnippet(t *testing.T) {
+	doc := searchDoc()
+	label := doc.Objects["label"]
+	label.Data = json.RawMessage(`{"content":"` + strings.Repeat("á", 40) + "ÉCRAN" + strings.Repeat("é", 40) + `"}`)
+	doc.Objects["label"] = label
+
+	matches, _ := Search(doc, "écran", 10)
+	if len(matches) != 1 {
+		t.Fatalf("matches %+v, want one", matches)
+	}
+	// 30 characters either side, not bytes, with ellipses where cut
+	if want := "…" + strings.Repeat("á", 30) + "ÉCRAN" + strings.Repeat("é", 30) + "…"; matches[0].Snippet != want {
+		t.Errorf("snippet %q, want %q", matches[0].Snippet, want)
+	}
+}
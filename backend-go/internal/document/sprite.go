@@ -0,0 +1,87 @@
+package document
+
+import (
+	"fmt"
+	"math"
+)
+
+// SpriteSequenceData is the data of a SpriteSequence object: a pre-rendered
+// image sequence played back as the timeline runs. Its frames are either
+// separate assets, in order, or the cells of a sprite sheet asset read left
+// to right, top to bottom.
+type SpriteSequenceData struct {
+	AssetIDs []string `json:"assetIds,omitempty"`
+
+	// Sprite sheet, when AssetIDs is empty
+	SheetAssetID string `json:"sheetAssetId,omitempty"`
+	FrameCount   int    `json:"frameCount,omitempty"`
+	Columns      int    `json:"columns,omitempty"`
+
+	Width  float64 `json:"width"` // Size of one frame
+	Height float64 `json:"height"`
+
+	// Playback: the first frame shows at StartFrame, then Rate sequence
+	// frames advance per timeline frame (0 means 1). After the last frame the
+	// sequence loops or holds it.
+	StartFrame int     `json:"startFrame,omitempty"`
+	Rate       float64 `json:"rate,omitempty"`
+	Loop       bool    `json:"loop,omitempty"`
+}
+
+// Frames returns the number of frames in the sequence.
+func (d SpriteSequenceData) Frames() int {
+	if len(d.AssetIDs) > 0 {
+		return len(d.AssetIDs)
+	}
+	return d.FrameCount
+}
+
+// FrameAt returns the index of the sequence frame shown at a (possibly
+// fractional) timeline frame, or -1 if the sequence is empty. The first frame
+// is held before StartFrame.
+func (d SpriteSequenceData) FrameAt(frame float64) int {
+	n := d.Frames()
+	if n == 0 {
+		return -1
+	}
+	rate := d.Rate
+	if rate <= 0 {
+		rate = 1
+	}
+	// Nudge so a whole frame times a fractional rate doesn't land just short
+	i := int(math.Floor((frame-float64(d.StartFrame))*rate + 1e-9))
+	switch {
+	case i < 0:
+		return 0
+	case d.Loop:
+		return i % n
+	default:
+		return min(i, n-1)
+	}
+}
+
+// Cell returns the position of sequence frame i in the sprite sheet.
+func (d SpriteSequenceData) Cell(i int) (x, y float64) {
+	cols := max(d.Columns, 1)
+	return float64(i%cols) * d.Width, float64(i/cols) * d.Height
+}
+
+// Validate checks the sequence has frames and a positive size, and a sprite
+// sheet a positive frame count and column count.
+func (d SpriteSequenceData) Validate() error {
+	if len(d.AssetIDs) == 0 {
+		if d.SheetAssetID == "" {
+			return fmt.Errorf("needs assetIds or a sheetAssetId")
+		}
+		if d.FrameCount < 1 || d.Columns < 1 {
+			return fmt.Errorf("sprite sheet needs a positive frameCount and columns")
+		}
+	}
+	if d.Width <= 0 || d.Height <= 0 {
+		return fmt.Errorf("frame size must be positive")
+	}
+	if d.Rate < 0 {
+		return fmt.Errorf("rate must not be negative")
+	}
+	return nil
+}
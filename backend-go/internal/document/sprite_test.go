@@ -0,0 +1,80 @@
+package document
+
+import "testing"
+
+func TestSpriteSequenceFrameAt(t *testing.T) {
+	three := SpriteSequenceData{AssetIDs: []string{"a", "b", "c"}, Width: 1, Height: 1}
+	tests := []struct {
+		name  string
+		data  func(d SpriteSequenceData) SpriteSequenceData
+		frame float64
+		want  int
+	}{
+		{"first", nil, 0, 0},
+		{"second", nil, 1, 1},
+		{"third", nil, 2, 2},
+		{"between frames", nil, 1.5, 1},
+		{"holds the last", nil, 10, 2},
+		{"loops", func(d SpriteSequenceData) SpriteSequenceData { d.Loop = true; return d }, 4, 1},
+		{"holds the first before the start", func(d SpriteSequenceData) SpriteSequenceData { d.StartFrame = 5; return d }, 2, 0},
+		{"offset by the start", func(d SpriteSequenceData) SpriteSequenceData { d.StartFrame = 5; return d }, 6, 1},
+		{"half rate", func(d SpriteSequenceData) SpriteSequenceData { d.Rate = 0.5; return d }, 3, 1},
+		{"double rate", func(d SpriteSequenceData) SpriteSequenceData { d.Rate = 2; return d }, 1, 2},
+		// Without a nudge, 10 × 0.1 would land just short of frame 1
+		{"fractional rate on a whole frame", func(d SpriteSequenceData) SpriteSequenceData { d.Rate = 0.1; return d }, 10, 1},
+		{"sheet", func(d SpriteSequenceData) SpriteSequenceData {
+			return SpriteSequenceData{SheetAssetID: "s", FrameCount: 5, Columns: 2, Width: 1, Height: 1}
+		}, 4, 4},
+	}
+	for _, tt := range tests {
+		d := three
+		if tt.data != nil {
+			d = tt.data(d)
+		}
+		if got := d.FrameAt(tt.frame); got != tt.want {
+			t.Errorf("%s: frame at %v = %d, want %d", tt.name, tt.frame, got, tt.want)
+		}
+	}
+	if got := (SpriteSequenceData{}).FrameAt(0); got != -1 {
+		t.Errorf("empty sequence frame = %d, want -1", got)
+	}
+}
+
+func TestSpriteSequenceCell(t *testing.T) {
+	d := SpriteSequenceData{SheetAssetID: "s", FrameCount: 5, Columns: 2, Width: 30, Height: 20}
+	tests := []struct {
+		i    int
+		x, y float64
+	}{
+		{0, 0, 0},
+		{1, 30, 0},
+		{2, 0, 20},
+		{4, 0, 40},
+	}
+	for _, tt := range tests {
+		if x, y := d.Cell(tt.i); x != tt.x || y != tt.y {
+			t.Errorf("cell %d at %v,%v, want %v,%v", tt.i, x, y, tt.x, tt.y)
+		}
+	}
+}
+
+func TestSpriteSequenceValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  SpriteSequenceData
+		valid bool
+	}{
+		{"assets", SpriteSequenceData{AssetIDs: []string{"a"}, Width: 10, Height: 10}, true},
+		{"sheet", SpriteSequenceData{SheetAssetID: "s", FrameCount: 4, Columns: 2, Width: 10, Height: 10}, true},
+		{"no frames", SpriteSequenceData{Width: 10, Height: 10}, false},
+		{"sheet without a count", SpriteSequenceData{SheetAssetID: "s", Columns: 2, Width: 10, Height: 10}, false},
+		{"sheet without columns", SpriteSequenceData{SheetAssetID: "s", FrameCount: 4, Width: 10, Height: 10}, false},
+		{"no size", SpriteSequenceData{AssetIDs: []string{"a"}}, false},
+		{"negative rate", SpriteSequenceData{AssetIDs: []string{"a"}, Width: 10, Height: 10, Rate: -1}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.data.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: err %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
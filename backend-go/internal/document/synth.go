@@ -0,0 +1,172 @@
+package document
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+)
+
+// SynthOptions sizes a generated document. Zero values fall back to the
+// defaults noted on each field.
+type SynthOptions struct {
+	Objects           int   // Shapes in the scene (default 100)
+	GroupSize         int   // Shapes per group; 0 puts every shape under the root
+	TracksPerObject   int   // Animated properties per shape, capped at len(synthProperties)
+	KeyframesPerTrack int   // Default 2
+	Length            int   // Timeline length in frames (default 120)
+	Seed              int64 // Seeds placement, colors and keyframe values
+}
+
+// synthProperties are the properties animated by generated tracks, in the
+// order they are assigned to each object.
+var synthProperties = []string{
+	"transform.x",
+	"transform.y",
+	"transform.r",
+	"transform.sx",
+	"transform.sy",
+	"style.opacity",
+	"style.fill",
+}
+
+var synthEasings = []EasingType{
+	EasingLinear,
+	EasingEaseInOut,
+	EasingCubicOut,
+	EasingBackOut,
+}
+
+// NewSynthDocument creates a deterministic document of the given size for
+// benchmarking the engine. Shapes alternate between rects, ellipses and paths
+// so every build path is exercised. IDs are readable ("obj_12", "track_12_0")
+// rather than typeids so runs are reproducible.
+func NewSynthDocument(opts SynthOptions) *InDocument {
+	if opts.Objects <= 0 {
+		opts.Objects = 100
+	}
+	if opts.KeyframesPerTrack <= 0 {
+		opts.KeyframesPerTrack = 2
+	}
+	if opts.Length <= 0 {
+		opts.Length = 120
+	}
+	if opts.TracksPerObject > len(synthProperties) {
+		opts.TracksPerObject = len(synthProperties)
+	}
+
+	rng := rand.New(rand.NewSource(opts.Seed))
+	doc := NewEmptyDocument("proj_synth", "Synthetic Project", "scene_synth", "root_synth", "timeline_synth")
+	scene := doc.Scenes["scene_synth"]
+	timeline := doc.Timelines["timeline_synth"]
+	timeline.Length = opts.Length
+
+	parentID := scene.Root
+	for i := 0; i < opts.Objects; i++ {
+		if opts.GroupSize > 0 && i%opts.GroupSize == 0 {
+			parentID = fmt.Sprintf("group_%d", i/opts.GroupSize)
+			addSynthChild(doc, scene.Root, ObjectNode{
+				ID:        parentID,
+				Type:      ObjectTypeGroup,
+				Children:  []string{},
+				Transform: Transform{SX: 1, SY: 1},
+				Style:     Style{Opacity: 1},
+				Visible:   true,
+				Data:      json.RawMessage(`{}`),
+			})
+		}
+
+		obj := synthObject(rng, i, scene)
+		addSynthChild(doc, parentID, obj)
+
+		for p := 0; p < opts.TracksPerObject; p++ {
+			track := Track{
+				ID:       fmt.Sprintf("track_%d_%d", i, p),
+				ObjectID: obj.ID,
+				Property: synthProperties[p],
+				Keys:     make([]string, 0, opts.KeyframesPerTrack),
+			}
+			for k := 0; k < opts.KeyframesPerTrack; k++ {
+				kf := Keyframe{
+					ID:     fmt.Sprintf("kf_%d_%d_%d", i, p, k),
+					Frame:  k * opts.Length / opts.KeyframesPerTrack,
+					Value:  synthValue(rng, track.Property, scene),
+					Easing: synthEasings[(i+p+k)%len(synthEasings)],
+				}
+				doc.Keyframes[kf.ID] = kf
+				track.Keys = append(track.Keys, kf.ID)
+			}
+			doc.Tracks[track.ID] = track
+			timeline.Tracks = append(timeline.Tracks, track.ID)
+		}
+	}
+
+	doc.Timelines[timeline.ID] = timeline
+	return doc
+}
+
+func addSynthChild(doc *InDocument, parentID string, obj ObjectNode) {
+	parent := doc.Objects[parentID]
+	parent.Children = append(parent.Children, obj.ID)
+	doc.Objects[parentID] = parent
+
+	obj.Parent = &parentID
+	doc.Objects[obj.ID] = obj
+}
+
+func synthObject(rng *rand.Rand, i int, scene Scene) ObjectNode {
+	obj := ObjectNode{
+		ID:       fmt.Sprintf("obj_%d", i),
+		Children: []string{},
+		Transform: Transform{
+			X:  rng.Float64() * float64(scene.Width),
+			Y:  rng.Float64() * float64(scene.Height),
+			SX: 1,
+			SY: 1,
+			R:  rng.Float64() * 360,
+		},
+		Style: Style{
+			Fill:        synthColor(rng),
+			Stroke:      "#000000",
+			StrokeWidth: 1,
+			Opacity:     1,
+		},
+		Visible: true,
+	}
+
+	switch i % 3 {
+	case 0:
+		obj.Type = ObjectTypeShapeRect
+		obj.Data = json.RawMessage(`{"width":40,"height":30}`)
+	case 1:
+		obj.Type = ObjectTypeShapeEllipse
+		obj.Data = json.RawMessage(`{"rx":20,"ry":15}`)
+	default:
+		obj.Type = ObjectTypeVectorPath
+		obj.Data = json.RawMessage(`{"commands":[["M",0,0],["C",10,-20,30,-20,40,0],["L",20,30],["Z"]]}`)
+	}
+	return obj
+}
+
+func synthValue(rng *rand.Rand, property string, scene Scene) json.RawMessage {
+	var v interface{}
+	switch property {
+	case "transform.x":
+		v = rng.Float64() * float64(scene.Width)
+	case "transform.y":
+		v = rng.Float64() * float64(scene.Height)
+	case "transform.r":
+		v = rng.Float64() * 360
+	case "transform.sx", "transform.sy":
+		v = 0.5 + rng.Float64()
+	case "style.opacity":
+		v = rng.Float64()
+	default:
+		v = synthColor(rng)
+	}
+	raw, _ := json.Marshal(v)
+	return raw
+}
+
+func synthColor(rng *rand.Rand) string {
+	return fmt.Sprintf("#%06x", rng.Intn(0x1000000))
+}
@@ -0,0 +1,190 @@
+package document
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// Validate checks a document's structure before it is stored as a new
+// project: every reference resolves, map keys match their entities' IDs, the
+// object hierarchy is a forest with consistent parent and child links, and
+// scene sizes, safe areas, fps, units and direction are in range. Object
+// types must be known, except in documents from a newer version (see
+// CurrentVersion). It reports the first problem found.
+func (doc *InDocument) Validate() error {
+	if doc.Project.FPS < 1 || doc.Project.FPS > MaxFPS {
+		return fmt.Errorf("fps must be between 1 and %d", MaxFPS)
+	}
+	if !ValidUnits(doc.Project.Units) {
+		return fmt.Errorf("unknown units %q", doc.Project.Units)
+	}
+	if !ValidDirection(doc.Project.Direction) {
+		return fmt.Errorf("unknown direction %q", doc.Project.Direction)
+	}
+	if len(doc.Project.Scenes) == 0 {
+		return fmt.Errorf("document has no scenes")
+	}
+	for _, id := range doc.Project.Scenes {
+		scene, ok := doc.Scenes[id]
+		if !ok {
+			return fmt.Errorf("scene %q not found", id)
+		}
+		if scene.Width < 1 || scene.Width > MaxSceneSize || scene.Height < 1 || scene.Height > MaxSceneSize {
+			return fmt.Errorf("scene %s: size must be between 1 and %d", id, MaxSceneSize)
+		}
+		if scene.SafeAreaInsets != nil {
+			if err := scene.SafeAreaInsets.Validate(scene.Width, scene.Height); err != nil {
+				return fmt.Errorf("scene %s: safe area %w", id, err)
+			}
+		}
+	}
+	return doc.checkStructure(true)
+}
+
+// CheckIntegrity checks what every document the server holds must satisfy
+// however it was edited, before it is saved: map keys match their entities'
+// IDs, scenes, timelines, tracks and children resolve, and linked parents
+// and children agree with no cycles. Unlike Validate it allows what edits
+// leave behind, such as the descendants and tracks of a deleted object, and
+// settings stored before their ranges were enforced. It reports the first
+// problem found.
+func (doc *InDocument) CheckIntegrity() error {
+	return doc.checkStructure(false)
+}
+
+// checkStructure checks a document's references and hierarchy. When strict,
+// every reference must resolve: no object's parent or track's object may be
+// missing, and symbols' timelines, sprite sequences' data and fill gradients
+// must be valid. Objects must be of known types unless the document is from a
+// newer version, whose unknown types UnknownObjects reports instead.
+func (doc *InDocument) checkStructure(strict bool) error {
+	if _, ok := doc.Timelines[doc.Project.RootTimeline]; !ok {
+		return fmt.Errorf("root timeline %q not found", doc.Project.RootTimeline)
+	}
+
+	for _, id := range doc.Project.Scenes {
+		scene, ok := doc.Scenes[id]
+		if !ok {
+			return fmt.Errorf("scene %q not found", id)
+		}
+		root, ok := doc.Objects[scene.Root]
+		if !ok {
+			return fmt.Errorf("scene %s: root object %q not found", id, scene.Root)
+		}
+		if root.Parent != nil {
+			return fmt.Errorf("scene %s: root object %s has a parent", id, scene.Root)
+		}
+	}
+	for id, scene := range doc.Scenes {
+		if scene.ID != id {
+			return fmt.Errorf("scene %s: id does not match its key", id)
+		}
+	}
+
+	for id, obj := range doc.Objects {
+		if obj.ID != id {
+			return fmt.Errorf("object %s: id does not match its key", id)
+		}
+		if obj.Parent != nil {
+			parent, ok := doc.Objects[*obj.Parent]
+			if !ok && strict {
+				return fmt.Errorf("object %s: parent %q not found", id, *obj.Parent)
+			}
+			if ok && !slices.Contains(parent.Children, id) {
+				return fmt.Errorf("object %s: not among its parent's children", id)
+			}
+		}
+		for _, childID := range obj.Children {
+			child, ok := doc.Objects[childID]
+			if !ok {
+				return fmt.Errorf("object %s: child %q not found", id, childID)
+			}
+			if child.Parent == nil || *child.Parent != id {
+				return fmt.Errorf("object %s: child %s has a different parent", id, childID)
+			}
+		}
+		if !strict {
+			continue
+		}
+		if !obj.Type.Known() && !doc.Newer() {
+			return fmt.Errorf("object %s: unknown type %q", id, obj.Type)
+		}
+		if obj.Style.FillGradient != nil {
+			if err := obj.Style.FillGradient.Validate(); err != nil {
+				return fmt.Errorf("object %s: fill gradient: %w", id, err)
+			}
+		}
+		if obj.Type == ObjectTypeSymbol {
+			var data struct {
+				TimelineID string `json:"timelineId"`
+			}
+			json.Unmarshal(obj.Data, &data)
+			if _, ok := doc.Timelines[data.TimelineID]; data.TimelineID != "" && !ok {
+				return fmt.Errorf("symbol %s: timeline %q not found", id, data.TimelineID)
+			}
+		}
+		if obj.Type == ObjectTypeSpriteSequence {
+			var data SpriteSequenceData
+			if err := json.Unmarshal(obj.Data, &data); err != nil {
+				return fmt.Errorf("sprite sequence %s: invalid data: %w", id, err)
+			}
+			if err := data.Validate(); err != nil {
+				return fmt.Errorf("sprite sequence %s: %w", id, err)
+			}
+		}
+	}
+	// With consistent links, a cycle is the only way an object can fail to
+	// reach a root or a deleted parent
+	for id := range doc.Objects {
+		current := id
+		for steps := 0; ; steps++ {
+			if steps > len(doc.Objects) {
+				return fmt.Errorf("object %s: parent cycle", id)
+			}
+			parent := doc.Objects[current].Parent
+			if parent == nil {
+				break
+			}
+			if _, ok := doc.Objects[*parent]; !ok {
+				break
+			}
+			current = *parent
+		}
+	}
+
+	for id, tl := range doc.Timelines {
+		if tl.ID != id {
+			return fmt.Errorf("timeline %s: id does not match its key", id)
+		}
+		for _, trackID := range tl.Tracks {
+			if _, ok := doc.Tracks[trackID]; !ok {
+				return fmt.Errorf("timeline %s: track %q not found", id, trackID)
+			}
+		}
+	}
+	for id, track := range doc.Tracks {
+		if track.ID != id {
+			return fmt.Errorf("track %s: id does not match its key", id)
+		}
+		if _, ok := doc.Objects[track.ObjectID]; !ok && strict {
+			return fmt.Errorf("track %s: object %q not found", id, track.ObjectID)
+		}
+		for _, keyID := range track.Keys {
+			if _, ok := doc.Keyframes[keyID]; !ok {
+				return fmt.Errorf("track %s: keyframe %q not found", id, keyID)
+			}
+		}
+	}
+	for id, kf := range doc.Keyframes {
+		if kf.ID != id {
+			return fmt.Errorf("keyframe %s: id does not match its key", id)
+		}
+	}
+	for id, asset := range doc.Assets {
+		if asset.ID != id {
+			return fmt.Errorf("asset %s: id does not match its key", id)
+		}
+	}
+	return nil
+}
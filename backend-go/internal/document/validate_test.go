@@ -0,0 +1,86 @@
+package document
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckIntegrity(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(doc *InDocument, rootID string)
+		want    string // Expected error substring; empty if the document passes
+		strict  bool   // Whether Validate still rejects a document that passes
+	}{
+		{"untouched", func(doc *InDocument, rootID string) {}, "", false},
+		{"dangling child", func(doc *InDocument, rootID string) {
+			root := doc.Objects[rootID]
+			root.Children = append(root.Children, "obj_missing")
+			doc.Objects[rootID] = root
+		}, `child "obj_missing" not found`, false},
+		{"missing root timeline", func(doc *InDocument, rootID string) {
+			delete(doc.Timelines, doc.Project.RootTimeline)
+		}, "root timeline", false},
+		{"parent cycle", func(doc *InDocument, rootID string) {
+			a, b := "obj_a", "obj_b"
+			doc.Objects[a] = ObjectNode{ID: a, Type: ObjectTypeGroup, Parent: &b, Children: []string{b}}
+			doc.Objects[b] = ObjectNode{ID: b, Type: ObjectTypeGroup, Parent: &a, Children: []string{a}}
+		}, "cycle", false},
+		// What deleting an object leaves behind is allowed, though a
+		// document arriving that way is not
+		{"track of a deleted object", func(doc *InDocument, rootID string) {
+			doc.Tracks["trk_orphan"] = Track{ID: "trk_orphan", ObjectID: "obj_deleted", Property: "transform.x", Keys: []string{}}
+		}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := NewEmptyDocument("proj_test", "Test", "scene_test", "obj_root", "tl_root")
+			tt.corrupt(doc, "obj_root")
+
+			err := doc.CheckIntegrity()
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("CheckIntegrity: %v, want no error", err)
+				}
+				if verr := doc.Validate(); (verr != nil) != tt.strict {
+					t.Errorf("Validate: %v, want an error: %v", verr, tt.strict)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("CheckIntegrity: %v, want an error mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnknownObjectTypes(t *testing.T) {
+	for _, tt := range []struct {
+		version int
+		valid   bool
+	}{
+		{CurrentVersion, false},
+		{CurrentVersion + 1, true},
+	} {
+		doc := NewEmptyDocument("proj_test", "Test", "scene_test", "obj_root", "tl_root")
+		doc.Project.Version = tt.version
+		root := doc.Objects["obj_root"]
+		root.Children = append(root.Children, "obj_camera")
+		doc.Objects["obj_root"] = root
+		parent := "obj_root"
+		doc.Objects["obj_camera"] = ObjectNode{ID: "obj_camera", Type: "Camera", Parent: &parent, Children: []string{}}
+
+		if err := doc.Validate(); (err == nil) != tt.valid {
+			t.Errorf("version %d: Validate = %v, want valid: %v", tt.version, err, tt.valid)
+		}
+		if err := doc.CheckIntegrity(); err != nil {
+			t.Errorf("version %d: CheckIntegrity = %v, want the unknown type kept", tt.version, err)
+		}
+		if got := doc.UnknownObjects(); len(got) != 1 || got[0] != (UnknownObject{ID: "obj_camera", Type: "Camera"}) {
+			t.Errorf("version %d: unknown objects %+v, want the camera", tt.version, got)
+		}
+		if doc.Newer() != tt.valid {
+			t.Errorf("version %d: Newer = %v", tt.version, doc.Newer())
+		}
+	}
+}
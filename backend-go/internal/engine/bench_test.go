@@ -0,0 +1,138 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+)
+
+// defaultFrameBudget is how long TestFrameBudget lets a frame of the
+// 1k-object fixture take. The benchmarks below measure each stage of the
+// render pipeline against synthetic documents:
+//
+//	go test -run '^$' -bench . ./internal/engine              # every stage and size
+//	go test -run '^$' -bench 'Frame/1000' ./internal/engine   # one of them
+//	go test -run TestFrameBudget -v ./internal/engine         # check the budget
+//
+// The budget applies to a full frame (BuildSceneGraph + CompileDrawCommands),
+// and can be overridden with ENGINE_FRAME_BUDGET
+// (e.g. ENGINE_FRAME_BUDGET=8ms; 0 skips the check). The default is
+// deliberately loose so it only fails on slow CI machines when something got
+// much slower; after an intended performance change, rerun the benchmarks
+// and update defaultFrameBudget.
+const defaultFrameBudget = 50 * time.Millisecond
+
+// benchSizes are the object counts each stage is benchmarked at.
+var benchSizes = []int{100, 1000, 10000}
+
+// benchTracks are the animated tracks per object each stage is benchmarked
+// with.
+var benchTracks = []int{0, 3}
+
+func benchFixture(objects, tracks int) *document.InDocument {
+	return document.NewSynthDocument(document.SynthOptions{
+		Objects:           objects,
+		GroupSize:         10,
+		TracksPerObject:   tracks,
+		KeyframesPerTrack: 4,
+		Seed:              1,
+	})
+}
+
+// runSizes runs bench as a sub-benchmark for each fixture size and track
+// count.
+func runSizes(b *testing.B, bench func(b *testing.B, doc *document.InDocument)) {
+	for _, objects := range benchSizes {
+		for _, tracks := range benchTracks {
+			doc := benchFixture(objects, tracks)
+			b.Run(fmt.Sprintf("%d/tracks=%d", objects, tracks), func(b *testing.B) {
+				b.ReportAllocs()
+				bench(b, doc)
+			})
+		}
+	}
+}
+
+func BenchmarkEvaluateTimeline(b *testing.B) {
+	runSizes(b, func(b *testing.B, doc *document.InDocument) {
+		timelineID := doc.Project.RootTimeline
+		length := doc.Timelines[timelineID].Length
+		for i := 0; i < b.N; i++ {
+			EvaluateTimeline(doc, timelineID, float64(i%length))
+		}
+	})
+}
+
+func BenchmarkBuildSceneGraph(b *testing.B) {
+	runSizes(b, func(b *testing.B, doc *document.InDocument) {
+		sceneID := doc.Project.Scenes[0]
+		timelineID := doc.Project.RootTimeline
+		length := doc.Timelines[timelineID].Length
+		for i := 0; i < b.N; i++ {
+			BuildSceneGraph(doc, sceneID, float64(i%length), timelineID, false, nil)
+		}
+	})
+}
+
+func BenchmarkCompileDrawCommands(b *testing.B) {
+	runSizes(b, func(b *testing.B, doc *document.InDocument) {
+		sg := BuildSceneGraph(doc, doc.Project.Scenes[0], 0, doc.Project.RootTimeline, false, nil)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			CompileDrawCommands(sg)
+		}
+	})
+}
+
+func BenchmarkBuildSpatialIndex(b *testing.B) {
+	runSizes(b, func(b *testing.B, doc *document.InDocument) {
+		sg := BuildSceneGraph(doc, doc.Project.Scenes[0], 0, doc.Project.RootTimeline, false, nil)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			BuildSpatialIndex(sg)
+		}
+	})
+}
+
+// BenchmarkFrame measures what the editor does per playback tick.
+func BenchmarkFrame(b *testing.B) {
+	runSizes(b, benchFrame)
+}
+
+func benchFrame(b *testing.B, doc *document.InDocument) {
+	sceneID := doc.Project.Scenes[0]
+	timelineID := doc.Project.RootTimeline
+	length := doc.Timelines[timelineID].Length
+	for i := 0; i < b.N; i++ {
+		sg := BuildSceneGraph(doc, sceneID, float64(i%length), timelineID, true, nil)
+		CompileDrawCommands(sg)
+	}
+}
+
+func TestFrameBudget(t *testing.T) {
+	if testing.Short() {
+		t.Skip("benchmarks the 1k-object fixture")
+	}
+	budget := defaultFrameBudget
+	if v := os.Getenv("ENGINE_FRAME_BUDGET"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			t.Fatalf("invalid ENGINE_FRAME_BUDGET %q: %v", v, err)
+		}
+		budget = d
+	}
+	if budget <= 0 {
+		t.Skip("ENGINE_FRAME_BUDGET disables the check")
+	}
+
+	doc := benchFixture(1000, 3)
+	r := testing.Benchmark(func(b *testing.B) { benchFrame(b, doc) })
+	perFrame := time.Duration(r.NsPerOp())
+	t.Logf("1000 objects: %s/frame, %d allocs/frame (budget %s)", perFrame, r.AllocsPerOp(), budget)
+	if perFrame > budget {
+		t.Errorf("frame time %s exceeds budget %s", perFrame, budget)
+	}
+}
@@ -3,14 +3,18 @@ package engine
 import (
 	"encoding/json"
 	"math"
+	"unicode/utf8"
 
+	"github.com/inamate/inamate/backend-go/internal/color"
 	"github.com/inamate/inamate/backend-go/internal/document"
 )
 
-// BuildSceneGraph builds a render-ready scene graph from the document at the given frame.
-// Keyframe overrides are always evaluated. If dragOverlay is non-nil, the specified objects
+// BuildSceneGraph builds a render-ready scene graph from the document at the given
+// (possibly fractional) frame.
+// Root timeline keyframe overrides are always evaluated; Symbol timelines only
+// when animateSymbols is set (playing, or previewing while paused). If dragOverlay is non-nil, the specified objects
 // use the overlay transforms instead of document/keyframe values (for drag preview).
-func BuildSceneGraph(doc *document.InDocument, sceneID string, frame int, rootTimelineID string, playing bool, dragOverlay *DragOverlay) *SceneGraph {
+func BuildSceneGraph(doc *document.InDocument, sceneID string, frame float64, rootTimelineID string, animateSymbols bool, dragOverlay *DragOverlay) *SceneGraph {
 	sg := NewSceneGraph()
 
 	scene, ok := doc.Scenes[sceneID]
@@ -27,7 +31,7 @@ func BuildSceneGraph(doc *document.InDocument, sceneID string, frame int, rootTi
 	evalResult := EvaluateTimeline(doc, rootTimelineID, frame)
 
 	// Build the tree starting from root
-	sg.Root = buildNode(doc, &rootObj, nil, Identity(), 1.0, evalResult, frame, sg, playing, dragOverlay)
+	sg.Root = buildNode(doc, &rootObj, nil, Identity(), 1.0, evalResult, frame, sg, animateSymbols, dragOverlay)
 	sg.Dirty = false
 
 	return sg
@@ -41,46 +45,25 @@ func buildNode(
 	parentWorldTransform Matrix2D,
 	parentOpacity float64,
 	eval EvalResult,
-	frame int,
+	frame float64,
 	sg *SceneGraph,
-	playing bool,
+	animateSymbols bool,
 	dragOverlay *DragOverlay,
 ) *SceneNode {
-	if !obj.Visible {
+	visible := obj.Visible
+	if boolOverrides, ok := eval.Bools[obj.ID]; ok {
+		if v, ok := boolOverrides["visible"]; ok {
+			visible = v
+		}
+	}
+	if !visible {
 		return nil
 	}
 
 	// For Symbols, evaluate their nested timeline FIRST so overrides apply to the Symbol itself
-	// Only evaluate when playing
-	if playing && obj.Type == document.ObjectTypeSymbol {
-		symData := ParseSymbolData(obj.Data)
-		if symData.TimelineID != "" {
-			// Apply loop: wrap frame around timeline length
-			symFrame := frame
-			if symData.Loop {
-				if tl, ok := doc.Timelines[symData.TimelineID]; ok && tl.Length > 0 {
-					symFrame = frame % tl.Length
-				}
-			}
-			// Evaluate the symbol's timeline and merge overrides
-			symbolEval := EvaluateTimeline(doc, symData.TimelineID, symFrame)
-			for objID, props := range symbolEval.Numeric {
-				if eval.Numeric[objID] == nil {
-					eval.Numeric[objID] = make(PropertyOverrides)
-				}
-				for k, v := range props {
-					eval.Numeric[objID][k] = v
-				}
-			}
-			for objID, props := range symbolEval.Strings {
-				if eval.Strings[objID] == nil {
-					eval.Strings[objID] = make(StringPropertyOverrides)
-				}
-				for k, v := range props {
-					eval.Strings[objID][k] = v
-				}
-			}
-		}
+	// Only evaluate when playing or previewing animation
+	if animateSymbols && obj.Type == document.ObjectTypeSymbol {
+		mergeSymbolTimeline(doc, obj, frame, eval)
 	}
 
 	// Apply property overrides if any
@@ -115,6 +98,8 @@ func buildNode(
 	opacity := parentOpacity * style.Opacity
 
 	// Create the scene node
+	fill, fillAlpha := splitPaintAlpha(style.Fill)
+	stroke, strokeAlpha := splitPaintAlpha(style.Stroke)
 	node := &SceneNode{
 		ID:             obj.ID,
 		Type:           mapObjectType(obj.Type),
@@ -123,8 +108,11 @@ func buildNode(
 		Opacity:        opacity,
 		Visible:        true,
 		Parent:         parent,
-		Fill:           style.Fill,
-		Stroke:         style.Stroke,
+		Fill:           fill,
+		FillAlpha:      fillAlpha,
+		FillGradient:   style.FillGradient,
+		Stroke:         stroke,
+		StrokeAlpha:    strokeAlpha,
 		StrokeWidth:    style.StrokeWidth,
 	}
 
@@ -176,14 +164,37 @@ func buildNode(
 			node.Bounds = Rect{X: bMinX, Y: bMinY, Width: bMaxX - bMinX, Height: bMaxY - bMinY}
 		}
 
+	case document.ObjectTypeSpriteSequence:
+		node.Type = "image"
+		var seqData document.SpriteSequenceData
+		if err := json.Unmarshal(obj.Data, &seqData); err == nil {
+			// Show the sequence frame the timeline frame maps to
+			if i := seqData.FrameAt(frame); i >= 0 {
+				if len(seqData.AssetIDs) > 0 {
+					node.ImageAssetID = seqData.AssetIDs[i]
+				} else {
+					x, y := seqData.Cell(i)
+					node.ImageAssetID = seqData.SheetAssetID
+					node.ImageSource = &Rect{X: x, Y: y, Width: seqData.Width, Height: seqData.Height}
+				}
+			}
+			node.ImageWidth = seqData.Width
+			node.ImageHeight = seqData.Height
+			node.Bounds = worldMatrix.TransformRect(Rect{Width: seqData.Width, Height: seqData.Height})
+		}
+
 	case document.ObjectTypeText:
 		node.Type = "text"
 		var textData struct {
-			Content    string  `json:"content"`
-			FontSize   float64 `json:"fontSize"`
-			FontFamily string  `json:"fontFamily"`
-			FontWeight string  `json:"fontWeight"`
-			TextAlign  string  `json:"textAlign"`
+			Content           string   `json:"content"`
+			FontSize          float64  `json:"fontSize"`
+			FontFamily        string   `json:"fontFamily"`
+			FontWeight        string   `json:"fontWeight"`
+			TextAlign         string   `json:"textAlign"`
+			LetterSpacing     float64  `json:"letterSpacing"`
+			VisibleCharacters *float64 `json:"visibleCharacters"`
+			PathID            string   `json:"pathId"`
+			PathOffset        float64  `json:"pathOffset"`
 		}
 		if err := json.Unmarshal(obj.Data, &textData); err == nil {
 			// Apply data.* keyframe overrides
@@ -191,6 +202,15 @@ func buildNode(
 				if v, ok := numOv["data.fontSize"]; ok {
 					textData.FontSize = v
 				}
+				if v, ok := numOv["data.letterSpacing"]; ok {
+					textData.LetterSpacing = v
+				}
+				if v, ok := numOv["data.visibleCharacters"]; ok {
+					textData.VisibleCharacters = &v
+				}
+				if v, ok := numOv["data.pathOffset"]; ok {
+					textData.PathOffset = v
+				}
 			}
 			if strOv, ok := eval.Strings[obj.ID]; ok {
 				if v, ok := strOv["data.content"]; ok {
@@ -207,39 +227,69 @@ func buildNode(
 				}
 			}
 
-			node.TextContent = textData.Content
+			node.TextContent = truncateText(textData.Content, textData.VisibleCharacters)
 			node.TextFontSize = textData.FontSize
 			node.TextFontFamily = textData.FontFamily
 			node.TextFontWeight = textData.FontWeight
 			node.TextAlign = textData.TextAlign
-
-			// Heuristic bounds (frontend uses measureText for accurate bounds)
-			estWidth := textData.FontSize * 0.6 * float64(len(textData.Content))
-			estHeight := textData.FontSize * 1.2
-			corners := [][2]float64{
-				{0, 0},
-				{estWidth, 0},
-				{estWidth, estHeight},
-				{0, estHeight},
-			}
-			var bMinX, bMinY, bMaxX, bMaxY float64
-			for i, c := range corners {
-				wx, wy := worldMatrix.TransformPoint(c[0], c[1])
-				if i == 0 {
-					bMinX, bMaxX = wx, wx
-					bMinY, bMaxY = wy, wy
-				} else {
-					bMinX = math.Min(bMinX, wx)
-					bMaxX = math.Max(bMaxX, wx)
-					bMinY = math.Min(bMinY, wy)
-					bMaxY = math.Max(bMaxY, wy)
+			node.TextLetterSpacing = textData.LetterSpacing
+			node.TextPathOffset = textData.PathOffset
+
+			if textData.PathID != "" {
+				if pathObj, ok := doc.Objects[textData.PathID]; ok && pathObj.Type == document.ObjectTypeVectorPath {
+					// Express the path in the text's local space so the renderer can
+					// lay glyphs out under the text's own transform
+					toLocal := worldMatrix.Invert().Multiply(objectWorldMatrix(doc, textData.PathID, eval))
+					node.TextPath = transformPath(extractVectorPath(pathObj.Data), toLocal)
 				}
 			}
-			node.Bounds = Rect{X: bMinX, Y: bMinY, Width: bMaxX - bMinX, Height: bMaxY - bMinY}
+
+			if len(node.TextPath) > 0 {
+				// Glyphs sit on the baseline and may be rotated to follow it, so pad
+				// the path's bounds by a line height on every side
+				pad := textData.FontSize * 1.2
+				b := computePathBounds(node.TextPath, Identity())
+				node.Bounds = worldMatrix.TransformRect(Rect{
+					X:      b.X - pad,
+					Y:      b.Y - pad,
+					Width:  b.Width + 2*pad,
+					Height: b.Height + 2*pad,
+				})
+			} else {
+				layout := LayoutText(TextLayoutInput{
+					Content:       node.TextContent,
+					FontSize:      textData.FontSize,
+					FontWeight:    textData.FontWeight,
+					LetterSpacing: textData.LetterSpacing,
+				})
+				node.Bounds = worldMatrix.TransformRect(Rect{
+					X:      alignOffset(textData.TextAlign, layout.Width),
+					Width:  layout.Width,
+					Height: layout.Height,
+				})
+			}
 		}
 
 	case document.ObjectTypeSymbol:
 		// Symbol timeline already evaluated above before applying overrides
+
+	default:
+		if !obj.Type.Known() {
+			// A type from a newer client: draw a labeled box where it sits so
+			// users can see, select and delete it
+			w, h := placeholderSize(obj.Data)
+			node.Path = []PathCommand{{"M", 0.0, 0.0}, {"L", w, 0.0}, {"L", w, h}, {"L", 0.0, h}, {"Z"}}
+			node.Fill, node.FillAlpha, node.FillGradient = placeholderColor, placeholderFillAlpha, nil
+			node.Stroke, node.StrokeAlpha, node.StrokeWidth = placeholderColor, 1, 1
+			node.Placeholder = string(obj.Type)
+			node.Bounds = computePathBounds(node.Path, worldMatrix)
+		}
+	}
+
+	// Thick strokes paint outside the geometry; without this a stroked
+	// straight line has zero-area bounds and can't be selected
+	if len(node.Path) > 0 && node.hasStroke() {
+		node.Bounds = node.Bounds.Expand(strokeExtent(worldMatrix, node.StrokeWidth))
 	}
 
 	// Register node in the lookup map
@@ -252,7 +302,7 @@ func buildNode(
 			continue
 		}
 
-		childNode := buildNode(doc, &childObj, node, worldMatrix, opacity, eval, frame, sg, playing, dragOverlay)
+		childNode := buildNode(doc, &childObj, node, worldMatrix, opacity, eval, frame, sg, animateSymbols, dragOverlay)
 		if childNode != nil {
 			node.Children = append(node.Children, childNode)
 
@@ -266,6 +316,21 @@ func buildNode(
 	return node
 }
 
+// alignOffset returns where a line of the given width starts relative to the
+// text's origin, which the renderer aligns it to like canvas textAlign: the
+// left edge by default, the middle for "center", the right edge for "right"
+// or "end".
+func alignOffset(align string, width float64) float64 {
+	switch align {
+	case "center":
+		return -width / 2
+	case "right", "end":
+		return -width
+	default:
+		return 0
+	}
+}
+
 // mapObjectType converts document ObjectType to scene graph type string.
 func mapObjectType(objType document.ObjectType) string {
 	switch objType {
@@ -275,13 +340,148 @@ func mapObjectType(objType document.ObjectType) string {
 		return "shape"
 	case document.ObjectTypeSymbol:
 		return "symbol"
-	case document.ObjectTypeRasterImage:
+	case document.ObjectTypeRasterImage, document.ObjectTypeSpriteSequence:
 		return "image"
 	case document.ObjectTypeText:
 		return "text"
 	default:
-		return "unknown"
+		return "placeholder"
+	}
+}
+
+// How objects of unknown types are drawn
+const (
+	placeholderColor     = "#888888"
+	placeholderFillAlpha = 0.1
+	defaultPlaceholder   = 100 // Width and height when the data has none
+)
+
+// placeholderSize returns the size of an unknown object's placeholder: the
+// width and height in its data if it has them, as most types do.
+func placeholderSize(data json.RawMessage) (float64, float64) {
+	var size struct {
+		Width  float64 `json:"width"`
+		Height float64 `json:"height"`
+	}
+	json.Unmarshal(data, &size)
+	if size.Width <= 0 || size.Height <= 0 {
+		return defaultPlaceholder, defaultPlaceholder
+	}
+	return size.Width, size.Height
+}
+
+// splitPaintAlpha separates a paint color's alpha from its RGB so the
+// renderer can apply it independently of opacity. Values that are not colors
+// (e.g. "none") are returned unchanged with alpha 1.
+func splitPaintAlpha(paint string) (string, float64) {
+	if paint == "" || paint == color.None {
+		return paint, 1
+	}
+	c, err := color.Parse(paint)
+	if err != nil {
+		return paint, 1
+	}
+	return c.RGBHex(), c.Alpha()
+}
+
+// truncateText returns the first visible characters of content, counted in
+// runes. A nil count shows the whole string.
+func truncateText(content string, visible *float64) string {
+	if visible == nil {
+		return content
+	}
+	n := int(math.Floor(*visible))
+	if n <= 0 {
+		return ""
+	}
+	if n >= utf8.RuneCountInString(content) {
+		return content
+	}
+	return string([]rune(content)[:n])
+}
+
+// mergeSymbolTimeline evaluates a Symbol's nested timeline at frame, wrapped
+// to its length if the symbol loops, and merges the overrides into eval.
+func mergeSymbolTimeline(doc *document.InDocument, symbol *document.ObjectNode, frame float64, eval EvalResult) {
+	symData := ParseSymbolData(symbol.Data)
+	if symData.TimelineID == "" {
+		return
+	}
+	// Apply loop: wrap frame around timeline length
+	symFrame := frame
+	if symData.Loop {
+		if tl, ok := doc.Timelines[symData.TimelineID]; ok && tl.Length > 0 {
+			symFrame = math.Mod(frame, float64(tl.Length))
+		}
+	}
+	// Evaluate the symbol's timeline and merge overrides
+	symbolEval := EvaluateTimeline(doc, symData.TimelineID, symFrame)
+	for objID, props := range symbolEval.Numeric {
+		if eval.Numeric[objID] == nil {
+			eval.Numeric[objID] = make(PropertyOverrides)
+		}
+		for k, v := range props {
+			eval.Numeric[objID][k] = v
+		}
+	}
+	for objID, props := range symbolEval.Strings {
+		if eval.Strings[objID] == nil {
+			eval.Strings[objID] = make(StringPropertyOverrides)
+		}
+		for k, v := range props {
+			eval.Strings[objID][k] = v
+		}
+	}
+	for objID, props := range symbolEval.Bools {
+		if eval.Bools[objID] == nil {
+			eval.Bools[objID] = make(BoolPropertyOverrides)
+		}
+		for k, v := range props {
+			eval.Bools[objID][k] = v
+		}
+	}
+}
+
+// objectWorldMatrix computes an object's world transform by walking its parent
+// chain, applying keyframe overrides at each level. Used when one object
+// references another that may not have been built yet (e.g. text on a path).
+func objectWorldMatrix(doc *document.InDocument, objectID string, eval EvalResult) Matrix2D {
+	m := Identity()
+	id := objectID
+	for depth := 0; depth <= len(doc.Objects); depth++ {
+		obj, ok := doc.Objects[id]
+		if !ok {
+			break
+		}
+		t := obj.Transform
+		if numOverrides, ok := eval.Numeric[id]; ok {
+			t = ApplyOverridesToTransform(t, numOverrides)
+		}
+		m = FromTransform(t.X, t.Y, t.SX, t.SY, t.R, t.AX, t.AY, t.SkewX, t.SkewY).Multiply(m)
+		if obj.Parent == nil {
+			break
+		}
+		id = *obj.Parent
+	}
+	return m
+}
+
+// transformPath maps every point of a path through m.
+func transformPath(path []PathCommand, m Matrix2D) []PathCommand {
+	result := make([]PathCommand, 0, len(path))
+	for _, cmd := range path {
+		if len(cmd) == 0 {
+			continue
+		}
+		out := make(PathCommand, len(cmd))
+		out[0] = cmd[0]
+		for i := 1; i+1 < len(cmd); i += 2 {
+			x, y := m.TransformPoint(toFloat64(cmd[i]), toFloat64(cmd[i+1]))
+			out[i], out[i+1] = x, y
+		}
+		result = append(result, out)
 	}
+	return result
 }
 
 // generateRectPath generates path commands for a rectangle.
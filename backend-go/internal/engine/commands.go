@@ -2,22 +2,30 @@ package engine
 
 import (
 	"encoding/json"
+	"math"
+
+	"github.com/inamate/inamate/backend-go/internal/color"
+	"github.com/inamate/inamate/backend-go/internal/document"
 )
 
 // DrawCommand represents a single drawing operation for the frontend to execute.
 // The frontend receives a list of these and executes them on a Canvas2D context.
 type DrawCommand struct {
-	Op           string        `json:"op"`                     // Operation: "path", "image", "save", "restore", "clip"
-	ObjectID     string        `json:"objectId,omitempty"`     // For hit correlation
-	Transform    []float64     `json:"transform,omitempty"`    // [a, b, c, d, e, f] affine matrix
-	Path         []PathCommand `json:"path,omitempty"`         // Path data for "path" ops
-	Fill         string        `json:"fill,omitempty"`         // Fill color
-	Stroke       string        `json:"stroke,omitempty"`       // Stroke color
-	StrokeWidth  float64       `json:"strokeWidth,omitempty"`  // Stroke width
-	Opacity      float64       `json:"opacity,omitempty"`      // Global alpha
-	ImageAssetID string        `json:"imageAssetId,omitempty"` // Asset ID for image lookup
-	ImageWidth   float64       `json:"imageWidth,omitempty"`   // Image natural width
-	ImageHeight  float64       `json:"imageHeight,omitempty"`  // Image natural height
+	Op           string         `json:"op"`                     // Operation: "path", "image", "save", "restore", "clip"
+	ObjectID     string         `json:"objectId,omitempty"`     // For hit correlation
+	Transform    []float64      `json:"transform,omitempty"`    // [a, b, c, d, e, f] affine matrix
+	Path         []PathCommand  `json:"path,omitempty"`         // Path data for "path" ops
+	Fill         string         `json:"fill,omitempty"`         // Fill color (RGB)
+	FillAlpha    *float64       `json:"fillAlpha,omitempty"`    // Fill color alpha, if not opaque; applied on top of Opacity
+	FillGradient *GradientPaint `json:"fillGradient,omitempty"` // Gradient to fill with in place of Fill, which stays as the fallback
+	Stroke       string         `json:"stroke,omitempty"`       // Stroke color (RGB)
+	StrokeAlpha  *float64       `json:"strokeAlpha,omitempty"`  // Stroke color alpha, if not opaque
+	StrokeWidth  float64        `json:"strokeWidth,omitempty"`  // Stroke width
+	Opacity      float64        `json:"opacity,omitempty"`      // Global alpha
+	ImageAssetID string         `json:"imageAssetId,omitempty"` // Asset ID for image lookup
+	ImageWidth   float64        `json:"imageWidth,omitempty"`   // Image natural width
+	ImageHeight  float64        `json:"imageHeight,omitempty"`  // Image natural height
+	ImageSource  []float64      `json:"imageSource,omitempty"`  // [x, y, width, height] of the image to draw, if not all of it
 
 	// Text rendering
 	TextContent    string  `json:"textContent,omitempty"`
@@ -25,6 +33,11 @@ type DrawCommand struct {
 	TextFontFamily string  `json:"textFontFamily,omitempty"`
 	TextFontWeight string  `json:"textFontWeight,omitempty"`
 	TextAlign      string  `json:"textAlign,omitempty"`
+
+	// Text layout
+	TextLetterSpacing float64       `json:"textLetterSpacing,omitempty"` // Extra advance between characters
+	TextPath          []PathCommand `json:"textPath,omitempty"`          // Baseline path in local space (text-on-path)
+	TextPathOffset    float64       `json:"textPathOffset,omitempty"`    // Start distance along TextPath
 }
 
 // CompileDrawCommands generates a draw command buffer from a scene graph.
@@ -35,15 +48,33 @@ func CompileDrawCommands(sg *SceneGraph) []DrawCommand {
 	}
 
 	var commands []DrawCommand
-	compileNode(sg.Root, &commands)
+	compileNode(sg.Root, nil, &commands, nil)
 	return commands
 }
 
+// CompileDrawCommandsInView is CompileDrawCommands without the draw commands
+// of nodes whose world bounds lie entirely outside view. Their children are
+// still visited, and clips are kept so saves and restores stay balanced. It
+// also returns how many commands were culled.
+func CompileDrawCommandsInView(sg *SceneGraph, view Rect) ([]DrawCommand, int) {
+	if sg == nil || sg.Root == nil {
+		return nil, 0
+	}
+
+	var commands []DrawCommand
+	culled := 0
+	compileNode(sg.Root, &view, &commands, &culled)
+	return commands, culled
+}
+
 // compileNode recursively generates draw commands for a node and its children.
-func compileNode(node *SceneNode, commands *[]DrawCommand) {
+// With a view, nodes known to be outside it are counted in culled instead of
+// drawn.
+func compileNode(node *SceneNode, view *Rect, commands *[]DrawCommand, culled *int) {
 	if node == nil || !node.Visible {
 		return
 	}
+	offscreen := view != nil && !node.Bounds.IsEmpty() && !node.Bounds.Intersects(*view)
 
 	// Handle clipping/masking
 	hasClip := node.ClipPath != nil
@@ -60,20 +91,30 @@ func compileNode(node *SceneNode, commands *[]DrawCommand) {
 	}
 
 	// If this node has renderable content, emit a draw command
-	if node.Type == "text" && node.TextContent != "" {
+	if offscreen && (node.Type == "text" && node.TextContent != "" ||
+		node.Type == "image" && node.ImageAssetID != "" || len(node.Path) > 0) {
+		*culled++
+	} else if node.Type == "text" && node.TextContent != "" {
 		cmd := DrawCommand{
 			Op:             "text",
 			ObjectID:       node.ID,
 			Transform:      node.WorldTransform.ToSlice(),
 			Opacity:        node.Opacity,
 			Fill:           node.Fill,
+			FillAlpha:      paintAlpha(node.FillAlpha),
+			FillGradient:   gradientPaint(node.FillGradient),
 			Stroke:         node.Stroke,
+			StrokeAlpha:    paintAlpha(node.StrokeAlpha),
 			StrokeWidth:    node.StrokeWidth,
 			TextContent:    node.TextContent,
 			TextFontSize:   node.TextFontSize,
 			TextFontFamily: node.TextFontFamily,
 			TextFontWeight: node.TextFontWeight,
 			TextAlign:      node.TextAlign,
+
+			TextLetterSpacing: node.TextLetterSpacing,
+			TextPath:          node.TextPath,
+			TextPathOffset:    node.TextPathOffset,
 		}
 		*commands = append(*commands, cmd)
 	} else if node.Type == "image" && node.ImageAssetID != "" {
@@ -86,24 +127,33 @@ func compileNode(node *SceneNode, commands *[]DrawCommand) {
 			ImageWidth:   node.ImageWidth,
 			ImageHeight:  node.ImageHeight,
 		}
+		if src := node.ImageSource; src != nil {
+			cmd.ImageSource = []float64{src.X, src.Y, src.Width, src.Height}
+		}
 		*commands = append(*commands, cmd)
 	} else if len(node.Path) > 0 {
 		cmd := DrawCommand{
-			Op:          "path",
-			ObjectID:    node.ID,
-			Transform:   node.WorldTransform.ToSlice(),
-			Path:        node.Path,
-			Opacity:     node.Opacity,
-			Fill:        node.Fill,
-			Stroke:      node.Stroke,
-			StrokeWidth: node.StrokeWidth,
+			Op:           "path",
+			ObjectID:     node.ID,
+			Transform:    node.WorldTransform.ToSlice(),
+			Path:         node.Path,
+			Opacity:      node.Opacity,
+			Fill:         node.Fill,
+			FillAlpha:    paintAlpha(node.FillAlpha),
+			FillGradient: gradientPaint(node.FillGradient),
+			Stroke:       node.Stroke,
+			StrokeAlpha:  paintAlpha(node.StrokeAlpha),
+			StrokeWidth:  node.StrokeWidth,
 		}
 		*commands = append(*commands, cmd)
+		if node.Placeholder != "" {
+			*commands = append(*commands, placeholderLabel(node))
+		}
 	}
 
 	// Recurse into children
 	for _, child := range node.Children {
-		compileNode(child, commands)
+		compileNode(child, view, commands, culled)
 	}
 
 	// Restore state if we saved it for clipping
@@ -112,6 +162,86 @@ func compileNode(node *SceneNode, commands *[]DrawCommand) {
 	}
 }
 
+// placeholderLabelSize and placeholderLabelInset place the type name on an
+// unknown object's placeholder box.
+const (
+	placeholderLabelSize  = 12
+	placeholderLabelInset = 4
+)
+
+// placeholderLabel returns the command drawing an unknown object's type name
+// in the top-left corner of its placeholder.
+func placeholderLabel(node *SceneNode) DrawCommand {
+	return DrawCommand{
+		Op:           "text",
+		ObjectID:     node.ID,
+		Transform:    node.WorldTransform.Multiply(Translate(placeholderLabelInset, placeholderLabelInset)).ToSlice(),
+		Opacity:      node.Opacity,
+		Fill:         placeholderColor,
+		TextContent:  node.Placeholder,
+		TextFontSize: placeholderLabelSize,
+	}
+}
+
+// paintAlpha returns alpha for a DrawCommand, or nil when the paint is opaque
+// so the common case adds nothing to the JSON.
+func paintAlpha(alpha float64) *float64 {
+	if alpha >= 1 {
+		return nil
+	}
+	return &alpha
+}
+
+// GradientPaint describes a gradient for the frontend to build with
+// createLinearGradient or createRadialGradient, in the command's local space.
+type GradientPaint struct {
+	Type   string                  `json:"type"`   // "linear" or "radial"
+	Coords []float64               `json:"coords"` // Linear: [x1, y1, x2, y2]; radial: [cx, cy, r]
+	Stops  []document.GradientStop `json:"stops"`  // Colors as "#rrggbbaa"
+}
+
+// gradientPaint returns the paint for a gradient, or nil when there is none.
+func gradientPaint(g *document.Gradient) *GradientPaint {
+	if g == nil {
+		return nil
+	}
+	paint := &GradientPaint{Type: string(g.Type), Stops: make([]document.GradientStop, len(g.Stops))}
+	if g.Type == document.GradientRadial {
+		paint.Coords = []float64{g.CX, g.CY, g.R}
+	} else {
+		paint.Coords = []float64{g.X1, g.Y1, g.X2, g.Y2}
+	}
+	for i, stop := range g.Stops {
+		if c, err := color.Normalize(stop.Color); err == nil {
+			stop.Color = c
+		}
+		paint.Stops[i] = stop
+	}
+	return paint
+}
+
+// RoundTransforms rounds the transform matrices of commands to decimals
+// places, in place, so the JSON sent to the frontend doesn't carry float
+// noise. A matrix that rounding would make singular, such as a tiny scale,
+// is left exact.
+func RoundTransforms(commands []DrawCommand, decimals int) {
+	scale := math.Pow10(decimals)
+	for _, cmd := range commands {
+		m := cmd.Transform
+		if len(m) != 6 {
+			continue
+		}
+		var rounded [6]float64
+		for i, v := range m {
+			rounded[i] = math.Round(v*scale) / scale
+		}
+		if rounded[0]*rounded[3]-rounded[1]*rounded[2] == 0 && m[0]*m[3]-m[1]*m[2] != 0 {
+			continue
+		}
+		copy(m, rounded[:])
+	}
+}
+
 // DrawCommandsToJSON serializes draw commands to JSON.
 func DrawCommandsToJSON(commands []DrawCommand) (string, error) {
 	data, err := json.Marshal(commands)
@@ -156,6 +286,11 @@ func hitTestNode(node *SceneNode, x, y float64) string {
 	// Test this node if it has bounds and renderable content (path, image, or text)
 	if (len(node.Path) > 0 || node.Type == "image" || node.Type == "text") && !node.Bounds.IsEmpty() {
 		if node.Bounds.Contains(x, y) {
+			// An unfilled shape is only its outline, so only the stroke band
+			// counts as a hit
+			if len(node.Path) > 0 && !node.hasFill() && node.hasStroke() && !hitsStroke(node, x, y) {
+				return ""
+			}
 			return node.ID
 		}
 	}
@@ -0,0 +1,63 @@
+package engine
+
+import "testing"
+
+// commandFor returns the draw command drawing objectID.
+func commandFor(t *testing.T, commands []DrawCommand, objectID string) DrawCommand {
+	t.Helper()
+	for _, cmd := range commands {
+		if cmd.ObjectID == objectID {
+			return cmd
+		}
+	}
+	t.Fatalf("no draw command for %s", objectID)
+	return DrawCommand{}
+}
+
+// TestPaintAlphaSeparateFromOpacity checks a fill or stroke color's alpha
+// reaches the draw command on its own, leaving the object's opacity alone.
+func TestPaintAlphaSeparateFromOpacity(t *testing.T) {
+	tests := []struct {
+		fill, stroke           string
+		wantFill, wantStroke   string
+		fillAlpha, strokeAlpha *float64
+	}{
+		{"#ff000080", "#0000ff", "#ff0000", "#0000ff", floatPtr(128.0 / 255), nil},
+		{"rgba(0, 255, 0, 0.25)", "#00000040", "#00ff00", "#000000", floatPtr(64.0 / 255), floatPtr(64.0 / 255)},
+		{"#336699ff", "none", "#336699", "none", nil, nil},
+	}
+	for _, tt := range tests {
+		doc, rectID := rectDoc()
+		rect := doc.Objects[rectID]
+		rect.Style.Fill, rect.Style.Stroke, rect.Style.Opacity = tt.fill, tt.stroke, 0.5
+		doc.Objects[rectID] = rect
+
+		cmd := commandFor(t, CompileDrawCommands(buildAt(doc, 0)), rectID)
+		if cmd.Fill != tt.wantFill || cmd.Stroke != tt.wantStroke {
+			t.Errorf("%s/%s: fill %q stroke %q, want %q %q", tt.fill, tt.stroke, cmd.Fill, cmd.Stroke, tt.wantFill, tt.wantStroke)
+		}
+		if !sameAlpha(cmd.FillAlpha, tt.fillAlpha) || !sameAlpha(cmd.StrokeAlpha, tt.strokeAlpha) {
+			t.Errorf("%s/%s: alphas %v %v, want %v %v", tt.fill, tt.stroke, deref(cmd.FillAlpha), deref(cmd.StrokeAlpha), deref(tt.fillAlpha), deref(tt.strokeAlpha))
+		}
+		if cmd.Opacity != 0.5 {
+			t.Errorf("%s: opacity %v, want 0.5", tt.fill, cmd.Opacity)
+		}
+	}
+}
+
+func floatPtr(v float64) *float64 { return &v }
+
+func sameAlpha(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return near(*a, *b)
+}
+
+// deref returns *p, or 1 (opaque) for nil.
+func deref(p *float64) float64 {
+	if p == nil {
+		return 1
+	}
+	return *p
+}
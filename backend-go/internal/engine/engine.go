@@ -2,10 +2,17 @@ package engine
 
 import (
 	"encoding/json"
+	"math"
+	"sort"
+	"time"
 
 	"github.com/inamate/inamate/backend-go/internal/document"
 )
 
+// DefaultRenderPrecision is how many decimals the transforms of rendered draw
+// commands keep: well under a pixel even for a matrix scaling a large scene.
+const DefaultRenderPrecision = 4
+
 // Engine is the main animation engine that owns the document and scene graph state.
 // It processes commands from the frontend and returns query results.
 type Engine struct {
@@ -16,11 +23,22 @@ type Engine struct {
 	// Retained scene graph
 	sceneGraph *SceneGraph
 
+	// Spatial index over sceneGraph, built on demand; nil when stale
+	spatialIndex *SpatialIndex
+
+	// Viewport in scene coordinates — when non-nil, Render omits the draw
+	// commands of nodes entirely outside it
+	cullViewport *Rect
+
 	// Playback state
 	frame   int
 	playing bool
 	fps     int
 
+	// Animation preview — when true, Symbol timelines animate while paused so
+	// scrubbing shows the values playback would
+	preview bool
+
 	// Total frames in root timeline
 	totalFrames int
 
@@ -30,8 +48,36 @@ type Engine struct {
 	// Dirty flag - scene graph needs rebuild
 	dirty bool
 
+	// Safe-area guides — when true, RenderOverlay outlines the scene's
+	// action-safe and title-safe areas
+	safeAreaGuides bool
+
+	// Size of the safe areas of scenes that don't set their own
+	safeAreaPercent document.SafeAreaPercent
+
 	// Drag overlay — when non-nil, overrides transforms for specific objects during drag
 	dragOverlay *DragOverlay
+
+	// Decimals the transforms of rendered draw commands are rounded to;
+	// negative leaves them exact
+	renderPrecision int
+
+	// Stats from the most recent Render
+	stats RenderStats
+}
+
+// RenderStats describes the most recent Render, for performance debugging.
+// Timings are in microseconds; BuildMicros is zero when the retained scene
+// graph was reused. IndexMicros is the time GetVisibleObjects has since spent
+// building the spatial index over it.
+type RenderStats struct {
+	NodeCount     int   `json:"nodeCount"`
+	CommandCount  int   `json:"commandCount"`
+	CulledCount   int   `json:"culledCount"` // Draw commands omitted by viewport culling
+	Rebuilt       bool  `json:"rebuilt"`     // false on a cache hit
+	BuildMicros   int64 `json:"buildMicros"`
+	IndexMicros   int64 `json:"indexMicros"`
+	CompileMicros int64 `json:"compileMicros"`
 }
 
 // DragOverlay holds per-object transform overrides for drag preview rendering.
@@ -45,9 +91,11 @@ type DragOverlay struct {
 // NewEngine creates a new engine instance.
 func NewEngine() *Engine {
 	return &Engine{
-		fps:        24,
-		sceneGraph: NewSceneGraph(),
-		dirty:      true,
+		fps:             24,
+		sceneGraph:      NewSceneGraph(),
+		dirty:           true,
+		safeAreaPercent: document.StandardSafeAreaPercent,
+		renderPrecision: DefaultRenderPrecision,
 	}
 }
 
@@ -94,7 +142,14 @@ func (e *Engine) UpdateDocument(jsonData string) error {
 		return err
 	}
 
-	e.doc = &doc
+	e.ReplaceDocument(&doc)
+	return nil
+}
+
+// ReplaceDocument swaps in an already-decoded document while preserving
+// playback state, like UpdateDocument. The engine takes ownership of doc.
+func (e *Engine) ReplaceDocument(doc *document.InDocument) {
+	e.doc = doc
 	e.fps = doc.Project.FPS
 	if e.fps <= 0 {
 		e.fps = 24
@@ -120,8 +175,6 @@ func (e *Engine) UpdateDocument(jsonData string) error {
 
 	// Preserve playing state and selection — don't reset them
 	e.dirty = true
-
-	return nil
 }
 
 // LoadSampleDocument loads the built-in sample document.
@@ -164,17 +217,35 @@ func (e *Engine) SetPlayhead(frame int) {
 
 // Play starts playback.
 func (e *Engine) Play() {
-	e.playing = true
+	e.setPlaying(true)
 }
 
 // Pause stops playback.
 func (e *Engine) Pause() {
-	e.playing = false
+	e.setPlaying(false)
 }
 
 // TogglePlay toggles play/pause state.
 func (e *Engine) TogglePlay() {
-	e.playing = !e.playing
+	e.setPlaying(!e.playing)
+}
+
+// setPlaying changes the playback state, rebuilding on the next render since
+// Symbol timelines animate only while playing (or previewing).
+func (e *Engine) setPlaying(playing bool) {
+	if e.playing != playing {
+		e.playing = playing
+		e.dirty = true
+	}
+}
+
+// SetAnimationPreview enables or disables evaluating Symbol timelines while
+// paused. Playback always evaluates them.
+func (e *Engine) SetAnimationPreview(enabled bool) {
+	if e.preview != enabled {
+		e.preview = enabled
+		e.dirty = true
+	}
 }
 
 // SetScene switches the active scene.
@@ -238,27 +309,95 @@ func (e *Engine) Render() string {
 		return "[]"
 	}
 
+	stats := RenderStats{Rebuilt: e.dirty}
+	start := time.Now()
+
 	// Rebuild scene graph if dirty
 	if e.dirty {
 		e.sceneGraph = BuildSceneGraph(
 			e.doc,
 			e.sceneID,
-			e.frame,
+			float64(e.frame),
 			e.doc.Project.RootTimeline,
-			e.playing,
+			e.playing || e.preview,
 			e.dragOverlay,
 		)
+		e.spatialIndex = nil
 		e.dirty = false
+		stats.BuildMicros = time.Since(start).Microseconds()
+		start = time.Now()
 	}
 
 	// Compile to draw commands
-	commands := CompileDrawCommands(e.sceneGraph)
+	var commands []DrawCommand
+	if e.cullViewport != nil {
+		commands, stats.CulledCount = CompileDrawCommandsInView(e.sceneGraph, *e.cullViewport)
+	} else {
+		commands = CompileDrawCommands(e.sceneGraph)
+	}
+
+	stats.CompileMicros = time.Since(start).Microseconds()
+	stats.NodeCount = len(e.sceneGraph.NodesById)
+	stats.CommandCount = len(commands)
+	e.stats = stats
 
 	// Serialize to JSON
+	e.roundTransforms(commands)
 	result, _ := DrawCommandsToJSON(commands)
 	return result
 }
 
+// RenderAtTime returns draw commands for the document at a time in seconds,
+// which may fall between frames, so exports can sample at a higher fps than
+// the document's. Symbol timelines animate as in playback. Neither the
+// playhead nor the retained scene graph changes.
+func (e *Engine) RenderAtTime(seconds float64) string {
+	if e.doc == nil || e.fps <= 0 {
+		return "[]"
+	}
+
+	// Round away float error so times on a whole frame evaluate exactly
+	frame := math.Round(seconds*float64(e.fps)*1e6) / 1e6
+	frame = math.Max(0, math.Min(frame, float64(e.totalFrames-1)))
+
+	sg := BuildSceneGraph(e.doc, e.sceneID, frame, e.doc.Project.RootTimeline, true, nil)
+	commands := CompileDrawCommands(sg)
+	e.roundTransforms(commands)
+	result, _ := DrawCommandsToJSON(commands)
+	return result
+}
+
+// RenderAtFrame returns draw commands for the document at frame, clamped to
+// the root timeline, for previews such as thumbnail strips. Symbol timelines
+// animate only if playing is set. Like RenderAtTime it builds a throwaway
+// scene graph, leaving the playhead and the retained scene graph alone.
+func (e *Engine) RenderAtFrame(frame int, playing bool) string {
+	if e.doc == nil {
+		return "[]"
+	}
+
+	if frame >= e.totalFrames {
+		frame = e.totalFrames - 1
+	}
+	if frame < 0 {
+		frame = 0
+	}
+
+	sg := BuildSceneGraph(e.doc, e.sceneID, float64(frame), e.doc.Project.RootTimeline, playing, nil)
+	commands := CompileDrawCommands(sg)
+	e.roundTransforms(commands)
+	result, _ := DrawCommandsToJSON(commands)
+	return result
+}
+
+// roundTransforms rounds the transforms of draw commands about to be
+// returned to the render precision.
+func (e *Engine) roundTransforms(commands []DrawCommand) {
+	if e.renderPrecision >= 0 {
+		RoundTransforms(commands, e.renderPrecision)
+	}
+}
+
 // HitTest performs a hit test at the given coordinates.
 // Returns the object ID of the topmost hit, or empty string.
 func (e *Engine) HitTest(x, y float64) string {
@@ -268,6 +407,40 @@ func (e *Engine) HitTest(x, y float64) string {
 	return HitTest(e.sceneGraph, x, y)
 }
 
+// SetCullToViewport enables or disables viewport culling in Render. The
+// viewport is in scene coordinates; nodes whose world bounds lie entirely
+// outside it are left out of the draw commands.
+func (e *Engine) SetCullToViewport(enabled bool, viewport Rect) {
+	if !enabled {
+		e.cullViewport = nil
+		return
+	}
+	e.cullViewport = &viewport
+}
+
+// SetRenderPrecision sets how many decimals the transforms of rendered draw
+// commands are rounded to. A negative value leaves them exact.
+func (e *Engine) SetRenderPrecision(decimals int) {
+	e.renderPrecision = decimals
+}
+
+// GetVisibleObjects returns the IDs of the visible objects whose world bounds
+// intersect the rect, in painter's order, as JSON. It uses the scene graph of
+// the most recent Render, indexing it on first use; the index build time is
+// added to the render stats.
+func (e *Engine) GetVisibleObjects(view Rect) string {
+	if e.sceneGraph == nil {
+		return "[]"
+	}
+	if e.spatialIndex == nil {
+		start := time.Now()
+		e.spatialIndex = BuildSpatialIndex(e.sceneGraph)
+		e.stats.IndexMicros += time.Since(start).Microseconds()
+	}
+	data, _ := json.Marshal(e.spatialIndex.Query(view))
+	return string(data)
+}
+
 // GetSelectionBounds returns the bounding box of the current selection as JSON.
 func (e *Engine) GetSelectionBounds() string {
 	if e.sceneGraph == nil || len(e.selection) == 0 {
@@ -292,11 +465,25 @@ func (e *Engine) GetScene() string {
 	return string(data)
 }
 
+// GetLayerTree returns the layer tree of a scene (see BuildLayerTree) as
+// JSON. An empty scene ID means the active scene.
+func (e *Engine) GetLayerTree(sceneID string) string {
+	if e.doc == nil {
+		return "[]"
+	}
+	if sceneID == "" {
+		sceneID = e.sceneID
+	}
+	data, _ := json.Marshal(BuildLayerTree(e.doc, sceneID))
+	return string(data)
+}
+
 // GetPlaybackState returns the current playback state as JSON.
 func (e *Engine) GetPlaybackState() string {
 	data, _ := json.Marshal(map[string]interface{}{
 		"frame":       e.frame,
 		"playing":     e.playing,
+		"preview":     e.preview,
 		"fps":         e.fps,
 		"totalFrames": e.totalFrames,
 	})
@@ -322,12 +509,217 @@ func (e *Engine) GetTimelineLength(timelineID string) int {
 	return 0
 }
 
-// GetDocument returns the full document as JSON (for debugging/sync).
+// TimelineInfo summarizes a timeline for the timeline switcher.
+type TimelineInfo struct {
+	ID            string `json:"id"`
+	Length        int    `json:"length"`
+	FPS           int    `json:"fps"`
+	TrackCount    int    `json:"trackCount"`
+	IsRoot        bool   `json:"isRoot"`
+	OwnerSymbolID string `json:"ownerSymbolId,omitempty"`
+}
+
+// GetTimelines returns every timeline in the document as JSON, root first,
+// with the Symbol that owns each nested timeline resolved.
+func (e *Engine) GetTimelines() string {
+	if e.doc == nil {
+		return "[]"
+	}
+
+	owners := make(map[string]string)
+	for id, obj := range e.doc.Objects {
+		if obj.Type != document.ObjectTypeSymbol {
+			continue
+		}
+		if tlID := GetSymbolTimelineID(obj.Data); tlID != "" {
+			// Prefer the lowest object ID if a timeline is shared, for stable output
+			if existing, ok := owners[tlID]; !ok || id < existing {
+				owners[tlID] = id
+			}
+		}
+	}
+
+	timelines := make([]TimelineInfo, 0, len(e.doc.Timelines))
+	for id, tl := range e.doc.Timelines {
+		timelines = append(timelines, TimelineInfo{
+			ID:            id,
+			Length:        tl.Length,
+			FPS:           e.fps,
+			TrackCount:    len(tl.Tracks),
+			IsRoot:        id == e.doc.Project.RootTimeline,
+			OwnerSymbolID: owners[id],
+		})
+	}
+
+	sort.Slice(timelines, func(i, j int) bool {
+		if timelines[i].IsRoot != timelines[j].IsRoot {
+			return timelines[i].IsRoot
+		}
+		return timelines[i].ID < timelines[j].ID
+	})
+
+	data, _ := json.Marshal(timelines)
+	return string(data)
+}
+
+// TrackKeyframes lists a track's keyframes within a frame range.
+type TrackKeyframes struct {
+	TrackID   string              `json:"trackId"`
+	ObjectID  string              `json:"objectId"`
+	Property  string              `json:"property"`
+	Keyframes []document.Keyframe `json:"keyframes"`
+}
+
+// GetKeyframesInRange returns the keyframes of a timeline whose frames fall in
+// [startFrame, endFrame] as JSON, grouped by track in timeline order and sorted
+// by frame. Tracks with no keyframes in range are omitted.
+func (e *Engine) GetKeyframesInRange(timelineID string, startFrame, endFrame int) string {
+	if e.doc == nil {
+		return "[]"
+	}
+	tl, ok := e.doc.Timelines[timelineID]
+	if !ok {
+		return "[]"
+	}
+
+	result := make([]TrackKeyframes, 0)
+	for _, trackID := range tl.Tracks {
+		track, ok := e.doc.Tracks[trackID]
+		if !ok {
+			continue
+		}
+		var keys []document.Keyframe
+		for _, keyID := range track.Keys {
+			if kf, ok := e.doc.Keyframes[keyID]; ok && kf.Frame >= startFrame && kf.Frame <= endFrame {
+				keys = append(keys, kf)
+			}
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		sort.SliceStable(keys, func(i, j int) bool { return keys[i].Frame < keys[j].Frame })
+		result = append(result, TrackKeyframes{
+			TrackID:   track.ID,
+			ObjectID:  track.ObjectID,
+			Property:  track.Property,
+			Keyframes: keys,
+		})
+	}
+
+	data, _ := json.Marshal(result)
+	return string(data)
+}
+
+// GetKeyframeFrames returns the sorted frames holding at least one keyframe
+// of the given objects' tracks, or of every track when objectIDs is empty,
+// for drawing tick marks on the timeline. Frames are as stored, relative to
+// each track's timeline.
+func (e *Engine) GetKeyframeFrames(objectIDs []string) []int {
+	frames := make([]int, 0)
+	if e.doc == nil {
+		return frames
+	}
+	wanted := make(map[string]bool, len(objectIDs))
+	for _, id := range objectIDs {
+		wanted[id] = true
+	}
+
+	seen := make(map[int]bool)
+	for _, track := range e.doc.Tracks {
+		if len(wanted) > 0 && !wanted[track.ObjectID] {
+			continue
+		}
+		for _, keyID := range track.Keys {
+			if kf, ok := e.doc.Keyframes[keyID]; ok && !seen[kf.Frame] {
+				seen[kf.Frame] = true
+				frames = append(frames, kf.Frame)
+			}
+		}
+	}
+	sort.Ints(frames)
+	return frames
+}
+
+// GetMotionPath returns, as JSON, the motion path of an object across
+// startFrame to endFrame of a timeline (the root timeline when timelineID is
+// empty), sampled every step frames, for drawing trails on canvas. See
+// MotionPath.
+func (e *Engine) GetMotionPath(objectID, timelineID string, startFrame, endFrame, step int) string {
+	if e.doc == nil {
+		return "[]"
+	}
+	if timelineID == "" {
+		timelineID = e.doc.Project.RootTimeline
+	}
+	points := MotionPath(e.doc, objectID, timelineID, startFrame, endFrame, step)
+	if e.renderPrecision >= 0 {
+		for i := range points {
+			points[i].X = document.Round(points[i].X, e.renderPrecision)
+			points[i].Y = document.Round(points[i].Y, e.renderPrecision)
+		}
+	}
+	data, _ := json.Marshal(points)
+	return string(data)
+}
+
+// GetEasingPresets returns the document's named easing presets as JSON.
+func (e *Engine) GetEasingPresets() string {
+	if e.doc == nil || e.doc.EasingPresets == nil {
+		return "{}"
+	}
+	data, _ := json.Marshal(e.doc.EasingPresets)
+	return string(data)
+}
+
+// findObjectsLimit caps the matches FindObjects returns.
+const findObjectsLimit = 100
+
+// FindObjects searches the document's scene names, text content and object
+// meta for query, ignoring case (see document.Search), and returns the
+// matches as JSON.
+func (e *Engine) FindObjects(query string) string {
+	if e.doc == nil {
+		return "[]"
+	}
+	matches, _ := document.Search(e.doc, query, findObjectsLimit)
+	data, _ := json.Marshal(matches)
+	return string(data)
+}
+
+// GetRenderStats returns the stats of the most recent Render as JSON.
+func (e *Engine) GetRenderStats() string {
+	data, _ := json.Marshal(e.stats)
+	return string(data)
+}
+
+// Diagnostics describes what of the loaded document this build can't fully
+// handle, so the editor can warn before someone edits a newer document.
+type Diagnostics struct {
+	Version        int                      `json:"version"`        // The document's format version
+	CurrentVersion int                      `json:"currentVersion"` // The newest format this build knows
+	Newer          bool                     `json:"newer"`          // Written by a newer version
+	UnknownObjects []document.UnknownObject `json:"unknownObjects"` // Drawn as placeholders
+}
+
+// GetDiagnostics returns the loaded document's Diagnostics as JSON.
+func (e *Engine) GetDiagnostics() string {
+	diag := Diagnostics{CurrentVersion: document.CurrentVersion, UnknownObjects: []document.UnknownObject{}}
+	if e.doc != nil {
+		diag.Version = e.doc.Project.Version
+		diag.Newer = e.doc.Newer()
+		diag.UnknownObjects = e.doc.UnknownObjects()
+	}
+	data, _ := json.Marshal(diag)
+	return string(data)
+}
+
+// GetDocument returns the full document as canonical JSON (for
+// debugging/sync), byte-identical for equal documents.
 func (e *Engine) GetDocument() string {
 	if e.doc == nil {
 		return "{}"
 	}
-	data, _ := json.Marshal(e.doc)
+	data, _ := document.MarshalCanonical(e.doc)
 	return string(data)
 }
 
@@ -348,7 +740,7 @@ func (e *Engine) GetAnimatedTransform(objectID string) string {
 	transform := obj.Transform
 
 	// Evaluate keyframe overrides at the current frame
-	evalResult := EvaluateTimeline(e.doc, e.doc.Project.RootTimeline, e.frame)
+	evalResult := EvaluateTimeline(e.doc, e.doc.Project.RootTimeline, float64(e.frame))
 	if numOverrides, ok := evalResult.Numeric[objectID]; ok {
 		transform = ApplyOverridesToTransform(transform, numOverrides)
 	}
@@ -0,0 +1,339 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// spinner is the sample document with a Symbol added under its root: a
+// rect turning once over the Symbol's own 24 frame timeline.
+type spinner struct {
+	doc        *document.InDocument
+	symbolID   string
+	timelineID string
+	rectID     string
+}
+
+func newSpinner() spinner {
+	s := spinner{
+		doc:        document.NewSampleDocument(typeid.NewProjectID()),
+		symbolID:   typeid.NewObjectID(),
+		timelineID: typeid.NewTimelineID(),
+		rectID:     typeid.NewObjectID(),
+	}
+	s.doc.Timelines[s.timelineID] = document.Timeline{ID: s.timelineID, Length: 24, Tracks: []string{}}
+	addChild(s.doc, s.doc.Scenes[s.doc.Project.Scenes[0]].Root, document.ObjectNode{
+		ID:        s.symbolID,
+		Type:      document.ObjectTypeSymbol,
+		Transform: document.Transform{X: 100, Y: 100, SX: 1, SY: 1},
+		Style:     document.Style{Opacity: 1},
+		Visible:   true,
+		Data:      json.RawMessage(fmt.Sprintf(`{"timelineId":%q,"loop":true}`, s.timelineID)),
+	})
+	addChild(s.doc, s.symbolID, document.ObjectNode{
+		ID:        s.rectID,
+		Type:      document.ObjectTypeShapeRect,
+		Transform: document.Transform{SX: 1, SY: 1},
+		Style:     document.Style{Fill: "#3366ff", Opacity: 1},
+		Visible:   true,
+		Data:      json.RawMessage(`{"width":40,"height":10}`),
+	})
+	animateIn(s.doc, s.timelineID, s.rectID, "transform.r", []int{0, 24}, []float64{0, 360})
+	return s
+}
+
+// engine returns an engine with the spinner loaded.
+func (s spinner) engine() *Engine {
+	e := NewEngine()
+	e.ReplaceDocument(s.doc)
+	return e
+}
+
+func TestGetTimelines(t *testing.T) {
+	s := newSpinner()
+
+	var timelines []TimelineInfo
+	if err := json.Unmarshal([]byte(s.engine().GetTimelines()), &timelines); err != nil {
+		t.Fatal(err)
+	}
+	want := []TimelineInfo{
+		{ID: s.doc.Project.RootTimeline, Length: 48, FPS: 24, IsRoot: true},
+		{ID: s.timelineID, Length: 24, FPS: 24, TrackCount: 1, OwnerSymbolID: s.symbolID},
+	}
+	if len(timelines) != len(want) {
+		t.Fatalf("timelines %+v, want %+v", timelines, want)
+	}
+	for i := range want {
+		if timelines[i] != want[i] {
+			t.Errorf("timeline %d = %+v, want %+v", i, timelines[i], want[i])
+		}
+	}
+}
+
+func TestGetTimelinesWithoutDocument(t *testing.T) {
+	if got := NewEngine().GetTimelines(); got != "[]" {
+		t.Errorf("GetTimelines = %s, want []", got)
+	}
+}
+
+// reachable counts the visible objects under id, including itself.
+func reachable(doc *document.InDocument, id string) int {
+	obj, ok := doc.Objects[id]
+	if !ok || !obj.Visible {
+		return 0
+	}
+	n := 1
+	for _, child := range obj.Children {
+		n += reachable(doc, child)
+	}
+	return n
+}
+
+func TestRenderStats(t *testing.T) {
+	s := newSpinner()
+	doc, e := s.doc, s.engine()
+
+	var commands []json.RawMessage
+	if err := json.Unmarshal([]byte(e.Render()), &commands); err != nil {
+		t.Fatal(err)
+	}
+	var stats RenderStats
+	if err := json.Unmarshal([]byte(e.GetRenderStats()), &stats); err != nil {
+		t.Fatal(err)
+	}
+	want := reachable(doc, doc.Scenes[doc.Project.Scenes[0]].Root)
+	if stats.NodeCount != want || want != 3 {
+		t.Errorf("node count %d, want the spinner's %d visible objects", stats.NodeCount, want)
+	}
+	if stats.CommandCount != len(commands) || stats.CommandCount == 0 {
+		t.Errorf("command count %d, rendered %d commands", stats.CommandCount, len(commands))
+	}
+	if !stats.Rebuilt {
+		t.Error("first render did not report a rebuild")
+	}
+
+	e.Render()
+	if err := json.Unmarshal([]byte(e.GetRenderStats()), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Rebuilt || stats.BuildMicros != 0 || stats.NodeCount != want {
+		t.Errorf("stats %+v for a cached render, want no rebuild and the same nodes", stats)
+	}
+}
+
+func TestRenderStatsBeforeRender(t *testing.T) {
+	var stats RenderStats
+	if err := json.Unmarshal([]byte(NewEngine().GetRenderStats()), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats != (RenderStats{}) {
+		t.Errorf("stats %+v before any render, want zero", stats)
+	}
+}
+
+// rotation returns the spinner rect's rendered rotation in degrees.
+func (s spinner) rotation(t *testing.T, e *Engine) float64 {
+	t.Helper()
+	e.Render()
+	node, ok := e.sceneGraph.NodesById[s.rectID]
+	if !ok {
+		t.Fatal("rect not in the scene graph")
+	}
+	m := node.LocalTransform
+	return math.Round(math.Atan2(m[1], m[0]) * 180 / math.Pi)
+}
+
+func TestAnimationPreview(t *testing.T) {
+	s := newSpinner()
+	e := s.engine()
+	e.SetPlayhead(6)
+
+	// Editing shows the Symbol's base pose
+	if r := s.rotation(t, e); r != 0 {
+		t.Errorf("edit mode rotation %v, want 0", r)
+	}
+
+	// Previewing while paused shows the animated value, and rebuilds
+	e.SetAnimationPreview(true)
+	if r := s.rotation(t, e); r != 90 {
+		t.Errorf("preview rotation %v, want 90", r)
+	}
+	var state struct{ Playing, Preview bool }
+	json.Unmarshal([]byte(e.GetPlaybackState()), &state)
+	if state.Playing || !state.Preview {
+		t.Errorf("playback state %+v, want paused with preview", state)
+	}
+
+	// Playback animates with or without preview
+	e.SetAnimationPreview(false)
+	e.Play()
+	e.SetPlayhead(12)
+	if r := s.rotation(t, e); r != 180 {
+		t.Errorf("playback rotation %v, want 180", r)
+	}
+	e.Pause()
+	if r := s.rotation(t, e); r != 0 {
+		t.Errorf("rotation %v after pausing without preview, want 0", r)
+	}
+}
+
+func TestRenderAtFrame(t *testing.T) {
+	s := newSpinner()
+	e := s.engine()
+	e.SetPlayhead(3)
+	before := e.Render()
+	retained := e.sceneGraph
+
+	// Rendering frame 10 matches what the playhead would show there, with
+	// and without Symbol animation
+	for _, playing := range []bool{true, false} {
+		other := s.engine()
+		other.SetPlayhead(10)
+		if playing {
+			other.Play()
+		}
+		if got, want := e.RenderAtFrame(10, playing), other.Render(); got != want {
+			t.Errorf("RenderAtFrame(10, %v) = %s, want %s", playing, got, want)
+		}
+	}
+
+	// The playhead and the retained scene graph are untouched, so the next
+	// render is still the cached frame 3
+	if f := e.GetFrame(); f != 3 {
+		t.Errorf("frame = %d after RenderAtFrame, want 3", f)
+	}
+	if e.sceneGraph != retained || e.dirty {
+		t.Error("RenderAtFrame replaced the retained scene graph or marked it dirty")
+	}
+	if got := e.Render(); got != before {
+		t.Errorf("render after RenderAtFrame = %s, want %s", got, before)
+	}
+	var stats RenderStats
+	json.Unmarshal([]byte(e.GetRenderStats()), &stats)
+	if stats.Rebuilt {
+		t.Error("render after RenderAtFrame rebuilt the scene graph")
+	}
+
+	// Frames past either end clamp to the timeline
+	if got, want := e.RenderAtFrame(1000, true), e.RenderAtFrame(47, true); got != want {
+		t.Errorf("RenderAtFrame(1000) = %s, want the last frame's %s", got, want)
+	}
+	if got, want := e.RenderAtFrame(-5, true), e.RenderAtFrame(0, true); got != want {
+		t.Errorf("RenderAtFrame(-5) = %s, want the first frame's %s", got, want)
+	}
+	if got := NewEngine().RenderAtFrame(10, true); got != "[]" {
+		t.Errorf("RenderAtFrame without a document = %s, want []", got)
+	}
+}
+
+func TestGetKeyframesInRange(t *testing.T) {
+	s := newSpinner()
+	doc := s.doc
+	trackID := keyTrack(doc, s.timelineID, s.rectID, "transform.x", []int{20, 4, 12}, []string{"3", "1", "2"})
+	keyTrack(doc, s.timelineID, s.rectID, "style.fill", []int{23}, []string{`"#ff0000"`})
+	e := s.engine()
+
+	var tracks []TrackKeyframes
+	if err := json.Unmarshal([]byte(e.GetKeyframesInRange(s.timelineID, 4, 20)), &tracks); err != nil {
+		t.Fatal(err)
+	}
+	// The rotation track's keys at 0 and 24 and the fill's at 23 are outside
+	if len(tracks) != 1 || tracks[0].TrackID != trackID {
+		t.Fatalf("tracks %+v, want only the x track", tracks)
+	}
+	track := tracks[0]
+	if track.ObjectID != s.rectID || track.Property != "transform.x" {
+		t.Errorf("track %s %s, want the rect's transform.x", track.ObjectID, track.Property)
+	}
+	var frames, values []string
+	for _, kf := range track.Keyframes {
+		frames = append(frames, fmt.Sprint(kf.Frame))
+		values = append(values, string(kf.Value))
+		if kf.Easing != document.EasingLinear {
+			t.Errorf("keyframe at %d has easing %q", kf.Frame, kf.Easing)
+		}
+	}
+	if fmt.Sprint(frames) != "[4 12 20]" || fmt.Sprint(values) != "[1 2 3]" {
+		t.Errorf("keys at %v with values %v, want 4 12 20 holding 1 2 3", frames, values)
+	}
+
+	if got := e.GetKeyframesInRange(s.timelineID, 0, 24); !json.Valid([]byte(got)) || len(got) < 3 {
+		t.Errorf("full range = %s", got)
+	}
+	for _, got := range []string{
+		e.GetKeyframesInRange(typeid.NewTimelineID(), 0, 24),
+		e.GetKeyframesInRange(s.timelineID, 30, 40),
+		NewEngine().GetKeyframesInRange(s.timelineID, 0, 24),
+	} {
+		if got != "[]" {
+			t.Errorf("empty query = %s, want []", got)
+		}
+	}
+}
+
+func TestGetKeyframeFrames(t *testing.T) {
+	s := newSpinner()
+	// A second object keyed on the root timeline, sharing frame 0
+	other := typeid.NewObjectID()
+	addChild(s.doc, s.doc.Scenes[s.doc.Project.Scenes[0]].Root, document.ObjectNode{
+		ID:        other,
+		Type:      document.ObjectTypeShapeRect,
+		Transform: document.Transform{SX: 1, SY: 1},
+		Style:     document.Style{Opacity: 1},
+		Visible:   true,
+		Data:      json.RawMessage(`{"width":10,"height":10}`),
+	})
+	animate(s.doc, other, "transform.x", []int{0, 10, 30}, []float64{0, 50, 100})
+	e := s.engine()
+
+	tests := []struct {
+		name      string
+		objectIDs []string
+		want      []int
+	}{
+		{"spinner rotation", []string{s.rectID}, []int{0, 24}},
+		{"all tracks", nil, []int{0, 10, 24, 30}},
+		{"both objects", []string{other, s.rectID}, []int{0, 10, 24, 30}},
+		{"unkeyed object", []string{s.symbolID}, []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := e.GetKeyframeFrames(tt.objectIDs); !slices.Equal(got, tt.want) {
+				t.Errorf("GetKeyframeFrames(%v) = %v, want %v", tt.objectIDs, got, tt.want)
+			}
+		})
+	}
+	if got := NewEngine().GetKeyframeFrames(nil); got == nil || len(got) != 0 {
+		t.Errorf("GetKeyframeFrames without a document = %#v, want an empty slice", got)
+	}
+}
+
+func TestFindObjects(t *testing.T) {
+	e := NewEngine()
+	if got := e.FindObjects("cta"); got != "[]" {
+		t.Errorf("without a document: %s, want []", got)
+	}
+
+	doc, rectID := rectDoc()
+	rect := doc.Objects[rectID]
+	rect.Meta = json.RawMessage(`{"name":"CTA button"}`)
+	doc.Objects[rectID] = rect
+	e.ReplaceDocument(doc)
+
+	var matches []document.SearchMatch
+	if err := json.Unmarshal([]byte(e.FindObjects("cta")), &matches); err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || matches[0].ObjectID != rectID || matches[0].Field != "meta.name" {
+		t.Errorf("matches %+v, want the rect's name", matches)
+	}
+	if got := e.FindObjects("missing"); got != "[]" {
+		t.Errorf("no match: %s, want []", got)
+	}
+}
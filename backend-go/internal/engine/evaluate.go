@@ -6,6 +6,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/inamate/inamate/backend-go/internal/color"
 	"github.com/inamate/inamate/backend-go/internal/document"
 )
 
@@ -13,21 +14,46 @@ import (
 // Keys are property paths like "transform.x", "transform.r", "style.opacity".
 type PropertyOverrides map[string]float64
 
-// StringPropertyOverrides holds step-interpolated string property values (e.g. colors).
+// StringPropertyOverrides holds string property values: colors blended between
+// keyframes, other strings step-interpolated.
 type StringPropertyOverrides map[string]string
 
-// EvalResult contains both numeric and string property overrides per object.
+// BoolPropertyOverrides holds step-interpolated boolean property values (e.g. visibility).
+type BoolPropertyOverrides map[string]bool
+
+// EvalResult contains numeric, string, and boolean property overrides per object.
 type EvalResult struct {
 	Numeric map[string]PropertyOverrides
 	Strings map[string]StringPropertyOverrides
+	Bools   map[string]BoolPropertyOverrides
+}
+
+// vectorProperties maps properties keyed with array values (e.g. [x, y]) to the
+// scalar override paths their components are routed to.
+var vectorProperties = map[string][]string{
+	"transform.position": {"transform.x", "transform.y"},
+	"transform.scale":    {"transform.sx", "transform.sy"},
+	"transform.anchor":   {"transform.ax", "transform.ay"},
+	"transform.skew":     {"transform.skewX", "transform.skewY"},
 }
 
-// EvaluateTimeline evaluates all tracks in a timeline at the given frame.
-// Returns numeric overrides (linearly interpolated) and string overrides (step/hold).
-func EvaluateTimeline(doc *document.InDocument, timelineID string, frame int) EvalResult {
+// integerProperties are numeric properties that only take whole values. Their
+// interpolated values are floored so e.g. a typewriter effect reveals one
+// character at a time rather than rounding up early.
+var integerProperties = map[string]bool{
+	"data.visibleCharacters": true,
+}
+
+// EvaluateTimeline evaluates all tracks in a timeline at the given frame,
+// which may be fractional (e.g. when sampling at a higher fps than the
+// document's); keyframes sit on whole frames. Returns numeric overrides
+// (interpolated, including vector components) and string and boolean
+// overrides (step/hold).
+func EvaluateTimeline(doc *document.InDocument, timelineID string, frame float64) EvalResult {
 	result := EvalResult{
 		Numeric: make(map[string]PropertyOverrides),
 		Strings: make(map[string]StringPropertyOverrides),
+		Bools:   make(map[string]BoolPropertyOverrides),
 	}
 
 	timeline, ok := doc.Timelines[timelineID]
@@ -44,6 +70,9 @@ func EvaluateTimeline(doc *document.InDocument, timelineID string, frame int) Ev
 		// Try numeric interpolation first
 		value := interpolateTrack(doc, &track, frame)
 		if value != nil {
+			if integerProperties[track.Property] {
+				*value = math.Floor(*value)
+			}
 			if result.Numeric[track.ObjectID] == nil {
 				result.Numeric[track.ObjectID] = make(PropertyOverrides)
 			}
@@ -51,7 +80,31 @@ func EvaluateTimeline(doc *document.InDocument, timelineID string, frame int) Ev
 			continue
 		}
 
-		// Fall back to string step interpolation (for colors etc.)
+		// Vector values ([x, y]) interpolate component-wise
+		if components, ok := vectorProperties[track.Property]; ok {
+			if values := interpolateVectorTrack(doc, &track, frame); values != nil {
+				if result.Numeric[track.ObjectID] == nil {
+					result.Numeric[track.ObjectID] = make(PropertyOverrides)
+				}
+				for i, path := range components {
+					if i < len(values) {
+						result.Numeric[track.ObjectID][path] = values[i]
+					}
+				}
+				continue
+			}
+		}
+
+		// Booleans step/hold like strings
+		if boolValue := interpolateBoolTrack(doc, &track, frame); boolValue != nil {
+			if result.Bools[track.ObjectID] == nil {
+				result.Bools[track.ObjectID] = make(BoolPropertyOverrides)
+			}
+			result.Bools[track.ObjectID][track.Property] = *boolValue
+			continue
+		}
+
+		// Fall back to strings: colors blend, anything else steps
 		strValue := interpolateStringTrack(doc, &track, frame)
 		if strValue != nil {
 			if result.Strings[track.ObjectID] == nil {
@@ -65,7 +118,7 @@ func EvaluateTimeline(doc *document.InDocument, timelineID string, frame int) Ev
 }
 
 // interpolateTrack evaluates a single track at the given frame.
-func interpolateTrack(doc *document.InDocument, track *document.Track, frame int) *float64 {
+func interpolateTrack(doc *document.InDocument, track *document.Track, frame float64) *float64 {
 	if len(track.Keys) == 0 {
 		return nil
 	}
@@ -89,10 +142,10 @@ func interpolateTrack(doc *document.InDocument, track *document.Track, frame int
 	// Find surrounding keyframes
 	var prev, next *document.Keyframe
 	for i := range keyframes {
-		if keyframes[i].Frame <= frame {
+		if float64(keyframes[i].Frame) <= frame {
 			prev = &keyframes[i]
 		}
-		if keyframes[i].Frame >= frame && next == nil {
+		if float64(keyframes[i].Frame) >= frame && next == nil {
 			next = &keyframes[i]
 		}
 	}
@@ -120,50 +173,193 @@ func interpolateTrack(doc *document.InDocument, track *document.Track, frame int
 	}
 
 	// Calculate interpolation factor
-	t := float64(frame-prev.Frame) / float64(next.Frame-prev.Frame)
-	t = applyEasing(t, prev.Easing)
+	t := (frame - float64(prev.Frame)) / float64(next.Frame-prev.Frame)
+	t = applyEasing(t, track.SegmentEasing(*prev, *next), doc.EasingPresets)
 
 	// Linear interpolation
 	result := *prevVal + (*nextVal-*prevVal)*t
 	return &result
 }
 
-// interpolateStringTrack evaluates a string track at the given frame using step/hold interpolation.
-// Returns the string value of the keyframe at or before the current frame.
-func interpolateStringTrack(doc *document.InDocument, track *document.Track, frame int) *string {
-	if len(track.Keys) == 0 {
+// interpolateStringTrack evaluates a string track at the given frame. Hex
+// colors (#rgb, #rrggbb, #rrggbbaa) blend between keyframes with the
+// segment's easing; other strings step/hold, taking the value of the
+// keyframe at or before the current frame.
+func interpolateStringTrack(doc *document.InDocument, track *document.Track, frame float64) *string {
+	keyframes := sortedKeyframes(doc, track)
+	if len(keyframes) == 0 {
 		return nil
 	}
 
+	var prev, next *document.Keyframe
+	for i := range keyframes {
+		if float64(keyframes[i].Frame) <= frame {
+			prev = &keyframes[i]
+		}
+		if float64(keyframes[i].Frame) >= frame && next == nil {
+			next = &keyframes[i]
+		}
+	}
+
+	// Before first keyframe — use first value
+	if prev == nil {
+		return parseStringKeyframeValue(keyframes[0].Value)
+	}
+	// After the last keyframe, or exactly on one
+	if next == nil || prev.Frame == next.Frame {
+		return parseStringKeyframeValue(prev.Value)
+	}
+
+	prevVal := parseStringKeyframeValue(prev.Value)
+	nextVal := parseStringKeyframeValue(next.Value)
+	if prevVal == nil || nextVal == nil {
+		return prevVal
+	}
+	from, ok := parseHexColor(*prevVal)
+	if !ok {
+		return prevVal
+	}
+	to, ok := parseHexColor(*nextVal)
+	if !ok {
+		return prevVal
+	}
+
+	t := (frame - float64(prev.Frame)) / float64(next.Frame-prev.Frame)
+	t = applyEasing(t, track.SegmentEasing(*prev, *next), doc.EasingPresets)
+	blended := lerpColor(from, to, t)
+	return &blended
+}
+
+// parseHexColor parses s if it is a #rgb, #rrggbb or #rrggbbaa color.
+func parseHexColor(s string) (color.RGBA, bool) {
+	if !strings.HasPrefix(s, "#") || (len(s) != 4 && len(s) != 7 && len(s) != 9) {
+		return color.RGBA{}, false
+	}
+	c, err := color.Parse(s)
+	return c, err == nil
+}
+
+// lerpColor blends from toward to by t and returns the result as
+// "#rrggbbaa". Color channels blend in linear RGB, so a midpoint is as bright
+// as the eye expects, and alpha blends directly. Easings that overshoot are
+// clamped to the channel range.
+func lerpColor(from, to color.RGBA, t float64) string {
+	mix := func(a, b uint8) uint8 {
+		la, lb := srgbToLinear(a), srgbToLinear(b)
+		return linearToSRGB(la + (lb-la)*t)
+	}
+	alpha := float64(from.A) + (float64(to.A)-float64(from.A))*t
+	return color.RGBA{
+		R: mix(from.R, to.R),
+		G: mix(from.G, to.G),
+		B: mix(from.B, to.B),
+		A: uint8(math.Round(math.Max(0, math.Min(255, alpha)))),
+	}.Hex()
+}
+
+// srgbToLinear converts an 8-bit sRGB channel to linear light in [0, 1].
+func srgbToLinear(c uint8) float64 {
+	v := float64(c) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// linearToSRGB converts linear light to an 8-bit sRGB channel, clamping it
+// to [0, 1] first.
+func linearToSRGB(v float64) uint8 {
+	v = math.Max(0, math.Min(1, v))
+	if v <= 0.0031308 {
+		v *= 12.92
+	} else {
+		v = 1.055*math.Pow(v, 1/2.4) - 0.055
+	}
+	return uint8(math.Round(v * 255))
+}
+
+// sortedKeyframes returns a track's keyframes ordered by frame.
+func sortedKeyframes(doc *document.InDocument, track *document.Track) []document.Keyframe {
 	keyframes := make([]document.Keyframe, 0, len(track.Keys))
 	for _, kfID := range track.Keys {
 		if kf, ok := doc.Keyframes[kfID]; ok {
 			keyframes = append(keyframes, kf)
 		}
 	}
+	sort.Slice(keyframes, func(i, j int) bool {
+		return keyframes[i].Frame < keyframes[j].Frame
+	})
+	return keyframes
+}
 
+// interpolateVectorTrack evaluates a track whose keyframe values are numeric
+// arrays, interpolating each component with the segment's easing. Keyframes of
+// different lengths interpolate over their common components.
+func interpolateVectorTrack(doc *document.InDocument, track *document.Track, frame float64) []float64 {
+	keyframes := sortedKeyframes(doc, track)
 	if len(keyframes) == 0 {
 		return nil
 	}
 
-	sort.Slice(keyframes, func(i, j int) bool {
-		return keyframes[i].Frame < keyframes[j].Frame
-	})
+	// Hold the first value before the first keyframe and the last value after the last
+	if frame <= float64(keyframes[0].Frame) {
+		return parseVectorKeyframeValue(keyframes[0].Value)
+	}
+	last := keyframes[len(keyframes)-1]
+	if frame >= float64(last.Frame) {
+		return parseVectorKeyframeValue(last.Value)
+	}
 
-	// Find the keyframe at or before the current frame (step/hold)
-	var prev *document.Keyframe
-	for i := range keyframes {
-		if keyframes[i].Frame <= frame {
-			prev = &keyframes[i]
+	i := sort.Search(len(keyframes), func(i int) bool { return float64(keyframes[i].Frame) > frame })
+	prev, next := keyframes[i-1], keyframes[i]
+	prevVal := parseVectorKeyframeValue(prev.Value)
+	nextVal := parseVectorKeyframeValue(next.Value)
+	if prevVal == nil || nextVal == nil || prev.Frame == next.Frame {
+		return prevVal
+	}
+
+	t := (frame - float64(prev.Frame)) / float64(next.Frame-prev.Frame)
+	t = applyEasing(t, track.SegmentEasing(prev, next), doc.EasingPresets)
+
+	result := make([]float64, min(len(prevVal), len(nextVal)))
+	for c := range result {
+		result[c] = prevVal[c] + (nextVal[c]-prevVal[c])*t
+	}
+	return result
+}
+
+// interpolateBoolTrack evaluates a boolean track using step/hold interpolation.
+func interpolateBoolTrack(doc *document.InDocument, track *document.Track, frame float64) *bool {
+	keyframes := sortedKeyframes(doc, track)
+	if len(keyframes) == 0 {
+		return nil
+	}
+
+	current := keyframes[0]
+	for _, kf := range keyframes {
+		if float64(kf.Frame) <= frame {
+			current = kf
 		}
 	}
+	return parseBoolKeyframeValue(current.Value)
+}
 
-	// Before first keyframe — use first value
-	if prev == nil {
-		return parseStringKeyframeValue(keyframes[0].Value)
+// parseVectorKeyframeValue extracts a numeric array from a keyframe's JSON value.
+func parseVectorKeyframeValue(raw json.RawMessage) []float64 {
+	var v []float64
+	if err := json.Unmarshal(raw, &v); err != nil || len(v) == 0 {
+		return nil
 	}
+	return v
+}
 
-	return parseStringKeyframeValue(prev.Value)
+// parseBoolKeyframeValue extracts a boolean from a keyframe's JSON value.
+func parseBoolKeyframeValue(raw json.RawMessage) *bool {
+	var v bool
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil
+	}
+	return &v
 }
 
 // parseStringKeyframeValue extracts a string from a keyframe's JSON value.
@@ -184,9 +380,34 @@ func parseKeyframeValue(raw json.RawMessage) *float64 {
 	return &v
 }
 
-// applyEasing applies an easing function to interpolation factor t (0-1).
-func applyEasing(t float64, easing document.EasingType) float64 {
+// applyEasing applies the easing of the segment starting at kf to
+// interpolation factor t (0-1). Preset references resolve against presets; a
+// missing preset is linear. kf's EasingParams tune springs (see
+// document.EasingType.Spring), and its Bezier shapes cubicBezier, which is
+// linear if the control points are missing or degenerate.
+func applyEasing(t float64, kf document.Keyframe, presets map[string]document.EasingPreset) float64 {
+	easing := kf.Easing
+	if name, ok := easing.PresetName(); ok {
+		if p, ok := presets[name]; ok {
+			return cubicBezier(t, p.X1, p.Y1, p.X2, p.Y2)
+		}
+		return t
+	}
+	if spring, ok := easing.Spring(kf.EasingParams); ok {
+		if spring.Reversed {
+			return 1 - springEase(1-t, spring)
+		}
+		return springEase(t, spring)
+	}
+
 	switch easing {
+	case document.EasingCubicBezier:
+		if kf.Bezier == nil || !document.ValidBezier(*kf.Bezier) {
+			return t
+		}
+		b := kf.Bezier
+		return cubicBezier(t, b[0], b[1], b[2], b[3])
+
 	case document.EasingEaseIn:
 		return t * t
 
@@ -247,6 +468,53 @@ func applyEasing(t float64, easing document.EasingType) float64 {
 	}
 }
 
+// cubicBezier evaluates a CSS-style cubic-bezier timing curve with endpoints
+// (0,0) and (1,1). It solves x(s) = t for the curve parameter s with Newton's
+// method, falling back to bisection where the slope is too flat, then returns y(s).
+func cubicBezier(t, x1, y1, x2, y2 float64) float64 {
+	if t <= 0 || t >= 1 {
+		return t
+	}
+
+	// Polynomial coefficients: b(s) = ((a*s + b)*s + c)*s
+	cx := 3 * x1
+	bx := 3*(x2-x1) - cx
+	ax := 1 - cx - bx
+	cy := 3 * y1
+	by := 3*(y2-y1) - cy
+	ay := 1 - cy - by
+
+	sampleX := func(s float64) float64 { return ((ax*s+bx)*s + cx) * s }
+	sampleY := func(s float64) float64 { return ((ay*s+by)*s + cy) * s }
+	slopeX := func(s float64) float64 { return (3*ax*s+2*bx)*s + cx }
+
+	const epsilon = 1e-7
+	s := t
+	for i := 0; i < 8; i++ {
+		dx := sampleX(s) - t
+		if math.Abs(dx) < epsilon {
+			return sampleY(s)
+		}
+		d := slopeX(s)
+		if math.Abs(d) < 1e-6 {
+			break
+		}
+		s -= dx / d
+	}
+
+	lo, hi := 0.0, 1.0
+	s = t
+	for i := 0; i < 64 && hi-lo > epsilon; i++ {
+		if sampleX(s) < t {
+			lo = s
+		} else {
+			hi = s
+		}
+		s = (lo + hi) / 2
+	}
+	return sampleY(s)
+}
+
 // bounceOut implements the standard 4-segment parabolic bounce curve.
 func bounceOut(t float64) float64 {
 	n1 := 7.5625
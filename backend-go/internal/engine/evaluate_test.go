@@ -0,0 +1,367 @@
+package engine
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// rectDoc returns a document whose root holds a rect at the origin, and the
+// rect's ID.
+func rectDoc() (*document.InDocument, string) {
+	rootID, rectID := typeid.NewObjectID(), typeid.NewObjectID()
+	doc := document.NewEmptyDocument(typeid.NewProjectID(), "Rect", typeid.NewSceneID(), rootID, typeid.NewTimelineID())
+	addChild(doc, rootID, document.ObjectNode{
+		ID:        rectID,
+		Type:      document.ObjectTypeShapeRect,
+		Transform: document.Transform{SX: 1, SY: 1},
+		Style:     document.Style{Fill: "#ff0000", Opacity: 1},
+		Visible:   true,
+		Data:      json.RawMessage(`{"width":40,"height":30}`),
+	})
+	return doc, rectID
+}
+
+// easedDoc returns a rect document whose rect moves x from 0 to 100 over
+// frames 0 to 10, with easing on the first keyframe, and the rect's ID.
+func easedDoc(easing document.EasingType) (*document.InDocument, string) {
+	doc, rectID := rectDoc()
+	animate(doc, rectID, "transform.x", []int{0, 10}, []float64{0, 100})
+	for _, track := range doc.Tracks {
+		kf := doc.Keyframes[track.Keys[0]]
+		kf.Easing = easing
+		doc.Keyframes[kf.ID] = kf
+	}
+	return doc, rectID
+}
+
+func xAt(doc *document.InDocument, rectID string, frame float64) float64 {
+	return EvaluateTimeline(doc, doc.Project.RootTimeline, frame).Numeric[rectID]["transform.x"]
+}
+
+func TestEasingPresetResolved(t *testing.T) {
+	doc, rectID := easedDoc(document.EasingPresetPrefix + "snappy")
+	doc.EasingPresets = map[string]document.EasingPreset{
+		"snappy": {X1: 0.2, Y1: 0.9, X2: 0.3, Y2: 1},
+	}
+
+	want := 100 * cubicBezier(0.5, 0.2, 0.9, 0.3, 1)
+	if got := xAt(doc, rectID, 5); !near(got, want) {
+		t.Errorf("x at frame 5 = %v, want %v", got, want)
+	}
+
+	// Editing the preset changes every keyframe that uses it
+	doc.EasingPresets["snappy"] = document.EasingPreset{X1: 0.7, Y1: 0, X2: 0.8, Y2: 0.1}
+	want = 100 * cubicBezier(0.5, 0.7, 0, 0.8, 0.1)
+	if got := xAt(doc, rectID, 5); !near(got, want) {
+		t.Errorf("x at frame 5 after editing the preset = %v, want %v", got, want)
+	}
+	if want >= 50 {
+		t.Fatalf("edited preset eases to %v at the midpoint, want below linear", want)
+	}
+}
+
+func TestMissingEasingPresetIsLinear(t *testing.T) {
+	tests := []struct {
+		name    string
+		presets map[string]document.EasingPreset
+	}{
+		{"no presets", nil},
+		{"other presets", map[string]document.EasingPreset{"other": {X1: 0.9, Y1: 0, X2: 1, Y2: 0}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, rectID := easedDoc(document.EasingPresetPrefix + "deleted")
+			doc.EasingPresets = tt.presets
+			for _, frame := range []float64{0, 2.5, 5, 10} {
+				if got := xAt(doc, rectID, frame); !near(got, frame*10) {
+					t.Errorf("x at frame %v = %v, want %v", frame, got, frame*10)
+				}
+			}
+		})
+	}
+}
+
+func TestGetEasingPresets(t *testing.T) {
+	e := NewEngine()
+	if got := e.GetEasingPresets(); got != "{}" {
+		t.Errorf("without a document: %s, want {}", got)
+	}
+
+	doc, _ := rectDoc()
+	doc.EasingPresets = map[string]document.EasingPreset{"snappy": {X1: 0.2, Y1: 0.9, X2: 0.3, Y2: 1}}
+	e.ReplaceDocument(doc)
+	if got, want := e.GetEasingPresets(), `{"snappy":{"x1":0.2,"y1":0.9,"x2":0.3,"y2":1}}`; got != want {
+		t.Errorf("GetEasingPresets = %s, want %s", got, want)
+	}
+}
+
+func TestVectorKeyframesInterpolateComponents(t *testing.T) {
+	doc, rectID := rectDoc()
+	keyTrack(doc, doc.Project.RootTimeline, rectID, "transform.position", []int{10, 20}, []string{`[0, 100]`, `[50, 300]`})
+
+	eval := EvaluateTimeline(doc, doc.Project.RootTimeline, 15)
+	got := eval.Numeric[rectID]
+	if !near(got["transform.x"], 25) || !near(got["transform.y"], 200) {
+		t.Errorf("position at frame 15 = (%v, %v), want (25, 200)", got["transform.x"], got["transform.y"])
+	}
+	if _, ok := got["transform.position"]; ok {
+		t.Error("vector value routed to its own property rather than its components")
+	}
+
+	node := buildAt(doc, 15).NodesById[rectID]
+	if node == nil || !near(node.LocalTransform[4], 25) || !near(node.LocalTransform[5], 200) {
+		t.Errorf("rect node %+v, want translated to (25, 200)", node)
+	}
+}
+
+func TestBooleanKeyframesStep(t *testing.T) {
+	doc, rectID := rectDoc()
+	keyTrack(doc, doc.Project.RootTimeline, rectID, "visible", []int{0, 10, 20}, []string{`true`, `false`, `true`})
+
+	tests := []struct {
+		frame float64
+		want  bool
+	}{
+		{0, true},
+		{9.9, true},
+		{10, false},
+		{15, false},
+		{20, true},
+	}
+	for _, tt := range tests {
+		eval := EvaluateTimeline(doc, doc.Project.RootTimeline, tt.frame)
+		got, ok := eval.Bools[rectID]["visible"]
+		if !ok || got != tt.want {
+			t.Errorf("visible at frame %v = %v (set %v), want %v", tt.frame, got, ok, tt.want)
+		}
+		if _, ok := eval.Strings[rectID]["visible"]; ok {
+			t.Errorf("frame %v: boolean value also routed as a string", tt.frame)
+		}
+		if shown := buildAt(doc, tt.frame).NodesById[rectID] != nil; shown != tt.want {
+			t.Errorf("rect in the scene graph at frame %v: %v, want %v", tt.frame, shown, tt.want)
+		}
+	}
+}
+
+func TestFractionalFrames(t *testing.T) {
+	doc, rectID := rectDoc()
+	animate(doc, rectID, "transform.x", []int{0, 10}, []float64{0, 100})
+	keyTrack(doc, doc.Project.RootTimeline, rectID, "style.fill", []int{0, 10}, []string{`"#000000"`, `"#ffffff"`})
+	keyTrack(doc, doc.Project.RootTimeline, rectID, "data.content", []int{0, 5}, []string{`"hello"`, `"world"`})
+
+	if x := xAt(doc, rectID, 2.5); x != 25 {
+		t.Errorf("x at frame 2.5 = %v, want 25", x)
+	}
+	// Between whole frames the color blends; text holds until its next key
+	eval := EvaluateTimeline(doc, doc.Project.RootTimeline, 4.5)
+	if fill := eval.Strings[rectID]["style.fill"]; fill == "#000000" || fill == "#ffffff" {
+		t.Errorf("fill at frame 4.5 = %s, want a blend", fill)
+	}
+	if text := eval.Strings[rectID]["data.content"]; text != "hello" {
+		t.Errorf("text at frame 4.5 = %q, want hello", text)
+	}
+
+	// Whole frames and keyframe hits evaluate exactly as before
+	for _, frame := range []int{0, 3, 5, 10, 12} {
+		got := EvaluateTimeline(doc, doc.Project.RootTimeline, float64(frame))
+		if x := got.Numeric[rectID]["transform.x"]; x != math.Min(float64(frame)*10, 100) {
+			t.Errorf("x at frame %d = %v", frame, x)
+		}
+		wantText := "hello"
+		if frame >= 5 {
+			wantText = "world"
+		}
+		if text := got.Strings[rectID]["data.content"]; text != wantText {
+			t.Errorf("text at frame %d = %q, want %q", frame, text, wantText)
+		}
+	}
+}
+
+func TestColorTracksInterpolate(t *testing.T) {
+	fillAt := func(values []string, easing document.EasingType, frame float64) string {
+		doc, rectID := rectDoc()
+		trackID := keyTrack(doc, doc.Project.RootTimeline, rectID, "style.fill", []int{0, 10}, values)
+		for _, id := range doc.Tracks[trackID].Keys {
+			kf := doc.Keyframes[id]
+			kf.Easing = easing
+			doc.Keyframes[id] = kf
+		}
+		return EvaluateTimeline(doc, doc.Project.RootTimeline, frame).Strings[rectID]["style.fill"]
+	}
+
+	tests := []struct {
+		name   string
+		values []string
+		easing document.EasingType
+		frame  float64
+		want   string
+	}{
+		// Linear light 0.5 is sRGB 188, brighter than the component-wise 128
+		{"black to white", []string{`"#000000"`, `"#ffffff"`}, document.EasingLinear, 5, "#bcbcbcff"},
+		{"short form", []string{`"#000"`, `"#fff"`}, document.EasingLinear, 5, "#bcbcbcff"},
+		{"alpha blends directly", []string{`"#ff000000"`, `"#ff0000ff"`}, document.EasingLinear, 5, "#ff000080"},
+		{"start", []string{`"#000000"`, `"#ffffff"`}, document.EasingLinear, 0, "#000000"},
+		{"end", []string{`"#000000"`, `"#ffffff"`}, document.EasingLinear, 10, "#ffffff"},
+		{"overshoot clamps", []string{`"#000000"`, `"#ffffff"`}, document.EasingBackOut, 7, "#ffffffff"},
+		{"named values step", []string{`"red"`, `"blue"`}, document.EasingLinear, 5, "red"},
+		{"named value steps into a color", []string{`"red"`, `"#0000ff"`}, document.EasingLinear, 5, "red"},
+		{"named value steps at its key", []string{`"red"`, `"blue"`}, document.EasingLinear, 10, "blue"},
+	}
+	for _, tt := range tests {
+		if got := fillAt(tt.values, tt.easing, tt.frame); got != tt.want {
+			t.Errorf("%s: fill at frame %v = %s, want %s", tt.name, tt.frame, got, tt.want)
+		}
+	}
+}
+
+func TestRenderAtTime(t *testing.T) {
+	s := newSpinner()
+	e := s.engine()
+
+	// On a whole frame, the render matches playback at that frame
+	e.Play()
+	e.SetPlayhead(6)
+	if got, want := e.RenderAtTime(0.25), e.Render(); got != want {
+		t.Errorf("render at 0.25s differs from frame 6:\n got %s\nwant %s", got, want)
+	}
+	if e.GetFrame() != 6 {
+		t.Errorf("rendering at a time moved the playhead to %d", e.GetFrame())
+	}
+
+	// Between frames it samples the motion rather than repeating a frame
+	between := e.RenderAtTime(6.5 / 24)
+	e.SetPlayhead(7)
+	if between == e.Render() || between == e.RenderAtTime(0.25) {
+		t.Error("render between frames 6 and 7 matches one of them")
+	}
+
+	if got := NewEngine().RenderAtTime(1); got != "[]" {
+		t.Errorf("render without a document = %s", got)
+	}
+}
+
+func bezierKey(points ...float64) document.Keyframe {
+	kf := document.Keyframe{Easing: document.EasingCubicBezier}
+	if len(points) == 4 {
+		b := [4]float64(points)
+		kf.Bezier = &b
+	}
+	return kf
+}
+
+// TestCubicBezierCSSReference checks curves against the values browsers give
+// the CSS timing functions.
+func TestCubicBezierCSSReference(t *testing.T) {
+	tests := []struct {
+		name   string
+		points [4]float64
+		want   [3]float64 // At t = 0.25, 0.5 and 0.75
+	}{
+		{"ease", [4]float64{0.25, 0.1, 0.25, 1}, [3]float64{0.408511, 0.802403, 0.960459}},
+		{"ease-in", [4]float64{0.42, 0, 1, 1}, [3]float64{0.093465, 0.315357, 0.621862}},
+		{"ease-out", [4]float64{0, 0, 0.58, 1}, [3]float64{0.378138, 0.684643, 0.906535}},
+		{"ease-in-out", [4]float64{0.42, 0, 0.58, 1}, [3]float64{0.129162, 0.5, 0.870838}},
+		{"back", [4]float64{0.68, -0.55, 0.265, 1.55}, [3]float64{-0.082807, 0.60668, 1.089166}},
+	}
+	for _, tt := range tests {
+		kf := bezierKey(tt.points[:]...)
+		for i, at := range []float64{0.25, 0.5, 0.75} {
+			if got := applyEasing(at, kf, nil); math.Abs(got-tt.want[i]) > 1e-5 {
+				t.Errorf("%s at %v = %v, want %v", tt.name, at, got, tt.want[i])
+			}
+		}
+		for _, at := range []float64{0, 1} {
+			if got := applyEasing(at, kf, nil); got != at {
+				t.Errorf("%s at %v = %v, want the endpoint", tt.name, at, got)
+			}
+		}
+	}
+
+	// On a keyframe, the curve shapes the segment it starts
+	doc, rectID := easedDoc(document.EasingCubicBezier)
+	for _, track := range doc.Tracks {
+		kf := doc.Keyframes[track.Keys[0]]
+		kf.Bezier = &[4]float64{0.42, 0, 1, 1}
+		doc.Keyframes[kf.ID] = kf
+	}
+	if got := xAt(doc, rectID, 5); math.Abs(got-31.5357) > 1e-3 {
+		t.Errorf("x at frame 5 = %v, want ease-in's 31.5357", got)
+	}
+}
+
+func TestCubicBezierDegenerate(t *testing.T) {
+	// Missing or invalid control points are linear
+	for name, kf := range map[string]document.Keyframe{
+		"missing":   bezierKey(),
+		"x1 over 1": bezierKey(1.5, 0, 0.5, 1),
+		"x2 below":  bezierKey(0.5, 0, -0.1, 1),
+		"NaN":       bezierKey(0.5, math.NaN(), 0.5, 1),
+		"infinite":  bezierKey(0.5, 0, 0.5, math.Inf(1)),
+	} {
+		for _, at := range []float64{0.1, 0.5, 0.9} {
+			if got := applyEasing(at, kf, nil); got != at {
+				t.Errorf("%s at %v = %v, want linear", name, at, got)
+			}
+		}
+	}
+
+	// Flat stretches, where Newton's method stalls, still converge onto a
+	// monotonic curve
+	for _, points := range [][4]float64{{1, 0, 0, 1}, {0, 1, 1, 0}, {1, 1, 1, 1}, {0, 0, 0, 0}} {
+		kf := bezierKey(points[:]...)
+		prev := math.Inf(-1)
+		for i := 1; i < 100; i++ {
+			got := applyEasing(float64(i)/100, kf, nil)
+			if math.IsNaN(got) || got < prev-1e-9 || got < 0 || got > 1 {
+				t.Fatalf("%v at %v = %v after %v", points, float64(i)/100, got, prev)
+			}
+			prev = got
+		}
+	}
+	// The symmetric ease-in-out crosses the middle exactly
+	if got := applyEasing(0.5, bezierKey(1, 0, 0, 1), nil); math.Abs(got-0.5) > 1e-6 {
+		t.Errorf("steep symmetric curve at 0.5 = %v, want 0.5", got)
+	}
+}
+
+func TestTrackEasingMode(t *testing.T) {
+	easeIn := func(t float64) float64 { return t * t }
+	easeOut := func(t float64) float64 { return t * (2 - t) }
+	tests := []struct {
+		mode document.EasingMode
+		ease func(float64) float64
+	}{
+		{"", easeIn}, // Existing documents keep outgoing easing
+		{document.EasingOutgoing, easeIn},
+		{document.EasingIncoming, easeOut},
+	}
+	for _, tt := range tests {
+		doc, rectID := rectDoc()
+		r := keyTrack(doc, doc.Project.RootTimeline, rectID, "transform.r", []int{0, 10}, []string{`0`, `100`})
+		pos := keyTrack(doc, doc.Project.RootTimeline, rectID, "transform.position", []int{0, 10}, []string{`[0, 0]`, `[0, 50]`})
+		// Ease in out of the first keyframe, ease out into the second
+		for _, id := range []string{r, pos} {
+			track := doc.Tracks[id]
+			track.EasingMode = tt.mode
+			doc.Tracks[id] = track
+			first, second := doc.Keyframes[track.Keys[0]], doc.Keyframes[track.Keys[1]]
+			first.Easing, second.Easing = document.EasingEaseIn, document.EasingEaseOut
+			doc.Keyframes[first.ID], doc.Keyframes[second.ID] = first, second
+		}
+
+		for _, frame := range []float64{0, 2.5, 5, 7.5, 10} {
+			got := EvaluateTimeline(doc, doc.Project.RootTimeline, frame).Numeric[rectID]
+			want := tt.ease(frame / 10)
+			if !near(got["transform.r"], 100*want) {
+				t.Errorf("%q: r at frame %v = %v, want %v", tt.mode, frame, got["transform.r"], 100*want)
+			}
+			if !near(got["transform.y"], 50*want) {
+				t.Errorf("%q: position y at frame %v = %v, want %v", tt.mode, frame, got["transform.y"], 50*want)
+			}
+		}
+	}
+}
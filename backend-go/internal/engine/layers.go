@@ -0,0 +1,56 @@
+package engine
+
+import "github.com/inamate/inamate/backend-go/internal/document"
+
+// LayerNode is an object as the layers panel shows it.
+type LayerNode struct {
+	ID       string              `json:"id"`
+	Type     document.ObjectType `json:"type"`
+	Name     string              `json:"name"`
+	Visible  bool                `json:"visible"`
+	Locked   bool                `json:"locked"`
+	Children []*LayerNode        `json:"children"`
+}
+
+// BuildLayerTree returns the objects under a scene's root, nested as in the
+// document and in render order (back to front). An object's name is its
+// document.ObjectNode.Name, or its type when it has none.
+func BuildLayerTree(doc *document.InDocument, sceneID string) []*LayerNode {
+	layers := make([]*LayerNode, 0)
+	scene, ok := doc.Scenes[sceneID]
+	if !ok {
+		return layers
+	}
+	root, ok := doc.Objects[scene.Root]
+	if !ok {
+		return layers
+	}
+	visited := map[string]bool{root.ID: true}
+	return appendLayers(doc, layers, root.Children, visited)
+}
+
+// appendLayers appends the layers of the objects with the given IDs, and of
+// their descendants, skipping missing objects and any already visited.
+func appendLayers(doc *document.InDocument, layers []*LayerNode, ids []string, visited map[string]bool) []*LayerNode {
+	for _, id := range ids {
+		obj, ok := doc.Objects[id]
+		if !ok || visited[id] {
+			continue
+		}
+		visited[id] = true
+
+		name := obj.Name()
+		if name == "" {
+			name = string(obj.Type)
+		}
+		layers = append(layers, &LayerNode{
+			ID:       obj.ID,
+			Type:     obj.Type,
+			Name:     name,
+			Visible:  obj.Visible,
+			Locked:   obj.Locked,
+			Children: appendLayers(doc, make([]*LayerNode, 0), obj.Children, visited),
+		})
+	}
+	return layers
+}
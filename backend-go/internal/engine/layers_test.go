@@ -0,0 +1,65 @@
+package engine
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+func TestGetLayerTree(t *testing.T) {
+	s := newSpinner()
+	// The spinner gets a second child, a named, locked and hidden hub
+	hubID := typeid.NewObjectID()
+	addChild(s.doc, s.symbolID, document.ObjectNode{
+		ID:        hubID,
+		Type:      document.ObjectTypeShapeEllipse,
+		Transform: document.Transform{SX: 1, SY: 1},
+		Style:     document.Style{Fill: "#000000", Opacity: 1},
+		Locked:    true,
+		Data:      json.RawMessage(`{"rx":4,"ry":4}`),
+		Meta:      json.RawMessage(`{"name":"Hub"}`),
+	})
+
+	var layers []LayerNode
+	if err := json.Unmarshal([]byte(s.engine().GetLayerTree("")), &layers); err != nil {
+		t.Fatal(err)
+	}
+	if len(layers) != 1 || layers[0].ID != s.symbolID || layers[0].Type != document.ObjectTypeSymbol {
+		t.Fatalf("layers %+v, want just the spinner", layers)
+	}
+	spinner := layers[0]
+	if spinner.Name != string(document.ObjectTypeSymbol) || !spinner.Visible || spinner.Locked {
+		t.Errorf("spinner %+v, want named by its type, visible and unlocked", spinner)
+	}
+	if len(spinner.Children) != 2 {
+		t.Fatalf("spinner has %d children, want 2", len(spinner.Children))
+	}
+	// Children in render order, back to front
+	rect, hub := spinner.Children[0], spinner.Children[1]
+	if rect.ID != s.rectID || rect.Name != string(document.ObjectTypeShapeRect) || rect.Children == nil || len(rect.Children) != 0 {
+		t.Errorf("first child %+v, want the rect with no children", rect)
+	}
+	if hub.ID != hubID || hub.Name != "Hub" || !hub.Locked || hub.Visible {
+		t.Errorf("second child %+v, want the named, locked, hidden hub", hub)
+	}
+}
+
+func TestGetLayerTreeEmpty(t *testing.T) {
+	if got := NewEngine().GetLayerTree(""); got != "[]" {
+		t.Errorf("without a document: %s, want []", got)
+	}
+	s := newSpinner()
+	if got := s.engine().GetLayerTree("scene_missing"); got != "[]" {
+		t.Errorf("missing scene: %s, want []", got)
+	}
+
+	// An object listed twice is shown once
+	root := s.doc.Objects[s.doc.Scenes[s.doc.Project.Scenes[0]].Root]
+	root.Children = append(root.Children, s.symbolID, "obj_missing")
+	s.doc.Objects[root.ID] = root
+	if layers := BuildLayerTree(s.doc, s.doc.Project.Scenes[0]); len(layers) != 1 {
+		t.Errorf("%d top-level layers, want the spinner once", len(layers))
+	}
+}
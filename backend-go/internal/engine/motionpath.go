@@ -0,0 +1,119 @@
+package engine
+
+import "github.com/inamate/inamate/backend-go/internal/document"
+
+// maxMotionPathSamples caps the points a motion path is sampled at; longer
+// ranges are sampled at a coarser step.
+const maxMotionPathSamples = 1000
+
+// MotionPathPoint is where an object's anchor sits in scene space at a frame.
+// Keyframe marks frames where a transform keyframe of the object or one of
+// its ancestors sits.
+type MotionPathPoint struct {
+	Frame    int     `json:"frame"`
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+	Keyframe bool    `json:"keyframe,omitempty"`
+}
+
+// MotionPath samples an object's animated world position on a timeline from
+// startFrame to endFrame (clamped to the timeline) every step frames, plus
+// the range's last frame and every keyframe frame inside it, so the curve
+// passes through its keys. Symbols among the object and its ancestors play
+// their own timelines at each frame, as they do in playback.
+func MotionPath(doc *document.InDocument, objectID, timelineID string, startFrame, endFrame, step int) []MotionPathPoint {
+	points := make([]MotionPathPoint, 0)
+	timeline, ok := doc.Timelines[timelineID]
+	if !ok {
+		return points
+	}
+	if _, ok := doc.Objects[objectID]; !ok {
+		return points
+	}
+	startFrame = max(startFrame, 0)
+	endFrame = min(endFrame, timeline.Length-1)
+	if endFrame < startFrame {
+		return points
+	}
+	step = max(step, 1, (endFrame-startFrame+maxMotionPathSamples-1)/maxMotionPathSamples)
+
+	// The object and its ancestors, root first
+	var chain []*document.ObjectNode
+	for id := objectID; len(chain) <= len(doc.Objects); {
+		obj, ok := doc.Objects[id]
+		if !ok {
+			break
+		}
+		chain = append([]*document.ObjectNode{&obj}, chain...)
+		if obj.Parent == nil {
+			break
+		}
+		id = *obj.Parent
+	}
+
+	// Frames keyed per timeline, for the markers
+	inChain := make(map[string]bool, len(chain))
+	for _, obj := range chain {
+		inChain[obj.ID] = true
+	}
+	keyed := make(map[string]map[int]bool)
+	for tlID, tl := range doc.Timelines {
+		for _, trackID := range tl.Tracks {
+			track, ok := doc.Tracks[trackID]
+			if !ok || !inChain[track.ObjectID] || !IsTransformProperty(track.Property) {
+				continue
+			}
+			for _, keyID := range track.Keys {
+				if kf, ok := doc.Keyframes[keyID]; ok {
+					if keyed[tlID] == nil {
+						keyed[tlID] = make(map[int]bool)
+					}
+					keyed[tlID][kf.Frame] = true
+				}
+			}
+		}
+	}
+
+	// Symbols play their own timelines, except one being sampled
+	var symbols []*document.ObjectNode
+	for _, obj := range chain {
+		if obj.Type == document.ObjectTypeSymbol {
+			if tlID := GetSymbolTimelineID(obj.Data); tlID != "" && tlID != timelineID {
+				symbols = append(symbols, obj)
+			}
+		}
+	}
+	keyedAt := func(f int) bool {
+		if keyed[timelineID][f] {
+			return true
+		}
+		for _, obj := range symbols {
+			symData := ParseSymbolData(obj.Data)
+			symFrame := f
+			if tl, ok := doc.Timelines[symData.TimelineID]; ok && symData.Loop && tl.Length > 0 {
+				symFrame = f % tl.Length
+			}
+			if keyed[symData.TimelineID][symFrame] {
+				return true
+			}
+		}
+		return false
+	}
+
+	for f := startFrame; f <= endFrame; f++ {
+		isKey := keyedAt(f)
+		if (f-startFrame)%step != 0 && f != endFrame && !isKey {
+			continue
+		}
+		eval := EvaluateTimeline(doc, timelineID, float64(f))
+		for _, obj := range symbols {
+			mergeSymbolTimeline(doc, obj, float64(f), eval)
+		}
+		// The anchor lands where the object's position puts it in its
+		// parent's space
+		t := ApplyOverridesToTransform(chain[len(chain)-1].Transform, eval.Numeric[objectID])
+		x, y := objectWorldMatrix(doc, objectID, eval).TransformPoint(t.AX, t.AY)
+		points = append(points, MotionPathPoint{Frame: f, X: x, Y: y, Keyframe: isKey})
+	}
+	return points
+}
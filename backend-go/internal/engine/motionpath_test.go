@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestMotionPathInSymbol(t *testing.T) {
+	// The spinner's rect also slides right 48px over each 24 frame loop of
+	// its Symbol, which sits at (100, 100)
+	s := newSpinner()
+	animateIn(s.doc, s.timelineID, s.rectID, "transform.x", []int{0, 24}, []float64{0, 48})
+
+	got := MotionPath(s.doc, s.rectID, s.doc.Project.RootTimeline, 0, 47, 12)
+	want := []MotionPathPoint{
+		{Frame: 0, X: 100, Y: 100, Keyframe: true},
+		{Frame: 12, X: 124, Y: 100},
+		{Frame: 24, X: 100, Y: 100, Keyframe: true}, // The loop starts over
+		{Frame: 36, X: 124, Y: 100},
+		{Frame: 47, X: 146, Y: 100},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("path %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i].Frame != want[i].Frame || !near(got[i].X, want[i].X) || !near(got[i].Y, want[i].Y) || got[i].Keyframe != want[i].Keyframe {
+			t.Errorf("point %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestMotionPathFollowsAncestors(t *testing.T) {
+	// The rect sits 50px right of the Symbol's origin while the Symbol
+	// turns a quarter on the root timeline, swinging the rect below it
+	s := newSpinner()
+	rect := s.doc.Objects[s.rectID]
+	rect.Transform.X = 50
+	s.doc.Objects[s.rectID] = rect
+	animate(s.doc, s.symbolID, "transform.r", []int{0, 40}, []float64{0, 90})
+	e := s.engine()
+
+	var points []MotionPathPoint
+	if err := json.Unmarshal([]byte(e.GetMotionPath(s.rectID, "", -10, 100, 20)), &points); err != nil {
+		t.Fatal(err)
+	}
+	// Sampled every 20 frames of the clamped range 0-47, plus the last
+	// frame and the keyframes: the Symbol's at 40 and the rect's own
+	// rotation key at 24, where its loop starts over
+	frames := []int{0, 20, 24, 40, 47}
+	if len(points) != len(frames) {
+		t.Fatalf("path %+v, want frames %v", points, frames)
+	}
+	for i, f := range frames {
+		if points[i].Frame != f {
+			t.Errorf("point %d at frame %d, want %d", i, points[i].Frame, f)
+		}
+	}
+	angle := func(deg float64) (float64, float64) {
+		rad := deg * math.Pi / 180
+		return 100 + 50*math.Cos(rad), 100 + 50*math.Sin(rad)
+	}
+	for i, deg := range []float64{0, 45, 54, 90, 90} {
+		x, y := angle(deg)
+		if math.Abs(points[i].X-x) > 0.01 || math.Abs(points[i].Y-y) > 0.01 {
+			t.Errorf("frame %d at (%v, %v), want (%.2f, %.2f)", points[i].Frame, points[i].X, points[i].Y, x, y)
+		}
+	}
+	for i, key := range []bool{true, false, true, true, false} {
+		if points[i].Keyframe != key {
+			t.Errorf("frame %d keyframe = %v, want %v", points[i].Frame, points[i].Keyframe, key)
+		}
+	}
+
+	if got := e.GetMotionPath("obj_missing", "", 0, 47, 1); got != "[]" {
+		t.Errorf("path of a missing object = %s, want []", got)
+	}
+}
@@ -0,0 +1,118 @@
+package engine
+
+import (
+	"github.com/inamate/inamate/backend-go/internal/document"
+)
+
+// Safe-area guide styling
+const (
+	actionSafeColor = "#00c8ff"
+	titleSafeColor  = "#ff9f1c"
+	guideAlpha      = 0.8
+	guideWidth      = 1.0
+)
+
+// SetSafeAreaGuides shows or hides the safe-area guides in RenderOverlay.
+func (e *Engine) SetSafeAreaGuides(enabled bool) {
+	e.safeAreaGuides = enabled
+}
+
+// SetSafeAreaPercent sets the size of the safe areas of scenes that don't
+// set their own, as the percentage of the scene each keeps (see
+// document.SafeAreaPercent). The default is the broadcast standard.
+func (e *Engine) SetSafeAreaPercent(action, title float64) error {
+	p := document.SafeAreaPercent{Action: action, Title: title}
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	e.safeAreaPercent = p
+	return nil
+}
+
+// SafeAreas are a scene's action-safe and title-safe areas in scene
+// coordinates.
+type SafeAreas struct {
+	Action Rect
+	Title  Rect
+}
+
+// GetSafeAreas returns a scene's safe areas as JSON rects, or "{}" if there
+// is no such scene. An empty sceneID means the current scene.
+func (e *Engine) GetSafeAreas(sceneID string) string {
+	if sceneID == "" {
+		sceneID = e.sceneID
+	}
+	if e.doc == nil {
+		return "{}"
+	}
+	scene, ok := e.doc.Scenes[sceneID]
+	if !ok {
+		return "{}"
+	}
+	areas := SafeAreaRects(scene, scene.SafeAreaWith(e.safeAreaPercent))
+	return `{"action":` + RectToJSON(areas.Action) + `,"title":` + RectToJSON(areas.Title) + `}`
+}
+
+// SafeAreaRects returns the rects a scene's safe-area insets enclose.
+func SafeAreaRects(scene document.Scene, safe document.SafeAreaInsets) SafeAreas {
+	return SafeAreas{
+		Action: InsetRect(scene.Width, scene.Height, safe.Action),
+		Title:  InsetRect(scene.Width, scene.Height, safe.Title),
+	}
+}
+
+// RenderOverlay returns draw commands for editor guides drawn over the scene,
+// as JSON. Like Render's commands they are in scene coordinates, so they
+// scale with the viewport transform the frontend applies.
+func (e *Engine) RenderOverlay() string {
+	if e.doc == nil || !e.safeAreaGuides {
+		return "[]"
+	}
+	scene, ok := e.doc.Scenes[e.sceneID]
+	if !ok {
+		return "[]"
+	}
+	areas := SafeAreaRects(scene, scene.SafeAreaWith(e.safeAreaPercent))
+	result, _ := DrawCommandsToJSON(SafeAreaGuides(areas))
+	return result
+}
+
+// SafeAreaGuides returns commands outlining safe areas.
+func SafeAreaGuides(areas SafeAreas) []DrawCommand {
+	alpha := guideAlpha
+	guide := func(r Rect, stroke string) DrawCommand {
+		return DrawCommand{
+			Op:          "path",
+			Transform:   Identity().ToSlice(),
+			Path:        rectPath(r),
+			Stroke:      stroke,
+			StrokeAlpha: &alpha,
+			StrokeWidth: guideWidth,
+		}
+	}
+	return []DrawCommand{
+		guide(areas.Action, actionSafeColor),
+		guide(areas.Title, titleSafeColor),
+	}
+}
+
+// InsetRect returns the area of a width × height scene inside the insets.
+func InsetRect(width, height int, in document.Insets) Rect {
+	return Rect{
+		X:      in.Left,
+		Y:      in.Top,
+		Width:  float64(width) - in.Left - in.Right,
+		Height: float64(height) - in.Top - in.Bottom,
+	}
+}
+
+// rectPath returns a closed path around r.
+func rectPath(r Rect) []PathCommand {
+	return []PathCommand{
+		{"M", r.X, r.Y},
+		{"L", r.X + r.Width, r.Y},
+		{"L", r.X + r.Width, r.Y + r.Height},
+		{"L", r.X, r.Y + r.Height},
+		{"Z"},
+	}
+}
@@ -0,0 +1,139 @@
+package engine
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+)
+
+func TestInsetRect(t *testing.T) {
+	tests := []struct {
+		in   document.Insets
+		want Rect
+	}{
+		{document.Insets{}, Rect{X: 0, Y: 0, Width: 1280, Height: 720}},
+		{document.Insets{Top: 10, Right: 20, Bottom: 30, Left: 40}, Rect{X: 40, Y: 10, Width: 1220, Height: 680}},
+		{document.DefaultSafeAreaInsets(1280, 720).Title, Rect{X: 64, Y: 36, Width: 1152, Height: 648}},
+	}
+	for _, tt := range tests {
+		if got := InsetRect(1280, 720, tt.in); got != tt.want {
+			t.Errorf("InsetRect(%+v) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRenderOverlaySafeAreas(t *testing.T) {
+	doc, _ := rectDoc()
+	sceneID := doc.Project.Scenes[0]
+	scene := doc.Scenes[sceneID]
+	scene.SafeAreaInsets = &document.SafeAreaInsets{
+		Action: document.Insets{Top: 10, Right: 10, Bottom: 10, Left: 10},
+		Title:  document.Insets{Top: 20, Right: 30, Bottom: 20, Left: 30},
+	}
+	doc.Scenes[sceneID] = scene
+	e := NewEngine()
+	e.ReplaceDocument(doc)
+
+	if got := e.RenderOverlay(); got != "[]" {
+		t.Fatalf("overlay with guides off = %s", got)
+	}
+	e.SetSafeAreaGuides(true)
+	var cmds []DrawCommand
+	if err := json.Unmarshal([]byte(e.RenderOverlay()), &cmds); err != nil {
+		t.Fatal(err)
+	}
+	if len(cmds) != 2 {
+		t.Fatalf("%d overlay commands, want action and title guides", len(cmds))
+	}
+
+	// Guides are in scene coordinates under an identity transform, so they
+	// scale with the viewport like the scene itself
+	wantPaths := [][]PathCommand{
+		{{"M", 10.0, 10.0}, {"L", 1270.0, 10.0}, {"L", 1270.0, 710.0}, {"L", 10.0, 710.0}, {"Z"}},
+		{{"M", 30.0, 20.0}, {"L", 1250.0, 20.0}, {"L", 1250.0, 700.0}, {"L", 30.0, 700.0}, {"Z"}},
+	}
+	for i, cmd := range cmds {
+		if cmd.Op != "path" || cmd.Fill != "" || cmd.Stroke == "" {
+			t.Errorf("guide %d: %+v, want an unfilled stroked path", i, cmd)
+		}
+		if !reflect.DeepEqual(cmd.Transform, Identity().ToSlice()) {
+			t.Errorf("guide %d transform %v, want identity", i, cmd.Transform)
+		}
+		if !reflect.DeepEqual(cmd.Path, wantPaths[i]) {
+			t.Errorf("guide %d path %v, want %v", i, cmd.Path, wantPaths[i])
+		}
+	}
+
+	e.SetSafeAreaGuides(false)
+	if got := e.RenderOverlay(); got != "[]" {
+		t.Errorf("overlay after hiding guides = %s", got)
+	}
+}
+
+func TestGetSafeAreas(t *testing.T) {
+	doc, _ := rectDoc()
+	sceneID := doc.Project.Scenes[0]
+	e := NewEngine()
+	e.ReplaceDocument(doc)
+
+	areas := func(sceneID string) (a struct{ Action, Title Rect }) {
+		t.Helper()
+		if err := json.Unmarshal([]byte(e.GetSafeAreas(sceneID)), &a); err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+	sameRect := func(a, b Rect) bool {
+		return near(a.X, b.X) && near(a.Y, b.Y) && near(a.Width, b.Width) && near(a.Height, b.Height)
+	}
+
+	// The broadcast standard by default, for the current scene
+	std := areas("")
+	if want := (Rect{X: 64, Y: 36, Width: 1152, Height: 648}); !sameRect(std.Title, want) {
+		t.Errorf("standard title-safe %+v, want 90%% %+v", std.Title, want)
+	}
+	if want := (Rect{X: 44.8, Y: 25.2, Width: 1190.4, Height: 669.6}); !sameRect(std.Action, want) {
+		t.Errorf("standard action-safe %+v, want 93%% %+v", std.Action, want)
+	}
+
+	// 80% title-safe of 1280x720 is 1024x576, centred
+	if err := e.SetSafeAreaPercent(90, 80); err != nil {
+		t.Fatal(err)
+	}
+	got := areas(sceneID)
+	if want := (Rect{X: 128, Y: 72, Width: 1024, Height: 576}); !sameRect(got.Title, want) {
+		t.Errorf("80%% title-safe %+v, want %+v", got.Title, want)
+	}
+	if want := (Rect{X: 64, Y: 36, Width: 1152, Height: 648}); !sameRect(got.Action, want) {
+		t.Errorf("90%% action-safe %+v, want %+v", got.Action, want)
+	}
+
+	// A scene's own insets win over the percentages
+	scene := doc.Scenes[sceneID]
+	scene.SafeAreaInsets = &document.SafeAreaInsets{Title: document.Insets{Top: 10, Left: 20}}
+	doc.Scenes[sceneID] = scene
+	if got := areas(sceneID); got.Title != (Rect{X: 20, Y: 10, Width: 1260, Height: 710}) {
+		t.Errorf("scene's own title-safe %+v", got.Title)
+	}
+
+	if got := e.GetSafeAreas("scene_missing"); got != "{}" {
+		t.Errorf("unknown scene areas %s, want {}", got)
+	}
+}
+
+func TestSetSafeAreaPercentRejected(t *testing.T) {
+	e := NewEngine()
+	for _, p := range [][2]float64{{0, 0}, {101, 90}, {90, 0}, {80, 90}, {90, -5}} {
+		if err := e.SetSafeAreaPercent(p[0], p[1]); err == nil {
+			t.Errorf("action %v title %v accepted", p[0], p[1])
+		}
+	}
+	if e.safeAreaPercent != document.StandardSafeAreaPercent {
+		t.Errorf("rejected percentages changed the engine's to %+v", e.safeAreaPercent)
+	}
+	if err := e.SetSafeAreaPercent(100, 100); err != nil {
+		t.Errorf("full-scene areas rejected: %v", err)
+	}
+}
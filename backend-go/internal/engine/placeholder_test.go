@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// futureDoc returns a document from a newer version holding a Camera, a
+// type this build doesn't know, at (10, 10), and the camera's ID.
+func futureDoc(data string) (*document.InDocument, string) {
+	rootID, cameraID := typeid.NewObjectID(), typeid.NewObjectID()
+	doc := document.NewEmptyDocument(typeid.NewProjectID(), "Future", typeid.NewSceneID(), rootID, typeid.NewTimelineID())
+	doc.Project.Version = document.CurrentVersion + 1
+	addChild(doc, rootID, document.ObjectNode{
+		ID:        cameraID,
+		Type:      "Camera",
+		Transform: document.Transform{X: 10, Y: 10, SX: 1, SY: 1},
+		Style:     document.Style{Fill: "#ff0000", Opacity: 1},
+		Visible:   true,
+		Data:      json.RawMessage(data),
+	})
+	return doc, cameraID
+}
+
+func TestUnknownTypeDrawnAsPlaceholder(t *testing.T) {
+	for _, tt := range []struct {
+		data          string
+		width, height float64
+	}{
+		{`{"width":60,"height":40,"zoom":2}`, 60, 40},
+		{`{"zoom":2}`, defaultPlaceholder, defaultPlaceholder},
+	} {
+		doc, cameraID := futureDoc(tt.data)
+		sg := buildAt(doc, 0)
+		node := sg.NodesById[cameraID]
+		if node.Type != "placeholder" || node.Placeholder != "Camera" {
+			t.Fatalf("%s: node type %q placeholder %q, want a Camera placeholder", tt.data, node.Type, node.Placeholder)
+		}
+		// Bounds take in half the box's 1px outline
+		if b := node.Bounds; !near(b.X, 9.5) || !near(b.Y, 9.5) || !near(b.Width, tt.width+1) || !near(b.Height, tt.height+1) {
+			t.Errorf("%s: bounds %+v, want %vx%v at (10, 10) and the outline", tt.data, b, tt.width, tt.height)
+		}
+
+		// A box in the placeholder color, then its label
+		var box, label *DrawCommand
+		for _, cmd := range CompileDrawCommands(sg) {
+			if cmd.ObjectID != cameraID {
+				continue
+			}
+			switch cmd.Op {
+			case "path":
+				box = &cmd
+			case "text":
+				label = &cmd
+			}
+		}
+		if box == nil || box.Fill != placeholderColor || box.Stroke != placeholderColor {
+			t.Errorf("%s: box %+v, want one drawn in %s", tt.data, box, placeholderColor)
+		}
+		if label == nil || label.TextContent != "Camera" {
+			t.Errorf("%s: label %+v, want the type name", tt.data, label)
+		}
+		if got := HitTest(sg, 10+tt.width/2, 10+tt.height/2); got != cameraID {
+			t.Errorf("%s: hit in the box = %q, want the camera", tt.data, got)
+		}
+	}
+}
+
+func TestGetDiagnostics(t *testing.T) {
+	doc, cameraID := futureDoc(`{}`)
+	e := NewEngine()
+	e.ReplaceDocument(doc)
+
+	var diag Diagnostics
+	if err := json.Unmarshal([]byte(e.GetDiagnostics()), &diag); err != nil {
+		t.Fatal(err)
+	}
+	if diag.Version != document.CurrentVersion+1 || diag.CurrentVersion != document.CurrentVersion || !diag.Newer {
+		t.Errorf("diagnostics %+v, want a newer document", diag)
+	}
+	if len(diag.UnknownObjects) != 1 || diag.UnknownObjects[0] != (document.UnknownObject{ID: cameraID, Type: "Camera"}) {
+		t.Errorf("unknown objects %+v, want the camera", diag.UnknownObjects)
+	}
+
+	if got := NewEngine().GetDiagnostics(); got != `{"version":0,"currentVersion":1,"newer":false,"unknownObjects":[]}` {
+		t.Errorf("diagnostics without a document = %s", got)
+	}
+}
@@ -1,5 +1,7 @@
 package engine
 
+import "github.com/inamate/inamate/backend-go/internal/document"
+
 // SceneGraph is the evaluated, render-ready state of the document at a point in time.
 // This is the retained scene graph - it persists between frames and is incrementally updated.
 type SceneGraph struct {
@@ -12,7 +14,7 @@ type SceneGraph struct {
 // All transforms are computed, all properties are resolved (including inherited ones).
 type SceneNode struct {
 	ID   string
-	Type string // "group", "shape", "symbol", "mask"
+	Type string // "group", "shape", "symbol", "image", "text", "placeholder"
 
 	// Transform state
 	WorldTransform Matrix2D // computed world transform (parent * local)
@@ -30,15 +32,19 @@ type SceneNode struct {
 	ClipPath *SceneNode // mask reference if any
 
 	// Render data (resolved from document)
-	Path        []PathCommand // for shapes
-	Fill        string
-	Stroke      string
-	StrokeWidth float64
-
-	// Image data (for RasterImage nodes)
+	Path         []PathCommand      // for shapes
+	Fill         string             // RGB only; alpha is split into FillAlpha
+	FillAlpha    float64            // 1 = opaque
+	FillGradient *document.Gradient // fills in place of Fill when set; local space
+	Stroke       string             // RGB only; alpha is split into StrokeAlpha
+	StrokeAlpha  float64            // 1 = opaque
+	StrokeWidth  float64
+
+	// Image data (for RasterImage and SpriteSequence nodes)
 	ImageAssetID string
 	ImageWidth   float64
 	ImageHeight  float64
+	ImageSource  *Rect // region of the image to draw (a sprite sheet cell); nil for all of it
 
 	// Text data (for Text nodes)
 	TextContent    string
@@ -47,6 +53,15 @@ type SceneNode struct {
 	TextFontWeight string
 	TextAlign      string
 
+	// Text layout (for Text nodes)
+	TextLetterSpacing float64
+	TextPath          []PathCommand // baseline path in the text's local space, if attached
+	TextPathOffset    float64       // distance along TextPath where the text starts
+
+	// Placeholder is the type of an object this build doesn't know, labeling
+	// the box drawn in its place
+	Placeholder string
+
 	// Hit testing
 	Bounds Rect // axis-aligned bounding box in world space
 }
@@ -81,6 +96,18 @@ func (r Rect) IsEmpty() bool {
 	return r.Width <= 0 || r.Height <= 0
 }
 
+// Intersects checks if the rects overlap or touch.
+func (r Rect) Intersects(other Rect) bool {
+	return r.X <= other.X+other.Width && other.X <= r.X+r.Width &&
+		r.Y <= other.Y+other.Height && other.Y <= r.Y+r.Height
+}
+
+// Expand grows the rect by dx on the left and right and dy on the top and
+// bottom.
+func (r Rect) Expand(dx, dy float64) Rect {
+	return Rect{X: r.X - dx, Y: r.Y - dy, Width: r.Width + 2*dx, Height: r.Height + 2*dy}
+}
+
 // Union returns the smallest rect containing both rects.
 func (r Rect) Union(other Rect) Rect {
 	if r.IsEmpty() {
@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"math"
+	"sort"
+)
+
+// maxGridCells bounds the spatial grid to maxGridCells × maxGridCells cells.
+const maxGridCells = 64
+
+// SpatialIndex is a uniform grid over the world bounds of a scene graph's
+// nodes, for finding the nodes in a region without visiting all of them.
+type SpatialIndex struct {
+	bounds     Rect // Union of the indexed nodes' bounds
+	cols, rows int  // Grid size; zero when nothing is indexed
+	cellW      float64
+	cellH      float64
+	cells      [][]int      // Row-major; indices into nodes
+	nodes      []*SceneNode // Painter's order
+}
+
+// BuildSpatialIndex indexes the visible nodes of sg that have bounds. The grid
+// is sized to the scene so each cell holds about one node.
+func BuildSpatialIndex(sg *SceneGraph) *SpatialIndex {
+	idx := &SpatialIndex{}
+	if sg == nil || sg.Root == nil {
+		return idx
+	}
+	collectIndexNodes(sg.Root, &idx.nodes)
+	if len(idx.nodes) == 0 {
+		return idx
+	}
+
+	for _, node := range idx.nodes {
+		idx.bounds = idx.bounds.Union(node.Bounds)
+	}
+	side := min(maxGridCells, max(1, int(math.Ceil(math.Sqrt(float64(len(idx.nodes)))))))
+	idx.cols, idx.rows = side, side
+	idx.cellW = idx.bounds.Width / float64(side)
+	idx.cellH = idx.bounds.Height / float64(side)
+	idx.cells = make([][]int, side*side)
+
+	for i, node := range idx.nodes {
+		c0, r0, c1, r1 := idx.cellRange(node.Bounds)
+		for r := r0; r <= r1; r++ {
+			for c := c0; c <= c1; c++ {
+				idx.cells[r*idx.cols+c] = append(idx.cells[r*idx.cols+c], i)
+			}
+		}
+	}
+	return idx
+}
+
+// collectIndexNodes appends node and its visible descendants that have bounds,
+// in painter's order.
+func collectIndexNodes(node *SceneNode, out *[]*SceneNode) {
+	if node == nil || !node.Visible {
+		return
+	}
+	if !node.Bounds.IsEmpty() {
+		*out = append(*out, node)
+	}
+	for _, child := range node.Children {
+		collectIndexNodes(child, out)
+	}
+}
+
+// cellRange returns the columns and rows of the cells r overlaps, clamped to
+// the grid.
+func (idx *SpatialIndex) cellRange(r Rect) (c0, r0, c1, r1 int) {
+	cell := func(v, origin, size float64, n int) int {
+		if size <= 0 {
+			return 0
+		}
+		return min(n-1, max(0, int((v-origin)/size)))
+	}
+	c0 = cell(r.X, idx.bounds.X, idx.cellW, idx.cols)
+	c1 = cell(r.X+r.Width, idx.bounds.X, idx.cellW, idx.cols)
+	r0 = cell(r.Y, idx.bounds.Y, idx.cellH, idx.rows)
+	r1 = cell(r.Y+r.Height, idx.bounds.Y, idx.cellH, idx.rows)
+	return c0, r0, c1, r1
+}
+
+// Query returns the IDs of the indexed nodes whose bounds intersect view, in
+// painter's order.
+func (idx *SpatialIndex) Query(view Rect) []string {
+	ids := make([]string, 0)
+	if idx.cols == 0 || view.IsEmpty() || !view.Intersects(idx.bounds) {
+		return ids
+	}
+
+	var hits []int
+	seen := make(map[int]bool)
+	c0, r0, c1, r1 := idx.cellRange(view)
+	for r := r0; r <= r1; r++ {
+		for c := c0; c <= c1; c++ {
+			for _, i := range idx.cells[r*idx.cols+c] {
+				if !seen[i] && idx.nodes[i].Bounds.Intersects(view) {
+					hits = append(hits, i)
+				}
+				seen[i] = true
+			}
+		}
+	}
+
+	sort.Ints(hits)
+	for _, i := range hits {
+		ids = append(ids, idx.nodes[i].ID)
+	}
+	return ids
+}
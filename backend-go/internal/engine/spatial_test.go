@@ -0,0 +1,205 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// gridDoc returns a document holding a 10×10 grid of 40×30 rects 100 apart,
+// a group at (2000, 0) with a rect at (0, 500) inside it, and a hidden rect
+// at the origin.
+func gridDoc() *document.InDocument {
+	doc, rectID := rectDoc()
+	rootID := *doc.Objects[rectID].Parent
+	hidden := doc.Objects[rectID]
+	hidden.Visible = false
+	doc.Objects[rectID] = hidden
+
+	for row := range 10 {
+		for col := range 10 {
+			addChild(doc, rootID, document.ObjectNode{
+				ID:        fmt.Sprintf("obj_grid_%d_%d", row, col),
+				Type:      document.ObjectTypeShapeRect,
+				Transform: document.Transform{X: float64(col * 100), Y: float64(row * 100), SX: 1, SY: 1},
+				Style:     document.Style{Fill: "#00ff00", Opacity: 1},
+				Visible:   true,
+				Data:      json.RawMessage(`{"width":40,"height":30}`),
+			})
+		}
+	}
+	groupID := typeid.NewObjectID()
+	addChild(doc, rootID, document.ObjectNode{
+		ID: groupID, Type: document.ObjectTypeGroup, Visible: true,
+		Transform: document.Transform{X: 2000, SX: 1, SY: 1}, Style: document.Style{Opacity: 1},
+	})
+	addChild(doc, groupID, document.ObjectNode{
+		ID:        "obj_nested",
+		Type:      document.ObjectTypeShapeRect,
+		Transform: document.Transform{Y: 500, SX: 1, SY: 1},
+		Style:     document.Style{Fill: "#0000ff", Opacity: 1},
+		Visible:   true,
+		Data:      json.RawMessage(`{"width":40,"height":30}`),
+	})
+	return doc
+}
+
+// bruteForce returns the visible nodes of sg intersecting view, in painter's
+// order, by visiting every node.
+func bruteForce(sg *SceneGraph, view Rect) []string {
+	ids := []string{}
+	var visit func(node *SceneNode)
+	visit = func(node *SceneNode) {
+		if !node.Visible {
+			return
+		}
+		if !node.Bounds.IsEmpty() && node.Bounds.Intersects(view) {
+			ids = append(ids, node.ID)
+		}
+		for _, child := range node.Children {
+			visit(child)
+		}
+	}
+	visit(sg.Root)
+	return ids
+}
+
+func TestSpatialIndexQuery(t *testing.T) {
+	doc := gridDoc()
+	sg := buildAt(doc, 0)
+	idx := BuildSpatialIndex(sg)
+
+	tests := []struct {
+		view Rect
+		want []string
+	}{
+		{Rect{X: 90, Y: 90, Width: 20, Height: 20}, []string{"obj_grid_1_1"}},
+		{Rect{X: 145, Y: 0, Width: 40, Height: 10}, []string{}},
+		{Rect{X: 2010, Y: 510, Width: 5, Height: 5}, []string{"obj_nested"}},
+		{Rect{X: -500, Y: -500, Width: 100, Height: 100}, []string{}},
+		{Rect{X: 0, Y: 0, Width: 0, Height: 0}, []string{}},
+	}
+	for _, tt := range tests {
+		got := idx.Query(tt.view)
+		// Group bounds include the nested rect, so groups may come along
+		got = slices.DeleteFunc(got, func(id string) bool { return sg.NodesById[id].Type == "group" || id == sg.Root.ID })
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Query(%+v) = %v, want %v", tt.view, got, tt.want)
+		}
+	}
+
+	// Any region finds exactly what visiting every node does
+	rng := rand.New(rand.NewSource(1))
+	for range 200 {
+		view := Rect{X: rng.Float64()*2400 - 200, Y: rng.Float64()*1200 - 200, Width: rng.Float64() * 600, Height: rng.Float64() * 600}
+		if got, want := idx.Query(view), bruteForce(sg, view); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Query(%+v) = %v, want %v", view, got, want)
+		}
+	}
+
+	if got := BuildSpatialIndex(nil).Query(Rect{Width: 100, Height: 100}); len(got) != 0 {
+		t.Errorf("empty index found %v", got)
+	}
+}
+
+// TestCulledMatchesUnculledInView checks culling only drops the draw commands
+// of objects entirely outside the view, keeping the rest in order.
+func TestCulledMatchesUnculledInView(t *testing.T) {
+	doc := gridDoc()
+	sg := buildAt(doc, 0)
+	all := CompileDrawCommands(sg)
+
+	for _, view := range []Rect{
+		{X: 150, Y: 150, Width: 300, Height: 200},
+		{X: 1990, Y: 490, Width: 100, Height: 100},
+		{X: -1000, Y: -1000, Width: 10, Height: 10},
+		{X: -100, Y: -100, Width: 5000, Height: 5000},
+	} {
+		culled, count := CompileDrawCommandsInView(sg, view)
+		if len(culled)+count != len(all) {
+			t.Errorf("%+v: %d kept and %d culled of %d", view, len(culled), count, len(all))
+		}
+
+		// Walking both lists, every command of an object in view is kept and
+		// in the same order; every one dropped is of an object outside it
+		i := 0
+		for _, cmd := range all {
+			if i < len(culled) && reflect.DeepEqual(culled[i], cmd) {
+				i++
+				continue
+			}
+			if node := sg.NodesById[cmd.ObjectID]; cmd.ObjectID == "" || node.Bounds.Intersects(view) {
+				t.Errorf("%+v: dropped %s command of %q, which is in view", view, cmd.Op, cmd.ObjectID)
+			}
+		}
+		if i != len(culled) {
+			t.Errorf("%+v: culled commands aren't a subsequence of the unculled ones", view)
+		}
+
+		depth := 0
+		for _, cmd := range culled {
+			switch cmd.Op {
+			case "save":
+				depth++
+			case "restore":
+				depth--
+			}
+		}
+		if depth != 0 {
+			t.Errorf("%+v: saves and restores off by %d", view, depth)
+		}
+	}
+}
+
+func TestEngineCullingAndVisibleObjects(t *testing.T) {
+	e := NewEngine()
+	e.ReplaceDocument(gridDoc())
+	full := e.Render()
+
+	view := Rect{X: 0, Y: 0, Width: 150, Height: 150}
+	e.SetCullToViewport(true, view)
+	e.ReplaceDocument(gridDoc())
+	culled := e.Render()
+	var stats RenderStats
+	if err := json.Unmarshal([]byte(e.GetRenderStats()), &stats); err != nil {
+		t.Fatal(err)
+	}
+	// Four grid rects overlap the view; the other 97 visible rects are culled
+	if stats.CulledCount != 97 || len(culled) >= len(full) {
+		t.Errorf("culled %d commands, want 97", stats.CulledCount)
+	}
+
+	var ids []string
+	if err := json.Unmarshal([]byte(e.GetVisibleObjects(view)), &ids); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"obj_grid_0_0", "obj_grid_0_1", "obj_grid_1_0", "obj_grid_1_1"}
+	if got := slices.DeleteFunc(ids, func(id string) bool { return e.sceneGraph.NodesById[id].Type == "group" }); !reflect.DeepEqual(got, want) {
+		t.Errorf("visible objects %v, want %v", got, want)
+	}
+
+	// The index is built once per scene graph
+	index := e.spatialIndex
+	e.GetVisibleObjects(Rect{X: 500, Y: 500, Width: 10, Height: 10})
+	if e.spatialIndex != index {
+		t.Error("index rebuilt for an unchanged scene graph")
+	}
+	e.SetCullToViewport(false, Rect{})
+	e.ReplaceDocument(gridDoc())
+	if e.Render() != full {
+		t.Error("render with culling off differs from the first")
+	}
+	if e.spatialIndex != nil {
+		t.Error("index kept across a rebuilt scene graph")
+	}
+
+	if got := NewEngine().GetVisibleObjects(view); got != "[]" {
+		t.Errorf("visible objects before any render %s, want []", got)
+	}
+}
@@ -0,0 +1,130 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// Defaults for new objects, matching what the editor's tools create
+const (
+	DefaultShapeSize   = 100.0
+	DefaultFontSize    = 32.0
+	DefaultText        = "Text"
+	defaultShapeFill   = "#4a90d9"
+	defaultShapeStroke = "#2d5a87"
+	defaultStrokeWidth = 2.0
+)
+
+// NewObject returns a new object of type t with a fresh ID and the defaults
+// the editor gives it: an identity transform anchored at the centre of its
+// shape, full opacity, visible, no children, and data sized so it renders.
+// Image, sprite sequence and symbol data still need their asset or timeline.
+func NewObject(t document.ObjectType) (document.ObjectNode, error) {
+	obj := document.ObjectNode{
+		ID:        typeid.NewObjectID(),
+		Type:      t,
+		Children:  []string{},
+		Transform: document.Transform{SX: 1, SY: 1},
+		Style:     document.Style{Opacity: 1},
+		Visible:   true,
+	}
+	size := DefaultShapeSize
+	var data interface{}
+	switch t {
+	case document.ObjectTypeGroup:
+		data = struct{}{}
+	case document.ObjectTypeShapeRect:
+		obj.Transform.AX, obj.Transform.AY = size/2, size/2
+		obj.Style = shapeStyle()
+		data = map[string]float64{"width": size, "height": size}
+	case document.ObjectTypeShapeEllipse:
+		// The ellipse path is centred on the origin
+		obj.Style = shapeStyle()
+		data = map[string]float64{"rx": size / 2, "ry": size / 2}
+	case document.ObjectTypeVectorPath:
+		obj.Transform.AX, obj.Transform.AY = size/2, size/2
+		obj.Style = document.Style{Fill: "none", Stroke: defaultShapeFill, StrokeWidth: defaultStrokeWidth, Opacity: 1}
+		data = map[string]interface{}{"commands": []PathCommand{
+			{"M", size / 2, 0.0},
+			{"L", size, size},
+			{"L", 0.0, size},
+			{"Z"},
+		}}
+	case document.ObjectTypeRasterImage:
+		obj.Transform.AX, obj.Transform.AY = size/2, size/2
+		data = map[string]interface{}{"assetId": "", "width": size, "height": size}
+	case document.ObjectTypeSpriteSequence:
+		obj.Transform.AX, obj.Transform.AY = size/2, size/2
+		data = document.SpriteSequenceData{Width: size, Height: size}
+	case document.ObjectTypeSymbol:
+		data = map[string]string{"timelineId": ""}
+	case document.ObjectTypeText:
+		layout := LayoutText(TextLayoutInput{Content: DefaultText, FontSize: DefaultFontSize, FontWeight: "normal"})
+		obj.Transform.AX, obj.Transform.AY = layout.Width/2, layout.Height/2
+		obj.Style = document.Style{Fill: "#000000", Stroke: "none", Opacity: 1}
+		data = map[string]interface{}{
+			"content":    DefaultText,
+			"fontSize":   DefaultFontSize,
+			"fontFamily": "sans-serif",
+			"fontWeight": "normal",
+			"textAlign":  "left",
+		}
+	default:
+		return document.ObjectNode{}, fmt.Errorf("unknown object type %q", t)
+	}
+	obj.Data, _ = json.Marshal(data)
+	return obj, nil
+}
+
+func shapeStyle() document.Style {
+	return document.Style{Fill: defaultShapeFill, Stroke: defaultShapeStroke, StrokeWidth: defaultStrokeWidth, Opacity: 1}
+}
+
+// CreateObjectJSON returns a new object of type t (see NewObject) as JSON,
+// with overrides, a partial object in JSON, applied on top. Transform and
+// style fields override individually; data fields are merged over the
+// type's default data.
+func CreateObjectJSON(t string, overrides string) (string, error) {
+	obj, err := NewObject(document.ObjectType(t))
+	if err != nil {
+		return "", err
+	}
+	if overrides != "" {
+		// Unmarshalling reuses a RawMessage's buffer, so detach the default
+		defaultData := obj.Data
+		obj.Data = nil
+		if err := json.Unmarshal([]byte(overrides), &obj); err != nil {
+			return "", fmt.Errorf("invalid overrides: %w", err)
+		}
+		if obj.Data == nil {
+			obj.Data = defaultData
+		} else if obj.Data, err = mergeData(defaultData, obj.Data); err != nil {
+			return "", err
+		}
+		// The type decides the defaults, so it can't be overridden
+		obj.Type = document.ObjectType(t)
+	}
+	out, err := json.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// mergeData returns base with the fields of overrides set on it.
+func mergeData(base, overrides json.RawMessage) (json.RawMessage, error) {
+	var merged, fields map[string]json.RawMessage
+	if err := json.Unmarshal(base, &merged); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(overrides, &fields); err != nil {
+		return nil, fmt.Errorf("invalid data overrides: %w", err)
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return json.Marshal(merged)
+}
@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// TestNewObjectRendersVisibly checks every object type's defaults draw
+// something with area once given what only a caller can supply: an image's
+// asset, a symbol's timeline, and a group's or symbol's contents.
+func TestNewObjectRendersVisibly(t *testing.T) {
+	setData := func(doc *document.InDocument, id, data string) {
+		obj := doc.Objects[id]
+		obj.Data = json.RawMessage(data)
+		doc.Objects[id] = obj
+	}
+	tests := []struct {
+		objType document.ObjectType
+		setup   func(doc *document.InDocument, id string) string // Returns the ID expected to draw
+	}{
+		{document.ObjectTypeShapeRect, nil},
+		{document.ObjectTypeShapeEllipse, nil},
+		{document.ObjectTypeVectorPath, nil},
+		{document.ObjectTypeText, nil},
+		{document.ObjectTypeRasterImage, func(doc *document.InDocument, id string) string {
+			setData(doc, id, `{"assetId":"asset_1","width":100,"height":100}`)
+			return id
+		}},
+		{document.ObjectTypeSpriteSequence, func(doc *document.InDocument, id string) string {
+			setData(doc, id, `{"assetIds":["asset_1"],"width":100,"height":100}`)
+			return id
+		}},
+		{document.ObjectTypeGroup, func(doc *document.InDocument, id string) string {
+			child, _ := NewObject(document.ObjectTypeShapeRect)
+			addChild(doc, id, child)
+			return child.ID
+		}},
+		{document.ObjectTypeSymbol, func(doc *document.InDocument, id string) string {
+			timelineID := typeid.NewTimelineID()
+			doc.Timelines[timelineID] = document.Timeline{ID: timelineID, Length: 24, Tracks: []string{}}
+			setData(doc, id, `{"timelineId":"`+timelineID+`"}`)
+			child, _ := NewObject(document.ObjectTypeShapeEllipse)
+			addChild(doc, id, child)
+			return child.ID
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.objType), func(t *testing.T) {
+			obj, err := NewObject(tt.objType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if obj.Transform.SX != 1 || obj.Transform.SY != 1 || obj.Style.Opacity != 1 || !obj.Visible || obj.Children == nil {
+				t.Errorf("defaults %+v", obj)
+			}
+			if err := typeid.Validate(obj.ID, typeid.PrefixObject); err != nil {
+				t.Errorf("ID %q: %v", obj.ID, err)
+			}
+
+			doc, _ := rectDoc()
+			addChild(doc, doc.Scenes[doc.Project.Scenes[0]].Root, obj)
+			drawn := obj.ID
+			if tt.setup != nil {
+				drawn = tt.setup(doc, obj.ID)
+			}
+
+			cmd := commandFor(t, CompileDrawCommands(buildAt(doc, 0)), drawn)
+			if cmd.Opacity <= 0 {
+				t.Errorf("drawn with opacity %v", cmd.Opacity)
+			}
+			if b := buildAt(doc, 0).NodesById[drawn].Bounds; b.Width <= 0 || b.Height <= 0 {
+				t.Errorf("bounds %+v have no area", b)
+			}
+		})
+	}
+
+	if _, err := NewObject("Blob"); err == nil {
+		t.Error("unknown type created")
+	}
+}
+
+func TestCreateObjectJSONOverrides(t *testing.T) {
+	out, err := CreateObjectJSON("ShapeRect", `{"type":"Text","transform":{"x":40},"style":{"fill":"#ff0000"},"data":{"width":20}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var obj document.ObjectNode
+	if err := json.Unmarshal([]byte(out), &obj); err != nil {
+		t.Fatal(err)
+	}
+	if obj.Type != document.ObjectTypeShapeRect {
+		t.Errorf("type overridden to %s", obj.Type)
+	}
+	// Overridden fields change; the rest keep their defaults
+	if obj.Transform.X != 40 || obj.Transform.SX != 1 || obj.Transform.AX != DefaultShapeSize/2 {
+		t.Errorf("transform %+v, want x 40 over the defaults", obj.Transform)
+	}
+	if obj.Style.Fill != "#ff0000" || obj.Style.Stroke != defaultShapeStroke || obj.Style.Opacity != 1 {
+		t.Errorf("style %+v, want a red fill over the defaults", obj.Style)
+	}
+	var data struct{ Width, Height float64 }
+	json.Unmarshal(obj.Data, &data)
+	if data.Width != 20 || data.Height != DefaultShapeSize {
+		t.Errorf("data %s, want width 20 merged over the default size", obj.Data)
+	}
+
+	for _, tt := range []struct{ objType, overrides string }{
+		{"Blob", ""},
+		{"ShapeRect", `{"transform":`},
+		{"ShapeRect", `{"data":[1]}`},
+	} {
+		if _, err := CreateObjectJSON(tt.objType, tt.overrides); err == nil {
+			t.Errorf("%s with %q created", tt.objType, tt.overrides)
+		}
+	}
+}
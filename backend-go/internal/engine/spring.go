@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"math"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+)
+
+// springTolerance is how far from rest, as a fraction of the distance
+// travelled, a spring's envelope has decayed by the time it is considered
+// settled.
+const springTolerance = 1e-3
+
+// springEase evaluates a spring easing at t in [0, 1]. The spring is run from
+// release until it settles and that motion is stretched over the segment, so
+// it overshoots mid-segment and arrives exactly at 1. Since the duration is
+// the segment's, the parameters shape the curve through the damping ratio
+// they imply: the lower it is, the further and longer the spring wobbles.
+func springEase(t float64, p document.SpringParams) float64 {
+	if t <= 0 {
+		return 0
+	}
+	if t >= 1 {
+		return 1
+	}
+
+	omega := math.Sqrt(p.Stiffness / p.Mass)
+	zeta := p.Damping / (2 * math.Sqrt(p.Stiffness*p.Mass))
+
+	var position func(tau float64) float64
+	var decay float64
+	switch {
+	case zeta < 1-1e-9: // Underdamped: oscillates around rest
+		wd := omega * math.Sqrt(1-zeta*zeta)
+		decay = zeta * omega
+		position = func(tau float64) float64 {
+			return 1 - math.Exp(-decay*tau)*(math.Cos(wd*tau)+decay/wd*math.Sin(wd*tau))
+		}
+	case zeta <= 1+1e-9: // Critically damped
+		decay = omega
+		position = func(tau float64) float64 {
+			return 1 - math.Exp(-omega*tau)*(1+omega*tau)
+		}
+	default: // Overdamped: creeps to rest at the slower rate
+		root := math.Sqrt(zeta*zeta - 1)
+		r1, r2 := -omega*(zeta-root), -omega*(zeta+root)
+		decay = -r1
+		position = func(tau float64) float64 {
+			return 1 - (r2*math.Exp(r1*tau)-r1*math.Exp(r2*tau))/(r2-r1)
+		}
+	}
+
+	settle := math.Log(1/springTolerance) / decay
+	return position(t*settle) / position(settle)
+}
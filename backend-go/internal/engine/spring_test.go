@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+)
+
+// peak returns the highest x the rect reaches over frames 0 to 10, sampled
+// every tenth of a frame, and the frame it's reached at.
+func peak(doc *document.InDocument, rectID string) (float64, float64) {
+	best, at := 0.0, 0.0
+	for i := 0; i <= 100; i++ {
+		frame := float64(i) / 10
+		if x := xAt(doc, rectID, frame); x > best {
+			best, at = x, frame
+		}
+	}
+	return best, at
+}
+
+func TestSpringOvershootsThenSettles(t *testing.T) {
+	doc, rectID := easedDoc(document.EasingSpring)
+
+	var xs []float64
+	for frame := 0; frame <= 10; frame++ {
+		xs = append(xs, xAt(doc, rectID, float64(frame)))
+	}
+	if xs[0] != 0 || xs[10] != 100 {
+		t.Errorf("x from %v to %v, want exactly 0 to 100", xs[0], xs[10])
+	}
+	// It shoots past 100 early in the segment and is back near 100 well
+	// before the end
+	if xs[1] <= 0 || xs[1] >= xs[2] {
+		t.Errorf("x %v, want the spring to start moving towards 100", xs[:3])
+	}
+	top, at := peak(doc, rectID)
+	if top <= 105 || at >= 5 {
+		t.Errorf("peak %v at frame %v, want a clear overshoot in the first half", top, at)
+	}
+	for frame := 7; frame < 10; frame++ {
+		if x := xs[frame]; x < 99 || x > 101 {
+			t.Errorf("x at frame %d = %v, want settled near 100", frame, x)
+		}
+	}
+
+	// Past the last keyframe the end value holds
+	for _, frame := range []float64{10, 10.5, 20} {
+		if x := xAt(doc, rectID, frame); x != 100 {
+			t.Errorf("x at frame %v = %v, want 100", frame, x)
+		}
+	}
+}
+
+func TestSpringVariants(t *testing.T) {
+	peaks := map[document.EasingType]float64{}
+	for _, easing := range []document.EasingType{document.EasingSpringSoft, document.EasingSpring, document.EasingSpringStiff} {
+		doc, rectID := easedDoc(easing)
+		peaks[easing], _ = peak(doc, rectID)
+		if x := xAt(doc, rectID, 10); x != 100 {
+			t.Errorf("%s: x at the next keyframe = %v, want 100", easing, x)
+		}
+	}
+	if !(peaks[document.EasingSpringSoft] > peaks[document.EasingSpring] && peaks[document.EasingSpring] > peaks[document.EasingSpringStiff]) {
+		t.Errorf("peaks %v, want soft springs to overshoot most and stiff ones least", peaks)
+	}
+	if peaks[document.EasingSpringStiff] <= 100 {
+		t.Errorf("stiff spring peaked at %v, want a slight overshoot", peaks[document.EasingSpringStiff])
+	}
+}
+
+func TestSpringParams(t *testing.T) {
+	// Critically damped and overdamped springs approach without overshooting
+	for _, params := range []string{`{"damping":20}`, `{"damping":60}`} {
+		doc, rectID := easedDoc(document.EasingSpring)
+		for _, kf := range doc.Keyframes {
+			kf.EasingParams = json.RawMessage(params)
+			doc.Keyframes[kf.ID] = kf
+		}
+		prev := 0.0
+		for i := 1; i <= 100; i++ {
+			x := xAt(doc, rectID, float64(i)/10)
+			if x < prev || x > 100+1e-9 {
+				t.Fatalf("%s: x at frame %v = %v after %v, want a steady approach to 100", params, float64(i)/10, x, prev)
+			}
+			prev = x
+		}
+		if prev != 100 {
+			t.Errorf("%s: ended at %v, want 100", params, prev)
+		}
+	}
+
+	tests := []struct {
+		easing document.EasingType
+		raw    string
+		want   document.SpringParams
+		ok     bool
+	}{
+		{document.EasingSpring, ``, document.SpringParams{Stiffness: 100, Damping: 10, Mass: 1}, true},
+		{document.EasingSpringSoft, `{"stiffness":300}`, document.SpringParams{Stiffness: 300, Damping: 6, Mass: 1}, true},
+		{document.EasingSpringStiff, `{"damping":-1,"mass":2}`, document.SpringParams{Stiffness: 100, Damping: 16, Mass: 2}, true},
+		{document.EasingSpring, `"bouncy"`, document.SpringParams{Stiffness: 100, Damping: 10, Mass: 1}, true},
+		{document.EasingEaseOut, `{"stiffness":300}`, document.SpringParams{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := tt.easing.Spring(json.RawMessage(tt.raw))
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%s with %s: %+v %v, want %+v %v", tt.easing, tt.raw, got, ok, tt.want, tt.ok)
+		}
+	}
+}
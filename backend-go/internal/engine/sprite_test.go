@@ -0,0 +1,65 @@
+package engine
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// spriteDoc returns a document holding a sprite sequence with data under the
+// root, and the sequence's ID.
+func spriteDoc(data string) (*document.InDocument, string) {
+	doc, _ := rectDoc()
+	id := typeid.NewObjectID()
+	addChild(doc, doc.Scenes[doc.Project.Scenes[0]].Root, document.ObjectNode{
+		ID:        id,
+		Type:      document.ObjectTypeSpriteSequence,
+		Transform: document.Transform{X: 10, Y: 20, SX: 1, SY: 1},
+		Style:     document.Style{Opacity: 1},
+		Visible:   true,
+		Data:      json.RawMessage(data),
+	})
+	return doc, id
+}
+
+func TestSpriteSequenceAssetPerFrame(t *testing.T) {
+	doc, seqID := spriteDoc(`{"assetIds":["asset_a","asset_b","asset_c"],"width":64,"height":48}`)
+
+	for frame, want := range []string{"asset_a", "asset_b", "asset_c"} {
+		cmd := commandFor(t, CompileDrawCommands(buildAt(doc, float64(frame))), seqID)
+		if cmd.Op != "image" || cmd.ImageAssetID != want {
+			t.Errorf("frame %d draws %s %q, want image %q", frame, cmd.Op, cmd.ImageAssetID, want)
+		}
+		if cmd.ImageWidth != 64 || cmd.ImageHeight != 48 || cmd.ImageSource != nil {
+			t.Errorf("frame %d image %vx%v from %v, want all of a 64x48 image", frame, cmd.ImageWidth, cmd.ImageHeight, cmd.ImageSource)
+		}
+	}
+
+	sg := buildAt(doc, 0)
+	if b := sg.NodesById[seqID].Bounds; b != (Rect{X: 10, Y: 20, Width: 64, Height: 48}) {
+		t.Errorf("bounds %+v, want the frame size at the object's position", b)
+	}
+}
+
+func TestSpriteSequenceSheetCells(t *testing.T) {
+	doc, seqID := spriteDoc(`{"sheetAssetId":"asset_sheet","frameCount":3,"columns":2,"width":64,"height":48,"loop":true}`)
+
+	tests := []struct {
+		frame  float64
+		source []float64
+	}{
+		{0, []float64{0, 0, 64, 48}},
+		{1, []float64{64, 0, 64, 48}},
+		{2, []float64{0, 48, 64, 48}},
+		{3, []float64{0, 0, 64, 48}}, // Looped
+	}
+	for _, tt := range tests {
+		cmd := commandFor(t, CompileDrawCommands(buildAt(doc, tt.frame)), seqID)
+		if cmd.ImageAssetID != "asset_sheet" || !reflect.DeepEqual(cmd.ImageSource, tt.source) {
+			t.Errorf("frame %v draws %q from %v, want the sheet from %v", tt.frame, cmd.ImageAssetID, cmd.ImageSource, tt.source)
+		}
+	}
+}
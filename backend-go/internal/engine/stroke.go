@@ -0,0 +1,163 @@
+package engine
+
+import (
+	"math"
+
+	"github.com/inamate/inamate/backend-go/internal/color"
+)
+
+// curveSegments is how many line segments each bezier is flattened into for
+// stroke hit testing.
+const curveSegments = 16
+
+// hasFill reports whether the node paints a fill.
+func (n *SceneNode) hasFill() bool {
+	return n.FillGradient != nil || n.Fill != "" && n.Fill != color.None
+}
+
+// hasStroke reports whether the node paints a stroke.
+func (n *SceneNode) hasStroke() bool {
+	return n.Stroke != "" && n.Stroke != color.None && n.StrokeWidth > 0
+}
+
+// strokeExtent returns how far a stroke of the given width reaches past its
+// path along each world axis. The stroke is drawn in local space, so its pen
+// is a circle of radius width/2 mapped through the transform: an ellipse whose
+// axis-aligned half extents come from the rows of the linear part.
+func strokeExtent(m Matrix2D, width float64) (float64, float64) {
+	half := width / 2
+	return half * math.Hypot(m[0], m[2]), half * math.Hypot(m[1], m[3])
+}
+
+// hitsStroke reports whether the world point (x, y) lies within the stroke
+// band of the node's path. The test runs in local space, where the band is
+// exactly half the stroke width on either side of the path.
+func hitsStroke(node *SceneNode, x, y float64) bool {
+	if node.WorldTransform.Determinant() == 0 {
+		return false
+	}
+	px, py := node.WorldTransform.Invert().TransformPoint(x, y)
+	half := node.StrokeWidth / 2
+
+	hit := false
+	forEachSegment(node.Path, func(x0, y0, x1, y1 float64) bool {
+		hit = distToSegment(px, py, x0, y0, x1, y1) <= half
+		return !hit
+	})
+	return hit
+}
+
+// forEachSegment flattens a path into line segments, calling fn for each
+// until it returns false. Curves are split into curveSegments pieces and
+// "Z" closes back to the start of the subpath.
+func forEachSegment(path []PathCommand, fn func(x0, y0, x1, y1 float64) bool) {
+	for _, line := range FlattenPath(path) {
+		pts := line.Points
+		for i := 1; i < len(pts); i++ {
+			if !fn(pts[i-1][0], pts[i-1][1], pts[i][0], pts[i][1]) {
+				return
+			}
+		}
+		if line.Closed && !fn(pts[len(pts)-1][0], pts[len(pts)-1][1], pts[0][0], pts[0][1]) {
+			return
+		}
+	}
+}
+
+// Polyline is a flattened subpath.
+type Polyline struct {
+	Points [][2]float64
+	Closed bool // Ended with "Z", which joins the last point back to the first
+}
+
+// FlattenPath flattens a path into its subpaths, splitting curves into
+// curveSegments lines each. A subpath that doesn't start with "M" starts at
+// the previous subpath's start, or at the origin.
+func FlattenPath(path []PathCommand) []Polyline {
+	var lines []Polyline
+	var line Polyline
+	var curX, curY, startX, startY float64
+	flush := func() {
+		if len(line.Points) > 1 {
+			lines = append(lines, line)
+		}
+		line = Polyline{}
+	}
+	lineTo := func(x, y float64) {
+		if len(line.Points) == 0 {
+			line.Points = append(line.Points, [2]float64{curX, curY})
+		}
+		line.Points = append(line.Points, [2]float64{x, y})
+		curX, curY = x, y
+	}
+
+	for _, cmd := range path {
+		if len(cmd) == 0 {
+			continue
+		}
+		op, _ := cmd[0].(string)
+		switch op {
+		case "M":
+			if len(cmd) >= 3 {
+				flush()
+				curX, curY = toFloat64(cmd[1]), toFloat64(cmd[2])
+				startX, startY = curX, curY
+			}
+
+		case "L":
+			if len(cmd) >= 3 {
+				lineTo(toFloat64(cmd[1]), toFloat64(cmd[2]))
+			}
+
+		case "C":
+			if len(cmd) >= 7 {
+				x0, y0 := curX, curY
+				x1, y1 := toFloat64(cmd[1]), toFloat64(cmd[2])
+				x2, y2 := toFloat64(cmd[3]), toFloat64(cmd[4])
+				x, y := toFloat64(cmd[5]), toFloat64(cmd[6])
+				for i := 1; i <= curveSegments; i++ {
+					t := float64(i) / curveSegments
+					mt := 1 - t
+					lineTo(
+						mt*mt*mt*x0+3*mt*mt*t*x1+3*mt*t*t*x2+t*t*t*x,
+						mt*mt*mt*y0+3*mt*mt*t*y1+3*mt*t*t*y2+t*t*t*y,
+					)
+				}
+			}
+
+		case "Q":
+			if len(cmd) >= 5 {
+				x0, y0 := curX, curY
+				x1, y1 := toFloat64(cmd[1]), toFloat64(cmd[2])
+				x, y := toFloat64(cmd[3]), toFloat64(cmd[4])
+				for i := 1; i <= curveSegments; i++ {
+					t := float64(i) / curveSegments
+					mt := 1 - t
+					lineTo(mt*mt*x0+2*mt*t*x1+t*t*x, mt*mt*y0+2*mt*t*y1+t*t*y)
+				}
+			}
+
+		case "Z":
+			if len(line.Points) == 0 {
+				line.Points = append(line.Points, [2]float64{curX, curY})
+			}
+			line.Closed = true
+			flush()
+			curX, curY = startX, startY
+		}
+	}
+	flush()
+	return lines
+}
+
+// distToSegment returns the distance from (px, py) to the segment
+// (x0, y0)-(x1, y1).
+func distToSegment(px, py, x0, y0, x1, y1 float64) float64 {
+	dx, dy := x1-x0, y1-y0
+	lenSq := dx*dx + dy*dy
+	if lenSq == 0 {
+		return math.Hypot(px-x0, py-y0)
+	}
+	t := math.Max(0, math.Min(1, ((px-x0)*dx+(py-y0)*dy)/lenSq))
+	return math.Hypot(px-(x0+t*dx), py-(y0+t*dy))
+}
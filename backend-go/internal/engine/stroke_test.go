@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// strokedDoc returns a document holding obj under the root, and obj's ID.
+func strokedDoc(obj document.ObjectNode) (*document.InDocument, string) {
+	doc, _ := rectDoc()
+	obj.ID = typeid.NewObjectID()
+	obj.Visible = true
+	addChild(doc, doc.Scenes[doc.Project.Scenes[0]].Root, obj)
+	return doc, obj.ID
+}
+
+// thickLine is a 100px horizontal line with a 10px stroke and no fill.
+func thickLine(transform document.Transform) document.ObjectNode {
+	return document.ObjectNode{
+		Type:      document.ObjectTypeVectorPath,
+		Transform: transform,
+		Style:     document.Style{Fill: "none", Stroke: "#000000", StrokeWidth: 10, Opacity: 1},
+		Data:      json.RawMessage(`{"commands":[["M",0,0],["L",100,0]]}`),
+	}
+}
+
+func TestStrokeExpandsBounds(t *testing.T) {
+	tests := []struct {
+		name      string
+		transform document.Transform
+		want      Rect
+	}{
+		// The stroke pads every side, ends included
+		{"unscaled", document.Transform{X: 50, Y: 50, SX: 1, SY: 1}, Rect{X: 45, Y: 45, Width: 110, Height: 10}},
+		// The pen scales with the shape: 10px wide across x, 5px across y
+		{"scaled", document.Transform{X: 50, Y: 50, SX: 2, SY: 1}, Rect{X: 40, Y: 45, Width: 220, Height: 10}},
+		{"rotated", document.Transform{X: 50, Y: 50, SX: 1, SY: 1, R: 90}, Rect{X: 45, Y: 45, Width: 10, Height: 110}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, lineID := strokedDoc(thickLine(tt.transform))
+			e := NewEngine()
+			e.ReplaceDocument(doc)
+			e.Render()
+			e.SetSelection([]string{lineID})
+
+			var got Rect
+			if err := json.Unmarshal([]byte(e.GetSelectionBounds()), &got); err != nil {
+				t.Fatal(err)
+			}
+			if !near(got.X, tt.want.X) || !near(got.Y, tt.want.Y) || !near(got.Width, tt.want.Width) || !near(got.Height, tt.want.Height) {
+				t.Errorf("selection bounds %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHitTestStrokeBand(t *testing.T) {
+	doc, lineID := strokedDoc(thickLine(document.Transform{X: 50, Y: 50, SX: 1, SY: 1}))
+	outline := thickLine(document.Transform{X: 300, Y: 300, SX: 1, SY: 1})
+	outline.Type = document.ObjectTypeShapeRect
+	outline.Data = json.RawMessage(`{"width":100,"height":100}`)
+	outlineID := typeid.NewObjectID()
+	outline.ID, outline.Visible = outlineID, true
+	addChild(doc, doc.Scenes[doc.Project.Scenes[0]].Root, outline)
+	e := NewEngine()
+	e.ReplaceDocument(doc)
+	e.Render()
+
+	tests := []struct {
+		x, y float64
+		want string
+	}{
+		{100, 50, lineID},
+		{100, 54, lineID}, // Within half the stroke width
+		{100, 56, ""},
+		{45, 50, lineID}, // Half the width past the end
+		{44, 50, ""},
+		{300, 350, outlineID},
+		{303, 350, outlineID},
+		{350, 350, ""}, // An unfilled shape's interior is not a hit
+	}
+	for _, tt := range tests {
+		if got := e.HitTest(tt.x, tt.y); got != tt.want {
+			t.Errorf("hit at %v,%v = %q, want %q", tt.x, tt.y, got, tt.want)
+		}
+	}
+
+	// Filled, the interior hits
+	filled := doc.Objects[outlineID]
+	filled.Style.Fill = "#ff0000"
+	doc.Objects[outlineID] = filled
+	e.ReplaceDocument(doc)
+	e.Render()
+	if got := e.HitTest(350, 350); got != outlineID {
+		t.Errorf("filled interior hit = %q, want the rect", got)
+	}
+}
+
+// TestMarqueeReachesStroke checks a marquee over a thick line's stroke, but
+// not its zero-height geometry, finds it.
+func TestMarqueeReachesStroke(t *testing.T) {
+	doc, lineID := strokedDoc(thickLine(document.Transform{X: 50, Y: 50, SX: 1, SY: 1}))
+	e := NewEngine()
+	e.ReplaceDocument(doc)
+	e.Render()
+
+	var ids []string
+	if err := json.Unmarshal([]byte(e.GetVisibleObjects(Rect{X: 60, Y: 52, Width: 10, Height: 2})), &ids); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(ids, lineID) {
+		t.Errorf("marquee over the stroke found %v", ids)
+	}
+	json.Unmarshal([]byte(e.GetVisibleObjects(Rect{X: 60, Y: 57, Width: 10, Height: 2})), &ids)
+	if slices.Contains(ids, lineID) {
+		t.Error("marquee below the stroke found the line")
+	}
+}
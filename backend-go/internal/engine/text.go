@@ -0,0 +1,161 @@
+package engine
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// Text layout uses embedded Helvetica / Helvetica-Bold advance widths (from
+// the standard AFM metrics, in 1/1000 em) so the editor and server-side export
+// wrap text identically regardless of which fonts the browser has installed.
+// Characters outside printable ASCII use the average glyph width.
+
+const (
+	fontAscent      = 0.718 // Helvetica Ascender, em
+	fontDescent     = 0.207 // Helvetica Descender (magnitude), em
+	lineHeightRatio = 1.2
+	defaultAdvance  = 556
+)
+
+// helveticaWidths holds advance widths for ASCII 32..126.
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278, // space - /
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, // 0 - 9
+	278, 278, 584, 584, 584, 556, 1015, // : - @
+	667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, // A - M
+	722, 778, 667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, // N - Z
+	278, 278, 278, 469, 556, 333, // [ - `
+	556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, // a - m
+	556, 556, 556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, // n - z
+	334, 260, 334, 584, // { - ~
+}
+
+// helveticaBoldWidths holds bold advance widths for ASCII 32..126.
+var helveticaBoldWidths = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278, // space - /
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, // 0 - 9
+	333, 333, 584, 584, 584, 611, 975, // : - @
+	722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, // A - M
+	722, 778, 667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, // N - Z
+	333, 278, 333, 584, 556, 333, // [ - `
+	556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, // a - m
+	611, 611, 611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, // n - z
+	389, 280, 389, 584, // { - ~
+}
+
+// TextLayoutInput describes the text to measure. MaxWidth <= 0 disables wrapping.
+type TextLayoutInput struct {
+	Content       string  `json:"content"`
+	FontSize      float64 `json:"fontSize"`
+	FontWeight    string  `json:"fontWeight"`
+	LetterSpacing float64 `json:"letterSpacing"`
+	MaxWidth      float64 `json:"maxWidth"`
+}
+
+// TextLayout is the measured result. Baselines are y offsets from the top of
+// the text box, one per line.
+type TextLayout struct {
+	Lines     []string  `json:"lines"`
+	Width     float64   `json:"width"`
+	Height    float64   `json:"height"`
+	Baselines []float64 `json:"baselines"`
+}
+
+// LayoutText breaks content into lines that fit MaxWidth and measures them.
+// Explicit newlines always break; otherwise lines wrap at spaces, and words
+// wider than MaxWidth are broken between characters.
+func LayoutText(in TextLayoutInput) TextLayout {
+	widths := &helveticaWidths
+	if in.FontWeight == "bold" {
+		widths = &helveticaBoldWidths
+	}
+	measure := func(s string) float64 {
+		return measureLine(s, in.FontSize, in.LetterSpacing, widths)
+	}
+
+	var lines []string
+	for _, para := range strings.Split(in.Content, "\n") {
+		lines = append(lines, wrapParagraph(para, in.MaxWidth, measure)...)
+	}
+
+	lineHeight := in.FontSize * lineHeightRatio
+	halfLeading := (lineHeight - (fontAscent+fontDescent)*in.FontSize) / 2
+
+	layout := TextLayout{
+		Lines:     lines,
+		Height:    lineHeight * float64(len(lines)),
+		Baselines: make([]float64, len(lines)),
+	}
+	for i, line := range lines {
+		layout.Width = math.Max(layout.Width, measure(line))
+		layout.Baselines[i] = float64(i)*lineHeight + halfLeading + fontAscent*in.FontSize
+	}
+	return layout
+}
+
+// measureLine returns the advance width of a single line.
+func measureLine(s string, fontSize, letterSpacing float64, widths *[95]int) float64 {
+	n := 0
+	units := 0
+	for _, r := range s {
+		if r >= 32 && r <= 126 {
+			units += widths[r-32]
+		} else {
+			units += defaultAdvance
+		}
+		n++
+	}
+	return float64(units)/1000*fontSize + letterSpacing*math.Max(float64(n-1), 0)
+}
+
+// wrapParagraph greedily wraps a paragraph without newlines.
+func wrapParagraph(para string, maxWidth float64, measure func(string) float64) []string {
+	if maxWidth <= 0 || measure(para) <= maxWidth {
+		return []string{para}
+	}
+
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(para) {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+		if measure(candidate) <= maxWidth {
+			line = candidate
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+			line = ""
+		}
+		// Break words that cannot fit on a line of their own
+		for measure(word) > maxWidth && utf8.RuneCountInString(word) > 1 {
+			runes := []rune(word)
+			cut := 1
+			for cut < len(runes) && measure(string(runes[:cut+1])) <= maxWidth {
+				cut++
+			}
+			lines = append(lines, string(runes[:cut]))
+			word = string(runes[cut:])
+		}
+		line = word
+	}
+	if line != "" || len(lines) == 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// MeasureText lays out text described by a TextLayoutInput JSON object and
+// returns the TextLayout as JSON.
+func (e *Engine) MeasureText(data json.RawMessage) string {
+	var in TextLayoutInput
+	if err := json.Unmarshal(data, &in); err != nil {
+		return `{"lines":[],"width":0,"height":0,"baselines":[]}`
+	}
+	result, _ := json.Marshal(LayoutText(in))
+	return string(result)
+}
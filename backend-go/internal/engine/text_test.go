@@ -0,0 +1,296 @@
+package engine
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// textDoc returns a document whose root holds a text object at (10, 10),
+// and the text's ID.
+func textDoc(data string) (*document.InDocument, string) {
+	rootID, textID := typeid.NewObjectID(), typeid.NewObjectID()
+	doc := document.NewEmptyDocument(typeid.NewProjectID(), "Text", typeid.NewSceneID(), rootID, typeid.NewTimelineID())
+	addChild(doc, rootID, document.ObjectNode{
+		ID:        textID,
+		Type:      document.ObjectTypeText,
+		Transform: document.Transform{X: 10, Y: 10, SX: 1, SY: 1},
+		Style:     document.Style{Fill: "#000000", Opacity: 1},
+		Visible:   true,
+		Data:      json.RawMessage(data),
+	})
+	return doc, textID
+}
+
+func addChild(doc *document.InDocument, parentID string, obj document.ObjectNode) {
+	parent := doc.Objects[parentID]
+	parent.Children = append(parent.Children, obj.ID)
+	doc.Objects[parentID] = parent
+	obj.Parent = &parentID
+	obj.Children = []string{}
+	doc.Objects[obj.ID] = obj
+}
+
+// animate adds a linear track on the root timeline taking property through
+// values at frames.
+func animate(doc *document.InDocument, objectID, property string, frames []int, values []float64) {
+	animateIn(doc, doc.Project.RootTimeline, objectID, property, frames, values)
+}
+
+// animateIn adds a linear track to a timeline taking property through values
+// at frames.
+func animateIn(doc *document.InDocument, timelineID, objectID, property string, frames []int, values []float64) {
+	raw := make([]string, len(values))
+	for i, v := range values {
+		raw[i] = fmt.Sprint(v)
+	}
+	keyTrack(doc, timelineID, objectID, property, frames, raw)
+}
+
+// keyTrack adds a linear track to a timeline with a keyframe at each frame
+// holding the matching JSON value, and returns the track's ID.
+func keyTrack(doc *document.InDocument, timelineID, objectID, property string, frames []int, values []string) string {
+	track := document.Track{ID: typeid.NewTrackID(), ObjectID: objectID, Property: property}
+	for i, frame := range frames {
+		kf := document.Keyframe{
+			ID:     typeid.NewKeyframeID(),
+			Frame:  frame,
+			Value:  json.RawMessage(values[i]),
+			Easing: document.EasingLinear,
+		}
+		doc.Keyframes[kf.ID] = kf
+		track.Keys = append(track.Keys, kf.ID)
+	}
+	doc.Tracks[track.ID] = track
+	timeline := doc.Timelines[timelineID]
+	timeline.Tracks = append(timeline.Tracks, track.ID)
+	doc.Timelines[timeline.ID] = timeline
+	return track.ID
+}
+
+func buildAt(doc *document.InDocument, frame float64) *SceneGraph {
+	return BuildSceneGraph(doc, doc.Project.Scenes[0], frame, doc.Project.RootTimeline, false, nil)
+}
+
+func TestTextBoundsFollowAlignment(t *testing.T) {
+	tests := []struct {
+		align string
+		left  func(width float64) float64 // Left edge of the bounds for the text at x 10
+	}{
+		{"", func(w float64) float64 { return 10 }},
+		{"left", func(w float64) float64 { return 10 }},
+		{"center", func(w float64) float64 { return 10 - w/2 }},
+		{"right", func(w float64) float64 { return 10 - w }},
+		{"end", func(w float64) float64 { return 10 - w }},
+	}
+	for _, tt := range tests {
+		t.Run(cmp.Or(tt.align, "default"), func(t *testing.T) {
+			doc, textID := textDoc(`{"content":"Hello","fontSize":20,"textAlign":"` + tt.align + `"}`)
+			sg := buildAt(doc, 0)
+			bounds := sg.NodesById[textID].Bounds
+			if bounds.IsEmpty() || bounds.Width <= 0 || bounds.Height <= 0 {
+				t.Fatalf("bounds %+v, want the measured text", bounds)
+			}
+			if want := tt.left(bounds.Width); !near(bounds.X, want) || !near(bounds.Y, 10) {
+				t.Errorf("bounds at (%v, %v), want (%v, 10)", bounds.X, bounds.Y, want)
+			}
+
+			var text *DrawCommand
+			for _, cmd := range CompileDrawCommands(sg) {
+				if cmd.Op == "text" && cmd.ObjectID == textID {
+					text = &cmd
+				}
+			}
+			if text == nil || text.TextContent != "Hello" || text.TextFontSize != 20 || text.TextAlign != tt.align {
+				t.Fatalf("text command %+v, want Hello at 20px aligned %q", text, tt.align)
+			}
+
+			// Hit testing uses the aligned bounds
+			midX, midY := bounds.X+bounds.Width/2, bounds.Y+bounds.Height/2
+			if got := HitTest(sg, midX, midY); got != textID {
+				t.Errorf("hit at the bounds' middle = %q, want the text", got)
+			}
+			if got := HitTest(sg, bounds.X-5, midY); got == textID {
+				t.Errorf("hit left of the bounds found the text")
+			}
+		})
+	}
+}
+
+func TestVisibleCharactersKeyframed(t *testing.T) {
+	doc, textID := textDoc(`{"content":"Hello, wörld","fontSize":20}`)
+	animate(doc, textID, "data.visibleCharacters", []int{0, 24}, []float64{0, 12})
+
+	tests := []struct {
+		frame float64
+		text  string
+	}{
+		{0, ""},
+		{1.9, ""},
+		{2, "H"},
+		{10.5, "Hello"},
+		{18, "Hello, wö"},
+		{24, "Hello, wörld"},
+		{30, "Hello, wörld"},
+	}
+	for _, tt := range tests {
+		node := buildAt(doc, tt.frame).NodesById[textID]
+		if node.TextContent != tt.text {
+			t.Errorf("frame %v: text %q, want %q", tt.frame, node.TextContent, tt.text)
+		}
+	}
+
+	// Evaluation floors rather than rounds, so characters appear one at a time
+	if v := EvaluateTimeline(doc, doc.Project.RootTimeline, 11.9).Numeric[textID]["data.visibleCharacters"]; v != 5 {
+		t.Errorf("visibleCharacters at 11.9 = %v, want 5", v)
+	}
+
+	// Bounds shrink with the hidden characters
+	empty, full := buildAt(doc, 0).NodesById[textID], buildAt(doc, 24).NodesById[textID]
+	if empty.Bounds.Width >= full.Bounds.Width {
+		t.Errorf("bounds width %v with no characters, %v with all", empty.Bounds.Width, full.Bounds.Width)
+	}
+}
+
+func TestVisibleCharactersOutOfRange(t *testing.T) {
+	for _, tt := range []struct {
+		data string
+		text string
+	}{
+		{`{"content":"abc","visibleCharacters":-2}`, ""},
+		{`{"content":"abc","visibleCharacters":1.99}`, "a"},
+		{`{"content":"abc","visibleCharacters":99}`, "abc"},
+		{`{"content":"abc"}`, "abc"},
+	} {
+		doc, textID := textDoc(tt.data)
+		if got := buildAt(doc, 0).NodesById[textID].TextContent; got != tt.text {
+			t.Errorf("%s: text %q, want %q", tt.data, got, tt.text)
+		}
+	}
+}
+
+func TestTextOnPath(t *testing.T) {
+	pathID := typeid.NewObjectID()
+	doc, textID := textDoc(fmt.Sprintf(`{"content":"along","fontSize":10,"pathId":%q,"pathOffset":5}`, pathID))
+	addChild(doc, doc.Scenes[doc.Project.Scenes[0]].Root, document.ObjectNode{
+		ID:        pathID,
+		Type:      document.ObjectTypeVectorPath,
+		Transform: document.Transform{X: 100, Y: 50, SX: 1, SY: 1},
+		Style:     document.Style{Stroke: "#000000", Opacity: 1},
+		Visible:   true,
+		Data:      json.RawMessage(`{"commands":[["M",0,0],["L",200,0],["L",200,100]]}`),
+	})
+	animate(doc, textID, "data.pathOffset", []int{0, 10}, []float64{5, 105})
+
+	node := buildAt(doc, 5).NodesById[textID]
+	if node.TextPathOffset != 55 {
+		t.Errorf("path offset %v, want 55", node.TextPathOffset)
+	}
+
+	// The path is in the text's local space: offset by the path's position
+	// less the text's
+	want := [][2]float64{{90, 40}, {290, 40}, {290, 140}}
+	if len(node.TextPath) != len(want) {
+		t.Fatalf("text path %v, want %d commands", node.TextPath, len(want))
+	}
+	for i, cmd := range node.TextPath {
+		if x, y := toFloat64(cmd[1]), toFloat64(cmd[2]); !near(x, want[i][0]) || !near(y, want[i][1]) {
+			t.Errorf("command %d at (%v, %v), want (%v, %v)", i, x, y, want[i][0], want[i][1])
+		}
+	}
+
+	// Bounds are the path's, in world space, padded by a line height
+	wantBounds := Rect{X: 100 - 12, Y: 50 - 12, Width: 200 + 24, Height: 100 + 24}
+	if b := node.Bounds; !near(b.X, wantBounds.X) || !near(b.Y, wantBounds.Y) || !near(b.Width, wantBounds.Width) || !near(b.Height, wantBounds.Height) {
+		t.Errorf("bounds %+v, want %+v", b, wantBounds)
+	}
+
+	// Moving the text leaves the glyphs on the path
+	animate(doc, textID, "transform.x", []int{0}, []float64{60})
+	if b := buildAt(doc, 0).NodesById[textID].Bounds; !near(b.X, wantBounds.X) || !near(b.Width, wantBounds.Width) {
+		t.Errorf("bounds after moving the text %+v, want %+v", b, wantBounds)
+	}
+}
+
+func TestTextOnMissingPath(t *testing.T) {
+	doc, textID := textDoc(`{"content":"plain","fontSize":10,"pathId":"obj_missing"}`)
+	node := buildAt(doc, 0).NodesById[textID]
+	if node.TextPath != nil {
+		t.Errorf("text path %v for a missing path object", node.TextPath)
+	}
+	if node.Bounds.Width <= 0 || node.Bounds.X != 10 {
+		t.Errorf("bounds %+v, want the text's own layout", node.Bounds)
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestMeasureTextWrapsTwoWords(t *testing.T) {
+	var layout TextLayout
+	out := NewEngine().MeasureText(json.RawMessage(`{"content":"Hello world","fontSize":20,"maxWidth":60}`))
+	if err := json.Unmarshal([]byte(out), &layout); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(layout.Lines) != 2 || layout.Lines[0] != "Hello" || layout.Lines[1] != "world" {
+		t.Fatalf("lines %q, want Hello and world", layout.Lines)
+	}
+	// Two 1.2em lines; "world" is the wider at 2389/1000 em
+	if !near(layout.Height, 48) || !near(layout.Width, 47.78) {
+		t.Errorf("size %vx%v, want 47.78x48", layout.Width, layout.Height)
+	}
+	// Half the leading, then the ascent, down each line
+	if len(layout.Baselines) != 2 || !near(layout.Baselines[0], 17.11) || !near(layout.Baselines[1], 41.11) {
+		t.Errorf("baselines %v, want 17.11 and 41.11", layout.Baselines)
+	}
+
+	if unwrapped := LayoutText(TextLayoutInput{Content: "Hello world", FontSize: 20}); len(unwrapped.Lines) != 1 || !near(unwrapped.Width, 98.9) {
+		t.Errorf("without a width: %q at %v, want one line at 98.9", unwrapped.Lines, unwrapped.Width)
+	}
+}
+
+func TestLayoutTextBreaks(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    TextLayoutInput
+		lines []string
+	}{
+		{"newlines", TextLayoutInput{Content: "one\ntwo\n\nfour", FontSize: 10}, []string{"one", "two", "", "four"}},
+		{"fits", TextLayoutInput{Content: "a b c", FontSize: 10, MaxWidth: 100}, []string{"a b c"}},
+		{"greedy", TextLayoutInput{Content: "aa bb cc dd", FontSize: 10, MaxWidth: 26}, []string{"aa bb", "cc dd"}},
+		{"long word", TextLayoutInput{Content: "mmmmmm", FontSize: 10, MaxWidth: 20}, []string{"mm", "mm", "mm"}},
+		{"spacing", TextLayoutInput{Content: "aa bb", FontSize: 10, MaxWidth: 26, LetterSpacing: 2}, []string{"aa", "bb"}},
+		{"regular", TextLayoutInput{Content: "ab ab", FontSize: 10, MaxWidth: 25.5}, []string{"ab ab"}},
+		{"bold", TextLayoutInput{Content: "ab ab", FontSize: 10, MaxWidth: 25.5, FontWeight: "bold"}, []string{"ab", "ab"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := LayoutText(tt.in).Lines
+			if len(got) != len(tt.lines) {
+				t.Fatalf("lines %q, want %q", got, tt.lines)
+			}
+			for i := range got {
+				if got[i] != tt.lines[i] {
+					t.Errorf("lines %q, want %q", got, tt.lines)
+				}
+			}
+		})
+	}
+}
+
+func TestMeasureTextInvalidInput(t *testing.T) {
+	var layout TextLayout
+	if err := json.Unmarshal([]byte(NewEngine().MeasureText(json.RawMessage(`{`))), &layout); err != nil {
+		t.Fatal(err)
+	}
+	if len(layout.Lines) != 0 || layout.Width != 0 || layout.Height != 0 {
+		t.Errorf("layout %+v for invalid input, want empty", layout)
+	}
+}
@@ -0,0 +1,358 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/inamate/inamate/backend-go/internal/auth"
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/httperr"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+const testProjectID = "proj_01h455vb4pex5vsknk084sn02q"
+
+// accessHandler returns a handler whose only member of testProjectID is
+// "member", and a pointer to how many times a project was loaded.
+func accessHandler(t *testing.T) (*Handler, *int) {
+	t.Helper()
+	loads := 0
+	h := NewHandler("true", func(ctx context.Context, projectID string) (*document.InDocument, error) {
+		loads++
+		return document.NewEmptyDocument(projectID, "Test", typeid.NewSceneID(), typeid.NewObjectID(), typeid.NewTimelineID()), nil
+	}, nil)
+	h.SetMemberCheck(func(ctx context.Context, projectID, userID string) (bool, error) {
+		return projectID == testProjectID && userID == "member", nil
+	})
+	return h, &loads
+}
+
+// exportForm builds a multipart export body with the given fields and no
+// frames.
+func exportForm(t *testing.T, fields map[string]string) (*bytes.Buffer, string) {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for k, v := range fields {
+		mw.WriteField(k, v)
+	}
+	mw.Close()
+	return &body, mw.FormDataContentType()
+}
+
+// asProjectRoute makes r look routed through
+// /api/projects/{projectId}/export by userID.
+func asProjectRoute(r *http.Request, projectID, userID string) *http.Request {
+	r = mux.SetURLVars(r, map[string]string{"projectId": projectID})
+	if userID != "" {
+		r = r.WithContext(context.WithValue(r.Context(), auth.UserIDKey, userID))
+	}
+	return r
+}
+
+func errorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body struct {
+		Code string `json:"code"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	return body.Code
+}
+
+func TestExportVideoRefusesProjectOnPublicRoute(t *testing.T) {
+	h, loads := accessHandler(t)
+	body, contentType := exportForm(t, map[string]string{"format": "mp4", "projectId": testProjectID})
+	r := httptest.NewRequest(http.MethodPost, "/export/video", body)
+	r.Header.Set("Content-Type", contentType)
+	rec := httptest.NewRecorder()
+
+	h.ExportVideo(rec, r)
+
+	if rec.Code != http.StatusBadRequest || errorCode(t, rec) != "validation_failed" {
+		t.Fatalf("status = %d, want 400 validation_failed: %s", rec.Code, rec.Body)
+	}
+	if *loads != 0 {
+		t.Errorf("project loaded %d times for an anonymous caller", *loads)
+	}
+}
+
+func TestExportVideoProjectRouteChecksMembership(t *testing.T) {
+	tests := []struct {
+		name      string
+		userID    string
+		wantCode  int
+		wantLoads int
+	}{
+		{"member", "member", http.StatusBadRequest, 1}, // Gets as far as "no frames uploaded"
+		{"non-member", "stranger", http.StatusForbidden, 0},
+		{"unauthenticated", "", http.StatusForbidden, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, loads := accessHandler(t)
+			body, contentType := exportForm(t, map[string]string{"format": "mp4"})
+			r := httptest.NewRequest(http.MethodPost, "/api/projects/"+testProjectID+"/export/video", body)
+			r.Header.Set("Content-Type", contentType)
+			r = asProjectRoute(r, testProjectID, tt.userID)
+			rec := httptest.NewRecorder()
+
+			h.ExportVideo(rec, r)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if tt.wantCode == http.StatusForbidden && errorCode(t, rec) != "not_a_member" {
+				t.Errorf("code = %q, want not_a_member", errorCode(t, rec))
+			}
+			if *loads != tt.wantLoads {
+				t.Errorf("project loaded %d times, want %d", *loads, tt.wantLoads)
+			}
+		})
+	}
+}
+
+func TestExportVideoRejectsMismatchedProject(t *testing.T) {
+	h, loads := accessHandler(t)
+	body, contentType := exportForm(t, map[string]string{"format": "mp4", "projectId": "proj_01h455vb4pex5vsknk084sn02r"})
+	r := httptest.NewRequest(http.MethodPost, "/api/projects/"+testProjectID+"/export/video", body)
+	r.Header.Set("Content-Type", contentType)
+	r = asProjectRoute(r, testProjectID, "member")
+	rec := httptest.NewRecorder()
+
+	h.ExportVideo(rec, r)
+
+	if rec.Code != http.StatusBadRequest || *loads != 0 {
+		t.Fatalf("status = %d, loads = %d; want 400 and no load", rec.Code, *loads)
+	}
+}
+
+func TestCreateJobChecksProjectAccess(t *testing.T) {
+	tests := []struct {
+		name     string
+		route    bool
+		userID   string
+		wantCode int
+	}{
+		{"public route", false, "", http.StatusBadRequest},
+		{"non-member", true, "stranger", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, loads := accessHandler(t)
+			body, contentType := exportForm(t, map[string]string{"format": "mp4", "projectId": testProjectID, "scenes": "all"})
+			r := httptest.NewRequest(http.MethodPost, "/export/jobs", body)
+			r.Header.Set("Content-Type", contentType)
+			if tt.route {
+				r = asProjectRoute(r, testProjectID, tt.userID)
+			}
+			rec := httptest.NewRecorder()
+
+			h.CreateJob(rec, r)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if *loads != 0 {
+				t.Errorf("project loaded %d times", *loads)
+			}
+			if len(h.jobs.jobs) != 0 {
+				t.Errorf("job was queued")
+			}
+		})
+	}
+}
+
+func TestRenderVideoChecksProjectAccess(t *testing.T) {
+	tests := []struct {
+		name     string
+		route    bool
+		userID   string
+		body     string
+		wantCode int
+	}{
+		{"public route with projectId", false, "", `{"format":"mp4","projectId":"` + testProjectID + `"}`, http.StatusBadRequest},
+		{"non-member", true, "stranger", `{"format":"mp4"}`, http.StatusForbidden},
+		{"unauthenticated", true, "", `{"format":"mp4"}`, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, loads := accessHandler(t)
+			r := httptest.NewRequest(http.MethodPost, "/export/render", strings.NewReader(tt.body))
+			if tt.route {
+				r = asProjectRoute(r, testProjectID, tt.userID)
+			}
+			rec := httptest.NewRecorder()
+
+			h.RenderVideo(rec, r)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if *loads != 0 {
+				t.Errorf("project loaded %d times", *loads)
+			}
+		})
+	}
+}
+
+func TestRenderVideoMemberLoadsRouteProject(t *testing.T) {
+	h, loads := accessHandler(t)
+	r := httptest.NewRequest(http.MethodPost, "/api/projects/"+testProjectID+"/export/render",
+		strings.NewReader(`{"format":"mp4","sceneId":"scene_01h455vb4pex5vsknk084sn02q"}`))
+	r = asProjectRoute(r, testProjectID, "member")
+	rec := httptest.NewRecorder()
+
+	h.RenderVideo(rec, r)
+
+	// The empty project has no such scene, so the render stops after loading it.
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400: %s", rec.Code, rec.Body)
+	}
+	if *loads != 1 {
+		t.Errorf("project loaded %d times, want 1", *loads)
+	}
+}
+
+func TestExportsDisabled(t *testing.T) {
+	endpoints := []struct {
+		name   string
+		handle func(*Handler, http.ResponseWriter, *http.Request)
+	}{
+		{"ExportVideo", (*Handler).ExportVideo},
+		{"CreateJob", (*Handler).CreateJob},
+		{"RenderVideo", (*Handler).RenderVideo},
+	}
+	for _, e := range endpoints {
+		t.Run(e.name, func(t *testing.T) {
+			h, loads := accessHandler(t)
+			h.SetEnabled(false)
+			body, contentType := exportForm(t, map[string]string{"format": "mp4", "scenes": "all"})
+			r := httptest.NewRequest(http.MethodPost, "/api/projects/"+testProjectID+"/export", body)
+			r.Header.Set("Content-Type", contentType)
+			rec := httptest.NewRecorder()
+
+			e.handle(h, rec, asProjectRoute(r, testProjectID, "member"))
+
+			if rec.Code != http.StatusForbidden || errorCode(t, rec) != httperr.CodeFeatureDisabled {
+				t.Errorf("status %d code %q, want 403 %s", rec.Code, errorCode(t, rec), httperr.CodeFeatureDisabled)
+			}
+			if *loads != 0 || len(h.jobs.jobs) != 0 {
+				t.Errorf("disabled export loaded the project %d times and queued %d jobs", *loads, len(h.jobs.jobs))
+			}
+		})
+	}
+}
+
+// finishedJob registers a completed background job of projectID ("" for a
+// public one) with a download ready.
+func finishedJob(t *testing.T, h *Handler, projectID string) string {
+	t.Helper()
+	jobID := typeid.NewExportID()
+	dir := t.TempDir()
+	output := filepath.Join(dir, "animation-intro.mp4")
+	if err := os.WriteFile(output, []byte("video"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	status := &JobStatus{ID: jobID, ProjectID: projectID, Format: "mp4", State: JobCompleted,
+		Download: "animation-intro.mp4", CreatedAt: now, FinishedAt: &now}
+	if _, err := h.jobs.enqueue(jobID, dir, status); err != nil {
+		t.Fatal(err)
+	}
+	h.jobs.update(jobID, func(j *job) {
+		j.output, j.contentType = output, "video/mp4"
+		close(j.done)
+	})
+	return jobID
+}
+
+func TestJobRoutesCheckProjectAccess(t *testing.T) {
+	const otherProjectID = "proj_01h455vb4pex5vsknk084sn02r"
+	endpoints := []struct {
+		name   string
+		method string
+		handle func(*Handler, http.ResponseWriter, *http.Request)
+	}{
+		{"GetJob", http.MethodGet, (*Handler).GetJob},
+		{"DownloadJob", http.MethodGet, (*Handler).DownloadJob},
+		{"CancelJob", http.MethodDelete, (*Handler).CancelJob},
+	}
+	tests := []struct {
+		name       string
+		jobProject string // Project the job exports
+		route      string // Project route it's requested through, "" for the public one
+		userID     string
+		wantCode   int
+	}{
+		{"public job on public route", "", "", "", 0},
+		{"project job on public route", testProjectID, "", "", http.StatusNotFound},
+		{"member", testProjectID, testProjectID, "member", 0},
+		{"non-member", testProjectID, testProjectID, "stranger", http.StatusForbidden},
+		{"unauthenticated", testProjectID, testProjectID, "", http.StatusForbidden},
+		{"another project's job", otherProjectID, testProjectID, "member", http.StatusNotFound},
+		{"public job on project route", "", testProjectID, "member", http.StatusNotFound},
+	}
+	for _, e := range endpoints {
+		for _, tt := range tests {
+			t.Run(e.name+"/"+tt.name, func(t *testing.T) {
+				h, _ := accessHandler(t)
+				jobID := finishedJob(t, h, tt.jobProject)
+				r := httptest.NewRequest(e.method, "/export/jobs/"+jobID, nil)
+				vars := map[string]string{"jobId": jobID}
+				if tt.route != "" {
+					r = asProjectRoute(r, tt.route, tt.userID)
+					vars["projectId"] = tt.route
+				}
+				r = mux.SetURLVars(r, vars)
+				rec := httptest.NewRecorder()
+
+				e.handle(h, rec, r)
+
+				if tt.wantCode == 0 {
+					if rec.Code >= 300 {
+						t.Fatalf("status = %d, want success: %s", rec.Code, rec.Body)
+					}
+					return
+				}
+				if rec.Code != tt.wantCode {
+					t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+				}
+				if _, ok := h.jobs.jobs[jobID]; !ok {
+					t.Errorf("refused request discarded the job")
+				}
+			})
+		}
+	}
+}
+
+func TestCreateJobLocationUnderProjectRoute(t *testing.T) {
+	h, _ := accessHandler(t)
+	// Its one scene has no frames, so the job fails once run; it's queued all the same
+	body, contentType := exportForm(t, map[string]string{"format": "mp4", "scenes": "all"})
+	r := httptest.NewRequest(http.MethodPost, "/api/projects/"+testProjectID+"/export/jobs", body)
+	r.Header.Set("Content-Type", contentType)
+	rec := httptest.NewRecorder()
+
+	h.CreateJob(rec, asProjectRoute(r, testProjectID, "member"))
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want 202: %s", rec.Code, rec.Body)
+	}
+	var status JobStatus
+	json.Unmarshal(rec.Body.Bytes(), &status)
+	want := "/api/projects/" + testProjectID + "/export/jobs/" + status.ID
+	if got := rec.Header().Get("Location"); got != want {
+		t.Errorf("Location = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,559 @@
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/httperr"
+	"github.com/inamate/inamate/backend-go/internal/notification"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+	"github.com/inamate/inamate/backend-go/internal/webhook"
+)
+
+// maxRunningJobs bounds how many background exports encode at once; the rest
+// wait queued.
+const maxRunningJobs = 2
+
+var formatContentTypes = map[string]string{"mp4": "video/mp4", "gif": "image/gif", "webm": "video/webm"}
+
+// sceneJob is one scene of a background export, with its frames already
+// copied into dir.
+type sceneJob struct {
+	sceneID  string
+	scene    *document.Scene // Nil when no project was given
+	dir      string
+	file     string // Output name within the job's workspace
+	padWidth int
+	frames   []frameInfo
+	failed   bool // Rejected before encoding; the status holds the reason
+}
+
+// requestJobID returns the job ID the client chose for an export, or a new
+// one if it didn't choose.
+func requestJobID(r *http.Request) (string, error) {
+	jobID := r.FormValue("jobId")
+	if jobID == "" {
+		return typeid.NewExportID(), nil
+	}
+	if err := typeid.Validate(jobID, typeid.PrefixExport); err != nil {
+		return "", err
+	}
+	return jobID, nil
+}
+
+// CreateJob starts a background export of one or more scenes from uploaded
+// frames keyed "<sceneId>/frame_<n>", with optional "<sceneId>/name" fields
+// naming scenes the server has no document for. The scenes field is "all" (every scene
+// of the project, in order, when exported through the project's route), a comma-separated list of scene IDs, or empty for
+// every scene with frames. Each scene is encoded to its own file named
+// {name}-{scene}.{format}, name defaulting to the project's name; a scene
+// that fails doesn't stop the others.
+// Progress is polled from the job's URL in the Location header (under the
+// project's route for a project's job) and the result, zipped when more than
+// one scene encoded, fetched from its download endpoint.
+func (h *Handler) CreateJob(w http.ResponseWriter, r *http.Request) {
+	if h.disabled {
+		httperr.Write(w, http.StatusForbidden, httperr.CodeFeatureDisabled, "exports are disabled")
+		return
+	}
+	if _, err := exec.LookPath(h.ffmpegPath); err != nil {
+		httperr.Write(w, http.StatusServiceUnavailable, httperr.CodeFfmpegUnavailable,
+			"video export is unavailable: ffmpeg was not found on the server")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		httperr.Write(w, http.StatusBadRequest, httperr.CodePayloadTooLarge, "request too large")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	jobID, err := requestJobID(r)
+	if err != nil {
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeInvalidID, "invalid job id")
+		return
+	}
+
+	format := r.FormValue("format")
+	if format != "mp4" && format != "gif" && format != "webm" {
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed, "invalid format: must be mp4, gif, or webm")
+		return
+	}
+	fps, err := strconv.Atoi(r.FormValue("fps"))
+	if err != nil || fps <= 0 || fps > 120 {
+		fps = 24
+	}
+	var doc *document.InDocument
+	projectID, err := h.requestProject(r, r.FormValue("projectId"))
+	if err != nil {
+		writeProjectError(w, err)
+		return
+	}
+	if projectID != "" {
+		doc, err = h.loadProject(r.Context(), projectID)
+		if errors.Is(err, context.DeadlineExceeded) {
+			httperr.Write(w, http.StatusGatewayTimeout, httperr.CodeTimeout, "timed out loading project")
+			return
+		}
+		if err != nil {
+			code := httperr.CodeValidationFailed
+			if errors.Is(err, errProjectNotFound) {
+				code = httperr.CodeProjectNotFound
+			}
+			httperr.Write(w, http.StatusBadRequest, code, err.Error())
+			return
+		}
+	}
+
+	// Files are named after the project unless the client names them
+	name := r.FormValue("name")
+	if name == "" && doc != nil {
+		name = doc.Project.Name
+	}
+	if name == "" {
+		name = "animation"
+	}
+	name = sanitizeName(name)
+
+	uploads, err := groupSceneFrames(r.MultipartForm.File)
+	if err != nil {
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeInvalidFrames, err.Error())
+		return
+	}
+	sceneIDs, err := selectScenes(r.FormValue("scenes"), doc, uploads)
+	if err != nil {
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed, err.Error())
+		return
+	}
+	if len(sceneIDs) == 0 {
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeInvalidFrames, "no frames uploaded")
+		return
+	}
+	for _, sceneID := range sceneIDs {
+		if n := len(uploads[sceneID]); n > h.maxFrames {
+			httperr.WriteDetails(w, http.StatusRequestEntityTooLarge, httperr.CodePayloadTooLarge,
+				fmt.Sprintf("too many frames in scene %s: %d exceeds the limit of %d", sceneID, n, h.maxFrames),
+				map[string]interface{}{"sceneId": sceneID, "frames": n, "maxFrames": h.maxFrames})
+			return
+		}
+	}
+
+	tempDir, err := os.MkdirTemp("", tempDirPattern)
+	if err != nil {
+		httperr.Internal(w, "create temp dir", err)
+		return
+	}
+
+	status := &JobStatus{
+		ID:        jobID,
+		ProjectID: projectID,
+		Format:    format,
+		State:     JobQueued,
+		CreatedAt: time.Now().UTC(),
+	}
+	files := sceneFileNames(name, format, sceneIDs, doc, sceneLabels(r.MultipartForm.Value))
+	scenes := make([]*sceneJob, len(sceneIDs))
+	for i, sceneID := range sceneIDs {
+		sj := &sceneJob{sceneID: sceneID, dir: filepath.Join(tempDir, strconv.Itoa(i)), file: files[i]}
+		st := SceneStatus{SceneID: sceneID, File: files[i], State: JobQueued, Frames: len(uploads[sceneID])}
+		if reason, err := h.stageScene(sj, doc, uploads[sceneID]); err != nil {
+			os.RemoveAll(tempDir)
+			httperr.Internal(w, "write frame file", err)
+			return
+		} else if reason != "" {
+			sj.failed = true
+			st.State, st.Error = JobFailed, reason
+		}
+		scenes[i] = sj
+		status.Scenes = append(status.Scenes, st)
+	}
+
+	ctx, err := h.jobs.enqueue(jobID, tempDir, status)
+	if err != nil {
+		os.RemoveAll(tempDir)
+		httperr.Write(w, http.StatusConflict, httperr.CodeExportJobExists, "export job already running: "+jobID)
+		return
+	}
+	response := status.clone()
+	go h.runJob(ctx, jobID, tempDir, name, format, fps, scenes)
+
+	slog.Info("export job queued", "job_id", jobID, "format", format, "scenes", len(scenes), "fps", fps)
+	location := "/export/jobs/" + jobID
+	if projectID != "" {
+		location = "/api/projects/" + projectID + "/export/jobs/" + jobID
+	}
+	w.Header().Set("Location", location)
+	writeJSON(w, http.StatusAccepted, response)
+}
+
+// groupSceneFrames groups uploaded "<sceneId>/frame_<n>" files by scene,
+// ignoring other fields.
+func groupSceneFrames(files map[string][]*multipart.FileHeader) (map[string]map[string]*multipart.FileHeader, error) {
+	uploads := make(map[string]map[string]*multipart.FileHeader)
+	for key, headers := range files {
+		sceneID, frameKey, ok := strings.Cut(key, "/")
+		if !ok || !strings.HasPrefix(frameKey, "frame_") || len(headers) == 0 {
+			continue
+		}
+		if sceneID == "" {
+			return nil, fmt.Errorf("invalid frame key: %s", key)
+		}
+		if uploads[sceneID] == nil {
+			uploads[sceneID] = make(map[string]*multipart.FileHeader)
+		}
+		uploads[sceneID][frameKey] = headers[0]
+	}
+	return uploads, nil
+}
+
+// selectScenes resolves the scenes field to the ordered scene IDs to export.
+func selectScenes(selection string, doc *document.InDocument, uploads map[string]map[string]*multipart.FileHeader) ([]string, error) {
+	switch strings.TrimSpace(selection) {
+	case "all":
+		if doc == nil {
+			return nil, fmt.Errorf(`scenes "all" requires exporting through the project's route`)
+		}
+		return doc.Project.Scenes, nil
+
+	case "":
+		// Every scene with frames: document order first, then any the
+		// document doesn't know in ID order
+		var ids []string
+		if doc != nil {
+			for _, id := range doc.Project.Scenes {
+				if len(uploads[id]) > 0 {
+					ids = append(ids, id)
+				}
+			}
+		}
+		var rest []string
+		for id := range uploads {
+			if !slices.Contains(ids, id) {
+				rest = append(rest, id)
+			}
+		}
+		sort.Strings(rest)
+		return append(ids, rest...), nil
+	}
+
+	var ids []string
+	for _, raw := range strings.Split(selection, ",") {
+		id := strings.TrimSpace(raw)
+		if id == "" {
+			continue
+		}
+		if slices.Contains(ids, id) {
+			return nil, fmt.Errorf("scene listed twice: %s", id)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// sceneLabels collects the "<sceneId>/name" fields of an export request.
+func sceneLabels(values map[string][]string) map[string]string {
+	labels := make(map[string]string)
+	for key, vals := range values {
+		if sceneID, ok := strings.CutSuffix(key, "/name"); ok && len(vals) > 0 && vals[0] != "" {
+			labels[sceneID] = vals[0]
+		}
+	}
+	return labels
+}
+
+// sceneFileNames names each scene's output {name}-{scene}.{format}, using the
+// scene's name from the document, then the client's label, then its ID.
+// Scenes whose names collide get a numeric suffix.
+func sceneFileNames(name, format string, sceneIDs []string, doc *document.InDocument, labels map[string]string) []string {
+	files := make([]string, len(sceneIDs))
+	used := make(map[string]bool)
+	for i, sceneID := range sceneIDs {
+		label := sceneID
+		if l, ok := labels[sceneID]; ok {
+			label = l
+		}
+		if doc != nil {
+			if scene, ok := doc.Scenes[sceneID]; ok && scene.Name != "" {
+				label = scene.Name
+			}
+		}
+		base := name + "-" + sanitizeName(label)
+		file := base + "." + format
+		for n := 2; used[file]; n++ {
+			file = fmt.Sprintf("%s-%d.%s", base, n, format)
+		}
+		used[file] = true
+		files[i] = file
+	}
+	return files
+}
+
+// stageScene copies a scene's uploaded frames into its directory. A problem
+// with the scene itself is returned as a reason for it to fail; the error is
+// reserved for server-side I/O failures.
+func (h *Handler) stageScene(sj *sceneJob, doc *document.InDocument, uploads map[string]*multipart.FileHeader) (string, error) {
+	if doc != nil {
+		scene, err := sceneOf(doc, sj.sceneID)
+		if err != nil {
+			return err.Error(), nil
+		}
+		sj.scene = scene
+	}
+	if len(uploads) == 0 {
+		return "no frames uploaded for scene", nil
+	}
+
+	indices := make(map[string]int, len(uploads))
+	maxIndex := 0
+	for key := range uploads {
+		idx, err := strconv.Atoi(strings.TrimPrefix(key, "frame_"))
+		if err != nil || idx < 0 {
+			return "invalid frame key: " + sj.sceneID + "/" + key, nil
+		}
+		indices[key] = idx
+		maxIndex = max(maxIndex, idx)
+	}
+	sj.padWidth = max(4, len(strconv.Itoa(maxIndex)))
+
+	if err := os.Mkdir(sj.dir, 0o755); err != nil {
+		return "", err
+	}
+	for key, fh := range uploads {
+		path := filepath.Join(sj.dir, fmt.Sprintf("frame_%0*d.png", sj.padWidth, indices[key]))
+		frame, err := writeFrame(fh, sj.sceneID+"/"+key, indices[key], path)
+		var badFrame *frameError
+		if errors.As(err, &badFrame) {
+			return badFrame.msg, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		sj.frames = append(sj.frames, frame)
+	}
+	return "", nil
+}
+
+// runJob encodes a background export's scenes in order once a worker slot is
+// free, then packages whatever succeeded.
+func (h *Handler) runJob(ctx context.Context, jobID, dir, name, format string, fps int, scenes []*sceneJob) {
+	select {
+	case h.slots <- struct{}{}:
+		defer func() { <-h.slots }()
+	case <-ctx.Done():
+		h.endJob(ctx, jobID, dir, name, format, fps)
+		return
+	}
+
+	h.active.Add(1)
+	defer h.active.Add(-1)
+	h.jobs.update(jobID, func(j *job) { j.status.State = JobRunning })
+
+	for i, sj := range scenes {
+		if ctx.Err() != nil {
+			break
+		}
+		if sj.failed {
+			continue
+		}
+		h.jobs.update(jobID, func(j *job) { j.status.Scenes[i].State = JobRunning })
+
+		output, reason := h.encodeScene(ctx, sj, format, fps, func(n int) {
+			h.jobs.update(jobID, func(j *job) { j.status.Scenes[i].Encoded = n })
+		})
+		if ctx.Err() != nil {
+			break
+		}
+
+		var size int64
+		if reason == "" {
+			dest := filepath.Join(dir, sj.file)
+			if err := os.Rename(output, dest); err != nil {
+				reason = "move output: " + err.Error()
+			} else if info, err := os.Stat(dest); err == nil {
+				size = info.Size()
+			}
+		}
+		os.RemoveAll(sj.dir)
+
+		h.jobs.update(jobID, func(j *job) {
+			st := &j.status.Scenes[i]
+			if reason != "" {
+				st.State, st.Error = JobFailed, reason
+				return
+			}
+			st.State, st.Size, st.Encoded = JobCompleted, size, st.Frames
+		})
+		if reason != "" {
+			slog.Warn("export scene failed", "job_id", jobID, "scene_id", sj.sceneID, "reason", reason)
+		}
+	}
+
+	h.endJob(ctx, jobID, dir, name, format, fps)
+}
+
+// encodeScene validates a scene's frames and encodes them, returning the
+// output file or the reason the scene failed.
+func (h *Handler) encodeScene(ctx context.Context, sj *sceneJob, format string, fps int, onFrame func(int)) (string, string) {
+	frames := sj.frames
+	sort.Slice(frames, func(i, j int) bool { return frames[i].index < frames[j].index })
+	if gaps := findGaps(frames); gaps != nil {
+		return "", gaps.message()
+	}
+
+	width, height := frames[0].width, frames[0].height
+	background := ""
+	if sj.scene != nil {
+		width, height = sj.scene.Width, sj.scene.Height
+		if hexColorPattern.MatchString(sj.scene.Background) {
+			background = sj.scene.Background
+		}
+	}
+	if offenders := mismatchedFrames(frames, width, height); len(offenders) > 0 {
+		return "", fmt.Sprintf("frame dimensions must all be %dx%d; mismatched frames: %s", width, height, strings.Join(offenders, ", "))
+	}
+
+	inputPattern := filepath.Join(sj.dir, fmt.Sprintf("frame_%%0%dd.png", sj.padWidth))
+	output, _, err := h.encode(ctx, sj.dir, inputPattern, format, fps, width, height, background, onFrame)
+	if errors.Is(err, ErrFfmpegUnavailable) {
+		return "", "video export is unavailable: ffmpeg could not be executed on the server"
+	}
+	if err != nil {
+		return "", fmt.Sprintf("encoding failed: %v", err)
+	}
+	return output, ""
+}
+
+// endJob settles a background job's final state. A cancelled job loses its
+// workspace; otherwise the encoded scenes become the download, zipped when
+// there are several.
+func (h *Handler) endJob(ctx context.Context, jobID, dir, name, format string, fps int) {
+	cancelled := errors.Is(context.Cause(ctx), errJobCancelled)
+
+	var done []SceneStatus
+	h.jobs.update(jobID, func(j *job) {
+		for _, st := range j.status.Scenes {
+			if st.State == JobCompleted {
+				done = append(done, st)
+			}
+		}
+	})
+
+	state := JobCompleted
+	var output, contentType, download, jobErr string
+	switch {
+	case cancelled:
+		state = JobCancelled
+		os.RemoveAll(dir)
+		h.cancelled.Add(1)
+
+	case len(done) == 0:
+		state, jobErr = JobFailed, "no scene could be exported"
+
+	case len(done) == 1:
+		output, download = filepath.Join(dir, done[0].File), done[0].File
+		contentType = formatContentTypes[format]
+
+	default:
+		download = name + ".zip"
+		output, contentType = filepath.Join(dir, download), "application/zip"
+		files := make([]string, len(done))
+		for i, st := range done {
+			files[i] = st.File
+		}
+		if err := zipFiles(output, dir, files); err != nil {
+			slog.Error("zip export", "job_id", jobID, "error", err)
+			state, jobErr, output, download = JobFailed, "failed to package scenes", "", ""
+		}
+	}
+	if state == JobFailed {
+		h.failed.Add(1)
+	} else if state == JobCompleted {
+		h.completed.Add(1)
+	}
+
+	now := time.Now().UTC()
+	var status JobStatus
+	h.jobs.update(jobID, func(j *job) {
+		j.status.State, j.status.Error, j.status.Download = state, jobErr, download
+		j.status.FinishedAt = &now
+		for i := range j.status.Scenes {
+			if st := &j.status.Scenes[i]; cancelled && (st.State == JobQueued || st.State == JobRunning) {
+				st.State = JobCancelled
+			}
+		}
+		j.output, j.contentType = output, contentType
+		status = j.status.clone()
+		close(j.done)
+	})
+	slog.Info("export job finished", "job_id", jobID, "state", state, "scenes", len(status.Scenes), "encoded", len(done))
+
+	if state == JobCompleted && status.ProjectID != "" {
+		var size int64
+		for _, st := range done {
+			size += st.Size
+		}
+		h.webhooks.Dispatch(status.ProjectID, webhook.EventExportCompleted, map[string]interface{}{
+			"format": format,
+			"name":   name,
+			"fps":    fps,
+			"size":   size,
+			"scenes": status.Scenes,
+		})
+		h.notes.NotifyMembers(status.ProjectID, notification.TypeExportCompleted, "", map[string]interface{}{
+			"format": format,
+			"name":   name,
+			"jobId":  jobID,
+		})
+	}
+}
+
+// zipFiles bundles the named files in dir into a zip at path. Video is
+// already compressed, so entries are stored as-is.
+func zipFiles(path, dir string, files []string) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	zw := zip.NewWriter(out)
+	for _, file := range files {
+		if err := addZipFile(zw, filepath.Join(dir, file), file); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+func addZipFile(zw *zip.Writer, path, name string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, f)
+	return err
+}
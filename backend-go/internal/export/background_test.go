@@ -0,0 +1,178 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+)
+
+func TestSceneFileNames(t *testing.T) {
+	doc := twoScenes()
+	intro, end := doc.Project.Scenes[0], doc.Project.Scenes[1]
+	tests := []struct {
+		name     string
+		sceneIDs []string
+		doc      *document.InDocument
+		labels   map[string]string
+		want     []string
+	}{
+		{"scene IDs", []string{"scene_1", "scene_2"}, nil, nil,
+			[]string{"promo-scene_1.mp4", "promo-scene_2.mp4"}},
+		{"client labels", []string{"scene_1", "scene_2"}, nil, map[string]string{"scene_1": "Act 1"},
+			[]string{"promo-Act-1.mp4", "promo-scene_2.mp4"}},
+		{"document names win", []string{intro, end}, doc, map[string]string{intro: "Opening"},
+			[]string{"promo-Intro.mp4", "promo-The-End.mp4"}},
+		{"duplicates numbered", []string{"scene_1", "scene_2", "scene_3"}, nil,
+			map[string]string{"scene_1": "Intro", "scene_2": "Intro", "scene_3": "Intro"},
+			[]string{"promo-Intro.mp4", "promo-Intro-2.mp4", "promo-Intro-3.mp4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sceneFileNames("promo", "mp4", tt.sceneIDs, tt.doc, tt.labels)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("files %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// waitJob polls a background job until it finishes and returns its status.
+func waitJob(t *testing.T, h *Handler, jobID, projectID string) JobStatus {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if st, ok := h.jobs.status(jobID, projectID); ok && st.FinishedAt != nil {
+			return st
+		}
+	}
+	t.Fatal("export job never finished")
+	return JobStatus{}
+}
+
+func TestBackgroundJobScenes(t *testing.T) {
+	// The stand-in ffmpeg writes its last argument, the output file
+	ffmpeg := fakeFfmpeg(t, `for arg; do out=$arg; done; echo video > "$out"`, 0o755)
+	h := NewHandler(ffmpeg, nil, nil)
+
+	// scene_2 is missing a frame, and scene_3 shares scene_1's name
+	body, contentType := framesForm(t, map[string]string{
+		"name":         "promo",
+		"scene_1/name": "Intro",
+		"scene_3/name": "Intro",
+	}, "scene_1/frame_0000", "scene_1/frame_0001", "scene_2/frame_0000", "scene_2/frame_0002",
+		"scene_3/frame_0000", "scene_3/frame_0001")
+	r := httptest.NewRequest(http.MethodPost, "/export/jobs", body)
+	r.Header.Set("Content-Type", contentType)
+	rec := httptest.NewRecorder()
+	h.CreateJob(rec, r)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("create job status %d: %s", rec.Code, rec.Body)
+	}
+	var created JobStatus
+	json.Unmarshal(rec.Body.Bytes(), &created)
+
+	st := waitJob(t, h, created.ID, "")
+	if st.State != JobCompleted || st.Download != "promo.zip" {
+		t.Fatalf("job %s with download %q, want completed with promo.zip", st.State, st.Download)
+	}
+	want := []struct {
+		file  string
+		state JobState
+	}{
+		{"promo-Intro.mp4", JobCompleted},
+		{"promo-scene_2.mp4", JobFailed},
+		{"promo-Intro-2.mp4", JobCompleted},
+	}
+	if len(st.Scenes) != len(want) {
+		t.Fatalf("scenes %+v, want %d", st.Scenes, len(want))
+	}
+	for i, w := range want {
+		sc := st.Scenes[i]
+		if sc.File != w.file || sc.State != w.state {
+			t.Errorf("scene %d: %s %s, want %s %s", i, sc.File, sc.State, w.file, w.state)
+		}
+		if (sc.Error != "") != (w.state == JobFailed) {
+			t.Errorf("scene %d error %q", i, sc.Error)
+		}
+	}
+
+	r = mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/export/jobs/"+created.ID+"/download", nil),
+		map[string]string{"jobId": created.ID})
+	rec = httptest.NewRecorder()
+	h.DownloadJob(rec, r)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("download status %d: %s", rec.Code, rec.Body)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	slices.Sort(names)
+	if want := []string{"promo-Intro-2.mp4", "promo-Intro.mp4"}; !slices.Equal(names, want) {
+		t.Errorf("zip entries %v, want %v", names, want)
+	}
+}
+
+func TestCreateJobNamesFilesAfterProject(t *testing.T) {
+	tests := []struct {
+		name string
+		form string // The name field, if sent
+		want []string
+	}{
+		{"project name", "", []string{"Launch-Promo-Intro.mp4", "Launch-Promo-The-End.mp4"}},
+		{"name field", "clip", []string{"clip-Intro.mp4", "clip-The-End.mp4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := twoScenes()
+			doc.Project.Name = "Launch Promo"
+			h := NewHandler("true", func(ctx context.Context, projectID string) (*document.InDocument, error) {
+				return doc, nil
+			}, nil)
+			h.SetMemberCheck(func(ctx context.Context, projectID, userID string) (bool, error) { return true, nil })
+
+			fields := map[string]string{"scenes": "all"}
+			if tt.form != "" {
+				fields["name"] = tt.form
+			}
+			var keys []string
+			for _, sceneID := range doc.Project.Scenes {
+				keys = append(keys, sceneID+"/frame_0000")
+			}
+			body, contentType := framesForm(t, fields, keys...)
+			r := httptest.NewRequest(http.MethodPost, "/api/projects/"+testProjectID+"/export/jobs", body)
+			r.Header.Set("Content-Type", contentType)
+			rec := httptest.NewRecorder()
+			h.CreateJob(rec, asProjectRoute(r, testProjectID, "member"))
+
+			if rec.Code != http.StatusAccepted {
+				t.Fatalf("status = %d, want 202: %s", rec.Code, rec.Body)
+			}
+			var st JobStatus
+			json.Unmarshal(rec.Body.Bytes(), &st)
+			var files []string
+			for _, sc := range st.Scenes {
+				files = append(files, sc.File)
+			}
+			if !slices.Equal(files, tt.want) {
+				t.Errorf("files %v, want %v", files, tt.want)
+			}
+			// The 1x1 frames don't fit the 32x24 scenes, so the job fails;
+			// only the names matter here
+			waitJob(t, h, st.ID, testProjectID)
+		})
+	}
+}
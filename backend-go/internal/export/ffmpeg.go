@@ -0,0 +1,68 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrFfmpegUnavailable means the configured ffmpeg binary could not be
+// executed at all, as opposed to running and failing to encode.
+var ErrFfmpegUnavailable = errors.New("ffmpeg unavailable")
+
+const probeTimeout = 5 * time.Second
+
+// Probe runs `ffmpeg -version` and returns the first line of its output.
+func Probe(ctx context.Context, ffmpegPath string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, ffmpegPath, "-version").Output()
+	if err != nil {
+		return "", fmt.Errorf("%w: %s: %v", ErrFfmpegUnavailable, ffmpegPath, err)
+	}
+
+	line, _, _ := bufio.NewReader(bytes.NewReader(out)).ReadLine()
+	return string(line), nil
+}
+
+// classifyRunError distinguishes a binary that cannot be started (missing,
+// not executable, wrong architecture) from one that ran and exited non-zero.
+func classifyRunError(ffmpegPath string, err error, stderr string) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return fmt.Errorf("%v: %s", err, stderr)
+	}
+	return fmt.Errorf("%w: %s: %v", ErrFfmpegUnavailable, ffmpegPath, err)
+}
+
+// progressWriter parses the key=value lines ffmpeg writes with -progress,
+// reporting each frame count.
+type progressWriter struct {
+	onFrame func(int)
+	buf     []byte
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := string(p.buf[:i])
+		p.buf = p.buf[i+1:]
+		if v, ok := strings.CutPrefix(line, "frame="); ok {
+			if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+				p.onFrame(n)
+			}
+		}
+	}
+	return len(b), nil
+}
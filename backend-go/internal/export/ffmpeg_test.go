@@ -0,0 +1,101 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+const bogusFfmpeg = "/nonexistent/bin/ffmpeg"
+
+// fakeFfmpeg writes a shell script standing in for ffmpeg and returns its
+// path.
+func fakeFfmpeg(t *testing.T, script string, mode os.FileMode) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	path := filepath.Join(t.TempDir(), "ffmpeg")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), mode); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestProbe(t *testing.T) {
+	path := fakeFfmpeg(t, `echo "ffmpeg version 6.1 Copyright (c) the FFmpeg developers"; echo "built with gcc"`, 0o755)
+	version, err := Probe(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "ffmpeg version 6.1 Copyright (c) the FFmpeg developers" {
+		t.Errorf("version %q, want the first line", version)
+	}
+
+	if _, err := Probe(context.Background(), bogusFfmpeg); !errors.Is(err, ErrFfmpegUnavailable) {
+		t.Errorf("probe of a missing binary: %v, want ErrFfmpegUnavailable", err)
+	}
+}
+
+func TestRunFfmpegClassifiesErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		unavailable bool
+	}{
+		{"missing", bogusFfmpeg, true},
+		{"not executable", fakeFfmpeg(t, "exit 0", 0o644), true},
+		{"encoding failed", fakeFfmpeg(t, "echo 'Invalid data found' >&2; exit 1", 0o755), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(tt.path, nil, nil)
+			err := h.runFfmpeg(context.Background(), nil, "-i", "in.png", "out.mp4")
+			if err == nil {
+				t.Fatal("runFfmpeg succeeded")
+			}
+			if errors.Is(err, ErrFfmpegUnavailable) != tt.unavailable {
+				t.Errorf("err %v, unavailable = %v", err, tt.unavailable)
+			}
+			if !tt.unavailable && !strings.Contains(err.Error(), "Invalid data found") {
+				t.Errorf("err %v does not carry ffmpeg's stderr", err)
+			}
+		})
+	}
+}
+
+func TestBogusFfmpegPathIsUnavailable(t *testing.T) {
+	h := NewHandler(bogusFfmpeg, nil, nil)
+	for name, serve := range map[string]http.HandlerFunc{
+		"export": h.ExportVideo,
+		"render": h.RenderVideo,
+	} {
+		rec := httptest.NewRecorder()
+		serve(rec, httptest.NewRequest(http.MethodPost, "/export/video", nil))
+		if rec.Code != http.StatusServiceUnavailable || errorCode(t, rec) != "ffmpeg_unavailable" {
+			t.Errorf("%s: got %d %s, want 503 ffmpeg_unavailable", name, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestEncodeErrorResponses(t *testing.T) {
+	h := NewHandler("ffmpeg", nil, nil)
+
+	rec := httptest.NewRecorder()
+	h.writeEncodeError(context.Background(), rec, classifyRunError("ffmpeg", os.ErrPermission, ""))
+	if rec.Code != http.StatusServiceUnavailable || errorCode(t, rec) != "ffmpeg_unavailable" {
+		t.Errorf("unstartable ffmpeg: got %d %s, want 503 ffmpeg_unavailable", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	h.writeEncodeError(context.Background(), rec, errors.New("exit status 1: Invalid data found"))
+	if rec.Code != http.StatusInternalServerError || errorCode(t, rec) != "encoding_failed" {
+		t.Errorf("failed encode: got %d %s, want 500 encoding_failed", rec.Code, rec.Body)
+	}
+}
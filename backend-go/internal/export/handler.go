@@ -3,45 +3,190 @@ package export
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"image/png"
 	"io"
 	"log/slog"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/inamate/inamate/backend-go/internal/auth"
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/httperr"
+	"github.com/inamate/inamate/backend-go/internal/notification"
+	"github.com/inamate/inamate/backend-go/internal/raster"
+	"github.com/inamate/inamate/backend-go/internal/webhook"
 )
 
 const maxUploadSize = 500 << 20 // 500MB
 
+// ffmpegWaitDelay bounds how long a killed ffmpeg may keep its output pipes
+// open before Wait gives up on it.
+const ffmpegWaitDelay = 5 * time.Second
+
+// defaultMaxFrames caps the frames one export may upload or render (2.5 minutes at 24fps)
+const defaultMaxFrames = 3600
+
+// hexColorPattern matches the #rrggbb / #rrggbbaa colors we are willing to pass
+// through to an ffmpeg filtergraph.
+var hexColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}([0-9a-fA-F]{2})?$`)
+
+var (
+	errProjectNotFound = errors.New("project not found")
+	errNotMember       = errors.New("not a project member")
+	errPublicProject   = errors.New("exports of a stored project go through /api/projects/{projectId}/export")
+	errProjectMismatch = errors.New("projectId does not match the route")
+)
+
+// DocumentLoader loads the current document for a project.
+type DocumentLoader func(ctx context.Context, projectID string) (*document.InDocument, error)
+
+// MemberCheck reports whether userID is a member of projectID.
+type MemberCheck func(ctx context.Context, projectID, userID string) (bool, error)
+
 type Handler struct {
 	ffmpegPath string
+	loadDoc    DocumentLoader // Optional; used to resolve scene size and background
+	members    MemberCheck    // Without one, no stored project can be exported
+	docTimeout time.Duration
+	maxFrames  int
+	images     raster.ImageLoader // Loads image assets for frames rendered here
+	disabled   bool               // The deployment has turned exports off
+	webhooks   *webhook.Dispatcher
+	notes      *notification.Service
+	jobs       jobRegistry
+	slots      chan struct{} // Bounds concurrently running background jobs
+
+	active    atomic.Int64
+	completed atomic.Int64
+	failed    atomic.Int64
+	cancelled atomic.Int64
+}
+
+func NewHandler(ffmpegPath string, loadDoc DocumentLoader, webhooks *webhook.Dispatcher) *Handler {
+	return &Handler{
+		ffmpegPath: ffmpegPath,
+		loadDoc:    loadDoc,
+		webhooks:   webhooks,
+		docTimeout: 10 * time.Second,
+		maxFrames:  defaultMaxFrames,
+		slots:      make(chan struct{}, maxRunningJobs),
+	}
+}
+
+// SetNotifications sets the service that project members are notified
+// through when an export of the project completes.
+func (h *Handler) SetNotifications(notes *notification.Service) {
+	h.notes = notes
 }
 
-func NewHandler(ffmpegPath string) *Handler {
-	return &Handler{ffmpegPath: ffmpegPath}
+// SetMemberCheck sets how the project routes check the caller may export
+// the project.
+func (h *Handler) SetMemberCheck(check MemberCheck) {
+	h.members = check
+}
+
+// SetEnabled sets whether new exports are accepted. They are by default.
+func (h *Handler) SetEnabled(enabled bool) {
+	h.disabled = !enabled
+}
+
+// SetMaxFrames bounds how many frames a single export may upload or render.
+func (h *Handler) SetMaxFrames(n int) {
+	if n > 0 {
+		h.maxFrames = n
+	}
+}
+
+// SetDocumentTimeout bounds how long resolving the exported scene may take.
+func (h *Handler) SetDocumentTimeout(d time.Duration) {
+	if d > 0 {
+		h.docTimeout = d
+	}
+}
+
+// Stats is a point-in-time view of export activity since startup.
+type Stats struct {
+	Active    int64 `json:"active"`
+	Completed int64 `json:"completed"`
+	Failed    int64 `json:"failed"`
+	Cancelled int64 `json:"cancelled"`
+}
+
+func (h *Handler) Stats() Stats {
+	return Stats{
+		Active:    h.active.Load(),
+		Completed: h.completed.Load(),
+		Failed:    h.failed.Load(),
+		Cancelled: h.cancelled.Load(),
+	}
+}
+
+// frameInfo records the pixel dimensions of an uploaded frame.
+type frameInfo struct {
+	key    string
+	index  int
+	width  int
+	height int
 }
 
 func (h *Handler) ExportVideo(w http.ResponseWriter, r *http.Request) {
+	if h.disabled {
+		httperr.Write(w, http.StatusForbidden, httperr.CodeFeatureDisabled, "exports are disabled")
+		return
+	}
 	if _, err := exec.LookPath(h.ffmpegPath); err != nil {
-		http.Error(w, "video export requires ffmpeg to be installed", http.StatusServiceUnavailable)
+		httperr.Write(w, http.StatusServiceUnavailable, httperr.CodeFfmpegUnavailable,
+			"video export is unavailable: ffmpeg was not found on the server")
 		return
 	}
 
+	h.active.Add(1)
+	defer h.active.Add(-1)
+
 	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
 
 	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
-		http.Error(w, "request too large", http.StatusBadRequest)
+		httperr.Write(w, http.StatusBadRequest, httperr.CodePayloadTooLarge, "request too large")
 		return
 	}
 	defer r.MultipartForm.RemoveAll()
 
+	// The client may name the job so it can cancel it while the request is
+	// still running; client disconnects cancel it either way.
+	jobID, err := requestJobID(r)
+	if err != nil {
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeInvalidID, "invalid job id")
+		return
+	}
+	projectID, err := h.requestProject(r, r.FormValue("projectId"))
+	if err != nil {
+		writeProjectError(w, err)
+		return
+	}
+	ctx, err := h.jobs.start(r.Context(), jobID, projectID)
+	if err != nil {
+		httperr.Write(w, http.StatusConflict, httperr.CodeExportJobExists, "export job already running: "+jobID)
+		return
+	}
+	defer h.jobs.finish(jobID)
+
 	format := r.FormValue("format")
 	if format != "mp4" && format != "gif" && format != "webm" {
-		http.Error(w, "invalid format: must be mp4, gif, or webm", http.StatusBadRequest)
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed, "invalid format: must be mp4, gif, or webm")
 		return
 	}
 
@@ -54,22 +199,44 @@ func (h *Handler) ExportVideo(w http.ResponseWriter, r *http.Request) {
 	if name == "" {
 		name = "animation"
 	}
-	// Sanitize filename
-	name = strings.Map(func(r rune) rune {
-		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
-			return r
+	name = sanitizeName(name)
+
+	// Resolve the target scene when the client tells us which project it is
+	// exporting, so output size and background come from the document rather
+	// than from whatever the client happened to render.
+	var scene *document.Scene
+	if projectID != "" {
+		scene, err = h.resolveScene(ctx, projectID, r.FormValue("sceneId"))
+		if errors.Is(err, context.DeadlineExceeded) {
+			httperr.Write(w, http.StatusGatewayTimeout, httperr.CodeTimeout, "timed out loading project")
+			return
 		}
-		return '-'
-	}, name)
+		if err != nil {
+			code := httperr.CodeValidationFailed
+			if errors.Is(err, errProjectNotFound) {
+				code = httperr.CodeProjectNotFound
+			}
+			httperr.Write(w, http.StatusBadRequest, code, err.Error())
+			return
+		}
+	}
+
+	// Reject oversized exports before writing any frames or invoking ffmpeg
+	if n := countFrames(r.MultipartForm.File); n > h.maxFrames {
+		httperr.WriteDetails(w, http.StatusRequestEntityTooLarge, httperr.CodePayloadTooLarge,
+			fmt.Sprintf("too many frames: %d exceeds the limit of %d", n, h.maxFrames),
+			map[string]interface{}{"frames": n, "maxFrames": h.maxFrames})
+		return
+	}
 
 	// Create temp directory for frames
 	tempDir, err := os.MkdirTemp("", "inamate-export-*")
 	if err != nil {
-		slog.Error("create temp dir", "error", err)
-		http.Error(w, "internal error", http.StatusInternalServerError)
+		httperr.Internal(w, "create temp dir", err)
 		return
 	}
 	defer os.RemoveAll(tempDir)
+	h.jobs.setDir(jobID, tempDir)
 
 	// Determine frame padding from the expected frame count (sent by frontend)
 	// so filenames match the ffmpeg input pattern.
@@ -86,8 +253,12 @@ func (h *Handler) ExportVideo(w http.ResponseWriter, r *http.Request) {
 	// from the key name (e.g. "frame_0003" → "frame_0003.png").
 	// Map iteration order is random in Go, so we must use the key name
 	// rather than a counter to keep frames in the correct sequence.
-	frameCount := 0
+	var frames []frameInfo
 	for key, files := range r.MultipartForm.File {
+		if ctx.Err() != nil {
+			// Cancelled or abandoned; ffmpeg below fails straight away
+			break
+		}
 		if !strings.HasPrefix(key, "frame_") {
 			continue
 		}
@@ -98,60 +269,341 @@ func (h *Handler) ExportVideo(w http.ResponseWriter, r *http.Request) {
 		// Extract frame index from key name (e.g. "frame_0003" → 3)
 		indexStr := strings.TrimPrefix(key, "frame_")
 		frameIdx, err := strconv.Atoi(indexStr)
-		if err != nil {
-			slog.Error("parse frame index", "key", key, "error", err)
-			http.Error(w, "invalid frame key: "+key, http.StatusBadRequest)
-			return
-		}
-
-		f, err := files[0].Open()
-		if err != nil {
-			slog.Error("open uploaded frame", "key", key, "error", err)
-			http.Error(w, "failed to read frame", http.StatusBadRequest)
+		if err != nil || frameIdx < 0 {
+			httperr.Write(w, http.StatusBadRequest, httperr.CodeInvalidFrames, "invalid frame key: "+key)
 			return
 		}
 
 		outPath := filepath.Join(tempDir, fmt.Sprintf("frame_%0*d.png", padWidth, frameIdx))
-		out, err := os.Create(outPath)
-		if err != nil {
-			f.Close()
-			slog.Error("create frame file", "error", err)
-			http.Error(w, "internal error", http.StatusInternalServerError)
+		frame, err := writeFrame(files[0], key, frameIdx, outPath)
+		var badFrame *frameError
+		if errors.As(err, &badFrame) {
+			httperr.Write(w, http.StatusBadRequest, httperr.CodeInvalidFrames, badFrame.msg)
 			return
 		}
-
-		_, err = io.Copy(out, f)
-		f.Close()
-		out.Close()
 		if err != nil {
-			slog.Error("write frame file", "error", err)
-			http.Error(w, "internal error", http.StatusInternalServerError)
+			httperr.Internal(w, "write frame file", err)
 			return
 		}
-		frameCount++
+		frames = append(frames, frame)
 	}
 
+	frameCount := len(frames)
 	if frameCount == 0 {
-		http.Error(w, "no frames uploaded", http.StatusBadRequest)
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeInvalidFrames, "no frames uploaded")
 		return
 	}
 
-	slog.Info("export started", "format", format, "frames", frameCount, "fps", fps)
+	// Frames must run 0..n-1 with no gaps or duplicates, or ffmpeg's input
+	// pattern would silently stop at the first missing index
+	sort.Slice(frames, func(i, j int) bool { return frames[i].index < frames[j].index })
+	if gaps := findGaps(frames); gaps != nil {
+		httperr.WriteDetails(w, http.StatusBadRequest, httperr.CodeInvalidFrames, gaps.message(), gaps)
+		return
+	}
+
+	// All frames must share one size: the scene size when known, otherwise
+	// the size of the first frame in sequence.
+	width, height := frames[0].width, frames[0].height
+	background := ""
+	if scene != nil {
+		width, height = scene.Width, scene.Height
+		if hexColorPattern.MatchString(scene.Background) {
+			background = scene.Background
+		}
+	}
+	if offenders := mismatchedFrames(frames, width, height); len(offenders) > 0 {
+		httperr.WriteDetails(w, http.StatusBadRequest, httperr.CodeInvalidFrames,
+			fmt.Sprintf("frame dimensions must all be %dx%d; mismatched frames: %s", width, height, strings.Join(offenders, ", ")),
+			map[string]interface{}{"width": width, "height": height, "mismatched": offenders})
+		return
+	}
+
+	slog.Info("export started", "job_id", jobID, "format", format, "frames", frameCount, "fps", fps, "width", width, "height", height)
 
 	inputPattern := filepath.Join(tempDir, fmt.Sprintf("frame_%%0%dd.png", padWidth))
+	outputFile, contentType, cmdErr := h.encode(ctx, tempDir, inputPattern, format, fps, width, height, background, nil)
+
+	if cmdErr != nil {
+		h.writeEncodeError(ctx, w, cmdErr)
+		return
+	}
+
+	size, err := sendOutput(w, outputFile, contentType, name+"."+format)
+	if err != nil {
+		httperr.Internal(w, "open output file", err)
+		return
+	}
+
+	h.completed.Add(1)
+	slog.Info("export complete", "job_id", jobID, "format", format, "size", size)
+
+	if projectID != "" {
+		h.announce(projectID, format, name, frameCount, fps, size)
+	}
+}
+
+// writeEncodeError reports an encode that failed, or that stopped because
+// its job was cancelled.
+func (h *Handler) writeEncodeError(ctx context.Context, w http.ResponseWriter, err error) {
+	if errors.Is(context.Cause(ctx), errJobCancelled) {
+		h.cancelled.Add(1)
+		httperr.Write(w, http.StatusConflict, httperr.CodeExportCancelled, "export was cancelled")
+		return
+	}
+	h.failed.Add(1)
+	slog.Error("ffmpeg failed", "error", err)
+	if errors.Is(err, ErrFfmpegUnavailable) {
+		httperr.Write(w, http.StatusServiceUnavailable, httperr.CodeFfmpegUnavailable,
+			"video export is unavailable: ffmpeg could not be executed on the server")
+		return
+	}
+	httperr.Write(w, http.StatusInternalServerError, httperr.CodeEncodingFailed, fmt.Sprintf("encoding failed: %v", err))
+}
+
+// sendOutput streams an encoded export back as a download named filename and
+// returns its size. An error means nothing was written yet.
+func sendOutput(w http.ResponseWriter, outputFile, contentType, filename string) (int64, error) {
+	outFile, err := os.Open(outputFile)
+	if err != nil {
+		return 0, err
+	}
+	defer outFile.Close()
+
+	stat, err := outFile.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.Header().Set("Content-Length", strconv.FormatInt(stat.Size(), 10))
+	io.Copy(w, outFile)
+	return stat.Size(), nil
+}
+
+// requestProject returns the stored project an export is of, or "" for one
+// that brings its own frames or document. Only the authenticated routes
+// under /api/projects/{projectId}/export name a project, and only for its
+// members; a public route sent a projectId is refused, so anonymous callers
+// can't read a project or announce exports of it. bodyProjectID is the
+// projectId the request body names, if any, which must match the route's.
+func (h *Handler) requestProject(r *http.Request, bodyProjectID string) (string, error) {
+	projectID := mux.Vars(r)["projectId"]
+	if projectID == "" {
+		if bodyProjectID != "" {
+			return "", errPublicProject
+		}
+		return "", nil
+	}
+	if bodyProjectID != "" && bodyProjectID != projectID {
+		return "", errProjectMismatch
+	}
 
-	// Build and run ffmpeg command
-	var outputFile string
-	var contentType string
-	var cmdErr error
+	userID := auth.UserIDFromContext(r.Context())
+	if userID == "" || h.members == nil {
+		return "", errNotMember
+	}
+	ok, err := h.members(r.Context(), projectID, userID)
+	if err != nil {
+		return "", fmt.Errorf("check membership: %w", err)
+	}
+	if !ok {
+		return "", errNotMember
+	}
+	return projectID, nil
+}
+
+// writeProjectError reports why an export can't be of the project it names.
+func writeProjectError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errPublicProject), errors.Is(err, errProjectMismatch):
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed, err.Error())
+	case errors.Is(err, errNotMember):
+		httperr.Write(w, http.StatusForbidden, httperr.CodeNotAMember, "not a project member")
+	default:
+		httperr.Internal(w, "export project", err)
+	}
+}
+
+// announce tells a project's webhooks and members that an export of it
+// completed.
+func (h *Handler) announce(projectID, format, name string, frames, fps int, size int64) {
+	h.webhooks.Dispatch(projectID, webhook.EventExportCompleted, map[string]interface{}{
+		"format": format,
+		"name":   name,
+		"frames": frames,
+		"fps":    fps,
+		"size":   size,
+	})
+	h.notes.NotifyMembers(projectID, notification.TypeExportCompleted, "", map[string]interface{}{
+		"format": format,
+		"name":   name,
+	})
+}
+
+// resolveScene looks up the scene being exported. An empty sceneID selects the
+// project's first scene.
+func (h *Handler) resolveScene(ctx context.Context, projectID, sceneID string) (*document.Scene, error) {
+	doc, err := h.loadProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if sceneID == "" {
+		if len(doc.Project.Scenes) == 0 {
+			return nil, fmt.Errorf("project has no scenes")
+		}
+		sceneID = doc.Project.Scenes[0]
+	}
+	return sceneOf(doc, sceneID)
+}
+
+// loadProject loads the exported project's document, bounded by the document
+// timeout.
+func (h *Handler) loadProject(ctx context.Context, projectID string) (*document.InDocument, error) {
+	if h.loadDoc == nil {
+		return nil, fmt.Errorf("project lookup is not available")
+	}
+	ctx, cancel := context.WithTimeout(ctx, h.docTimeout)
+	defer cancel()
+	doc, err := h.loadDoc(ctx, projectID)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errProjectNotFound, projectID)
+	}
+	return doc, nil
+}
+
+// sceneOf returns a scene of doc that frames can be exported at.
+func sceneOf(doc *document.InDocument, sceneID string) (*document.Scene, error) {
+	scene, ok := doc.Scenes[sceneID]
+	if !ok {
+		return nil, fmt.Errorf("scene not found: %s", sceneID)
+	}
+	if scene.Width <= 0 || scene.Height <= 0 {
+		return nil, fmt.Errorf("scene has invalid dimensions: %dx%d", scene.Width, scene.Height)
+	}
+	return &scene, nil
+}
+
+// countFrames returns the number of uploaded frame files.
+func countFrames(files map[string][]*multipart.FileHeader) int {
+	n := 0
+	for key, headers := range files {
+		if strings.HasPrefix(key, "frame_") && len(headers) > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// maxListedFrames bounds how many missing or duplicate indices an error lists.
+const maxListedFrames = 20
+
+// frameGaps describes why a frame sequence isn't contiguous.
+type frameGaps struct {
+	Missing      []int `json:"missing"`      // The first maxListedFrames missing indices
+	MissingCount int   `json:"missingCount"` // Every missing index below the highest uploaded
+	Duplicates   []int `json:"duplicates"`   // Indices uploaded under more than one key
+}
+
+// findGaps returns the missing and duplicate indices in frames, which must be
+// sorted by index and numbered from 0, or nil if there are none.
+func findGaps(frames []frameInfo) *frameGaps {
+	gaps := &frameGaps{Missing: []int{}, Duplicates: []int{}}
+	next := 0
+	for i, f := range frames {
+		if i > 0 && f.index == frames[i-1].index {
+			if len(gaps.Duplicates) < maxListedFrames && !slices.Contains(gaps.Duplicates, f.index) {
+				gaps.Duplicates = append(gaps.Duplicates, f.index)
+			}
+			continue
+		}
+		for ; next < f.index; next++ {
+			if len(gaps.Missing) < maxListedFrames {
+				gaps.Missing = append(gaps.Missing, next)
+			}
+			gaps.MissingCount++
+		}
+		next = f.index + 1
+	}
+	if gaps.MissingCount == 0 && len(gaps.Duplicates) == 0 {
+		return nil
+	}
+	return gaps
+}
+
+// message summarizes the gaps for the error envelope.
+func (g *frameGaps) message() string {
+	var parts []string
+	if g.MissingCount > 0 {
+		part := "missing frames " + joinInts(g.Missing)
+		if g.MissingCount > len(g.Missing) {
+			part += fmt.Sprintf(" and %d more", g.MissingCount-len(g.Missing))
+		}
+		parts = append(parts, part)
+	}
+	if len(g.Duplicates) > 0 {
+		parts = append(parts, "duplicate frames "+joinInts(g.Duplicates))
+	}
+	return strings.Join(parts, "; ") + ": frames must be numbered from 0 without gaps"
+}
+
+func joinInts(vs []int) string {
+	strs := make([]string, len(vs))
+	for i, v := range vs {
+		strs[i] = strconv.Itoa(v)
+	}
+	return strings.Join(strs, ", ")
+}
+
+// mismatchedFrames returns a description of every frame whose size differs
+// from width x height, in frame order.
+func mismatchedFrames(frames []frameInfo, width, height int) []string {
+	var offenders []string
+	for _, f := range frames {
+		if f.width != width || f.height != height {
+			offenders = append(offenders, fmt.Sprintf("%s (%dx%d)", f.key, f.width, f.height))
+		}
+	}
+	return offenders
+}
+
+// baseFilter builds the filtergraph applied to the frame input before
+// encoding. Formats without alpha are flattened onto the scene background when
+// one is known, and formats using 4:2:0 chroma are padded to even dimensions
+// since ffmpeg rejects odd sizes for yuv420p/yuva420p.
+func baseFilter(format string, fps, width, height int, background string) string {
+	flatten := background != "" && format != "webm"
+
+	chain := "[0:v]null"
+	if flatten {
+		chain = fmt.Sprintf("color=c=%s:s=%dx%d:r=%d[bg];[bg][0:v]overlay=shortest=1", background, width, height, fps)
+	}
+	if format != "gif" {
+		chain += ",pad=ceil(iw/2)*2:ceil(ih/2)*2"
+		if flatten {
+			chain += ":color=" + background
+		}
+	}
+	return chain
+}
+
+// encode runs ffmpeg over the frames matching inputPattern, writing the
+// output into dir. It returns the output file and its content type. onFrame,
+// if set, receives the number of frames encoded so far.
+func (h *Handler) encode(ctx context.Context, dir, inputPattern, format string, fps, width, height int, background string, onFrame func(int)) (string, string, error) {
+	filter := baseFilter(format, fps, width, height, background)
 
 	switch format {
 	case "mp4":
-		outputFile = filepath.Join(tempDir, "output.mp4")
-		contentType = "video/mp4"
-		cmdErr = h.runFfmpeg(r, tempDir, fps,
+		outputFile := filepath.Join(dir, "output.mp4")
+		return outputFile, "video/mp4", h.runFfmpeg(ctx, onFrame,
 			"-framerate", strconv.Itoa(fps),
 			"-i", inputPattern,
+			"-filter_complex", filter,
 			"-c:v", "libx264",
 			"-pix_fmt", "yuv420p",
 			"-crf", "18",
@@ -161,32 +613,32 @@ func (h *Handler) ExportVideo(w http.ResponseWriter, r *http.Request) {
 		)
 
 	case "gif":
-		outputFile = filepath.Join(tempDir, "output.gif")
-		contentType = "image/gif"
+		outputFile := filepath.Join(dir, "output.gif")
 		// Two-pass GIF: generate palette then apply
-		palettePath := filepath.Join(tempDir, "palette.png")
-		cmdErr = h.runFfmpeg(r, tempDir, fps,
+		palettePath := filepath.Join(dir, "palette.png")
+		err := h.runFfmpeg(ctx, nil,
 			"-framerate", strconv.Itoa(fps),
 			"-i", inputPattern,
-			"-vf", "palettegen=stats_mode=diff",
+			"-filter_complex", filter+",palettegen=stats_mode=diff",
 			palettePath,
 		)
-		if cmdErr == nil {
-			cmdErr = h.runFfmpeg(r, tempDir, fps,
+		if err == nil {
+			err = h.runFfmpeg(ctx, onFrame,
 				"-framerate", strconv.Itoa(fps),
 				"-i", inputPattern,
 				"-i", palettePath,
-				"-lavfi", "paletteuse=dither=bayer:bayer_scale=5:diff_mode=rectangle",
+				"-filter_complex", filter+"[v];[v][1:v]paletteuse=dither=bayer:bayer_scale=5:diff_mode=rectangle",
 				outputFile,
 			)
 		}
+		return outputFile, "image/gif", err
 
 	case "webm":
-		outputFile = filepath.Join(tempDir, "output.webm")
-		contentType = "video/webm"
-		cmdErr = h.runFfmpeg(r, tempDir, fps,
+		outputFile := filepath.Join(dir, "output.webm")
+		return outputFile, "video/webm", h.runFfmpeg(ctx, onFrame,
 			"-framerate", strconv.Itoa(fps),
 			"-i", inputPattern,
+			"-filter_complex", filter,
 			"-c:v", "libvpx-vp9",
 			"-crf", "30",
 			"-b:v", "0",
@@ -194,50 +646,82 @@ func (h *Handler) ExportVideo(w http.ResponseWriter, r *http.Request) {
 			outputFile,
 		)
 	}
-
-	if cmdErr != nil {
-		slog.Error("ffmpeg failed", "error", cmdErr)
-		http.Error(w, fmt.Sprintf("encoding failed: %v", cmdErr), http.StatusInternalServerError)
-		return
-	}
-
-	// Stream result file back
-	outFile, err := os.Open(outputFile)
-	if err != nil {
-		slog.Error("open output file", "error", err)
-		http.Error(w, "internal error", http.StatusInternalServerError)
-		return
-	}
-	defer outFile.Close()
-
-	stat, err := outFile.Stat()
-	if err != nil {
-		slog.Error("stat output file", "error", err)
-		http.Error(w, "internal error", http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
-	w.Header().Set("Content-Length", strconv.FormatInt(stat.Size(), 10))
-	io.Copy(w, outFile)
-
-	slog.Info("export complete", "format", format, "size", stat.Size())
+	return "", "", fmt.Errorf("unsupported format: %s", format)
 }
 
-func (h *Handler) runFfmpeg(r *http.Request, _ string, _ int, args ...string) error {
-	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Minute)
+func (h *Handler) runFfmpeg(ctx context.Context, onFrame func(int), args ...string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
 	defer cancel()
 
 	// Prepend -y to overwrite output without prompting
 	fullArgs := append([]string{"-y"}, args...)
+	if onFrame != nil {
+		fullArgs = append([]string{"-progress", "pipe:1", "-nostats"}, fullArgs...)
+	}
 	cmd := exec.CommandContext(ctx, h.ffmpegPath, fullArgs...)
+	killProcessGroup(cmd)
+	cmd.WaitDelay = ffmpegWaitDelay
 
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
+	if onFrame != nil {
+		cmd.Stdout = &progressWriter{onFrame: onFrame}
+	}
 
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("%v: %s", err, stderr.String())
+		return classifyRunError(h.ffmpegPath, err, stderr.String())
 	}
 	return nil
 }
+
+// frameError is an uploaded frame the client must fix, as opposed to a
+// server-side I/O failure.
+type frameError struct {
+	msg string
+}
+
+func (e *frameError) Error() string { return e.msg }
+
+// writeFrame copies an uploaded PNG frame to path, reading its header for the
+// frame's dimensions.
+func writeFrame(fh *multipart.FileHeader, key string, index int, path string) (frameInfo, error) {
+	f, err := fh.Open()
+	if err != nil {
+		slog.Error("open uploaded frame", "key", key, "error", err)
+		return frameInfo{}, &frameError{"failed to read frame"}
+	}
+	defer f.Close()
+
+	// Read only the PNG header to get dimensions, then rewind for the copy
+	cfg, err := png.DecodeConfig(f)
+	if err != nil {
+		return frameInfo{}, &frameError{"invalid PNG frame: " + key}
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		slog.Error("rewind uploaded frame", "key", key, "error", err)
+		return frameInfo{}, &frameError{"failed to read frame"}
+	}
+
+	out, err := os.Create(path)
+	if err != nil {
+		return frameInfo{}, err
+	}
+	_, err = io.Copy(out, f)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return frameInfo{}, err
+	}
+	return frameInfo{key: key, index: index, width: cfg.Width, height: cfg.Height}, nil
+}
+
+// sanitizeName reduces a name to characters safe in a download filename.
+func sanitizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			return r
+		}
+		return '-'
+	}, name)
+}
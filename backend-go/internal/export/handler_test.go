@@ -0,0 +1,166 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// framesForm builds a multipart export body with the given fields (format
+// defaults to mp4), uploading a 1x1 PNG under each of the given frame keys.
+func framesForm(t *testing.T, fields map[string]string, keys ...string) (*bytes.Buffer, string) {
+	t.Helper()
+	var frame bytes.Buffer
+	if err := png.Encode(&frame, image.NewRGBA(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	if fields["format"] == "" {
+		mw.WriteField("format", "mp4")
+	}
+	for k, v := range fields {
+		mw.WriteField(k, v)
+	}
+	for _, key := range keys {
+		part, err := mw.CreateFormFile(key, key+".png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(frame.Bytes())
+	}
+	mw.Close()
+	return &body, mw.FormDataContentType()
+}
+
+// frameKeys returns "frame_0000" and on for each index.
+func frameKeys(indices ...int) []string {
+	keys := make([]string, len(indices))
+	for i, index := range indices {
+		keys[i] = fmt.Sprintf("frame_%04d", index)
+	}
+	return keys
+}
+
+func exportFrames(t *testing.T, h *Handler, keys ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	body, contentType := framesForm(t, nil, keys...)
+	r := httptest.NewRequest(http.MethodPost, "/export/video", body)
+	r.Header.Set("Content-Type", contentType)
+	rec := httptest.NewRecorder()
+	h.ExportVideo(rec, r)
+	return rec
+}
+
+func TestExportVideoFrameCap(t *testing.T) {
+	h := NewHandler("true", nil, nil)
+	h.SetMaxFrames(3)
+
+	rec := exportFrames(t, h, frameKeys(0, 1, 2, 3)...)
+	if rec.Code != http.StatusRequestEntityTooLarge || errorCode(t, rec) != "payload_too_large" {
+		t.Fatalf("status = %d, want 413 payload_too_large: %s", rec.Code, rec.Body)
+	}
+	if !strings.Contains(rec.Body.String(), `"maxFrames":3`) {
+		t.Errorf("body %s does not report the limit", rec.Body)
+	}
+
+	// At the cap the export gets past the check (to ffmpeg, which "true"
+	// stands in for and which writes no output)
+	if rec := exportFrames(t, h, frameKeys(0, 1, 2)...); rec.Code == http.StatusRequestEntityTooLarge {
+		t.Errorf("export at the cap rejected: %s", rec.Body)
+	}
+}
+
+func TestExportVideoFrameGaps(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []string
+	}{
+		{"gap", frameKeys(0, 1, 3)},
+		{"not from zero", frameKeys(1, 2)},
+		{"duplicate", []string{"frame_0000", "frame_0001", "frame_1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := exportFrames(t, NewHandler("true", nil, nil), tt.keys...)
+			if rec.Code != http.StatusBadRequest || errorCode(t, rec) != "invalid_frames" {
+				t.Errorf("status = %d, want 400 invalid_frames: %s", rec.Code, rec.Body)
+			}
+		})
+	}
+}
+
+func TestExportVideoNegativeFrameIndex(t *testing.T) {
+	rec := exportFrames(t, NewHandler("true", nil, nil), "frame_0000", "frame_-1")
+	if rec.Code != http.StatusBadRequest || errorCode(t, rec) != "invalid_frames" {
+		t.Errorf("status = %d, want 400 invalid_frames: %s", rec.Code, rec.Body)
+	}
+}
+
+// TestExportVideoListsMissingFrames checks frames 0, 1 and 3 are rejected
+// naming frame 2, rather than ffmpeg stopping at frame 1.
+func TestExportVideoListsMissingFrames(t *testing.T) {
+	rec := exportFrames(t, NewHandler("true", nil, nil), frameKeys(0, 1, 3)...)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400: %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		Message string    `json:"message"`
+		Details frameGaps `json:"details"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(body.Message, "missing frames 2:") {
+		t.Errorf("message %q, want it to name frame 2", body.Message)
+	}
+	if fmt.Sprint(body.Details.Missing) != "[2]" || body.Details.MissingCount != 1 || len(body.Details.Duplicates) != 0 {
+		t.Errorf("details %+v, want frame 2 missing", body.Details)
+	}
+}
+
+func TestFindGaps(t *testing.T) {
+	frames := func(indices ...int) []frameInfo {
+		out := make([]frameInfo, len(indices))
+		for i, index := range indices {
+			out[i] = frameInfo{index: index}
+		}
+		return out
+	}
+	tests := []struct {
+		name    string
+		indices []int
+		message string // Empty if contiguous
+	}{
+		{"contiguous", []int{0, 1, 2}, ""},
+		{"single", []int{0}, ""},
+		{"one missing", []int{0, 1, 3}, "missing frames 2"},
+		{"leading", []int{2, 3}, "missing frames 0, 1"},
+		{"duplicate", []int{0, 1, 1, 2}, "duplicate frames 1"},
+		{"both", []int{0, 0, 2}, "missing frames 1; duplicate frames 0"},
+		{"many missing", []int{0, 30}, "missing frames 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20 and 9 more"},
+	}
+	for _, tt := range tests {
+		gaps := findGaps(frames(tt.indices...))
+		if tt.message == "" {
+			if gaps != nil {
+				t.Errorf("%s: gaps %+v", tt.name, gaps)
+			}
+			continue
+		}
+		if gaps == nil {
+			t.Errorf("%s: no gaps found", tt.name)
+			continue
+		}
+		if got := strings.TrimSuffix(gaps.message(), ": frames must be numbered from 0 without gaps"); got != tt.message {
+			t.Errorf("%s: message %q, want %q", tt.name, got, tt.message)
+		}
+	}
+}
@@ -0,0 +1,384 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/inamate/inamate/backend-go/internal/httperr"
+)
+
+// tempDirPattern names export workspaces under os.TempDir().
+const tempDirPattern = "inamate-export-*"
+
+// cancelWait bounds how long CancelJob waits for a job to stop and clean up.
+const cancelWait = 2 * ffmpegWaitDelay
+
+// errJobCancelled is the cancellation cause of an export stopped through
+// CancelJob, as opposed to one whose client went away.
+var errJobCancelled = errors.New("export cancelled")
+
+var errJobExists = errors.New("export job already running")
+
+// JobState is the lifecycle stage of a background export or one of its scenes.
+type JobState string
+
+const (
+	JobQueued    JobState = "queued"
+	JobRunning   JobState = "running"
+	JobCompleted JobState = "completed"
+	JobFailed    JobState = "failed"
+	JobCancelled JobState = "cancelled"
+)
+
+// SceneStatus reports one scene of a background export.
+type SceneStatus struct {
+	SceneID string   `json:"sceneId"`
+	File    string   `json:"file"` // Output name, e.g. "promo-intro.mp4"
+	State   JobState `json:"state"`
+	Frames  int      `json:"frames"`
+	Encoded int      `json:"encoded"` // Frames ffmpeg has written so far
+	Size    int64    `json:"size,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// JobStatus is the client-visible state of a background export. A job that
+// finishes with at least one scene encoded is completed; the scenes list
+// which succeeded and which failed.
+type JobStatus struct {
+	ID         string        `json:"id"`
+	ProjectID  string        `json:"projectId,omitempty"`
+	Format     string        `json:"format"`
+	State      JobState      `json:"state"`
+	Scenes     []SceneStatus `json:"scenes"`
+	Download   string        `json:"download,omitempty"` // File served by the download endpoint
+	Error      string        `json:"error,omitempty"`
+	CreatedAt  time.Time     `json:"createdAt"`
+	FinishedAt *time.Time    `json:"finishedAt,omitempty"`
+}
+
+func (s *JobStatus) clone() JobStatus {
+	c := *s
+	c.Scenes = slices.Clone(s.Scenes)
+	return c
+}
+
+// job is an export in progress, or a finished background export awaiting
+// download. Cancelling it kills ffmpeg; the export then removes its
+// workspace as it returns.
+type job struct {
+	cancel  context.CancelCauseFunc
+	done    chan struct{} // Closed once the job has stopped and cleaned up
+	dir     string        // Workspace, set once created
+	project string        // Stored project exported, or "" for a public export
+
+	// Background jobs only
+	status      *JobStatus
+	output      string // Path of the finished download
+	contentType string
+}
+
+// jobRegistry tracks exports by job ID.
+type jobRegistry struct {
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+// lookup returns the job with id if it belongs to project. Job IDs can be
+// chosen by clients and are handed out in Location headers and webhooks, so
+// a project's jobs are only reachable through that project's routes.
+// Callers hold reg.mu.
+func (reg *jobRegistry) lookup(id, project string) (*job, bool) {
+	j, ok := reg.jobs[id]
+	if !ok || j.project != project {
+		return nil, false
+	}
+	return j, true
+}
+
+func (reg *jobRegistry) add(id string, j *job) error {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if _, ok := reg.jobs[id]; ok {
+		return errJobExists
+	}
+	if reg.jobs == nil {
+		reg.jobs = make(map[string]*job)
+	}
+	j.done = make(chan struct{})
+	reg.jobs[id] = j
+	return nil
+}
+
+// start registers a synchronous export of project ("" for none) and returns a
+// context that ends when the job is cancelled or ctx ends. The caller must
+// call finish with the same ID.
+func (reg *jobRegistry) start(ctx context.Context, id, project string) (context.Context, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	if err := reg.add(id, &job{cancel: cancel, project: project}); err != nil {
+		cancel(err)
+		return nil, err
+	}
+	return ctx, nil
+}
+
+// enqueue registers a background export whose frames are already in dir. Its
+// context is detached from the request that created it.
+func (reg *jobRegistry) enqueue(id, dir string, status *JobStatus) (context.Context, error) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	if err := reg.add(id, &job{cancel: cancel, dir: dir, project: status.ProjectID, status: status}); err != nil {
+		cancel(err)
+		return nil, err
+	}
+	return ctx, nil
+}
+
+func (reg *jobRegistry) setDir(id, dir string) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if j, ok := reg.jobs[id]; ok {
+		j.dir = dir
+	}
+}
+
+// update runs fn on a job under the registry lock.
+func (reg *jobRegistry) update(id string, fn func(j *job)) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if j, ok := reg.jobs[id]; ok {
+		fn(j)
+	}
+}
+
+// status returns a copy of a background job's status.
+func (reg *jobRegistry) status(id, project string) (JobStatus, bool) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	j, ok := reg.lookup(id, project)
+	if !ok || j.status == nil {
+		return JobStatus{}, false
+	}
+	return j.status.clone(), true
+}
+
+// result returns a background job's status and its download, if finished.
+func (reg *jobRegistry) result(id, project string) (JobStatus, string, string, bool) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	j, ok := reg.lookup(id, project)
+	if !ok || j.status == nil {
+		return JobStatus{}, "", "", false
+	}
+	return j.status.clone(), j.output, j.contentType, true
+}
+
+// finish unregisters a synchronous export once its workspace is removed.
+func (reg *jobRegistry) finish(id string) {
+	reg.mu.Lock()
+	j, ok := reg.jobs[id]
+	delete(reg.jobs, id)
+	reg.mu.Unlock()
+	if ok {
+		j.cancel(context.Canceled)
+		close(j.done)
+	}
+}
+
+// cancel signals a running job and returns a channel closed once it has
+// stopped, or false if there is no such job. A finished background job is
+// discarded along with its download instead.
+func (reg *jobRegistry) cancel(id, project string) (<-chan struct{}, bool) {
+	reg.mu.Lock()
+	j, ok := reg.lookup(id, project)
+	finished := ok && j.status != nil && j.status.FinishedAt != nil
+	if finished {
+		delete(reg.jobs, id)
+	}
+	reg.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+	if finished {
+		os.RemoveAll(j.dir)
+		return j.done, true
+	}
+	j.cancel(errJobCancelled)
+	return j.done, true
+}
+
+// expire drops background jobs that finished before cutoff, returning their
+// workspaces.
+func (reg *jobRegistry) expire(cutoff time.Time) []string {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	var dirs []string
+	for id, j := range reg.jobs {
+		if j.status != nil && j.status.FinishedAt != nil && j.status.FinishedAt.Before(cutoff) {
+			delete(reg.jobs, id)
+			if j.dir != "" {
+				dirs = append(dirs, j.dir)
+			}
+		}
+	}
+	return dirs
+}
+
+// dirs returns the workspaces of registered jobs.
+func (reg *jobRegistry) dirs() map[string]bool {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	dirs := make(map[string]bool, len(reg.jobs))
+	for _, j := range reg.jobs {
+		if j.dir != "" {
+			dirs[j.dir] = true
+		}
+	}
+	return dirs
+}
+
+// GetJob returns a background export's status, including per-scene progress.
+// A stored project's jobs are served only through its route, to members.
+func (h *Handler) GetJob(w http.ResponseWriter, r *http.Request) {
+	projectID, err := h.requestProject(r, "")
+	if err != nil {
+		writeProjectError(w, err)
+		return
+	}
+	status, ok := h.jobs.status(mux.Vars(r)["jobId"], projectID)
+	if !ok {
+		httperr.Write(w, http.StatusNotFound, httperr.CodeExportJobNotFound, "export job not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// DownloadJob streams a completed background export: the single scene's file,
+// or a zip of every scene that encoded.
+func (h *Handler) DownloadJob(w http.ResponseWriter, r *http.Request) {
+	projectID, err := h.requestProject(r, "")
+	if err != nil {
+		writeProjectError(w, err)
+		return
+	}
+	status, output, contentType, ok := h.jobs.result(mux.Vars(r)["jobId"], projectID)
+	if !ok {
+		httperr.Write(w, http.StatusNotFound, httperr.CodeExportJobNotFound, "export job not found")
+		return
+	}
+	if status.State != JobCompleted {
+		httperr.Write(w, http.StatusConflict, httperr.CodeExportNotReady, "export job is "+string(status.State))
+		return
+	}
+
+	f, err := os.Open(output)
+	if err != nil {
+		httperr.Internal(w, "open export output", err)
+		return
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		httperr.Internal(w, "stat export output", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+status.Download+`"`)
+	http.ServeContent(w, r, status.Download, stat.ModTime(), f)
+}
+
+// CancelJob stops a running export: its ffmpeg process group is killed and
+// its workspace removed, and the export fails with export_cancelled (a
+// background job is marked cancelled). The response waits for the job to
+// stop, so ffmpeg is gone and the files removed once it arrives. A finished
+// background job is discarded with its download.
+func (h *Handler) CancelJob(w http.ResponseWriter, r *http.Request) {
+	projectID, err := h.requestProject(r, "")
+	if err != nil {
+		writeProjectError(w, err)
+		return
+	}
+	jobID := mux.Vars(r)["jobId"]
+	done, ok := h.jobs.cancel(jobID, projectID)
+	if !ok {
+		httperr.Write(w, http.StatusNotFound, httperr.CodeExportJobNotFound, "export job not found")
+		return
+	}
+	select {
+	case <-done:
+	case <-time.After(cancelWait):
+		slog.Warn("export job slow to stop after cancel", "job_id", jobID)
+	case <-r.Context().Done():
+	}
+	slog.Info("export job cancelled", "job_id", jobID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SweepTempDirs removes export workspaces older than maxAge: finished
+// background jobs nobody downloaded in time, and directories no job owns.
+// The latter are left behind when the server dies mid-export, since the
+// deferred cleanup never runs. It returns the number of directories removed.
+func (h *Handler) SweepTempDirs(maxAge time.Duration) int {
+	cutoff := time.Now().Add(-maxAge)
+	removed := 0
+	for _, dir := range h.jobs.expire(cutoff) {
+		if err := os.RemoveAll(dir); err != nil {
+			slog.Warn("remove expired export dir", "dir", dir, "error", err)
+			continue
+		}
+		removed++
+	}
+
+	matches, err := filepath.Glob(filepath.Join(os.TempDir(), tempDirPattern))
+	if err != nil {
+		return removed
+	}
+	active := h.jobs.dirs()
+	for _, dir := range matches {
+		if active[dir] {
+			continue
+		}
+		info, err := os.Stat(dir)
+		if err != nil || !info.IsDir() || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			slog.Warn("remove stale export dir", "dir", dir, "error", err)
+			continue
+		}
+		removed++
+	}
+	return removed
+}
+
+// RunSweeper sweeps stale export workspaces at startup and then every
+// interval until ctx is done.
+func (h *Handler) RunSweeper(ctx context.Context, interval, maxAge time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n := h.SweepTempDirs(maxAge); n > 0 {
+			slog.Info("removed stale export dirs", "count", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
@@ -0,0 +1,245 @@
+//go:build unix
+
+package export
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// slowFfmpeg writes a stand-in for ffmpeg that starts a long-running child,
+// records the child's PID in pidFile, and waits on it.
+func slowFfmpeg(t *testing.T) (path, pidFile string) {
+	t.Helper()
+	dir := t.TempDir()
+	path, pidFile = filepath.Join(dir, "ffmpeg"), filepath.Join(dir, "child.pid")
+	script := fmt.Sprintf("#!/bin/sh\nsleep 60 &\necho $! > %s\nwait\n", pidFile)
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path, pidFile
+}
+
+// alive reports whether pid is running (not exited or a zombie).
+func alive(pid int) bool {
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err == nil {
+		// The state follows the parenthesized command name
+		fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+		return len(fields) > 0 && fields[0] != "Z"
+	}
+	return syscall.Kill(pid, 0) == nil
+}
+
+func TestCancelJobKillsFfmpegAndCleansUp(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	ffmpeg, pidFile := slowFfmpeg(t)
+	h := NewHandler(ffmpeg, nil, nil)
+	jobID := typeid.NewExportID()
+
+	exported := make(chan *httptest.ResponseRecorder)
+	go func() {
+		body, contentType := framesForm(t, map[string]string{"jobId": jobID}, frameKeys(0, 1)...)
+		r := httptest.NewRequest(http.MethodPost, "/export/video", body)
+		r.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		h.ExportVideo(rec, r)
+		exported <- rec
+	}()
+
+	pid := waitChildPID(t, pidFile)
+	workspaces, _ := filepath.Glob(filepath.Join(os.TempDir(), tempDirPattern))
+	if len(workspaces) != 1 {
+		t.Fatalf("workspaces %v, want the running export's", workspaces)
+	}
+
+	cancelJob(t, h, jobID)
+
+	select {
+	case rec := <-exported:
+		if rec.Code != http.StatusConflict || errorCode(t, rec) != "export_cancelled" {
+			t.Errorf("export answered %d %s, want 409 export_cancelled", rec.Code, rec.Body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("export still running after cancel")
+	}
+	if alive(pid) {
+		syscall.Kill(pid, syscall.SIGKILL)
+		t.Error("ffmpeg's child survived the cancel")
+	}
+	if _, err := os.Stat(workspaces[0]); !os.IsNotExist(err) {
+		t.Errorf("workspace %s left behind: %v", workspaces[0], err)
+	}
+
+	// The job is gone once cancelled
+	r := mux.SetURLVars(httptest.NewRequest(http.MethodDelete, "/export/jobs/"+jobID, nil), map[string]string{"jobId": jobID})
+	rec := httptest.NewRecorder()
+	h.CancelJob(rec, r)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("second cancel status %d, want 404", rec.Code)
+	}
+}
+
+func TestSweepTempDirs(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	h := NewHandler("true", nil, nil)
+
+	stale, _ := os.MkdirTemp("", tempDirPattern)
+	fresh, _ := os.MkdirTemp("", tempDirPattern)
+	owned, _ := os.MkdirTemp("", tempDirPattern)
+	other, _ := os.MkdirTemp("", "other-*")
+	old := time.Now().Add(-2 * time.Hour)
+	for _, dir := range []string{stale, owned, other} {
+		os.Chtimes(dir, old, old)
+	}
+	// A running job's workspace is kept however old it is
+	if _, err := h.jobs.start(t.Context(), "exp_running", ""); err != nil {
+		t.Fatal(err)
+	}
+	h.jobs.setDir("exp_running", owned)
+
+	if n := h.SweepTempDirs(time.Hour); n != 1 {
+		t.Errorf("swept %d dirs, want 1", n)
+	}
+	for dir, want := range map[string]bool{stale: false, fresh: true, owned: true, other: true} {
+		if _, err := os.Stat(dir); (err == nil) != want {
+			t.Errorf("%s exists = %v, want %v", filepath.Base(dir), err == nil, want)
+		}
+	}
+}
+
+// waitChildPID waits for slowFfmpeg's child to start and returns its PID.
+func waitChildPID(t *testing.T, pidFile string) int {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		data, _ := os.ReadFile(pidFile)
+		if pid, _ := strconv.Atoi(strings.TrimSpace(string(data))); pid != 0 {
+			return pid
+		}
+	}
+	t.Fatal("fake ffmpeg never started")
+	return 0
+}
+
+// createJob queues a background export of two frames of one scene.
+func createJob(t *testing.T, h *Handler, jobID string) {
+	t.Helper()
+	body, contentType := framesForm(t, map[string]string{"jobId": jobID}, "scene_1/frame_0000", "scene_1/frame_0001")
+	r := httptest.NewRequest(http.MethodPost, "/export/jobs", body)
+	r.Header.Set("Content-Type", contentType)
+	rec := httptest.NewRecorder()
+	h.CreateJob(rec, r)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("create job status %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func cancelJob(t *testing.T, h *Handler, jobID string) {
+	t.Helper()
+	r := mux.SetURLVars(httptest.NewRequest(http.MethodDelete, "/export/jobs/"+jobID, nil), map[string]string{"jobId": jobID})
+	rec := httptest.NewRecorder()
+	h.CancelJob(rec, r)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("cancel status %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestCancelBackgroundJob(t *testing.T) {
+	tests := []struct {
+		name    string
+		running bool
+	}{
+		{"queued", false},
+		{"running", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TMPDIR", t.TempDir())
+			ffmpeg, pidFile := slowFfmpeg(t)
+			h := NewHandler(ffmpeg, nil, nil)
+			if !tt.running {
+				// Take every worker slot so the job waits its turn
+				for range maxRunningJobs {
+					h.slots <- struct{}{}
+				}
+			}
+			jobID := typeid.NewExportID()
+			createJob(t, h, jobID)
+
+			var pid int
+			if tt.running {
+				pid = waitChildPID(t, pidFile)
+			} else if st, _ := h.jobs.status(jobID, ""); st.State != JobQueued {
+				t.Fatalf("job %s before cancel, want queued", st.State)
+			}
+			workspaces, _ := filepath.Glob(filepath.Join(os.TempDir(), tempDirPattern))
+
+			cancelJob(t, h, jobID)
+			st, ok := h.jobs.status(jobID, "")
+			if !ok || st.State != JobCancelled || st.FinishedAt == nil {
+				t.Fatalf("job after cancel: %+v, want cancelled", st)
+			}
+			if st.Scenes[0].State != JobCancelled {
+				t.Errorf("scene %s, want cancelled", st.Scenes[0].State)
+			}
+			if pid != 0 && alive(pid) {
+				syscall.Kill(pid, syscall.SIGKILL)
+				t.Error("ffmpeg's child survived the cancel")
+			}
+			for _, dir := range workspaces {
+				if _, err := os.Stat(dir); !os.IsNotExist(err) {
+					t.Errorf("workspace %s left behind: %v", dir, err)
+				}
+			}
+			if s := h.Stats(); s.Cancelled != 1 {
+				t.Errorf("cancelled count %d, want 1", s.Cancelled)
+			}
+		})
+	}
+}
+
+// TestClientDisconnectStopsExport checks a synchronous export runs under its
+// request's context, so a client going away kills ffmpeg.
+func TestClientDisconnectStopsExport(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	ffmpeg, pidFile := slowFfmpeg(t)
+	h := NewHandler(ffmpeg, nil, nil)
+
+	ctx, disconnect := context.WithCancel(t.Context())
+	exported := make(chan struct{})
+	go func() {
+		defer close(exported)
+		body, contentType := framesForm(t, nil, frameKeys(0, 1)...)
+		r := httptest.NewRequestWithContext(ctx, http.MethodPost, "/export/video", body)
+		r.Header.Set("Content-Type", contentType)
+		h.ExportVideo(httptest.NewRecorder(), r)
+	}()
+
+	pid := waitChildPID(t, pidFile)
+	disconnect()
+	select {
+	case <-exported:
+	case <-time.After(5 * time.Second):
+		t.Fatal("export still running after the client went away")
+	}
+	if alive(pid) {
+		syscall.Kill(pid, syscall.SIGKILL)
+		t.Error("ffmpeg's child survived the disconnect")
+	}
+	if workspaces, _ := filepath.Glob(filepath.Join(os.TempDir(), tempDirPattern)); len(workspaces) != 0 {
+		t.Errorf("workspaces %v left behind", workspaces)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package export
+
+import "os/exec"
+
+// killProcessGroup leaves cmd with the default cancellation, which kills only
+// the ffmpeg process itself.
+func killProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package export
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// killProcessGroup runs cmd in its own process group and makes context
+// cancellation kill the whole group, so helpers ffmpeg spawns don't outlive it.
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
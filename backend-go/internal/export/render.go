@@ -0,0 +1,367 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/httperr"
+	"github.com/inamate/inamate/backend-go/internal/raster"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// maxRenderDocumentSize bounds an inline document sent to be rendered.
+const maxRenderDocumentSize = 50 << 20 // 50MB
+
+// maxRenderScenes bounds how many scenes one render export may encode.
+const maxRenderScenes = 20
+
+// renderRequest is the body of POST /export/render. Exactly one of ProjectID
+// and Document names what is rendered; a stored project is rendered through
+// /api/projects/{projectId}/export/render, which fills in ProjectID.
+type renderRequest struct {
+	ProjectID string               `json:"projectId"`
+	Document  *document.InDocument `json:"document"`
+	SceneID   string               `json:"sceneId"` // Empty for the first scene
+	Scenes    string               `json:"scenes"`  // "all" or comma-separated scene IDs to zip; excludes SceneID
+	Format    string               `json:"format"`
+	FPS       int                  `json:"fps"` // Zero for the document's fps
+	Name      string               `json:"name"`
+	JobID     string               `json:"jobId"`
+}
+
+// SetImageLoader sets how image assets are loaded when frames are rendered
+// on the server. Without one, images are left out of rendered frames.
+func (h *Handler) SetImageLoader(images raster.ImageLoader) {
+	h.images = images
+}
+
+// RenderVideo handles POST /export/render: it renders every frame of a scene
+// of an inline document, or of a stored project when routed through the
+// project's export route, with the headless rasterizer, encodes them as
+// ExportVideo does, and streams the result back. Frames are
+// the scene's size, and there are as many as the root timeline is long.
+// When scenes is given, each selected scene is encoded to its own
+// {name}-{scene}.{format} file and the files are streamed back as one zip.
+func (h *Handler) RenderVideo(w http.ResponseWriter, r *http.Request) {
+	if h.disabled {
+		httperr.Write(w, http.StatusForbidden, httperr.CodeFeatureDisabled, "exports are disabled")
+		return
+	}
+	if _, err := exec.LookPath(h.ffmpegPath); err != nil {
+		httperr.Write(w, http.StatusServiceUnavailable, httperr.CodeFfmpegUnavailable,
+			"video export is unavailable: ffmpeg was not found on the server")
+		return
+	}
+
+	h.active.Add(1)
+	defer h.active.Add(-1)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxRenderDocumentSize)
+	var req renderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var mbe *http.MaxBytesError
+		if errors.As(err, &mbe) {
+			httperr.Write(w, http.StatusRequestEntityTooLarge, httperr.CodePayloadTooLarge, "document too large (max 50MB)")
+			return
+		}
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeInvalidBody, "invalid request body")
+		return
+	}
+	projectID, err := h.requestProject(r, req.ProjectID)
+	if err != nil {
+		writeProjectError(w, err)
+		return
+	}
+	req.ProjectID = projectID
+
+	jobID := req.JobID
+	if jobID == "" {
+		jobID = typeid.NewExportID()
+	} else if err := typeid.Validate(jobID, typeid.PrefixExport); err != nil {
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeInvalidID, "invalid job id")
+		return
+	}
+
+	if req.Format != "mp4" && req.Format != "gif" && req.Format != "webm" {
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed, "invalid format: must be mp4, gif, or webm")
+		return
+	}
+	if (req.ProjectID == "") == (req.Document == nil) {
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed, "exactly one of projectId and document is required")
+		return
+	}
+
+	name := req.Name
+	if name == "" {
+		name = "animation"
+	}
+	name = sanitizeName(name)
+
+	ctx, err := h.jobs.start(r.Context(), jobID, req.ProjectID)
+	if err != nil {
+		httperr.Write(w, http.StatusConflict, httperr.CodeExportJobExists, "export job already running: "+jobID)
+		return
+	}
+	defer h.jobs.finish(jobID)
+
+	doc := req.Document
+	if doc != nil {
+		if err := doc.Validate(); err != nil {
+			httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed, "invalid document: "+err.Error())
+			return
+		}
+	} else {
+		doc, err = h.loadProject(ctx, req.ProjectID)
+		if errors.Is(err, context.DeadlineExceeded) {
+			httperr.Write(w, http.StatusGatewayTimeout, httperr.CodeTimeout, "timed out loading project")
+			return
+		}
+		if err != nil {
+			code := httperr.CodeValidationFailed
+			if errors.Is(err, errProjectNotFound) {
+				code = httperr.CodeProjectNotFound
+			}
+			httperr.Write(w, http.StatusBadRequest, code, err.Error())
+			return
+		}
+	}
+
+	var sceneIDs []string
+	if req.Scenes != "" {
+		if req.SceneID != "" {
+			httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed, "sceneId and scenes cannot both be given")
+			return
+		}
+		sceneIDs, err = selectScenes(req.Scenes, doc, nil)
+		if err == nil && len(sceneIDs) == 0 {
+			err = fmt.Errorf("no scenes selected")
+		}
+		if err != nil {
+			httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed, err.Error())
+			return
+		}
+		if len(sceneIDs) > maxRenderScenes {
+			httperr.WriteDetails(w, http.StatusRequestEntityTooLarge, httperr.CodePayloadTooLarge,
+				fmt.Sprintf("too many scenes: %d exceeds the limit of %d", len(sceneIDs), maxRenderScenes),
+				map[string]interface{}{"scenes": len(sceneIDs), "maxScenes": maxRenderScenes})
+			return
+		}
+	} else {
+		sceneID := req.SceneID
+		if sceneID == "" {
+			if len(doc.Project.Scenes) == 0 {
+				httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed, "project has no scenes")
+				return
+			}
+			sceneID = doc.Project.Scenes[0]
+		}
+		sceneIDs = []string{sceneID}
+	}
+	scenes := make([]*document.Scene, len(sceneIDs))
+	for i, sceneID := range sceneIDs {
+		if scenes[i], err = sceneOf(doc, sceneID); err != nil {
+			httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed, err.Error())
+			return
+		}
+	}
+
+	// Every scene renders the whole root timeline, so the frame limit
+	// bounds their total
+	timeline, ok := doc.Timelines[doc.Project.RootTimeline]
+	if !ok || timeline.Length < 1 {
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed, "root timeline has no frames")
+		return
+	}
+	frameCount := timeline.Length
+	if total := frameCount * len(sceneIDs); total > h.maxFrames {
+		httperr.WriteDetails(w, http.StatusRequestEntityTooLarge, httperr.CodePayloadTooLarge,
+			fmt.Sprintf("too many frames: %d exceeds the limit of %d", total, h.maxFrames),
+			map[string]interface{}{"frames": total, "maxFrames": h.maxFrames})
+		return
+	}
+
+	fps := req.FPS
+	if fps == 0 {
+		fps = doc.Project.FPS
+	}
+	if fps <= 0 || fps > 120 {
+		fps = 24
+	}
+
+	tempDir, err := os.MkdirTemp("", "inamate-export-*")
+	if err != nil {
+		httperr.Internal(w, "create temp dir", err)
+		return
+	}
+	defer os.RemoveAll(tempDir)
+	h.jobs.setDir(jobID, tempDir)
+
+	if req.Scenes != "" {
+		h.renderScenes(ctx, w, tempDir, jobID, name, &req, fps, frameCount, doc, sceneIDs, scenes)
+		return
+	}
+	scene := scenes[0]
+
+	slog.Info("render export started", "job_id", jobID, "format", req.Format, "frames", frameCount, "fps", fps, "width", scene.Width, "height", scene.Height)
+
+	padWidth := max(4, len(strconv.Itoa(frameCount-1)))
+	if err := h.renderFrames(ctx, tempDir, padWidth, doc, sceneIDs[0], frameCount); err != nil {
+		h.writeRenderError(ctx, w, err)
+		return
+	}
+
+	// Frames are rendered over the scene background already, so there's
+	// nothing to flatten onto
+	inputPattern := filepath.Join(tempDir, fmt.Sprintf("frame_%%0%dd.png", padWidth))
+	outputFile, contentType, cmdErr := h.encode(ctx, tempDir, inputPattern, req.Format, fps, scene.Width, scene.Height, "", nil)
+	if cmdErr != nil {
+		h.writeEncodeError(ctx, w, cmdErr)
+		return
+	}
+
+	size, err := sendOutput(w, outputFile, contentType, name+"."+req.Format)
+	if err != nil {
+		httperr.Internal(w, "open output file", err)
+		return
+	}
+
+	h.completed.Add(1)
+	slog.Info("render export complete", "job_id", jobID, "format", req.Format, "size", size)
+
+	if req.ProjectID != "" {
+		h.announce(req.ProjectID, req.Format, name, frameCount, fps, size)
+	}
+}
+
+// renderScenes renders and encodes each scene to its own file in dir, as
+// RenderVideo does for one, and streams the files back zipped together.
+func (h *Handler) renderScenes(ctx context.Context, w http.ResponseWriter, dir, jobID, name string, req *renderRequest, fps, frameCount int, doc *document.InDocument, sceneIDs []string, scenes []*document.Scene) {
+	slog.Info("render export started", "job_id", jobID, "format", req.Format, "scenes", len(sceneIDs), "frames", frameCount, "fps", fps)
+
+	files := sceneFileNames(name, req.Format, sceneIDs, doc, nil)
+	padWidth := max(4, len(strconv.Itoa(frameCount-1)))
+	for i, sceneID := range sceneIDs {
+		sceneDir := filepath.Join(dir, strconv.Itoa(i))
+		if err := os.Mkdir(sceneDir, 0o755); err != nil {
+			httperr.Internal(w, "create scene dir", err)
+			return
+		}
+		if err := h.renderFrames(ctx, sceneDir, padWidth, doc, sceneID, frameCount); err != nil {
+			h.writeRenderError(ctx, w, err)
+			return
+		}
+		inputPattern := filepath.Join(sceneDir, fmt.Sprintf("frame_%%0%dd.png", padWidth))
+		output, _, err := h.encode(ctx, sceneDir, inputPattern, req.Format, fps, scenes[i].Width, scenes[i].Height, "", nil)
+		if err != nil {
+			h.writeEncodeError(ctx, w, err)
+			return
+		}
+		if err := os.Rename(output, filepath.Join(dir, files[i])); err != nil {
+			httperr.Internal(w, "move output", err)
+			return
+		}
+		os.RemoveAll(sceneDir)
+	}
+
+	download := name + ".zip"
+	if err := zipFiles(filepath.Join(dir, download), dir, files); err != nil {
+		h.failed.Add(1)
+		httperr.Internal(w, "zip export", err)
+		return
+	}
+	size, err := sendOutput(w, filepath.Join(dir, download), "application/zip", download)
+	if err != nil {
+		httperr.Internal(w, "open output file", err)
+		return
+	}
+
+	h.completed.Add(1)
+	slog.Info("render export complete", "job_id", jobID, "format", req.Format, "scenes", len(sceneIDs), "size", size)
+
+	if req.ProjectID != "" {
+		h.announce(req.ProjectID, req.Format, name, frameCount*len(sceneIDs), fps, size)
+	}
+}
+
+// writeRenderError reports frames that failed to render, or that stopped
+// because their job was cancelled.
+func (h *Handler) writeRenderError(ctx context.Context, w http.ResponseWriter, err error) {
+	if errors.Is(context.Cause(ctx), errJobCancelled) {
+		h.cancelled.Add(1)
+		httperr.Write(w, http.StatusConflict, httperr.CodeExportCancelled, "export was cancelled")
+		return
+	}
+	if errors.Is(err, raster.ErrFrameTooLarge) {
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed, "scene is too large to render")
+		return
+	}
+	h.failed.Add(1)
+	httperr.Internal(w, "render frames", err)
+}
+
+// renderFrames renders frames 0..count-1 of a scene into dir as PNGs named
+// to match the frame_%0<padWidth>d.png input pattern. It stops early when
+// ctx is done.
+func (h *Handler) renderFrames(ctx context.Context, dir string, padWidth int, doc *document.InDocument, sceneID string, count int) error {
+	images := h.images
+	if images != nil {
+		images = memoizeImages(images)
+	}
+	for frame := 0; frame < count; frame++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		img, err := raster.Frame(doc, sceneID, frame, 1, images)
+		if err != nil {
+			return err
+		}
+		if err := writePNG(filepath.Join(dir, fmt.Sprintf("frame_%0*d.png", padWidth, frame)), img); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// memoizeImages wraps an image loader so each asset is decoded once per
+// export rather than once per frame.
+func memoizeImages(load raster.ImageLoader) raster.ImageLoader {
+	type loaded struct {
+		img image.Image
+		err error
+	}
+	cache := make(map[string]loaded)
+	return func(assetID string) (image.Image, error) {
+		if l, ok := cache[assetID]; ok {
+			return l.img, l.err
+		}
+		img, err := load(assetID)
+		cache[assetID] = loaded{img, err}
+		return img, err
+	}
+}
+
+// writePNG encodes img as a PNG file at path.
+func writePNG(path string, img image.Image) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	// Rendered frames are temporary; favor speed over size
+	enc := png.Encoder{CompressionLevel: png.BestSpeed}
+	err = enc.Encode(out, img)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
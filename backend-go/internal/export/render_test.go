@@ -0,0 +1,115 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// twoScenes returns a document with scenes "Intro" and "The End", each a
+// small empty frame, on a two frame root timeline.
+func twoScenes() *document.InDocument {
+	doc := document.NewEmptyDocument(testProjectID, "Test", typeid.NewSceneID(), typeid.NewObjectID(), typeid.NewTimelineID())
+	first := doc.Scenes[doc.Project.Scenes[0]]
+	first.Name, first.Width, first.Height = "Intro", 32, 24
+	doc.Scenes[first.ID] = first
+
+	second := first
+	second.ID, second.Name, second.Root = typeid.NewSceneID(), "The End", typeid.NewObjectID()
+	root := doc.Objects[first.Root]
+	root.ID = second.Root
+	doc.Objects[second.Root] = root
+	doc.Scenes[second.ID] = second
+	doc.Project.Scenes = append(doc.Project.Scenes, second.ID)
+
+	timeline := doc.Timelines[doc.Project.RootTimeline]
+	timeline.Length = 2
+	doc.Timelines[timeline.ID] = timeline
+	return doc
+}
+
+func TestRenderVideoZipsScenes(t *testing.T) {
+	// The stand-in ffmpeg writes its last argument, the output file
+	ffmpeg := fakeFfmpeg(t, `for arg; do out=$arg; done; echo video > "$out"`, 0o755)
+	doc := twoScenes()
+	h := NewHandler(ffmpeg, func(ctx context.Context, projectID string) (*document.InDocument, error) {
+		return doc, nil
+	}, nil)
+	h.SetMemberCheck(func(ctx context.Context, projectID, userID string) (bool, error) { return true, nil })
+
+	r := httptest.NewRequest(http.MethodPost, "/api/projects/"+testProjectID+"/export/render",
+		strings.NewReader(`{"format":"webm","scenes":"all","name":"clip"}`))
+	rec := httptest.NewRecorder()
+	h.RenderVideo(rec, asProjectRoute(r, testProjectID, "member"))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/zip" {
+		t.Errorf("content type %q, want application/zip", ct)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(rc)
+		rc.Close()
+		if string(body) != "video\n" {
+			t.Errorf("%s holds %q, want the encoded video", f.Name, body)
+		}
+	}
+	if want := []string{"clip-Intro.webm", "clip-The-End.webm"}; !slices.Equal(names, want) {
+		t.Errorf("zip entries %v, want %v", names, want)
+	}
+}
+
+func TestRenderVideoScenesBounds(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		frames   int
+		wantCode int
+	}{
+		{"sceneId and scenes", `{"format":"mp4","scenes":"all","sceneId":"scene_01h455vb4pex5vsknk084sn02q"}`, 2, http.StatusBadRequest},
+		{"unknown scene", `{"format":"mp4","scenes":"scene_01h455vb4pex5vsknk084sn02q"}`, 2, http.StatusBadRequest},
+		// Each scene renders the whole timeline, so the frames add up
+		{"total frames", `{"format":"mp4","scenes":"all"}`, 6, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := twoScenes()
+			timeline := doc.Timelines[doc.Project.RootTimeline]
+			timeline.Length = tt.frames
+			doc.Timelines[timeline.ID] = timeline
+			h := NewHandler("true", func(ctx context.Context, projectID string) (*document.InDocument, error) {
+				return doc, nil
+			}, nil)
+			h.SetMemberCheck(func(ctx context.Context, projectID, userID string) (bool, error) { return true, nil })
+			h.SetMaxFrames(10)
+
+			r := httptest.NewRequest(http.MethodPost, "/api/projects/"+testProjectID+"/export/render", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			h.RenderVideo(rec, asProjectRoute(r, testProjectID, "member"))
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+		})
+	}
+}
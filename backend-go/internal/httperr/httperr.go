@@ -0,0 +1,139 @@
+// Package httperr writes structured error responses for the REST API.
+//
+// Every error response has the same JSON envelope:
+//
+//	{"code": "project_not_found", "message": "project not found", "details": ..., "requestId": "..."}
+//
+// Clients should branch on code, which is stable; message is for humans and
+// may change. details is optional and code-specific. The envelope and codes
+// are described for clients in api/openapi.yaml.
+package httperr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+// RequestIDHeader carries the request ID set by the RequestID middleware. It is
+// echoed into error envelopes so reports can be matched with server logs.
+const RequestIDHeader = "X-Request-ID"
+
+// Stable error codes.
+const (
+	// 400
+	CodeInvalidBody      = "invalid_body"      // Body is not valid JSON or multipart
+	CodeValidationFailed = "validation_failed" // A field is missing or invalid
+	CodeInvalidFrames    = "invalid_frames"    // Export frames are missing, unreadable, or mismatched
+	CodeInvalidID        = "invalid_id"        // A path ID is not a TypeID with the expected prefix
+
+	// 401
+	CodeUnauthorized       = "unauthorized"        // No or malformed Authorization header
+	CodeInvalidToken       = "invalid_token"       // Token failed validation or names an unknown user
+	CodeInvalidCredentials = "invalid_credentials" // Wrong email or password
+
+	// 403
+	CodeForbidden          = "forbidden"           // Caller lacks permission (e.g. not the owner)
+	CodeNotAMember         = "not_a_member"        // Caller is not a member of the project
+	CodeAccountDeactivated = "account_deactivated" // Account was deactivated by an operator
+	CodeAdminRequired      = "admin_required"      // Endpoint requires the admin role
+	CodeFeatureDisabled    = "feature_disabled"    // The deployment has turned the feature off
+	CodeMemberLimit        = "member_limit"        // The project has as many members as the deployment allows
+
+	// 404
+	CodeNotFound          = "not_found"
+	CodeProjectNotFound   = "project_not_found"
+	CodeSnapshotNotFound  = "snapshot_not_found"
+	CodeUserNotFound      = "user_not_found" // No account has that ID or email, e.g. when inviting (a 500 before codes)
+	CodeWebhookNotFound   = "webhook_not_found"
+	CodeRoomNotFound      = "room_not_found"
+	CodeExportJobNotFound = "export_job_not_found"
+	CodeAssetNotFound     = "asset_not_found"
+
+	// 409
+	CodeEmailTaken      = "email_taken"
+	CodeAssetExists     = "asset_exists"      // An upload named an asset ID that is already stored
+	CodeExportJobExists = "export_job_exists" // An export named a job ID that is already running
+	CodeExportCancelled = "export_cancelled"  // The export job was cancelled before it finished
+	CodeExportNotReady  = "export_not_ready"  // The export job has not completed, so there is nothing to download
+	CodeVersionConflict = "version_conflict"  // Another writer kept saving the document first; retry the request
+
+	// 413 / 415
+	CodePayloadTooLarge      = "payload_too_large"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+
+	// 429
+	CodeRateLimited   = "rate_limited"
+	CodeQuotaExceeded = "quota_exceeded"
+
+	// 5xx
+	CodeTimeout           = "timeout" // A database or document operation exceeded its deadline
+	CodeInternal          = "internal_error"
+	CodeEncodingFailed    = "encoding_failed"     // ffmpeg failed to encode an export
+	CodeUnavailable       = "service_unavailable" // A required dependency is missing
+	CodeFfmpegUnavailable = "ffmpeg_unavailable"  // Video export is disabled because ffmpeg cannot run
+	CodeCorruptDocument   = "corrupt_document"    // The live document failed its integrity check, so it was not saved
+)
+
+// Error is the JSON envelope for every error response.
+type Error struct {
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"requestId,omitempty"`
+}
+
+// Write sends an error envelope with the given status.
+func Write(w http.ResponseWriter, status int, code, message string) {
+	WriteDetails(w, status, code, message, nil)
+}
+
+// WriteDetails sends an error envelope carrying code-specific details.
+func WriteDetails(w http.ResponseWriter, status int, code, message string, details interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(Error{
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestID: w.Header().Get(RequestIDHeader),
+	})
+}
+
+// Internal logs err and sends a generic 500 so internals are not leaked.
+// Errors caused by a context deadline are reported as a 504 timeout instead.
+func Internal(w http.ResponseWriter, msg string, err error) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		slog.Warn(msg, "error", err, "requestId", w.Header().Get(RequestIDHeader))
+		Write(w, http.StatusGatewayTimeout, CodeTimeout, "request timed out")
+		return
+	}
+	slog.Error(msg, "error", err, "requestId", w.Header().Get(RequestIDHeader))
+	Write(w, http.StatusInternalServerError, CodeInternal, "internal error")
+}
+
+// Mapping associates a service sentinel error with its response.
+type Mapping struct {
+	Err     error
+	Status  int
+	Code    string
+	Message string // Defaults to Err.Error()
+}
+
+// FromError writes the response for the first mapping that err matches via
+// errors.Is. Unmatched errors are handled by Internal.
+func FromError(w http.ResponseWriter, err error, mappings []Mapping) {
+	for _, m := range mappings {
+		if errors.Is(err, m.Err) {
+			msg := m.Message
+			if msg == "" {
+				msg = err.Error()
+			}
+			Write(w, m.Status, m.Code, msg)
+			return
+		}
+	}
+	Internal(w, "service error", err)
+}
@@ -0,0 +1,115 @@
+package httperr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder) Error {
+	t.Helper()
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type %q, want application/json", ct)
+	}
+	var e Error
+	if err := json.Unmarshal(rec.Body.Bytes(), &e); err != nil {
+		t.Fatalf("body %q is not an envelope: %v", rec.Body, err)
+	}
+	return e
+}
+
+func TestWriteEchoesRequestID(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rec.Header().Set(RequestIDHeader, "req-1")
+	WriteDetails(rec, http.StatusBadRequest, CodeValidationFailed, "name is required", map[string]string{"field": "name"})
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status %d, want 400", rec.Code)
+	}
+	e := decode(t, rec)
+	if e.Code != CodeValidationFailed || e.Message != "name is required" || e.RequestID != "req-1" {
+		t.Errorf("envelope %+v", e)
+	}
+	if details, _ := e.Details.(map[string]interface{}); details["field"] != "name" {
+		t.Errorf("details %v, want field name", e.Details)
+	}
+}
+
+var errMissing = errors.New("thing not found")
+
+func TestFromError(t *testing.T) {
+	mappings := []Mapping{
+		{Err: errMissing, Status: http.StatusNotFound, Code: CodeNotFound},
+		{Err: context.Canceled, Status: http.StatusBadRequest, Code: CodeValidationFailed, Message: "cancelled"},
+	}
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		code    string
+		message string
+	}{
+		{"mapped", errMissing, http.StatusNotFound, CodeNotFound, "thing not found"},
+		{"wrapped", fmt.Errorf("load: %w", errMissing), http.StatusNotFound, CodeNotFound, "load: thing not found"},
+		{"custom message", context.Canceled, http.StatusBadRequest, CodeValidationFailed, "cancelled"},
+		{"unmapped", errors.New("db exploded"), http.StatusInternalServerError, CodeInternal, "internal error"},
+		{"deadline", fmt.Errorf("query: %w", context.DeadlineExceeded), http.StatusGatewayTimeout, CodeTimeout, "request timed out"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			FromError(rec, tt.err, mappings)
+			if rec.Code != tt.status {
+				t.Errorf("status %d, want %d", rec.Code, tt.status)
+			}
+			if e := decode(t, rec); e.Code != tt.code || e.Message != tt.message {
+				t.Errorf("envelope %+v, want code %s message %q", e, tt.code, tt.message)
+			}
+		})
+	}
+}
+
+// TestOpenAPIListsEveryCode keeps the ErrorCode enum in api/openapi.yaml in
+// step with the codes declared here.
+func TestOpenAPIListsEveryCode(t *testing.T) {
+	spec, err := os.ReadFile("../../api/openapi.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "httperr.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var codes int
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, s := range gen.Specs {
+			vs := s.(*ast.ValueSpec)
+			if !strings.HasPrefix(vs.Names[0].Name, "Code") {
+				continue
+			}
+			code, _ := strconv.Unquote(vs.Values[0].(*ast.BasicLit).Value)
+			codes++
+			if !strings.Contains(string(spec), "\n        - "+code+"\n") {
+				t.Errorf("%s (%q) is missing from the ErrorCode enum", vs.Names[0].Name, code)
+			}
+		}
+	}
+	if codes == 0 {
+		t.Fatal("found no codes in httperr.go")
+	}
+}
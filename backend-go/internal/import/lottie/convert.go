@@ -0,0 +1,838 @@
+// Package lottie converts Lottie (bodymovin) JSON animations into documents.
+//
+// Supported: shape, solid, image, null and precomp layers (precomps become
+// Symbols with their own timeline), layer parenting, rectangle, ellipse and
+// path shapes in nested groups with solid fills and strokes, and keyframed
+// transforms, opacity, fill/stroke color and stroke width. Anything else is
+// skipped or approximated and reported as a warning.
+package lottie
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/inamate/inamate/backend-go/internal/color"
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/engine"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// ErrInvalid is returned for input that isn't a usable Lottie animation.
+var ErrInvalid = errors.New("invalid Lottie file")
+
+// maxPrecompDepth bounds precomp nesting, which also breaks reference cycles.
+const maxPrecompDepth = 16
+
+// Image is an image asset the animation uses. The converted document already
+// refers to it as /assets/<AssetID>.png; the client uploads the image under
+// that ID.
+type Image struct {
+	AssetID  string `json:"assetId"`
+	Name     string `json:"name"`
+	Path     string `json:"path"` // File path relative to the Lottie file, or a data URI when Embedded
+	Embedded bool   `json:"embedded"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+}
+
+// Result is a converted animation.
+type Result struct {
+	Document *document.InDocument
+	Images   []Image
+	Warnings []string
+}
+
+// Convert converts Lottie JSON into a single-scene document. The project ID is
+// left for the caller to assign. Lottie has no background color, so the scene
+// uses background.
+func Convert(data []byte, background string) (*Result, error) {
+	var anim animation
+	if err := json.Unmarshal(data, &anim); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if anim.Width <= 0 || anim.Height <= 0 || anim.FrameRate <= 0 || anim.OutPoint <= anim.InPoint {
+		return nil, fmt.Errorf("%w: missing size, frame rate or frame range", ErrInvalid)
+	}
+
+	settings := document.SceneSettings{
+		Width:      round(anim.Width),
+		Height:     round(anim.Height),
+		Background: background,
+		FPS:        round(anim.FrameRate),
+	}
+	if err := settings.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	name := anim.Name
+	if name == "" {
+		name = "Lottie import"
+	}
+	rootID, timelineID := typeid.NewObjectID(), typeid.NewTimelineID()
+	doc := document.NewDocument("", name, typeid.NewSceneID(), rootID, timelineID, settings)
+	doc.EasingPresets = make(map[string]document.EasingPreset)
+
+	c := &converter{
+		doc:         doc,
+		length:      max(1, round(anim.OutPoint-anim.InPoint)),
+		assets:      make(map[string]asset, len(anim.Assets)),
+		imageIDs:    make(map[string]string),
+		images:      []Image{},
+		presetNames: make(map[document.EasingPreset]string),
+		warnings:    []string{},
+		warned:      make(map[string]bool),
+	}
+	for _, a := range anim.Assets {
+		c.assets[a.ID] = a
+	}
+	timeline := doc.Timelines[timelineID]
+	timeline.Length = c.length
+	doc.Timelines[timelineID] = timeline
+
+	if anim.FrameRate != float64(settings.FPS) {
+		c.warn("frame rate %g fps was rounded to %d fps", anim.FrameRate, settings.FPS)
+	}
+	if anim.ThreeD != 0 {
+		c.warn("3D layers are not supported and are imported flat")
+	}
+
+	c.convertLayers(anim.Layers, rootID, timelineID, -anim.InPoint, 0)
+
+	if len(doc.EasingPresets) == 0 {
+		doc.EasingPresets = nil
+	}
+	return &Result{Document: doc, Images: c.images, Warnings: c.warnings}, nil
+}
+
+type converter struct {
+	doc    *document.InDocument
+	length int // Root timeline length; nested timelines share it
+
+	assets      map[string]asset  // Lottie assets by ID
+	imageIDs    map[string]string // Lottie image asset ID → document asset ID
+	images      []Image
+	presetNames map[document.EasingPreset]string
+
+	warnings []string
+	warned   map[string]bool
+}
+
+// warn records a warning once, however many times it occurs.
+func (c *converter) warn(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if !c.warned[msg] {
+		c.warned[msg] = true
+		c.warnings = append(c.warnings, msg)
+	}
+}
+
+// --- Layers ---
+
+// convertLayers converts a composition's layers into children of parentID,
+// animated on timelineID. offset converts the composition's frame numbers to
+// timeline frames.
+func (c *converter) convertLayers(layers []layer, parentID, timelineID string, offset float64, depth int) {
+	ids := make([]string, len(layers))
+	byIndex := make(map[int]int, len(layers)) // Lottie ind → position in layers
+	hasChildren := make(map[int]bool)
+	for i, l := range layers {
+		if l.Index != nil {
+			byIndex[*l.Index] = i
+		}
+		if l.Parent != nil {
+			hasChildren[*l.Parent] = true
+		}
+	}
+	for i, l := range layers {
+		isParent := l.Index != nil && hasChildren[*l.Index]
+		ids[i] = c.convertLayer(l, timelineID, offset, depth, isParent)
+	}
+
+	// Lottie lists layers top first, while children draw in order
+	for i := len(layers) - 1; i >= 0; i-- {
+		if ids[i] == "" {
+			continue
+		}
+		l := layers[i]
+		parent := parentID
+		if l.Parent != nil {
+			j, ok := byIndex[*l.Parent]
+			switch {
+			case !ok || ids[j] == "":
+				c.warn("layer %q: parent layer %d was not imported", l.Name, *l.Parent)
+			case parentCycle(layers, byIndex, i):
+				c.warn("layer %q: parenting cycle ignored", l.Name)
+			default:
+				parent = ids[j]
+				if o := layers[j].Transform.Opacity; o != nil && (isAnimated(o) || numbers(c.static(o)).at(0) != 100) {
+					c.warn("layer %q: inherits its parent's opacity, which Lottie parenting does not", l.Name)
+				}
+			}
+		}
+		c.attach(parent, ids[i])
+	}
+}
+
+// parentCycle reports whether following layer i's parents leads back to it.
+func parentCycle(layers []layer, byIndex map[int]int, i int) bool {
+	seen := map[int]bool{i: true}
+	for j := i; layers[j].Parent != nil; {
+		next, ok := byIndex[*layers[j].Parent]
+		if !ok {
+			return false
+		}
+		if seen[next] {
+			return true
+		}
+		seen[next] = true
+		j = next
+	}
+	return false
+}
+
+// convertLayer creates the object for one layer, with its content, and
+// returns its ID, or "" if the layer was skipped.
+func (c *converter) convertLayer(l layer, timelineID string, offset float64, depth int, isParent bool) string {
+	if l.MatteSource != 0 {
+		c.warn("layer %q: track mattes are not supported; the matte layer was skipped", l.Name)
+		return ""
+	}
+	if l.MatteMode != 0 {
+		c.warn("layer %q: track mattes are not supported", l.Name)
+	}
+	if len(l.Effects) > 0 {
+		c.warn("layer %q: effects are not supported", l.Name)
+	}
+	if len(l.Masks) > 0 {
+		c.warn("layer %q: masks are not supported", l.Name)
+	}
+	if l.ThreeD != 0 {
+		c.warn("layer %q: 3D layers are not supported and are imported flat", l.Name)
+	}
+	if l.TimeRemap != nil {
+		c.warn("layer %q: time remapping is not supported", l.Name)
+	}
+
+	var obj document.ObjectNode
+	var precomp asset
+	switch l.Type {
+	case layerShape, layerNull:
+		obj = newObject(document.ObjectTypeGroup, map[string]interface{}{})
+
+	case layerSolid:
+		fill, err := color.Normalize(l.SolidColor)
+		if err != nil {
+			c.warn("layer %q: invalid solid color %q", l.Name, l.SolidColor)
+			fill = color.None
+		}
+		obj = newObject(document.ObjectTypeShapeRect, map[string]interface{}{
+			"width":  l.SolidWidth,
+			"height": l.SolidHeight,
+		})
+		obj.Style.Fill, obj.Style.Stroke = fill, color.None
+
+	case layerImage:
+		img, ok := c.image(l.RefID)
+		if !ok {
+			c.warn("layer %q: image asset %q not found", l.Name, l.RefID)
+			return ""
+		}
+		obj = newObject(document.ObjectTypeRasterImage, map[string]interface{}{
+			"assetId": img.AssetID,
+			"width":   img.Width,
+			"height":  img.Height,
+		})
+
+	case layerPrecomp:
+		a, ok := c.assets[l.RefID]
+		if !ok || a.Layers == nil {
+			c.warn("layer %q: precomp %q not found", l.Name, l.RefID)
+			return ""
+		}
+		if depth >= maxPrecompDepth {
+			c.warn("layer %q: precomps nested more than %d deep were skipped", l.Name, maxPrecompDepth)
+			return ""
+		}
+		precomp = a
+		nested := typeid.NewTimelineID()
+		c.doc.Timelines[nested] = document.Timeline{ID: nested, Length: c.length, Tracks: []string{}}
+		obj = newObject(document.ObjectTypeSymbol, map[string]interface{}{
+			"timelineId": nested,
+			"loop":       false,
+		})
+
+	case layerText:
+		c.warn("layer %q: text layers are not supported", l.Name)
+		return ""
+
+	default:
+		c.warn("layer %q: unsupported layer type %d", l.Name, l.Type)
+		return ""
+	}
+
+	obj.Visible = !l.Hidden
+	c.applyTransform(&obj, l.Transform, timelineID, offset)
+	if obj.Visible {
+		c.applyLayerWindow(&obj, l, timelineID, offset, isParent)
+	}
+	c.doc.Objects[obj.ID] = obj
+
+	switch l.Type {
+	case layerShape:
+		c.convertShapes(l.Shapes, obj.ID, timelineID, offset, paint{}, l.Name)
+	case layerPrecomp:
+		c.convertLayers(precomp.Layers, obj.ID, engine.GetSymbolTimelineID(obj.Data), offset+l.StartTime, depth+1)
+	}
+	return obj.ID
+}
+
+// applyLayerWindow hides a layer outside its in/out points with a visibility
+// track. Layers other layers are parented to stay visible, since hiding them
+// would also hide their children.
+func (c *converter) applyLayerWindow(obj *document.ObjectNode, l layer, timelineID string, offset float64, isParent bool) {
+	in, out := round(l.InPoint+offset), round(l.OutPoint+offset)
+	if in <= 0 && out >= c.length {
+		return
+	}
+	if isParent {
+		if l.Type != layerNull {
+			c.warn("layer %q: in/out points ignored because other layers are parented to it", l.Name)
+		}
+		return
+	}
+
+	visible := func(frame int, v bool) key {
+		return key{frame: frame, value: json.RawMessage(fmt.Sprint(v)), easing: document.EasingLinear}
+	}
+	keys := []key{visible(0, in <= 0)}
+	if in > 0 {
+		keys = append(keys, visible(in, true))
+	}
+	if out < c.length {
+		keys = append(keys, visible(out, false))
+	}
+	c.addTrack(timelineID, obj.ID, "visible", keys, func(v json.RawMessage) (json.RawMessage, bool) { return v, true })
+}
+
+// image returns the document asset for a Lottie image asset, creating it on
+// first use.
+func (c *converter) image(refID string) (Image, bool) {
+	a, ok := c.assets[refID]
+	if !ok || a.Layers != nil || a.Path == "" {
+		return Image{}, false
+	}
+	if id, ok := c.imageIDs[refID]; ok {
+		for _, img := range c.images {
+			if img.AssetID == id {
+				return img, true
+			}
+		}
+	}
+
+	id := typeid.NewAssetID()
+	name := a.Name
+	if name == "" {
+		name = a.Path
+		if a.Embedded != 0 {
+			name = a.ID
+		}
+	}
+	img := Image{
+		AssetID:  id,
+		Name:     name,
+		Path:     a.Path,
+		Embedded: a.Embedded != 0,
+		Width:    round(a.Width),
+		Height:   round(a.Height),
+	}
+	if !img.Embedded {
+		img.Path = a.Dir + a.Path
+	}
+
+	c.imageIDs[refID] = id
+	c.images = append(c.images, img)
+	c.doc.Assets[id] = document.Asset{
+		ID:   id,
+		Type: "png",
+		Name: name,
+		URL:  "/assets/" + id + ".png",
+		Meta: json.RawMessage(`{}`),
+	}
+	c.doc.Project.Assets = append(c.doc.Project.Assets, id)
+	return img, true
+}
+
+// --- Shapes ---
+
+// paint is the fill and stroke a shape group applies to its shapes.
+type paint struct {
+	fill, stroke *shape
+}
+
+// unsupportedShapes names the shape items that are skipped.
+var unsupportedShapes = map[string]string{
+	"gf": "gradient fills",
+	"gs": "gradient strokes",
+	"tm": "trim paths",
+	"rd": "rounded corners",
+	"rp": "repeaters",
+	"mm": "merge paths",
+	"pb": "pucker/bloat",
+	"op": "offset paths",
+	"tw": "twist",
+	"zz": "zig zag",
+	"sr": "polystars",
+}
+
+// convertShapes converts a shape layer's or group's items into children of
+// parentID. Fills and strokes apply to every shape in their group and its
+// subgroups, unless a subgroup has its own.
+func (c *converter) convertShapes(items []shape, parentID, timelineID string, offset float64, inherited paint, layerName string) {
+	p := inherited
+	fillSet, strokeSet := false, false
+	for i := range items {
+		it := &items[i]
+		if it.Hidden {
+			continue
+		}
+		switch it.Type {
+		case "fl":
+			if !fillSet {
+				p.fill, fillSet = it, true
+			}
+		case "st":
+			if !strokeSet {
+				p.stroke, strokeSet = it, true
+			}
+		default:
+			if feature, ok := unsupportedShapes[it.Type]; ok {
+				c.warn("layer %q: %s are not supported", layerName, feature)
+			}
+		}
+	}
+
+	// Items are listed top first, while children draw in order
+	for i := len(items) - 1; i >= 0; i-- {
+		it := items[i]
+		if it.Hidden {
+			continue
+		}
+		switch it.Type {
+		case "gr":
+			group := newObject(document.ObjectTypeGroup, map[string]interface{}{})
+			for _, tr := range it.Items {
+				if tr.Type == "tr" {
+					c.applyTransform(&group, transform{
+						Anchor:   tr.Anchor,
+						Position: tr.Position,
+						Scale:    tr.Size,
+						Rotation: tr.Roundness,
+						Opacity:  tr.Opacity,
+						Skew:     tr.Skew,
+					}, timelineID, offset)
+				}
+			}
+			c.doc.Objects[group.ID] = group
+			c.attach(parentID, group.ID)
+			c.convertShapes(it.Items, group.ID, timelineID, offset, p, layerName)
+
+		case "rc", "el", "sh":
+			obj, ok := c.geometry(it, timelineID, offset, layerName)
+			if !ok {
+				continue
+			}
+			c.applyPaint(&obj, p, timelineID, offset, layerName)
+			c.doc.Objects[obj.ID] = obj
+			c.attach(parentID, obj.ID)
+		}
+	}
+}
+
+// geometry converts a rectangle, ellipse or path item. Rectangles and
+// ellipses are positioned by their transform; their size can't be animated.
+func (c *converter) geometry(it shape, timelineID string, offset float64, layerName string) (document.ObjectNode, bool) {
+	switch it.Type {
+	case "rc":
+		size := numbers(c.static(it.Size))
+		w, h := size.at(0), size.at(1)
+		obj := newObject(document.ObjectTypeShapeRect, map[string]interface{}{"width": w, "height": h})
+		// Lottie positions rectangles by their center
+		c.animate(&obj, it.Position, timelineID, offset,
+			channel{property: "transform.x", index: 0, scale: 1, offset: -w / 2},
+			channel{property: "transform.y", index: 1, scale: 1, offset: -h / 2},
+		)
+		if isAnimated(it.Size) {
+			c.warn("layer %q: animated rectangle size is not supported; using the first value", layerName)
+		}
+		if r := numbers(c.static(it.Roundness)); r.at(0) > 0 {
+			c.warn("layer %q: rounded rectangles are imported with square corners", layerName)
+		}
+		return obj, true
+
+	case "el":
+		size := numbers(c.static(it.Size))
+		obj := newObject(document.ObjectTypeShapeEllipse, map[string]interface{}{"rx": size.at(0) / 2, "ry": size.at(1) / 2})
+		c.animate(&obj, it.Position, timelineID, offset,
+			channel{property: "transform.x", index: 0, scale: 1},
+			channel{property: "transform.y", index: 1, scale: 1},
+		)
+		if isAnimated(it.Size) {
+			c.warn("layer %q: animated ellipse size is not supported; using the first value", layerName)
+		}
+		return obj, true
+
+	case "sh":
+		path, ok := c.staticPath(it.Path)
+		if !ok {
+			c.warn("layer %q: unreadable path skipped", layerName)
+			return document.ObjectNode{}, false
+		}
+		if isAnimated(it.Path) {
+			c.warn("layer %q: animated paths are not supported; using the first shape", layerName)
+		}
+		obj := newObject(document.ObjectTypeVectorPath, map[string]interface{}{"commands": pathCommands(path)})
+		return obj, true
+	}
+	return document.ObjectNode{}, false
+}
+
+// staticPath returns a path property's value, or its first keyframe's.
+func (c *converter) staticPath(p *property) (bezierPath, bool) {
+	var path bezierPath
+	if p == nil {
+		return path, false
+	}
+	if len(p.Keyframes) == 0 {
+		return path, json.Unmarshal(p.K, &path) == nil && len(path.Vertices) > 0
+	}
+	var paths []bezierPath
+	if json.Unmarshal(p.Keyframes[0].Start, &paths) != nil || len(paths) == 0 || len(paths[0].Vertices) == 0 {
+		return path, false
+	}
+	return paths[0], true
+}
+
+// pathCommands converts a Lottie bezier path (tangents relative to their
+// vertex) to VectorPath commands, using lines where both tangents are zero.
+func pathCommands(p bezierPath) [][]interface{} {
+	v := p.Vertices
+	tangent := func(ts [][2]float64, i int) [2]float64 {
+		if i < len(ts) {
+			return ts[i]
+		}
+		return [2]float64{}
+	}
+	commands := [][]interface{}{{"M", v[0][0], v[0][1]}}
+	segment := func(from, to int) {
+		out, in := tangent(p.Out, from), tangent(p.In, to)
+		if out == [2]float64{} && in == [2]float64{} {
+			commands = append(commands, []interface{}{"L", v[to][0], v[to][1]})
+			return
+		}
+		commands = append(commands, []interface{}{"C",
+			v[from][0] + out[0], v[from][1] + out[1],
+			v[to][0] + in[0], v[to][1] + in[1],
+			v[to][0], v[to][1],
+		})
+	}
+	for i := 1; i < len(v); i++ {
+		segment(i-1, i)
+	}
+	if p.Closed {
+		segment(len(v)-1, 0)
+		commands = append(commands, []interface{}{"Z"})
+	}
+	return commands
+}
+
+// applyPaint styles a shape with its group's fill and stroke.
+func (c *converter) applyPaint(obj *document.ObjectNode, p paint, timelineID string, offset float64, layerName string) {
+	obj.Style.Fill, obj.Style.Stroke = color.None, color.None
+	if p.fill != nil {
+		c.animateColor(obj, p.fill, "style.fill", timelineID, offset, layerName)
+	}
+	if p.stroke != nil {
+		c.animateColor(obj, p.stroke, "style.stroke", timelineID, offset, layerName)
+		c.animate(obj, p.stroke.Width, timelineID, offset, channel{property: "style.strokeWidth", index: 0, scale: 1})
+	}
+}
+
+// animateColor sets a fill or stroke color, with a track when the color is
+// keyframed. The paint's opacity is folded into the color's alpha.
+func (c *converter) animateColor(obj *document.ObjectNode, item *shape, property string, timelineID string, offset float64, layerName string) {
+	opacity := 1.0
+	if item.Opacity != nil {
+		opacity = numbers(c.static(item.Opacity)).at(0) / 100
+		if isAnimated(item.Opacity) {
+			c.warn("layer %q: animated fill/stroke opacity is not supported; using the first value", layerName)
+		}
+	}
+	if item.Color == nil {
+		return
+	}
+	c.checkExpression(item.Color)
+
+	obj.Style = engine.ApplyStringOverridesToStyle(obj.Style, engine.StringPropertyOverrides{
+		property: hexColor(c.static(item.Color), opacity),
+	})
+	if isAnimated(item.Color) {
+		c.addTrack(timelineID, obj.ID, property, c.keys(item.Color, offset), func(v json.RawMessage) (json.RawMessage, bool) {
+			var rgba numbers
+			if json.Unmarshal(v, &rgba) != nil {
+				return nil, false
+			}
+			raw, err := json.Marshal(hexColor(rgba, opacity))
+			return raw, err == nil
+		})
+	}
+}
+
+// hexColor converts a Lottie [r, g, b, a?] color (0–1, or 0–255 in some
+// older files) to canonical hex, multiplying in opacity.
+func hexColor(v []float64, opacity float64) string {
+	rgba := numbers(v)
+	scale := 1.0
+	if rgba.at(0) > 1 || rgba.at(1) > 1 || rgba.at(2) > 1 {
+		scale = 255
+	}
+	alpha := 1.0
+	if len(rgba) > 3 {
+		alpha = rgba[3] / scale
+	}
+	return color.RGBA{
+		R: toByte(rgba.at(0) / scale),
+		G: toByte(rgba.at(1) / scale),
+		B: toByte(rgba.at(2) / scale),
+		A: toByte(alpha * opacity),
+	}.Hex()
+}
+
+func toByte(v float64) uint8 {
+	return uint8(math.Round(math.Max(0, math.Min(1, v)) * 255))
+}
+
+// --- Properties and keyframes ---
+
+// channel maps one component of a property's value to a document property.
+type channel struct {
+	property      string
+	index         int
+	scale, offset float64
+}
+
+func (ch channel) value(v []float64) float64 {
+	return numbers(v).at(ch.index)*ch.scale + ch.offset
+}
+
+// applyTransform sets an object's transform and opacity from a Lottie
+// transform, adding tracks for keyframed values.
+func (c *converter) applyTransform(obj *document.ObjectNode, t transform, timelineID string, offset float64) {
+	c.animate(obj, t.Anchor, timelineID, offset,
+		channel{property: "transform.ax", index: 0, scale: 1},
+		channel{property: "transform.ay", index: 1, scale: 1},
+	)
+	c.animate(obj, t.Position, timelineID, offset,
+		channel{property: "transform.x", index: 0, scale: 1},
+		channel{property: "transform.y", index: 1, scale: 1},
+	)
+	c.animate(obj, t.Scale, timelineID, offset,
+		channel{property: "transform.sx", index: 0, scale: 0.01},
+		channel{property: "transform.sy", index: 1, scale: 0.01},
+	)
+	c.animate(obj, t.Rotation, timelineID, offset,
+		channel{property: "transform.r", index: 0, scale: 1},
+	)
+	c.animate(obj, t.Opacity, timelineID, offset,
+		channel{property: "style.opacity", index: 0, scale: 0.01},
+	)
+	if t.Skew != nil && (isAnimated(t.Skew) || numbers(c.static(t.Skew)).at(0) != 0) {
+		c.warn("skew is not supported")
+	}
+}
+
+// animate applies a property's first value to obj through channels and, when
+// the property is keyframed, adds a track per channel.
+func (c *converter) animate(obj *document.ObjectNode, p *property, timelineID string, offset float64, channels ...channel) {
+	if p == nil {
+		return
+	}
+	if p.Split {
+		// Each split dimension is its own one-component property
+		for i, dim := range []*property{p.X, p.Y} {
+			var dimChannels []channel
+			for _, ch := range channels {
+				if ch.index == i {
+					ch.index = 0
+					dimChannels = append(dimChannels, ch)
+				}
+			}
+			c.animate(obj, dim, timelineID, offset, dimChannels...)
+		}
+		return
+	}
+	c.checkExpression(p)
+
+	v := c.static(p)
+	overrides := make(engine.PropertyOverrides, len(channels))
+	for _, ch := range channels {
+		overrides[ch.property] = ch.value(v)
+	}
+	obj.Transform = engine.ApplyOverridesToTransform(obj.Transform, overrides)
+	obj.Style = engine.ApplyOverridesToStyle(obj.Style, overrides)
+
+	if !isAnimated(p) {
+		return
+	}
+	keys := c.keys(p, offset)
+	for _, ch := range channels {
+		c.addTrack(timelineID, obj.ID, ch.property, keys, func(v json.RawMessage) (json.RawMessage, bool) {
+			var n numbers
+			if json.Unmarshal(v, &n) != nil {
+				return nil, false
+			}
+			raw, err := json.Marshal(ch.value(n))
+			return raw, err == nil
+		})
+	}
+}
+
+func (c *converter) checkExpression(p *property) {
+	if p.Expression != "" {
+		c.warn("expressions are not supported; their properties use the keyframed or static value")
+	}
+}
+
+// isAnimated reports whether a property has more than one keyframe.
+func isAnimated(p *property) bool {
+	if p == nil {
+		return false
+	}
+	if p.Split {
+		return isAnimated(p.X) || isAnimated(p.Y)
+	}
+	return len(p.Keyframes) > 1
+}
+
+// static returns a property's value, or its first keyframe's value when it is
+// keyframed.
+func (c *converter) static(p *property) []float64 {
+	if p == nil {
+		return nil
+	}
+	if p.Split {
+		return []float64{numbers(c.static(p.X)).at(0), numbers(c.static(p.Y)).at(0)}
+	}
+	raw := p.K
+	if len(p.Keyframes) > 0 {
+		raw = p.Keyframes[0].Start
+	}
+	var n numbers
+	if json.Unmarshal(raw, &n) != nil {
+		return nil
+	}
+	return n
+}
+
+// key is a Lottie keyframe in document terms.
+type key struct {
+	frame  int
+	value  json.RawMessage // Lottie value, converted per track
+	easing document.EasingType
+	hold   bool
+}
+
+// keys converts a keyframed property's keyframes, with times offset to
+// timeline frames.
+func (c *converter) keys(p *property, offset float64) []key {
+	keys := make([]key, 0, len(p.Keyframes))
+	for i, kf := range p.Keyframes {
+		value := kf.Start
+		if len(value) == 0 && i > 0 {
+			value = p.Keyframes[i-1].End
+		}
+		if len(value) == 0 {
+			continue
+		}
+		k := key{frame: round(kf.Time + offset), value: value, easing: document.EasingLinear, hold: kf.Hold != 0}
+		if !k.hold && i < len(p.Keyframes)-1 {
+			k.easing = c.easing(kf)
+		}
+		if nonZero(kf.SpatialIn) || nonZero(kf.SpatialOut) {
+			c.warn("curved motion paths are imported as straight lines")
+		}
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// addTrack adds a track of keys to a timeline, converting each value with
+// convert (keys it rejects are dropped). A hold key is followed by a copy one
+// frame before the next key, since there is no hold easing.
+func (c *converter) addTrack(timelineID, objectID, property string, keys []key, convert func(json.RawMessage) (json.RawMessage, bool)) {
+	track := document.Track{ID: typeid.NewTrackID(), ObjectID: objectID, Property: property, Keys: []string{}}
+	add := func(frame int, value json.RawMessage, easing document.EasingType) {
+		kf := document.Keyframe{ID: typeid.NewKeyframeID(), Frame: frame, Value: value, Easing: easing}
+		c.doc.Keyframes[kf.ID] = kf
+		track.Keys = append(track.Keys, kf.ID)
+	}
+	for i, k := range keys {
+		value, ok := convert(k.value)
+		if !ok {
+			continue
+		}
+		add(k.frame, value, k.easing)
+		if k.hold && i+1 < len(keys) && keys[i+1].frame-k.frame > 1 {
+			add(keys[i+1].frame-1, value, document.EasingLinear)
+		}
+	}
+	if len(track.Keys) == 0 {
+		return
+	}
+
+	c.doc.Tracks[track.ID] = track
+	timeline := c.doc.Timelines[timelineID]
+	timeline.Tracks = append(timeline.Tracks, track.ID)
+	c.doc.Timelines[timelineID] = timeline
+}
+
+// --- Objects ---
+
+// newObject returns a visible object with an identity transform.
+func newObject(typ document.ObjectType, data map[string]interface{}) document.ObjectNode {
+	raw, _ := json.Marshal(data)
+	return document.ObjectNode{
+		ID:        typeid.NewObjectID(),
+		Type:      typ,
+		Children:  []string{},
+		Transform: document.Transform{SX: 1, SY: 1},
+		Style:     document.Style{Opacity: 1},
+		Visible:   true,
+		Data:      raw,
+	}
+}
+
+// attach appends an object to its parent's children.
+func (c *converter) attach(parentID, childID string) {
+	child := c.doc.Objects[childID]
+	child.Parent = &parentID
+	c.doc.Objects[childID] = child
+
+	parent := c.doc.Objects[parentID]
+	parent.Children = append(parent.Children, childID)
+	c.doc.Objects[parentID] = parent
+}
+
+func round(v float64) int {
+	return int(math.Round(v))
+}
+
+func nonZero(vs []float64) bool {
+	for _, v := range vs {
+		if v != 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,213 @@
+package lottie
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+)
+
+func convertFixture(t *testing.T, name string) *Result {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Convert(data, "#ffffff")
+	if err != nil {
+		t.Fatalf("Convert(%s): %v", name, err)
+	}
+	return result
+}
+
+// rootChildren returns the objects directly under the scene root, in draw
+// order.
+func rootChildren(doc *document.InDocument) []document.ObjectNode {
+	root := doc.Objects[doc.Scenes[doc.Project.Scenes[0]].Root]
+	var children []document.ObjectNode
+	for _, id := range root.Children {
+		children = append(children, doc.Objects[id])
+	}
+	return children
+}
+
+// trackFor returns objectID's track on property, and its keyframes in order.
+func trackFor(t *testing.T, doc *document.InDocument, objectID, property string) []document.Keyframe {
+	t.Helper()
+	for _, track := range doc.Tracks {
+		if track.ObjectID == objectID && track.Property == property {
+			var keys []document.Keyframe
+			for _, id := range track.Keys {
+				keys = append(keys, doc.Keyframes[id])
+			}
+			return keys
+		}
+	}
+	t.Fatalf("no %s track on %s", property, objectID)
+	return nil
+}
+
+func TestConvertBounce(t *testing.T) {
+	result := convertFixture(t, "bounce.json")
+	doc := result.Document
+
+	scene := doc.Scenes[doc.Project.Scenes[0]]
+	if scene.Width != 400 || scene.Height != 300 || scene.Background != "#ffffff" || doc.Project.FPS != 30 {
+		t.Errorf("scene %dx%d %s at %d fps", scene.Width, scene.Height, scene.Background, doc.Project.FPS)
+	}
+	if doc.Timelines[doc.Project.RootTimeline].Length != 60 || doc.Project.Name != "Bounce" {
+		t.Errorf("project %q with %d frames", doc.Project.Name, doc.Timelines[doc.Project.RootTimeline].Length)
+	}
+
+	// Lottie lists the ball above the floor, so the floor draws first
+	children := rootChildren(doc)
+	if len(children) != 2 {
+		t.Fatalf("root has %d children, want the floor and ball layers", len(children))
+	}
+	floor, ball := children[0], children[1]
+	if floor.Type != document.ObjectTypeShapeRect || floor.Style.Fill != "#333333ff" {
+		t.Errorf("floor is %s filled %s, want a solid rect", floor.Type, floor.Style.Fill)
+	}
+	if ball.Type != document.ObjectTypeGroup || len(ball.Children) != 1 {
+		t.Fatalf("ball layer is %s with %d children", ball.Type, len(ball.Children))
+	}
+	group := doc.Objects[ball.Children[0]]
+	ellipse := doc.Objects[group.Children[0]]
+	if ellipse.Type != document.ObjectTypeShapeEllipse || string(ellipse.Data) != `{"rx":20,"ry":20}` {
+		t.Errorf("ellipse is %s %s", ellipse.Type, ellipse.Data)
+	}
+	// Paint opacity folds into the color's alpha
+	if ellipse.Style.Fill != "#ff3333ff" || ellipse.Style.Stroke != "#00000080" || ellipse.Style.StrokeWidth != 2 {
+		t.Errorf("ellipse style %+v", ellipse.Style)
+	}
+
+	keys := trackFor(t, doc, ball.ID, "transform.y")
+	var frames []int
+	var easings []document.EasingType
+	for _, kf := range keys {
+		frames = append(frames, kf.Frame)
+		easings = append(easings, kf.Easing)
+	}
+	if !slices.Equal(frames, []int{0, 30, 60}) || string(keys[1].Value) != "250" {
+		t.Errorf("y keys at %v, middle value %s", frames, keys[1].Value)
+	}
+	// The first curve is far from any built-in and becomes a preset; the
+	// second is close to easeInOut
+	want := []document.EasingType{document.EasingPresetPrefix + "lottie-1", document.EasingEaseInOut, document.EasingLinear}
+	if !slices.Equal(easings, want) {
+		t.Errorf("easings %v, want %v", easings, want)
+	}
+	if preset := doc.EasingPresets["lottie-1"]; preset != (document.EasingPreset{X1: 0.333, Y1: 0, X2: 0.667, Y2: 1}) {
+		t.Errorf("preset %+v", preset)
+	}
+	if len(result.Warnings) != 0 || len(result.Images) != 0 {
+		t.Errorf("warnings %v, images %v", result.Warnings, result.Images)
+	}
+}
+
+func TestConvertPrecomp(t *testing.T) {
+	result := convertFixture(t, "precomp.json")
+	doc := result.Document
+
+	children := rootChildren(doc)
+	if len(children) != 2 {
+		t.Fatalf("root has %d children, want the controller and the blade", len(children))
+	}
+	controller, symbol := children[0], children[1]
+
+	// Precomps become Symbols playing their own timeline
+	if symbol.Type != document.ObjectTypeSymbol || symbol.Transform.X != 100 {
+		t.Fatalf("precomp layer is %s at x %v", symbol.Type, symbol.Transform.X)
+	}
+	var data struct {
+		TimelineID string `json:"timelineId"`
+	}
+	json.Unmarshal(symbol.Data, &data)
+	nested, ok := doc.Timelines[data.TimelineID]
+	if !ok || data.TimelineID == doc.Project.RootTimeline || len(nested.Tracks) != 1 {
+		t.Fatalf("symbol timeline %q: %+v", data.TimelineID, nested)
+	}
+	bar := doc.Objects[symbol.Children[0]]
+	track := doc.Tracks[nested.Tracks[0]]
+	if track.ObjectID != bar.ID || track.Property != "transform.r" {
+		t.Errorf("nested track animates %s of %s, want the bar's rotation", track.Property, track.ObjectID)
+	}
+	keys := trackFor(t, doc, bar.ID, "transform.r")
+	if keys[0].Easing != document.EasingLinear || string(keys[1].Value) != "360" {
+		t.Errorf("rotation keys %+v", keys)
+	}
+	rect := doc.Objects[bar.Children[0]]
+	if rect.Type != document.ObjectTypeShapeRect || rect.Transform.X != -30 || rect.Transform.Y != -5 {
+		t.Errorf("rect %s at %v,%v, want centered on the layer", rect.Type, rect.Transform.X, rect.Transform.Y)
+	}
+
+	// The parented dot is under the null, and hidden outside its in/out points
+	if controller.Type != document.ObjectTypeGroup || len(controller.Children) != 1 {
+		t.Fatalf("null layer is %s with %d children", controller.Type, len(controller.Children))
+	}
+	dot := doc.Objects[controller.Children[0]]
+	path := doc.Objects[dot.Children[0]]
+	if path.Type != document.ObjectTypeVectorPath || !strings.Contains(string(path.Data), `["Z"]`) {
+		t.Errorf("dot shape %s %s, want a closed path", path.Type, path.Data)
+	}
+	var visible []string
+	for _, kf := range trackFor(t, doc, dot.ID, "visible") {
+		visible = append(visible, string(kf.Value))
+	}
+	if strings.Join(visible, " ") != "false true false" {
+		t.Errorf("visibility keys %v, want hidden, shown at 12, hidden at 36", visible)
+	}
+}
+
+func TestConvertImageAndWarnings(t *testing.T) {
+	result := convertFixture(t, "logo.json")
+	doc := result.Document
+
+	if len(result.Images) != 1 {
+		t.Fatalf("images %+v, want one", result.Images)
+	}
+	img := result.Images[0]
+	if img.Path != "images/img_0.png" || img.Embedded || img.Width != 128 || img.Height != 64 {
+		t.Errorf("image %+v", img)
+	}
+	asset, ok := doc.Assets[img.AssetID]
+	if !ok || asset.URL != "/assets/"+img.AssetID+".png" || !slices.Contains(doc.Project.Assets, img.AssetID) {
+		t.Errorf("asset %+v not in the project", asset)
+	}
+	children := rootChildren(doc)
+	if len(children) != 1 || children[0].Type != document.ObjectTypeRasterImage {
+		t.Fatalf("root children %+v, want just the image (text is skipped)", children)
+	}
+	if !strings.Contains(string(children[0].Data), img.AssetID) {
+		t.Errorf("image data %s does not refer to its asset", children[0].Data)
+	}
+	if keys := trackFor(t, doc, children[0].ID, "style.opacity"); string(keys[1].Value) != "1" {
+		t.Errorf("opacity keys end at %s, want 1", keys[1].Value)
+	}
+
+	for _, want := range []string{"expressions", "effects", "text layers"} {
+		if !slices.ContainsFunc(result.Warnings, func(w string) bool { return strings.Contains(w, want) }) {
+			t.Errorf("warnings %q do not mention %s", result.Warnings, want)
+		}
+	}
+}
+
+func TestConvertInvalid(t *testing.T) {
+	for _, in := range []string{
+		``,
+		`[]`,
+		`{"fr":30,"ip":0,"op":60,"w":0,"h":300}`,
+		`{"fr":0,"ip":0,"op":60,"w":400,"h":300}`,
+		`{"fr":30,"ip":60,"op":60,"w":400,"h":300}`,
+		`{"fr":30,"ip":0,"op":60,"w":100000,"h":300}`,
+	} {
+		if _, err := Convert([]byte(in), "#ffffff"); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Convert(%s): %v, want ErrInvalid", in, err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package lottie
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+)
+
+// builtinCurves approximates each built-in easing as a CSS cubic-bezier, so
+// Lottie curves close to one can use the built-in rather than a new preset.
+var builtinCurves = []struct {
+	easing document.EasingType
+	curve  document.EasingPreset
+}{
+	{document.EasingEaseIn, document.EasingPreset{X1: 0.11, Y1: 0, X2: 0.5, Y2: 0}},
+	{document.EasingEaseOut, document.EasingPreset{X1: 0.5, Y1: 1, X2: 0.89, Y2: 1}},
+	{document.EasingEaseInOut, document.EasingPreset{X1: 0.45, Y1: 0, X2: 0.55, Y2: 1}},
+	{document.EasingCubicIn, document.EasingPreset{X1: 0.32, Y1: 0, X2: 0.67, Y2: 0}},
+	{document.EasingCubicOut, document.EasingPreset{X1: 0.33, Y1: 1, X2: 0.68, Y2: 1}},
+	{document.EasingCubicInOut, document.EasingPreset{X1: 0.65, Y1: 0, X2: 0.35, Y2: 1}},
+	{document.EasingBackIn, document.EasingPreset{X1: 0.36, Y1: 0, X2: 0.66, Y2: -0.56}},
+	{document.EasingBackOut, document.EasingPreset{X1: 0.34, Y1: 1.56, X2: 0.64, Y2: 1}},
+	{document.EasingBackInOut, document.EasingPreset{X1: 0.68, Y1: -0.6, X2: 0.32, Y2: 1.6}},
+}
+
+// builtinTolerance is how far (per control point coordinate) a curve may be
+// from a built-in easing and still use it.
+const builtinTolerance = 0.05
+
+// easing maps a keyframe's out/in handles to an easing: linear, the nearest
+// built-in within builtinTolerance, or a preset added to the document.
+func (c *converter) easing(kf keyframe) document.EasingType {
+	if kf.Out == nil || kf.In == nil {
+		return document.EasingLinear
+	}
+	curve := document.EasingPreset{
+		X1: round3(kf.Out.X.at(0)),
+		Y1: round3(kf.Out.Y.at(0)),
+		X2: round3(kf.In.X.at(0)),
+		Y2: round3(kf.In.Y.at(0)),
+	}
+	if curve.X1 == curve.Y1 && curve.X2 == curve.Y2 {
+		return document.EasingLinear
+	}
+
+	best, bestDist := document.EasingType(""), math.Inf(1)
+	for _, b := range builtinCurves {
+		if d := curveDistance(curve, b.curve); d < bestDist {
+			best, bestDist = b.easing, d
+		}
+	}
+	if bestDist <= builtinTolerance {
+		return best
+	}
+
+	// Reuse a preset for the same curve
+	if name, ok := c.presetNames[curve]; ok {
+		return document.EasingType(document.EasingPresetPrefix + name)
+	}
+	name := fmt.Sprintf("lottie-%d", len(c.presetNames)+1)
+	c.presetNames[curve] = name
+	c.doc.EasingPresets[name] = curve
+	return document.EasingType(document.EasingPresetPrefix + name)
+}
+
+// curveDistance is the largest difference between two curves' control
+// point coordinates.
+func curveDistance(a, b document.EasingPreset) float64 {
+	return math.Max(
+		math.Max(math.Abs(a.X1-b.X1), math.Abs(a.Y1-b.Y1)),
+		math.Max(math.Abs(a.X2-b.X2), math.Abs(a.Y2-b.Y2)),
+	)
+}
+
+func round3(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
@@ -0,0 +1,213 @@
+package lottie
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// The subset of the bodymovin schema the converter reads. Field names follow
+// the format's abbreviations; see https://lottiefiles.github.io/lottie-docs/.
+
+type animation struct {
+	Version   string  `json:"v"`
+	FrameRate float64 `json:"fr"`
+	InPoint   float64 `json:"ip"`
+	OutPoint  float64 `json:"op"`
+	Width     float64 `json:"w"`
+	Height    float64 `json:"h"`
+	Name      string  `json:"nm"`
+	ThreeD    int     `json:"ddd"`
+	Layers    []layer `json:"layers"`
+	Assets    []asset `json:"assets"`
+}
+
+// asset is either a precomposition (Layers set) or an image.
+type asset struct {
+	ID       string  `json:"id"`
+	Name     string  `json:"nm"`
+	Layers   []layer `json:"layers"`
+	Width    float64 `json:"w"`
+	Height   float64 `json:"h"`
+	Dir      string  `json:"u"`
+	Path     string  `json:"p"`
+	Embedded int     `json:"e"`
+}
+
+// Layer types
+const (
+	layerPrecomp = 0
+	layerSolid   = 1
+	layerImage   = 2
+	layerNull    = 3
+	layerShape   = 4
+	layerText    = 5
+)
+
+type layer struct {
+	Type        int               `json:"ty"`
+	Index       *int              `json:"ind"`
+	Parent      *int              `json:"parent"`
+	Name        string            `json:"nm"`
+	RefID       string            `json:"refId"`
+	InPoint     float64           `json:"ip"`
+	OutPoint    float64           `json:"op"`
+	StartTime   float64           `json:"st"`
+	Hidden      bool              `json:"hd"`
+	ThreeD      int               `json:"ddd"`
+	Transform   transform         `json:"ks"`
+	Shapes      []shape           `json:"shapes"`
+	SolidColor  string            `json:"sc"`
+	SolidWidth  float64           `json:"sw"`
+	SolidHeight float64           `json:"sh"`
+	Effects     []json.RawMessage `json:"ef"`
+	Masks       []json.RawMessage `json:"masksProperties"`
+	MatteMode   int               `json:"tt"`
+	MatteSource int               `json:"td"`
+	TimeRemap   *property         `json:"tm"`
+}
+
+type transform struct {
+	Anchor   *property `json:"a"`
+	Position *property `json:"p"`
+	Scale    *property `json:"s"`
+	Rotation *property `json:"r"`
+	Opacity  *property `json:"o"`
+	Skew     *property `json:"sk"`
+}
+
+// shape is any item in a shape layer. Which fields apply depends on Type:
+// groups ("gr") hold Items, geometry ("rc", "el", "sh") and paints ("fl",
+// "st") use their own subsets, and a group's transform ("tr") uses the
+// transform fields.
+type shape struct {
+	Type   string  `json:"ty"`
+	Name   string  `json:"nm"`
+	Hidden bool    `json:"hd"`
+	Items  []shape `json:"it"`
+
+	Anchor    *property `json:"a"`
+	Position  *property `json:"p"`
+	Size      *property `json:"s"` // Rect/ellipse size, or a transform's scale
+	Roundness *property `json:"r"` // Rect roundness, or a transform's rotation
+	Skew      *property `json:"sk"`
+	Path      *property `json:"ks"`
+	Color     *property `json:"c"`
+	Opacity   *property `json:"o"`
+	Width     *property `json:"w"`
+}
+
+// property is an animatable value: either static (K holds the value) or a
+// list of keyframes. Position may instead be split into separate X and Y
+// properties.
+type property struct {
+	K          json.RawMessage
+	Keyframes  []keyframe
+	Expression string
+	Split      bool
+	X, Y       *property
+}
+
+func (p *property) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		K json.RawMessage `json:"k"`
+		X json.RawMessage `json:"x"`
+		Y json.RawMessage `json:"y"`
+		S json.RawMessage `json:"s"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	var split bool
+	if json.Unmarshal(raw.S, &split) == nil && split {
+		p.Split = true
+		if err := json.Unmarshal(raw.X, &p.X); err != nil {
+			return fmt.Errorf("split position x: %w", err)
+		}
+		if err := json.Unmarshal(raw.Y, &p.Y); err != nil {
+			return fmt.Errorf("split position y: %w", err)
+		}
+		return nil
+	}
+
+	// "x" is an expression when it's a string
+	json.Unmarshal(raw.X, &p.Expression)
+
+	p.K = raw.K
+	if isKeyframeList(raw.K) {
+		if err := json.Unmarshal(raw.K, &p.Keyframes); err != nil {
+			return fmt.Errorf("keyframes: %w", err)
+		}
+	}
+	return nil
+}
+
+// isKeyframeList reports whether k is an array of keyframe objects rather
+// than a static value.
+func isKeyframeList(k json.RawMessage) bool {
+	var items []json.RawMessage
+	if json.Unmarshal(k, &items) != nil || len(items) == 0 {
+		return false
+	}
+	var kf struct {
+		T *float64 `json:"t"`
+	}
+	first := bytes.TrimSpace(items[0])
+	return len(first) > 0 && first[0] == '{' && json.Unmarshal(first, &kf) == nil && kf.T != nil
+}
+
+type keyframe struct {
+	Time  float64         `json:"t"`
+	Start json.RawMessage `json:"s"`
+	End   json.RawMessage `json:"e"` // Pre-5.5 files store the end value on each keyframe
+	In    *tangent        `json:"i"`
+	Out   *tangent        `json:"o"`
+	Hold  int             `json:"h"`
+
+	// Spatial tangents for curved motion paths
+	SpatialIn  []float64 `json:"ti"`
+	SpatialOut []float64 `json:"to"`
+}
+
+// tangent is a keyframe easing handle. Each coordinate is a number or a
+// per-dimension array; only the first dimension is used.
+type tangent struct {
+	X numbers `json:"x"`
+	Y numbers `json:"y"`
+}
+
+// numbers is a value that may be a single number or an array of numbers.
+type numbers []float64
+
+func (n *numbers) UnmarshalJSON(data []byte) error {
+	var v float64
+	if err := json.Unmarshal(data, &v); err == nil {
+		*n = numbers{v}
+		return nil
+	}
+	var vs []float64
+	if err := json.Unmarshal(data, &vs); err != nil {
+		return err
+	}
+	*n = vs
+	return nil
+}
+
+func (n numbers) at(i int) float64 {
+	if i < len(n) {
+		return n[i]
+	}
+	if len(n) > 0 {
+		return n[len(n)-1]
+	}
+	return 0
+}
+
+// bezierPath is a shape path value: vertices with tangents relative to them.
+type bezierPath struct {
+	Closed   bool         `json:"c"`
+	Vertices [][2]float64 `json:"v"`
+	In       [][2]float64 `json:"i"`
+	Out      [][2]float64 `json:"o"`
+}
@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/inamate/inamate/backend-go/internal/auth"
+	"github.com/inamate/inamate/backend-go/internal/httperr"
+)
+
+// IdempotencyHeader is the request header clients set to make a create request retry-safe.
+const IdempotencyHeader = "Idempotency-Key"
+
+const maxIdempotencyKeyLen = 255
+
+// maxAnonymousIdempotentBody bounds the request bodies hashed into the scope
+// of anonymous keyed requests. It covers the largest batch upload.
+const maxAnonymousIdempotentBody = 64 << 20
+
+// IdempotencyStore remembers the responses of create requests by their
+// Idempotency-Key so that a retried request replays the original response
+// instead of creating a duplicate resource.
+type IdempotencyStore struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*idempotencyEntry
+}
+
+type idempotencyEntry struct {
+	done    chan struct{} // closed once the first request has finished
+	ok      bool          // response was cached (2xx); otherwise the entry is discarded
+	status  int
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+// NewIdempotencyStore creates a store that keeps responses for ttl.
+func NewIdempotencyStore(ttl time.Duration) *IdempotencyStore {
+	return &IdempotencyStore{
+		ttl:     ttl,
+		entries: make(map[string]*idempotencyEntry),
+	}
+}
+
+// Middleware replays cached responses for requests carrying a previously seen
+// Idempotency-Key. Keys are scoped to the request route and the authenticated
+// user, or for anonymous requests to a hash of the body, so anonymous clients
+// that pick the same key only share a response when they sent the same
+// request. Concurrent duplicates wait for the first request to finish. Only
+// successful responses are cached so failed requests can be retried.
+func (s *IdempotencyStore) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyHeader)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed, "idempotency key too long")
+			return
+		}
+
+		caller := auth.UserIDFromContext(r.Context())
+		if caller == "" {
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxAnonymousIdempotentBody))
+			if err != nil {
+				var mbe *http.MaxBytesError
+				if errors.As(err, &mbe) {
+					httperr.Write(w, http.StatusRequestEntityTooLarge, httperr.CodePayloadTooLarge, "request body too large")
+				} else {
+					httperr.Write(w, http.StatusBadRequest, httperr.CodeInvalidBody, "failed to read request body")
+				}
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			sum := sha256.Sum256(body)
+			caller = "body:" + hex.EncodeToString(sum[:])
+		}
+		scope := r.Method + " " + r.URL.Path + " " + caller + " " + key
+
+		for {
+			entry, owner := s.reserve(scope)
+			if owner {
+				rec := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+				next.ServeHTTP(rec, r)
+				s.complete(scope, entry, rec)
+				return
+			}
+
+			select {
+			case <-entry.done:
+			case <-r.Context().Done():
+				return
+			}
+			if entry.ok {
+				replay(w, entry)
+				return
+			}
+			// The original request failed; try to become the new owner.
+		}
+	})
+}
+
+// reserve returns the entry for scope, creating it if needed. owner is true
+// when the caller created the entry and must run the handler.
+func (s *IdempotencyStore) reserve(scope string) (*idempotencyEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, e := range s.entries {
+		if e.ok && now.After(e.expires) {
+			delete(s.entries, k)
+		}
+	}
+
+	if e, ok := s.entries[scope]; ok {
+		return e, false
+	}
+	e := &idempotencyEntry{done: make(chan struct{})}
+	s.entries[scope] = e
+	return e, true
+}
+
+// complete stores the recorded response (or drops the entry on failure) and
+// wakes any waiting duplicates.
+func (s *IdempotencyStore) complete(scope string, e *idempotencyEntry, rec *recordingWriter) {
+	s.mu.Lock()
+	if rec.status >= 200 && rec.status < 300 {
+		e.ok = true
+		e.status = rec.status
+		e.header = rec.Header().Clone()
+		e.body = rec.body.Bytes()
+		e.expires = time.Now().Add(s.ttl)
+	} else {
+		delete(s.entries, scope)
+	}
+	s.mu.Unlock()
+	close(e.done)
+}
+
+func replay(w http.ResponseWriter, e *idempotencyEntry) {
+	for k, v := range e.header {
+		w.Header()[k] = v
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(e.status)
+	w.Write(e.body)
+}
+
+// recordingWriter passes the response through while keeping a copy of it.
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
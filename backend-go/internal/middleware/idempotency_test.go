@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/inamate/inamate/backend-go/internal/auth"
+)
+
+// creator stands in for a create endpoint: each request it handles creates a
+// new resource named after its body.
+type creator struct {
+	created atomic.Int64
+}
+
+func (c *creator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	n := c.created.Add(1)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	fmt.Fprintf(w, `{"id":"res_%d","name":%q}`, n, body)
+}
+
+func keyedRequest(userID, key, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/api/projects", strings.NewReader(body))
+	r.Header.Set(IdempotencyHeader, key)
+	if userID != "" {
+		r = r.WithContext(context.WithValue(r.Context(), auth.UserIDKey, userID))
+	}
+	return r
+}
+
+func serve(h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return rec
+}
+
+func TestIdempotentCreateReplays(t *testing.T) {
+	c := &creator{}
+	h := NewIdempotencyStore(time.Hour).Middleware(c)
+
+	first := serve(h, keyedRequest("user_a", "key-1", `{"name":"Film"}`))
+	second := serve(h, keyedRequest("user_a", "key-1", `{"name":"Film"}`))
+
+	if got := c.created.Load(); got != 1 {
+		t.Fatalf("created %d resources, want 1", got)
+	}
+	if second.Code != first.Code || second.Body.String() != first.Body.String() {
+		t.Errorf("retry got %d %s, want %d %s", second.Code, second.Body, first.Code, first.Body)
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("retry not marked as replayed")
+	}
+}
+
+func TestIdempotencyKeyScopedToCaller(t *testing.T) {
+	tests := []struct {
+		name        string
+		first, then *http.Request
+		wantCreated int64
+	}{
+		{"other user", keyedRequest("user_a", "k", `{}`), keyedRequest("user_b", "k", `{}`), 2},
+		{"anonymous, other body", keyedRequest("", "k", `{"name":"a"}`), keyedRequest("", "k", `{"name":"b"}`), 2},
+		{"anonymous, same body", keyedRequest("", "k", `{"name":"a"}`), keyedRequest("", "k", `{"name":"a"}`), 1},
+		{"anonymous, then a user", keyedRequest("", "k", `{}`), keyedRequest("user_a", "k", `{}`), 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &creator{}
+			h := NewIdempotencyStore(time.Hour).Middleware(c)
+			first := serve(h, tt.first)
+			then := serve(h, tt.then)
+			if got := c.created.Load(); got != tt.wantCreated {
+				t.Errorf("created %d resources, want %d", got, tt.wantCreated)
+			}
+			// The second caller gets its own resource, not the first's
+			if tt.wantCreated == 2 && then.Body.String() == first.Body.String() {
+				t.Errorf("second caller was replayed the first's response %s", first.Body)
+			}
+		})
+	}
+}
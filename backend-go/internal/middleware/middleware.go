@@ -2,12 +2,35 @@ package middleware
 
 import (
 	"bufio"
+	"context"
+	"fmt"
 	"log/slog"
 	"net"
 	"net/http"
 	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"github.com/inamate/inamate/backend-go/internal/httperr"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
 )
 
+const maxRequestIDLen = 128
+
+// RequestID tags every response with an X-Request-ID, reusing the caller's
+// value when one is supplied, so error reports can be matched to server logs.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(httperr.RequestIDHeader)
+		if id == "" || len(id) > maxRequestIDLen {
+			id = uuid.New().String()
+		}
+		w.Header().Set(httperr.RequestIDHeader, id)
+		next.ServeHTTP(w, r)
+	})
+}
+
 func Logger(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
@@ -18,16 +41,53 @@ func Logger(next http.Handler) http.Handler {
 			"path", r.URL.Path,
 			"status", wrapped.status,
 			"duration", time.Since(start).String(),
+			"requestId", w.Header().Get(httperr.RequestIDHeader),
 		)
 	})
 }
 
+// Timeout gives each request a context deadline of d. Handlers and the
+// services they call observe it through r.Context().
+func Timeout(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// PathIDs rejects requests whose route variables don't hold a TypeID with the
+// expected prefix. prefixes maps a variable name (e.g. "projectId") to its
+// prefix; other variables are not checked. Must be used on a mux router so
+// the variables are set.
+func PathIDs(prefixes map[string]string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for name, value := range mux.Vars(r) {
+				prefix, ok := prefixes[name]
+				if !ok {
+					continue
+				}
+				if err := typeid.Validate(value, prefix); err != nil {
+					httperr.WriteDetails(w, http.StatusBadRequest, httperr.CodeInvalidID,
+						fmt.Sprintf("%s must be a %q id", name, prefix),
+						map[string]string{"param": name, "prefix": prefix})
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func Recovery(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
 				slog.Error("panic recovered", "error", err, "path", r.URL.Path)
-				http.Error(w, "internal server error", http.StatusInternalServerError)
+				httperr.Write(w, http.StatusInternalServerError, httperr.CodeInternal, "internal server error")
 			}
 		}()
 		next.ServeHTTP(w, r)
@@ -42,8 +102,9 @@ func CORSWithOrigins(allowedOrigins map[string]bool) func(http.Handler) http.Han
 				w.Header().Set("Access-Control-Allow-Origin", origin)
 			}
 			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type")
+			w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, Idempotency-Key")
 			w.Header().Set("Access-Control-Allow-Credentials", "true")
+			w.Header().Set("Access-Control-Expose-Headers", httperr.RequestIDHeader)
 			w.Header().Set("Access-Control-Max-Age", "300")
 
 			if r.Method == http.MethodOptions {
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/inamate/inamate/backend-go/internal/httperr"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// TestTimeoutSurfacesAsGatewayTimeout checks a handler stuck on a slow
+// dependency gets its context cancelled, and that the error it reports
+// reaches the client as a timeout.
+func TestTimeoutSurfacesAsGatewayTimeout(t *testing.T) {
+	stuck := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			httperr.Internal(w, "query", fmt.Errorf("get project: %w", r.Context().Err()))
+		case <-time.After(5 * time.Second):
+			w.WriteHeader(http.StatusOK)
+		}
+	})
+
+	start := time.Now()
+	rec := serve(Timeout(20*time.Millisecond)(stuck), httptest.NewRequest(http.MethodGet, "/api/projects", nil))
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("request took %v", elapsed)
+	}
+	var e httperr.Error
+	if err := json.Unmarshal(rec.Body.Bytes(), &e); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusGatewayTimeout || e.Code != httperr.CodeTimeout {
+		t.Errorf("got %d %s, want 504 %s", rec.Code, e.Code, httperr.CodeTimeout)
+	}
+}
+
+func TestPathIDs(t *testing.T) {
+	router := mux.NewRouter()
+	router.Handle("/projects/{projectId}/assets/{assetId}/{name}", PathIDs(map[string]string{
+		"projectId": typeid.PrefixProject,
+		"assetId":   typeid.PrefixAsset,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+
+	projectID, assetID := typeid.NewProjectID(), typeid.NewAssetID()
+	tests := []struct {
+		name, path, param string // param is empty if the request passes
+	}{
+		{"valid", "/projects/" + projectID + "/assets/" + assetID + "/anything", ""},
+		{"legacy project", "/projects/playground/assets/" + assetID + "/x", "projectId"},
+		{"wrong prefix", "/projects/" + assetID + "/assets/" + assetID + "/x", "projectId"},
+		{"bad asset", "/projects/" + projectID + "/assets/logo/x", "assetId"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(router, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if tt.param == "" {
+				if rec.Code != http.StatusNoContent {
+					t.Errorf("got %d: %s", rec.Code, rec.Body)
+				}
+				return
+			}
+			var e httperr.Error
+			if err := json.Unmarshal(rec.Body.Bytes(), &e); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != http.StatusBadRequest || e.Code != httperr.CodeInvalidID {
+				t.Errorf("got %d %s, want 400 %s", rec.Code, e.Code, httperr.CodeInvalidID)
+			}
+			if details, _ := e.Details.(map[string]interface{}); details["param"] != tt.param {
+				t.Errorf("details %v, want param %s", e.Details, tt.param)
+			}
+		})
+	}
+}
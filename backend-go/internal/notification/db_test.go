@@ -0,0 +1,97 @@
+package notification
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/inamate/inamate/backend-go/internal/db/dbgen"
+)
+
+// query is a statement the service ran, by its sqlc name.
+type query struct {
+	name string
+	args []interface{}
+}
+
+// fakeDB records the statements run through it. Multi-row queries return the
+// rows set for their name, or none. Single-row queries return their
+// arguments as the row, which suits sqlc's INSERT ... RETURNING statements
+// whose columns start with the inserted values.
+type fakeDB struct {
+	queries []query
+	many    map[string][]echoRow
+}
+
+func newFakeService() (*Service, *fakeDB) {
+	db := &fakeDB{many: map[string][]echoRow{}}
+	return NewService(dbgen.New(db)), db
+}
+
+// ran returns the arguments of each run of the named query.
+func (db *fakeDB) ran(name string) [][]interface{} {
+	var runs [][]interface{}
+	for _, q := range db.queries {
+		if q.name == name {
+			runs = append(runs, q.args)
+		}
+	}
+	return runs
+}
+
+func (db *fakeDB) record(sql string, args []interface{}) string {
+	name, _, _ := strings.Cut(strings.TrimPrefix(sql, "-- name: "), " ")
+	db.queries = append(db.queries, query{name: name, args: args})
+	return name
+}
+
+func (db *fakeDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	db.record(sql, args)
+	return pgconn.NewCommandTag("UPDATE 1"), nil
+}
+
+func (db *fakeDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	name := db.record(sql, args)
+	return &fakeRows{rows: db.many[name]}, nil
+}
+
+func (db *fakeDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	db.record(sql, args)
+	return echoRow(args)
+}
+
+// echoRow scans its values into the leading destinations of matching type.
+type echoRow []interface{}
+
+func (row echoRow) Scan(dest ...interface{}) error {
+	for i, d := range dest {
+		if i >= len(row) {
+			break
+		}
+		v, target := reflect.ValueOf(row[i]), reflect.ValueOf(d).Elem()
+		if v.IsValid() && v.Type().AssignableTo(target.Type()) {
+			target.Set(v)
+		}
+	}
+	return nil
+}
+
+// fakeRows iterates echoRows. The pgx.Rows methods sqlc doesn't call are
+// left unimplemented.
+type fakeRows struct {
+	pgx.Rows
+	rows []echoRow
+	next int
+}
+
+func (r *fakeRows) Next() bool {
+	r.next++
+	return r.next <= len(r.rows)
+}
+
+func (r *fakeRows) Scan(dest ...interface{}) error { return r.rows[r.next-1].Scan(dest...) }
+func (r *fakeRows) Err() error                     { return nil }
+func (r *fakeRows) Close()                         {}
@@ -0,0 +1,129 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+
+	"github.com/inamate/inamate/backend-go/internal/collab"
+	"github.com/inamate/inamate/backend-go/internal/db/dbgen"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+const queueSize = 256
+
+type job struct {
+	projectID string
+	typ       string
+	actorID   string
+	userIDs   []string // nil for every member of the project
+	data      interface{}
+}
+
+// Run delivers enqueued notifications and blocks until ctx is cancelled.
+// Notifications are delivered one at a time, so each user's feed is in the
+// order they were enqueued.
+func (s *Service) Run(ctx context.Context) {
+	for {
+		select {
+		case j := <-s.queue:
+			s.deliver(ctx, j)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Notify enqueues a notification for the users given. It never blocks;
+// notifications are dropped if the queue is full.
+func (s *Service) Notify(projectID, typ, actorID string, data interface{}, userIDs ...string) {
+	if len(userIDs) == 0 {
+		return
+	}
+	s.enqueue(job{projectID: projectID, typ: typ, actorID: actorID, userIDs: userIDs, data: data})
+}
+
+// NotifyMembers enqueues a notification for every member of a project
+// except actorID, who caused it. It never blocks.
+func (s *Service) NotifyMembers(projectID, typ, actorID string, data interface{}) {
+	s.enqueue(job{projectID: projectID, typ: typ, actorID: actorID, data: data})
+}
+
+func (s *Service) enqueue(j job) {
+	if s == nil {
+		return
+	}
+	select {
+	case s.queue <- j:
+	default:
+		slog.Warn("notification queue full, dropping notification", "project", j.projectID, "type", j.typ)
+	}
+}
+
+// deliver fans a notification out to its recipients, skipping the actor and
+// anyone who muted the project or type.
+func (s *Service) deliver(ctx context.Context, j job) {
+	recipients := j.userIDs
+	if recipients == nil {
+		members, err := s.queries.ListProjectMembers(ctx, j.projectID)
+		if err != nil {
+			slog.Error("list notification recipients", "project", j.projectID, "error", err)
+			return
+		}
+		for _, m := range members {
+			recipients = append(recipients, m.UserID)
+		}
+	}
+	recipients = slices.DeleteFunc(slices.Clone(recipients), func(id string) bool { return id == j.actorID })
+	if len(recipients) == 0 {
+		return
+	}
+
+	dbPrefs, err := s.queries.ListNotificationPreferences(ctx, recipients)
+	if err != nil {
+		slog.Error("list notification preferences", "project", j.projectID, "error", err)
+		return
+	}
+	prefs := make(map[string]Preferences, len(dbPrefs))
+	for _, p := range dbPrefs {
+		prefs[p.UserID] = Preferences{MutedProjects: p.MutedProjects, MutedTypes: p.MutedTypes}
+	}
+
+	data, err := json.Marshal(j.data)
+	if err != nil {
+		slog.Error("marshal notification data", "type", j.typ, "error", err)
+		return
+	}
+
+	for _, userID := range recipients {
+		if prefs[userID].Mutes(j.projectID, j.typ) {
+			continue
+		}
+		dbNote, err := s.queries.CreateNotification(ctx, dbgen.CreateNotificationParams{
+			ID:        typeid.NewNotificationID(),
+			UserID:    userID,
+			ProjectID: j.projectID,
+			Type:      j.typ,
+			ActorID:   j.actorID,
+			Data:      data,
+		})
+		if err != nil {
+			slog.Error("create notification", "user", userID, "type", j.typ, "error", err)
+			continue
+		}
+		s.push(userID, dbNotificationToNotification(dbNote))
+	}
+}
+
+// push sends a new notification to the user's open connections, if any.
+func (s *Service) push(userID string, note Notification) {
+	if s.hub == nil {
+		return
+	}
+	payload, err := json.Marshal(note)
+	if err != nil {
+		return
+	}
+	s.hub.SendToUser(userID, &collab.Message{Type: collab.TypeNotificationNew, UserID: userID, Payload: payload})
+}
@@ -0,0 +1,139 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/inamate/inamate/backend-go/internal/collab"
+	"github.com/inamate/inamate/backend-go/internal/collab/collabtest"
+	"github.com/inamate/inamate/backend-go/internal/db/dbgen"
+	"github.com/inamate/inamate/backend-go/internal/document"
+)
+
+const projectID = "proj_01h455vb4pex5vsknk084sn02q"
+
+// member is a ListProjectMembers row.
+func member(userID string) echoRow {
+	return echoRow{projectID, userID, dbgen.ProjectRoleEditor}
+}
+
+// muted is a ListNotificationPreferences row.
+func muted(userID string, projects, types []string) echoRow {
+	return echoRow{userID, projects, types}
+}
+
+// notified returns who each CreateNotification was for, in order.
+func notified(db *fakeDB) []string {
+	var users []string
+	for _, args := range db.ran("CreateNotification") {
+		users = append(users, args[1].(string))
+	}
+	return users
+}
+
+func TestDeliverFansOutToMembers(t *testing.T) {
+	s, db := newFakeService()
+	db.many["ListProjectMembers"] = []echoRow{member("actor"), member("ann"), member("bob"), member("cat"), member("dan")}
+	db.many["ListNotificationPreferences"] = []echoRow{
+		muted("bob", []string{projectID}, []string{}),
+		muted("cat", []string{}, []string{TypeExportCompleted}),
+		muted("dan", []string{"proj_other"}, []string{TypeRemoved}),
+	}
+
+	s.deliver(context.Background(), job{projectID: projectID, typ: TypeExportCompleted, actorID: "actor", data: map[string]string{"jobId": "job_1"}})
+
+	// The actor isn't told of their own export, bob muted the project and
+	// cat the type
+	if got, want := notified(db), []string{"ann", "dan"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("notified %v, want %v", got, want)
+	}
+	prefs := db.ran("ListNotificationPreferences")
+	if len(prefs) != 1 || !reflect.DeepEqual(prefs[0][0], []string{"ann", "bob", "cat", "dan"}) {
+		t.Errorf("looked up preferences of %v, want every member but the actor", prefs)
+	}
+	for _, args := range db.ran("CreateNotification") {
+		if args[2] != projectID || args[3] != TypeExportCompleted || args[4] != "actor" || string(args[5].([]byte)) != `{"jobId":"job_1"}` {
+			t.Errorf("created %v", args)
+		}
+	}
+}
+
+func TestDeliverToNamedUsers(t *testing.T) {
+	s, db := newFakeService()
+	db.many["ListNotificationPreferences"] = []echoRow{muted("bob", []string{}, []string{TypeInvited})}
+
+	s.deliver(context.Background(), job{projectID: projectID, typ: TypeInvited, actorID: "actor", userIDs: []string{"ann", "bob", "actor"}})
+
+	if got, want := notified(db), []string{"ann"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("notified %v, want %v", got, want)
+	}
+	if len(db.ran("ListProjectMembers")) != 0 {
+		t.Error("named recipients looked up the project's members")
+	}
+
+	// Nobody left once the actor is dropped means no queries at all
+	db.queries = nil
+	s.deliver(context.Background(), job{projectID: projectID, typ: TypeInvited, actorID: "actor", userIDs: []string{"actor"}})
+	if len(db.queries) != 0 {
+		t.Errorf("ran %v for the actor alone", db.queries)
+	}
+}
+
+func TestNotifyNilAndFull(t *testing.T) {
+	var none *Service
+	none.Notify(projectID, TypeInvited, "actor", nil, "ann")
+	none.NotifyMembers(projectID, TypeInvited, "actor", nil)
+
+	s, _ := newFakeService()
+	s.Notify(projectID, TypeInvited, "actor", nil)
+	if len(s.queue) != 0 {
+		t.Error("a notification for nobody was queued")
+	}
+	for range queueSize + 10 {
+		s.NotifyMembers(projectID, TypeInvited, "actor", nil)
+	}
+	if len(s.queue) != queueSize {
+		t.Errorf("queued %d, want the queue full at %d without blocking", len(s.queue), queueSize)
+	}
+}
+
+func TestRunPushesToConnectedUsers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	store := collabtest.NewStore()
+	doc := document.NewSampleDocument(projectID)
+	if err := store.Put(projectID, doc); err != nil {
+		t.Fatal(err)
+	}
+	server := collabtest.NewServer(store)
+	defer server.Close()
+	client, err := server.Connect(ctx, projectID, "ann", "ann")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if _, _, err := client.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	s, _ := newFakeService()
+	s.SetHub(server.Hub)
+	go s.Run(ctx)
+	s.Notify(projectID, TypeInvited, "actor", map[string]string{"role": "editor"}, "ann")
+
+	msg, err := client.Expect(ctx, collab.TypeNotificationNew)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var note Notification
+	if err := json.Unmarshal(msg.Payload, &note); err != nil {
+		t.Fatal(err)
+	}
+	if note.ProjectID != projectID || note.Type != TypeInvited || note.ActorID != "actor" || string(note.Data) != `{"role":"editor"}` {
+		t.Errorf("pushed %+v", note)
+	}
+}
@@ -0,0 +1,111 @@
+package notification
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/inamate/inamate/backend-go/internal/auth"
+	"github.com/inamate/inamate/backend-go/internal/httperr"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromContext(r.Context())
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	offset, _ := strconv.Atoi(query.Get("offset"))
+	unreadOnly, _ := strconv.ParseBool(query.Get("unread"))
+
+	page, err := h.service.List(r.Context(), userID, unreadOnly, limit, offset)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+type markReadRequest struct {
+	IDs []string `json:"ids"`
+	All bool     `json:"all"`
+}
+
+func (h *Handler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromContext(r.Context())
+
+	var req markReadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	if len(req.IDs) == 0 && !req.All {
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed, "ids or all is required")
+		return
+	}
+	if len(req.IDs) > maxPageSize {
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed, "too many ids")
+		return
+	}
+
+	marked, unread, err := h.service.MarkRead(r.Context(), userID, req.IDs, req.All)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int64{"marked": marked, "unread": unread})
+}
+
+func (h *Handler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromContext(r.Context())
+
+	prefs, err := h.service.Preferences(r.Context(), userID)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, prefs)
+}
+
+func (h *Handler) SetPreferences(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromContext(r.Context())
+
+	var req Preferences
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	prefs, err := h.service.SetPreferences(r.Context(), userID, req)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, prefs)
+}
+
+var serviceErrors = []httperr.Mapping{
+	{Err: ErrInvalidType, Status: http.StatusBadRequest, Code: httperr.CodeValidationFailed},
+	{Err: ErrInvalidProject, Status: http.StatusBadRequest, Code: httperr.CodeValidationFailed},
+}
+
+func handleServiceError(w http.ResponseWriter, err error) {
+	httperr.FromError(w, err, serviceErrors)
+}
+
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
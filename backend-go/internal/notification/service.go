@@ -0,0 +1,212 @@
+// Package notification keeps each user's in-app notification feed. Other
+// services enqueue notifications as things happen to a project; they are
+// filtered by the recipient's preferences, stored, and pushed to any
+// WebSocket connection the recipient has open.
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/inamate/inamate/backend-go/internal/collab"
+	"github.com/inamate/inamate/backend-go/internal/db/dbgen"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// Notification types.
+const (
+	TypeInvited         = "member.invited"   // The recipient was added to a project
+	TypeRemoved         = "member.removed"   // The recipient was removed from a project
+	TypeExportCompleted = "export.completed" // An export of one of the recipient's projects finished
+)
+
+// Types lists every notification type a user can mute.
+var Types = []string{
+	TypeInvited,
+	TypeRemoved,
+	TypeExportCompleted,
+}
+
+var (
+	ErrInvalidType    = errors.New("unknown notification type")
+	ErrInvalidProject = errors.New("invalid project id")
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// Service stores notifications and delivers them. A nil *Service is valid
+// and drops everything enqueued on it.
+type Service struct {
+	queries *dbgen.Queries
+	hub     *collab.Hub
+	queue   chan job
+}
+
+func NewService(queries *dbgen.Queries) *Service {
+	return &Service{queries: queries, queue: make(chan job, queueSize)}
+}
+
+// SetHub attaches the collaboration hub that new notifications are pushed
+// through. The hub is created after the service, so it's set separately.
+func (s *Service) SetHub(hub *collab.Hub) {
+	s.hub = hub
+}
+
+type Notification struct {
+	ID        string          `json:"id"`
+	ProjectID string          `json:"projectId"`
+	Type      string          `json:"type"`
+	ActorID   string          `json:"actorId,omitempty"` // Who caused it, if anyone
+	Data      json.RawMessage `json:"data"`
+	ReadAt    string          `json:"readAt,omitempty"`
+	CreatedAt string          `json:"createdAt"`
+}
+
+// Page is a page of a user's feed, newest first, with their unread count.
+type Page struct {
+	Notifications []Notification `json:"notifications"`
+	Unread        int64          `json:"unread"`
+}
+
+// Preferences are the projects and types a user doesn't want to be notified
+// about.
+type Preferences struct {
+	MutedProjects []string `json:"mutedProjects"`
+	MutedTypes    []string `json:"mutedTypes"`
+}
+
+// Mutes reports whether the preferences filter out a notification.
+func (p Preferences) Mutes(projectID, typ string) bool {
+	return slices.Contains(p.MutedProjects, projectID) || slices.Contains(p.MutedTypes, typ)
+}
+
+// List returns a page of a user's notifications, only the unread ones when
+// unreadOnly is set.
+func (s *Service) List(ctx context.Context, userID string, unreadOnly bool, limit, offset int) (*Page, error) {
+	if offset < 0 {
+		offset = 0
+	}
+	dbNotes, err := s.queries.ListNotifications(ctx, dbgen.ListNotificationsParams{
+		UserID:     userID,
+		UnreadOnly: unreadOnly,
+		MaxResults: int32(clampPageSize(limit)),
+		Skip:       int32(offset),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list notifications: %w", err)
+	}
+	unread, err := s.queries.CountUnreadNotifications(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("count unread notifications: %w", err)
+	}
+
+	page := &Page{Notifications: make([]Notification, len(dbNotes)), Unread: unread}
+	for i, n := range dbNotes {
+		page.Notifications[i] = dbNotificationToNotification(n)
+	}
+	return page, nil
+}
+
+// MarkRead marks a user's notifications read: those in ids, or all of them
+// when all is set. It returns how many were newly marked and how many remain
+// unread. IDs of other users' notifications are ignored.
+func (s *Service) MarkRead(ctx context.Context, userID string, ids []string, all bool) (marked, unread int64, err error) {
+	if all {
+		marked, err = s.queries.MarkAllNotificationsRead(ctx, userID)
+	} else {
+		marked, err = s.queries.MarkNotificationsRead(ctx, dbgen.MarkNotificationsReadParams{
+			UserID: userID,
+			Ids:    ids,
+		})
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("mark notifications read: %w", err)
+	}
+	unread, err = s.queries.CountUnreadNotifications(ctx, userID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("count unread notifications: %w", err)
+	}
+	return marked, unread, nil
+}
+
+// Preferences returns a user's notification preferences. Users who never set
+// any are notified of everything.
+func (s *Service) Preferences(ctx context.Context, userID string) (*Preferences, error) {
+	prefs, err := s.queries.GetNotificationPreferences(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return &Preferences{MutedProjects: []string{}, MutedTypes: []string{}}, nil
+		}
+		return nil, fmt.Errorf("get notification preferences: %w", err)
+	}
+	return &Preferences{MutedProjects: prefs.MutedProjects, MutedTypes: prefs.MutedTypes}, nil
+}
+
+// SetPreferences replaces a user's notification preferences. They apply to
+// notifications enqueued from then on; the feed is left as it is.
+func (s *Service) SetPreferences(ctx context.Context, userID string, prefs Preferences) (*Preferences, error) {
+	for _, id := range prefs.MutedProjects {
+		if err := typeid.Validate(id, typeid.PrefixProject); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidProject, id)
+		}
+	}
+	for _, typ := range prefs.MutedTypes {
+		if !slices.Contains(Types, typ) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidType, typ)
+		}
+	}
+
+	saved, err := s.queries.UpsertNotificationPreferences(ctx, dbgen.UpsertNotificationPreferencesParams{
+		UserID:        userID,
+		MutedProjects: dedupe(prefs.MutedProjects),
+		MutedTypes:    dedupe(prefs.MutedTypes),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("save notification preferences: %w", err)
+	}
+	return &Preferences{MutedProjects: saved.MutedProjects, MutedTypes: saved.MutedTypes}, nil
+}
+
+// dedupe returns the distinct values sorted, never nil.
+func dedupe(values []string) []string {
+	out := slices.Clone(values)
+	slices.Sort(out)
+	out = slices.Compact(out)
+	if out == nil {
+		out = []string{}
+	}
+	return out
+}
+
+func clampPageSize(limit int) int {
+	if limit <= 0 {
+		return defaultPageSize
+	}
+	if limit > maxPageSize {
+		return maxPageSize
+	}
+	return limit
+}
+
+func dbNotificationToNotification(n dbgen.Notification) Notification {
+	note := Notification{
+		ID:        n.ID,
+		ProjectID: n.ProjectID,
+		Type:      n.Type,
+		ActorID:   n.ActorID,
+		Data:      n.Data,
+		CreatedAt: n.CreatedAt.Time.Format("2006-01-02T15:04:05Z"),
+	}
+	if n.ReadAt.Valid {
+		note.ReadAt = n.ReadAt.Time.Format("2006-01-02T15:04:05Z")
+	}
+	return note
+}
@@ -0,0 +1,54 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPreferencesMutes(t *testing.T) {
+	prefs := Preferences{MutedProjects: []string{projectID}, MutedTypes: []string{TypeRemoved}}
+	tests := []struct {
+		projectID, typ string
+		want           bool
+	}{
+		{projectID, TypeInvited, true},
+		{"proj_other", TypeRemoved, true},
+		{"proj_other", TypeInvited, false},
+	}
+	for _, tt := range tests {
+		if got := prefs.Mutes(tt.projectID, tt.typ); got != tt.want {
+			t.Errorf("Mutes(%s, %s) = %v, want %v", tt.projectID, tt.typ, got, tt.want)
+		}
+	}
+	if (Preferences{}).Mutes(projectID, TypeInvited) {
+		t.Error("no preferences muted a notification")
+	}
+}
+
+func TestSetPreferences(t *testing.T) {
+	s, db := newFakeService()
+	for name, prefs := range map[string]Preferences{
+		"unknown type":    {MutedTypes: []string{"comment.added"}},
+		"invalid project": {MutedProjects: []string{"scene_1"}},
+	} {
+		if _, err := s.SetPreferences(context.Background(), "ann", prefs); !errors.Is(err, ErrInvalidType) && !errors.Is(err, ErrInvalidProject) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	if len(db.queries) != 0 {
+		t.Fatalf("rejected preferences ran %v", db.queries)
+	}
+
+	saved, err := s.SetPreferences(context.Background(), "ann", Preferences{
+		MutedTypes: []string{TypeRemoved, TypeInvited, TypeRemoved},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Preferences{MutedProjects: []string{}, MutedTypes: []string{TypeInvited, TypeRemoved}}
+	if !reflect.DeepEqual(*saved, want) {
+		t.Errorf("saved %+v, want %+v deduplicated", *saved, want)
+	}
+}
@@ -0,0 +1,102 @@
+// Package oplog persists the operations collaboration rooms apply between
+// snapshots, so a restart loses none of them.
+package oplog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/inamate/inamate/backend-go/internal/collab"
+	"github.com/inamate/inamate/backend-go/internal/db/dbgen"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// Store keeps each project's op log in the project_ops table, keyed by
+// server sequence. It implements collab.OpStore.
+type Store struct {
+	queries *dbgen.Queries
+}
+
+func NewStore(queries *dbgen.Queries) *Store {
+	return &Store{queries: queries}
+}
+
+// Append logs ops in one statement. Sequences already logged are skipped, so
+// a batch can safely be written twice.
+func (s *Store) Append(ctx context.Context, projectID string, ops []collab.RecordedOperation) error {
+	if len(ops) == 0 {
+		return nil
+	}
+	params := dbgen.AppendProjectOpsParams{
+		ProjectID: projectID,
+		Ids:       make([]string, len(ops)),
+		UserIds:   make([]string, len(ops)),
+		Seqs:      make([]int64, len(ops)),
+		OpTypes:   make([]string, len(ops)),
+		Payloads:  make([]string, len(ops)),
+	}
+	for i, rec := range ops {
+		payload, err := json.Marshal(rec.Operation)
+		if err != nil {
+			return fmt.Errorf("encode op %d: %w", rec.Seq, err)
+		}
+		params.Ids[i] = typeid.NewOpID()
+		params.UserIds[i] = rec.UserID
+		params.Seqs[i] = rec.Seq
+		params.OpTypes[i] = rec.Operation.Type
+		params.Payloads[i] = string(payload)
+	}
+	if err := s.queries.AppendProjectOps(ctx, params); err != nil {
+		return fmt.Errorf("append ops: %w", err)
+	}
+	return nil
+}
+
+// Since returns the project's logged ops with a sequence after seq, in order.
+// Their timestamps are when they were logged, which trails when they were
+// applied by at most a flush interval.
+func (s *Store) Since(ctx context.Context, projectID string, seq int64) ([]collab.RecordedOperation, error) {
+	rows, err := s.queries.ListProjectOpsAfter(ctx, dbgen.ListProjectOpsAfterParams{
+		ProjectID: projectID,
+		Seq:       seq,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list ops: %w", err)
+	}
+	ops := make([]collab.RecordedOperation, len(rows))
+	for i, row := range rows {
+		if err := json.Unmarshal(row.Payload, &ops[i].Operation); err != nil {
+			return nil, fmt.Errorf("decode op %d: %w", row.Seq, err)
+		}
+		ops[i].Seq = row.Seq
+		ops[i].UserID = row.UserID
+		ops[i].Timestamp = row.CreatedAt.Time.UnixMilli()
+	}
+	return ops, nil
+}
+
+// Compact deletes the ops a snapshot saved at throughSeq already covers.
+func (s *Store) Compact(ctx context.Context, projectID string, throughSeq int64) error {
+	_, err := s.queries.DeleteProjectOpsThrough(ctx, dbgen.DeleteProjectOpsThroughParams{
+		ProjectID: projectID,
+		Seq:       throughSeq,
+	})
+	if err != nil {
+		return fmt.Errorf("compact ops: %w", err)
+	}
+	return nil
+}
+
+// Discard deletes the ops after afterSeq, which could not be replayed and
+// would otherwise hold sequences the room is about to reuse.
+func (s *Store) Discard(ctx context.Context, projectID string, afterSeq int64) error {
+	_, err := s.queries.DeleteProjectOpsAfter(ctx, dbgen.DeleteProjectOpsAfterParams{
+		ProjectID: projectID,
+		Seq:       afterSeq,
+	})
+	if err != nil {
+		return fmt.Errorf("discard ops: %w", err)
+	}
+	return nil
+}
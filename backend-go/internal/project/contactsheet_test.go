@@ -0,0 +1,138 @@
+package project
+
+import (
+	"context"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/inamate/inamate/backend-go/internal/auth"
+	"github.com/inamate/inamate/backend-go/internal/httperr"
+	"github.com/inamate/inamate/backend-go/internal/rendercache"
+)
+
+// getContactSheet requests the project's contact sheet as user_1.
+func getContactSheet(h *Handler, projectID, query string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/api/projects/"+projectID+"/contactsheet.png?"+query, nil)
+	r = mux.SetURLVars(r, map[string]string{"projectId": projectID})
+	r = r.WithContext(context.WithValue(r.Context(), auth.UserIDKey, "user_1"))
+	rec := httptest.NewRecorder()
+	h.ContactSheet(rec, r)
+	return rec
+}
+
+func TestContactSheet(t *testing.T) {
+	p := newOpsProject()
+	service, db := newFakeService()
+	cache, err := rendercache.New(16<<20, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.SetRenderer(cache, nil)
+	db.rows = map[string][]pgx.Row{"GetLatestSnapshot": {snapshotRow(t, p.doc, 1)}}
+	h := NewHandler(service)
+
+	rec := getContactSheet(h, p.id, "cols=5&frames=20&width=240")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "image/png" {
+		t.Errorf("content type %q", ct)
+	}
+	img, err := png.Decode(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 20 tiles of 240x135 in 5 columns, 4px apart and around
+	if b := img.Bounds(); b.Dx() != 5*244+4 || b.Dy() != 4*139+4 {
+		t.Errorf("sheet %dx%d, want %dx%d", b.Dx(), b.Dy(), 5*244+4, 4*139+4)
+	}
+	if s := cache.Stats(); s.Renders != 20 {
+		t.Errorf("rendered %d frames, want 20", s.Renders)
+	}
+
+	if rec := getContactSheet(h, p.id, "scene=scene_missing"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown scene: status %d, want 404", rec.Code)
+	}
+}
+
+func TestContactSheetValidatesQuery(t *testing.T) {
+	h := NewHandler(nil)
+	for _, query := range []string{"cols=0", "cols=21", "frames=x", "frames=201", "width=-1", "width=1025"} {
+		t.Run(query, func(t *testing.T) {
+			rec := getContactSheet(h, "proj_x", query)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status %d, want 400", rec.Code)
+			}
+			if e := decodeError(t, rec); e.Code != httperr.CodeValidationFailed {
+				t.Errorf("code %q, want %s", e.Code, httperr.CodeValidationFailed)
+			}
+		})
+	}
+}
+
+// getFrame requests a frame of the project rendered as PNG, as user_1.
+func getFrame(h *Handler, projectID, frame, query string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/api/projects/"+projectID+"/frames/"+frame+".png?"+query, nil)
+	r = mux.SetURLVars(r, map[string]string{"projectId": projectID, "frame": frame})
+	r = r.WithContext(context.WithValue(r.Context(), auth.UserIDKey, "user_1"))
+	rec := httptest.NewRecorder()
+	h.RenderFrame(rec, r)
+	return rec
+}
+
+func TestRenderFrame(t *testing.T) {
+	p := newOpsProject()
+	service, db := newFakeService()
+	db.rows = map[string][]pgx.Row{"GetLatestSnapshot": {snapshotRow(t, p.doc, 1)}}
+	h := NewHandler(service)
+
+	rec := getFrame(h, p.id, "0", "width=320")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "image/png" {
+		t.Errorf("content type %q", ct)
+	}
+	img, err := png.Decode(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 320 || b.Dy() != 180 {
+		t.Errorf("frame %dx%d, want 320x180", b.Dx(), b.Dy())
+	}
+
+	// Without a width the frame is the scene's size
+	if img, err := png.Decode(getFrame(h, p.id, "3", "").Body); err != nil || img.Bounds().Dx() != 1280 {
+		t.Errorf("default width frame: %v", err)
+	}
+	if rec := getFrame(h, p.id, "0", "scene=scene_missing"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown scene: status %d, want 404", rec.Code)
+	}
+
+	// A tall scene at the widest width is more pixels than a frame may have
+	scene := p.doc.Scenes[p.doc.Project.Scenes[0]]
+	scene.Height = 4096
+	p.doc.Scenes[scene.ID] = scene
+	db.rows["GetLatestSnapshot"] = []pgx.Row{snapshotRow(t, p.doc, 2)}
+	if rec := getFrame(h, p.id, "0", "width=4096"); rec.Code != http.StatusBadRequest {
+		t.Errorf("frame over the pixel limit: status %d, want 400", rec.Code)
+	}
+}
+
+func TestRenderFrameValidates(t *testing.T) {
+	h := NewHandler(nil)
+	for _, tt := range []struct{ frame, query string }{{"-1", ""}, {"x", ""}, {"0", "width=0"}, {"0", "width=4097"}, {"0", "width=x"}} {
+		rec := getFrame(h, "proj_x", tt.frame, tt.query)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("frame %s %s: status %d, want 400", tt.frame, tt.query, rec.Code)
+		}
+		if e := decodeError(t, rec); e.Code != httperr.CodeValidationFailed {
+			t.Errorf("frame %s %s: code %q, want %s", tt.frame, tt.query, e.Code, httperr.CodeValidationFailed)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/inamate/inamate/backend-go/internal/db/dbgen"
+)
+
+// query is a statement the service ran, by its sqlc name.
+type query struct {
+	name string
+	args []interface{}
+}
+
+// fakeDB records the statements run through it. Single-row queries return
+// the rows queued for their name in turn, the last repeating, or else their
+// arguments as the row, which suits sqlc's INSERT ... RETURNING statements
+// whose columns start with the inserted values. A nil row in a queue echoes
+// the arguments too. Multi-row queries return the rows in many for their
+// name, and fail without an entry.
+type fakeDB struct {
+	queries []query
+	rows    map[string][]pgx.Row
+	many    map[string][]echoRow
+}
+
+func newFakeService() (*Service, *fakeDB) {
+	db := &fakeDB{}
+	return NewService(dbgen.New(db), nil), db
+}
+
+// ran returns the arguments of each run of the named query.
+func (db *fakeDB) ran(name string) [][]interface{} {
+	var runs [][]interface{}
+	for _, q := range db.queries {
+		if q.name == name {
+			runs = append(runs, q.args)
+		}
+	}
+	return runs
+}
+
+func (db *fakeDB) record(sql string, args []interface{}) {
+	name, _, _ := strings.Cut(strings.TrimPrefix(sql, "-- name: "), " ")
+	db.queries = append(db.queries, query{name: name, args: args})
+}
+
+func (db *fakeDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	db.record(sql, args)
+	return pgconn.NewCommandTag("INSERT 0 1"), nil
+}
+
+func (db *fakeDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	db.record(sql, args)
+	rows, ok := db.many[db.queries[len(db.queries)-1].name]
+	if !ok {
+		return nil, errors.New("fakeDB: no rows for multi-row query")
+	}
+	return &fakeRows{rows: rows}, nil
+}
+
+func (db *fakeDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	db.record(sql, args)
+	name := db.queries[len(db.queries)-1].name
+	if queue := db.rows[name]; len(queue) > 0 {
+		row := queue[0]
+		if len(queue) > 1 {
+			db.rows[name] = queue[1:]
+		}
+		if row != nil {
+			return row
+		}
+	}
+	return echoRow(args)
+}
+
+// echoRow scans its values into the leading destinations of matching type.
+type echoRow []interface{}
+
+func (row echoRow) Scan(dest ...interface{}) error {
+	for i, d := range dest {
+		if i >= len(row) {
+			break
+		}
+		v, target := reflect.ValueOf(row[i]), reflect.ValueOf(d).Elem()
+		if v.IsValid() && v.Type().AssignableTo(target.Type()) {
+			target.Set(v)
+		}
+	}
+	return nil
+}
+
+// fakeRows iterates echoRows. The pgx.Rows methods sqlc doesn't call are
+// left unimplemented.
+type fakeRows struct {
+	pgx.Rows
+	rows []echoRow
+	next int
+}
+
+func (r *fakeRows) Next() bool {
+	r.next++
+	return r.next <= len(r.rows)
+}
+
+func (r *fakeRows) Scan(dest ...interface{}) error { return r.rows[r.next-1].Scan(dest...) }
+func (r *fakeRows) Err() error                     { return nil }
+func (r *fakeRows) Close()                         {}
+
+// errRow is a row that fails to scan with its error, e.g. pgx.ErrNoRows.
+type errRow struct{ err error }
+
+func (row errRow) Scan(dest ...interface{}) error { return row.err }
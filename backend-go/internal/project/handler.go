@@ -3,13 +3,27 @@ package project
 import (
 	"encoding/json"
 	"errors"
-	"log/slog"
+	"image/png"
+	"io"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/gorilla/mux"
+	"github.com/inamate/inamate/backend-go/internal/asset"
 	"github.com/inamate/inamate/backend-go/internal/auth"
+	"github.com/inamate/inamate/backend-go/internal/collab"
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/httperr"
+	"github.com/inamate/inamate/backend-go/internal/import/lottie"
+	"github.com/inamate/inamate/backend-go/internal/raster"
 )
 
+// maxImportSize bounds an imported document, which may embed its images.
+const maxImportSize = 50 << 20
+
 type Handler struct {
 	service *Service
 }
@@ -20,10 +34,40 @@ func NewHandler(service *Service) *Handler {
 
 type createRequest struct {
 	Name string `json:"name"`
+
+	// Optional overrides of the configured scene defaults
+	Width      *int    `json:"width"`
+	Height     *int    `json:"height"`
+	Background *string `json:"background"`
+	FPS        *int    `json:"fps"`
+}
+
+// sceneSettings applies the request's overrides to defaults.
+func (req createRequest) sceneSettings(defaults document.SceneSettings) document.SceneSettings {
+	settings := defaults
+	if req.Width != nil {
+		settings.Width = *req.Width
+	}
+	if req.Height != nil {
+		settings.Height = *req.Height
+	}
+	if req.Background != nil {
+		settings.Background = strings.TrimSpace(*req.Background)
+	}
+	if req.FPS != nil {
+		settings.FPS = *req.FPS
+	}
+	return settings
+}
+
+type fromPlaygroundRequest struct {
+	Name     string               `json:"name"`     // Defaults to the document's name
+	Document *document.InDocument `json:"document"` // Defaults to the shared playground's
 }
 
 type inviteRequest struct {
 	Email string `json:"email"`
+	Role  string `json:"role"` // "editor" (the default) or "viewer"
 }
 
 func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
@@ -31,25 +75,109 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 
 	var req createRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeInvalidBody, "invalid request body")
 		return
 	}
 
 	if req.Name == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "name is required"})
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed, "name is required")
+		return
+	}
+
+	settings := req.sceneSettings(h.service.SceneDefaults())
+	if err := settings.Validate(); err != nil {
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed, err.Error())
+		return
+	}
+
+	project, err := h.service.Create(r.Context(), req.Name, userID, settings)
+	if err != nil {
+		httperr.Internal(w, "create project failed", err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, project)
+}
+
+// CreateFromPlayground handles POST /projects/from-playground: it copies a
+// playground document into a new project owned by the caller, so work done
+// anonymously survives signing up.
+func (h *Handler) CreateFromPlayground(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromContext(r.Context())
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	var req fromPlaygroundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var mbe *http.MaxBytesError
+		if errors.As(err, &mbe) {
+			httperr.Write(w, http.StatusRequestEntityTooLarge, httperr.CodePayloadTooLarge, "document too large (max 50MB)")
+			return
+		}
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeInvalidBody, "invalid request body")
 		return
 	}
 
-	project, err := h.service.Create(r.Context(), req.Name, userID)
+	project, err := h.service.CreateFromPlayground(r.Context(), strings.TrimSpace(req.Name), userID, req.Document)
 	if err != nil {
-		slog.Error("create project failed", "error", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		handleServiceError(w, err)
 		return
 	}
 
 	writeJSON(w, http.StatusCreated, project)
 }
 
+// importResponse is returned from a Lottie import. Images lists the image
+// assets the new document refers to, which the client uploads under their
+// asset IDs.
+type importResponse struct {
+	Project  *Project       `json:"project"`
+	Images   []lottie.Image `json:"images"`
+	Warnings []string       `json:"warnings"`
+}
+
+// ImportLottie handles POST /projects/import/lottie: the body is Lottie JSON,
+// converted into the document of a new project. The optional name query
+// param overrides the animation's name. Unsupported features are skipped and
+// listed in the response's warnings.
+func (h *Handler) ImportLottie(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromContext(r.Context())
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	data, err := io.ReadAll(r.Body)
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		httperr.Write(w, http.StatusRequestEntityTooLarge, httperr.CodePayloadTooLarge, "file too large (max 50MB)")
+		return
+	}
+	if err != nil {
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	result, err := lottie.Convert(data, h.service.SceneDefaults().Background)
+	if err != nil {
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed, err.Error())
+		return
+	}
+
+	name := strings.TrimSpace(r.URL.Query().Get("name"))
+	if name == "" {
+		name = result.Document.Project.Name
+	}
+
+	project, err := h.service.CreateFromDocument(r.Context(), name, userID, result.Document)
+	if err != nil {
+		httperr.Internal(w, "import project failed", err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, importResponse{
+		Project:  project,
+		Images:   result.Images,
+		Warnings: result.Warnings,
+	})
+}
+
 func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 	userID := auth.UserIDFromContext(r.Context())
 	projectID := mux.Vars(r)["projectId"]
@@ -68,8 +196,7 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 
 	projects, err := h.service.List(r.Context(), userID)
 	if err != nil {
-		slog.Error("list projects failed", "error", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		httperr.Internal(w, "list projects failed", err)
 		return
 	}
 
@@ -95,16 +222,16 @@ func (h *Handler) Invite(w http.ResponseWriter, r *http.Request) {
 
 	var req inviteRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeInvalidBody, "invalid request body")
 		return
 	}
 
 	if req.Email == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "email is required"})
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed, "email is required")
 		return
 	}
 
-	err := h.service.InviteByEmail(r.Context(), projectID, userID, req.Email)
+	err := h.service.InviteByEmail(r.Context(), projectID, userID, req.Email, req.Role)
 	if err != nil {
 		handleServiceError(w, err)
 		return
@@ -155,18 +282,420 @@ func (h *Handler) GetLatestSnapshot(w http.ResponseWriter, r *http.Request) {
 	w.Write(doc)
 }
 
-func handleServiceError(w http.ResponseWriter, err error) {
-	switch {
-	case errors.Is(err, ErrNotFound):
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
-	case errors.Is(err, ErrForbidden):
-		writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
-	case errors.Is(err, ErrNotMember):
-		writeJSON(w, http.StatusForbidden, map[string]string{"error": "not a project member"})
-	default:
-		slog.Error("service error", "error", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+// Snapshot listing bounds and defaults
+const (
+	defaultSnapshotLimit = 50
+	maxSnapshotLimit     = 500
+)
+
+// ListSnapshots handles GET /projects/{projectId}/snapshots: the project's
+// saved versions, newest first, without their documents. Safety snapshots,
+// taken before destructive operations, are flagged. limit caps how many are
+// returned and offset skips that many of the newest, to page back through
+// the history.
+func (h *Handler) ListSnapshots(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromContext(r.Context())
+	projectID := mux.Vars(r)["projectId"]
+	query := r.URL.Query()
+
+	limit := defaultSnapshotLimit
+	if raw := query.Get("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 || v > maxSnapshotLimit {
+			httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed,
+				"limit must be an integer from 1 to "+strconv.Itoa(maxSnapshotLimit))
+			return
+		}
+		limit = v
 	}
+	offset := 0
+	if raw := query.Get("offset"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 || v > math.MaxInt32 {
+			httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed,
+				"offset must be a non-negative integer")
+			return
+		}
+		offset = v
+	}
+
+	snapshots, err := h.service.ListSnapshots(r.Context(), projectID, userID, limit, offset)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, snapshots)
+}
+
+// GetSnapshot handles GET /projects/{projectId}/snapshots/{version}: the
+// document saved as that version. Versions the project never had are 404s.
+func (h *Handler) GetSnapshot(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromContext(r.Context())
+	vars := mux.Vars(r)
+
+	version, err := strconv.Atoi(vars["version"])
+	if err != nil {
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed,
+			"version must be an integer")
+		return
+	}
+
+	doc, err := h.service.GetSnapshot(r.Context(), vars["projectId"], userID, version)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(doc)
+}
+
+// GetRecording returns the live session's operations, with timestamps and
+// authors, for playback. Query params: fromSeq and toSeq bound the range
+// (inclusive; omitted means from the start / through the latest), and
+// raw=true disables transform downsampling.
+func (h *Handler) GetRecording(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromContext(r.Context())
+	projectID := mux.Vars(r)["projectId"]
+	query := r.URL.Query()
+
+	var seqs [2]int64
+	for i, name := range []string{"fromSeq", "toSeq"} {
+		raw := query.Get(name)
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || v < 0 {
+			httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed, name+" must be a non-negative integer")
+			return
+		}
+		seqs[i] = v
+	}
+	if seqs[1] > 0 && seqs[0] > seqs[1] {
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed, "fromSeq must not exceed toSeq")
+		return
+	}
+
+	rec, err := h.service.Recording(r.Context(), projectID, userID, seqs[0], seqs[1], query.Get("raw") != "true")
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rec)
+}
+
+// maxValidateOps bounds the operations in one validate or apply request.
+const maxValidateOps = 1000
+
+type validateOpsRequest struct {
+	Document   *document.InDocument `json:"document"` // Defaults to the project's current document
+	Operations []collab.Operation   `json:"operations"`
+}
+
+type validateOpsResponse struct {
+	Valid   bool                  `json:"valid"` // Every operation would be acknowledged
+	Results []collab.DryRunResult `json:"results"`
+}
+
+// ValidateOperations handles POST /projects/{projectId}/operations/validate:
+// it trial-applies the operations in order to a copy of the project's
+// document (or an inline one) and returns each operation's result, without
+// saving or broadcasting anything. Importers and scripts use it to check
+// operations before submitting them.
+func (h *Handler) ValidateOperations(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromContext(r.Context())
+	projectID := mux.Vars(r)["projectId"]
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	var req validateOpsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var mbe *http.MaxBytesError
+		if errors.As(err, &mbe) {
+			httperr.Write(w, http.StatusRequestEntityTooLarge, httperr.CodePayloadTooLarge, "request too large (max 50MB)")
+			return
+		}
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeInvalidBody, "invalid request body")
+		return
+	}
+	if len(req.Operations) == 0 {
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed, "operations are required")
+		return
+	}
+	if len(req.Operations) > maxValidateOps {
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed, "at most "+strconv.Itoa(maxValidateOps)+" operations per request")
+		return
+	}
+
+	results, err := h.service.ValidateOperations(r.Context(), projectID, userID, req.Document, req.Operations)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	resp := validateOpsResponse{Valid: true, Results: results}
+	for _, res := range results {
+		if !res.OK {
+			resp.Valid = false
+			break
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// applyOpsRequest is a batch of operations to apply. A request body may
+// instead be a single operation.
+type applyOpsRequest struct {
+	Operations []collab.Operation `json:"operations"`
+}
+
+type applyOpsResponse struct {
+	Applied bool              `json:"applied"` // Every operation was acknowledged
+	Results []collab.OpResult `json:"results"`
+}
+
+// ApplyOperations handles POST /projects/{projectId}/operations: it applies
+// one operation, or a batch in order, for callers without a WebSocket, such
+// as scripts. Each result holds the op.ack or op.nack payload a session
+// client would have received. A rejected operation doesn't stop later ones.
+func (h *Handler) ApplyOperations(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromContext(r.Context())
+	projectID := mux.Vars(r)["projectId"]
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	var body json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		var mbe *http.MaxBytesError
+		if errors.As(err, &mbe) {
+			httperr.Write(w, http.StatusRequestEntityTooLarge, httperr.CodePayloadTooLarge, "request too large (max 50MB)")
+			return
+		}
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeInvalidBody, "invalid request body")
+		return
+	}
+	var req applyOpsRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeInvalidBody, "invalid request body")
+		return
+	}
+	if req.Operations == nil {
+		var op collab.Operation
+		if err := json.Unmarshal(body, &op); err == nil && op.Type != "" {
+			req.Operations = []collab.Operation{op}
+		}
+	}
+	if len(req.Operations) == 0 {
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed, "an operation or operations are required")
+		return
+	}
+	if len(req.Operations) > maxValidateOps {
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed, "at most "+strconv.Itoa(maxValidateOps)+" operations per request")
+		return
+	}
+
+	results, err := h.service.ApplyOperations(r.Context(), projectID, userID, req.Operations)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	resp := applyOpsResponse{Applied: true, Results: results}
+	for _, res := range results {
+		if res.Nack != nil {
+			resp.Applied = false
+			break
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Contact sheet bounds and defaults
+const (
+	defaultSheetColumns = 5
+	defaultSheetFrames  = 20
+	defaultSheetWidth   = 240
+	maxSheetColumns     = 20
+	maxSheetFrames      = 200
+	maxSheetTileWidth   = 1024
+)
+
+// ContactSheet handles GET /projects/{projectId}/contactsheet.png: a PNG grid
+// of thumbnails sampled evenly across the timeline, each labeled with its
+// frame number. Query parameters: cols, frames, width (of each tile) and
+// scene (defaults to the first).
+func (h *Handler) ContactSheet(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromContext(r.Context())
+	projectID := mux.Vars(r)["projectId"]
+	query := r.URL.Query()
+
+	opts := raster.SheetOptions{
+		SceneID:   query.Get("scene"),
+		Columns:   defaultSheetColumns,
+		Frames:    defaultSheetFrames,
+		TileWidth: defaultSheetWidth,
+	}
+	for _, param := range []struct {
+		name  string
+		value *int
+		max   int
+	}{
+		{"cols", &opts.Columns, maxSheetColumns},
+		{"frames", &opts.Frames, maxSheetFrames},
+		{"width", &opts.TileWidth, maxSheetTileWidth},
+	} {
+		raw := query.Get(param.name)
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 || v > param.max {
+			httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed,
+				param.name+" must be an integer from 1 to "+strconv.Itoa(param.max))
+			return
+		}
+		*param.value = v
+	}
+
+	sheet, err := h.service.ContactSheet(r.Context(), projectID, userID, opts)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.WriteHeader(http.StatusOK)
+	png.Encode(w, sheet.Image)
+}
+
+// maxFrameWidth bounds the width of a single rendered frame.
+const maxFrameWidth = 4096
+
+// RenderFrame handles GET /projects/{projectId}/frames/{frame}.png: one frame
+// rendered server-side over the scene's background, for thumbnails and share
+// previews. Query parameters: scene (defaults to the first) and width
+// (defaults to the scene's).
+func (h *Handler) RenderFrame(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromContext(r.Context())
+	vars := mux.Vars(r)
+	query := r.URL.Query()
+
+	frame, err := strconv.Atoi(vars["frame"])
+	if err != nil || frame < 0 {
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed,
+			"frame must be a non-negative integer")
+		return
+	}
+	width := 0
+	if raw := query.Get("width"); raw != "" {
+		width, err = strconv.Atoi(raw)
+		if err != nil || width < 1 || width > maxFrameWidth {
+			httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed,
+				"width must be an integer from 1 to "+strconv.Itoa(maxFrameWidth))
+			return
+		}
+	}
+
+	data, err := h.service.RenderFrame(r.Context(), vars["projectId"], userID, query.Get("scene"), frame, width)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// Search bounds and defaults
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 200
+	maxSearchQuery     = 200
+)
+
+// Search handles GET /projects/{projectId}/search?q=: a case-insensitive
+// search of the project's scene names, text content and object meta, with a
+// snippet for each match. limit caps the matches returned.
+func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromContext(r.Context())
+	projectID := mux.Vars(r)["projectId"]
+	query := r.URL.Query()
+
+	q := strings.TrimSpace(query.Get("q"))
+	if q == "" || utf8.RuneCountInString(q) > maxSearchQuery {
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed,
+			"q must be 1 to "+strconv.Itoa(maxSearchQuery)+" characters")
+		return
+	}
+	limit := defaultSearchLimit
+	if raw := query.Get("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 || v > maxSearchLimit {
+			httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed,
+				"limit must be an integer from 1 to "+strconv.Itoa(maxSearchLimit))
+			return
+		}
+		limit = v
+	}
+
+	results, err := h.service.Search(r.Context(), projectID, userID, q, limit)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+// ReplaceAssetContent handles PUT /projects/{projectId}/assets/{assetId}/content
+// with a PNG or JPEG body. The asset keeps its ID and the response is its
+// updated record, whose revision counts the replacements.
+func (h *Handler) ReplaceAssetContent(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromContext(r.Context())
+	vars := mux.Vars(r)
+
+	record, err := h.service.ReplaceAssetContent(r.Context(), vars["projectId"], userID, vars["assetId"], r.Header.Get("Content-Type"), r.Body)
+	if err != nil {
+		httperr.FromError(w, err, assetContentErrors)
+		return
+	}
+	writeJSON(w, http.StatusOK, record)
+}
+
+var serviceErrors = []httperr.Mapping{
+	{Err: ErrNotFound, Status: http.StatusNotFound, Code: httperr.CodeProjectNotFound},
+	{Err: ErrSnapshotNotFound, Status: http.StatusNotFound, Code: httperr.CodeSnapshotNotFound},
+	{Err: ErrVersionNotFound, Status: http.StatusNotFound, Code: httperr.CodeSnapshotNotFound},
+	// Inviting an email with no account was an unmapped 500 before errors
+	// carried codes; it's the caller's mistake, so it is reported as a 404
+	{Err: ErrUserNotFound, Status: http.StatusNotFound, Code: httperr.CodeUserNotFound},
+	{Err: ErrRoomNotFound, Status: http.StatusNotFound, Code: httperr.CodeRoomNotFound},
+	{Err: ErrForbidden, Status: http.StatusForbidden, Code: httperr.CodeForbidden},
+	{Err: ErrNotMember, Status: http.StatusForbidden, Code: httperr.CodeNotAMember},
+	{Err: ErrNotEditor, Status: http.StatusForbidden, Code: httperr.CodeForbidden},
+	{Err: ErrVersionConflict, Status: http.StatusConflict, Code: httperr.CodeVersionConflict},
+	{Err: ErrInvalidDocument, Status: http.StatusBadRequest, Code: httperr.CodeValidationFailed},
+	{Err: ErrInvalidRole, Status: http.StatusBadRequest, Code: httperr.CodeValidationFailed},
+	{Err: ErrLinkedAssets, Status: http.StatusForbidden, Code: httperr.CodeForbidden},
+	{Err: ErrSceneNotFound, Status: http.StatusNotFound, Code: httperr.CodeNotFound},
+	{Err: ErrMemberLimit, Status: http.StatusForbidden, Code: httperr.CodeMemberLimit},
+	{Err: raster.ErrSheetTooLarge, Status: http.StatusBadRequest, Code: httperr.CodeValidationFailed},
+	{Err: raster.ErrFrameTooLarge, Status: http.StatusBadRequest, Code: httperr.CodeValidationFailed},
+}
+
+var assetContentErrors = append([]httperr.Mapping{
+	{Err: asset.ErrAssetNotFound, Status: http.StatusNotFound, Code: httperr.CodeAssetNotFound},
+	{Err: asset.ErrAssetNotOwned, Status: http.StatusForbidden, Code: httperr.CodeForbidden},
+	{Err: asset.ErrUnsupportedType, Status: http.StatusBadRequest, Code: httperr.CodeUnsupportedMediaType},
+	{Err: asset.ErrInvalidImage, Status: http.StatusBadRequest, Code: httperr.CodeValidationFailed},
+	{Err: asset.ErrFileTooLarge, Status: http.StatusRequestEntityTooLarge, Code: httperr.CodePayloadTooLarge},
+}, serviceErrors...)
+
+func handleServiceError(w http.ResponseWriter, err error) {
+	httperr.FromError(w, err, serviceErrors)
 }
 
 func writeJSON(w http.ResponseWriter, status int, data interface{}) {
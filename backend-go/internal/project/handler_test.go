@@ -0,0 +1,328 @@
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/inamate/inamate/backend-go/internal/auth"
+	"github.com/inamate/inamate/backend-go/internal/db/dbgen"
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/httperr"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+	"github.com/jackc/pgx/v5"
+)
+
+func decodeError(t *testing.T, rec *httptest.ResponseRecorder) httperr.Error {
+	t.Helper()
+	var e httperr.Error
+	if err := json.Unmarshal(rec.Body.Bytes(), &e); err != nil {
+		t.Fatalf("body %q is not an error envelope: %v", rec.Body, err)
+	}
+	return e
+}
+
+func TestServiceErrorsCarryCodes(t *testing.T) {
+	for _, m := range assetContentErrors {
+		t.Run(m.Err.Error(), func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			httperr.FromError(rec, fmt.Errorf("wrapped: %w", m.Err), assetContentErrors)
+			if rec.Code < 400 || rec.Code >= 500 {
+				t.Errorf("status %d, want a 4xx", rec.Code)
+			}
+			if e := decodeError(t, rec); e.Code == "" || e.Code != m.Code {
+				t.Errorf("code %q, want %q", e.Code, m.Code)
+			}
+		})
+	}
+}
+
+func TestInviteUnknownUserIsNotFound(t *testing.T) {
+	rec := httptest.NewRecorder()
+	handleServiceError(rec, ErrUserNotFound)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status %d, want 404", rec.Code)
+	}
+	if e := decodeError(t, rec); e.Code != httperr.CodeUserNotFound {
+		t.Errorf("code %q, want %s", e.Code, httperr.CodeUserNotFound)
+	}
+
+	rec = httptest.NewRecorder()
+	handleServiceError(rec, errors.New("connection reset"))
+	if e := decodeError(t, rec); rec.Code != http.StatusInternalServerError || e.Code != httperr.CodeInternal {
+		t.Errorf("unmapped error: status %d code %q, want 500 %s", rec.Code, e.Code, httperr.CodeInternal)
+	}
+}
+
+func TestInviteValidationErrors(t *testing.T) {
+	h := NewHandler(nil)
+	tests := []struct {
+		name string
+		body string
+		code string
+	}{
+		{"malformed body", `{`, httperr.CodeInvalidBody},
+		{"missing email", `{"role":"editor"}`, httperr.CodeValidationFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/projects/proj_x/members", strings.NewReader(tt.body))
+			r = mux.SetURLVars(r, map[string]string{"projectId": "proj_x"})
+			rec := httptest.NewRecorder()
+			h.Invite(rec, r)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status %d, want 400", rec.Code)
+			}
+			if e := decodeError(t, rec); e.Code != tt.code {
+				t.Errorf("code %q, want %s", e.Code, tt.code)
+			}
+		})
+	}
+}
+
+func TestGetRecordingValidatesRange(t *testing.T) {
+	h := NewHandler(nil)
+	for _, query := range []string{"fromSeq=x", "toSeq=-1", "fromSeq=1.5", "fromSeq=9&toSeq=3"} {
+		t.Run(query, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/projects/proj_x/recording?"+query, nil)
+			r = mux.SetURLVars(r, map[string]string{"projectId": "proj_x"})
+			rec := httptest.NewRecorder()
+			h.GetRecording(rec, r)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status %d, want 400", rec.Code)
+			}
+			if e := decodeError(t, rec); e.Code != httperr.CodeValidationFailed {
+				t.Errorf("code %q, want %s", e.Code, httperr.CodeValidationFailed)
+			}
+		})
+	}
+}
+
+func TestCreateSceneSettings(t *testing.T) {
+	defaults := document.SceneSettings{Width: 1080, Height: 1080, Background: "#000000", FPS: 30}
+	width, background := 1920, " #112233 "
+
+	if got := (createRequest{}).sceneSettings(defaults); got != defaults {
+		t.Errorf("no overrides: %+v, want the configured defaults", got)
+	}
+	got := createRequest{Width: &width, Background: &background}.sceneSettings(defaults)
+	want := document.SceneSettings{Width: 1920, Height: 1080, Background: "#112233", FPS: 30}
+	if got != want {
+		t.Errorf("overrides: %+v, want %+v", got, want)
+	}
+}
+
+func TestCreateRejectsInvalidScene(t *testing.T) {
+	service := NewService(nil, nil)
+	service.SetSceneDefaults(document.SceneSettings{Width: 1080, Height: 1080, Background: "#000000", FPS: 30})
+	h := NewHandler(service)
+
+	for _, body := range []string{
+		`{"name":"p","width":0}`,
+		`{"name":"p","fps":500}`,
+		`{"name":"p","background":"dark"}`,
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/api/projects", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		h.Create(rec, r)
+		if rec.Code != http.StatusBadRequest || decodeError(t, rec).Code != httperr.CodeValidationFailed {
+			t.Errorf("%s: status %d %s, want 400 %s", body, rec.Code, rec.Body, httperr.CodeValidationFailed)
+		}
+	}
+}
+
+func TestImportLottieRejectsInvalid(t *testing.T) {
+	h := NewHandler(NewService(nil, nil))
+	for _, body := range []string{
+		`not json`,
+		`{"v":"5.7.4","layers":[]}`,
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/api/projects/import/lottie", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		h.ImportLottie(rec, r)
+		if rec.Code != http.StatusBadRequest || decodeError(t, rec).Code != httperr.CodeValidationFailed {
+			t.Errorf("%s: status %d %s, want 400 %s", body, rec.Code, rec.Body, httperr.CodeValidationFailed)
+		}
+	}
+}
+
+// playgroundJSON is a playground document as a client would send it, with
+// the seeded playground's legacy IDs.
+const playgroundJSON = `{
+	"project": {"id": "proj_playground", "name": "Playground", "version": 1, "fps": 24,
+		"scenes": ["scene_playground"], "assets": [], "rootTimeline": "timeline_main"},
+	"scenes": {"scene_playground": {"id": "scene_playground", "name": "Scene 1", "width": 800, "height": 600,
+		"background": "#ffffff", "root": "root_playground"}},
+	"objects": {
+		"root_playground": {"id": "root_playground", "type": "Group", "parent": null, "children": ["rect_1"], "visible": true, "data": {}},
+		"rect_1": {"id": "rect_1", "type": "ShapeRect", "parent": "root_playground", "children": [], "visible": true,
+			"data": {"width": 10, "height": 10}}
+	},
+	"timelines": {"timeline_main": {"id": "timeline_main", "length": 48, "tracks": []}},
+	"tracks": {}, "keyframes": {}, "assets": {}
+}`
+
+func TestCreateFromPlayground(t *testing.T) {
+	service, db := newFakeService()
+	h := NewHandler(service)
+
+	body := `{"name":"Keeper","document":` + playgroundJSON + `}`
+	r := httptest.NewRequest(http.MethodPost, "/api/projects/from-playground", strings.NewReader(body))
+	r = r.WithContext(context.WithValue(r.Context(), auth.UserIDKey, "user_1"))
+	rec := httptest.NewRecorder()
+	h.CreateFromPlayground(rec, r)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var project Project
+	if err := json.Unmarshal(rec.Body.Bytes(), &project); err != nil {
+		t.Fatal(err)
+	}
+	if project.OwnerID != "user_1" || project.Name != "Keeper" || project.Width != 800 || project.FPS != 24 {
+		t.Errorf("project %+v, want Keeper owned by user_1", project)
+	}
+
+	members := db.ran("AddProjectMember")
+	if len(members) != 1 || members[0][0] != project.ID || members[0][1] != "user_1" || members[0][2] != dbgen.ProjectRoleOwner {
+		t.Errorf("members added %v, want user_1 as owner of %s", members, project.ID)
+	}
+
+	// The seeded document is the playground's under fresh IDs
+	snapshots := db.ran("CreateSnapshot")
+	if len(snapshots) != 1 {
+		t.Fatalf("%d snapshots created, want 1", len(snapshots))
+	}
+	var doc document.InDocument
+	if err := json.Unmarshal(snapshots[0][3].([]byte), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Project.ID != project.ID || len(doc.Objects) != 2 {
+		t.Errorf("snapshot of %s with %d objects", doc.Project.ID, len(doc.Objects))
+	}
+	if _, ok := doc.Objects["rect_1"]; ok || doc.Project.Scenes[0] == "scene_playground" {
+		t.Error("snapshot kept the playground's IDs")
+	}
+	if err := doc.Validate(); err != nil {
+		t.Errorf("seeded document invalid: %v", err)
+	}
+}
+
+func TestCreateFromPlaygroundRejectsInvalid(t *testing.T) {
+	service, db := newFakeService()
+	h := NewHandler(service)
+
+	broken := strings.Replace(playgroundJSON, `"children": ["rect_1"]`, `"children": []`, 1)
+	for _, body := range []string{
+		`{"document":` + broken + `}`,
+		`{"document":{"project":{"fps":24}}}`,
+		`not json`,
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/api/projects/from-playground", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		h.CreateFromPlayground(rec, r)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%.40s: status %d %s, want 400", body, rec.Code, rec.Body)
+		}
+	}
+	if len(db.queries) != 0 {
+		t.Errorf("invalid documents reached the database: %v", db.queries)
+	}
+}
+
+// validateOps posts a validate request for a project as user_1.
+func validateOps(h *Handler, projectID, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/api/projects/"+projectID+"/operations/validate", strings.NewReader(body))
+	r = mux.SetURLVars(r, map[string]string{"projectId": projectID})
+	r = r.WithContext(context.WithValue(r.Context(), auth.UserIDKey, "user_1"))
+	rec := httptest.NewRecorder()
+	h.ValidateOperations(rec, r)
+	return rec
+}
+
+func TestValidateOperations(t *testing.T) {
+	projectID, rootID := typeid.NewProjectID(), typeid.NewObjectID()
+	doc := document.NewEmptyDocument(projectID, "Scripted", typeid.NewSceneID(), rootID, typeid.NewTimelineID())
+	snapshot, _ := json.Marshal(doc)
+	service, db := newFakeService()
+	db.rows = map[string][]pgx.Row{"GetLatestSnapshot": {echoRow{nil, nil, nil, snapshot}}}
+	h := NewHandler(service)
+
+	rectID := typeid.NewObjectID()
+	create := `{"id":"op_create","type":"object.create","parentId":"` + rootID + `","object":{"id":"` + rectID +
+		`","type":"ShapeRect","parent":"` + rootID + `","children":[],"transform":{"sx":1,"sy":1},"visible":true,"data":{"width":10,"height":10}}}`
+	move := `{"id":"op_move","type":"object.transform","objectId":"` + rectID + `","transform":{"x":5}}`
+
+	var resp validateOpsResponse
+	rec := validateOps(h, projectID, `{"operations":[`+create+`,`+move+`]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if !resp.Valid || len(resp.Results) != 2 || resp.Results[0].ObjectIDs[0] != rectID {
+		t.Fatalf("response %s, want both ops ok", rec.Body)
+	}
+
+	// Nothing was kept: the rect doesn't exist for the next request
+	rec = validateOps(h, projectID, `{"operations":[`+move+`]}`)
+	resp = validateOpsResponse{}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if resp.Valid || resp.Results[0].OK || !strings.Contains(resp.Results[0].Reason, "not found") {
+		t.Errorf("move of an uncreated rect: %s", rec.Body)
+	}
+	if writes := len(db.ran("CreateSnapshot")) + len(db.ran("UpdateProject")); writes != 0 {
+		t.Errorf("validating wrote %d times", writes)
+	}
+
+	// An inline document is used instead of the project's
+	inline := document.NewEmptyDocument(projectID, "Inline", typeid.NewSceneID(), rectID, typeid.NewTimelineID())
+	inlineJSON, _ := json.Marshal(inline)
+	rec = validateOps(h, projectID, `{"document":`+string(inlineJSON)+`,"operations":[`+move+`]}`)
+	resp = validateOpsResponse{}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if !resp.Valid {
+		t.Errorf("move of the inline document's root: %s", rec.Body)
+	}
+}
+
+func TestValidateOperationsRejected(t *testing.T) {
+	projectID := typeid.NewProjectID()
+	service, db := newFakeService()
+	h := NewHandler(service)
+	move := `{"id":"op_move","type":"object.transform","objectId":"` + typeid.NewObjectID() + `","transform":{"x":5}}`
+
+	tests := []struct {
+		name, body string
+		status     int
+		code       string
+	}{
+		{"no operations", `{"operations":[]}`, http.StatusBadRequest, httperr.CodeValidationFailed},
+		{"too many", `{"operations":[` + strings.Repeat(move+",", maxValidateOps) + move + `]}`, http.StatusBadRequest, httperr.CodeValidationFailed},
+		{"not json", `{"operations":`, http.StatusBadRequest, httperr.CodeInvalidBody},
+		{"invalid inline document", `{"document":{"project":{"fps":24}},"operations":[` + move + `]}`, http.StatusBadRequest, httperr.CodeValidationFailed},
+	}
+	for _, tt := range tests {
+		rec := validateOps(h, projectID, tt.body)
+		if rec.Code != tt.status || decodeError(t, rec).Code != tt.code {
+			t.Errorf("%s: %d %s, want %d %s", tt.name, rec.Code, rec.Body, tt.status, tt.code)
+		}
+	}
+
+	db.rows = map[string][]pgx.Row{"GetProjectMember": {errRow{pgx.ErrNoRows}}}
+	rec := validateOps(h, projectID, `{"operations":[`+move+`]}`)
+	if rec.Code != http.StatusForbidden || decodeError(t, rec).Code != httperr.CodeNotAMember {
+		t.Errorf("non-member: %d %s, want 403 not_a_member", rec.Code, rec.Body)
+	}
+
+	db.rows = map[string][]pgx.Row{"GetLatestSnapshot": {errRow{pgx.ErrNoRows}}}
+	rec = validateOps(h, projectID, `{"operations":[`+move+`]}`)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("no snapshot: %d %s, want 404", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,53 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/inamate/inamate/backend-go/internal/httperr"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+func TestInviteMemberLimit(t *testing.T) {
+	projectID := typeid.NewProjectID()
+	invite := func(limit int, members ...string) (*fakeDB, error) {
+		service, db := newFakeService()
+		service.SetMaxMembers(limit)
+		db.rows = map[string][]pgx.Row{"GetProject": {echoRow{projectID, "Limited", "owner"}}}
+		db.many = map[string][]echoRow{"ListProjectMembers": {}}
+		for _, userID := range members {
+			db.many["ListProjectMembers"] = append(db.many["ListProjectMembers"], echoRow{projectID, userID})
+		}
+		return db, service.InviteByEmail(context.Background(), projectID, "owner", "new@example.com", "")
+	}
+
+	// The owner and one editor fill a project limited to two
+	db, err := invite(2, "owner", "editor")
+	if !errors.Is(err, ErrMemberLimit) {
+		t.Errorf("inviting a third member: %v, want ErrMemberLimit", err)
+	}
+	if len(db.ran("AddProjectMember")) != 0 {
+		t.Error("member added past the limit")
+	}
+
+	for _, limit := range []int{3, 0} {
+		if db, err := invite(limit, "owner", "editor"); err != nil || len(db.ran("AddProjectMember")) != 1 {
+			t.Errorf("limit %d: %v, want the member added", limit, err)
+		}
+	}
+	// Without a limit the members aren't counted
+	if db, _ := invite(0); len(db.ran("ListProjectMembers")) != 0 {
+		t.Error("members listed without a limit")
+	}
+
+	rec := httptest.NewRecorder()
+	handleServiceError(rec, ErrMemberLimit)
+	if e := decodeError(t, rec); rec.Code != http.StatusForbidden || e.Code != httperr.CodeMemberLimit {
+		t.Errorf("status %d code %q, want 403 %s", rec.Code, e.Code, httperr.CodeMemberLimit)
+	}
+}
@@ -0,0 +1,238 @@
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/inamate/inamate/backend-go/internal/auth"
+	"github.com/inamate/inamate/backend-go/internal/collab"
+	"github.com/inamate/inamate/backend-go/internal/collab/collabtest"
+	"github.com/inamate/inamate/backend-go/internal/db/dbgen"
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/httperr"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// opsProject is a project whose document holds an empty scene, as stored.
+type opsProject struct {
+	id, rootID string
+	doc        *document.InDocument
+}
+
+func newOpsProject() opsProject {
+	p := opsProject{id: typeid.NewProjectID(), rootID: typeid.NewObjectID()}
+	p.doc = document.NewEmptyDocument(p.id, "Scripted", typeid.NewSceneID(), p.rootID, typeid.NewTimelineID())
+	return p
+}
+
+// snapshotRow is a GetLatestSnapshot row holding doc at version.
+func snapshotRow(t *testing.T, doc *document.InDocument, version int32) pgx.Row {
+	t.Helper()
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return echoRow{"snap_1", doc.Project.ID, version, data}
+}
+
+// applyOps posts body to the project's operations endpoint as user_1.
+func applyOps(h *Handler, projectID, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/api/projects/"+projectID+"/operations", strings.NewReader(body))
+	r = mux.SetURLVars(r, map[string]string{"projectId": projectID})
+	r = r.WithContext(context.WithValue(r.Context(), auth.UserIDKey, "user_1"))
+	rec := httptest.NewRecorder()
+	h.ApplyOperations(rec, r)
+	return rec
+}
+
+func decodeApplied(t *testing.T, rec *httptest.ResponseRecorder) applyOpsResponse {
+	t.Helper()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var resp applyOpsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+// savedDocument decodes the document of a CreateSnapshot run.
+func savedDocument(t *testing.T, args []interface{}) *document.InDocument {
+	t.Helper()
+	var doc document.InDocument
+	if err := json.Unmarshal(args[3].([]byte), &doc); err != nil {
+		t.Fatal(err)
+	}
+	return &doc
+}
+
+func renameOp(name string) string {
+	return `{"id":"` + typeid.NewOpID() + `","type":"project.rename","name":"` + name + `"}`
+}
+
+func TestApplyOperationsColdProject(t *testing.T) {
+	p := newOpsProject()
+	service, db := newFakeService()
+	db.rows = map[string][]pgx.Row{"GetLatestSnapshot": {snapshotRow(t, p.doc, 3)}}
+	h := NewHandler(service)
+
+	// A single operation
+	resp := decodeApplied(t, applyOps(h, p.id, renameOp("Renamed")))
+	if !resp.Applied || len(resp.Results) != 1 || resp.Results[0].Ack == nil || resp.Results[0].Ack.ServerSeq != 1 {
+		t.Fatalf("response %+v, want one ack at seq 1", resp)
+	}
+	saves := db.ran("CreateSnapshot")
+	if len(saves) != 1 || saves[0][2] != int32(4) {
+		t.Fatalf("snapshots saved %v, want version 4", saves)
+	}
+	if doc := savedDocument(t, saves[0]); doc.Project.Name != "Renamed" {
+		t.Errorf("saved project name %q", doc.Project.Name)
+	}
+
+	// A batch: a rejected operation doesn't stop the rest
+	missing := `{"id":"op_missing","type":"object.transform","objectId":"` + typeid.NewObjectID() + `","transform":{"x":1}}`
+	resp = decodeApplied(t, applyOps(h, p.id, `{"operations":[`+missing+`,`+renameOp("Again")+`]}`))
+	if resp.Applied || resp.Results[0].Nack == nil || resp.Results[1].Ack == nil {
+		t.Errorf("batch results %+v, want a nack then an ack", resp.Results)
+	}
+
+	// Nothing changed, nothing saved
+	before := len(db.ran("CreateSnapshot"))
+	resp = decodeApplied(t, applyOps(h, p.id, missing))
+	if resp.Applied || len(db.ran("CreateSnapshot")) != before {
+		t.Errorf("a rejected operation saved a snapshot: %+v", resp)
+	}
+}
+
+// TestApplyOperationsVersionConflict checks the operations are reapplied to
+// the newer snapshot when another writer saves the next version first.
+func TestApplyOperationsVersionConflict(t *testing.T) {
+	p := newOpsProject()
+	// Another writer's version 4 has a dark background
+	var raced document.InDocument
+	data, _ := json.Marshal(p.doc)
+	json.Unmarshal(data, &raced)
+	scene := raced.Scenes[raced.Project.Scenes[0]]
+	scene.Background = "#000000"
+	raced.Scenes[scene.ID] = scene
+
+	service, db := newFakeService()
+	duplicate := errRow{&pgconn.PgError{Code: "23505"}}
+	db.rows = map[string][]pgx.Row{
+		"GetLatestSnapshot": {snapshotRow(t, p.doc, 3), snapshotRow(t, &raced, 4)},
+		"CreateSnapshot":    {duplicate, nil},
+	}
+	h := NewHandler(service)
+
+	resp := decodeApplied(t, applyOps(h, p.id, renameOp("Mine")))
+	if !resp.Applied {
+		t.Fatalf("response %+v, want applied", resp)
+	}
+	saves := db.ran("CreateSnapshot")
+	if len(saves) != 2 || saves[0][2] != int32(4) || saves[1][2] != int32(5) {
+		t.Fatalf("snapshot versions %v, want 4 then 5", saves)
+	}
+	// The retry applies to the other writer's document
+	doc := savedDocument(t, saves[1])
+	if doc.Project.Name != "Mine" || doc.Scenes[doc.Project.Scenes[0]].Background != "#000000" {
+		t.Errorf("retry saved %q on a %s scene, want Mine over the newer version", doc.Project.Name, doc.Scenes[doc.Project.Scenes[0]].Background)
+	}
+
+	// A writer that always wins exhausts the retries
+	db.queries = nil
+	db.rows = map[string][]pgx.Row{
+		"GetLatestSnapshot": {snapshotRow(t, p.doc, 3)},
+		"CreateSnapshot":    {duplicate},
+	}
+	rec := applyOps(h, p.id, renameOp("Never"))
+	if rec.Code != http.StatusConflict || decodeError(t, rec).Code != httperr.CodeVersionConflict {
+		t.Errorf("status %d %s, want 409 version_conflict", rec.Code, rec.Body)
+	}
+	if n := len(db.ran("CreateSnapshot")); n != maxSaveAttempts {
+		t.Errorf("%d save attempts, want %d", n, maxSaveAttempts)
+	}
+}
+
+func TestApplyOperationsLiveRoom(t *testing.T) {
+	p := newOpsProject()
+	store := collabtest.NewStore()
+	if err := store.Put(p.id, p.doc); err != nil {
+		t.Fatal(err)
+	}
+	server := collabtest.NewServer(store)
+	t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	watcher, err := server.ConnectAs(ctx, p.id, "watcher", "watcher", collab.RoleEditor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { watcher.Close() })
+	if _, _, err := watcher.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	service, db := newFakeService()
+	service.SetHub(server.Hub)
+	h := NewHandler(service)
+
+	opID := typeid.NewOpID()
+	resp := decodeApplied(t, applyOps(h, p.id, `{"id":"`+opID+`","type":"project.rename","name":"Live"}`))
+	if !resp.Applied || resp.Results[0].Ack == nil {
+		t.Fatalf("response %+v, want an ack", resp)
+	}
+	// Connected editors see it as it happens
+	broadcast, err := watcher.Broadcast(ctx, opID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if broadcast.UserID != "user_1" || broadcast.Operation.Name != "Live" || broadcast.ServerSeq != resp.Results[0].Ack.ServerSeq {
+		t.Errorf("broadcast %+v, want user_1's rename at seq %d", broadcast, resp.Results[0].Ack.ServerSeq)
+	}
+	// The room saves on its own schedule
+	if n := len(db.ran("GetLatestSnapshot")) + len(db.ran("CreateSnapshot")); n != 0 {
+		t.Errorf("live apply touched snapshots %d times", n)
+	}
+}
+
+func TestApplyOperationsRejected(t *testing.T) {
+	projectID := typeid.NewProjectID()
+	service, db := newFakeService()
+	h := NewHandler(service)
+
+	tests := []struct {
+		name, body string
+		code       string
+	}{
+		{"empty", `{}`, httperr.CodeValidationFailed},
+		{"no operations", `{"operations":[]}`, httperr.CodeValidationFailed},
+		{"too many", `{"operations":[` + strings.Repeat(renameOp("x")+",", maxValidateOps) + renameOp("x") + `]}`, httperr.CodeValidationFailed},
+		{"not json", `[`, httperr.CodeInvalidBody},
+	}
+	for _, tt := range tests {
+		rec := applyOps(h, projectID, tt.body)
+		if rec.Code != http.StatusBadRequest || decodeError(t, rec).Code != tt.code {
+			t.Errorf("%s: %d %s, want 400 %s", tt.name, rec.Code, rec.Body, tt.code)
+		}
+	}
+
+	db.rows = map[string][]pgx.Row{"GetProjectMember": {echoRow{projectID, "user_1", dbgen.ProjectRoleViewer}}}
+	rec := applyOps(h, projectID, renameOp("Viewer"))
+	if rec.Code != http.StatusForbidden || decodeError(t, rec).Code != httperr.CodeForbidden {
+		t.Errorf("viewer: %d %s, want 403 forbidden", rec.Code, rec.Body)
+	}
+	if len(db.ran("GetLatestSnapshot")) != 0 {
+		t.Error("a viewer's request loaded the project")
+	}
+}
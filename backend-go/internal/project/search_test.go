@@ -0,0 +1,122 @@
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/inamate/inamate/backend-go/internal/auth"
+	"github.com/inamate/inamate/backend-go/internal/collab"
+	"github.com/inamate/inamate/backend-go/internal/collab/collabtest"
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/httperr"
+)
+
+// search requests the project's search endpoint with query as user_1.
+func search(h *Handler, projectID, query string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/api/projects/"+projectID+"/search?"+query, nil)
+	r = mux.SetURLVars(r, map[string]string{"projectId": projectID})
+	r = r.WithContext(context.WithValue(r.Context(), auth.UserIDKey, "user_1"))
+	rec := httptest.NewRecorder()
+	h.Search(rec, r)
+	return rec
+}
+
+func decodeSearch(t *testing.T, rec *httptest.ResponseRecorder) SearchResults {
+	t.Helper()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var results SearchResults
+	if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	}
+	return results
+}
+
+// namedScene renames the project's first scene.
+func namedScene(doc *document.InDocument, name string) {
+	scene := doc.Scenes[doc.Project.Scenes[0]]
+	scene.Name = name
+	doc.Scenes[scene.ID] = scene
+}
+
+func TestSearchSavedDocument(t *testing.T) {
+	p := newOpsProject()
+	namedScene(p.doc, "Saved CTA")
+	service, db := newFakeService()
+	db.rows = map[string][]pgx.Row{"GetLatestSnapshot": {snapshotRow(t, p.doc, 1)}}
+	h := NewHandler(service)
+
+	results := decodeSearch(t, search(h, p.id, "q="+url.QueryEscape(" cta ")))
+	if results.Query != "cta" || results.Truncated || len(results.Matches) != 1 {
+		t.Fatalf("results %+v, want the scene name", results)
+	}
+	if m := results.Matches[0]; m.SceneID != p.doc.Project.Scenes[0] || m.Field != "name" || m.Snippet != "Saved CTA" {
+		t.Errorf("match %+v", m)
+	}
+	if len(db.ran("GetProjectMember")) != 1 {
+		t.Error("searched without checking membership")
+	}
+
+	db.rows["GetProjectMember"] = []pgx.Row{errRow{pgx.ErrNoRows}}
+	rec := search(h, p.id, "q=cta")
+	if e := decodeError(t, rec); rec.Code != http.StatusForbidden || e.Code != httperr.CodeNotAMember {
+		t.Errorf("non-member: status %d code %q, want 403 %s", rec.Code, e.Code, httperr.CodeNotAMember)
+	}
+}
+
+func TestSearchLiveRoom(t *testing.T) {
+	p := newOpsProject()
+	namedScene(p.doc, "Live CTA")
+	store := collabtest.NewStore()
+	if err := store.Put(p.id, p.doc); err != nil {
+		t.Fatal(err)
+	}
+	server := collabtest.NewServer(store)
+	t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	watcher, err := server.ConnectAs(ctx, p.id, "watcher", "watcher", collab.RoleEditor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { watcher.Close() })
+	if _, _, err := watcher.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	service, db := newFakeService()
+	service.SetHub(server.Hub)
+	results := decodeSearch(t, search(NewHandler(service), p.id, "q=live"))
+	if len(results.Matches) != 1 || results.Matches[0].Snippet != "Live CTA" {
+		t.Errorf("results %+v, want the live scene name", results)
+	}
+	if len(db.ran("GetLatestSnapshot")) != 0 {
+		t.Error("searched the saved snapshot of a live project")
+	}
+}
+
+func TestSearchValidatesQuery(t *testing.T) {
+	h := NewHandler(nil)
+	for _, query := range []string{"", "q=", "q=%20%20", "q=" + strings.Repeat("a", 201), "q=cta&limit=0", "q=cta&limit=201", "q=cta&limit=x"} {
+		t.Run(query, func(t *testing.T) {
+			rec := search(h, "proj_x", query)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status %d, want 400", rec.Code)
+			}
+			if e := decodeError(t, rec); e.Code != httperr.CodeValidationFailed {
+				t.Errorf("code %q, want %s", e.Code, httperr.CodeValidationFailed)
+			}
+		})
+	}
+}
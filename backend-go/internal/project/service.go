@@ -5,26 +5,110 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"strings"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 
+	"github.com/inamate/inamate/backend-go/internal/asset"
+	"github.com/inamate/inamate/backend-go/internal/collab"
 	"github.com/inamate/inamate/backend-go/internal/db/dbgen"
 	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/notification"
+	"github.com/inamate/inamate/backend-go/internal/raster"
+	"github.com/inamate/inamate/backend-go/internal/rendercache"
 	"github.com/inamate/inamate/backend-go/internal/typeid"
+	"github.com/inamate/inamate/backend-go/internal/webhook"
 )
 
 var (
 	ErrNotFound  = errors.New("project not found")
 	ErrForbidden = errors.New("forbidden")
 	ErrNotMember = errors.New("not a project member")
+
+	ErrUserNotFound     = errors.New("user not found")
+	ErrSnapshotNotFound = errors.New("project has no snapshot")
+	ErrVersionNotFound  = errors.New("project has no snapshot with that version")
+	ErrRoomNotFound     = errors.New("project has no live session")
+	ErrInvalidDocument  = errors.New("invalid document")
+	ErrNotEditor        = errors.New("viewers cannot edit the project")
+	ErrInvalidRole      = errors.New("role must be editor or viewer")
+	ErrVersionConflict  = errors.New("the project was saved concurrently")
+	ErrLinkedAssets     = errors.New("the project's assets can't be replaced")
+	ErrSceneNotFound    = errors.New("scene not found")
+	ErrMemberLimit      = errors.New("the project has reached its member limit")
 )
 
+// maxSaveAttempts bounds how often ApplyOperations reapplies operations to a
+// newer snapshot after another writer saved first.
+const maxSaveAttempts = 3
+
 type Service struct {
-	queries *dbgen.Queries
+	queries  *dbgen.Queries
+	webhooks *webhook.Dispatcher
+	notes    *notification.Service
+	hub      *collab.Hub
+	ops      collab.OpStore
+	assets   *asset.Handler
+	renders  *rendercache.Cache
+	images   raster.ImageLoader
+	defaults document.SceneSettings
+
+	maxMembers int // Including the owner; 0 for no limit
+}
+
+func NewService(queries *dbgen.Queries, webhooks *webhook.Dispatcher) *Service {
+	return &Service{queries: queries, webhooks: webhooks, defaults: document.DefaultSceneSettings}
+}
+
+// SetSceneDefaults sets the scene settings new projects start with.
+func (s *Service) SetSceneDefaults(defaults document.SceneSettings) {
+	s.defaults = defaults
+}
+
+// SceneDefaults returns the scene settings new projects start with.
+func (s *Service) SceneDefaults() document.SceneSettings {
+	return s.defaults
+}
+
+// SetHub attaches the collaboration hub that session recordings and the
+// live playground are read from. The hub is created after the service, so it's set separately.
+func (s *Service) SetHub(hub *collab.Hub) {
+	s.hub = hub
+}
+
+// SetOpStore sets the log of operations live sessions applied after the
+// latest snapshot, which edits made without a session build on.
+func (s *Service) SetOpStore(ops collab.OpStore) {
+	s.ops = ops
+}
+
+// SetNotifications sets the service that members are notified through when
+// they are invited to or removed from a project.
+func (s *Service) SetNotifications(notes *notification.Service) {
+	s.notes = notes
+}
+
+// SetRenderer sets where server-side renders cache their frames and load
+// image assets from.
+func (s *Service) SetRenderer(renders *rendercache.Cache, images raster.ImageLoader) {
+	s.renders = renders
+	s.images = images
 }
 
-func NewService(queries *dbgen.Queries) *Service {
-	return &Service{queries: queries}
+// SetMaxMembers bounds how many members, the owner included, a project may
+// have. Zero or less means no limit.
+func (s *Service) SetMaxMembers(n int) {
+	s.maxMembers = max(n, 0)
+}
+
+// SetAssetStore enables replacing the content of assets in projects that
+// allow it. Without a store, ReplaceAssetContent always fails.
+func (s *Service) SetAssetStore(assets *asset.Handler) {
+	s.assets = assets
 }
 
 type Project struct {
@@ -45,13 +129,44 @@ type Member struct {
 	Email       string `json:"email"`
 }
 
-func (s *Service) Create(ctx context.Context, name, ownerID string) (*Project, error) {
+// Snapshot describes a saved version of a project's document.
+type Snapshot struct {
+	ID            string `json:"id"`
+	Version       int    `json:"version"`
+	Seq           int64  `json:"seq"`    // Server sequence it was saved at
+	Label         string `json:"label"`  // Why it was saved; empty for autosaves
+	Safety        bool   `json:"safety"` // Taken before a destructive operation
+	DocumentBytes int    `json:"documentBytes"`
+	CreatedAt     string `json:"createdAt"`
+}
+
+// Create creates a project whose first scene uses settings (see
+// SceneDefaults).
+func (s *Service) Create(ctx context.Context, name, ownerID string, settings document.SceneSettings) (*Project, error) {
+	doc := document.NewDocument("", name, typeid.NewSceneID(), typeid.NewObjectID(), typeid.NewTimelineID(), settings)
+	return s.CreateFromDocument(ctx, name, ownerID, doc)
+}
+
+// CreateFromDocument creates a project seeded with doc, such as an imported
+// animation. It assigns doc the new project's ID and name; the project's size
+// comes from doc's first scene.
+func (s *Service) CreateFromDocument(ctx context.Context, name, ownerID string, doc *document.InDocument) (*Project, error) {
+	if len(doc.Project.Scenes) == 0 {
+		return nil, fmt.Errorf("document has no scenes")
+	}
+	scene := doc.Scenes[doc.Project.Scenes[0]]
+
 	projectID := typeid.NewProjectID()
+	doc.Project.ID = projectID
+	doc.Project.Name = name
 
 	dbProj, err := s.queries.CreateProject(ctx, dbgen.CreateProjectParams{
 		ID:      projectID,
 		Name:    name,
 		OwnerID: ownerID,
+		Fps:     int32(doc.Project.FPS),
+		Width:   int32(scene.Width),
+		Height:  int32(scene.Height),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("create project: %w", err)
@@ -67,14 +182,10 @@ func (s *Service) Create(ctx context.Context, name, ownerID string) (*Project, e
 		return nil, fmt.Errorf("add owner as member: %w", err)
 	}
 
-	// Seed empty document snapshot
-	sceneID := typeid.NewSceneID()
-	rootID := typeid.NewObjectID()
-	timelineID := typeid.NewTimelineID()
-	emptyDoc := document.NewEmptyDocument(projectID, name, sceneID, rootID, timelineID)
-	docJSON, err := json.Marshal(emptyDoc)
+	// Seed the initial document snapshot
+	docJSON, err := document.MarshalCanonical(doc)
 	if err != nil {
-		return nil, fmt.Errorf("marshal empty document: %w", err)
+		return nil, fmt.Errorf("marshal document: %w", err)
 	}
 
 	_, err = s.queries.CreateSnapshot(ctx, dbgen.CreateSnapshotParams{
@@ -90,6 +201,333 @@ func (s *Service) Create(ctx context.Context, name, ownerID string) (*Project, e
 	return dbProjectToProject(dbProj), nil
 }
 
+// CreateFromPlayground creates a project owned by ownerID from a copy of a
+// playground document: doc when the client supplies one, otherwise the shared
+// playground's current document. The copy is validated and given fresh IDs.
+// An empty name keeps the document's.
+func (s *Service) CreateFromPlayground(ctx context.Context, name, ownerID string, doc *document.InDocument) (*Project, error) {
+	if doc == nil {
+		var err error
+		if doc, err = s.currentDocument(ctx, collab.PlaygroundProjectID); err != nil {
+			return nil, err
+		}
+	}
+
+	document.MigrateIDs(doc)
+	if err := doc.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+	if unknown := doc.UnknownObjects(); len(unknown) > 0 {
+		slog.Warn("copying document with objects of unknown types", "version", doc.Project.Version, "objects", len(unknown), "first_type", unknown[0].Type)
+	}
+	document.ReassignIDs(doc)
+
+	if name == "" {
+		name = doc.Project.Name
+	}
+	return s.CreateFromDocument(ctx, name, ownerID, doc)
+}
+
+// currentDocument returns a copy of a project's live document, or its last
+// saved snapshot when no one is editing it.
+func (s *Service) currentDocument(ctx context.Context, projectID string) (*document.InDocument, error) {
+	if s.hub != nil {
+		doc, err := s.hub.Document(projectID)
+		if !errors.Is(err, collab.ErrRoomNotFound) {
+			return doc, err
+		}
+	}
+
+	snap, err := s.queries.GetLatestSnapshot(ctx, projectID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrSnapshotNotFound
+		}
+		return nil, fmt.Errorf("get snapshot: %w", err)
+	}
+	var doc document.InDocument
+	if err := json.Unmarshal(snap.Document, &doc); err != nil {
+		return nil, fmt.Errorf("decode snapshot: %w", err)
+	}
+	return &doc, nil
+}
+
+// ValidateOperations dry-runs ops against a project's current document, or
+// against doc when the caller supplies one, and reports how a live session
+// would answer each (see collab.DryRun). Nothing is saved or broadcast.
+func (s *Service) ValidateOperations(ctx context.Context, projectID, userID string, doc *document.InDocument, ops []collab.Operation) ([]collab.DryRunResult, error) {
+	if err := s.checkMembership(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+
+	inline := doc != nil
+	if !inline {
+		var err error
+		if doc, err = s.currentDocument(ctx, projectID); err != nil {
+			return nil, err
+		}
+	}
+	// Match the IDs the collaboration room sees
+	document.MigrateIDs(doc)
+	if inline {
+		if err := doc.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+		}
+	}
+
+	var policy collab.OpPolicy
+	if s.hub != nil {
+		policy = s.hub.OpPolicy()
+	}
+	return collab.DryRun(doc, ops, policy), nil
+}
+
+// ApplyOperations applies ops in order to a project on behalf of userID, who
+// must be an owner or editor, and returns the answer a live session would
+// give each. While someone is editing the project the operations go through
+// their room and are broadcast to them. Otherwise they are applied to the
+// latest snapshot and saved as the next version; if another writer takes that
+// version first, they are reapplied to the new latest.
+func (s *Service) ApplyOperations(ctx context.Context, projectID, userID string, ops []collab.Operation) ([]collab.OpResult, error) {
+	if err := s.checkEditor(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+
+	var policy collab.OpPolicy
+	if s.hub != nil {
+		results, err := s.hub.SubmitOperations(projectID, userID, ops)
+		if !errors.Is(err, collab.ErrRoomNotFound) {
+			return results, err
+		}
+		policy = s.hub.OpPolicy()
+	}
+
+	// Applying fills in the operations, so each attempt starts from a copy
+	raw, err := json.Marshal(ops)
+	if err != nil {
+		return nil, fmt.Errorf("encode operations: %w", err)
+	}
+	for attempt := 1; ; attempt++ {
+		var attemptOps []collab.Operation
+		if err := json.Unmarshal(raw, &attemptOps); err != nil {
+			return nil, fmt.Errorf("decode operations: %w", err)
+		}
+		results, err := s.applyToSnapshot(ctx, projectID, userID, attemptOps, policy)
+		if !errors.Is(err, ErrVersionConflict) || attempt == maxSaveAttempts {
+			return results, err
+		}
+	}
+}
+
+// applyToSnapshot applies ops to the project's latest snapshot and saves the
+// result as the next version, if any of them changed it. It returns
+// ErrVersionConflict if that version was saved in the meantime.
+func (s *Service) applyToSnapshot(ctx context.Context, projectID, userID string, ops []collab.Operation, policy collab.OpPolicy) ([]collab.OpResult, error) {
+	snap, err := s.queries.GetLatestSnapshot(ctx, projectID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrSnapshotNotFound
+		}
+		return nil, fmt.Errorf("get snapshot: %w", err)
+	}
+	var doc document.InDocument
+	if err := json.Unmarshal(snap.Document, &doc); err != nil {
+		return nil, fmt.Errorf("decode snapshot: %w", err)
+	}
+	// Match the IDs the collaboration room sees
+	document.MigrateIDs(&doc)
+
+	// Catch up with what the last session logged but didn't save
+	seq, replayed, err := s.replayOps(ctx, projectID, &doc, snap.Seq)
+	if err != nil {
+		return nil, err
+	}
+
+	results, changed := collab.ApplyOperations(&doc, ops, userID, policy)
+	if !changed && !replayed {
+		return results, nil
+	}
+
+	docJSON, err := document.MarshalCanonical(&doc)
+	if err != nil {
+		return nil, fmt.Errorf("marshal document: %w", err)
+	}
+	saved, err := s.queries.CreateSnapshot(ctx, dbgen.CreateSnapshotParams{
+		ID:        typeid.NewSnapshotID(),
+		ProjectID: projectID,
+		Version:   snap.Version + 1,
+		Document:  docJSON,
+		Seq:       seq,
+	})
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return nil, ErrVersionConflict
+		}
+		return nil, fmt.Errorf("create snapshot: %w", err)
+	}
+	if replayed {
+		if err := s.ops.Compact(ctx, projectID, seq); err != nil {
+			slog.Warn("failed to compact op log", "project", projectID, "error", err)
+		}
+	}
+
+	s.webhooks.Dispatch(projectID, webhook.EventSnapshotSaved, map[string]interface{}{
+		"snapshotId": saved.ID,
+		"version":    saved.Version,
+	})
+	return results, nil
+}
+
+// replayOps applies the operations logged after seq to doc, as a room loading
+// it would, and returns the sequence reached and whether any were applied.
+// Ops that can't be replayed are discarded.
+func (s *Service) replayOps(ctx context.Context, projectID string, doc *document.InDocument, seq int64) (int64, bool, error) {
+	if s.ops == nil {
+		return seq, false, nil
+	}
+	ops, err := s.ops.Since(ctx, projectID, seq)
+	if err != nil {
+		return 0, false, fmt.Errorf("load op log: %w", err)
+	}
+	if len(ops) == 0 {
+		return seq, false, nil
+	}
+
+	reached, err := collab.NewDocumentStateAt(doc, seq).Replay(ops)
+	if err != nil {
+		slog.Warn("op log replay stopped early", "project", projectID, "seq", reached, "error", err)
+		if err := s.ops.Discard(ctx, projectID, reached); err != nil {
+			return 0, false, fmt.Errorf("discard unreplayable ops: %w", err)
+		}
+	}
+	return reached, reached > seq, nil
+}
+
+// ReplaceAssetContent replaces the content of one of the project's assets,
+// keeping its ID, for projects with linked assets. The caller must be an
+// owner or editor, and the asset must have been uploaded to the project. The asset's record gets the new revision and its URL
+// through an asset.update operation, so a live session reloads the image.
+func (s *Service) ReplaceAssetContent(ctx context.Context, projectID, userID, assetID, contentType string, src io.Reader) (*document.Asset, error) {
+	if err := s.checkEditor(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+	doc, err := s.currentDocument(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if s.assets == nil || !doc.Project.LinkedAssets {
+		return nil, ErrLinkedAssets
+	}
+	record, ok := doc.Assets[assetID]
+	if !ok {
+		return nil, asset.ErrAssetNotFound
+	}
+
+	stored, err := s.assets.Replace(projectID, assetID, contentType, src)
+	if err != nil {
+		return nil, err
+	}
+	record.Type = stored.Type
+	record.URL = stored.URL
+	record.Revision = stored.Revision
+
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("encode asset: %w", err)
+	}
+	results, err := s.ApplyOperations(ctx, projectID, userID, []collab.Operation{{
+		ID:        typeid.NewOpID(),
+		Type:      "asset.update",
+		Timestamp: collab.GetServerTimestamp(),
+		Asset:     raw,
+	}})
+	if err != nil {
+		return nil, err
+	}
+	if nack := results[0].Nack; nack != nil {
+		return nil, fmt.Errorf("update asset record: %s", nack.Reason)
+	}
+	return &record, nil
+}
+
+// ContactSheet renders evenly spaced frames of the project's current document
+// as a grid of labeled thumbnails (see raster.ContactSheet). The caller must
+// be a member. An empty scene ID means the project's first scene.
+func (s *Service) ContactSheet(ctx context.Context, projectID, userID string, opts raster.SheetOptions) (*raster.Sheet, error) {
+	if err := s.checkMembership(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+	doc, err := s.currentDocument(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if opts.SceneID == "" && len(doc.Project.Scenes) > 0 {
+		opts.SceneID = doc.Project.Scenes[0]
+	}
+	if _, ok := doc.Scenes[opts.SceneID]; !ok {
+		return nil, ErrSceneNotFound
+	}
+
+	docHash, err := rendercache.HashDocument(doc)
+	if err != nil {
+		return nil, err
+	}
+	return raster.ContactSheet(s.renders, docHash, doc, opts, s.images)
+}
+
+// RenderFrame renders a frame of a scene of the project's current document
+// as PNG (see raster.FramePNG), width pixels wide; zero means the scene's own
+// width. The caller must be a member. An empty scene ID means the project's
+// first scene.
+func (s *Service) RenderFrame(ctx context.Context, projectID, userID, sceneID string, frame, width int) ([]byte, error) {
+	if err := s.checkMembership(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+	doc, err := s.currentDocument(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if sceneID == "" && len(doc.Project.Scenes) > 0 {
+		sceneID = doc.Project.Scenes[0]
+	}
+	scene, ok := doc.Scenes[sceneID]
+	if !ok || scene.Width <= 0 {
+		return nil, ErrSceneNotFound
+	}
+	scale := 1.0
+	if width > 0 {
+		scale = float64(width) / float64(scene.Width)
+	}
+
+	docHash, err := rendercache.HashDocument(doc)
+	if err != nil {
+		return nil, err
+	}
+	return raster.FramePNG(s.renders, docHash, doc, sceneID, frame, scale, s.images)
+}
+
+// SearchResults is the answer to a project text search.
+type SearchResults struct {
+	Query     string                 `json:"query"`
+	Matches   []document.SearchMatch `json:"matches"`
+	Truncated bool                   `json:"truncated"` // More matches than were returned
+}
+
+// Search finds text in the project's current document, live if a session is
+// open and otherwise as last saved (see document.Search). The caller must be
+// a member. At most limit matches are returned.
+func (s *Service) Search(ctx context.Context, projectID, userID, query string, limit int) (*SearchResults, error) {
+	if err := s.checkMembership(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+	doc, err := s.currentDocument(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	matches, truncated := document.Search(doc, query, limit)
+	return &SearchResults{Query: query, Matches: matches, Truncated: truncated}, nil
+}
+
 func (s *Service) Get(ctx context.Context, projectID, userID string) (*Project, error) {
 	if err := s.checkMembership(ctx, projectID, userID); err != nil {
 		return nil, err
@@ -136,7 +574,18 @@ func (s *Service) Delete(ctx context.Context, projectID, userID string) error {
 	return s.queries.DeleteProject(ctx, projectID)
 }
 
-func (s *Service) InviteByEmail(ctx context.Context, projectID, ownerID, inviteeEmail string) error {
+// InviteByEmail adds the user with the given email to the project as an
+// editor or, if roleName is "viewer", as a read-only viewer. Only the owner
+// may invite, and not past the member limit.
+func (s *Service) InviteByEmail(ctx context.Context, projectID, ownerID, inviteeEmail, roleName string) error {
+	role := dbgen.ProjectRoleEditor
+	if roleName != "" {
+		role = dbgen.ProjectRole(roleName)
+	}
+	if role != dbgen.ProjectRoleEditor && role != dbgen.ProjectRoleViewer {
+		return ErrInvalidRole
+	}
+
 	// Verify the requester is the owner
 	dbProj, err := s.queries.GetProject(ctx, projectID)
 	if err != nil {
@@ -155,16 +604,40 @@ func (s *Service) InviteByEmail(ctx context.Context, projectID, ownerID, invitee
 	invitee, err := s.queries.GetUserByEmail(ctx, inviteeEmail)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return errors.New("user not found")
+			return ErrUserNotFound
 		}
 		return fmt.Errorf("find user: %w", err)
 	}
 
-	return s.queries.AddProjectMember(ctx, dbgen.AddProjectMemberParams{
+	if s.maxMembers > 0 {
+		members, err := s.queries.ListProjectMembers(ctx, projectID)
+		if err != nil {
+			return fmt.Errorf("list members: %w", err)
+		}
+		if len(members) >= s.maxMembers {
+			return ErrMemberLimit
+		}
+	}
+
+	err = s.queries.AddProjectMember(ctx, dbgen.AddProjectMemberParams{
 		ProjectID: projectID,
 		UserID:    invitee.ID,
-		Role:      dbgen.ProjectRoleEditor,
+		Role:      role,
 	})
+	if err != nil {
+		return err
+	}
+
+	s.webhooks.Dispatch(projectID, webhook.EventMemberAdded, map[string]string{
+		"userId":    invitee.ID,
+		"role":      string(role),
+		"invitedBy": ownerID,
+	})
+	s.notes.Notify(projectID, notification.TypeInvited, ownerID, map[string]string{
+		"projectName": dbProj.Name,
+		"role":        string(role),
+	}, invitee.ID)
+	return nil
 }
 
 func (s *Service) ListMembers(ctx context.Context, projectID, userID string) ([]Member, error) {
@@ -207,10 +680,22 @@ func (s *Service) RemoveMember(ctx context.Context, projectID, ownerID, targetUs
 		return errors.New("cannot remove project owner")
 	}
 
-	return s.queries.RemoveProjectMember(ctx, dbgen.RemoveProjectMemberParams{
+	err = s.queries.RemoveProjectMember(ctx, dbgen.RemoveProjectMemberParams{
 		ProjectID: projectID,
 		UserID:    targetUserID,
 	})
+	if err != nil {
+		return err
+	}
+
+	s.webhooks.Dispatch(projectID, webhook.EventMemberRemoved, map[string]string{
+		"userId":    targetUserID,
+		"removedBy": ownerID,
+	})
+	s.notes.Notify(projectID, notification.TypeRemoved, ownerID, map[string]string{
+		"projectName": dbProj.Name,
+	}, targetUserID)
+	return nil
 }
 
 func (s *Service) GetLatestSnapshot(ctx context.Context, projectID, userID string) (json.RawMessage, error) {
@@ -221,12 +706,108 @@ func (s *Service) GetLatestSnapshot(ctx context.Context, projectID, userID strin
 	snap, err := s.queries.GetLatestSnapshot(ctx, projectID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, ErrNotFound
+			return nil, ErrSnapshotNotFound
 		}
 		return nil, fmt.Errorf("get snapshot: %w", err)
 	}
+	return snapshotDocument(snap.Document)
+}
+
+// GetSnapshot returns the document saved as a version of the project, for
+// looking back through its history.
+func (s *Service) GetSnapshot(ctx context.Context, projectID, userID string, version int) (json.RawMessage, error) {
+	if err := s.checkMembership(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+	if version < 1 || version > math.MaxInt32 {
+		return nil, ErrVersionNotFound
+	}
 
-	return snap.Document, nil
+	snap, err := s.queries.GetSnapshotByVersion(ctx, dbgen.GetSnapshotByVersionParams{
+		ProjectID: projectID,
+		Version:   int32(version),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrVersionNotFound
+		}
+		return nil, fmt.Errorf("get snapshot: %w", err)
+	}
+	return snapshotDocument(snap.Document)
+}
+
+// snapshotDocument returns a stored snapshot's document with the IDs the
+// collaboration room sees (see document.MigrateIDs).
+func snapshotDocument(raw json.RawMessage) (json.RawMessage, error) {
+	var doc document.InDocument
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("decode snapshot: %w", err)
+	}
+	if !document.MigrateIDs(&doc) {
+		return raw, nil
+	}
+	return document.MarshalCanonical(&doc)
+}
+
+// ListSnapshots returns a page of the project's snapshots, newest first:
+// at most limit of them, skipping the offset newest.
+func (s *Service) ListSnapshots(ctx context.Context, projectID, userID string, limit, offset int) ([]Snapshot, error) {
+	if err := s.checkMembership(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.queries.ListSnapshots(ctx, dbgen.ListSnapshotsParams{
+		ProjectID: projectID,
+		Limit:     int32(limit),
+		Offset:    int32(offset),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list snapshots: %w", err)
+	}
+
+	snapshots := make([]Snapshot, len(rows))
+	for i, row := range rows {
+		snapshots[i] = Snapshot{
+			ID:            row.ID,
+			Version:       int(row.Version),
+			Seq:           row.Seq,
+			Label:         row.Label,
+			Safety:        strings.HasPrefix(row.Label, collab.BackupLabelPrefix),
+			DocumentBytes: int(row.DocumentBytes),
+			CreatedAt:     row.CreatedAt.Time.Format("2006-01-02T15:04:05Z"),
+		}
+	}
+	return snapshots, nil
+}
+
+// Recording returns the operations applied in the project's live session
+// between fromSeq and toSeq, with the document they start from. When
+// downsample is set, transform drags are thinned to one op per object per
+// collab.DefaultDownsampleWindow.
+func (s *Service) Recording(ctx context.Context, projectID, userID string, fromSeq, toSeq int64, downsample bool) (*collab.Recording, error) {
+	if err := s.checkMembership(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+	if s.hub == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	rec, err := s.hub.Recording(projectID, fromSeq, toSeq)
+	if err != nil {
+		if errors.Is(err, collab.ErrRoomNotFound) {
+			return nil, ErrRoomNotFound
+		}
+		return nil, fmt.Errorf("get recording: %w", err)
+	}
+	if downsample {
+		rec.Operations = collab.DownsampleTransforms(rec.Operations, collab.DefaultDownsampleWindow)
+	}
+	return rec, nil
+}
+
+// CheckMember returns ErrNotMember unless userID is a member of the project.
+func (s *Service) CheckMember(ctx context.Context, projectID, userID string) error {
+	return s.checkMembership(ctx, projectID, userID)
 }
 
 func (s *Service) checkMembership(ctx context.Context, projectID, userID string) error {
@@ -243,6 +824,32 @@ func (s *Service) checkMembership(ctx context.Context, projectID, userID string)
 	return nil
 }
 
+// checkEditor verifies userID is a member of the project allowed to edit it.
+func (s *Service) checkEditor(ctx context.Context, projectID, userID string) error {
+	member, err := s.queries.GetProjectMember(ctx, dbgen.GetProjectMemberParams{
+		ProjectID: projectID,
+		UserID:    userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotMember
+		}
+		return fmt.Errorf("check membership: %w", err)
+	}
+	if member.Role == dbgen.ProjectRoleViewer {
+		return ErrNotEditor
+	}
+	return nil
+}
+
+func isDuplicateKeyError(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "23505" // unique_violation
+	}
+	return false
+}
+
 func dbProjectToProject(p dbgen.Project) *Project {
 	return &Project{
 		ID:        p.ID,
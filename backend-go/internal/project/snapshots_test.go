@@ -0,0 +1,142 @@
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/inamate/inamate/backend-go/internal/auth"
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/httperr"
+)
+
+// listSnapshots requests the project's snapshot listing as user_1.
+func listSnapshots(h *Handler, projectID, query string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/api/projects/"+projectID+"/snapshots?"+query, nil)
+	r = mux.SetURLVars(r, map[string]string{"projectId": projectID})
+	r = r.WithContext(context.WithValue(r.Context(), auth.UserIDKey, "user_1"))
+	rec := httptest.NewRecorder()
+	h.ListSnapshots(rec, r)
+	return rec
+}
+
+func TestListSnapshotsFlagsSafety(t *testing.T) {
+	service, db := newFakeService()
+	at := pgtype.Timestamptz{Time: time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC), Valid: true}
+	db.many = map[string][]echoRow{"ListSnapshots": {
+		{"snap_3", int32(3), int64(40), "", int32(2048), at},
+		{"snap_2", int32(2), int64(31), "pre: scene.delete by Ada", int32(4096), at},
+		{"snap_1", int32(1), int64(0), "imported", int32(512), at},
+	}}
+	h := NewHandler(service)
+
+	rec := listSnapshots(h, "proj_1", "limit=3")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var got []Snapshot
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []Snapshot{
+		{ID: "snap_3", Version: 3, Seq: 40, DocumentBytes: 2048, CreatedAt: "2026-03-01T12:30:00Z"},
+		{ID: "snap_2", Version: 2, Seq: 31, Label: "pre: scene.delete by Ada", Safety: true, DocumentBytes: 4096, CreatedAt: "2026-03-01T12:30:00Z"},
+		{ID: "snap_1", Version: 1, Label: "imported", DocumentBytes: 512, CreatedAt: "2026-03-01T12:30:00Z"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("%d snapshots, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("snapshot %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if runs := db.ran("ListSnapshots"); len(runs) != 1 || runs[0][1] != int32(3) || runs[0][2] != int32(0) {
+		t.Errorf("listed with %v, want limit 3 from the newest", runs)
+	}
+	// Later pages skip the newest
+	if rec := listSnapshots(h, "proj_1", "limit=3&offset=3"); rec.Code != http.StatusOK {
+		t.Fatalf("second page: status %d: %s", rec.Code, rec.Body)
+	}
+	if runs := db.ran("ListSnapshots"); len(runs) != 2 || runs[1][1] != int32(3) || runs[1][2] != int32(3) {
+		t.Errorf("listed with %v, want limit 3 offset 3", runs)
+	}
+
+	for _, query := range []string{"limit=0", "limit=501", "limit=x", "offset=-1", "offset=x"} {
+		rec := listSnapshots(h, "proj_1", query)
+		if e := decodeError(t, rec); rec.Code != http.StatusBadRequest || e.Code != httperr.CodeValidationFailed {
+			t.Errorf("%s: status %d code %q, want 400 %s", query, rec.Code, e.Code, httperr.CodeValidationFailed)
+		}
+	}
+}
+
+// getSnapshot requests a version of the project's document as user_1.
+func getSnapshot(h *Handler, projectID, version string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/api/projects/"+projectID+"/snapshots/"+version, nil)
+	r = mux.SetURLVars(r, map[string]string{"projectId": projectID, "version": version})
+	r = r.WithContext(context.WithValue(r.Context(), auth.UserIDKey, "user_1"))
+	rec := httptest.NewRecorder()
+	h.GetSnapshot(rec, r)
+	return rec
+}
+
+func TestGetSnapshot(t *testing.T) {
+	service, db := newFakeService()
+	p := newOpsProject()
+	db.rows = map[string][]pgx.Row{"GetSnapshotByVersion": {snapshotRow(t, p.doc, 2)}}
+	h := NewHandler(service)
+
+	rec := getSnapshot(h, p.id, "2")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var doc document.InDocument
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Project.ID != p.id || doc.Scenes[doc.Project.Scenes[0]].Root != p.rootID {
+		t.Errorf("document %+v, want the stored one", doc.Project)
+	}
+	if runs := db.ran("GetSnapshotByVersion"); len(runs) != 1 || runs[0][0] != p.id || runs[0][1] != int32(2) {
+		t.Errorf("fetched with %v, want version 2 of %s", runs, p.id)
+	}
+}
+
+func TestGetSnapshotOutOfRange(t *testing.T) {
+	tests := []struct {
+		version string
+		queried bool // Whether the version is worth looking up
+	}{
+		{"0", false},
+		{"-3", false},
+		{"2147483648", false},
+		{"7", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			service, db := newFakeService()
+			db.rows = map[string][]pgx.Row{"GetSnapshotByVersion": {errRow{pgx.ErrNoRows}}}
+
+			rec := getSnapshot(NewHandler(service), "proj_1", tt.version)
+			if e := decodeError(t, rec); rec.Code != http.StatusNotFound || e.Code != httperr.CodeSnapshotNotFound {
+				t.Errorf("status %d code %q, want 404 %s", rec.Code, e.Code, httperr.CodeSnapshotNotFound)
+			}
+			if queried := len(db.ran("GetSnapshotByVersion")) > 0; queried != tt.queried {
+				t.Errorf("queried = %v, want %v", queried, tt.queried)
+			}
+		})
+	}
+
+	service, _ := newFakeService()
+	rec := getSnapshot(NewHandler(service), "proj_1", "latest")
+	if e := decodeError(t, rec); rec.Code != http.StatusBadRequest || e.Code != httperr.CodeValidationFailed {
+		t.Errorf("non-numeric version: status %d code %q, want 400 %s", rec.Code, e.Code, httperr.CodeValidationFailed)
+	}
+}
@@ -0,0 +1,277 @@
+// Package raster renders the engine's draw commands to images on the server,
+// matching what the editor's Canvas2D renderer draws closely enough for
+// thumbnails and previews. Paths are filled with the nonzero rule and stroked
+// with miter joins and butt caps, as Canvas2D does by default. Text is not
+// drawn: the server has no fonts.
+package raster
+
+import (
+	"errors"
+	"image"
+	imgcolor "image/color"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/inamate/inamate/backend-go/internal/color"
+	"github.com/inamate/inamate/backend-go/internal/engine"
+)
+
+// miterLimit is Canvas2D's default: longer miters are beveled.
+const miterLimit = 10
+
+// ImageLoader returns the decoded image of an asset.
+type ImageLoader func(assetID string) (image.Image, error)
+
+// AssetDir loads assets stored as <dir>/<asset ID>.png or .jpg, as the asset
+// handler stores them.
+func AssetDir(dir string) ImageLoader {
+	return func(assetID string) (image.Image, error) {
+		base := filepath.Join(dir, filepath.Base(assetID))
+		f, err := os.Open(base + ".png")
+		if errors.Is(err, os.ErrNotExist) {
+			f, err = os.Open(base + ".jpg")
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		img, _, err := image.Decode(f)
+		return img, err
+	}
+}
+
+// Canvas is an image that draw commands are executed on. Commands are in
+// scene coordinates, scaled by the canvas's scale.
+type Canvas struct {
+	img    *image.RGBA
+	base   engine.Matrix2D
+	clip   *mask   // nil when nothing is clipped
+	saved  []*mask // clips to return to on "restore"
+	load   ImageLoader
+	images map[string]image.Image // loaded images, nil for ones that failed
+}
+
+// NewCanvas returns a transparent width×height canvas. images loads the
+// assets of image commands; when nil, images aren't drawn.
+func NewCanvas(width, height int, scale float64, images ImageLoader) *Canvas {
+	return &Canvas{
+		img:    image.NewRGBA(image.Rect(0, 0, width, height)),
+		base:   engine.Scale(scale, scale),
+		load:   images,
+		images: make(map[string]image.Image),
+	}
+}
+
+// Image returns the canvas's pixels.
+func (c *Canvas) Image() *image.RGBA {
+	return c.img
+}
+
+// Clear fills the whole canvas with col.
+func (c *Canvas) Clear(col color.RGBA) {
+	fill := imgcolor.RGBA{
+		R: uint8(uint16(col.R) * uint16(col.A) / 255),
+		G: uint8(uint16(col.G) * uint16(col.A) / 255),
+		B: uint8(uint16(col.B) * uint16(col.A) / 255),
+		A: col.A,
+	}
+	pix := c.img.Pix
+	for i := 0; i < len(pix); i += 4 {
+		pix[i], pix[i+1], pix[i+2], pix[i+3] = fill.R, fill.G, fill.B, fill.A
+	}
+}
+
+// Draw executes draw commands in order.
+func (c *Canvas) Draw(cmds []engine.DrawCommand) {
+	for i := range cmds {
+		cmd := &cmds[i]
+		switch cmd.Op {
+		case "save":
+			c.saved = append(c.saved, c.clip)
+		case "restore":
+			if n := len(c.saved); n > 0 {
+				c.clip = c.saved[n-1]
+				c.saved = c.saved[:n-1]
+			}
+		case "clip":
+			c.clipTo(cmd)
+		case "path":
+			c.drawPath(cmd)
+		case "image":
+			c.drawImage(cmd)
+		}
+	}
+}
+
+// transform returns a command's transform to canvas pixels.
+func (c *Canvas) transform(cmd *engine.DrawCommand) engine.Matrix2D {
+	m := engine.Identity()
+	if len(cmd.Transform) == 6 {
+		copy(m[:], cmd.Transform)
+	}
+	return c.base.Multiply(m)
+}
+
+func (c *Canvas) clipTo(cmd *engine.DrawCommand) {
+	m := c.transform(cmd)
+	area := c.coverage(fillPolygons(engine.FlattenPath(cmd.Path), m))
+	c.clip = area.intersect(c.clip)
+}
+
+func (c *Canvas) drawPath(cmd *engine.DrawCommand) {
+	m := c.transform(cmd)
+	lines := engine.FlattenPath(cmd.Path)
+	if cmd.FillGradient != nil {
+		c.compositeGradient(c.coverage(fillPolygons(lines, m)), cmd.FillGradient, m, cmd.Opacity)
+	} else if paint, ok := parsePaint(cmd.Fill, cmd.FillAlpha, cmd.Opacity); ok {
+		c.composite(c.coverage(fillPolygons(lines, m)), paint)
+	}
+	if paint, ok := parsePaint(cmd.Stroke, cmd.StrokeAlpha, cmd.Opacity); ok && cmd.StrokeWidth > 0 {
+		c.composite(c.coverage(strokePolygons(lines, cmd.StrokeWidth/2, m)), paint)
+	}
+}
+
+// paint is a premultiplied color with alpha in [0, 1].
+type paint struct {
+	r, g, b, a float64
+}
+
+// parsePaint resolves a command's paint, or reports false if it paints
+// nothing.
+func parsePaint(value string, alpha *float64, opacity float64) (paint, bool) {
+	if value == "" || value == color.None {
+		return paint{}, false
+	}
+	col, err := color.Parse(value)
+	if err != nil {
+		return paint{}, false
+	}
+	a := col.Alpha() * opacity
+	if alpha != nil {
+		a *= *alpha
+	}
+	if a <= 0 {
+		return paint{}, false
+	}
+	return paint{
+		r: float64(col.R) * a,
+		g: float64(col.G) * a,
+		b: float64(col.B) * a,
+		a: a,
+	}, true
+}
+
+// composite paints p through a coverage mask, within the current clip.
+func (c *Canvas) composite(area *mask, p paint) {
+	area = area.intersect(c.clip)
+	if area == nil {
+		return
+	}
+	stride := c.img.Stride
+	for y := 0; y < area.h; y++ {
+		row := area.a[y*area.w : (y+1)*area.w]
+		off := (area.y+y)*stride + area.x*4
+		for x, cov := range row {
+			if cov <= 0 {
+				continue
+			}
+			k := float64(min(cov, 1))
+			blend(c.img.Pix[off+x*4:off+x*4+4], p.r*k, p.g*k, p.b*k, p.a*k)
+		}
+	}
+}
+
+// blend composites a premultiplied source (channels 0-255, alpha 0-1) over
+// a premultiplied pixel.
+func blend(dst []uint8, r, g, b, a float64) {
+	keep := 1 - a
+	dst[0] = uint8(math.Round(r + float64(dst[0])*keep))
+	dst[1] = uint8(math.Round(g + float64(dst[1])*keep))
+	dst[2] = uint8(math.Round(b + float64(dst[2])*keep))
+	dst[3] = uint8(math.Round(255*a + float64(dst[3])*keep))
+}
+
+// drawImage draws an image asset into its natural size in local space,
+// sampling the nearest source pixel.
+func (c *Canvas) drawImage(cmd *engine.DrawCommand) {
+	src := c.image(cmd.ImageAssetID)
+	if src == nil || cmd.ImageWidth <= 0 || cmd.ImageHeight <= 0 || cmd.Opacity <= 0 {
+		return
+	}
+	m := c.transform(cmd)
+	if m.Determinant() == 0 {
+		return
+	}
+	inv := m.Invert()
+
+	b := src.Bounds()
+	sx, sy, sw, sh := float64(b.Min.X), float64(b.Min.Y), float64(b.Dx()), float64(b.Dy())
+	if len(cmd.ImageSource) == 4 {
+		sx, sy = sx+cmd.ImageSource[0], sy+cmd.ImageSource[1]
+		sw, sh = cmd.ImageSource[2], cmd.ImageSource[3]
+	}
+
+	corners := [][2]float64{{0, 0}, {cmd.ImageWidth, 0}, {cmd.ImageWidth, cmd.ImageHeight}, {0, cmd.ImageHeight}}
+	x0, y0, x1, y1 := c.pixelBounds(transformPoints(corners, m))
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			if !c.clip.covers(x, y) {
+				continue
+			}
+			u, v := inv.TransformPoint(float64(x)+0.5, float64(y)+0.5)
+			if u < 0 || v < 0 || u >= cmd.ImageWidth || v >= cmd.ImageHeight {
+				continue
+			}
+			px := int(math.Floor(sx + u/cmd.ImageWidth*sw))
+			py := int(math.Floor(sy + v/cmd.ImageHeight*sh))
+			if !(image.Point{X: px, Y: py}.In(b)) {
+				continue
+			}
+			r, g, bl, a := src.At(px, py).RGBA()
+			k := cmd.Opacity * float64(c.clip.at(x, y)) / 0xffff
+			off := c.img.PixOffset(x, y)
+			blend(c.img.Pix[off:off+4], float64(r)*k*255, float64(g)*k*255, float64(bl)*k*255, float64(a)*k)
+		}
+	}
+}
+
+// image returns a loaded asset image, loading it on first use.
+func (c *Canvas) image(assetID string) image.Image {
+	if c.load == nil || assetID == "" {
+		return nil
+	}
+	img, ok := c.images[assetID]
+	if !ok {
+		img, _ = c.load(assetID)
+		c.images[assetID] = img
+	}
+	return img
+}
+
+// pixelBounds returns the canvas pixels a set of points spans, clamped to
+// the canvas.
+func (c *Canvas) pixelBounds(pts [][2]float64) (x0, y0, x1, y1 int) {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range pts {
+		minX, maxX = math.Min(minX, p[0]), math.Max(maxX, p[0])
+		minY, maxY = math.Min(minY, p[1]), math.Max(maxY, p[1])
+	}
+	size := c.img.Bounds().Size()
+	x0 = max(0, int(math.Floor(minX)))
+	y0 = max(0, int(math.Floor(minY)))
+	x1 = min(size.X, int(math.Ceil(maxX)))
+	y1 = min(size.Y, int(math.Ceil(maxY)))
+	return x0, y0, x1, y1
+}
+
+func transformPoints(pts [][2]float64, m engine.Matrix2D) [][2]float64 {
+	out := make([][2]float64, len(pts))
+	for i, p := range pts {
+		out[i][0], out[i][1] = m.TransformPoint(p[0], p[1])
+	}
+	return out
+}
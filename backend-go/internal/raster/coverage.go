@@ -0,0 +1,274 @@
+package raster
+
+import (
+	"math"
+	"sort"
+
+	"github.com/inamate/inamate/backend-go/internal/engine"
+)
+
+// subSamples is how many scanlines each pixel row is sampled at. Coverage
+// along a scanline is exact, so this only affects near-horizontal edges.
+const subSamples = 4
+
+// mask is the coverage, in [0, 1], of a w×h rectangle of canvas pixels at
+// (x, y).
+type mask struct {
+	x, y, w, h int
+	a          []float32
+}
+
+// at returns the coverage of a canvas pixel. A nil mask covers everything.
+func (m *mask) at(x, y int) float32 {
+	if m == nil {
+		return 1
+	}
+	x, y = x-m.x, y-m.y
+	if x < 0 || y < 0 || x >= m.w || y >= m.h {
+		return 0
+	}
+	return min(m.a[y*m.w+x], 1)
+}
+
+// covers reports whether a canvas pixel is at least partly covered.
+func (m *mask) covers(x, y int) bool {
+	return m.at(x, y) > 0
+}
+
+// intersect returns the coverage of both m and other, where a nil other
+// covers everything. It returns nil only when m is nil.
+func (m *mask) intersect(other *mask) *mask {
+	if other == nil || m == nil {
+		if m == nil {
+			return other
+		}
+		return m
+	}
+	x0, y0 := max(m.x, other.x), max(m.y, other.y)
+	x1, y1 := min(m.x+m.w, other.x+other.w), min(m.y+m.h, other.y+other.h)
+	out := &mask{x: x0, y: y0, w: max(0, x1-x0), h: max(0, y1-y0)}
+	out.a = make([]float32, out.w*out.h)
+	for y := 0; y < out.h; y++ {
+		for x := 0; x < out.w; x++ {
+			out.a[y*out.w+x] = m.at(x0+x, y0+y) * other.at(x0+x, y0+y)
+		}
+	}
+	return out
+}
+
+type edge struct {
+	x0, y0, x1, y1 float64 // y0 < y1
+	dir            int     // +1 downwards, -1 upwards
+}
+
+type crossing struct {
+	x   float64
+	dir int
+}
+
+// coverage rasterizes polygons, in canvas pixels, with the nonzero winding
+// rule.
+func (c *Canvas) coverage(polys [][][2]float64) *mask {
+	var edges []edge
+	var pts [][2]float64
+	for _, poly := range polys {
+		for i, p := range poly {
+			q := poly[(i+1)%len(poly)]
+			switch {
+			case p[1] < q[1]:
+				edges = append(edges, edge{p[0], p[1], q[0], q[1], 1})
+			case p[1] > q[1]:
+				edges = append(edges, edge{q[0], q[1], p[0], p[1], -1})
+			}
+		}
+		pts = append(pts, poly...)
+	}
+	if len(edges) == 0 {
+		return &mask{}
+	}
+	x0, y0, x1, y1 := c.pixelBounds(pts)
+	m := &mask{x: x0, y: y0, w: max(0, x1-x0), h: max(0, y1-y0)}
+	m.a = make([]float32, m.w*m.h)
+
+	var xs []crossing
+	for y := 0; y < m.h; y++ {
+		row := m.a[y*m.w : (y+1)*m.w]
+		for s := 0; s < subSamples; s++ {
+			sy := float64(y0+y) + (float64(s)+0.5)/subSamples
+			xs = xs[:0]
+			for _, e := range edges {
+				if sy < e.y0 || sy >= e.y1 {
+					continue
+				}
+				t := (sy - e.y0) / (e.y1 - e.y0)
+				xs = append(xs, crossing{e.x0 + t*(e.x1-e.x0) - float64(x0), e.dir})
+			}
+			sort.Slice(xs, func(i, j int) bool { return xs[i].x < xs[j].x })
+
+			winding := 0
+			var start float64
+			for _, cr := range xs {
+				if winding == 0 {
+					start = cr.x
+				}
+				winding += cr.dir
+				if winding == 0 {
+					addSpan(row, start, cr.x, 1.0/subSamples)
+				}
+			}
+		}
+	}
+	return m
+}
+
+// addSpan adds weight times the fraction of each pixel the span [xa, xb)
+// covers.
+func addSpan(row []float32, xa, xb float64, weight float32) {
+	xa, xb = math.Max(xa, 0), math.Min(xb, float64(len(row)))
+	if xb <= xa {
+		return
+	}
+	ia, ib := int(xa), int(xb)
+	if ia == ib {
+		row[ia] += float32(xb-xa) * weight
+		return
+	}
+	row[ia] += float32(float64(ia+1)-xa) * weight
+	for i := ia + 1; i < ib; i++ {
+		row[i] += weight
+	}
+	if ib < len(row) {
+		row[ib] += float32(xb-float64(ib)) * weight
+	}
+}
+
+// fillPolygons returns the polygons that fill a path's subpaths, each closed
+// implicitly as Canvas2D's fill does.
+func fillPolygons(lines []engine.Polyline, m engine.Matrix2D) [][][2]float64 {
+	polys := make([][][2]float64, 0, len(lines))
+	for _, line := range lines {
+		polys = append(polys, transformPoints(line.Points, m))
+	}
+	return polys
+}
+
+// strokePolygons returns polygons whose nonzero union is the stroke of a
+// path's subpaths, half wide on each side. The outline is built in local
+// space, so a scaled stroke stays the width it was drawn at.
+func strokePolygons(lines []engine.Polyline, half float64, m engine.Matrix2D) [][][2]float64 {
+	var polys [][][2]float64
+	add := func(poly ...[2]float64) {
+		polys = append(polys, transformPoints(orient(poly), m))
+	}
+
+	for _, line := range lines {
+		pts := dedupe(line.Points, line.Closed)
+		n := len(pts) - 1
+		if line.Closed {
+			n = len(pts)
+		}
+		if n < 1 {
+			continue
+		}
+		for i := 0; i < n; i++ {
+			p, q := pts[i], pts[(i+1)%len(pts)]
+			nx, ny := normal(p, q, half)
+			add(
+				[2]float64{p[0] + nx, p[1] + ny},
+				[2]float64{q[0] + nx, q[1] + ny},
+				[2]float64{q[0] - nx, q[1] - ny},
+				[2]float64{p[0] - nx, p[1] - ny},
+			)
+		}
+
+		// Joins at each vertex between two segments
+		for i := 1; i < len(pts); i++ {
+			if i == len(pts)-1 && !line.Closed {
+				break
+			}
+			if join := miterJoin(pts[i-1], pts[i], pts[(i+1)%len(pts)], half); join != nil {
+				add(join...)
+			}
+		}
+		if line.Closed && len(pts) > 2 {
+			if join := miterJoin(pts[len(pts)-1], pts[0], pts[1], half); join != nil {
+				add(join...)
+			}
+		}
+	}
+	return polys
+}
+
+// dedupe drops repeated points, and a closed line's last point when it
+// returns to the first.
+func dedupe(pts [][2]float64, closed bool) [][2]float64 {
+	out := make([][2]float64, 0, len(pts))
+	for _, p := range pts {
+		if len(out) == 0 || p != out[len(out)-1] {
+			out = append(out, p)
+		}
+	}
+	if closed && len(out) > 1 && out[0] == out[len(out)-1] {
+		out = out[:len(out)-1]
+	}
+	return out
+}
+
+// normal returns the left normal of the segment p→q, of length half.
+func normal(p, q [2]float64, half float64) (float64, float64) {
+	dx, dy := q[0]-p[0], q[1]-p[1]
+	l := math.Hypot(dx, dy)
+	return -dy / l * half, dx / l * half
+}
+
+// miterJoin returns the polygon filling the outside of the corner at v
+// between the segments a→v and v→b: a miter, or a bevel where the miter
+// would be longer than miterLimit stroke widths.
+func miterJoin(a, v, b [2]float64, half float64) [][2]float64 {
+	n1x, n1y := normal(a, v, half)
+	n2x, n2y := normal(v, b, half)
+	// Unit directions are the normals turned back a quarter
+	d1x, d1y := n1y/half, -n1x/half
+	d2x, d2y := n2y/half, -n2x/half
+	if math.Abs(d1x*d2y-d1y*d2x) < 1e-9 && d1x*d2x+d1y*d2y > 0 {
+		return nil // Straight on
+	}
+
+	// The outside is away from the direction of the turn
+	side := 1.0
+	if (n1x+n2x)*(d2x-d1x)+(n1y+n2y)*(d2y-d1y) > 0 {
+		side = -1
+	}
+	p1 := [2]float64{v[0] + side*n1x, v[1] + side*n1y}
+	p2 := [2]float64{v[0] + side*n2x, v[1] + side*n2y}
+
+	mx, my := n1x+n2x, n1y+n2y
+	ml := math.Hypot(mx, my)
+	if ml == 0 {
+		return [][2]float64{v, p1, p2}
+	}
+	// cos of half the angle between the normals
+	cosHalf := (mx*n1x + my*n1y) / (ml * half)
+	if cosHalf <= 0 || 1/cosHalf > miterLimit {
+		return [][2]float64{v, p1, p2}
+	}
+	length := half / cosHalf
+	tip := [2]float64{v[0] + side*mx/ml*length, v[1] + side*my/ml*length}
+	return [][2]float64{v, p1, tip, p2}
+}
+
+// orient returns poly wound clockwise on screen (positive signed area with y
+// down), so overlapping pieces of a stroke add up instead of cancelling.
+func orient(poly [][2]float64) [][2]float64 {
+	area := 0.0
+	for i, p := range poly {
+		q := poly[(i+1)%len(poly)]
+		area += p[0]*q[1] - q[0]*p[1]
+	}
+	if area < 0 {
+		for i, j := 0, len(poly)-1; i < j; i, j = i+1, j-1 {
+			poly[i], poly[j] = poly[j], poly[i]
+		}
+	}
+	return poly
+}
@@ -0,0 +1,160 @@
+package raster
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/color"
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/engine"
+	"github.com/inamate/inamate/backend-go/internal/rendercache"
+)
+
+func TestFramePNGCached(t *testing.T) {
+	doc := document.NewSampleDocument("proj_1")
+	sceneID := doc.Project.Scenes[0]
+	hash, err := rendercache.HashDocument(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, err := rendercache.New(1<<20, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := FramePNG(cache, hash, doc, sceneID, 0, 0.25, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := FramePNG(cache, hash, doc, sceneID, 0, 0.25, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := cache.Stats(); s.Renders != 1 || s.Hits != 1 {
+		t.Errorf("stats %+v, want one render and one hit", s)
+	}
+	if !bytes.Equal(first, second) {
+		t.Error("cached frame differs from the rendered one")
+	}
+	img, err := png.Decode(bytes.NewReader(second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 320 || b.Dy() != 180 {
+		t.Errorf("frame %dx%d, want 320x180", b.Dx(), b.Dy())
+	}
+}
+
+func TestCulledFrameMatchesInView(t *testing.T) {
+	doc := document.NewSampleDocument("proj_1")
+	sceneID := doc.Project.Scenes[0]
+	root := doc.Objects[doc.Scenes[sceneID].Root]
+	for i := range 12 {
+		id := fmt.Sprintf("obj_rect_%d", i)
+		root.Children = append(root.Children, id)
+		doc.Objects[id] = document.ObjectNode{
+			ID:        id,
+			Type:      document.ObjectTypeShapeRect,
+			Parent:    &root.ID,
+			Children:  []string{},
+			Transform: document.Transform{X: float64(i * 110), Y: float64(i * 55), R: float64(i * 10), SX: 1, SY: 1},
+			Style:     document.Style{Fill: "#3366cc", Stroke: "#000000", StrokeWidth: 4, Opacity: 1},
+			Visible:   true,
+			Data:      json.RawMessage(`{"width":120,"height":60}`),
+		}
+	}
+	doc.Objects[root.ID] = root
+
+	sg := engine.BuildSceneGraph(doc, sceneID, 0, doc.Project.RootTimeline, true, nil)
+	view := engine.Rect{X: 300, Y: 150, Width: 400, Height: 250}
+	culled, count := engine.CompileDrawCommandsInView(sg, view)
+	if count == 0 {
+		t.Fatal("nothing culled")
+	}
+
+	draw := func(cmds []engine.DrawCommand) *image.RGBA {
+		canvas := NewCanvas(1280, 720, 1, nil)
+		canvas.Clear(color.RGBA{R: 255, G: 255, B: 255, A: 255})
+		canvas.Draw(cmds)
+		return canvas.Image()
+	}
+	full, partial := draw(engine.CompileDrawCommands(sg)), draw(culled)
+	for y := int(view.Y); y < int(view.Y+view.Height); y++ {
+		for x := int(view.X); x < int(view.X+view.Width); x++ {
+			if a, b := full.RGBAAt(x, y), partial.RGBAAt(x, y); a != b {
+				t.Fatalf("pixel (%d, %d) is %v culled, %v unculled", x, y, b, a)
+			}
+		}
+	}
+}
+
+func TestFrameSampleDocument(t *testing.T) {
+	doc := document.NewSampleDocument("proj_1")
+	sceneID := doc.Project.Scenes[0]
+
+	img, err := Frame(doc, sceneID, 0, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 1280 || b.Dy() != 720 {
+		t.Fatalf("frame %dx%d, want 1280x720", b.Dx(), b.Dy())
+	}
+	for _, p := range []image.Point{{0, 0}, {1279, 719}, {640, 360}} {
+		if got := img.RGBAAt(p.X, p.Y); got.R != 255 || got.G != 255 || got.B != 255 || got.A != 255 {
+			t.Errorf("pixel %v is %v, want the white background", p, got)
+		}
+	}
+
+	// A half-transparent group moved by 100 holding a red square moved by 50
+	root := doc.Objects[doc.Scenes[sceneID].Root]
+	groupID, rectID := "obj_group", "obj_square"
+	root.Children = append(root.Children, groupID)
+	doc.Objects[root.ID] = root
+	doc.Objects[groupID] = document.ObjectNode{
+		ID: groupID, Type: document.ObjectTypeGroup, Parent: &root.ID, Children: []string{rectID},
+		Transform: document.Transform{X: 100, Y: 100, SX: 1, SY: 1}, Style: document.Style{Opacity: 0.5},
+		Visible: true, Data: json.RawMessage(`{}`),
+	}
+	doc.Objects[rectID] = document.ObjectNode{
+		ID: rectID, Type: document.ObjectTypeShapeRect, Parent: &groupID, Children: []string{},
+		Transform: document.Transform{X: 50, Y: 50, SX: 1, SY: 1}, Style: document.Style{Fill: "#ff0000", Opacity: 1},
+		Visible: true, Data: json.RawMessage(`{"width":100,"height":100}`),
+	}
+
+	data, err := FramePNG(nil, "", doc, sceneID, 0, 0.5, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := decoded.Bounds(); b.Dx() != 640 || b.Dy() != 360 {
+		t.Fatalf("half-scale frame %dx%d, want 640x360", b.Dx(), b.Dy())
+	}
+	// The square covers (150, 150) to (250, 250) in the scene, half that here
+	within := func(got, want uint32) bool { return got>>8 >= want-2 && got>>8 <= want+2 }
+	r, g, b, _ := decoded.At(100, 100).RGBA()
+	if !within(r, 255) || !within(g, 128) || !within(b, 128) {
+		t.Errorf("square's center %d,%d,%d, want half-transparent red over white", r>>8, g>>8, b>>8)
+	}
+	if r, g, b, _ := decoded.At(70, 70).RGBA(); r>>8 != 255 || g>>8 != 255 || b>>8 != 255 {
+		t.Errorf("pixel outside the square %d,%d,%d, want white", r>>8, g>>8, b>>8)
+	}
+}
+
+func TestFrameTooLarge(t *testing.T) {
+	doc := document.NewSampleDocument("proj_1")
+	if _, err := Frame(doc, doc.Project.Scenes[0], 0, 5, nil); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("6400x3600 frame: %v, want ErrFrameTooLarge", err)
+	}
+	if _, err := Frame(doc, "scene_missing", 0, 1, nil); err == nil {
+		t.Error("rendered a missing scene")
+	}
+}
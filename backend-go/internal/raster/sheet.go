@@ -0,0 +1,150 @@
+package raster
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	imgcolor "image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"strconv"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/rendercache"
+)
+
+const (
+	sheetGap       = 4        // Pixels between and around tiles
+	maxSheetPixels = 16 << 20 // Bounds a sheet's memory at 64MB
+)
+
+var ErrSheetTooLarge = errors.New("contact sheet too large")
+
+// SheetOptions lay out a contact sheet.
+type SheetOptions struct {
+	SceneID   string
+	Frames    int // Tiles, sampled evenly from the first frame to the last
+	Columns   int
+	TileWidth int // Tile height follows the scene's aspect ratio
+}
+
+// Sheet is a rendered contact sheet.
+type Sheet struct {
+	Image      *image.RGBA
+	Frames     []int // Frame in each tile, left to right, top to bottom
+	TileWidth  int
+	TileHeight int
+}
+
+// SampleFrames returns up to count frames spread evenly over a timeline of
+// total frames, including the first and last.
+func SampleFrames(total, count int) []int {
+	count = min(count, total)
+	if count <= 0 {
+		return nil
+	}
+	if count == 1 {
+		return []int{0}
+	}
+	frames := make([]int, count)
+	for i := range frames {
+		frames[i] = int(math.Round(float64(i) * float64(total-1) / float64(count-1)))
+	}
+	return frames
+}
+
+// ContactSheet renders evenly spaced frames of a scene as downscaled tiles
+// in a grid, each labeled with its frame number. Frames come from cache when
+// it holds them; docHash is doc's rendercache.HashDocument.
+func ContactSheet(cache *rendercache.Cache, docHash string, doc *document.InDocument, opts SheetOptions, images ImageLoader) (*Sheet, error) {
+	scene, ok := doc.Scenes[opts.SceneID]
+	if !ok {
+		return nil, fmt.Errorf("scene not found: %s", opts.SceneID)
+	}
+	if scene.Width <= 0 || scene.Height <= 0 || opts.TileWidth <= 0 || opts.Columns <= 0 {
+		return nil, fmt.Errorf("contact sheet needs a scene, tile width and columns with size")
+	}
+
+	total := 1
+	if tl, ok := doc.Timelines[doc.Project.RootTimeline]; ok && tl.Length > 0 {
+		total = tl.Length
+	}
+	frames := SampleFrames(total, opts.Frames)
+	scale := float64(opts.TileWidth) / float64(scene.Width)
+	tileW, tileH := scaledSize(scene, scale)
+	if tileH < 1 {
+		tileH = 1
+	}
+
+	columns := min(opts.Columns, len(frames))
+	rows := (len(frames) + columns - 1) / columns
+	width := columns*(tileW+sheetGap) + sheetGap
+	height := rows*(tileH+sheetGap) + sheetGap
+	if width*height > maxSheetPixels {
+		return nil, ErrSheetTooLarge
+	}
+
+	sheet := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(sheet, sheet.Bounds(), image.NewUniform(sheetBackground), image.Point{}, draw.Src)
+	labelSize := max(1, tileW/80)
+	for i, frame := range frames {
+		data, err := FramePNG(cache, docHash, doc, opts.SceneID, frame, scale, images)
+		if err != nil {
+			return nil, err
+		}
+		tile, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("decode frame %d: %w", frame, err)
+		}
+		at := image.Pt(sheetGap+i%columns*(tileW+sheetGap), sheetGap+i/columns*(tileH+sheetGap))
+		draw.Draw(sheet, image.Rectangle{Min: at, Max: at.Add(image.Pt(tileW, tileH))}, tile, tile.Bounds().Min, draw.Src)
+		drawLabel(sheet, at.X, at.Y+tileH, strconv.Itoa(frame), labelSize)
+	}
+	return &Sheet{Image: sheet, Frames: frames, TileWidth: tileW, TileHeight: tileH}, nil
+}
+
+var (
+	sheetBackground = imgcolor.RGBA{R: 0x20, G: 0x20, B: 0x20, A: 0xff}
+	labelBackground = imgcolor.RGBA{A: 0xa0}
+	labelForeground = imgcolor.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+)
+
+// digitGlyphs are 3×5 pixel digits, one row per byte, high bit on the left.
+var digitGlyphs = [10][5]uint8{
+	{0b111, 0b101, 0b101, 0b101, 0b111},
+	{0b010, 0b110, 0b010, 0b010, 0b111},
+	{0b111, 0b001, 0b111, 0b100, 0b111},
+	{0b111, 0b001, 0b111, 0b001, 0b111},
+	{0b101, 0b101, 0b111, 0b001, 0b001},
+	{0b111, 0b100, 0b111, 0b001, 0b111},
+	{0b111, 0b100, 0b111, 0b101, 0b111},
+	{0b111, 0b001, 0b001, 0b001, 0b001},
+	{0b111, 0b101, 0b111, 0b101, 0b111},
+	{0b111, 0b101, 0b111, 0b001, 0b111},
+}
+
+// drawLabel draws digits in a box whose bottom-left corner is (x, bottom),
+// with each glyph pixel size×size.
+func drawLabel(img *image.RGBA, x, bottom int, digits string, size int) {
+	box := image.Rect(x, bottom-7*size, x+(4*len(digits)+1)*size, bottom)
+	draw.Draw(img, box, image.NewUniform(labelBackground), image.Point{}, draw.Over)
+
+	fg := image.NewUniform(labelForeground)
+	for i, d := range digits {
+		if d < '0' || d > '9' {
+			continue
+		}
+		left := box.Min.X + (1+4*i)*size
+		for row, bits := range digitGlyphs[d-'0'] {
+			for col := 0; col < 3; col++ {
+				if bits&(0b100>>col) == 0 {
+					continue
+				}
+				px := image.Rect(left+col*size, box.Min.Y+(1+row)*size, left+(col+1)*size, box.Min.Y+(2+row)*size)
+				draw.Draw(img, px, fg, image.Point{}, draw.Src)
+			}
+		}
+	}
+}
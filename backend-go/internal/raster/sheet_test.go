@@ -0,0 +1,97 @@
+package raster
+
+import (
+	"image"
+	imgcolor "image/color"
+	"reflect"
+	"testing"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+	"github.com/inamate/inamate/backend-go/internal/rendercache"
+)
+
+func TestSampleFrames(t *testing.T) {
+	tests := []struct {
+		total, count int
+		want         []int
+	}{
+		{48, 1, []int{0}},
+		{48, 2, []int{0, 47}},
+		{10, 4, []int{0, 3, 6, 9}},
+		{5, 20, []int{0, 1, 2, 3, 4}},
+		{48, 0, nil},
+		{0, 5, nil},
+	}
+	for _, tt := range tests {
+		if got := SampleFrames(tt.total, tt.count); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SampleFrames(%d, %d) = %v, want %v", tt.total, tt.count, got, tt.want)
+		}
+	}
+}
+
+func TestContactSheetTiles(t *testing.T) {
+	doc := document.NewSampleDocument("proj_1")
+	sceneID := doc.Project.Scenes[0]
+	hash, err := rendercache.HashDocument(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, err := rendercache.New(16<<20, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := SheetOptions{SceneID: sceneID, Frames: 20, Columns: 5, TileWidth: 240}
+
+	sheet, err := ContactSheet(cache, hash, doc, opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 1280x720 scales to 240x135 tiles, in 5 columns and 4 rows with a gap
+	// around each
+	if sheet.TileWidth != 240 || sheet.TileHeight != 135 {
+		t.Errorf("tiles %dx%d, want 240x135", sheet.TileWidth, sheet.TileHeight)
+	}
+	want := image.Rect(0, 0, 5*(240+sheetGap)+sheetGap, 4*(135+sheetGap)+sheetGap)
+	if b := sheet.Image.Bounds(); b != want {
+		t.Errorf("sheet %v, want %v", b, want)
+	}
+	if len(sheet.Frames) != 20 || sheet.Frames[0] != 0 || sheet.Frames[19] != 47 {
+		t.Errorf("frames %v, want 20 from 0 to 47", sheet.Frames)
+	}
+
+	// A tile's top-right corner shows the scene's background, clear of the
+	// label, and the gap beside it the sheet's
+	white := imgcolor.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	if tile := sheet.Image.RGBAAt(sheetGap+239, sheetGap); tile != white {
+		t.Errorf("tile corner %v, want the scene's background %v", tile, white)
+	}
+	if gap := sheet.Image.RGBAAt(sheetGap+240, sheetGap); gap != sheetBackground {
+		t.Errorf("gap %v, want %v", gap, sheetBackground)
+	}
+
+	// Rendering the sheet again reuses every frame
+	if _, err := ContactSheet(cache, hash, doc, opts, nil); err != nil {
+		t.Fatal(err)
+	}
+	if s := cache.Stats(); s.Renders != 20 || s.Hits != 20 {
+		t.Errorf("stats %+v, want 20 renders and 20 hits", s)
+	}
+}
+
+func TestContactSheetRejected(t *testing.T) {
+	doc := document.NewSampleDocument("proj_1")
+	sceneID := doc.Project.Scenes[0]
+	for name, opts := range map[string]SheetOptions{
+		"unknown scene": {SceneID: "scene_missing", Frames: 4, Columns: 2, TileWidth: 100},
+		"no columns":    {SceneID: sceneID, Frames: 4, TileWidth: 100},
+		"no width":      {SceneID: sceneID, Frames: 4, Columns: 2},
+	} {
+		if _, err := ContactSheet(nil, "", doc, opts, nil); err == nil {
+			t.Errorf("%s: sheet rendered", name)
+		}
+	}
+	huge := SheetOptions{SceneID: sceneID, Frames: 48, Columns: 4, TileWidth: 1024}
+	if _, err := ContactSheet(nil, "", doc, huge, nil); err != ErrSheetTooLarge {
+		t.Errorf("huge sheet: %v, want %v", err, ErrSheetTooLarge)
+	}
+}
@@ -0,0 +1,271 @@
+package rendercache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+)
+
+// Key identifies a rendered frame. Because the document is addressed by the
+// hash of its content, editing a document naturally stops its old frames from
+// being hit; they age out of the LRU instead of being invalidated explicitly.
+type Key struct {
+	DocHash string
+	SceneID string
+	Frame   int
+	Scale   float64
+}
+
+func (k Key) String() string {
+	return k.DocHash + "/" + k.SceneID + "/" + strconv.Itoa(k.Frame) + "@" + strconv.FormatFloat(k.Scale, 'g', -1, 64)
+}
+
+// HashDocument returns a stable content hash for a document, computed over
+// its canonical encoding so equal documents always produce the same hash.
+func HashDocument(doc *document.InDocument) (string, error) {
+	data, err := document.MarshalCanonical(doc)
+	if err != nil {
+		return "", fmt.Errorf("marshal document: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Stats is a point-in-time view of cache usage.
+type Stats struct {
+	Hits        int64 `json:"hits"`
+	Misses      int64 `json:"misses"`
+	Renders     int64 `json:"renders"`
+	Entries     int   `json:"entries"`
+	Bytes       int64 `json:"bytes"`
+	DiskEntries int   `json:"diskEntries"`
+	DiskBytes   int64 `json:"diskBytes"`
+}
+
+type entry struct {
+	key  string
+	size int64
+	data []byte // nil for disk entries
+}
+
+// lru tracks entries by recency and total size.
+type lru struct {
+	order *list.List // front = most recently used
+	items map[string]*list.Element
+	bytes int64
+}
+
+func newLRU() *lru {
+	return &lru{order: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (l *lru) get(key string) (*entry, bool) {
+	el, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	l.order.MoveToFront(el)
+	return el.Value.(*entry), true
+}
+
+func (l *lru) add(e *entry) {
+	if el, ok := l.items[e.key]; ok {
+		l.bytes -= el.Value.(*entry).size
+		el.Value = e
+		l.order.MoveToFront(el)
+	} else {
+		l.items[e.key] = l.order.PushFront(e)
+	}
+	l.bytes += e.size
+}
+
+func (l *lru) remove(key string) {
+	if el, ok := l.items[key]; ok {
+		l.bytes -= el.Value.(*entry).size
+		l.order.Remove(el)
+		delete(l.items, key)
+	}
+}
+
+func (l *lru) oldest() *entry {
+	el := l.order.Back()
+	if el == nil {
+		return nil
+	}
+	return el.Value.(*entry)
+}
+
+// Cache stores rendered PNG bytes in memory with LRU eviction. When a spill
+// directory is configured, entries evicted from memory are written to disk
+// (itself bounded by LRU) and promoted back to memory on their next hit.
+type Cache struct {
+	mu           sync.Mutex
+	maxBytes     int64
+	maxDiskBytes int64
+	dir          string
+	mem          *lru
+	disk         *lru
+	inflight     map[string]*call
+	hits         int64
+	misses       int64
+	renders      int64
+}
+
+type call struct {
+	done chan struct{}
+	data []byte
+	err  error
+}
+
+// New creates a cache holding up to maxBytes in memory. If dir is non-empty,
+// up to maxDiskBytes of evicted entries are kept there.
+func New(maxBytes int64, dir string, maxDiskBytes int64) (*Cache, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("create render cache dir: %w", err)
+		}
+	}
+	return &Cache{
+		maxBytes:     maxBytes,
+		maxDiskBytes: maxDiskBytes,
+		dir:          dir,
+		mem:          newLRU(),
+		disk:         newLRU(),
+		inflight:     make(map[string]*call),
+	}, nil
+}
+
+// Get returns the cached bytes for key, if present in memory or on disk.
+func (c *Cache) Get(key Key) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, ok := c.lookupLocked(key.String())
+	if ok {
+		c.hits++
+	} else {
+		c.misses++
+	}
+	return data, ok
+}
+
+// Put stores rendered bytes for key.
+func (c *Cache) Put(key Key, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.storeLocked(key.String(), data)
+}
+
+// GetOrRender returns the cached bytes for key, calling render on a miss.
+// Concurrent callers for the same key share a single render.
+func (c *Cache) GetOrRender(key Key, render func() ([]byte, error)) ([]byte, error) {
+	k := key.String()
+
+	c.mu.Lock()
+	if data, ok := c.lookupLocked(k); ok {
+		c.hits++
+		c.mu.Unlock()
+		return data, nil
+	}
+	c.misses++
+	if cl, ok := c.inflight[k]; ok {
+		c.mu.Unlock()
+		<-cl.done
+		return cl.data, cl.err
+	}
+	cl := &call{done: make(chan struct{})}
+	c.inflight[k] = cl
+	c.renders++
+	c.mu.Unlock()
+
+	cl.data, cl.err = render()
+
+	c.mu.Lock()
+	delete(c.inflight, k)
+	if cl.err == nil {
+		c.storeLocked(k, cl.data)
+	}
+	c.mu.Unlock()
+	close(cl.done)
+
+	return cl.data, cl.err
+}
+
+func (c *Cache) Stats() Stats {
+	if c == nil {
+		return Stats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Stats{
+		Hits:        c.hits,
+		Misses:      c.misses,
+		Renders:     c.renders,
+		Entries:     len(c.mem.items),
+		Bytes:       c.mem.bytes,
+		DiskEntries: len(c.disk.items),
+		DiskBytes:   c.disk.bytes,
+	}
+}
+
+func (c *Cache) lookupLocked(k string) ([]byte, bool) {
+	if e, ok := c.mem.get(k); ok {
+		return e.data, true
+	}
+	if _, ok := c.disk.get(k); !ok {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(c.path(k))
+	c.removeDiskLocked(k)
+	if err != nil {
+		slog.Warn("read render cache spill", "key", k, "error", err)
+		return nil, false
+	}
+	c.storeLocked(k, data)
+	return data, true
+}
+
+func (c *Cache) storeLocked(k string, data []byte) {
+	size := int64(len(data))
+	if size > c.maxBytes {
+		return
+	}
+	c.mem.add(&entry{key: k, size: size, data: data})
+	for c.mem.bytes > c.maxBytes {
+		old := c.mem.oldest()
+		c.mem.remove(old.key)
+		c.spillLocked(old)
+	}
+}
+
+func (c *Cache) spillLocked(e *entry) {
+	if c.dir == "" || e.size > c.maxDiskBytes {
+		return
+	}
+	if err := os.WriteFile(c.path(e.key), e.data, 0o644); err != nil {
+		slog.Warn("write render cache spill", "key", e.key, "error", err)
+		return
+	}
+	c.disk.add(&entry{key: e.key, size: e.size})
+	for c.disk.bytes > c.maxDiskBytes {
+		c.removeDiskLocked(c.disk.oldest().key)
+	}
+}
+
+func (c *Cache) removeDiskLocked(k string) {
+	c.disk.remove(k)
+	os.Remove(c.path(k))
+}
+
+func (c *Cache) path(k string) string {
+	sum := sha256.Sum256([]byte(k))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".png")
+}
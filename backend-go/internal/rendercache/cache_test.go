@@ -0,0 +1,209 @@
+package rendercache
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/inamate/inamate/backend-go/internal/document"
+)
+
+// counter is a render function that counts its calls and returns size bytes
+// of fill.
+type counter struct {
+	calls atomic.Int32
+}
+
+func (c *counter) render(fill byte, size int) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		c.calls.Add(1)
+		return bytes.Repeat([]byte{fill}, size), nil
+	}
+}
+
+func newCache(t *testing.T, maxBytes int64, dir string, maxDiskBytes int64) *Cache {
+	t.Helper()
+	c, err := New(maxBytes, dir, maxDiskBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestIdenticalRequestsRenderOnce(t *testing.T) {
+	c := newCache(t, 1<<20, "", 0)
+	key := Key{DocHash: "abc", SceneID: "scene_1", Frame: 12, Scale: 1}
+	var r counter
+
+	first, err := c.GetOrRender(key, r.render(1, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := c.GetOrRender(key, r.render(2, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := r.calls.Load(); n != 1 {
+		t.Errorf("rendered %d times, want 1", n)
+	}
+	if !bytes.Equal(first, second) {
+		t.Error("second request returned different bytes")
+	}
+	if s := c.Stats(); s.Hits != 1 || s.Misses != 1 || s.Renders != 1 || s.Entries != 1 || s.Bytes != 100 {
+		t.Errorf("stats %+v, want one hit, one miss, one 100 byte entry", s)
+	}
+}
+
+func TestKeyPartsSeparateEntries(t *testing.T) {
+	c := newCache(t, 1<<20, "", 0)
+	base := Key{DocHash: "abc", SceneID: "scene_1", Frame: 12, Scale: 1}
+	var r counter
+
+	keys := []Key{
+		base,
+		base,
+		{DocHash: "def", SceneID: "scene_1", Frame: 12, Scale: 1},
+		{DocHash: "abc", SceneID: "scene_2", Frame: 12, Scale: 1},
+		{DocHash: "abc", SceneID: "scene_1", Frame: 13, Scale: 1},
+		{DocHash: "abc", SceneID: "scene_1", Frame: 12, Scale: 0.5},
+	}
+	for _, key := range keys {
+		if _, err := c.GetOrRender(key, r.render(0, 10)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := r.calls.Load(); n != int32(len(keys)-1) {
+		t.Errorf("rendered %d times, want %d", n, len(keys)-1)
+	}
+}
+
+func TestConcurrentRequestsShareRender(t *testing.T) {
+	c := newCache(t, 1<<20, "", 0)
+	key := Key{DocHash: "abc", SceneID: "scene_1"}
+	release := make(chan struct{})
+	var calls atomic.Int32
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.GetOrRender(key, func() ([]byte, error) {
+				calls.Add(1)
+				<-release
+				return []byte("png"), nil
+			})
+		}()
+	}
+	// Let every caller find the render in flight before it finishes
+	for c.Stats().Misses < 8 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("rendered %d times, want 1", n)
+	}
+}
+
+func TestRenderErrorNotCached(t *testing.T) {
+	c := newCache(t, 1<<20, "", 0)
+	key := Key{DocHash: "abc", SceneID: "scene_1"}
+	errRender := errors.New("render failed")
+
+	if _, err := c.GetOrRender(key, func() ([]byte, error) { return nil, errRender }); !errors.Is(err, errRender) {
+		t.Fatalf("err %v, want %v", err, errRender)
+	}
+	var r counter
+	if _, err := c.GetOrRender(key, r.render(0, 10)); err != nil || r.calls.Load() != 1 {
+		t.Errorf("retry after a failed render: err %v, %d renders", err, r.calls.Load())
+	}
+}
+
+func TestEvictionBoundsMemory(t *testing.T) {
+	c := newCache(t, 250, "", 0)
+	var r counter
+	for frame := 0; frame < 3; frame++ {
+		c.GetOrRender(Key{DocHash: "abc", Frame: frame}, r.render(byte(frame), 100))
+	}
+	if s := c.Stats(); s.Entries != 2 || s.Bytes != 200 {
+		t.Errorf("stats %+v, want the two newest 100 byte entries", s)
+	}
+	if _, ok := c.Get(Key{DocHash: "abc", Frame: 0}); ok {
+		t.Error("oldest frame still cached")
+	}
+
+	// Entries larger than the whole cache are rendered but not kept
+	c.GetOrRender(Key{DocHash: "abc", Frame: 9}, r.render(9, 300))
+	if s := c.Stats(); s.Entries != 2 {
+		t.Errorf("%d entries after an oversized render, want 2", s.Entries)
+	}
+}
+
+func TestSpillToDisk(t *testing.T) {
+	dir := t.TempDir()
+	c := newCache(t, 100, dir, 150)
+	var r counter
+	key := func(frame int) Key { return Key{DocHash: "abc", Frame: frame} }
+
+	for frame := 0; frame < 3; frame++ {
+		c.GetOrRender(key(frame), r.render(byte(frame), 60))
+	}
+	// Frame 2 is in memory; 0 and 1 were spilled, within the disk bound
+	if s := c.Stats(); s.Entries != 1 || s.DiskEntries != 2 || s.DiskBytes != 120 {
+		t.Fatalf("stats %+v, want one entry in memory and two on disk", s)
+	}
+
+	// A disk hit is promoted back to memory, spilling frame 2 in its place
+	data, ok := c.Get(key(0))
+	if !ok || !bytes.Equal(data, bytes.Repeat([]byte{0}, 60)) {
+		t.Fatalf("frame 0 from disk: ok %v, %d bytes", ok, len(data))
+	}
+	if s := c.Stats(); s.Entries != 1 || s.DiskEntries != 2 {
+		t.Errorf("stats %+v after promotion", s)
+	}
+	if n := r.calls.Load(); n != 3 {
+		t.Errorf("rendered %d times, want 3", n)
+	}
+
+	// The disk is bounded too: a third spill evicts the oldest file
+	c.GetOrRender(key(3), r.render(3, 60))
+	files, _ := os.ReadDir(dir)
+	if s := c.Stats(); s.DiskEntries != 2 || len(files) != 2 {
+		t.Errorf("%d disk entries, %d files; want 2", s.DiskEntries, len(files))
+	}
+}
+
+func TestHashDocumentTracksContent(t *testing.T) {
+	doc := document.NewSampleDocument("proj_1")
+	same := document.NewSampleDocument("proj_1")
+
+	h1, err := HashDocument(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h2, _ := HashDocument(same)
+	if h1 != h2 {
+		t.Error("equal documents hash differently")
+	}
+
+	scene := doc.Scenes[doc.Project.Scenes[0]]
+	scene.Background = "#000000"
+	doc.Scenes[scene.ID] = scene
+	if h3, _ := HashDocument(doc); h3 == h1 {
+		t.Error("hash unchanged after editing the document")
+	}
+}
+
+func TestNilCacheStats(t *testing.T) {
+	var c *Cache
+	if s := c.Stats(); s != (Stats{}) {
+		t.Errorf("nil cache stats %+v", s)
+	}
+}
@@ -1,23 +1,27 @@
 package typeid
 
 import (
+	"crypto/sha256"
 	"fmt"
 
 	"go.jetify.com/typeid/v2"
 )
 
 const (
-	PrefixUser     = "user"
-	PrefixProject  = "proj"
-	PrefixSnapshot = "snap"
-	PrefixOp       = "op"
-	PrefixScene    = "scene"
-	PrefixObject   = "obj"
-	PrefixTimeline = "tl"
-	PrefixTrack    = "track"
-	PrefixKeyframe = "kf"
-	PrefixAsset    = "asset"
-	PrefixExport   = "exp"
+	PrefixUser         = "user"
+	PrefixProject      = "proj"
+	PrefixSnapshot     = "snap"
+	PrefixOp           = "op"
+	PrefixScene        = "scene"
+	PrefixObject       = "obj"
+	PrefixTimeline     = "tl"
+	PrefixTrack        = "track"
+	PrefixKeyframe     = "kf"
+	PrefixAsset        = "asset"
+	PrefixExport       = "exp"
+	PrefixWebhook      = "hook"
+	PrefixDelivery     = "dlv"
+	PrefixNotification = "ntf"
 )
 
 func New(prefix string) string {
@@ -25,17 +29,20 @@ func New(prefix string) string {
 	return id.String()
 }
 
-func NewUserID() string     { return New(PrefixUser) }
-func NewProjectID() string  { return New(PrefixProject) }
-func NewSnapshotID() string { return New(PrefixSnapshot) }
-func NewOpID() string       { return New(PrefixOp) }
-func NewSceneID() string    { return New(PrefixScene) }
-func NewObjectID() string   { return New(PrefixObject) }
-func NewTimelineID() string { return New(PrefixTimeline) }
-func NewTrackID() string    { return New(PrefixTrack) }
-func NewKeyframeID() string { return New(PrefixKeyframe) }
-func NewAssetID() string    { return New(PrefixAsset) }
-func NewExportID() string   { return New(PrefixExport) }
+func NewUserID() string         { return New(PrefixUser) }
+func NewProjectID() string      { return New(PrefixProject) }
+func NewSnapshotID() string     { return New(PrefixSnapshot) }
+func NewOpID() string           { return New(PrefixOp) }
+func NewSceneID() string        { return New(PrefixScene) }
+func NewObjectID() string       { return New(PrefixObject) }
+func NewTimelineID() string     { return New(PrefixTimeline) }
+func NewTrackID() string        { return New(PrefixTrack) }
+func NewKeyframeID() string     { return New(PrefixKeyframe) }
+func NewAssetID() string        { return New(PrefixAsset) }
+func NewExportID() string       { return New(PrefixExport) }
+func NewWebhookID() string      { return New(PrefixWebhook) }
+func NewDeliveryID() string     { return New(PrefixDelivery) }
+func NewNotificationID() string { return New(PrefixNotification) }
 
 func Validate(id, expectedPrefix string) error {
 	parsed, err := typeid.Parse(id)
@@ -47,3 +54,18 @@ func Validate(id, expectedPrefix string) error {
 	}
 	return nil
 }
+
+// FromName derives a stable ID with the given prefix from an arbitrary string,
+// so the same legacy ID always maps to the same TypeID. The suffix encodes a
+// name-based (version 8) UUID.
+func FromName(prefix, name string) string {
+	sum := sha256.Sum256([]byte(prefix + "\x00" + name))
+	uid := sum[:16]
+	uid[6] = uid[6]&0x0f | 0x80
+	uid[8] = uid[8]&0x3f | 0x80
+	id, err := typeid.FromBytes(prefix, uid)
+	if err != nil {
+		panic(err) // Only an invalid prefix can fail, and callers use the constants above
+	}
+	return id.String()
+}
@@ -0,0 +1,51 @@
+package typeid
+
+import "testing"
+
+func TestValidate(t *testing.T) {
+	prefixes := []string{
+		PrefixProject, PrefixScene, PrefixObject, PrefixTimeline,
+		PrefixTrack, PrefixKeyframe, PrefixAsset,
+	}
+	for _, prefix := range prefixes {
+		t.Run(prefix, func(t *testing.T) {
+			id := New(prefix)
+			if err := Validate(id, prefix); err != nil {
+				t.Errorf("Validate(%q): %v", id, err)
+			}
+			for _, bad := range []string{
+				"",
+				prefix,
+				prefix + "_",
+				prefix + "_playground",
+				"3f2504e0-4f89-11d3-9a0c-0305e82c3301",
+				id + "x",
+			} {
+				if Validate(bad, prefix) == nil {
+					t.Errorf("Validate(%q) accepted", bad)
+				}
+			}
+			// A valid ID of another kind is rejected
+			other := PrefixObject
+			if prefix == PrefixObject {
+				other = PrefixTrack
+			}
+			if Validate(New(other), prefix) == nil {
+				t.Errorf("%q id accepted as %q", other, prefix)
+			}
+		})
+	}
+}
+
+func TestFromNameIsStable(t *testing.T) {
+	id := FromName(PrefixScene, "scene_playground")
+	if err := Validate(id, PrefixScene); err != nil {
+		t.Fatalf("FromName = %q: %v", id, err)
+	}
+	if again := FromName(PrefixScene, "scene_playground"); again != id {
+		t.Errorf("FromName gave %q then %q", id, again)
+	}
+	if FromName(PrefixObject, "scene_playground") == id || FromName(PrefixScene, "other") == id {
+		t.Error("FromName collided across prefixes or names")
+	}
+}
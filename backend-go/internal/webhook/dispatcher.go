@@ -0,0 +1,287 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/inamate/inamate/backend-go/internal/db/dbgen"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// Event names delivered to webhook subscribers.
+const (
+	EventSnapshotSaved   = "snapshot.saved"
+	EventMemberAdded     = "member.added"
+	EventMemberRemoved   = "member.removed"
+	EventProjectRenamed  = "project.renamed"
+	EventExportCompleted = "export.completed"
+)
+
+// Events lists every event a webhook can subscribe to.
+var Events = []string{
+	EventSnapshotSaved,
+	EventMemberAdded,
+	EventMemberRemoved,
+	EventProjectRenamed,
+	EventExportCompleted,
+}
+
+const (
+	SignatureHeader = "X-Inamate-Signature"
+	EventHeader     = "X-Inamate-Event"
+	DeliveryHeader  = "X-Inamate-Delivery"
+
+	maxAttempts     = 4
+	deliveryTimeout = 10 * time.Second
+	queueSize       = 256
+	workerCount     = 4
+)
+
+var errBlockedAddress = errors.New("webhook target resolves to a private or loopback address")
+
+// Payload is the JSON body POSTed to subscribers.
+type Payload struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	ProjectID string      `json:"projectId"`
+	Timestamp string      `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// job is an event to fan out to a project's webhooks or, when hook is set, a
+// retry of one delivery.
+type job struct {
+	projectID string
+	event     string
+	data      interface{}
+
+	hook    *dbgen.ProjectWebhook
+	body    []byte
+	attempt int
+}
+
+// store is the subset of dbgen.Queries the dispatcher uses.
+type store interface {
+	ListProjectWebhooks(ctx context.Context, projectID string) ([]dbgen.ProjectWebhook, error)
+	CreateWebhookDelivery(ctx context.Context, arg dbgen.CreateWebhookDeliveryParams) error
+}
+
+// Dispatcher delivers signed webhook events in the background. Deliveries are
+// retried with exponential backoff and every attempt is recorded so it can be
+// inspected per webhook. Workers never wait out a backoff: a failed attempt
+// schedules its retry and the worker moves on to the next job. A nil
+// *Dispatcher is valid and drops all events.
+type Dispatcher struct {
+	queries store
+	client  *http.Client
+	queue   chan job
+	backoff time.Duration
+}
+
+// NewDispatcher creates a dispatcher. Unless allowPrivate is set, connections
+// to loopback, private, and link-local addresses are refused to prevent
+// webhooks from being used to probe internal services.
+func NewDispatcher(queries *dbgen.Queries, allowPrivate bool) *Dispatcher {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if !allowPrivate {
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || IsBlockedIP(ip) {
+				return errBlockedAddress
+			}
+			return nil
+		}
+	}
+
+	return &Dispatcher{
+		queries: queries,
+		client: &http.Client{
+			Timeout:   deliveryTimeout,
+			Transport: &http.Transport{DialContext: dialer.DialContext},
+			// Redirects could point anywhere; subscribers must use the final URL.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		queue:   make(chan job, queueSize),
+		backoff: time.Second,
+	}
+}
+
+// Run starts the delivery workers and blocks until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < workerCount; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case j := <-d.queue:
+					if j.hook != nil {
+						d.deliver(ctx, *j.hook, j.event, j.body, j.attempt)
+					} else {
+						d.deliverAll(ctx, j)
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// QueueLength reports how many events are waiting for a delivery worker.
+func (d *Dispatcher) QueueLength() int {
+	if d == nil {
+		return 0
+	}
+	return len(d.queue)
+}
+
+// Dispatch queues an event for every webhook of the project subscribed to it.
+// It never blocks; events are dropped if the queue is full.
+func (d *Dispatcher) Dispatch(projectID, event string, data interface{}) {
+	if d == nil {
+		return
+	}
+	select {
+	case d.queue <- job{projectID: projectID, event: event, data: data}:
+	default:
+		slog.Warn("webhook queue full, dropping event", "project", projectID, "event", event)
+	}
+}
+
+func (d *Dispatcher) deliverAll(ctx context.Context, j job) {
+	hooks, err := d.queries.ListProjectWebhooks(ctx, j.projectID)
+	if err != nil {
+		slog.Error("list webhooks", "project", j.projectID, "error", err)
+		return
+	}
+
+	for _, hook := range hooks {
+		if len(hook.Events) > 0 && !slices.Contains(hook.Events, j.event) {
+			continue
+		}
+
+		body, err := json.Marshal(Payload{
+			ID:        typeid.NewDeliveryID(),
+			Event:     j.event,
+			ProjectID: j.projectID,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Data:      j.data,
+		})
+		if err != nil {
+			slog.Error("marshal webhook payload", "event", j.event, "error", err)
+			return
+		}
+
+		d.deliver(ctx, hook, j.event, body, 1)
+	}
+}
+
+// deliver makes one attempt to POST body to a webhook. Network errors and
+// non-2xx responses are retried until maxAttempts, with the backoff
+// quadrupling after each attempt.
+func (d *Dispatcher) deliver(ctx context.Context, hook dbgen.ProjectWebhook, event string, body []byte, attempt int) {
+	deliveryID := typeid.NewDeliveryID()
+	start := time.Now()
+	status, err := d.post(ctx, hook, event, deliveryID, body)
+	success := err == nil && status >= 200 && status < 300
+
+	errMsg := ""
+	if err != nil {
+		errMsg = err.Error()
+	} else if !success {
+		errMsg = fmt.Sprintf("unexpected status %d", status)
+	}
+
+	if logErr := d.queries.CreateWebhookDelivery(ctx, dbgen.CreateWebhookDeliveryParams{
+		ID:         deliveryID,
+		WebhookID:  hook.ID,
+		Event:      event,
+		Payload:    body,
+		Attempt:    int32(attempt),
+		StatusCode: int32(status),
+		Error:      errMsg,
+		Success:    success,
+		DurationMs: int32(time.Since(start).Milliseconds()),
+	}); logErr != nil {
+		slog.Error("record webhook delivery", "webhook", hook.ID, "error", logErr)
+	}
+
+	if success {
+		return
+	}
+	slog.Warn("webhook delivery failed", "webhook", hook.ID, "event", event, "attempt", attempt, "error", errMsg)
+	if attempt >= maxAttempts || errors.Is(err, errBlockedAddress) {
+		return
+	}
+
+	retry := job{projectID: hook.ProjectID, event: event, hook: &hook, body: body, attempt: attempt + 1}
+	time.AfterFunc(d.backoff<<(2*(attempt-1)), func() {
+		// Retries wait for room in the queue rather than being dropped
+		select {
+		case d.queue <- retry:
+		case <-ctx.Done():
+		}
+	})
+}
+
+func (d *Dispatcher) post(ctx context.Context, hook dbgen.ProjectWebhook, event, deliveryID string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "inamate-webhooks/1")
+	req.Header.Set(EventHeader, event)
+	req.Header.Set(DeliveryHeader, deliveryID)
+	req.Header.Set(SignatureHeader, Sign(hook.Secret, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	return resp.StatusCode, nil
+}
+
+// Sign returns the signature header value for body: "sha256=" followed by the
+// hex-encoded HMAC-SHA256 of the body keyed by the webhook secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// IsBlockedIP reports whether ip is a loopback, private, link-local, or
+// otherwise non-routable address that webhooks must not target.
+func IsBlockedIP(ip net.IP) bool {
+	return ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified()
+}
@@ -0,0 +1,267 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/inamate/inamate/backend-go/internal/db/dbgen"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+// fakeStore serves webhooks from memory and reports every recorded delivery
+// on recorded.
+type fakeStore struct {
+	mu       sync.Mutex
+	hooks    map[string][]dbgen.ProjectWebhook
+	recorded chan dbgen.CreateWebhookDeliveryParams
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		hooks:    make(map[string][]dbgen.ProjectWebhook),
+		recorded: make(chan dbgen.CreateWebhookDeliveryParams, 100),
+	}
+}
+
+// addHook registers a webhook on a new project pointing at url and returns
+// the project's ID and the hook.
+func (s *fakeStore) addHook(url string, events ...string) (string, dbgen.ProjectWebhook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	projectID := typeid.NewProjectID()
+	hook := dbgen.ProjectWebhook{ID: typeid.NewWebhookID(), ProjectID: projectID, Url: url, Secret: "s3cret", Events: events}
+	s.hooks[projectID] = append(s.hooks[projectID], hook)
+	return projectID, hook
+}
+
+func (s *fakeStore) ListProjectWebhooks(ctx context.Context, projectID string) ([]dbgen.ProjectWebhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hooks[projectID], nil
+}
+
+func (s *fakeStore) CreateWebhookDelivery(ctx context.Context, arg dbgen.CreateWebhookDeliveryParams) error {
+	s.recorded <- arg
+	return nil
+}
+
+// next waits for the next recorded delivery attempt.
+func (s *fakeStore) next(t *testing.T) dbgen.CreateWebhookDeliveryParams {
+	t.Helper()
+	select {
+	case d := <-s.recorded:
+		return d
+	case <-time.After(5 * time.Second):
+		t.Fatal("no delivery recorded")
+		return dbgen.CreateWebhookDeliveryParams{}
+	}
+}
+
+// expectNone fails if another delivery attempt is recorded shortly.
+func (s *fakeStore) expectNone(t *testing.T) {
+	t.Helper()
+	select {
+	case d := <-s.recorded:
+		t.Errorf("unexpected attempt %d recorded", d.Attempt)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// startDispatcher runs a dispatcher over store, retrying after backoff.
+func startDispatcher(t *testing.T, store *fakeStore, allowPrivate bool, backoff time.Duration) *Dispatcher {
+	t.Helper()
+	d := NewDispatcher(nil, allowPrivate)
+	d.queries = store
+	d.backoff = backoff
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go d.Run(ctx)
+	return d
+}
+
+// statusServer answers each request with the next of statuses, repeating
+// the last one.
+func statusServer(t *testing.T, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		w.WriteHeader(statuses[min(n, len(statuses))-1])
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestSign(t *testing.T) {
+	got := Sign("key", []byte("The quick brown fox jumps over the lazy dog"))
+	want := "sha256=f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"
+	if got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+}
+
+func TestDeliverySigned(t *testing.T) {
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	requests := make(chan received, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{r.Header, body}
+	}))
+	defer srv.Close()
+
+	store := newFakeStore()
+	projectID, hook := store.addHook(srv.URL, EventSnapshotSaved)
+	d := startDispatcher(t, store, true, time.Millisecond)
+
+	// The hook isn't subscribed to member.added, so only the save arrives
+	d.Dispatch(projectID, EventMemberAdded, nil)
+	d.Dispatch(projectID, EventSnapshotSaved, map[string]int{"version": 7})
+
+	delivery := store.next(t)
+	if !delivery.Success || delivery.Attempt != 1 || delivery.StatusCode != http.StatusOK || delivery.WebhookID != hook.ID {
+		t.Errorf("delivery %+v, want a first-attempt success", delivery)
+	}
+	store.expectNone(t)
+
+	req := <-requests
+	mac := hmac.New(sha256.New, []byte(hook.Secret))
+	mac.Write(req.body)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); req.header.Get(SignatureHeader) != want {
+		t.Errorf("signature %q, want %q", req.header.Get(SignatureHeader), want)
+	}
+	if req.header.Get(EventHeader) != EventSnapshotSaved || req.header.Get(DeliveryHeader) != delivery.ID {
+		t.Errorf("event %q delivery %q, want %s %s", req.header.Get(EventHeader), req.header.Get(DeliveryHeader), EventSnapshotSaved, delivery.ID)
+	}
+	var payload Payload
+	if err := json.Unmarshal(req.body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Event != EventSnapshotSaved || payload.ProjectID != projectID || payload.Data.(map[string]interface{})["version"] != 7.0 {
+		t.Errorf("payload %+v", payload)
+	}
+}
+
+func TestDeliveryRetried(t *testing.T) {
+	srv, calls := statusServer(t, http.StatusInternalServerError, http.StatusBadGateway, http.StatusNoContent)
+	store := newFakeStore()
+	projectID, _ := store.addHook(srv.URL)
+	d := startDispatcher(t, store, true, time.Millisecond)
+
+	d.Dispatch(projectID, EventProjectRenamed, nil)
+
+	wantStatus := []int32{500, 502, 204}
+	var payload []byte
+	for i, status := range wantStatus {
+		delivery := store.next(t)
+		if delivery.Attempt != int32(i+1) || delivery.StatusCode != status || delivery.Success != (status == 204) {
+			t.Errorf("attempt %d: %+v, want status %d", i+1, delivery, status)
+		}
+		if payload != nil && string(delivery.Payload) != string(payload) {
+			t.Errorf("attempt %d sent a different payload", i+1)
+		}
+		payload = delivery.Payload
+	}
+	store.expectNone(t)
+	if n := calls.Load(); n != 3 {
+		t.Errorf("server called %d times, want 3", n)
+	}
+}
+
+func TestDeliveryGivesUp(t *testing.T) {
+	srv, _ := statusServer(t, http.StatusServiceUnavailable)
+	store := newFakeStore()
+	projectID, _ := store.addHook(srv.URL)
+	d := startDispatcher(t, store, true, time.Millisecond)
+
+	d.Dispatch(projectID, EventMemberRemoved, nil)
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if delivery := store.next(t); delivery.Attempt != int32(attempt) || delivery.Success {
+			t.Errorf("delivery %+v, want failed attempt %d", delivery, attempt)
+		}
+	}
+	store.expectNone(t)
+}
+
+// TestRetriesDoNotBlockWorkers checks a webhook waiting to retry doesn't hold
+// up deliveries to other webhooks.
+func TestRetriesDoNotBlockWorkers(t *testing.T) {
+	failing, _ := statusServer(t, http.StatusInternalServerError)
+	working, _ := statusServer(t, http.StatusOK)
+	store := newFakeStore()
+	failingProject, _ := store.addHook(failing.URL)
+	workingProject, _ := store.addHook(working.URL)
+	d := startDispatcher(t, store, true, time.Hour)
+
+	for i := 0; i < 2*workerCount; i++ {
+		d.Dispatch(failingProject, EventSnapshotSaved, nil)
+	}
+	for i := 0; i < 2*workerCount; i++ {
+		if delivery := store.next(t); delivery.Success {
+			t.Fatal("failing webhook succeeded")
+		}
+	}
+
+	d.Dispatch(workingProject, EventSnapshotSaved, nil)
+	if delivery := store.next(t); !delivery.Success {
+		t.Errorf("delivery %+v, want success while retries are pending", delivery)
+	}
+}
+
+func TestPrivateAddressBlocked(t *testing.T) {
+	srv, calls := statusServer(t, http.StatusOK)
+	store := newFakeStore()
+	projectID, _ := store.addHook(srv.URL)
+	d := startDispatcher(t, store, false, time.Millisecond)
+
+	d.Dispatch(projectID, EventSnapshotSaved, nil)
+	delivery := store.next(t)
+	if delivery.Success || !strings.Contains(delivery.Error, errBlockedAddress.Error()) {
+		t.Errorf("delivery %+v, want blocked", delivery)
+	}
+	// Blocked addresses stay blocked, so they aren't retried
+	store.expectNone(t)
+	if n := calls.Load(); n != 0 {
+		t.Errorf("blocked server called %d times", n)
+	}
+}
+
+func TestIsBlockedIP(t *testing.T) {
+	tests := []struct {
+		ip      string
+		blocked bool
+	}{
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"fe80::1", true},
+		{"fc00::1", true},
+		{"224.0.0.1", true},
+		{"0.0.0.0", true},
+		{"::", true},
+		{"93.184.216.34", false},
+		{"2606:4700::1111", false},
+	}
+	for _, tt := range tests {
+		if got := IsBlockedIP(net.ParseIP(tt.ip)); got != tt.blocked {
+			t.Errorf("IsBlockedIP(%s) = %v, want %v", tt.ip, got, tt.blocked)
+		}
+	}
+}
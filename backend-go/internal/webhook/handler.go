@@ -0,0 +1,106 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/inamate/inamate/backend-go/internal/auth"
+	"github.com/inamate/inamate/backend-go/internal/httperr"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+type createRequest struct {
+	URL    string   `json:"url"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events"`
+}
+
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromContext(r.Context())
+	projectID := mux.Vars(r)["projectId"]
+
+	var req createRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeInvalidBody, "invalid request body")
+		return
+	}
+
+	if req.URL == "" {
+		httperr.Write(w, http.StatusBadRequest, httperr.CodeValidationFailed, "url is required")
+		return
+	}
+
+	hook, err := h.service.Create(r.Context(), projectID, userID, req.URL, req.Secret, req.Events)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, hook)
+}
+
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromContext(r.Context())
+	projectID := mux.Vars(r)["projectId"]
+
+	hooks, err := h.service.List(r.Context(), projectID, userID)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, hooks)
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromContext(r.Context())
+	projectID := mux.Vars(r)["projectId"]
+	webhookID := mux.Vars(r)["webhookId"]
+
+	if err := h.service.Delete(r.Context(), projectID, userID, webhookID); err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromContext(r.Context())
+	projectID := mux.Vars(r)["projectId"]
+	webhookID := mux.Vars(r)["webhookId"]
+
+	deliveries, err := h.service.ListDeliveries(r.Context(), projectID, userID, webhookID)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, deliveries)
+}
+
+var serviceErrors = []httperr.Mapping{
+	{Err: ErrNotFound, Status: http.StatusNotFound, Code: httperr.CodeWebhookNotFound},
+	{Err: ErrProjectNotFound, Status: http.StatusNotFound, Code: httperr.CodeProjectNotFound},
+	{Err: ErrForbidden, Status: http.StatusForbidden, Code: httperr.CodeForbidden},
+	{Err: ErrInvalidURL, Status: http.StatusBadRequest, Code: httperr.CodeValidationFailed},
+	{Err: ErrInvalidEvent, Status: http.StatusBadRequest, Code: httperr.CodeValidationFailed},
+}
+
+func handleServiceError(w http.ResponseWriter, err error) {
+	httperr.FromError(w, err, serviceErrors)
+}
+
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
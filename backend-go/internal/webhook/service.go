@@ -0,0 +1,226 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/inamate/inamate/backend-go/internal/db/dbgen"
+	"github.com/inamate/inamate/backend-go/internal/typeid"
+)
+
+var (
+	ErrNotFound        = errors.New("webhook not found")
+	ErrProjectNotFound = errors.New("project not found")
+	ErrForbidden       = errors.New("forbidden")
+	ErrInvalidURL      = errors.New("webhook url must be an absolute http or https url to a public host")
+	ErrInvalidEvent    = errors.New("unknown webhook event")
+)
+
+const deliveryLogLimit = 50
+
+type Service struct {
+	queries      *dbgen.Queries
+	allowPrivate bool
+}
+
+func NewService(queries *dbgen.Queries, allowPrivate bool) *Service {
+	return &Service{queries: queries, allowPrivate: allowPrivate}
+}
+
+type Webhook struct {
+	ID        string   `json:"id"`
+	ProjectID string   `json:"projectId"`
+	URL       string   `json:"url"`
+	Secret    string   `json:"secret,omitempty"` // Only returned on create
+	Events    []string `json:"events"`
+	CreatedBy string   `json:"createdBy"`
+	CreatedAt string   `json:"createdAt"`
+}
+
+type Delivery struct {
+	ID         string          `json:"id"`
+	Event      string          `json:"event"`
+	Payload    json.RawMessage `json:"payload"`
+	Attempt    int             `json:"attempt"`
+	StatusCode int             `json:"statusCode"`
+	Error      string          `json:"error,omitempty"`
+	Success    bool            `json:"success"`
+	DurationMs int             `json:"durationMs"`
+	CreatedAt  string          `json:"createdAt"`
+}
+
+// Create registers a webhook on a project. Only the project owner may manage
+// webhooks. An empty secret is replaced with a random one.
+func (s *Service) Create(ctx context.Context, projectID, userID, rawURL, secret string, events []string) (*Webhook, error) {
+	if err := s.checkOwner(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+	if err := s.validateURL(rawURL); err != nil {
+		return nil, err
+	}
+	for _, ev := range events {
+		if !slices.Contains(Events, ev) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidEvent, ev)
+		}
+	}
+	if events == nil {
+		events = []string{}
+	}
+
+	if secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("generate secret: %w", err)
+		}
+		secret = hex.EncodeToString(buf)
+	}
+
+	dbHook, err := s.queries.CreateWebhook(ctx, dbgen.CreateWebhookParams{
+		ID:        typeid.NewWebhookID(),
+		ProjectID: projectID,
+		Url:       rawURL,
+		Secret:    secret,
+		Events:    events,
+		CreatedBy: userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create webhook: %w", err)
+	}
+
+	hook := dbWebhookToWebhook(dbHook)
+	hook.Secret = dbHook.Secret
+	return hook, nil
+}
+
+func (s *Service) List(ctx context.Context, projectID, userID string) ([]Webhook, error) {
+	if err := s.checkOwner(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+
+	dbHooks, err := s.queries.ListProjectWebhooks(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list webhooks: %w", err)
+	}
+
+	hooks := make([]Webhook, len(dbHooks))
+	for i, h := range dbHooks {
+		hooks[i] = *dbWebhookToWebhook(h)
+	}
+	return hooks, nil
+}
+
+func (s *Service) Delete(ctx context.Context, projectID, userID, webhookID string) error {
+	if err := s.checkOwner(ctx, projectID, userID); err != nil {
+		return err
+	}
+	if _, err := s.getWebhook(ctx, projectID, webhookID); err != nil {
+		return err
+	}
+
+	return s.queries.DeleteWebhook(ctx, dbgen.DeleteWebhookParams{
+		ID:        webhookID,
+		ProjectID: projectID,
+	})
+}
+
+// ListDeliveries returns the most recent delivery attempts for a webhook.
+func (s *Service) ListDeliveries(ctx context.Context, projectID, userID, webhookID string) ([]Delivery, error) {
+	if err := s.checkOwner(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+	if _, err := s.getWebhook(ctx, projectID, webhookID); err != nil {
+		return nil, err
+	}
+
+	dbDeliveries, err := s.queries.ListWebhookDeliveries(ctx, dbgen.ListWebhookDeliveriesParams{
+		WebhookID: webhookID,
+		Limit:     deliveryLogLimit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list deliveries: %w", err)
+	}
+
+	deliveries := make([]Delivery, len(dbDeliveries))
+	for i, d := range dbDeliveries {
+		deliveries[i] = Delivery{
+			ID:         d.ID,
+			Event:      d.Event,
+			Payload:    d.Payload,
+			Attempt:    int(d.Attempt),
+			StatusCode: int(d.StatusCode),
+			Error:      d.Error,
+			Success:    d.Success,
+			DurationMs: int(d.DurationMs),
+			CreatedAt:  d.CreatedAt.Time.Format("2006-01-02T15:04:05Z"),
+		}
+	}
+	return deliveries, nil
+}
+
+func (s *Service) getWebhook(ctx context.Context, projectID, webhookID string) (dbgen.ProjectWebhook, error) {
+	hook, err := s.queries.GetWebhook(ctx, dbgen.GetWebhookParams{
+		ID:        webhookID,
+		ProjectID: projectID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return hook, ErrNotFound
+		}
+		return hook, fmt.Errorf("get webhook: %w", err)
+	}
+	return hook, nil
+}
+
+func (s *Service) checkOwner(ctx context.Context, projectID, userID string) error {
+	dbProj, err := s.queries.GetProject(ctx, projectID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrProjectNotFound
+		}
+		return fmt.Errorf("get project: %w", err)
+	}
+	if dbProj.OwnerID != userID {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// validateURL rejects non-HTTP URLs and literal private addresses up front.
+// Hostnames are checked again at dial time by the dispatcher, since DNS can
+// change after the webhook is registered.
+func (s *Service) validateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return ErrInvalidURL
+	}
+	if s.allowPrivate {
+		return nil
+	}
+	if u.Hostname() == "localhost" {
+		return ErrInvalidURL
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && IsBlockedIP(ip) {
+		return ErrInvalidURL
+	}
+	return nil
+}
+
+func dbWebhookToWebhook(h dbgen.ProjectWebhook) *Webhook {
+	return &Webhook{
+		ID:        h.ID,
+		ProjectID: h.ProjectID,
+		URL:       h.Url,
+		Events:    h.Events,
+		CreatedBy: h.CreatedBy,
+		CreatedAt: h.CreatedAt.Time.Format("2006-01-02T15:04:05Z"),
+	}
+}
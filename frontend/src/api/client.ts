@@ -1,12 +1,26 @@
 export const API_BASE = import.meta.env.VITE_API_URL || "";
 
+// Error envelope returned by every failing API request
+export interface ApiErrorBody {
+  code: string; // Stable machine-readable code, e.g. "project_not_found"
+  message: string;
+  details?: unknown;
+  requestId?: string;
+}
+
 export class ApiError extends Error {
   status: number;
+  code: string;
+  details?: unknown;
+  requestId?: string;
 
-  constructor(status: number, message: string) {
-    super(message);
+  constructor(status: number, body: ApiErrorBody) {
+    super(body.message);
     this.name = "ApiError";
     this.status = status;
+    this.code = body.code;
+    this.details = body.details;
+    this.requestId = body.requestId;
   }
 }
 
@@ -31,8 +45,14 @@ export async function apiFetch<T>(
   });
 
   if (!res.ok) {
-    const body = await res.json().catch(() => ({ error: res.statusText }));
-    throw new ApiError(res.status, body.error || res.statusText);
+    const body: ApiErrorBody = await res.json().catch(() => ({
+      code: "unknown",
+      message: res.statusText,
+    }));
+    throw new ApiError(res.status, {
+      ...body,
+      message: body.message || res.statusText,
+    });
   }
 
   if (res.status === 204) {
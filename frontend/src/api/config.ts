@@ -0,0 +1,14 @@
+import { apiFetch } from './client'
+
+// Feature flags of the deployment, so UI for what it turned off can be hidden
+export interface Features {
+  playgroundEnabled: boolean
+  exportsEnabled: boolean
+  registrationEnabled: boolean
+  maxProjectMembers: number // Including the owner; 0 for no limit
+  experimental: Record<string, boolean>
+}
+
+export function getFeatures(): Promise<Features> {
+  return apiFetch<Features>('/config/features')
+}
@@ -0,0 +1,62 @@
+import { apiFetch } from './client'
+
+export type NotificationType =
+  | 'member.invited'
+  | 'member.removed'
+  | 'export.completed'
+
+export interface Notification {
+  id: string
+  projectId: string
+  type: NotificationType
+  actorId?: string // Who caused it, if anyone
+  data: Record<string, unknown>
+  readAt?: string
+  createdAt: string
+}
+
+export interface NotificationPage {
+  notifications: Notification[] // Newest first
+  unread: number
+}
+
+export function listNotifications(
+  options: { unread?: boolean; limit?: number; offset?: number } = {},
+): Promise<NotificationPage> {
+  const params = new URLSearchParams()
+  if (options.unread) params.set('unread', 'true')
+  if (options.limit !== undefined) params.set('limit', String(options.limit))
+  if (options.offset !== undefined) params.set('offset', String(options.offset))
+  const query = params.toString()
+  return apiFetch<NotificationPage>(
+    `/api/me/notifications${query ? `?${query}` : ''}`,
+  )
+}
+
+/** Marks the given notifications read, or all of them when ids is 'all'. */
+export function markNotificationsRead(
+  ids: string[] | 'all',
+): Promise<{ marked: number; unread: number }> {
+  return apiFetch('/api/me/notifications/read', {
+    method: 'POST',
+    body: JSON.stringify(ids === 'all' ? { all: true } : { ids }),
+  })
+}
+
+export interface NotificationPreferences {
+  mutedProjects: string[]
+  mutedTypes: NotificationType[]
+}
+
+export function getNotificationPreferences(): Promise<NotificationPreferences> {
+  return apiFetch<NotificationPreferences>('/api/me/notifications/preferences')
+}
+
+export function setNotificationPreferences(
+  prefs: NotificationPreferences,
+): Promise<NotificationPreferences> {
+  return apiFetch<NotificationPreferences>('/api/me/notifications/preferences', {
+    method: 'PUT',
+    body: JSON.stringify(prefs),
+  })
+}
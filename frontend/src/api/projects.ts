@@ -1,5 +1,6 @@
 import { apiFetch } from './client'
 import type { InDocument } from '../types/document'
+import type { Operation } from '../types/operations'
 
 export interface Project {
   id: string
@@ -16,10 +17,60 @@ export function listProjects(): Promise<Project[]> {
   return apiFetch<Project[]>('/api/projects')
 }
 
-export function createProject(name: string): Promise<Project> {
+/** Overrides for the server's default scene settings. */
+export interface SceneSettings {
+  width?: number
+  height?: number
+  background?: string
+  fps?: number
+}
+
+export function createProject(
+  name: string,
+  settings: SceneSettings = {},
+): Promise<Project> {
   return apiFetch<Project>('/api/projects', {
     method: 'POST',
-    body: JSON.stringify({ name }),
+    body: JSON.stringify({ name, ...settings }),
+  })
+}
+
+/**
+ * Creates a project owned by the caller from a copy of the playground: the
+ * given document, or the shared playground's current one.
+ */
+export function createProjectFromPlayground(
+  name?: string,
+  document?: InDocument,
+): Promise<Project> {
+  return apiFetch<Project>('/api/projects/from-playground', {
+    method: 'POST',
+    body: JSON.stringify({ name, document }),
+  })
+}
+
+/** An image the imported document refers to, still to be uploaded. */
+export interface ImportedImage {
+  assetId: string // Upload the image under this ID
+  name: string
+  path: string // Relative to the Lottie file, or a data URI when embedded
+  embedded: boolean
+  width: number
+  height: number
+}
+
+export interface ImportResult {
+  project: Project
+  images: ImportedImage[]
+  warnings: string[] // Unsupported features that were skipped or approximated
+}
+
+/** Creates a project from a Lottie (bodymovin) JSON animation. */
+export function importLottie(lottieJson: string, name?: string): Promise<ImportResult> {
+  const query = name ? `?name=${encodeURIComponent(name)}` : ''
+  return apiFetch<ImportResult>(`/api/projects/import/lottie${query}`, {
+    method: 'POST',
+    body: lottieJson,
   })
 }
 
@@ -31,13 +82,115 @@ export function deleteProject(id: string): Promise<void> {
   return apiFetch<void>(`/api/projects/${id}`, { method: 'DELETE' })
 }
 
-export function inviteToProject(projectId: string, email: string): Promise<void> {
+/** Viewers see the document and others' presence but can't edit. */
+export type ProjectRole = 'owner' | 'editor' | 'viewer'
+
+export interface ProjectMember {
+  userId: string
+  role: ProjectRole
+  displayName: string
+  email: string
+}
+
+export function inviteToProject(
+  projectId: string,
+  email: string,
+  role: Exclude<ProjectRole, 'owner'> = 'editor',
+): Promise<void> {
   return apiFetch<void>(`/api/projects/${projectId}/invite`, {
     method: 'POST',
-    body: JSON.stringify({ email }),
+    body: JSON.stringify({ email, role }),
   })
 }
 
+export function listMembers(projectId: string): Promise<ProjectMember[]> {
+  return apiFetch<ProjectMember[]>(`/api/projects/${projectId}/members`)
+}
+
+/** Where a search query was found; objectId is absent for a scene name. */
+export interface SearchMatch {
+  sceneId: string
+  objectId?: string
+  field: string // 'name', 'content' or 'meta.<key>'
+  snippet: string
+}
+
+export interface SearchResults {
+  query: string
+  matches: SearchMatch[]
+  truncated: boolean
+}
+
+/** Searches the project's scene names, text and object meta, ignoring case. */
+export function searchProject(
+  projectId: string,
+  query: string,
+  limit?: number,
+): Promise<SearchResults> {
+  const params = new URLSearchParams({ q: query })
+  if (limit !== undefined) params.set('limit', String(limit))
+  return apiFetch<SearchResults>(`/api/projects/${projectId}/search?${params}`)
+}
+
 export function getLatestSnapshot(projectId: string): Promise<InDocument> {
   return apiFetch<InDocument>(`/api/projects/${projectId}/snapshots/latest`)
 }
+
+/** A saved version of a project's document, without the document itself. */
+export interface SnapshotInfo {
+  id: string
+  version: number
+  seq: number
+  label: string // Why it was saved, e.g. 'pre: scene.delete by Ada'; '' for autosaves
+  safety: boolean // Taken before a destructive operation
+  documentBytes: number
+  createdAt: string
+}
+
+/** Lists the project's snapshots, newest first, skipping the first offset. */
+export function listSnapshots(
+  projectId: string,
+  options: { limit?: number; offset?: number } = {},
+): Promise<SnapshotInfo[]> {
+  const params = new URLSearchParams()
+  if (options.limit !== undefined) params.set('limit', String(options.limit))
+  if (options.offset !== undefined) params.set('offset', String(options.offset))
+  const query = params.toString()
+  return apiFetch<SnapshotInfo[]>(
+    `/api/projects/${projectId}/snapshots${query ? `?${query}` : ''}`,
+  )
+}
+
+/** Fetches the document saved as the given snapshot version. */
+export function getSnapshot(projectId: string, version: number): Promise<InDocument> {
+  return apiFetch<InDocument>(`/api/projects/${projectId}/snapshots/${version}`)
+}
+
+export interface RecordedOperation {
+  seq: number
+  userId: string
+  timestamp: number // Server time the op was applied (Unix ms)
+  operation: Operation
+}
+
+/** A span of a live session: the starting document and the ops after it. */
+export interface Recording {
+  fromSeq: number
+  toSeq: number
+  document: InDocument
+  operations: RecordedOperation[]
+}
+
+export function getRecording(
+  projectId: string,
+  options: { fromSeq?: number; toSeq?: number; raw?: boolean } = {},
+): Promise<Recording> {
+  const params = new URLSearchParams()
+  if (options.fromSeq !== undefined) params.set('fromSeq', String(options.fromSeq))
+  if (options.toSeq !== undefined) params.set('toSeq', String(options.toSeq))
+  if (options.raw) params.set('raw', 'true')
+  const query = params.toString()
+  return apiFetch<Recording>(
+    `/api/projects/${projectId}/recording${query ? `?${query}` : ''}`,
+  )
+}
@@ -40,6 +40,7 @@ interface CanvasViewportProps {
   sceneWidth: number;
   sceneHeight: number;
   sceneBackground: string;
+  sceneId?: string; // Remote cursors are only drawn over the same scene
   selectedObjectIds: string[];
   activeTool: Tool;
   spaceHeld: boolean;
@@ -88,6 +89,7 @@ export function CanvasViewport({
   sceneWidth,
   sceneHeight,
   sceneBackground,
+  sceneId,
   selectedObjectIds,
   activeTool,
   spaceHeld,
@@ -1168,7 +1170,12 @@ export function CanvasViewport({
       </div>
 
       {/* Cursor overlay - needs to account for pan/zoom */}
-      <CursorOverlay containerRef={containerRef} pan={pan} zoom={zoom} />
+      <CursorOverlay
+        containerRef={containerRef}
+        pan={pan}
+        zoom={zoom}
+        sceneId={sceneId}
+      />
 
       {/* Zoom controls */}
       <div className="absolute bottom-4 right-4 flex items-center gap-1 rounded bg-gray-800/80 p-1">
@@ -5,12 +5,14 @@ interface CursorOverlayProps {
   containerRef: React.RefObject<HTMLDivElement | null>;
   pan?: { x: number; y: number };
   zoom?: number;
+  sceneId?: string;
 }
 
 export function CursorOverlay({
   containerRef,
   pan = { x: 0, y: 0 },
   zoom = 1,
+  sceneId,
 }: CursorOverlayProps) {
   const presences = useEditorStore((s) => s.presences);
   const localUserId = useEditorStore((s) => s.localUserId);
@@ -46,15 +48,28 @@ export function CursorOverlay({
     updateLayout();
   }, [presences, updateLayout]);
 
-  // Filter out local user's cursor and entries without cursor position
-  const entries = Array.from(presences.values()).filter(
-    (p) => p.cursor !== null && p.userId !== localUserId,
+  // Remote users on this scene; those who didn't say which they're on are
+  // assumed to be
+  const remote = Array.from(presences.values()).filter(
+    (p) =>
+      p.userId !== localUserId &&
+      (!p.sceneId || !sceneId || p.sceneId === sceneId),
   );
 
+  // Cursors are in scene space; legacy ones (sent without a space) are drawn
+  // as if they were too
+  const entries = remote.filter((p) => p.cursor !== null);
+
+  // Remote selections, outlined from the bounds the server resolved
+  const selections = remote.filter((p) => p.selectionBounds !== null);
+
   if (!layout) return null;
 
   return (
     <div className="pointer-events-none absolute inset-0">
+      {selections.map((entry) => (
+        <SelectionBox key={entry.userId} entry={entry} layout={layout} />
+      ))}
       {entries.map((entry) => (
         <CursorMarker key={entry.userId} entry={entry} layout={layout} />
       ))}
@@ -62,6 +77,30 @@ export function CursorOverlay({
   );
 }
 
+function SelectionBox({
+  entry,
+  layout,
+}: {
+  entry: PresenceEntry;
+  layout: { offsetX: number; offsetY: number; scaleX: number; scaleY: number };
+}) {
+  const bounds = entry.selectionBounds;
+  if (!bounds) return null;
+
+  return (
+    <div
+      className="absolute border border-dashed"
+      style={{
+        left: layout.offsetX + bounds.x * layout.scaleX,
+        top: layout.offsetY + bounds.y * layout.scaleY,
+        width: bounds.width * layout.scaleX,
+        height: bounds.height * layout.scaleY,
+        borderColor: entry.color,
+      }}
+    />
+  );
+}
+
 function CursorMarker({
   entry,
   layout,
@@ -25,11 +25,16 @@ interface MenuBarProps {
   onSelectAll: () => void;
   onDeselect: () => void;
   onNewDocument: () => void;
+  // Copy the playground into a project (logged-in playground users only)
+  onSaveAsProject?: () => void;
   onExportPng: () => void;
   onExportPngSequence: () => void;
   onExportMp4: () => void;
+  onExportMp4HighFps: () => void; // MP4 sampled at 60 fps
   onExportGif: () => void;
   onExportWebm: () => void;
+  // Every scene as its own MP4 in one job (multi-scene documents only)
+  onExportAllScenes?: () => void;
   onExportHTML?: () => void;
   isExporting?: boolean;
   onZoomIn: () => void;
@@ -49,6 +54,7 @@ interface MenuBarProps {
   onUngroup?: () => void;
   canGroup?: boolean;
   canUngroup?: boolean;
+  onResetTransform?: () => void;
   // Undo/Redo
   onUndo?: () => void;
   onRedo?: () => void;
@@ -62,6 +68,7 @@ interface MenuBarProps {
   onDuplicate?: () => void;
   // SVG import
   onImportSvg?: () => void;
+  onImportImageSequence?: () => void;
   // Grid & snap
   gridEnabled?: boolean;
   snapToGrid?: boolean;
@@ -69,6 +76,9 @@ interface MenuBarProps {
   onToggleGrid?: () => void;
   onToggleSnap?: () => void;
   onSetGridSize?: (size: number) => void;
+  // Safe-area guides
+  safeAreaGuides?: boolean;
+  onToggleSafeAreaGuides?: () => void;
 }
 
 export function MenuBar({
@@ -79,11 +89,14 @@ export function MenuBar({
   onSelectAll,
   onDeselect,
   onNewDocument,
+  onSaveAsProject,
   onExportPng,
   onExportPngSequence,
   onExportMp4,
+  onExportMp4HighFps,
   onExportGif,
   onExportWebm,
+  onExportAllScenes,
   onExportHTML,
   isExporting,
   onZoomIn,
@@ -100,6 +113,7 @@ export function MenuBar({
   onUngroup,
   canGroup,
   canUngroup,
+  onResetTransform,
   onUndo,
   onRedo,
   canUndo,
@@ -110,12 +124,15 @@ export function MenuBar({
   canPaste,
   onDuplicate,
   onImportSvg,
+  onImportImageSequence,
   gridEnabled,
   snapToGrid,
   gridSize,
   onToggleGrid,
   onToggleSnap,
   onSetGridSize,
+  safeAreaGuides,
+  onToggleSafeAreaGuides,
 }: MenuBarProps) {
   const [openMenu, setOpenMenu] = useState<string | null>(null);
   const barRef = useRef<HTMLDivElement>(null);
@@ -132,11 +149,18 @@ export function MenuBar({
           shortcut: isMac() ? "Cmd+N" : "Ctrl+N",
           action: onNewDocument,
         },
+        ...(onSaveAsProject
+          ? [{ label: "Save as Project", action: onSaveAsProject }]
+          : []),
         {
           label: "Import SVG...",
           shortcut: isMac() ? "Cmd+I" : "Ctrl+I",
           action: onImportSvg,
         },
+        {
+          label: "Import Image Sequence...",
+          action: onImportImageSequence,
+        },
         { separator: true },
         {
           label: "Export Frame as PNG",
@@ -154,6 +178,11 @@ export function MenuBar({
           action: onExportMp4,
           disabled: isExporting,
         },
+        {
+          label: "Export MP4 Video (60 fps)",
+          action: onExportMp4HighFps,
+          disabled: isExporting,
+        },
         {
           label: "Export GIF",
           action: onExportGif,
@@ -164,6 +193,15 @@ export function MenuBar({
           action: onExportWebm,
           disabled: isExporting,
         },
+        ...(onExportAllScenes
+          ? [
+              {
+                label: "Export All Scenes as MP4",
+                action: onExportAllScenes,
+                disabled: isExporting,
+              },
+            ]
+          : []),
         { separator: true },
         {
           label: "Export HTML Animation",
@@ -244,6 +282,11 @@ export function MenuBar({
           action: onUngroup,
           disabled: !canUngroup,
         },
+        {
+          label: "Reset Transform",
+          action: onResetTransform,
+          disabled: !hasSelection,
+        },
         { separator: true },
         {
           label: "Bring to Front",
@@ -304,6 +347,10 @@ export function MenuBar({
           shortcut: "Shift+G",
           action: onToggleSnap,
         },
+        {
+          label: safeAreaGuides ? "Hide Safe Areas" : "Show Safe Areas",
+          action: onToggleSafeAreaGuides,
+        },
         { separator: true },
         {
           label: `Grid: 10px${gridSize === 10 ? "  \u2713" : ""}`,
@@ -1,4 +1,5 @@
 import { useCallback } from "react";
+import { normalizeColor } from "../../utils/color";
 import type {
   ObjectNode,
   Scene,
@@ -23,6 +24,7 @@ interface PropertiesPanelProps {
     changes: { transform?: Partial<Transform>; style?: Partial<Style> },
   ) => void;
   onDataUpdate?: (objectId: string, data: Record<string, unknown>) => void;
+  onDetachSymbol?: (objectId: string) => void;
   onAlign?: (type: AlignType) => void;
   onDistribute?: (axis: "horizontal" | "vertical") => void;
 }
@@ -34,6 +36,7 @@ export function PropertiesPanel({
   onSceneUpdate,
   onObjectUpdate,
   onDataUpdate,
+  onDetachSymbol,
   onAlign,
   onDistribute,
 }: PropertiesPanelProps) {
@@ -476,6 +479,14 @@ function ObjectProperties({
               className={`h-3.5 w-3.5 rounded border-gray-600 bg-gray-800 text-blue-500 focus:ring-blue-500 focus:ring-offset-0 ${isLocked ? "opacity-50 cursor-not-allowed" : ""}`}
             />
           </div>
+          <button
+            onClick={() => onDetachSymbol?.(object.id)}
+            disabled={isLocked}
+            title="Replace this instance with a plain group of its contents"
+            className={`mt-1 w-full rounded bg-gray-800 px-2 py-1 text-xs text-gray-300 ${isLocked ? "opacity-50 cursor-not-allowed" : "hover:bg-gray-700 hover:text-white"}`}
+          >
+            Detach Symbol
+          </button>
         </Section>
       )}
       {object.type === "RasterImage" && (
@@ -688,19 +699,33 @@ function ColorPropRow({
           {isNone ? "∅" : ""}
         </button>
       )}
+      {/* The picker has no alpha; keep the current one */}
       <input
         type="color"
-        value={value}
-        onChange={(e) => onChange(e.target.value)}
+        value={value.slice(0, 7)}
+        onChange={(e) => onChange(e.target.value + value.slice(7, 9))}
         className={`h-5 w-5 cursor-pointer rounded border border-gray-700 bg-transparent ${isNone || disabled ? "opacity-30" : ""}`}
         disabled={isNone || disabled}
       />
       <span className="text-xs text-gray-400">{label}</span>
+      {/* Commit on blur/Enter: partial input like "rgb(2" isn't a color */}
       <input
+        key={value}
         type="text"
-        value={isNone ? "none" : value}
-        onChange={(e) => onChange(e.target.value)}
-        onKeyDown={(e) => e.stopPropagation()}
+        defaultValue={isNone ? "none" : value}
+        onBlur={(e) => {
+          const next = e.target.value.trim();
+          if (next === value) return;
+          if (normalizeColor(next) === null) {
+            e.target.value = isNone ? "none" : value;
+            return;
+          }
+          onChange(next);
+        }}
+        onKeyDown={(e) => {
+          e.stopPropagation();
+          if (e.key === "Enter") e.currentTarget.blur();
+        }}
         className={`ml-auto w-16 rounded border border-gray-700 bg-gray-800 px-1 py-0.5 text-right text-xs text-gray-500 focus:border-blue-500 focus:outline-none ${isNone || disabled ? "italic opacity-50" : ""}`}
         disabled={isNone || disabled}
       />
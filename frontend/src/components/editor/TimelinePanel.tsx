@@ -70,6 +70,9 @@ const EASING_OPTIONS: { value: EasingType; label: string }[] = [
   { value: "backInOut", label: "Back In/Out" },
   { value: "elasticOut", label: "Elastic Out" },
   { value: "bounceOut", label: "Bounce Out" },
+  { value: "spring", label: "Spring" },
+  { value: "springSoft", label: "Spring (Soft)" },
+  { value: "springStiff", label: "Spring (Stiff)" },
 ];
 
 const LAYER_NAME_WIDTH = 144; // w-36 = 9rem = 144px
@@ -101,6 +104,9 @@ const TEXT_PROPERTIES = [
   { key: "data.fontFamily", label: "Font", short: "Ff" },
   { key: "data.fontWeight", label: "Weight", short: "Fw" },
   { key: "data.textAlign", label: "Align", short: "Al" },
+  { key: "data.letterSpacing", label: "Spacing", short: "LS" },
+  { key: "data.visibleCharacters", label: "Visible Chars", short: "VC" },
+  { key: "data.pathOffset", label: "Path Offset", short: "PO" },
 ] as const;
 
 type AnimatableProperty = { key: string; label: string; short: string };
@@ -401,6 +407,17 @@ export function TimelinePanel({
     [],
   );
 
+  // Built-in easings followed by the document's named presets
+  const easingOptions = useMemo(() => {
+    const presets = Object.keys(doc.easingPresets ?? {})
+      .sort()
+      .map((name) => ({
+        value: `preset:${name}` as EasingType,
+        label: name,
+      }));
+    return [...EASING_OPTIONS, ...presets];
+  }, [doc.easingPresets]);
+
   const handleEasingSelect = useCallback(
     (easing: EasingType) => {
       if (contextMenu && onUpdateKeyframeEasing) {
@@ -1241,7 +1258,7 @@ export function TimelinePanel({
             <div className="px-2 py-1 text-[10px] text-gray-500 uppercase tracking-wide">
               Easing
             </div>
-            {EASING_OPTIONS.map((option) => (
+            {easingOptions.map((option) => (
               <button
                 key={option.value}
                 onClick={() => handleEasingSelect(option.value)}
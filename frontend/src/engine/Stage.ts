@@ -26,6 +26,8 @@ export interface StageEvents {
   onFrameChange?: (frame: number) => void;
   onPlayStateChange?: (playing: boolean) => void;
   onSelectionChange?: (objectIds: string[]) => void;
+  // A loaded document is newer than the engine or has unknown object types
+  onDocumentDiagnostics?: (diagnostics: wasm.Diagnostics) => void;
 }
 
 /**
@@ -78,6 +80,9 @@ export class Stage {
   private gridEnabled = false;
   private gridSize = 20;
 
+  // Safe-area guide state
+  private safeAreaGuidesEnabled = false;
+
   constructor() {}
 
   /**
@@ -103,6 +108,10 @@ export class Stage {
         wasm.setSelection(this.pendingSelection);
         this.pendingSelection = null;
       }
+
+      if (this.safeAreaGuidesEnabled) {
+        wasm.setSafeAreaGuides(true);
+      }
     }
 
     // Start the render loop
@@ -140,6 +149,10 @@ export class Stage {
     this.assets = doc.assets || {};
     this.docObjects = doc.objects || {};
     this.scene = wasm.getScene();
+    const diagnostics = wasm.getDiagnostics();
+    if (diagnostics.newer || diagnostics.unknownObjects.length > 0) {
+      this.events.onDocumentDiagnostics?.(diagnostics);
+    }
     this.updateFrameInterval();
     this.resizeCanvas();
     this.needsRender = true;
@@ -276,6 +289,15 @@ export class Stage {
     this.events.onPlayStateChange?.(false);
   }
 
+  /**
+   * Animate Symbol timelines while paused (e.g. while scrubbing).
+   */
+  setAnimationPreview(enabled: boolean): void {
+    if (!this.wasmReady) return;
+    wasm.setAnimationPreview(enabled);
+    this.needsRender = true;
+  }
+
   seek(frame: number): void {
     if (!this.wasmReady) return;
     wasm.setPlayhead(frame);
@@ -283,6 +305,24 @@ export class Stage {
     this.events.onFrameChange?.(frame);
   }
 
+  /**
+   * Draw the document at a time in seconds, which may fall between frames,
+   * without moving the playhead. Used by exports sampling at their own fps.
+   * The next paused re-render (e.g. after seek or invalidate) replaces it.
+   */
+  renderAtTime(seconds: number): void {
+    if (!this.wasmReady || !this.ctx || !this.scene) return;
+    this.lastCommands = wasm.renderAtTime(seconds);
+    executeCommands(
+      this.ctx,
+      this.lastCommands,
+      this.scene.background,
+      this.dpr,
+      this.assets,
+    );
+    this.needsRender = false;
+  }
+
   /**
    * Get the current frame number.
    */
@@ -310,6 +350,17 @@ export class Stage {
     this.needsRender = true;
   }
 
+  /**
+   * Show or hide the scene's safe-area guides.
+   */
+  setSafeAreaGuides(enabled: boolean): void {
+    this.safeAreaGuidesEnabled = enabled;
+    if (this.wasmReady) {
+      wasm.setSafeAreaGuides(enabled);
+    }
+    this.needsRender = true;
+  }
+
   /**
    * Force a re-render (e.g., during drag operations).
    */
@@ -660,6 +711,11 @@ export class Stage {
       );
     }
 
+    // Render safe-area guides in scene space, so they follow the viewport
+    if (this.safeAreaGuidesEnabled) {
+      executeCommandsNoClear(this.ctx, wasm.renderOverlay(), this.dpr);
+    }
+
     // Render selection outlines
     if (this.selectedObjectIds.length > 0) {
       const showHandles = this.selectedObjectIds.length === 1;
@@ -20,31 +20,448 @@ import type {
   DeleteObjectOp,
   CreateObjectOp,
   ReparentObjectOp,
+  ReorderObjectOp,
   SetVisibilityOp,
   SetLockedOp,
+  SoloVisibilityOp,
+  SetRootTimelineOp,
+  UpdateProjectOp,
   UpdateDataOp,
+  SetMetaOp,
+  ResetTransformOp,
   CreateTrackOp,
   DeleteTrackOp,
+  ReverseTrackOp,
   UpdateTimelineOp,
+  InsertTimeOp,
+  RemoveTimeOp,
   UpdateSceneOp,
   CreateSceneOp,
   DeleteSceneOp,
   AddKeyframeOp,
   UpdateKeyframeOp,
+  ConvertEasingToBezierOp,
   DeleteKeyframeOp,
+  CreateEasingPresetOp,
+  UpdateEasingPresetOp,
+  DeleteEasingPresetOp,
+  CopyAnimationOp,
+  RestoreAnimationOp,
+  AnimationSnapshot,
+  InsertPathPointOp,
+  MovePathPointOp,
+  DeletePathPointOp,
+  SetPathClosedOp,
+  DetachSymbolOp,
+  RestoreSymbolOp,
 } from "../types/operations";
-import type { ObjectNode } from "../types/document";
-import type { Message } from "../types/protocol";
+import type {
+  EasingType,
+  InDocument,
+  Keyframe,
+  ObjectNode,
+  PathCommand,
+  SpringParams,
+  Track,
+  Transform,
+  VectorPathData,
+} from "../types/document";
+import type {
+  Message,
+  OperationBatchAckPayload,
+  OperationBatchBroadcastPayload,
+  OperationBatchNackPayload,
+} from "../types/protocol";
+import {
+  deletePathPoint,
+  getPathPoint,
+  insertPathPoint,
+  isSubpathClosed,
+  movePathPoint,
+  pathVertexCommands,
+  setPathClosed,
+} from "./pathUtils";
+import { normalizeColor } from "../utils/color";
+import { newId } from "../utils/typeid";
+import { prepareOperation } from "./wasmBridge";
 
 // Maximum undo history size
 const MAX_UNDO_STACK = 100;
 
+// Track properties whose keyframe values are colors
+const COLOR_PROPERTIES = new Set(["style.fill", "style.stroke"]);
+
+/**
+ * Canonicalize fill/stroke colors to "#rrggbbaa" the same way the server
+ * does, so the optimistic state matches what the server stores. Returns
+ * null if a color is invalid (the server would reject the operation).
+ */
+function normalizeOperationColors(
+  op: Operation,
+  doc: InDocument,
+): Operation | null {
+  switch (op.type) {
+    case "object.style": {
+      const style = { ...op.style };
+      for (const key of ["fill", "stroke"] as const) {
+        const value = style[key];
+        if (value === undefined) continue;
+        const normalized = normalizeColor(value);
+        if (normalized === null) return null;
+        style[key] = normalized;
+      }
+      return { ...op, style };
+    }
+
+    case "keyframe.add": {
+      const track = doc.tracks[op.trackId];
+      const value = op.keyframe.value;
+      if (!track || !COLOR_PROPERTIES.has(track.property)) return op;
+      if (typeof value !== "string") return op;
+      const normalized = normalizeColor(value);
+      if (normalized === null) return null;
+      return { ...op, keyframe: { ...op.keyframe, value: normalized } };
+    }
+
+    case "keyframe.update": {
+      const track = op.trackId
+        ? doc.tracks[op.trackId]
+        : Object.values(doc.tracks).find((t) =>
+            t.keys.includes(op.keyframeId),
+          );
+      const value = op.changes.value;
+      if (!track || !COLOR_PROPERTIES.has(track.property)) return op;
+      if (typeof value !== "string") return op;
+      const normalized = normalizeColor(value);
+      if (normalized === null) return null;
+      return { ...op, changes: { ...op.changes, value: normalized } };
+    }
+
+    default:
+      return op;
+  }
+}
+
+/**
+ * Resolve object.solo targets: the given objects become visible and each of
+ * their siblings is hidden. Must match the server's DocumentState.applySolo.
+ */
+function soloTargets(
+  doc: InDocument,
+  objectIds: string[],
+): Record<string, boolean> {
+  const solo = new Set(objectIds);
+  const targets: Record<string, boolean> = {};
+  for (const id of objectIds) {
+    const obj = doc.objects[id];
+    if (!obj) continue;
+    targets[id] = true;
+    const parent = obj.parent ? doc.objects[obj.parent] : undefined;
+    if (!parent) continue;
+    for (const siblingId of parent.children) {
+      if (!solo.has(siblingId)) targets[siblingId] = false;
+    }
+  }
+  return targets;
+}
+
+/**
+ * Apply per-object boolean states (bulk visibility/locked) to the objects map.
+ */
+function applyBoolStates(
+  objects: Record<string, ObjectNode>,
+  states: Record<string, boolean>,
+  field: "visible" | "locked",
+): Record<string, ObjectNode> {
+  const newObjects = { ...objects };
+  for (const [id, value] of Object.entries(states)) {
+    const obj = newObjects[id];
+    if (obj) newObjects[id] = { ...obj, [field]: value };
+  }
+  return newObjects;
+}
+
+/**
+ * Capture the current value of a boolean field for each object in states.
+ */
+function captureBoolStates(
+  doc: InDocument,
+  states: Record<string, boolean>,
+  field: "visible" | "locked",
+): Record<string, boolean> {
+  const previous: Record<string, boolean> = {};
+  for (const id of Object.keys(states)) {
+    const obj = doc.objects[id];
+    if (obj) previous[id] = obj[field];
+  }
+  return previous;
+}
+
+/**
+ * Work out a timeline.removeTime, matching the server's planTimeRemovalLocked:
+ * the new frame of each keyframe that moves, the keyframes deleted, and, per
+ * track, the keyframes deleted or clamped as they were. Null if the removal
+ * is invalid.
+ */
+function planTimeRemoval(
+  doc: InDocument,
+  op: RemoveTimeOp,
+): {
+  frames: Record<string, number>;
+  deleted: Set<string>;
+  removed: Record<string, Keyframe[]>;
+} | null {
+  const timeline = doc.timelines[op.timelineId];
+  if (!timeline || op.atFrame < 0 || op.frames <= 0) return null;
+  if (timeline.length - op.frames < 1) return null;
+
+  const at = op.atFrame;
+  const end = at + op.frames;
+  const frames: Record<string, number> = {};
+  const deleted = new Set<string>();
+  const removed: Record<string, Keyframe[]> = {};
+  for (const trackId of timeline.tracks) {
+    const landing: Keyframe[] = []; // Keyframes that end up on frame at
+    for (const keyId of doc.tracks[trackId]?.keys ?? []) {
+      const kf = doc.keyframes[keyId];
+      if (!kf || kf.frame < at) continue;
+      if (kf.frame >= end) {
+        frames[keyId] = kf.frame - op.frames;
+        if (kf.frame === end) landing.push(kf);
+        continue;
+      }
+      if (!removed[trackId]) removed[trackId] = [];
+      removed[trackId].push(kf);
+      if (op.clamp) {
+        frames[keyId] = at;
+        landing.push(kf);
+      } else {
+        deleted.add(keyId);
+      }
+    }
+
+    // Keep the latest of the keyframes landing on at
+    landing.sort((a, b) => a.frame - b.frame);
+    for (const kf of landing.slice(0, -1)) deleted.add(kf.id);
+  }
+  return { frames, deleted, removed };
+}
+
+/**
+ * The identity transform with t's anchor point, matching the server's reset.
+ */
+function resetTransform(t: Transform): Transform {
+  return {
+    x: 0,
+    y: 0,
+    sx: 1,
+    sy: 1,
+    r: 0,
+    ax: t.ax,
+    ay: t.ay,
+    skewX: 0,
+    skewY: 0,
+  };
+}
+
+/**
+ * The transform each object of an object.resetTransform ends up with.
+ */
+function resetTargets(
+  doc: InDocument,
+  op: ResetTransformOp,
+): Record<string, Transform> {
+  if (op.transforms) return op.transforms;
+  const targets: Record<string, Transform> = {};
+  for (const id of op.objectIds ?? []) {
+    const obj = doc.objects[id];
+    if (obj) targets[id] = resetTransform(obj.transform);
+  }
+  return targets;
+}
+
+/**
+ * Commands of a VectorPath object, or null for other objects.
+ */
+function pathCommands(doc: InDocument, objectId: string): PathCommand[] | null {
+  const obj = doc.objects[objectId];
+  if (obj?.type !== "VectorPath") return null;
+  return (obj.data as VectorPathData).commands ?? [];
+}
+
+/**
+ * Tracks on a timeline that animate objectId, in timeline order.
+ */
+function sourceTracks(
+  doc: InDocument,
+  timelineId: string,
+  objectId: string,
+): Track[] {
+  const timeline = doc.timelines[timelineId];
+  if (!timeline) return [];
+  return timeline.tracks
+    .map((id) => doc.tracks[id])
+    .filter((t): t is Track => !!t && t.objectId === objectId);
+}
+
+/**
+ * Source tracks an animation.copy clones: all of the source object's tracks,
+ * or only op.trackIds when given.
+ */
+function copySources(doc: InDocument, op: CopyAnimationOp): Track[] {
+  const all = sourceTracks(doc, op.timelineId, op.sourceObjectId);
+  if (!op.trackIds?.length) return all;
+  const wanted = new Set(op.trackIds);
+  return all.filter((t) => wanted.has(t.id));
+}
+
+/**
+ * Capture every track and keyframe animating objectIds on a timeline.
+ */
+function snapshotAnimation(
+  doc: InDocument,
+  timelineId: string,
+  objectIds: string[],
+): AnimationSnapshot {
+  const targets = new Set(objectIds);
+  const snapshot: AnimationSnapshot = { objectIds, tracks: [], keyframes: [] };
+  for (const trackId of doc.timelines[timelineId]?.tracks ?? []) {
+    const track = doc.tracks[trackId];
+    if (!track || !targets.has(track.objectId)) continue;
+    snapshot.tracks.push({ ...track });
+    for (const keyId of track.keys) {
+      const kf = doc.keyframes[keyId];
+      if (kf) snapshot.keyframes.push({ ...kf });
+    }
+  }
+  return snapshot;
+}
+
+/**
+ * Return a document with the matching tracks (and their keyframes) removed
+ * from a timeline.
+ */
+function removeTracks(
+  doc: InDocument,
+  timelineId: string,
+  match: (track: Track) => boolean,
+): InDocument {
+  const timeline = doc.timelines[timelineId];
+  if (!timeline) return doc;
+  const tracks = { ...doc.tracks };
+  const keyframes = { ...doc.keyframes };
+  const kept: string[] = [];
+  for (const trackId of timeline.tracks) {
+    const track = tracks[trackId];
+    if (track && match(track)) {
+      for (const keyId of track.keys) delete keyframes[keyId];
+      delete tracks[trackId];
+    } else {
+      kept.push(trackId);
+    }
+  }
+  return {
+    ...doc,
+    tracks,
+    keyframes,
+    timelines: {
+      ...doc.timelines,
+      [timelineId]: { ...timeline, tracks: kept },
+    },
+  };
+}
+
+/**
+ * Return a document with the subtree in `old` replaced by the one in `next`
+ * (each root first). The new root takes the old root's place under parentId,
+ * or fallbackIndex if the old root isn't there.
+ */
+function swapSubtree(
+  doc: InDocument,
+  parentId: string,
+  old: ObjectNode[],
+  next: ObjectNode[],
+  fallbackIndex: number,
+): InDocument {
+  const objects = { ...doc.objects };
+  for (const obj of old) delete objects[obj.id];
+  for (const obj of next) objects[obj.id] = obj;
+
+  const parent = objects[parentId];
+  if (!parent) return doc;
+  let index = parent.children.indexOf(old[0].id);
+  if (index < 0) index = fallbackIndex;
+  const children = parent.children.filter((id) => id !== old[0].id);
+  index = Math.max(0, Math.min(index, children.length));
+  children.splice(index, 0, next[0].id);
+  objects[parentId] = { ...parent, children };
+  return { ...doc, objects };
+}
+
+// Easings that play each other backwards in time (used by track.reverse)
+const MIRRORED_EASINGS: Partial<Record<EasingType, EasingType>> = {
+  easeIn: "easeOut",
+  easeOut: "easeIn",
+  cubicIn: "cubicOut",
+  cubicOut: "cubicIn",
+  backIn: "backOut",
+  backOut: "backIn",
+};
+
+const SPRING_EASINGS: ReadonlySet<EasingType> = new Set<EasingType>([
+  "spring",
+  "springSoft",
+  "springStiff",
+]);
+
+// The curve b played backwards in time, rounded as the server rounds it (to
+// the default document precision) so mirroring twice gives back b. Must
+// match the server's track.reverse.
+function mirroredBezier(b: [number, number, number, number]): [number, number, number, number] {
+  const round = (v: number) => Math.round(v * 1000) / 1000;
+  return [round(1 - b[2]), round(1 - b[3]), round(1 - b[0]), round(1 - b[1])];
+}
+
+// The spring parameters playing a spring backwards in time; other easings'
+// parameters are unchanged. Must match the server's
+// document.MirroredEasingParams.
+function mirroredEasingParams(
+  easing: EasingType,
+  params: SpringParams | null | undefined,
+): SpringParams | null | undefined {
+  if (!SPRING_EASINGS.has(easing)) return params;
+  const { reversed, ...rest } = params ?? {};
+  const mirrored: SpringParams = reversed ? rest : { ...rest, reversed: true };
+  return Object.keys(mirrored).length > 0 ? mirrored : undefined;
+}
+
+// Control points of the cubicBezier drawing each named easing that one curve
+// can draw (used by keyframe.convertEasingToBezier). Must match the server's
+// document.easingBeziers.
+const EASING_BEZIERS: Partial<
+  Record<EasingType, [number, number, number, number]>
+> = {
+  linear: [0, 0, 1, 1],
+  easeIn: [1 / 3, 0, 2 / 3, 1 / 3],
+  easeOut: [1 / 3, 2 / 3, 2 / 3, 1],
+  easeInOut: [0.485, 0.045, 0.515, 0.955],
+  cubicIn: [1 / 3, 0, 2 / 3, 0],
+  cubicOut: [1 / 3, 1, 2 / 3, 1],
+  cubicInOut: [0.625, -0.04, 0.375, 1.04],
+  backIn: [1 / 3, 0, 2 / 3, -1.70158 / 3],
+  backOut: [1 / 3, 1 + 1.70158 / 3, 2 / 3, 1],
+  backInOut: [0.74, -0.515, 0.26, 1.515],
+};
+
 class CommandDispatcher {
   private pendingOps = new Map<string, Operation>();
   private clientSeq = 0;
   private undoStack: Operation[] = [];
   private redoStack: Operation[] = [];
   private sendFn: ((msg: Message) => void) | null = null;
+  private batchDepth = 0;
+  private batchOps: Operation[] = [];
+  private pendingBatches = new Map<string, Operation[]>();
 
   /**
    * Set the WebSocket send function for backend sync.
@@ -54,6 +471,31 @@ class CommandDispatcher {
     this.sendFn = fn;
   }
 
+  /**
+   * Collect the operations dispatched until the matching endBatch() and send
+   * them as one op.batch, which the server applies all or nothing and
+   * broadcasts in one message. Use it for gestures that touch many objects,
+   * such as a drag. Batches nest; only the outermost sends.
+   */
+  beginBatch(): void {
+    this.batchDepth++;
+  }
+
+  endBatch(): void {
+    if (this.batchDepth === 0 || --this.batchDepth > 0) return;
+    const ops = this.batchOps;
+    this.batchOps = [];
+    if (ops.length === 0 || !this.sendFn) return;
+
+    if (ops.length === 1) {
+      this.sendFn({ type: "op.submit", payload: ops[0] });
+      return;
+    }
+    const id = crypto.randomUUID();
+    this.pendingBatches.set(id, ops);
+    this.sendFn({ type: "op.batch", payload: { id, operations: ops } });
+  }
+
   /**
    * Dispatch an operation.
    * 1. Adds metadata (id, timestamp, seq)
@@ -68,12 +510,19 @@ class CommandDispatcher {
     if (!doc) return;
 
     // Add metadata
-    const op: Operation = {
-      ...input,
-      id: crypto.randomUUID(),
-      timestamp: Date.now(),
-      clientSeq: ++this.clientSeq,
-    } as Operation;
+    const op = normalizeOperationColors(
+      {
+        ...input,
+        id: crypto.randomUUID(),
+        timestamp: Date.now(),
+        clientSeq: ++this.clientSeq,
+      } as Operation,
+      doc,
+    );
+    if (!op) {
+      console.warn(`Operation rejected: invalid color`);
+      return;
+    }
 
     // Capture previous state for undo
     const opWithPrevious = this.capturePreviousState(op, doc);
@@ -93,10 +542,14 @@ class CommandDispatcher {
     // Send to backend if connected
     if (this.sendFn) {
       this.pendingOps.set(op.id, opWithPrevious);
-      this.sendFn({
-        type: "op.submit",
-        payload: opWithPrevious,
-      });
+      if (this.batchDepth > 0) {
+        this.batchOps.push(opWithPrevious);
+      } else {
+        this.sendFn({
+          type: "op.submit",
+          payload: opWithPrevious,
+        });
+      }
     }
   }
 
@@ -116,21 +569,33 @@ class CommandDispatcher {
   handleNack(nack: OperationNack): void {
     const op = this.pendingOps.get(nack.operationId);
     if (op) {
-      // Rollback by applying inverse
-      const inverse = this.invertOperation(op);
-      if (inverse) {
-        this.applyOperation(inverse);
-      }
-      this.pendingOps.delete(nack.operationId);
+      this.rollback(op);
+      console.warn(`Operation rejected: ${nack.reason}`);
+    }
+  }
 
-      // Remove from undo stack
-      const idx = this.undoStack.findIndex((o) => o.id === op.id);
-      if (idx !== -1) {
-        this.undoStack.splice(idx, 1);
-      }
+  /**
+   * Handle ACK of a batch - all of its operations are confirmed.
+   */
+  handleBatchAck(ack: OperationBatchAckPayload): void {
+    this.pendingBatches.delete(ack.batchId);
+    for (const id of ack.operationIds) {
+      this.pendingOps.delete(id);
+    }
+  }
 
-      console.warn(`Operation rejected: ${nack.reason}`);
+  /**
+   * Handle NACK of a batch - the server applied none of it, so roll back
+   * every operation in it, last first.
+   */
+  handleBatchNack(nack: OperationBatchNackPayload): void {
+    const ops = this.pendingBatches.get(nack.batchId);
+    if (!ops) return;
+    this.pendingBatches.delete(nack.batchId);
+    for (const op of [...ops].reverse()) {
+      this.rollback(op);
     }
+    console.warn(`Operation batch rejected: ${nack.reason}`);
   }
 
   /**
@@ -143,6 +608,16 @@ class CommandDispatcher {
     // Don't add to our undo stack - it's not our operation
   }
 
+  /**
+   * Handle a batch broadcast from another client, applying its operations
+   * in order.
+   */
+  handleRemoteBatch(broadcast: OperationBatchBroadcastPayload): void {
+    for (const op of broadcast.operations) {
+      this.applyOperation(op);
+    }
+  }
+
   /**
    * Undo the last operation.
    */
@@ -227,10 +702,29 @@ class CommandDispatcher {
     this.undoStack = [];
     this.redoStack = [];
     this.pendingOps.clear();
+    this.pendingBatches.clear();
   }
 
   // --- Private Methods ---
 
+  /**
+   * Roll back a rejected optimistic operation by applying its inverse, and
+   * forget it.
+   */
+  private rollback(op: Operation): void {
+    const inverse = this.invertOperation(op);
+    if (inverse) {
+      this.applyOperation(inverse);
+    }
+    this.pendingOps.delete(op.id);
+
+    // Remove from undo stack
+    const idx = this.undoStack.findIndex((o) => o.id === op.id);
+    if (idx !== -1) {
+      this.undoStack.splice(idx, 1);
+    }
+  }
+
   /**
    * Capture previous state for undo support.
    */
@@ -288,7 +782,23 @@ class CommandDispatcher {
         break;
       }
 
+      case "object.reorder": {
+        const obj = doc.objects[op.objectId];
+        const parent = obj?.parent ? doc.objects[obj.parent] : undefined;
+        const oldIndex = parent?.children.indexOf(op.objectId) ?? -1;
+        if (oldIndex >= 0) {
+          return { ...op, previousIndex: oldIndex } as ReorderObjectOp;
+        }
+        break;
+      }
+
       case "object.visibility": {
+        if (op.states) {
+          return {
+            ...op,
+            previousStates: captureBoolStates(doc, op.states, "visible"),
+          } as SetVisibilityOp;
+        }
         const obj = doc.objects[op.objectId];
         if (obj) {
           return {
@@ -299,7 +809,41 @@ class CommandDispatcher {
         break;
       }
 
+      case "object.resetTransform": {
+        const previousTransforms: Record<string, Transform> = {};
+        for (const id of Object.keys(resetTargets(doc, op))) {
+          const obj = doc.objects[id];
+          if (obj) previousTransforms[id] = { ...obj.transform };
+        }
+        return { ...op, previousTransforms } as ResetTransformOp;
+      }
+
+      case "object.nudge": {
+        // Deltas are resolved by the same code the server runs, units and
+        // clamping included
+        try {
+          return prepareOperation(doc, op);
+        } catch (err) {
+          console.warn("Failed to prepare nudge:", err);
+        }
+        break;
+      }
+
+      case "object.solo": {
+        const targets = soloTargets(doc, op.objectIds);
+        return {
+          ...op,
+          previousStates: captureBoolStates(doc, targets, "visible"),
+        } as SoloVisibilityOp;
+      }
+
       case "object.locked": {
+        if (op.states) {
+          return {
+            ...op,
+            previousStates: captureBoolStates(doc, op.states, "locked"),
+          } as SetLockedOp;
+        }
         const obj = doc.objects[op.objectId];
         if (obj) {
           return {
@@ -327,6 +871,43 @@ class CommandDispatcher {
         break;
       }
 
+      case "object.setMeta": {
+        const obj = doc.objects[op.objectId];
+        if (obj) {
+          const previous: Record<string, unknown> = {};
+          for (const key of Object.keys(op.meta)) {
+            previous[key] = obj.meta?.[key] ?? null;
+          }
+          return {
+            ...op,
+            previous,
+          } as SetMetaOp;
+        }
+        break;
+      }
+
+      case "object.detachSymbol": {
+        // The copies and baked values come from the same code the server
+        // runs, so the optimistic apply matches what it broadcasts
+        try {
+          return prepareOperation(doc, op);
+        } catch (err) {
+          console.warn("Failed to prepare detachSymbol:", err);
+        }
+        break;
+      }
+
+      case "object.duplicate": {
+        // The server keeps the IDs prepared here, so the optimistic copies
+        // are the ones it broadcasts
+        try {
+          return prepareOperation(doc, op);
+        } catch (err) {
+          console.warn("Failed to prepare duplicate:", err);
+        }
+        break;
+      }
+
       case "scene.update": {
         const scene = doc.scenes[op.sceneId];
         if (scene) {
@@ -336,6 +917,8 @@ class CommandDispatcher {
           if (op.changes.height !== undefined) previous.height = scene.height;
           if (op.changes.background !== undefined)
             previous.background = scene.background;
+          if (op.changes.safeAreaInsets !== undefined)
+            previous.safeAreaInsets = scene.safeAreaInsets ?? null;
           return {
             ...op,
             previous,
@@ -374,12 +957,28 @@ class CommandDispatcher {
               frame: keyframe.frame,
               value: keyframe.value,
               easing: keyframe.easing,
+              easingParams: keyframe.easingParams ?? null,
+              bezier: keyframe.bezier ?? null,
             },
           } as UpdateKeyframeOp;
         }
         break;
       }
 
+      case "keyframe.convertEasingToBezier": {
+        const keyframe = doc.keyframes[op.keyframeId];
+        if (keyframe) {
+          return {
+            ...op,
+            previous: {
+              easing: keyframe.easing || "linear", // An update can't set an empty easing
+              bezier: keyframe.bezier ?? null,
+            },
+          } as ConvertEasingToBezierOp;
+        }
+        break;
+      }
+
       case "keyframe.delete": {
         const keyframe = doc.keyframes[op.keyframeId];
         if (keyframe) {
@@ -391,6 +990,31 @@ class CommandDispatcher {
         break;
       }
 
+      case "project.setRootTimeline": {
+        return {
+          ...op,
+          previousTimelineId: doc.project.rootTimeline,
+        } as SetRootTimelineOp;
+      }
+
+      case "project.update": {
+        // Unset settings undo to their defaults
+        const previous: UpdateProjectOp["previous"] = {};
+        if (op.changes.units !== undefined)
+          previous.units = doc.project.units ?? "px";
+        if (op.changes.direction !== undefined)
+          previous.direction = doc.project.direction ?? "ltr";
+        return { ...op, previous } as UpdateProjectOp;
+      }
+
+      case "timeline.removeTime": {
+        const plan = planTimeRemoval(doc, op);
+        if (plan) {
+          return { ...op, removedKeyframes: plan.removed } as RemoveTimeOp;
+        }
+        break;
+      }
+
       case "timeline.update": {
         const timeline = doc.timelines[op.timelineId];
         if (timeline) {
@@ -423,6 +1047,97 @@ class CommandDispatcher {
         }
         break;
       }
+
+      case "path.movePoint": {
+        const commands = pathCommands(doc, op.objectId);
+        const previousPoint = commands && getPathPoint(commands, op.index);
+        if (previousPoint) {
+          return { ...op, previousPoint } as MovePathPointOp;
+        }
+        break;
+      }
+
+      case "path.deletePoint": {
+        const commands = pathCommands(doc, op.objectId);
+        if (!commands) break;
+        const ci = pathVertexCommands(commands)[op.index];
+        if (ci !== undefined) {
+          return {
+            ...op,
+            previousCommand: commands[ci],
+          } as DeletePathPointOp;
+        }
+        break;
+      }
+
+      case "path.setClosed": {
+        const commands = pathCommands(doc, op.objectId);
+        if (commands) {
+          return {
+            ...op,
+            previousBool: isSubpathClosed(commands, op.index),
+          } as SetPathClosedOp;
+        }
+        break;
+      }
+
+      case "easingPreset.update": {
+        const preset = doc.easingPresets?.[op.presetName];
+        if (preset) {
+          return {
+            ...op,
+            previousPreset: { ...preset },
+          } as UpdateEasingPresetOp;
+        }
+        break;
+      }
+
+      case "animation.copy": {
+        // Assign IDs up front so the optimistic apply and the server agree
+        const idMap: Record<string, Record<string, string>> = {};
+        const sources = copySources(doc, op);
+        for (const targetId of op.objectIds) {
+          const ids: Record<string, string> = { ...op.idMap?.[targetId] };
+          for (const track of sources) {
+            ids[track.id] ??= newId("track");
+            for (const keyId of track.keys) {
+              ids[keyId] ??= newId("kf");
+            }
+          }
+          idMap[targetId] = ids;
+        }
+        return {
+          ...op,
+          idMap,
+          previousAnimation: snapshotAnimation(
+            doc,
+            op.timelineId,
+            op.objectIds,
+          ),
+        } as CopyAnimationOp;
+      }
+
+      case "animation.restore": {
+        return {
+          ...op,
+          previousAnimation: snapshotAnimation(
+            doc,
+            op.timelineId,
+            op.animation.objectIds,
+          ),
+        } as RestoreAnimationOp;
+      }
+
+      case "easingPreset.delete": {
+        const preset = doc.easingPresets?.[op.presetName];
+        if (preset) {
+          return {
+            ...op,
+            previousPreset: { ...preset },
+          } as DeleteEasingPresetOp;
+        }
+        break;
+      }
     }
 
     return op;
@@ -478,6 +1193,21 @@ class CommandDispatcher {
         } as DeleteObjectOp;
       }
 
+      case "object.duplicate": {
+        // Undoing removes the copy
+        const copyId = op.idMap?.[op.objectId]?.[op.objectId];
+        const copy = op.duplicate?.objects[0];
+        if (!copyId || !copy) return null;
+        return {
+          id: crypto.randomUUID(),
+          type: "object.delete",
+          timestamp: Date.now(),
+          clientSeq: 0,
+          objectId: copyId,
+          previous: copy,
+        } as DeleteObjectOp;
+      }
+
       case "object.reparent": {
         if (!op.previousParentId) return null;
         return {
@@ -490,7 +1220,26 @@ class CommandDispatcher {
         };
       }
 
+      case "object.reorder": {
+        if (op.previousIndex === undefined) return null;
+        return {
+          ...op,
+          id: crypto.randomUUID(),
+          newIndex: op.previousIndex,
+          previousIndex: op.newIndex,
+        };
+      }
+
       case "object.visibility": {
+        if (op.states) {
+          if (!op.previousStates) return null;
+          return {
+            ...op,
+            id: crypto.randomUUID(),
+            states: op.previousStates,
+            previousStates: op.states,
+          };
+        }
         if (op.previous === undefined) return null;
         return {
           ...op,
@@ -500,7 +1249,59 @@ class CommandDispatcher {
         };
       }
 
+      case "object.resetTransform": {
+        if (!op.previousTransforms) return null;
+        // Redoing sets the reset values explicitly
+        const applied: Record<string, Transform> = {};
+        for (const [id, t] of Object.entries(op.previousTransforms)) {
+          applied[id] = op.transforms?.[id] ?? resetTransform(t);
+        }
+        return {
+          ...op,
+          id: crypto.randomUUID(),
+          objectIds: undefined,
+          transforms: op.previousTransforms,
+          previousTransforms: applied,
+        };
+      }
+
+      case "object.nudge": {
+        if (!op.transforms || !op.previousTransforms) return null;
+        // Undo and redo set the resolved transforms explicitly
+        return {
+          id: crypto.randomUUID(),
+          type: "object.resetTransform",
+          timestamp: Date.now(),
+          clientSeq: 0,
+          transforms: op.previousTransforms,
+          previousTransforms: op.transforms,
+        } as ResetTransformOp;
+      }
+
+      case "object.solo": {
+        if (!op.previousStates) return null;
+        // Inverse of solo restores each affected object's visibility
+        return {
+          id: crypto.randomUUID(),
+          type: "object.visibility",
+          timestamp: Date.now(),
+          clientSeq: 0,
+          objectId: "",
+          visible: true,
+          states: op.previousStates,
+        } as SetVisibilityOp;
+      }
+
       case "object.locked": {
+        if (op.states) {
+          if (!op.previousStates) return null;
+          return {
+            ...op,
+            id: crypto.randomUUID(),
+            states: op.previousStates,
+            previousStates: op.states,
+          };
+        }
         if (op.previous === undefined) return null;
         return {
           ...op,
@@ -510,13 +1311,86 @@ class CommandDispatcher {
         };
       }
 
-      case "object.data": {
-        if (!op.previous) return null;
+      case "object.data": {
+        if (!op.previous) return null;
+        return {
+          ...op,
+          id: crypto.randomUUID(),
+          data: op.previous,
+          previous: op.data,
+        };
+      }
+
+      case "object.setMeta": {
+        if (!op.previous) return null;
+        return {
+          ...op,
+          id: crypto.randomUUID(),
+          meta: op.previous,
+          previous: op.meta,
+        };
+      }
+
+      case "object.detachSymbol":
+      case "object.restoreSymbol": {
+        if (!op.detachedObjects || !op.previousSymbol) return null;
+        return {
+          id: crypto.randomUUID(),
+          type:
+            op.type === "object.detachSymbol"
+              ? "object.restoreSymbol"
+              : "object.detachSymbol",
+          timestamp: Date.now(),
+          clientSeq: 0,
+          objectId: op.objectId,
+          idMap: op.idMap,
+          detachedObjects: op.detachedObjects,
+          previousSymbol: op.previousSymbol,
+        } as RestoreSymbolOp | DetachSymbolOp;
+      }
+
+      case "path.insertPoint": {
+        return {
+          id: crypto.randomUUID(),
+          type: "path.deletePoint",
+          timestamp: Date.now(),
+          clientSeq: 0,
+          objectId: op.objectId,
+          index: op.index,
+          previousCommand: op.command,
+        } as DeletePathPointOp;
+      }
+
+      case "path.movePoint": {
+        if (!op.previousPoint) return null;
+        return {
+          ...op,
+          id: crypto.randomUUID(),
+          point: op.previousPoint,
+          previousPoint: op.point,
+        };
+      }
+
+      case "path.deletePoint": {
+        if (!op.previousCommand) return null;
+        return {
+          id: crypto.randomUUID(),
+          type: "path.insertPoint",
+          timestamp: Date.now(),
+          clientSeq: 0,
+          objectId: op.objectId,
+          index: op.index,
+          command: op.previousCommand,
+        } as InsertPathPointOp;
+      }
+
+      case "path.setClosed": {
+        if (op.previousBool === undefined) return null;
         return {
           ...op,
           id: crypto.randomUUID(),
-          data: op.previous,
-          previous: op.data,
+          closed: op.previousBool,
+          previousBool: op.closed,
         };
       }
 
@@ -556,6 +1430,11 @@ class CommandDispatcher {
         } as CreateTrackOp;
       }
 
+      case "track.reverse": {
+        // Reversing is its own inverse
+        return { ...op, id: crypto.randomUUID() } as ReverseTrackOp;
+      }
+
       case "keyframe.add": {
         // Inverse of add is delete
         return {
@@ -579,6 +1458,20 @@ class CommandDispatcher {
         };
       }
 
+      case "keyframe.convertEasingToBezier": {
+        if (!op.previous) return null;
+        // Inverse puts the named easing back
+        return {
+          id: crypto.randomUUID(),
+          type: "keyframe.update",
+          timestamp: Date.now(),
+          clientSeq: 0,
+          keyframeId: op.keyframeId,
+          trackId: op.trackId,
+          changes: op.previous,
+        } as UpdateKeyframeOp;
+      }
+
       case "keyframe.delete": {
         if (!op.previous) return null;
         // Inverse of delete is add
@@ -602,6 +1495,44 @@ class CommandDispatcher {
         };
       }
 
+      case "timeline.insertTime": {
+        return {
+          ...op,
+          id: crypto.randomUUID(),
+          type: "timeline.removeTime",
+          removedKeyframes: undefined,
+        } as RemoveTimeOp;
+      }
+
+      case "timeline.removeTime": {
+        // Inserting the time back restores what the removal deleted
+        return {
+          ...op,
+          id: crypto.randomUUID(),
+          type: "timeline.insertTime",
+        } as InsertTimeOp;
+      }
+
+      case "project.update": {
+        if (!op.previous) return null;
+        return {
+          ...op,
+          id: crypto.randomUUID(),
+          changes: op.previous,
+          previous: op.changes,
+        };
+      }
+
+      case "project.setRootTimeline": {
+        if (!op.previousTimelineId) return null;
+        return {
+          ...op,
+          id: crypto.randomUUID(),
+          timelineId: op.previousTimelineId,
+          previousTimelineId: op.timelineId,
+        };
+      }
+
       case "scene.create": {
         // Inverse of create is delete
         return {
@@ -626,6 +1557,52 @@ class CommandDispatcher {
         } as CreateSceneOp;
       }
 
+      case "animation.copy":
+      case "animation.restore": {
+        if (!op.previousAnimation) return null;
+        return {
+          id: crypto.randomUUID(),
+          type: "animation.restore",
+          timestamp: Date.now(),
+          clientSeq: 0,
+          timelineId: op.timelineId,
+          animation: op.previousAnimation,
+        } as RestoreAnimationOp;
+      }
+
+      case "easingPreset.create": {
+        return {
+          id: crypto.randomUUID(),
+          type: "easingPreset.delete",
+          timestamp: Date.now(),
+          clientSeq: 0,
+          presetName: op.presetName,
+          previousPreset: op.preset,
+        } as DeleteEasingPresetOp;
+      }
+
+      case "easingPreset.update": {
+        if (!op.previousPreset) return null;
+        return {
+          ...op,
+          id: crypto.randomUUID(),
+          preset: op.previousPreset,
+          previousPreset: op.preset,
+        };
+      }
+
+      case "easingPreset.delete": {
+        if (!op.previousPreset) return null;
+        return {
+          id: crypto.randomUUID(),
+          type: "easingPreset.create",
+          timestamp: Date.now(),
+          clientSeq: 0,
+          presetName: op.presetName,
+          preset: op.previousPreset,
+        } as CreateEasingPresetOp;
+      }
+
       default:
         return null;
     }
@@ -755,7 +1732,28 @@ class CommandDispatcher {
         break;
       }
 
+      case "object.reorder": {
+        const obj = doc.objects[op.objectId];
+        const parent = obj?.parent ? doc.objects[obj.parent] : undefined;
+        if (!obj?.parent || !parent) return;
+        // Take the object out first so newIndex is its final position
+        const children = parent.children.filter((id) => id !== op.objectId);
+        children.splice(op.newIndex, 0, op.objectId);
+        store.setDocument({
+          ...doc,
+          objects: { ...doc.objects, [obj.parent]: { ...parent, children } },
+        });
+        break;
+      }
+
       case "object.visibility": {
+        if (op.states) {
+          store.setDocument({
+            ...doc,
+            objects: applyBoolStates(doc.objects, op.states, "visible"),
+          });
+          break;
+        }
         const obj = doc.objects[op.objectId];
         if (!obj) return;
         store.setDocument({
@@ -768,7 +1766,47 @@ class CommandDispatcher {
         break;
       }
 
+      case "object.resetTransform": {
+        const newObjects = { ...doc.objects };
+        for (const [id, transform] of Object.entries(resetTargets(doc, op))) {
+          const obj = newObjects[id];
+          if (obj) newObjects[id] = { ...obj, transform };
+        }
+        store.setDocument({ ...doc, objects: newObjects });
+        break;
+      }
+
+      case "object.nudge": {
+        if (!op.transforms) return;
+        const newObjects = { ...doc.objects };
+        for (const [id, transform] of Object.entries(op.transforms)) {
+          const obj = newObjects[id];
+          if (obj) newObjects[id] = { ...obj, transform };
+        }
+        store.setDocument({ ...doc, objects: newObjects });
+        break;
+      }
+
+      case "object.solo": {
+        store.setDocument({
+          ...doc,
+          objects: applyBoolStates(
+            doc.objects,
+            soloTargets(doc, op.objectIds),
+            "visible",
+          ),
+        });
+        break;
+      }
+
       case "object.locked": {
+        if (op.states) {
+          store.setDocument({
+            ...doc,
+            objects: applyBoolStates(doc.objects, op.states, "locked"),
+          });
+          break;
+        }
         const obj = doc.objects[op.objectId];
         if (!obj) return;
         store.setDocument({
@@ -797,6 +1835,144 @@ class CommandDispatcher {
         break;
       }
 
+      case "object.setMeta": {
+        const obj = doc.objects[op.objectId];
+        if (!obj) return;
+        const meta: Record<string, unknown> = { ...obj.meta };
+        for (const [key, value] of Object.entries(op.meta)) {
+          if (value === null) delete meta[key];
+          else meta[key] = value;
+        }
+        const updated: ObjectNode = { ...obj, meta };
+        if (Object.keys(meta).length === 0) delete updated.meta;
+        store.setDocument({
+          ...doc,
+          objects: { ...doc.objects, [op.objectId]: updated },
+        });
+        break;
+      }
+
+      case "object.detachSymbol": {
+        const snap = op.previousSymbol;
+        const symbol = doc.objects[op.objectId];
+        if (!snap || !op.detachedObjects?.length || !symbol?.parent) return;
+        const ids = op.idMap?.[op.objectId] ?? {};
+        const tracks = { ...doc.tracks };
+        const keyframes = { ...doc.keyframes };
+        const timelines = { ...doc.timelines };
+        if (snap.timeline) {
+          for (const trackId of snap.timeline.tracks) {
+            for (const keyId of tracks[trackId]?.keys ?? []) {
+              delete keyframes[keyId];
+            }
+            delete tracks[trackId];
+          }
+          delete timelines[snap.timeline.id];
+        }
+        for (const track of snap.tracks) {
+          const current = tracks[track.id];
+          if (current && ids[current.objectId]) {
+            tracks[track.id] = { ...current, objectId: ids[current.objectId] };
+          }
+        }
+        store.setDocument(
+          swapSubtree(
+            { ...doc, tracks, keyframes, timelines },
+            symbol.parent,
+            snap.objects,
+            op.detachedObjects,
+            snap.index,
+          ),
+        );
+        break;
+      }
+
+      case "object.duplicate": {
+        const dup = op.duplicate;
+        const parent = op.parentId ? doc.objects[op.parentId] : undefined;
+        if (!dup?.objects.length || !parent) return;
+        const rootId = dup.objects[0].id;
+        if (doc.objects[rootId]) return; // Already applied
+        const objects = { ...doc.objects };
+        for (const obj of dup.objects) objects[obj.id] = obj;
+        const children = [...parent.children];
+        children.splice(op.index ?? children.length, 0, rootId);
+        objects[parent.id] = { ...parent, children };
+        const tracks = { ...doc.tracks };
+        const keyframes = { ...doc.keyframes };
+        const timelines = { ...doc.timelines };
+        for (const [timelineId, snap] of Object.entries(dup.animation ?? {})) {
+          const timeline = timelines[timelineId];
+          if (!timeline) continue;
+          for (const track of snap.tracks) tracks[track.id] = track;
+          for (const kf of snap.keyframes) keyframes[kf.id] = kf;
+          timelines[timelineId] = {
+            ...timeline,
+            tracks: [...timeline.tracks, ...snap.tracks.map((t) => t.id)],
+          };
+        }
+        store.setDocument({ ...doc, objects, tracks, keyframes, timelines });
+        break;
+      }
+
+      case "object.restoreSymbol": {
+        const snap = op.previousSymbol;
+        const group = doc.objects[op.detachedObjects[0]?.id];
+        if (!group?.parent) return;
+        const tracks = { ...doc.tracks };
+        const keyframes = { ...doc.keyframes };
+        const timelines = { ...doc.timelines };
+        if (snap.timeline) timelines[snap.timeline.id] = snap.timeline;
+        for (const track of snap.tracks) tracks[track.id] = track;
+        for (const kf of snap.keyframes) keyframes[kf.id] = kf;
+        store.setDocument(
+          swapSubtree(
+            { ...doc, tracks, keyframes, timelines },
+            group.parent,
+            op.detachedObjects,
+            snap.objects,
+            snap.index,
+          ),
+        );
+        break;
+      }
+
+      case "path.insertPoint":
+      case "path.movePoint":
+      case "path.deletePoint":
+      case "path.setClosed": {
+        const obj = doc.objects[op.objectId];
+        const commands = pathCommands(doc, op.objectId);
+        if (!obj || !commands) return;
+        let updated: PathCommand[] | null = null;
+        switch (op.type) {
+          case "path.insertPoint":
+            updated = insertPathPoint(commands, op.index, op.command);
+            break;
+          case "path.movePoint":
+            updated = movePathPoint(commands, op.index, op.point);
+            break;
+          case "path.deletePoint":
+            updated = deletePathPoint(commands, op.index);
+            break;
+          case "path.setClosed":
+            updated = setPathClosed(commands, op.index, op.closed);
+            break;
+        }
+        if (!updated) return;
+        store.setDocument({
+          ...doc,
+          objects: {
+            ...doc.objects,
+            [op.objectId]: {
+              ...obj,
+              data: { ...(obj.data as VectorPathData), commands: updated },
+            } as ObjectNode,
+          },
+        });
+        break;
+      }
+
       case "scene.update": {
         const scene = doc.scenes[op.sceneId];
         if (!scene) return;
@@ -818,6 +1994,35 @@ class CommandDispatcher {
         break;
       }
 
+      case "project.update": {
+        store.setDocument({
+          ...doc,
+          project: { ...doc.project, ...op.changes },
+        });
+        break;
+      }
+
+      case "asset.update": {
+        const current = doc.assets[op.asset.id];
+        if (!current) return;
+        // A late update never undoes a newer one
+        if ((op.asset.revision ?? 0) < (current.revision ?? 0)) return;
+        store.setDocument({
+          ...doc,
+          assets: { ...doc.assets, [op.asset.id]: op.asset },
+        });
+        break;
+      }
+
+      case "project.setRootTimeline": {
+        if (!doc.timelines[op.timelineId]) return;
+        store.setDocument({
+          ...doc,
+          project: { ...doc.project, rootTimeline: op.timelineId },
+        });
+        break;
+      }
+
       case "track.create": {
         const timeline = doc.timelines[op.timelineId];
         if (!timeline) return;
@@ -871,6 +2076,49 @@ class CommandDispatcher {
         break;
       }
 
+      case "track.reverse": {
+        // Mirror must match the server's DocumentState.applyTrackReverse
+        const track = doc.tracks[op.trackId];
+        if (!track || track.keys.length < 2) return;
+
+        const keys = track.keys
+          .map((id) => doc.keyframes[id])
+          .filter((kf): kf is Keyframe => kf !== undefined)
+          .sort((a, b) => a.frame - b.frame);
+        if (keys.length !== track.keys.length) return;
+
+        const span = keys[0].frame + keys[keys.length - 1].frame;
+        const n = keys.length;
+        const newKeyframes = { ...doc.keyframes };
+        const newKeys: string[] = new Array(n);
+        keys.forEach((kf, i) => {
+          // Each segment keeps its easing, moved to the keyframe that now
+          // starts it (or ends it, for incoming easings)
+          const src =
+            track.easingMode === "incoming" ? keys[(i + 1) % n] : keys[(i + n - 1) % n];
+          let easing = src.easing;
+          let easingParams = src.easingParams;
+          let bezier = src.bezier;
+          if (op.mirrorEasing) {
+            easing = MIRRORED_EASINGS[easing] ?? easing;
+            easingParams = mirroredEasingParams(easing, easingParams);
+            if (bezier) bezier = mirroredBezier(bezier);
+          }
+          newKeyframes[kf.id] = { ...kf, frame: span - kf.frame, easing, easingParams, bezier };
+          newKeys[n - 1 - i] = kf.id;
+        });
+
+        store.setDocument({
+          ...doc,
+          keyframes: newKeyframes,
+          tracks: {
+            ...doc.tracks,
+            [op.trackId]: { ...track, keys: newKeys },
+          },
+        });
+        break;
+      }
+
       case "keyframe.add": {
         const newKeyframes = { ...doc.keyframes };
         newKeyframes[op.keyframe.id] = op.keyframe;
@@ -957,6 +2205,31 @@ class CommandDispatcher {
         break;
       }
 
+      case "keyframe.convertEasingToBezier": {
+        // Mirror must match the server's DocumentState.applyKeyframeConvertEasing
+        const keyframe = doc.keyframes[op.keyframeId];
+        if (!keyframe || keyframe.easing === "cubicBezier") return;
+
+        let bezier = EASING_BEZIERS[keyframe.easing || "linear"];
+        if (keyframe.easing.startsWith("preset:")) {
+          const preset =
+            doc.easingPresets?.[keyframe.easing.slice("preset:".length)];
+          bezier = preset
+            ? [preset.x1, preset.y1, preset.x2, preset.y2]
+            : EASING_BEZIERS.linear;
+        }
+        if (!bezier) return;
+
+        store.setDocument({
+          ...doc,
+          keyframes: {
+            ...doc.keyframes,
+            [op.keyframeId]: { ...keyframe, easing: "cubicBezier", bezier },
+          },
+        });
+        break;
+      }
+
       case "timeline.update": {
         const timeline = doc.timelines[op.timelineId];
         if (!timeline) return;
@@ -970,6 +2243,94 @@ class CommandDispatcher {
         break;
       }
 
+      case "timeline.insertTime": {
+        // Mirror must match the server's DocumentState.applyTimeShift
+        const timeline = doc.timelines[op.timelineId];
+        if (!timeline || op.atFrame < 0 || op.frames <= 0) return;
+        const restore = op.removedKeyframes ?? {};
+        if (
+          Object.keys(restore).some(
+            (id) => !doc.tracks[id] || !timeline.tracks.includes(id),
+          )
+        )
+          return;
+
+        const newKeyframes = { ...doc.keyframes };
+        for (const trackId of timeline.tracks) {
+          for (const keyId of doc.tracks[trackId]?.keys ?? []) {
+            const kf = newKeyframes[keyId];
+            if (kf && kf.frame >= op.atFrame) {
+              newKeyframes[keyId] = { ...kf, frame: kf.frame + op.frames };
+            }
+          }
+        }
+        const newTracks = { ...doc.tracks };
+        for (const trackId of Object.keys(restore).sort()) {
+          const keys = [...newTracks[trackId].keys];
+          for (const kf of restore[trackId]) {
+            newKeyframes[kf.id] = kf;
+            if (!keys.includes(kf.id)) keys.push(kf.id);
+          }
+          keys.sort((a, b) => newKeyframes[a].frame - newKeyframes[b].frame);
+          newTracks[trackId] = { ...newTracks[trackId], keys };
+        }
+
+        store.setDocument({
+          ...doc,
+          keyframes: newKeyframes,
+          tracks: newTracks,
+          timelines: {
+            ...doc.timelines,
+            [op.timelineId]: {
+              ...timeline,
+              length: timeline.length + op.frames,
+            },
+          },
+        });
+        break;
+      }
+
+      case "timeline.removeTime": {
+        // Mirror must match the server's DocumentState.applyRemoveTime
+        const timeline = doc.timelines[op.timelineId];
+        const plan = planTimeRemoval(doc, op);
+        if (!timeline || !plan) return;
+
+        const newKeyframes = { ...doc.keyframes };
+        const newTracks = { ...doc.tracks };
+        for (const trackId of timeline.tracks) {
+          const track = newTracks[trackId];
+          if (!track) continue;
+          const keys: string[] = [];
+          for (const keyId of track.keys) {
+            if (plan.deleted.has(keyId)) {
+              delete newKeyframes[keyId];
+              continue;
+            }
+            const frame = plan.frames[keyId];
+            if (frame !== undefined && newKeyframes[keyId]) {
+              newKeyframes[keyId] = { ...newKeyframes[keyId], frame };
+            }
+            keys.push(keyId);
+          }
+          newTracks[trackId] = { ...track, keys };
+        }
+
+        store.setDocument({
+          ...doc,
+          keyframes: newKeyframes,
+          tracks: newTracks,
+          timelines: {
+            ...doc.timelines,
+            [op.timelineId]: {
+              ...timeline,
+              length: timeline.length - op.frames,
+            },
+          },
+        });
+        break;
+      }
+
       case "scene.create": {
         // Guard against duplicate application
         if (doc.scenes[op.scene.id]) break;
@@ -1032,6 +2393,103 @@ class CommandDispatcher {
         }
         break;
       }
+
+      case "animation.copy": {
+        if (!doc.timelines[op.timelineId] || !op.idMap) return;
+        const sources = copySources(doc, op);
+        const properties = new Set(sources.map((t) => t.property));
+        let next = doc;
+        op.objectIds.forEach((targetId, i) => {
+          const ids = op.idMap![targetId] ?? {};
+          const offset = (op.startOffset ?? 0) + i * (op.frameOffset ?? 0);
+          next = removeTracks(
+            next,
+            op.timelineId,
+            (t) => t.objectId === targetId && properties.has(t.property),
+          );
+          const tracks = { ...next.tracks };
+          const keyframes = { ...next.keyframes };
+          const timeline = next.timelines[op.timelineId];
+          const trackIds = [...timeline.tracks];
+          for (const src of sources) {
+            const keys: string[] = [];
+            for (const keyId of src.keys) {
+              const kf = doc.keyframes[keyId];
+              if (!kf) continue;
+              keyframes[ids[keyId]] = {
+                ...kf,
+                id: ids[keyId],
+                frame: kf.frame + offset,
+              };
+              keys.push(ids[keyId]);
+            }
+            tracks[ids[src.id]] = {
+              id: ids[src.id],
+              objectId: targetId,
+              property: src.property,
+              keys,
+            };
+            trackIds.push(ids[src.id]);
+          }
+          next = {
+            ...next,
+            tracks,
+            keyframes,
+            timelines: {
+              ...next.timelines,
+              [op.timelineId]: { ...timeline, tracks: trackIds },
+            },
+          };
+        });
+        store.setDocument(next);
+        break;
+      }
+
+      case "animation.restore": {
+        if (!doc.timelines[op.timelineId]) return;
+        const targets = new Set(op.animation.objectIds);
+        const next = removeTracks(doc, op.timelineId, (t) =>
+          targets.has(t.objectId),
+        );
+        const timeline = next.timelines[op.timelineId];
+        const tracks = { ...next.tracks };
+        const keyframes = { ...next.keyframes };
+        for (const kf of op.animation.keyframes) keyframes[kf.id] = kf;
+        for (const track of op.animation.tracks) tracks[track.id] = track;
+        store.setDocument({
+          ...next,
+          tracks,
+          keyframes,
+          timelines: {
+            ...next.timelines,
+            [op.timelineId]: {
+              ...timeline,
+              tracks: [
+                ...timeline.tracks,
+                ...op.animation.tracks.map((t) => t.id),
+              ],
+            },
+          },
+        });
+        break;
+      }
+
+      case "easingPreset.create":
+      case "easingPreset.update": {
+        store.setDocument({
+          ...doc,
+          easingPresets: { ...doc.easingPresets, [op.presetName]: op.preset },
+        });
+        break;
+      }
+
+      case "easingPreset.delete": {
+        if (!doc.easingPresets?.[op.presetName]) return;
+        const easingPresets = { ...doc.easingPresets };
+        delete easingPresets[op.presetName];
+        store.setDocument({ ...doc, easingPresets });
+        break;
+      }
     }
   }
 }
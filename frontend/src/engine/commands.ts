@@ -1,4 +1,4 @@
-import type { PathCommand, Asset } from "../types/document";
+import type { PathCommand, Asset, GradientStop } from "../types/document";
 import type { AnchorPoint } from "./pathUtils";
 import { API_BASE } from "../api/client";
 
@@ -12,18 +12,35 @@ export interface DrawCommand {
   transform?: number[]; // [a, b, c, d, e, f] affine matrix
   path?: PathCommand[];
   fill?: string;
+  fillAlpha?: number; // Fill color alpha if not opaque, on top of opacity
+  fillGradient?: GradientPaint; // Fills in place of fill when set
   stroke?: string;
+  strokeAlpha?: number; // Stroke color alpha (omitted when opaque)
   strokeWidth?: number;
   opacity?: number;
   imageAssetId?: string;
   imageWidth?: number;
   imageHeight?: number;
+  imageSource?: [number, number, number, number]; // Region to draw (sprite sheet cell)
   // Text rendering
   textContent?: string;
   textFontSize?: number;
   textFontFamily?: string;
   textFontWeight?: string;
   textAlign?: string;
+  textLetterSpacing?: number;
+  textPath?: PathCommand[]; // Baseline path in local space (text-on-path)
+  textPathOffset?: number; // Start distance along textPath
+}
+
+/**
+ * A gradient fill in the command's local space. Coords are [x1, y1, x2, y2]
+ * for a linear gradient and [cx, cy, r] for a radial one.
+ */
+export interface GradientPaint {
+  type: "linear" | "radial";
+  coords: number[];
+  stops: GradientStop[];
 }
 
 // Module-level image cache: URL -> HTMLImageElement
@@ -55,6 +72,18 @@ function getCachedImage(url: string): HTMLImageElement | null {
   return null; // Will be available after load
 }
 
+/**
+ * Drop every cached revision of an asset's image, after its content was
+ * replaced. The current revision is loaded again when next drawn.
+ */
+export function forgetAssetImages(assetId: string): void {
+  for (const url of imageCache.keys()) {
+    if (url.includes(`/assets/${assetId}.`)) {
+      imageCache.delete(url);
+    }
+  }
+}
+
 // Asset lookup — set by the caller before executing commands
 let currentAssets: Record<string, Asset> = {};
 
@@ -159,6 +188,44 @@ export function executeCommands(
   executeCommandsNoClear(ctx, commands, dpr, assets);
 }
 
+/**
+ * Build a canvas gradient from a gradient paint, in the context's current
+ * (local) space.
+ */
+function buildGradient(
+  ctx: CanvasRenderingContext2D,
+  paint: GradientPaint,
+): CanvasGradient {
+  const [a, b, c, d] = paint.coords;
+  const gradient =
+    paint.type === "radial"
+      ? ctx.createRadialGradient(a, b, 0, a, b, c)
+      : ctx.createLinearGradient(a, b, c, d);
+  for (const stop of paint.stops) {
+    gradient.addColorStop(stop.offset, stop.color);
+  }
+  return gradient;
+}
+
+/**
+ * Run paint with the context's alpha scaled by a fill/stroke color's alpha,
+ * which the engine sends separately from the object's opacity.
+ */
+function withPaintAlpha(
+  ctx: CanvasRenderingContext2D,
+  alpha: number | undefined,
+  paint: () => void,
+): void {
+  if (alpha === undefined) {
+    paint();
+    return;
+  }
+  const base = ctx.globalAlpha;
+  ctx.globalAlpha = base * alpha;
+  paint();
+  ctx.globalAlpha = base;
+}
+
 /**
  * Draw a path command.
  */
@@ -180,17 +247,20 @@ function drawPath(ctx: CanvasRenderingContext2D, cmd: DrawCommand): void {
   // Build the path
   const path = buildPath(cmd.path);
 
-  // Fill
-  if (cmd.fill) {
-    ctx.fillStyle = cmd.fill;
+  // Fill; the gradient's stops carry their own alpha
+  if (cmd.fillGradient) {
+    ctx.fillStyle = buildGradient(ctx, cmd.fillGradient);
     ctx.fill(path);
+  } else if (cmd.fill) {
+    ctx.fillStyle = cmd.fill;
+    withPaintAlpha(ctx, cmd.fillAlpha, () => ctx.fill(path));
   }
 
   // Stroke
   if (cmd.stroke && cmd.strokeWidth && cmd.strokeWidth > 0) {
     ctx.strokeStyle = cmd.stroke;
     ctx.lineWidth = cmd.strokeWidth;
-    ctx.stroke(path);
+    withPaintAlpha(ctx, cmd.strokeAlpha, () => ctx.stroke(path));
   }
 
   ctx.restore();
@@ -223,7 +293,12 @@ function drawImage(ctx: CanvasRenderingContext2D, cmd: DrawCommand): void {
     ctx.globalAlpha = cmd.opacity;
   }
 
-  ctx.drawImage(img, 0, 0, cmd.imageWidth, cmd.imageHeight);
+  if (cmd.imageSource) {
+    const [sx, sy, sw, sh] = cmd.imageSource;
+    ctx.drawImage(img, sx, sy, sw, sh, 0, 0, cmd.imageWidth, cmd.imageHeight);
+  } else {
+    ctx.drawImage(img, 0, 0, cmd.imageWidth, cmd.imageHeight);
+  }
 
   ctx.restore();
 }
@@ -247,12 +322,27 @@ function drawText(ctx: CanvasRenderingContext2D, cmd: DrawCommand): void {
   const weight = cmd.textFontWeight || "normal";
   const family = cmd.textFontFamily || "sans-serif";
   ctx.font = `${weight} ${cmd.textFontSize}px ${family}`;
+  if (cmd.textLetterSpacing) {
+    ctx.letterSpacing = `${cmd.textLetterSpacing}px`;
+  }
+
+  if (cmd.textPath && cmd.textPath.length > 0) {
+    drawTextOnPath(ctx, cmd);
+    ctx.restore();
+    return;
+  }
+
   ctx.textAlign = (cmd.textAlign as CanvasTextAlign) || "left";
   ctx.textBaseline = "top";
 
-  if (cmd.fill && cmd.fill !== "none") {
+  if (cmd.fillGradient) {
+    ctx.fillStyle = buildGradient(ctx, cmd.fillGradient);
+    ctx.fillText(cmd.textContent!, 0, 0);
+  } else if (cmd.fill && cmd.fill !== "none") {
     ctx.fillStyle = cmd.fill;
-    ctx.fillText(cmd.textContent, 0, 0);
+    withPaintAlpha(ctx, cmd.fillAlpha, () =>
+      ctx.fillText(cmd.textContent!, 0, 0),
+    );
   }
 
   if (
@@ -263,12 +353,179 @@ function drawText(ctx: CanvasRenderingContext2D, cmd: DrawCommand): void {
   ) {
     ctx.strokeStyle = cmd.stroke;
     ctx.lineWidth = cmd.strokeWidth;
-    ctx.strokeText(cmd.textContent, 0, 0);
+    withPaintAlpha(ctx, cmd.strokeAlpha, () =>
+      ctx.strokeText(cmd.textContent!, 0, 0),
+    );
   }
 
   ctx.restore();
 }
 
+/**
+ * Draw text with its baseline following cmd.textPath. Each character is
+ * placed at its advance along the path and rotated to the path tangent.
+ * Characters that run past the end of the path are not drawn.
+ */
+function drawTextOnPath(ctx: CanvasRenderingContext2D, cmd: DrawCommand): void {
+  const points = flattenPath(cmd.textPath!);
+  if (points.length < 2) return;
+
+  const lengths = [0];
+  for (let i = 1; i < points.length; i++) {
+    lengths.push(
+      lengths[i - 1] +
+        Math.hypot(points[i].x - points[i - 1].x, points[i].y - points[i - 1].y),
+    );
+  }
+  const pathLength = lengths[lengths.length - 1];
+
+  // Letter spacing is applied per character below, not by the context
+  ctx.letterSpacing = "0px";
+  const spacing = cmd.textLetterSpacing || 0;
+  const chars = Array.from(cmd.textContent!);
+  const widths = chars.map((ch) => ctx.measureText(ch).width);
+  const total =
+    widths.reduce((sum, w) => sum + w, 0) +
+    spacing * Math.max(chars.length - 1, 0);
+
+  let dist = cmd.textPathOffset || 0;
+  if (cmd.textAlign === "center") dist += (pathLength - total) / 2;
+  else if (cmd.textAlign === "right") dist += pathLength - total;
+
+  ctx.textAlign = "center";
+  ctx.textBaseline = "alphabetic";
+  // Each character is drawn in its own rotated space, so a gradient would
+  // restart per character; the solid fill stands in for it
+  const fill = cmd.fill && cmd.fill !== "none" ? cmd.fill : null;
+  const stroke =
+    cmd.stroke &&
+    cmd.stroke !== "none" &&
+    cmd.strokeWidth &&
+    cmd.strokeWidth > 0
+      ? cmd.stroke
+      : null;
+  if (fill) ctx.fillStyle = fill;
+  if (stroke) {
+    ctx.strokeStyle = stroke;
+    ctx.lineWidth = cmd.strokeWidth!;
+  }
+
+  let seg = 1;
+  for (let i = 0; i < chars.length; i++) {
+    const mid = dist + widths[i] / 2;
+    dist += widths[i] + spacing;
+    if (mid < 0) continue;
+    if (mid > pathLength) break;
+
+    while (seg < lengths.length - 1 && lengths[seg] < mid) seg++;
+    const a = points[seg - 1];
+    const b = points[seg];
+    const segLen = lengths[seg] - lengths[seg - 1];
+    const t = segLen > 0 ? (mid - lengths[seg - 1]) / segLen : 0;
+
+    ctx.save();
+    ctx.translate(a.x + (b.x - a.x) * t, a.y + (b.y - a.y) * t);
+    ctx.rotate(Math.atan2(b.y - a.y, b.x - a.x));
+    if (fill) {
+      withPaintAlpha(ctx, cmd.fillAlpha, () => ctx.fillText(chars[i], 0, 0));
+    }
+    if (stroke) {
+      withPaintAlpha(ctx, cmd.strokeAlpha, () =>
+        ctx.strokeText(chars[i], 0, 0),
+      );
+    }
+    ctx.restore();
+  }
+}
+
+/**
+ * Flatten path commands into a polyline, sampling curves at fixed steps.
+ */
+function flattenPath(commands: PathCommand[]): { x: number; y: number }[] {
+  const CURVE_STEPS = 16;
+  const points: { x: number; y: number }[] = [];
+  let cx = 0;
+  let cy = 0;
+  let startX = 0;
+  let startY = 0;
+
+  for (const cmd of commands) {
+    switch (cmd[0]) {
+      case "M":
+        cx = startX = cmd[1];
+        cy = startY = cmd[2];
+        points.push({ x: cx, y: cy });
+        break;
+      case "L":
+        cx = cmd[1];
+        cy = cmd[2];
+        points.push({ x: cx, y: cy });
+        break;
+      case "C":
+        for (let s = 1; s <= CURVE_STEPS; s++) {
+          const t = s / CURVE_STEPS;
+          const u = 1 - t;
+          const w0 = u * u * u;
+          const w1 = 3 * u * u * t;
+          const w2 = 3 * u * t * t;
+          const w3 = t * t * t;
+          points.push({
+            x: w0 * cx + w1 * cmd[1] + w2 * cmd[3] + w3 * cmd[5],
+            y: w0 * cy + w1 * cmd[2] + w2 * cmd[4] + w3 * cmd[6],
+          });
+        }
+        cx = cmd[5];
+        cy = cmd[6];
+        break;
+      case "Q":
+        for (let s = 1; s <= CURVE_STEPS; s++) {
+          const t = s / CURVE_STEPS;
+          const u = 1 - t;
+          points.push({
+            x: u * u * cx + 2 * u * t * cmd[1] + t * t * cmd[3],
+            y: u * u * cy + 2 * u * t * cmd[2] + t * t * cmd[4],
+          });
+        }
+        cx = cmd[3];
+        cy = cmd[4];
+        break;
+      case "Z":
+        cx = startX;
+        cy = startY;
+        points.push({ x: cx, y: cy });
+        break;
+    }
+  }
+
+  return points;
+}
+
+/**
+ * Local-space bounds of a text command. Text on a path is padded by a line
+ * height around the path, matching the engine's hit-test bounds.
+ */
+function getTextLocalBounds(cmd: DrawCommand): Bounds {
+  const fontSize = cmd.textFontSize!;
+  if (cmd.textPath && cmd.textPath.length > 0) {
+    const b = getBoundsFromPath(cmd.textPath);
+    const pad = fontSize * 1.2;
+    return {
+      minX: b.minX - pad,
+      minY: b.minY - pad,
+      maxX: b.maxX + pad,
+      maxY: b.maxY + pad,
+    };
+  }
+
+  const mCtx = getMeasureCtx();
+  const weight = cmd.textFontWeight || "normal";
+  const family = cmd.textFontFamily || "sans-serif";
+  mCtx.font = `${weight} ${fontSize}px ${family}`;
+  mCtx.letterSpacing = `${cmd.textLetterSpacing || 0}px`;
+  const metrics = mCtx.measureText(cmd.textContent!);
+  return { minX: 0, minY: 0, maxX: metrics.width, maxY: fontSize * 1.2 };
+}
+
 // Module-level offscreen context for text measurement
 let measureCtx: OffscreenCanvasRenderingContext2D | null = null;
 function getMeasureCtx(): OffscreenCanvasRenderingContext2D {
@@ -442,17 +699,7 @@ export function getWorldBounds(cmd: DrawCommand): Bounds | null {
   let localBounds: Bounds;
 
   if (cmd.op === "text" && cmd.textContent && cmd.textFontSize) {
-    const mCtx = getMeasureCtx();
-    const weight = cmd.textFontWeight || "normal";
-    const family = cmd.textFontFamily || "sans-serif";
-    mCtx.font = `${weight} ${cmd.textFontSize}px ${family}`;
-    const metrics = mCtx.measureText(cmd.textContent);
-    localBounds = {
-      minX: 0,
-      minY: 0,
-      maxX: metrics.width,
-      maxY: cmd.textFontSize * 1.2,
-    };
+    localBounds = getTextLocalBounds(cmd);
   } else if (
     cmd.op === "image" &&
     cmd.imageAssetId &&
@@ -467,6 +714,16 @@ export function getWorldBounds(cmd: DrawCommand): Bounds | null {
     };
   } else if (cmd.path && cmd.path.length > 0) {
     localBounds = getBoundsFromPath(cmd.path);
+    // Thick strokes paint outside the geometry
+    if (cmd.stroke && cmd.stroke !== "none" && cmd.strokeWidth) {
+      const half = cmd.strokeWidth / 2;
+      localBounds = {
+        minX: localBounds.minX - half,
+        minY: localBounds.minY - half,
+        maxX: localBounds.maxX + half,
+        maxY: localBounds.maxY + half,
+      };
+    }
   } else {
     return null;
   }
@@ -513,15 +770,11 @@ function getTransformedCorners(cmd: DrawCommand): {
 
   let lMinX: number, lMinY: number, lMaxX: number, lMaxY: number;
   if (isText) {
-    const mCtx = getMeasureCtx();
-    const weight = cmd.textFontWeight || "normal";
-    const family = cmd.textFontFamily || "sans-serif";
-    mCtx.font = `${weight} ${cmd.textFontSize!}px ${family}`;
-    const metrics = mCtx.measureText(cmd.textContent!);
-    lMinX = 0;
-    lMinY = 0;
-    lMaxX = metrics.width;
-    lMaxY = cmd.textFontSize! * 1.2;
+    const b = getTextLocalBounds(cmd);
+    lMinX = b.minX;
+    lMinY = b.minY;
+    lMaxX = b.maxX;
+    lMaxY = b.maxY;
   } else if (isImage) {
     lMinX = 0;
     lMinY = 0;
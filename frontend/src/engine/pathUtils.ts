@@ -1,4 +1,4 @@
-import type { PathCommand } from "../types/document";
+import type { PathCommand, PathPoint } from "../types/document";
 
 /**
  * Anchor point representation for subselection editing.
@@ -138,3 +138,147 @@ export function anchorsToPath(
 export function isPathClosed(commands: PathCommand[]): boolean {
   return commands.length > 0 && commands[commands.length - 1][0] === "Z";
 }
+
+// --- Vertex-level edits ---
+// These mirror the server's document.MovePathPoint etc. and address vertices
+// the same way (see PathPoint). Each returns a new commands array, or null if
+// the edit doesn't fit the path's structure.
+
+/**
+ * Command index of each vertex (every non-"Z" command).
+ */
+export function pathVertexCommands(commands: PathCommand[]): number[] {
+  const indices: number[] = [];
+  commands.forEach((cmd, i) => {
+    if (cmd[0] !== "Z") indices.push(i);
+  });
+  return indices;
+}
+
+/**
+ * Read vertex index as an anchor with handles.
+ */
+export function getPathPoint(
+  commands: PathCommand[],
+  index: number,
+): PathPoint | null {
+  const ci = pathVertexCommands(commands)[index];
+  if (ci === undefined) return null;
+  const cmd = commands[ci] as (string | number)[];
+  const n = cmd.length;
+  const point: PathPoint = {
+    x: cmd[n - 2] as number,
+    y: cmd[n - 1] as number,
+  };
+  if (cmd[0] === "C" || cmd[0] === "Q") {
+    point.in = { x: cmd[n - 4] as number, y: cmd[n - 3] as number };
+  }
+  const next = commands[ci + 1] as (string | number)[] | undefined;
+  if (next?.[0] === "C") {
+    point.out = { x: next[1] as number, y: next[2] as number };
+  }
+  return point;
+}
+
+/**
+ * Move vertex index and, when given, its handles.
+ */
+export function movePathPoint(
+  commands: PathCommand[],
+  index: number,
+  point: PathPoint,
+): PathCommand[] | null {
+  const ci = pathVertexCommands(commands)[index];
+  if (ci === undefined) return null;
+  const cmd = [...commands[ci]] as (string | number)[];
+  const n = cmd.length;
+  if (point.in && cmd[0] !== "C" && cmd[0] !== "Q") return null;
+  const next = commands[ci + 1] as (string | number)[] | undefined;
+  if (point.out && next?.[0] !== "C") return null;
+
+  const result = [...commands];
+  cmd[n - 2] = point.x;
+  cmd[n - 1] = point.y;
+  if (point.in) {
+    cmd[n - 4] = point.in.x;
+    cmd[n - 3] = point.in.y;
+  }
+  result[ci] = cmd as PathCommand;
+  if (point.out && next) {
+    result[ci + 1] = [
+      "C",
+      point.out.x,
+      point.out.y,
+      ...next.slice(3),
+    ] as PathCommand;
+  }
+  return result;
+}
+
+/**
+ * Insert command so it becomes vertex index. A vertex appended to a closed
+ * subpath goes before its "Z".
+ */
+export function insertPathPoint(
+  commands: PathCommand[],
+  index: number,
+  command: PathCommand,
+): PathCommand[] | null {
+  if (command[0] === "M" || command[0] === "Z") return null;
+  const vertices = pathVertexCommands(commands);
+  if (index < 1 || index > vertices.length) return null;
+  let pos = index < vertices.length ? vertices[index] : commands.length;
+  if (pos > 0 && commands[pos - 1][0] === "Z") pos--;
+  return [...commands.slice(0, pos), command, ...commands.slice(pos)];
+}
+
+/**
+ * Remove vertex index. A subpath's "M" can't be deleted.
+ */
+export function deletePathPoint(
+  commands: PathCommand[],
+  index: number,
+): PathCommand[] | null {
+  const ci = pathVertexCommands(commands)[index];
+  if (ci === undefined || commands[ci][0] === "M") return null;
+  return [...commands.slice(0, ci), ...commands.slice(ci + 1)];
+}
+
+/**
+ * End command index (exclusive) of the subpath containing vertex index.
+ */
+function subpathEnd(commands: PathCommand[], index: number): number | null {
+  const ci = pathVertexCommands(commands)[index];
+  if (ci === undefined) return null;
+  for (let i = ci + 1; i < commands.length; i++) {
+    if (commands[i][0] === "M") return i;
+  }
+  return commands.length;
+}
+
+/**
+ * Whether the subpath containing vertex index ends with "Z".
+ */
+export function isSubpathClosed(
+  commands: PathCommand[],
+  index: number,
+): boolean {
+  const end = subpathEnd(commands, index);
+  return end !== null && commands[end - 1][0] === "Z";
+}
+
+/**
+ * Open or close the subpath containing vertex index.
+ */
+export function setPathClosed(
+  commands: PathCommand[],
+  index: number,
+  closed: boolean,
+): PathCommand[] | null {
+  const end = subpathEnd(commands, index);
+  if (end === null) return null;
+  if (isSubpathClosed(commands, index) === closed) return commands;
+  return closed
+    ? [...commands.slice(0, end), ["Z"], ...commands.slice(end)]
+    : [...commands.slice(0, end - 1), ...commands.slice(end)];
+}
@@ -18,9 +18,72 @@ export const RUNTIME_JS = `
     t -= 2.625/d1; return n1*t*t + 0.984375;
   }
 
-  function ease(t, type) {
+  // Springs run until they settle, stretched over the segment
+  var SPRINGS = {
+    spring: { stiffness: 100, damping: 10, mass: 1 },
+    springSoft: { stiffness: 100, damping: 6, mass: 1 },
+    springStiff: { stiffness: 100, damping: 16, mass: 1 }
+  };
+
+  function springEase(t, defaults, params) {
+    if (t <= 0) return 0;
+    if (t >= 1) return 1;
+    params = params || {};
+    var k = params.stiffness > 0 ? params.stiffness : defaults.stiffness;
+    var c = params.damping > 0 ? params.damping : defaults.damping;
+    var m = params.mass > 0 ? params.mass : defaults.mass;
+    var w = Math.sqrt(k/m), z = c/(2*Math.sqrt(k*m));
+    var decay, pos;
+    if (z < 1 - 1e-9) {
+      var wd = w*Math.sqrt(1 - z*z);
+      decay = z*w;
+      pos = function(s) { return 1 - Math.exp(-decay*s)*(Math.cos(wd*s) + decay/wd*Math.sin(wd*s)); };
+    } else if (z <= 1 + 1e-9) {
+      decay = w;
+      pos = function(s) { return 1 - Math.exp(-w*s)*(1 + w*s); };
+    } else {
+      var root = Math.sqrt(z*z - 1), r1 = -w*(z - root), r2 = -w*(z + root);
+      decay = -r1;
+      pos = function(s) { return 1 - (r2*Math.exp(r1*s) - r1*Math.exp(r2*s))/(r2 - r1); };
+    }
+    var settle = Math.log(1000)/decay;
+    return pos(t*settle)/pos(settle);
+  }
+
+  // CSS cubic-bezier(): solve x(s) = t by Newton's method, then bisection
+  function cubicBezier(t, b) {
+    if (!b || b.length !== 4 || b[0] < 0 || b[0] > 1 || b[2] < 0 || b[2] > 1) return t;
+    if (t <= 0 || t >= 1) return t;
+    var cx = 3*b[0], bx = 3*(b[2] - b[0]) - cx, ax = 1 - cx - bx;
+    var cy = 3*b[1], by = 3*(b[3] - b[1]) - cy, ay = 1 - cy - by;
+    function sx(s) { return ((ax*s + bx)*s + cx)*s; }
+    function sy(s) { return ((ay*s + by)*s + cy)*s; }
+    var s = t, i;
+    for (i = 0; i < 8; i++) {
+      var dx = sx(s) - t;
+      if (Math.abs(dx) < 1e-7) return sy(s);
+      var d = (3*ax*s + 2*bx)*s + cx;
+      if (Math.abs(d) < 1e-6) break;
+      s -= dx/d;
+    }
+    var lo = 0, hi = 1;
+    s = t;
+    for (i = 0; i < 64 && hi - lo > 1e-7; i++) {
+      if (sx(s) < t) lo = s; else hi = s;
+      s = (lo + hi)/2;
+    }
+    return sy(s);
+  }
+
+  function ease(t, type, params, bezier) {
+    if (SPRINGS[type]) {
+      // A reversed spring is the spring played backwards in time
+      if (params && params.reversed) return 1 - springEase(1 - t, SPRINGS[type], params);
+      return springEase(t, SPRINGS[type], params);
+    }
     switch (type) {
       case 'linear': return t;
+      case 'cubicBezier': return cubicBezier(t, bezier);
       case 'easeIn': return t*t;
       case 'easeOut': return t*(2-t);
       case 'easeInOut': return t < 0.5 ? 2*t*t : -1 + (4-2*t)*t;
@@ -48,6 +111,36 @@ export const RUNTIME_JS = `
     }
   }
 
+  // --- Color interpolation (matches the engine's lerpColor) ---
+  function parseHexColor(s) {
+    if (typeof s !== 'string' || !/^#([0-9a-f]{3}|[0-9a-f]{6}|[0-9a-f]{8})$/i.test(s)) return null;
+    var h = s.slice(1);
+    if (h.length === 3) h = h[0]+h[0]+h[1]+h[1]+h[2]+h[2];
+    if (h.length === 6) h += 'ff';
+    var c = [];
+    for (var i = 0; i < 8; i += 2) c.push(parseInt(h.slice(i, i+2), 16));
+    return c;
+  }
+  function toLinear(c) {
+    var v = c / 255;
+    return v <= 0.04045 ? v / 12.92 : Math.pow((v + 0.055) / 1.055, 2.4);
+  }
+  function toSRGB(v) {
+    v = Math.max(0, Math.min(1, v));
+    v = v <= 0.0031308 ? v * 12.92 : 1.055 * Math.pow(v, 1/2.4) - 0.055;
+    return Math.round(v * 255);
+  }
+  function lerpColor(a, b, t) {
+    var out = '#';
+    for (var i = 0; i < 4; i++) {
+      var c = i < 3
+        ? toSRGB(toLinear(a[i]) + (toLinear(b[i]) - toLinear(a[i])) * t)
+        : Math.round(Math.max(0, Math.min(255, a[i] + (b[i] - a[i]) * t)));
+      out += (c < 16 ? '0' : '') + c.toString(16);
+    }
+    return out;
+  }
+
   // --- Keyframe evaluation ---
   function evaluateTimeline(doc, timelineId, frame) {
     var tl = doc.timelines[timelineId];
@@ -74,9 +167,13 @@ export const RUNTIME_JS = `
       else if (prev === next || prev.frame === next.frame) val = prev.value;
       else {
         var t = (frame - prev.frame) / (next.frame - prev.frame);
-        var et = ease(t, prev.easing || 'linear');
+        var ek = track.easingMode === 'incoming' ? next : prev;
+        var et = ease(t, ek.easing || 'linear', ek.easingParams, ek.bezier);
+        var ca = parseHexColor(prev.value), cb = parseHexColor(next.value);
         if (typeof prev.value === 'number' && typeof next.value === 'number') {
           val = prev.value + (next.value - prev.value) * et;
+        } else if (ca && cb) {
+          val = lerpColor(ca, cb, et);
         } else {
           val = et < 0.5 ? prev.value : next.value;
         }
@@ -130,6 +227,17 @@ export const RUNTIME_JS = `
     ];
   }
 
+  // --- Paint ---
+  function gradientFill(ctx, g) {
+    var grad = g.type === 'radial'
+      ? ctx.createRadialGradient(g.cx || 0, g.cy || 0, 0, g.cx || 0, g.cy || 0, g.r)
+      : ctx.createLinearGradient(g.x1 || 0, g.y1 || 0, g.x2 || 0, g.y2 || 0);
+    for (var i = 0; i < g.stops.length; i++) {
+      grad.addColorStop(g.stops[i].offset, g.stops[i].color);
+    }
+    return grad;
+  }
+
   // --- Scene graph build + compile ---
   function buildAndRender(ctx, canvas, doc, sceneId, frame) {
     var scene = doc.scenes[sceneId];
@@ -163,7 +271,8 @@ export const RUNTIME_JS = `
     };
     var style = {
       fill: obj.style.fill, stroke: obj.style.stroke,
-      strokeWidth: obj.style.strokeWidth, opacity: obj.style.opacity
+      strokeWidth: obj.style.strokeWidth, opacity: obj.style.opacity,
+      fillGradient: obj.style.fillGradient
     };
 
     // Apply overrides
@@ -201,7 +310,10 @@ export const RUNTIME_JS = `
           case 'Z': p2d.closePath(); break;
         }
       }
-      if (style.fill && style.fill !== 'none') {
+      if (style.fillGradient) {
+        ctx.fillStyle = gradientFill(ctx, style.fillGradient);
+        ctx.fill(p2d);
+      } else if (style.fill && style.fill !== 'none') {
         ctx.fillStyle = style.fill;
         ctx.fill(p2d);
       }
@@ -230,7 +342,10 @@ export const RUNTIME_JS = `
       ctx.font = (d.fontWeight || 'normal') + ' ' + (d.fontSize || 16) + 'px ' + (d.fontFamily || 'sans-serif');
       ctx.textAlign = d.textAlign || 'left';
       ctx.textBaseline = 'top';
-      if (style.fill && style.fill !== 'none') {
+      if (style.fillGradient) {
+        ctx.fillStyle = gradientFill(ctx, style.fillGradient);
+        ctx.fillText(d.content, 0, 0);
+      } else if (style.fill && style.fill !== 'none') {
         ctx.fillStyle = style.fill;
         ctx.fillText(d.content, 0, 0);
       }
@@ -254,6 +369,31 @@ export const RUNTIME_JS = `
       }
     }
 
+    // Render SpriteSequence: the frame the timeline frame maps to
+    if (obj.type === 'SpriteSequence' && obj.data) {
+      var sd = obj.data;
+      var sheet = !(sd.assetIds && sd.assetIds.length);
+      var count = sheet ? (sd.frameCount || 0) : sd.assetIds.length;
+      if (count > 0) {
+        var seqIdx = Math.floor((frame - (sd.startFrame || 0)) * (sd.rate > 0 ? sd.rate : 1) + 1e-9);
+        seqIdx = seqIdx < 0 ? 0 : sd.loop ? seqIdx % count : Math.min(seqIdx, count - 1);
+        var seqAsset = doc.assets[sheet ? sd.sheetAssetId : sd.assetIds[seqIdx]];
+        if (seqAsset && seqAsset._img && seqAsset._img.complete) {
+          ctx.save();
+          ctx.transform(worldM[0], worldM[1], worldM[2], worldM[3], worldM[4], worldM[5]);
+          ctx.globalAlpha = opacity;
+          if (sheet) {
+            var cols = Math.max(sd.columns || 1, 1);
+            ctx.drawImage(seqAsset._img, (seqIdx % cols) * sd.width, Math.floor(seqIdx / cols) * sd.height,
+              sd.width, sd.height, 0, 0, sd.width, sd.height);
+          } else {
+            ctx.drawImage(seqAsset._img, 0, 0, sd.width, sd.height);
+          }
+          ctx.restore();
+        }
+      }
+    }
+
     // Evaluate Symbol nested timeline
     if (obj.type === 'Symbol' && obj.data && obj.data.timelineId) {
       var symFrame = frame;
@@ -1,5 +1,14 @@
-import type { InDocument, Scene } from "../types/document";
+import type {
+  EasingPreset,
+  InDocument,
+  Keyframe,
+  ObjectNode,
+  ObjectType,
+  Scene,
+} from "../types/document";
 import type { DrawCommand } from "./commands";
+import type { Recording } from "../api/projects";
+import type { Operation } from "../types/operations";
 
 /**
  * Type declarations for the WASM engine API exposed on window.
@@ -24,26 +33,83 @@ interface InamateEngine {
   play(): void;
   pause(): void;
   togglePlay(): void;
+  setAnimationPreview(enabled: boolean): void;
   setScene(sceneId: string): void;
+  setSafeAreaGuides(enabled: boolean): void;
+  setSafeAreaPercent(
+    action: number,
+    title: number,
+  ): { ok?: boolean; error?: string };
+  setCullToViewport(
+    enabled: boolean,
+    x?: number,
+    y?: number,
+    width?: number,
+    height?: number,
+  ): void;
+  setRenderPrecision(decimals: number): void;
   setSelection(ids: string[]): void;
   setDragOverlay(json: string): void;
   updateDragOverlay(json: string): void;
   clearDragOverlay(): void;
   tick(): string;
+  loadRecording(json: string): PlaybackStatus;
+  recordingAdvance(elapsedMs: number): PlaybackStatus;
+  recordingStep(): PlaybackStatus;
+  recordingSeek(index: number): PlaybackStatus;
+  recordingSetSpeed(speed: number): void;
+  prepareOperation(
+    docJson: string,
+    opJson: string,
+  ): { op?: string; error?: string };
 
   // Queries (frontend ← backend)
   render(): string;
+  renderAtTime(seconds: number): string;
+  renderAtFrame(frame: number, playing?: boolean): string;
+  renderOverlay(): string;
+  getSafeAreas(sceneId?: string): string;
   hitTest(x: number, y: number): string;
+  getVisibleObjects(
+    x: number,
+    y: number,
+    width: number,
+    height: number,
+  ): string;
+  findObjects(query: string): string;
   getSelectionBounds(): string;
   getScene(): string;
+  getLayerTree(sceneId?: string): string;
   getPlaybackState(): string;
   getAnimatedTransform(objectId: string): string;
   getDocument(): string;
+  getTimelines(): string;
+  getKeyframesInRange(
+    timelineId: string,
+    startFrame: number,
+    endFrame: number,
+  ): string;
+  getKeyframeFrames(objectIds?: string[]): number[];
+  getMotionPath(
+    objectId: string,
+    timelineId: string,
+    startFrame: number,
+    endFrame: number,
+    step: number,
+  ): string;
+  getEasingPresets(): string;
   getSelection(): string;
   getFrame(): number;
   isPlaying(): boolean;
   getFPS(): number;
   getTotalFrames(): number;
+  measureText(inputJson: string): string;
+  createObjectJSON(
+    type: string,
+    overridesJson?: string,
+  ): { object?: string; error?: string };
+  getRenderStats(): string;
+  getDiagnostics(): string;
 }
 
 let wasmReady = false;
@@ -155,6 +221,73 @@ export function loadDocument(doc: InDocument): void {
   }
 }
 
+export interface PlaybackStatus {
+  position: number; // Ops applied so far
+  total: number;
+  done: boolean;
+  error?: string;
+}
+
+function checkPlayback(status: PlaybackStatus): PlaybackStatus {
+  if (status.error) {
+    throw new Error(status.error);
+  }
+  return status;
+}
+
+/**
+ * Load a session recording for playback. The engine shows the recording's
+ * starting document; advance, step or seek to apply its operations.
+ */
+export function loadRecording(recording: Recording): PlaybackStatus {
+  const json = JSON.stringify({
+    ...recording,
+    document: stripAssetUrls(recording.document),
+  });
+  return checkPlayback(getEngine().loadRecording(json));
+}
+
+/**
+ * Advance playback by elapsed wall time, applying every op that falls due.
+ */
+export function advanceRecording(elapsedMs: number): PlaybackStatus {
+  return checkPlayback(getEngine().recordingAdvance(elapsedMs));
+}
+
+export function stepRecording(): PlaybackStatus {
+  return checkPlayback(getEngine().recordingStep());
+}
+
+/**
+ * Jump to just after the first `index` ops.
+ */
+export function seekRecording(index: number): PlaybackStatus {
+  return checkPlayback(getEngine().recordingSeek(index));
+}
+
+export function setRecordingSpeed(speed: number): void {
+  getEngine().recordingSetSpeed(speed);
+}
+
+/**
+ * Fill in the fields the server assigns to an operation (such as the copies
+ * object.detachSymbol creates), so it can be applied optimistically with the
+ * same result the server will reach.
+ */
+export function prepareOperation<T extends Operation>(
+  doc: InDocument,
+  op: T,
+): T {
+  const result = getEngine().prepareOperation(
+    JSON.stringify(stripAssetUrls(doc)),
+    JSON.stringify(op),
+  );
+  if (result.error || !result.op) {
+    throw new Error(result.error ?? "prepareOperation returned nothing");
+  }
+  return JSON.parse(result.op) as T;
+}
+
 export function updateDocument(doc: InDocument): void {
   const result = getEngine().updateDocument(
     JSON.stringify(stripAssetUrls(doc)),
@@ -184,10 +317,64 @@ export function togglePlay(): void {
   getEngine().togglePlay();
 }
 
+/**
+ * Evaluate Symbol timelines while paused, so scrubbing previews the values
+ * playback would show.
+ */
+export function setAnimationPreview(enabled: boolean): void {
+  getEngine().setAnimationPreview(enabled);
+}
+
 export function setScene(sceneId: string): void {
   getEngine().setScene(sceneId);
 }
 
+/**
+ * Outline the scene's action-safe and title-safe areas in renderOverlay.
+ */
+export function setSafeAreaGuides(enabled: boolean): void {
+  getEngine().setSafeAreaGuides(enabled);
+}
+
+/**
+ * Set the percentage of the scene the action-safe and title-safe areas keep,
+ * for scenes that don't set their own. The default is 93 and 90.
+ */
+export function setSafeAreaPercent(action: number, title: number): void {
+  const result = getEngine().setSafeAreaPercent(action, title);
+  if (result.error) {
+    throw new Error(result.error);
+  }
+}
+
+/**
+ * Leave objects entirely outside the viewport, a rect in scene coordinates,
+ * out of render's draw commands. Pass null to draw everything again.
+ */
+export function setCullToViewport(
+  viewport: { x: number; y: number; width: number; height: number } | null,
+): void {
+  if (!viewport) {
+    getEngine().setCullToViewport(false);
+    return;
+  }
+  getEngine().setCullToViewport(
+    true,
+    viewport.x,
+    viewport.y,
+    viewport.width,
+    viewport.height,
+  );
+}
+
+/**
+ * Round the transforms in render's draw commands to this many decimals, or
+ * leave them exact when negative. The engine defaults to 4.
+ */
+export function setRenderPrecision(decimals: number): void {
+  getEngine().setRenderPrecision(decimals);
+}
+
 export function setSelection(ids: string[]): void {
   getEngine().setSelection(ids);
 }
@@ -220,10 +407,78 @@ export function render(): DrawCommand[] {
   return JSON.parse(json) as DrawCommand[];
 }
 
+/**
+ * Draw commands at a time in seconds, which may fall between frames. Symbols
+ * animate as in playback; the playhead doesn't move.
+ */
+export function renderAtTime(seconds: number): DrawCommand[] {
+  const json = getEngine().renderAtTime(seconds);
+  return JSON.parse(json) as DrawCommand[];
+}
+
+/**
+ * Draw commands at a frame, e.g. for thumbnail strips. Symbols animate only
+ * when playing is set; the playhead doesn't move.
+ */
+export function renderAtFrame(frame: number, playing = false): DrawCommand[] {
+  const json = getEngine().renderAtFrame(frame, playing);
+  return JSON.parse(json) as DrawCommand[];
+}
+
+/**
+ * Draw commands for editor guides over the scene, in scene coordinates.
+ */
+export function renderOverlay(): DrawCommand[] {
+  const json = getEngine().renderOverlay();
+  return JSON.parse(json) as DrawCommand[];
+}
+
+export interface SafeAreas {
+  action: { x: number; y: number; width: number; height: number };
+  title: { x: number; y: number; width: number; height: number };
+}
+
+/**
+ * The action-safe and title-safe areas of a scene (the current one by
+ * default), in scene coordinates. Null if there is no such scene.
+ */
+export function getSafeAreas(sceneId?: string): SafeAreas | null {
+  const json = getEngine().getSafeAreas(sceneId);
+  const areas = JSON.parse(json) as Partial<SafeAreas>;
+  return areas.action && areas.title ? (areas as SafeAreas) : null;
+}
+
 export function hitTest(x: number, y: number): string {
   return getEngine().hitTest(x, y);
 }
 
+/**
+ * IDs of the visible objects whose world bounds intersect a rect in scene
+ * coordinates, in painter's order, as of the last render.
+ */
+export function getVisibleObjects(
+  x: number,
+  y: number,
+  width: number,
+  height: number,
+): string[] {
+  const json = getEngine().getVisibleObjects(x, y, width, height);
+  return JSON.parse(json) as string[];
+}
+
+/**
+ * Searches the loaded document's scene names, text content and object meta
+ * for a query, ignoring case. objectId is absent for a scene name match.
+ */
+export function findObjects(query: string): {
+  sceneId: string;
+  objectId?: string;
+  field: string;
+  snippet: string;
+}[] {
+  return JSON.parse(getEngine().findObjects(query));
+}
+
 export function getSelectionBounds(): {
   x: number;
   y: number;
@@ -239,9 +494,29 @@ export function getScene(): Scene {
   return JSON.parse(json) as Scene;
 }
 
+/** An object as the layers panel shows it. */
+export interface LayerNode {
+  id: string;
+  type: ObjectType;
+  name: string; // Meta name, or the type when unnamed
+  visible: boolean;
+  locked: boolean;
+  children: LayerNode[];
+}
+
+/**
+ * The objects under a scene's root, nested and in render order (back to
+ * front). Defaults to the active scene.
+ */
+export function getLayerTree(sceneId?: string): LayerNode[] {
+  const json = getEngine().getLayerTree(sceneId);
+  return JSON.parse(json) as LayerNode[];
+}
+
 export interface PlaybackState {
   frame: number;
   playing: boolean;
+  preview: boolean;
   fps: number;
   totalFrames: number;
 }
@@ -271,6 +546,163 @@ export function getDocument(): InDocument {
   return JSON.parse(json) as InDocument;
 }
 
+export interface TimelineInfo {
+  id: string;
+  length: number;
+  fps: number;
+  trackCount: number;
+  isRoot: boolean;
+  ownerSymbolId?: string;
+}
+
+export function getTimelines(): TimelineInfo[] {
+  const json = getEngine().getTimelines();
+  return JSON.parse(json) as TimelineInfo[];
+}
+
+export interface TrackKeyframes {
+  trackId: string;
+  objectId: string;
+  property: string;
+  keyframes: Keyframe[]; // Sorted by frame
+}
+
+/**
+ * Keyframes of a timeline within [startFrame, endFrame], grouped by track.
+ * Tracks with no keyframes in range are omitted.
+ */
+export function getKeyframesInRange(
+  timelineId: string,
+  startFrame: number,
+  endFrame: number,
+): TrackKeyframes[] {
+  const json = getEngine().getKeyframesInRange(
+    timelineId,
+    startFrame,
+    endFrame,
+  );
+  return JSON.parse(json) as TrackKeyframes[];
+}
+
+/**
+ * Sorted frames holding a keyframe of the given objects' tracks, or of every
+ * track when none are given, for timeline tick marks.
+ */
+export function getKeyframeFrames(objectIds: string[] = []): number[] {
+  return getEngine().getKeyframeFrames(objectIds);
+}
+
+export interface MotionPathPoint {
+  frame: number;
+  x: number; // Scene space
+  y: number;
+  keyframe?: boolean; // A transform keyframe of the object or an ancestor
+}
+
+/**
+ * Where an object's anchor sits at each sampled frame of a timeline (the root
+ * timeline when timelineId is empty), for drawing its motion trail. Samples
+ * include the last frame and every keyframe in the range.
+ */
+export function getMotionPath(
+  objectId: string,
+  startFrame: number,
+  endFrame: number,
+  step = 1,
+  timelineId = "",
+): MotionPathPoint[] {
+  const json = getEngine().getMotionPath(
+    objectId,
+    timelineId,
+    startFrame,
+    endFrame,
+    step,
+  );
+  return JSON.parse(json) as MotionPathPoint[];
+}
+
+export interface TextLayoutInput {
+  content: string;
+  fontSize: number;
+  fontWeight?: string;
+  letterSpacing?: number;
+  maxWidth?: number; // <= 0 or omitted disables wrapping
+}
+
+export interface TextLayout {
+  lines: string[];
+  width: number;
+  height: number;
+  baselines: number[]; // y offset of each line's baseline from the top
+}
+
+/**
+ * Lay out text with the engine's embedded font metrics, so wrapping matches
+ * server-side export.
+ */
+export function measureText(input: TextLayoutInput): TextLayout {
+  const json = getEngine().measureText(JSON.stringify(input));
+  return JSON.parse(json) as TextLayout;
+}
+
+/**
+ * Create a new object of the given type with the engine's defaults: a fresh
+ * ID, unit scale, full opacity, and data sized so it renders. Overrides are
+ * applied on top; their data fields merge over the type's default data.
+ */
+export function createObject(
+  type: ObjectType,
+  overrides: Partial<Omit<ObjectNode, "transform" | "style">> & {
+    transform?: Partial<ObjectNode["transform"]>;
+    style?: Partial<ObjectNode["style"]>;
+  } = {},
+): ObjectNode {
+  const result = getEngine().createObjectJSON(type, JSON.stringify(overrides));
+  if (result.error || !result.object) {
+    throw new Error(result.error ?? "createObjectJSON returned nothing");
+  }
+  return JSON.parse(result.object) as ObjectNode;
+}
+
+export function getEasingPresets(): Record<string, EasingPreset> {
+  const json = getEngine().getEasingPresets();
+  return JSON.parse(json) as Record<string, EasingPreset>;
+}
+
+// Stats of the most recent render, for the debug overlay. Timings are in
+// microseconds; buildMicros is 0 when the cached scene graph was reused.
+// indexMicros is time getVisibleObjects spent indexing that scene graph.
+export interface RenderStats {
+  nodeCount: number;
+  commandCount: number;
+  culledCount: number;
+  rebuilt: boolean;
+  buildMicros: number;
+  indexMicros: number;
+  compileMicros: number;
+}
+
+export function getRenderStats(): RenderStats {
+  const json = getEngine().getRenderStats();
+  return JSON.parse(json) as RenderStats;
+}
+
+/**
+ * What in the loaded document this build doesn't know: a newer format
+ * version, and objects of unknown types (drawn as labeled placeholders).
+ */
+export interface Diagnostics {
+  version: number;
+  currentVersion: number;
+  newer: boolean;
+  unknownObjects: { id: string; type: string }[];
+}
+
+export function getDiagnostics(): Diagnostics {
+  const json = getEngine().getDiagnostics();
+  return JSON.parse(json) as Diagnostics;
+}
+
 export function getSelectionIds(): string[] {
   const json = getEngine().getSelection();
   return JSON.parse(json) as string[];
@@ -1,5 +1,6 @@
 import { useCallback, useMemo, useRef } from 'react'
-import { useEditorStore } from '../stores/editorStore'
+import { useEditorStore, type PresenceEntry } from '../stores/editorStore'
+import { CURSOR_SPACE_SCENE } from '../types/protocol'
 import type { Message, PresencePayload, PresenceStatePayload, PresenceJoinPayload, PresenceLeavePayload } from '../types/protocol'
 
 function userIdToColor(userId: string): string {
@@ -26,7 +27,7 @@ export function usePresence(
   const throttleMs = 60
 
   const sendCursor = useCallback(
-    (x: number, y: number) => {
+    (x: number, y: number, sceneId?: string) => {
       const now = Date.now()
       if (now - lastSendTime.current < throttleMs) return
       lastSendTime.current = now
@@ -34,7 +35,7 @@ export function usePresence(
       send({
         type: 'presence.update',
         projectId,
-        payload: { cursor: { x, y }, selection: [] },
+        payload: { cursor: { x, y, space: CURSOR_SPACE_SCENE }, selection: [], sceneId },
       })
     },
     [send, projectId],
@@ -45,13 +46,16 @@ export function usePresence(
       switch (msg.type) {
         case 'presence.state': {
           const payload = msg.payload as PresenceStatePayload
-          const map = new Map<string, { userId: string; displayName: string; cursor: { x: number; y: number } | null; selection: string[]; color: string }>()
+          const map = new Map<string, PresenceEntry>()
           for (const [userId, p] of Object.entries(payload.presences)) {
             map.set(userId, {
               userId,
               displayName: p.displayName || '',
               cursor: p.cursor || null,
+              sceneId: p.sceneId || null,
               selection: p.selection || [],
+              editingObjects: p.editingObjects || [],
+              selectionBounds: p.selectionBounds || null,
               color: userIdToColor(userId),
             })
           }
@@ -65,7 +69,10 @@ export function usePresence(
           updatePresence(userId, {
             displayName: payload.displayName || undefined,
             cursor: payload.cursor || null,
+            sceneId: payload.sceneId || null,
             selection: payload.selection || [],
+            editingObjects: payload.editingObjects || [],
+            selectionBounds: payload.selectionBounds || null,
           })
           break
         }
@@ -74,7 +81,10 @@ export function usePresence(
           updatePresence(payload.userId, {
             displayName: payload.displayName,
             cursor: null,
+            sceneId: null,
             selection: [],
+            editingObjects: [],
+            selectionBounds: null,
             color: userIdToColor(payload.userId),
           })
           break
@@ -11,23 +11,40 @@ import {
   type DragType,
 } from "../components/canvas/CanvasViewport";
 import type { Bounds } from "../engine/commands";
-import { invertMatrix, transformVector } from "../engine/commands";
+import {
+  forgetAssetImages,
+  invertMatrix,
+  transformVector,
+} from "../engine/commands";
 import { Toolbar, type Tool } from "../components/editor/Toolbar";
 import { PropertiesPanel } from "../components/editor/PropertiesPanel";
 import { TimelinePanel } from "../components/editor/TimelinePanel";
 import type { BreadcrumbEntry } from "../components/editor/TimelinePanel";
 import { MenuBar } from "../components/editor/MenuBar";
-import { getLatestSnapshot } from "../api/projects";
+import {
+  createProjectFromPlayground,
+  getLatestSnapshot,
+} from "../api/projects";
 import { API_BASE } from "../api/client";
-import { exportPngSequence, exportVideo, exportHTML } from "../utils/export";
+import {
+  exportPngSequence,
+  exportVideo,
+  exportAllScenes,
+  exportHTML,
+} from "../utils/export";
 import { parseSVG } from "../utils/svgImport";
+import { newId } from "../utils/typeid";
 
-import { MessageTypes } from "../types/protocol";
-import type { Message } from "../types/protocol";
+import { CURSOR_SPACE_SCENE, MessageTypes } from "../types/protocol";
+import type { CursorPos, Message } from "../types/protocol";
 import type {
   OperationAckPayload,
   OperationNackPayload,
   OperationBroadcastPayload,
+  OperationBatchAckPayload,
+  OperationBatchNackPayload,
+  OperationBatchBroadcastPayload,
+  AssetUpdatedPayload,
   ErrorPayload,
 } from "../types/protocol";
 import type {
@@ -76,12 +93,14 @@ export function EditorPage() {
     total: number;
     phase: string;
   } | null>(null);
+  const exportAbortRef = useRef<AbortController | null>(null);
   const [toast, setToast] = useState<string | null>(null);
   const [onionSkinEnabled, setOnionSkinEnabled] = useState(false);
   const [clipboard, setClipboard] = useState<ObjectNode[] | null>(null);
   const [gridEnabled, setGridEnabled] = useState(false);
   const [snapToGrid, setSnapToGrid] = useState(false);
   const [gridSize, setGridSize] = useState(20);
+  const [safeAreaGuides, setSafeAreaGuides] = useState(false);
   const snapToGridRef = useRef(false);
   const gridSizeRef = useRef(20);
   snapToGridRef.current = snapToGrid;
@@ -145,11 +164,16 @@ export function EditorPage() {
       // Handle error message (e.g., document load failed)
       if (msg.type === MessageTypes.ERROR) {
         const error = msg.payload as ErrorPayload;
+        if (error.code === "sync_rate_limited") {
+          // The document is still loaded; only the resync was refused
+          console.warn(error.message);
+          return;
+        }
         setLoadError(error.message);
         return;
       }
 
-      // Handle document sync (sent when client joins)
+      // Handle document sync (sent when client joins or asks to resync)
       if (msg.type === MessageTypes.DOC_SYNC) {
         const syncedDoc = msg.payload as InDocument;
         setDocument(syncedDoc);
@@ -171,6 +195,28 @@ export function EditorPage() {
             msg.payload as OperationBroadcastPayload,
           );
           return;
+        case MessageTypes.OP_BATCH_ACK:
+          commandDispatcher.handleBatchAck(
+            msg.payload as OperationBatchAckPayload,
+          );
+          return;
+        case MessageTypes.OP_BATCH_NACK:
+          commandDispatcher.handleBatchNack(
+            msg.payload as OperationBatchNackPayload,
+          );
+          return;
+        case MessageTypes.OP_BATCH_BROADCAST:
+          commandDispatcher.handleRemoteBatch(
+            msg.payload as OperationBatchBroadcastPayload,
+          );
+          return;
+        case MessageTypes.ASSET_UPDATED: {
+          // The op broadcast carried the new URL; drop the stale image
+          const { asset } = msg.payload as AssetUpdatedPayload;
+          forgetAssetImages(asset.id);
+          stageRef.current.invalidate();
+          return;
+        }
       }
       // Handle presence messages
       handleMessage(msg);
@@ -287,8 +333,17 @@ export function EditorPage() {
           setCurrentFrame(stageRef.current.getCurrentFrame());
         }
       },
+      onDocumentDiagnostics: (diagnostics) => {
+        const unknown = diagnostics.unknownObjects.length;
+        showToast(
+          unknown > 0
+            ? `${unknown} object${unknown === 1 ? "" : "s"} of unknown type shown as placeholders — this document needs a newer editor`
+            : "This document was saved by a newer editor; some features may be missing",
+          5000,
+        );
+      },
     });
-  }, []);
+  }, [showToast]);
 
   // Load document - from API for authenticated projects only
   // Anonymous/local users receive their document via WebSocket doc.sync message
@@ -353,18 +408,54 @@ export function EditorPage() {
     stageRef.current.setGrid(gridEnabled, gridSize);
   }, [gridEnabled, gridSize]);
 
-  // Cursor presence send
+  // Sync safe-area guides to Stage
+  useEffect(() => {
+    stageRef.current.setSafeAreaGuides(safeAreaGuides);
+  }, [safeAreaGuides]);
+
+  // Presence send. Every update carries the cursor, selection and playhead,
+  // since each one replaces the user's previous presence; the server resolves
+  // the selection's bounds for other clients to outline.
+  const presenceRef = useRef({
+    cursor: null as CursorPos | null,
+    selection: [] as string[],
+    frame: 0,
+    sceneId: undefined as string | undefined,
+    editingObjects: [] as string[], // Held while dragging (edit locks)
+  });
+  presenceRef.current.selection = selectedObjectIds;
+  presenceRef.current.frame = currentFrame;
+  presenceRef.current.sceneId = scene?.id;
+
+  const sendPresence = useCallback(() => {
+    const { cursor, selection, frame, sceneId, editingObjects } =
+      presenceRef.current;
+    sendRef.current({
+      type: "presence.update",
+      projectId: projectId || "",
+      payload: {
+        cursor: cursor ?? undefined,
+        selection,
+        frame,
+        sceneId,
+        editingObjects,
+      },
+    });
+  }, [projectId]);
+
+  // Cursors are sent in scene space (see CURSOR_SPACE_SCENE)
   const sendCursor = useCallback(
     (x: number, y: number) => {
-      sendRef.current({
-        type: "presence.update",
-        projectId: projectId || "",
-        payload: { cursor: { x, y }, selection: [] },
-      });
+      presenceRef.current.cursor = { x, y, space: CURSOR_SPACE_SCENE };
+      sendPresence();
     },
-    [projectId],
+    [sendPresence],
   );
 
+  useEffect(() => {
+    sendPresence();
+  }, [selectedObjectIds, scene?.id, sendPresence]);
+
   const lastCursorSend = useRef(0);
   const throttledSendCursor = useCallback(
     (x: number, y: number) => {
@@ -574,8 +665,12 @@ export function EditorPage() {
         origWidth,
         origHeight,
       };
+
+      // Lock the dragged objects so others' edits don't interleave with ours
+      presenceRef.current.editingObjects = objectIds;
+      sendPresence();
     },
-    [doc, selectedObjectIds],
+    [doc, selectedObjectIds, sendPresence],
   );
 
   const handleDragMove = useCallback((x: number, y: number) => {
@@ -759,7 +854,7 @@ export function EditorPage() {
             type: "keyframe.add",
             trackId,
             keyframe: {
-              id: crypto.randomUUID(),
+              id: newId("kf"),
               frame,
               value,
               easing: "linear" as const,
@@ -803,6 +898,11 @@ export function EditorPage() {
     const drag = dragRef.current;
     if (!drag) return;
 
+    // Release the edit locks once the gesture's edits are sent
+    presenceRef.current.editingObjects = [];
+
+    // Send the gesture's edits together rather than one op per object
+    commandDispatcher.beginBatch();
     if (drag.dragType === "move") {
       for (const id of drag.objectIds) {
         const animOrig = drag.origTransforms.get(id);
@@ -826,8 +926,10 @@ export function EditorPage() {
       const animOrig = drag.origTransforms.get(singleId);
       const final = drag.lastOverlay[singleId];
       if (!animOrig || !final) {
+        commandDispatcher.endBatch();
         dragRef.current = null;
         stageRef.current.clearDragOverlay();
+        sendPresence();
         return;
       }
 
@@ -861,6 +963,7 @@ export function EditorPage() {
         updateTransformWithKeyframes(singleId, updates);
       }
     }
+    commandDispatcher.endBatch();
 
     // Dispatch updates the Zustand store synchronously, but the useEffect([doc])
     // that syncs to WASM fires asynchronously. Push the updated doc to WASM now
@@ -874,7 +977,8 @@ export function EditorPage() {
     stageRef.current.clearDragOverlay();
 
     dragRef.current = null;
-  }, [updateTransformWithKeyframes]);
+    sendPresence();
+  }, [updateTransformWithKeyframes, sendPresence]);
 
   // --- Playback controls (delegated to Stage) ---
 
@@ -904,6 +1008,19 @@ export function EditorPage() {
     setSelectedObjectIds([]);
   }, [selectedObjectIds]);
 
+  // Reset the selection's base transforms, keeping anchors and animation
+  const handleResetTransform = useCallback(() => {
+    const freshDoc = useEditorStore.getState().document;
+    const objectIds = selectedObjectIds.filter(
+      (id) => !freshDoc?.objects[id]?.locked,
+    );
+    if (objectIds.length === 0) return;
+    commandDispatcher.dispatch({
+      type: "object.resetTransform",
+      objectIds,
+    });
+  }, [selectedObjectIds]);
+
   const handleSelectAll = useCallback(() => {
     if (!doc || !scene) return;
     const root = doc.objects[scene.root];
@@ -1105,7 +1222,7 @@ export function EditorPage() {
       minY = 0;
     }
 
-    const groupId = crypto.randomUUID();
+    const groupId = newId("obj");
     const insertIndex = root.children.indexOf(sorted[0]);
 
     // Create the group object at the combined min corner
@@ -1257,8 +1374,8 @@ export function EditorPage() {
 
   const handleCreateScene = useCallback(() => {
     if (!doc) return;
-    const sceneId = crypto.randomUUID();
-    const rootId = crypto.randomUUID();
+    const sceneId = newId("scene");
+    const rootId = newId("obj");
     // Copy dimensions from current scene, or use defaults
     const width = scene?.width ?? 1280;
     const height = scene?.height ?? 720;
@@ -1420,6 +1537,26 @@ export function EditorPage() {
     [],
   );
 
+  const handleDetachSymbol = useCallback((objectId: string) => {
+    const before = useEditorStore.getState().document;
+    const parentId = before?.objects[objectId]?.parent;
+    if (!before || !parentId) return;
+    commandDispatcher.dispatch({
+      type: "object.detachSymbol",
+      objectId,
+    });
+
+    // Select the group that took the symbol's place
+    const freshDoc = useEditorStore.getState().document;
+    if (!freshDoc || freshDoc.objects[objectId]) return;
+    stageRef.current.updateDocument(freshDoc);
+    const previous = new Set(before.objects[parentId].children);
+    const groupId = freshDoc.objects[parentId]?.children.find(
+      (id) => !previous.has(id),
+    );
+    setSelectedObjectIds(groupId ? [groupId] : []);
+  }, []);
+
   // --- Object creation ---
 
   const handleCreateObject = useCallback(
@@ -1435,7 +1572,7 @@ export function EditorPage() {
         cy = Math.round(cy / gs) * gs;
       }
 
-      const objectId = crypto.randomUUID();
+      const objectId = newId("obj");
       const defaultSize = 100;
 
       // Build the new object based on tool type
@@ -1597,7 +1734,7 @@ export function EditorPage() {
       const ax = width / 2;
       const ay = height / 2;
 
-      const objectId = crypto.randomUUID();
+      const objectId = newId("obj");
       const newObject: ObjectNode = {
         id: objectId,
         type: "VectorPath",
@@ -1642,39 +1779,171 @@ export function EditorPage() {
 
   // --- Image paste/drop ---
 
-  const uploadAndCreateImage = useCallback(
-    async (blob: Blob) => {
-      if (!doc || !scene) return;
+  const uploadAndCreateImages = useCallback(
+    async (files: Blob[]) => {
+      if (!doc || !scene || files.length === 0) return;
 
-      // Upload to server
+      // One request for the lot; each file succeeds or fails on its own.
+      // The project goes first so the server knows who the files are for.
       const formData = new FormData();
-      formData.append("file", blob);
+      if (projectId) {
+        formData.append("projectId", projectId);
+      }
+      for (const file of files) {
+        formData.append("file", file);
+      }
 
       let resp: Response;
       try {
-        resp = await fetch(`${API_BASE}/assets/upload`, {
+        resp = await fetch(`${API_BASE}/assets/upload/batch`, {
           method: "POST",
           body: formData,
         });
       } catch {
-        console.error("Asset upload failed: network error");
+        showToast("Image upload failed: network error");
         return;
       }
       if (!resp.ok) {
-        console.error("Asset upload failed:", resp.status, await resp.text());
+        const err = await resp.json().catch(() => null);
+        showToast(`Image upload failed: ${err?.message ?? resp.status}`);
         return;
       }
 
-      const result = (await resp.json()) as {
+      // One result per file, in the order they were sent
+      const results = (await resp.json()) as {
         id: string;
         url: string;
         width: number;
         height: number;
         type: string;
         name: string;
+        error?: { code: string; message: string };
+      }[];
+
+      const createdIds: string[] = [];
+      const failed: string[] = [];
+      commandDispatcher.beginBatch();
+      for (const result of results) {
+        if (result.error) {
+          failed.push(`${result.name || "image"}: ${result.error.message}`);
+          continue;
+        }
+
+        const objectId = newId("obj");
+
+        const asset: Asset = {
+          id: result.id,
+          type: result.type as Asset["type"],
+          name: result.name,
+          url: result.url,
+          meta: {},
+        };
+
+        // Cascade the images so each one stays visible
+        const w = result.width;
+        const h = result.height;
+        const offset = createdIds.length * 20;
+
+        const newObject: ObjectNode = {
+          id: objectId,
+          type: "RasterImage",
+          parent: scene.root,
+          children: [],
+          transform: {
+            x: (scene.width - w) / 2 + offset,
+            y: (scene.height - h) / 2 + offset,
+            sx: 1,
+            sy: 1,
+            r: 0,
+            ax: w / 2,
+            ay: h / 2,
+            skewX: 0,
+            skewY: 0,
+          },
+          style: {
+            fill: "",
+            stroke: "",
+            strokeWidth: 0,
+            opacity: 1,
+          },
+          visible: true,
+          locked: false,
+          data: { assetId: result.id, width: w, height: h },
+        };
+
+        commandDispatcher.dispatch({
+          type: "object.create",
+          object: newObject,
+          parentId: scene.root,
+          asset,
+        });
+        createdIds.push(objectId);
+      }
+      commandDispatcher.endBatch();
+
+      if (failed.length > 0) {
+        showToast(
+          `${failed.length} of ${results.length} images failed to upload: ${failed.join("; ")}`,
+          5000,
+        );
+      }
+      if (createdIds.length > 0) {
+        setSelectedObjectIds(createdIds);
+        setActiveTool("select");
+      }
+    },
+    [doc, scene, projectId, showToast],
+  );
+
+  // --- Image sequence import ---
+
+  const uploadAndCreateSequence = useCallback(
+    async (files: File[]) => {
+      if (!doc || !scene || files.length === 0) return;
+
+      // Frames play in file name order (frame_2 before frame_10)
+      const sorted = [...files].sort((a, b) =>
+        a.name.localeCompare(b.name, undefined, { numeric: true }),
+      );
+      const formData = new FormData();
+      if (projectId) {
+        formData.append("projectId", projectId);
+      }
+      for (const file of sorted) {
+        formData.append("file", file);
+      }
+
+      let resp: Response;
+      try {
+        resp = await fetch(`${API_BASE}/assets/upload/sequence`, {
+          method: "POST",
+          body: formData,
+        });
+      } catch {
+        showToast("Image sequence upload failed: network error");
+        return;
+      }
+      if (!resp.ok) {
+        const err = await resp.json().catch(() => null);
+        showToast(
+          `Image sequence upload failed: ${err?.message ?? resp.status}`,
+        );
+        return;
+      }
+
+      // The frames come back stitched into one sprite sheet
+      const result = (await resp.json()) as {
+        id: string;
+        url: string;
+        type: string;
+        name: string;
+        frameCount: number;
+        columns: number;
+        frameWidth: number;
+        frameHeight: number;
       };
 
-      const objectId = crypto.randomUUID();
+      const objectId = newId("obj");
 
       const asset: Asset = {
         id: result.id,
@@ -1684,12 +1953,12 @@ export function EditorPage() {
         meta: {},
       };
 
-      const w = result.width;
-      const h = result.height;
+      const w = result.frameWidth;
+      const h = result.frameHeight;
 
       const newObject: ObjectNode = {
         id: objectId,
-        type: "RasterImage",
+        type: "SpriteSequence",
         parent: scene.root,
         children: [],
         transform: {
@@ -1711,7 +1980,14 @@ export function EditorPage() {
         },
         visible: true,
         locked: false,
-        data: { assetId: result.id, width: w, height: h },
+        data: {
+          sheetAssetId: result.id,
+          frameCount: result.frameCount,
+          columns: result.columns,
+          width: w,
+          height: h,
+          startFrame: currentFrame,
+        },
       };
 
       commandDispatcher.dispatch({
@@ -1723,10 +1999,22 @@ export function EditorPage() {
 
       setSelectedObjectIds([objectId]);
       setActiveTool("select");
+      showToast(`Imported ${result.frameCount} frame image sequence`);
     },
-    [doc, scene],
+    [doc, scene, projectId, currentFrame, showToast],
   );
 
+  const handleImportImageSequence = useCallback(() => {
+    const input = document.createElement("input");
+    input.type = "file";
+    input.accept = "image/png,image/jpeg";
+    input.multiple = true;
+    input.onchange = () => {
+      uploadAndCreateSequence(Array.from(input.files ?? []));
+    };
+    input.click();
+  }, [uploadAndCreateSequence]);
+
   // Paste handler (Cmd+V with image on clipboard)
   useEffect(() => {
     const handlePaste = (e: ClipboardEvent) => {
@@ -1739,7 +2027,7 @@ export function EditorPage() {
           e.preventDefault();
           const blob = item.getAsFile();
           if (!blob) continue;
-          uploadAndCreateImage(blob);
+          uploadAndCreateImages([blob]);
           return;
         }
       }
@@ -1747,7 +2035,7 @@ export function EditorPage() {
 
     window.addEventListener("paste", handlePaste);
     return () => window.removeEventListener("paste", handlePaste);
-  }, [doc, scene, uploadAndCreateImage]);
+  }, [doc, scene, uploadAndCreateImages]);
 
   // --- SVG Import ---
 
@@ -1764,7 +2052,7 @@ export function EditorPage() {
 
       if (parsed.length === 1) {
         const item = parsed[0];
-        const objectId = crypto.randomUUID();
+        const objectId = newId("obj");
         const t = item.transform;
         const posX = atX ?? scene.width / 2;
         const posY = atY ?? scene.height / 2;
@@ -1796,7 +2084,7 @@ export function EditorPage() {
         });
         createdIds.push(objectId);
       } else {
-        const groupId = crypto.randomUUID();
+        const groupId = newId("obj");
         const groupX = atX ?? scene.width / 2;
         const groupY = atY ?? scene.height / 2;
 
@@ -1828,7 +2116,7 @@ export function EditorPage() {
 
         for (let i = 0; i < parsed.length; i++) {
           const item = parsed[i];
-          const objectId = crypto.randomUUID();
+          const objectId = newId("obj");
           const t = item.transform;
           commandDispatcher.dispatch({
             type: "object.create",
@@ -1901,6 +2189,7 @@ export function EditorPage() {
       const files = e.dataTransfer?.files;
       if (!files) return;
 
+      const images: File[] = [];
       for (const file of files) {
         // SVG files: parse and import as vector objects
         if (file.type === "image/svg+xml" || file.name.endsWith(".svg")) {
@@ -1914,13 +2203,13 @@ export function EditorPage() {
             importSvgObjects(svgText, dropX, dropY);
           };
           reader.readAsText(file);
-          return;
+          continue;
         }
         if (file.type.startsWith("image/")) {
-          uploadAndCreateImage(file);
-          return; // One image at a time
+          images.push(file);
         }
       }
+      uploadAndCreateImages(images);
     };
 
     container.addEventListener("dragover", handleDragOver);
@@ -1929,7 +2218,7 @@ export function EditorPage() {
       container.removeEventListener("dragover", handleDragOver);
       container.removeEventListener("drop", handleDrop);
     };
-  }, [doc, scene, uploadAndCreateImage, importSvgObjects]);
+  }, [doc, scene, uploadAndCreateImages, importSvgObjects]);
 
   // --- Keyframe handlers ---
 
@@ -2043,7 +2332,7 @@ export function EditorPage() {
         }
 
         // No existing keyframe at this frame, add new one
-        const keyframeId = crypto.randomUUID();
+        const keyframeId = newId("kf");
         commandDispatcher.dispatch({
           type: "keyframe.add",
           trackId,
@@ -2056,7 +2345,7 @@ export function EditorPage() {
         });
       } else {
         // No track exists, create one and add keyframe
-        const newTrackId = crypto.randomUUID();
+        const newTrackId = newId("track");
         commandDispatcher.dispatch({
           type: "track.create",
           track: {
@@ -2069,7 +2358,7 @@ export function EditorPage() {
         });
 
         // Add the keyframe to the new track
-        const keyframeId = crypto.randomUUID();
+        const keyframeId = newId("kf");
         commandDispatcher.dispatch({
           type: "keyframe.add",
           trackId: newTrackId,
@@ -2162,16 +2451,22 @@ export function EditorPage() {
     });
   }, []);
 
+  // dropIndex is the gap among the siblings the object was dropped into,
+  // counting the object's own place
   const handleReorderObject = useCallback(
-    (objectId: string, newIndex: number) => {
+    (objectId: string, dropIndex: number) => {
       const freshDoc = useEditorStore.getState().document;
       if (!freshDoc) return;
       const obj = freshDoc.objects[objectId];
       if (!obj?.parent) return;
+      const parent = freshDoc.objects[obj.parent];
+      const currentIndex = parent?.children.indexOf(objectId) ?? -1;
+      if (currentIndex < 0) return;
+      const newIndex = dropIndex > currentIndex ? dropIndex - 1 : dropIndex;
+      if (newIndex === currentIndex) return;
       commandDispatcher.dispatch({
-        type: "object.reparent",
+        type: "object.reorder",
         objectId,
-        newParentId: obj.parent,
         newIndex,
       });
     },
@@ -2200,9 +2495,8 @@ export function EditorPage() {
     if (currentIndex === parent.children.length - 1) return;
 
     commandDispatcher.dispatch({
-      type: "object.reparent",
+      type: "object.reorder",
       objectId: singleSelectedId,
-      newParentId: obj.parent,
       newIndex: parent.children.length - 1,
     });
   }, [singleSelectedId, doc]);
@@ -2217,9 +2511,8 @@ export function EditorPage() {
     if (currentIndex === 0) return;
 
     commandDispatcher.dispatch({
-      type: "object.reparent",
+      type: "object.reorder",
       objectId: singleSelectedId,
-      newParentId: obj.parent,
       newIndex: 0,
     });
   }, [singleSelectedId, doc]);
@@ -2234,10 +2527,9 @@ export function EditorPage() {
     if (currentIndex >= parent.children.length - 1) return;
 
     commandDispatcher.dispatch({
-      type: "object.reparent",
+      type: "object.reorder",
       objectId: singleSelectedId,
-      newParentId: obj.parent,
-      newIndex: currentIndex + 2,
+      newIndex: currentIndex + 1,
     });
   }, [singleSelectedId, doc]);
 
@@ -2251,9 +2543,8 @@ export function EditorPage() {
     if (currentIndex <= 0) return;
 
     commandDispatcher.dispatch({
-      type: "object.reparent",
+      type: "object.reorder",
       objectId: singleSelectedId,
-      newParentId: obj.parent,
       newIndex: currentIndex - 1,
     });
   }, [singleSelectedId, doc]);
@@ -2339,7 +2630,7 @@ export function EditorPage() {
     // Build old→new ID mapping
     const idMap = new Map<string, string>();
     for (const obj of clipboard) {
-      idMap.set(obj.id, crypto.randomUUID());
+      idMap.set(obj.id, newId("obj"));
     }
 
     // Remap IDs, parents, children, and offset position
@@ -2464,26 +2755,46 @@ export function EditorPage() {
       const dy = e.key === "ArrowUp" ? -step : e.key === "ArrowDown" ? step : 0;
 
       e.preventDefault();
+      const unanimated: string[] = [];
       for (const id of selectedObjectIds) {
         const obj = freshDoc.objects[id];
         if (!obj || obj.locked) continue;
 
         // Get animated transform from WASM (respects keyframes at current frame)
         const animated = stageRef.current.getAnimatedTransform(id);
-        const currentX = animated?.x ?? obj.transform.x;
-        const currentY = animated?.y ?? obj.transform.y;
+        const values = {
+          "transform.x": (animated?.x ?? obj.transform.x) + dx,
+          "transform.y": (animated?.y ?? obj.transform.y) + dy,
+        };
+
+        // Keyframed properties are updated at the current frame; the rest
+        // move by the delta in one nudge, so concurrent edits aren't lost
+        const handled = updateWithKeyframes(id, values);
+        if (handled.size === 0) {
+          unanimated.push(id);
+        } else if (handled.size < 2) {
+          commandDispatcher.dispatch({
+            type: "object.nudge",
+            objectIds: [id],
+            deltas: handled.has("transform.x")
+              ? { y: `${dy}px` }
+              : { x: `${dx}px` },
+          });
+        }
+      }
 
-        // Use updateTransformWithKeyframes to handle both keyframed and base transforms
-        updateTransformWithKeyframes(id, {
-          "transform.x": currentX + dx,
-          "transform.y": currentY + dy,
+      if (unanimated.length > 0) {
+        commandDispatcher.dispatch({
+          type: "object.nudge",
+          objectIds: unanimated,
+          deltas: { x: `${dx}px`, y: `${dy}px` },
         });
       }
     };
 
     window.addEventListener("keydown", handleNudge);
     return () => window.removeEventListener("keydown", handleNudge);
-  }, [selectedObjectIds, updateTransformWithKeyframes]);
+  }, [selectedObjectIds, updateWithKeyframes]);
 
   // --- Record Keyframe handler ---
 
@@ -2617,6 +2928,17 @@ export function EditorPage() {
     navigate("/projects");
   }, [navigate]);
 
+  const handleSaveAsProject = useCallback(async () => {
+    if (!doc) return;
+    try {
+      const project = await createProjectFromPlayground(undefined, doc);
+      navigate(`/editor/${project.id}`);
+    } catch (error) {
+      console.error("Save as project failed:", error);
+      showToast("Could not save the playground as a project");
+    }
+  }, [doc, navigate, showToast]);
+
   const handleExportPng = useCallback(() => {
     if (!doc || !scene) return;
 
@@ -2711,7 +3033,7 @@ export function EditorPage() {
   }, [doc, scene, selectedObjectIds, currentFrame, totalFrames, showToast]);
 
   const handleExportVideo = useCallback(
-    async (format: "mp4" | "gif" | "webm") => {
+    async (format: "mp4" | "gif" | "webm", outputFps?: number) => {
       if (!doc || !scene) return;
 
       const canvas = containerRef.current?.querySelector("canvas");
@@ -2722,6 +3044,8 @@ export function EditorPage() {
 
       stageRef.current.setSelectedObjectIds([]);
 
+      const controller = new AbortController();
+      exportAbortRef.current = controller;
       try {
         await exportVideo(
           stageRef.current,
@@ -2731,11 +3055,18 @@ export function EditorPage() {
           format,
           doc.project.fps || 24,
           (progress) => setExportProgress(progress),
+          outputFps,
+          controller.signal,
         );
       } catch (error) {
-        console.error("Video export failed:", error);
-        showToast("Video export failed");
+        if (controller.signal.aborted) {
+          showToast("Export cancelled");
+        } else {
+          console.error("Video export failed:", error);
+          showToast("Video export failed");
+        }
       } finally {
+        exportAbortRef.current = null;
         setExportProgress(null);
         stageRef.current.seek(previousFrame);
         stageRef.current.setSelectedObjectIds(previousSelection);
@@ -2745,6 +3076,55 @@ export function EditorPage() {
     [doc, scene, selectedObjectIds, currentFrame, totalFrames, showToast],
   );
 
+  const handleExportAllScenes = useCallback(async () => {
+    if (!doc || !scene) return;
+
+    const canvas = containerRef.current?.querySelector("canvas");
+    if (!canvas) return;
+
+    const previousSelection = selectedObjectIds;
+    const previousFrame = currentFrame;
+
+    stageRef.current.setSelectedObjectIds([]);
+
+    const controller = new AbortController();
+    exportAbortRef.current = controller;
+    try {
+      const status = await exportAllScenes(
+        stageRef.current,
+        canvas,
+        doc,
+        totalFrames,
+        "mp4",
+        (progress) => setExportProgress(progress),
+        controller.signal,
+      );
+      const failed = status.scenes.filter((s) => s.state === "failed");
+      if (status.state !== "completed") {
+        showToast("Scene export failed");
+      } else if (failed.length > 0) {
+        showToast(
+          `Exported ${status.scenes.length - failed.length} of ${status.scenes.length} scenes`,
+          4000,
+        );
+      }
+    } catch (error) {
+      if (controller.signal.aborted) {
+        showToast("Export cancelled");
+      } else {
+        console.error("Scene export failed:", error);
+        showToast("Scene export failed");
+      }
+    } finally {
+      exportAbortRef.current = null;
+      setExportProgress(null);
+      stageRef.current.setScene(scene.id);
+      stageRef.current.seek(previousFrame);
+      stageRef.current.setSelectedObjectIds(previousSelection);
+      stageRef.current.invalidate();
+    }
+  }, [doc, scene, selectedObjectIds, currentFrame, totalFrames, showToast]);
+
   const handleExportHTML = useCallback(async () => {
     if (!doc) return;
     try {
@@ -2841,11 +3221,18 @@ export function EditorPage() {
         onSelectAll={handleSelectAll}
         onDeselect={handleDeselect}
         onNewDocument={handleNewDocument}
+        onSaveAsProject={!projectId && token ? handleSaveAsProject : undefined}
         onExportPng={handleExportPng}
         onExportPngSequence={handleExportPngSequence}
         onExportMp4={() => handleExportVideo("mp4")}
+        onExportMp4HighFps={() => handleExportVideo("mp4", 60)}
         onExportGif={() => handleExportVideo("gif")}
         onExportWebm={() => handleExportVideo("webm")}
+        onExportAllScenes={
+          doc && doc.project.scenes.length > 1
+            ? handleExportAllScenes
+            : undefined
+        }
         onExportHTML={handleExportHTML}
         isExporting={exportProgress !== null}
         onZoomIn={handleZoomIn}
@@ -2860,6 +3247,7 @@ export function EditorPage() {
         onDeleteAll={handleDeleteAll}
         onGroup={handleGroupSelection}
         onUngroup={handleUngroupSelection}
+        onResetTransform={handleResetTransform}
         canGroup={selectedObjectIds.length >= 2}
         canUngroup={
           selectedObjectIds.length === 1 &&
@@ -2875,12 +3263,15 @@ export function EditorPage() {
         canPaste={clipboard !== null && clipboard.length > 0}
         onDuplicate={handleDuplicate}
         onImportSvg={handleImportSvg}
+        onImportImageSequence={handleImportImageSequence}
         gridEnabled={gridEnabled}
         snapToGrid={snapToGrid}
         gridSize={gridSize}
         onToggleGrid={() => setGridEnabled((v) => !v)}
         onToggleSnap={() => setSnapToGrid((v) => !v)}
         onSetGridSize={setGridSize}
+        safeAreaGuides={safeAreaGuides}
+        onToggleSafeAreaGuides={() => setSafeAreaGuides((v) => !v)}
       />
 
       {/* Main editor area */}
@@ -2898,6 +3289,7 @@ export function EditorPage() {
             sceneWidth={scene.width}
             sceneHeight={scene.height}
             sceneBackground={scene.background || "#ffffff"}
+            sceneId={scene.id}
             selectedObjectIds={selectedObjectIds}
             activeTool={activeTool}
             spaceHeld={spaceHeld}
@@ -2929,6 +3321,7 @@ export function EditorPage() {
             onSceneUpdate={handleSceneUpdate}
             onObjectUpdate={handleObjectUpdate}
             onDataUpdate={handleDataUpdate}
+            onDetachSymbol={handleDetachSymbol}
             onAlign={handleAlign}
             onDistribute={handleDistribute}
           />
@@ -2997,6 +3390,14 @@ export function EditorPage() {
                 }}
               />
             </div>
+            {exportAbortRef.current && (
+              <button
+                className="mt-4 w-full rounded bg-gray-700 px-3 py-1.5 text-sm text-gray-200 hover:bg-gray-600"
+                onClick={() => exportAbortRef.current?.abort()}
+              >
+                Cancel
+              </button>
+            )}
           </div>
         </div>
       )}
@@ -1,11 +1,15 @@
 import { create } from "zustand";
 import type { InDocument, Transform } from "../types/document";
+import type { CursorPos, SelectionBounds } from "../types/protocol";
 
 export interface PresenceEntry {
   userId: string;
   displayName: string;
-  cursor: { x: number; y: number } | null;
+  cursor: CursorPos | null;
+  sceneId: string | null; // Scene the cursor is over, if the client said
   selection: string[];
+  editingObjects: string[]; // Objects the user holds edit locks on
+  selectionBounds: SelectionBounds | null;
   color: string;
 }
 
@@ -81,7 +85,10 @@ export const useEditorStore = create<EditorState>((set, get) => ({
       userId,
       displayName: "",
       cursor: null,
+      sceneId: null,
       selection: [],
+      editingObjects: [],
+      selectionBounds: null,
       color: userIdToColor(userId),
     };
     presences.set(userId, { ...existing, ...entry });
@@ -6,6 +6,7 @@ export interface InDocument {
   tracks: Record<string, Track>;
   keyframes: Record<string, Keyframe>;
   assets: Record<string, Asset>;
+  easingPresets?: Record<string, EasingPreset>;
 }
 
 export interface Project {
@@ -18,6 +19,25 @@ export interface Project {
   scenes: string[];
   assets: string[];
   rootTimeline: string;
+  units?: Units; // Display only; document values are always pixels
+  direction?: "ltr" | "rtl";
+  linkedAssets?: boolean; // Editors may replace asset content in place
+}
+
+export type Units = "px" | "pt" | "in" | "cm" | "mm";
+
+/** Distances in from each edge of a scene, in pixels. */
+export interface Insets {
+  top: number;
+  right: number;
+  bottom: number;
+  left: number;
+}
+
+/** Broadcast safe areas; title-safe lies within action-safe. */
+export interface SafeAreaInsets {
+  action: Insets;
+  title: Insets;
 }
 
 export interface Scene {
@@ -27,6 +47,7 @@ export interface Scene {
   height: number;
   background: string;
   root: string;
+  safeAreaInsets?: SafeAreaInsets | null; // Absent uses the standard areas
 }
 
 export type ObjectType =
@@ -36,7 +57,8 @@ export type ObjectType =
   | "VectorPath"
   | "RasterImage"
   | "Symbol"
-  | "Text";
+  | "Text"
+  | "SpriteSequence";
 
 export interface Transform {
   x: number;
@@ -50,11 +72,33 @@ export interface Transform {
   skewY: number;
 }
 
+export interface GradientStop {
+  offset: number; // 0 at the gradient's start, 1 at its end
+  color: string;
+}
+
+/**
+ * A fill blending between color stops, in the object's local space: linear
+ * from (x1, y1) to (x2, y2), radial from (cx, cy) out to radius r.
+ */
+export interface Gradient {
+  type: "linear" | "radial";
+  stops: GradientStop[];
+  x1?: number;
+  y1?: number;
+  x2?: number;
+  y2?: number;
+  cx?: number;
+  cy?: number;
+  r?: number;
+}
+
 export interface Style {
   fill: string;
   stroke: string;
   strokeWidth: number;
   opacity: number;
+  fillGradient?: Gradient; // Fills in place of fill when set
 }
 
 export interface ObjectNode {
@@ -73,7 +117,9 @@ export interface ObjectNode {
     | RasterImageData
     | SymbolData
     | TextData
+    | SpriteSequenceData
     | Record<string, never>;
+  meta?: Record<string, unknown>; // Integration metadata, never rendered
 }
 
 export type PathCommand =
@@ -87,6 +133,17 @@ export interface VectorPathData {
   commands: PathCommand[];
 }
 
+// One vertex of a VectorPath for vertex-level edits. Vertex i is the anchor
+// ending the i-th non-"Z" command; handles are absolute. "in" is the last
+// control point of the vertex's own C/Q command, "out" the first control
+// point of the following C command.
+export interface PathPoint {
+  x: number;
+  y: number;
+  in?: { x: number; y: number };
+  out?: { x: number; y: number };
+}
+
 export interface ShapeRectData {
   width: number;
   height: number;
@@ -103,6 +160,21 @@ export interface RasterImageData {
   height: number;
 }
 
+// A pre-rendered image sequence: separate frame assets, or the cells of a
+// sprite sheet read left to right, top to bottom. Frame i shows from
+// timeline frame startFrame + i / rate, then loops or holds the last frame.
+export interface SpriteSequenceData {
+  assetIds?: string[];
+  sheetAssetId?: string;
+  frameCount?: number;
+  columns?: number;
+  width: number;
+  height: number;
+  startFrame?: number;
+  rate?: number;
+  loop?: boolean;
+}
+
 export interface SymbolData {
   timelineId: string;
   loop?: boolean;
@@ -114,6 +186,10 @@ export interface TextData {
   fontFamily: string;
   fontWeight: "normal" | "bold";
   textAlign: "left" | "center" | "right";
+  letterSpacing?: number;
+  visibleCharacters?: number; // Typewriter effect: show only the first N characters
+  pathId?: string; // VectorPath whose outline the baseline follows
+  pathOffset?: number; // Distance along the path where the text starts
 }
 
 export interface Timeline {
@@ -127,6 +203,9 @@ export interface Track {
   objectId: string;
   property: string;
   keys: string[];
+  // Whether a segment is eased by the keyframe it leaves (the default) or
+  // the one it arrives at
+  easingMode?: "outgoing" | "incoming";
 }
 
 export type EasingType =
@@ -141,13 +220,43 @@ export type EasingType =
   | "backOut"
   | "backInOut"
   | "elasticOut"
-  | "bounceOut";
+  | "bounceOut"
+  | "spring"
+  | "springSoft"
+  | "springStiff"
+  | "cubicBezier" // Control points from Keyframe.bezier
+  | `preset:${string}`; // Named entry in InDocument.easingPresets
+
+// Reusable cubic-bezier curve, same control points as CSS cubic-bezier().
+// Keyframes reference presets by name, so editing one updates every user.
+export interface EasingPreset {
+  x1: number;
+  y1: number;
+  x2: number;
+  y2: number;
+}
+
+// Numbers and [x, y]-style arrays interpolate (arrays component-wise);
+// strings and booleans step/hold.
+export type KeyframeValue = number | string | boolean | number[];
+
+// Tunes a spring easing; missing fields take the easing's defaults.
+export interface SpringParams {
+  stiffness?: number;
+  damping?: number;
+  mass?: number;
+  reversed?: boolean; // Played backwards in time, as track.reverse mirrors it
+}
 
 export interface Keyframe {
   id: string;
   frame: number;
-  value: number | string;
+  value: KeyframeValue;
   easing: EasingType;
+  easingParams?: SpringParams | null; // null clears them in keyframe.update
+  // [x1, y1, x2, y2] as in CSS cubic-bezier(), for the cubicBezier easing;
+  // x1 and x2 must be in [0, 1]. null clears it in keyframe.update.
+  bezier?: [number, number, number, number] | null;
 }
 
 export interface Asset {
@@ -156,4 +265,5 @@ export interface Asset {
   name: string;
   url: string;
   meta: Record<string, unknown>;
+  revision?: number; // Times the content was replaced; the URL names it
 }
@@ -11,6 +11,13 @@ import type {
   Keyframe,
   Scene,
   Asset,
+  EasingPreset,
+  PathCommand,
+  PathPoint,
+  Timeline,
+  Track,
+  SafeAreaInsets,
+  Units,
 } from "./document";
 
 // Base operation interface - all operations extend this
@@ -19,6 +26,14 @@ export interface BaseOperation {
   type: string; // Discriminator for operation type
   timestamp: number; // Client timestamp (ms since epoch)
   clientSeq: number; // Monotonic sequence for ordering
+  // Optional optimistic concurrency (object.transform, object.style,
+  // object.data, keyframe.update). A stale base is nacked with reason
+  // "conflict" and the server's current value in conflictingOp. An
+  // object.transform with baseSeq and previous but no baseValue is instead
+  // rebased onto the concurrent changes as a delta.
+  baseSeq?: number;
+  baseValue?: unknown;
+  undoOf?: string; // Set by the server on the inverse it applies for op.undo
 }
 
 // --- Object Operations ---
@@ -61,11 +76,20 @@ export interface ReparentObjectOp extends BaseOperation {
   previousIndex?: number; // For undo
 }
 
+export interface ReorderObjectOp extends BaseOperation {
+  type: "object.reorder";
+  objectId: string;
+  newIndex: number; // Position among its siblings after the move
+  previousIndex?: number; // For undo
+}
+
 export interface SetVisibilityOp extends BaseOperation {
   type: "object.visibility";
   objectId: string;
   visible: boolean;
   previous?: boolean; // For undo
+  states?: Record<string, boolean>; // Bulk form: objectId → visible, applied atomically
+  previousStates?: Record<string, boolean>; // For undo of the bulk form
 }
 
 export interface SetLockedOp extends BaseOperation {
@@ -73,6 +97,15 @@ export interface SetLockedOp extends BaseOperation {
   objectId: string;
   locked: boolean;
   previous?: boolean; // For undo
+  states?: Record<string, boolean>; // Bulk form: objectId → locked, applied atomically
+  previousStates?: Record<string, boolean>; // For undo of the bulk form
+}
+
+// Show the given objects and hide all of their siblings
+export interface SoloVisibilityOp extends BaseOperation {
+  type: "object.solo";
+  objectIds: string[];
+  previousStates?: Record<string, boolean>; // For undo
 }
 
 export interface UpdateDataOp extends BaseOperation {
@@ -82,6 +115,122 @@ export interface UpdateDataOp extends BaseOperation {
   previous?: Record<string, unknown>; // For undo
 }
 
+// Merge integration metadata into an object's meta; a null value removes its
+// key. Meta never affects rendering.
+export interface SetMetaOp extends BaseOperation {
+  type: "object.setMeta";
+  objectId: string;
+  meta: Record<string, unknown>;
+  previous?: Record<string, unknown>; // For undo; null for keys that were unset
+}
+
+/**
+ * Reset base transforms to the identity, keeping each anchor point. Tracks
+ * still override the reset values during playback.
+ */
+export interface ResetTransformOp extends BaseOperation {
+  type: "object.resetTransform";
+  objectIds?: string[]; // Objects to reset
+  transforms?: Record<string, Transform>; // Or: objectId → transform to set (undo)
+  previousTransforms?: Record<string, Transform>; // For undo
+}
+
+/**
+ * Move base transforms by relative amounts, e.g. { x: "10px", r: "-15°",
+ * sx: "200%" }. Deltas land on whatever the server holds, so a nudge composes
+ * with concurrent edits; transforms and previousTransforms are resolved by
+ * prepareOperation and again by the server.
+ */
+export interface NudgeObjectsOp extends BaseOperation {
+  type: "object.nudge";
+  objectIds: string[];
+  deltas: Record<string, number | string>;
+  transforms?: Record<string, Transform>; // Where each object ends up
+  previousTransforms?: Record<string, Transform>; // For undo
+}
+
+// Symbol instance before a detach: the symbol and its descendants, its
+// nested timeline (when the detach removed it) and affected tracks/keyframes
+export interface SymbolSnapshot {
+  objects: ObjectNode[]; // The symbol first, then its descendants
+  index: number; // The symbol's position among its parent's children
+  timeline?: Timeline;
+  tracks: Track[];
+  keyframes: Keyframe[];
+}
+
+// Replace a Symbol instance with a plain Group of copies of its descendants,
+// baking in the nested timeline's first frame
+export interface DetachSymbolOp extends BaseOperation {
+  type: "object.detachSymbol";
+  objectId: string;
+  idMap?: Record<string, Record<string, string>>; // symbol → original ID → copy ID
+  detachedObjects?: ObjectNode[]; // The group first, then its descendants
+  previousSymbol?: SymbolSnapshot; // For undo
+}
+
+// Inverse of object.detachSymbol
+export interface RestoreSymbolOp extends BaseOperation {
+  type: "object.restoreSymbol";
+  objectId: string;
+  idMap?: Record<string, Record<string, string>>;
+  detachedObjects: ObjectNode[];
+  previousSymbol: SymbolSnapshot;
+}
+
+// Everything an object.duplicate creates
+export interface DuplicateSnapshot {
+  objects: ObjectNode[]; // The copied root first, then its descendants
+  animation?: Record<string, AnimationSnapshot>; // Timeline ID → copied tracks and keyframes
+}
+
+// Deep-copy an object, its descendants and their animation. The copy goes
+// under parentId (default: the original's parent) at index (default: right
+// after the original); the server fills in both and the copies.
+export interface DuplicateObjectOp extends BaseOperation {
+  type: "object.duplicate";
+  objectId: string;
+  parentId?: string;
+  index?: number;
+  idMap?: Record<string, Record<string, string>>; // original → original ID → copy ID
+  duplicate?: DuplicateSnapshot;
+}
+
+// --- Path Operations ---
+// Vertex-level VectorPath edits, so users editing different vertices of the
+// same path don't overwrite each other. The server fills in the previous*
+// fields.
+
+export interface InsertPathPointOp extends BaseOperation {
+  type: "path.insertPoint";
+  objectId: string;
+  index: number; // The new vertex's index; vertex 0 (the "M") can't move
+  command: PathCommand; // "L", "C" or "Q"
+}
+
+export interface MovePathPointOp extends BaseOperation {
+  type: "path.movePoint";
+  objectId: string;
+  index: number;
+  point: PathPoint; // Handles are only set if given
+  previousPoint?: PathPoint; // For undo
+}
+
+export interface DeletePathPointOp extends BaseOperation {
+  type: "path.deletePoint";
+  objectId: string;
+  index: number;
+  previousCommand?: PathCommand; // For undo
+}
+
+export interface SetPathClosedOp extends BaseOperation {
+  type: "path.setClosed";
+  objectId: string;
+  index: number; // Any vertex of the subpath
+  closed: boolean;
+  previousBool?: boolean; // For undo
+}
+
 // --- Track Operations ---
 
 export interface CreateTrackOp extends BaseOperation {
@@ -107,6 +256,44 @@ export interface DeleteTrackOp extends BaseOperation {
   };
 }
 
+export interface ReverseTrackOp extends BaseOperation {
+  type: "track.reverse";
+  trackId: string;
+  mirrorEasing?: boolean; // Swap easeIn/easeOut so motion plays back exactly
+}
+
+// --- Animation Operations ---
+
+// Every track, with its keyframes, animating a set of objects on one timeline
+export interface AnimationSnapshot {
+  objectIds: string[];
+  tracks: Track[];
+  keyframes: Keyframe[];
+}
+
+// Clone the source's tracks (or only trackIds) onto each target. Target i is
+// shifted by startOffset + i * frameOffset frames; a target's track for the
+// same property is replaced.
+export interface CopyAnimationOp extends BaseOperation {
+  type: "animation.copy";
+  sourceObjectId: string;
+  objectIds: string[];
+  timelineId: string;
+  trackIds?: string[]; // Source tracks to copy (default: all)
+  startOffset?: number;
+  frameOffset?: number;
+  idMap?: Record<string, Record<string, string>>; // target → source ID → new ID
+  previousAnimation?: AnimationSnapshot; // For undo
+}
+
+// Replace all animation of the snapshot's objects with the snapshot
+export interface RestoreAnimationOp extends BaseOperation {
+  type: "animation.restore";
+  timelineId: string;
+  animation: AnimationSnapshot;
+  previousAnimation?: AnimationSnapshot; // For undo
+}
+
 // --- Keyframe Operations ---
 
 export interface AddKeyframeOp extends BaseOperation {
@@ -123,6 +310,15 @@ export interface UpdateKeyframeOp extends BaseOperation {
   previous?: Partial<Keyframe>; // For undo
 }
 
+// Swap the keyframe's named easing for the cubicBezier drawing the same
+// curve, so its handles can be edited. Springs and bounces can't be converted.
+export interface ConvertEasingToBezierOp extends BaseOperation {
+  type: "keyframe.convertEasingToBezier";
+  keyframeId: string;
+  trackId?: string;
+  previous?: Pick<Keyframe, "easing" | "bezier">; // For undo
+}
+
 export interface DeleteKeyframeOp extends BaseOperation {
   type: "keyframe.delete";
   keyframeId: string;
@@ -139,6 +335,30 @@ export interface UpdateTimelineOp extends BaseOperation {
   previous?: { length?: number };
 }
 
+// Move every keyframe on the timeline's tracks at or after atFrame frames
+// later and grow the timeline to match, then put back removedKeyframes (how
+// a removeTime is undone).
+export interface InsertTimeOp extends BaseOperation {
+  type: "timeline.insertTime";
+  timelineId: string;
+  atFrame: number;
+  frames: number;
+  clamp?: boolean; // Carried for the inverse removeTime
+  removedKeyframes?: Record<string, Keyframe[]>; // track ID → keyframes
+}
+
+// Delete the frames [atFrame, atFrame + frames) and ripple later keyframes
+// earlier. Keyframes inside the span are deleted, or with clamp moved to
+// atFrame; where several of a track's land on one frame the latest is kept.
+export interface RemoveTimeOp extends BaseOperation {
+  type: "timeline.removeTime";
+  timelineId: string;
+  atFrame: number;
+  frames: number;
+  clamp?: boolean;
+  removedKeyframes?: Record<string, Keyframe[]>; // Deleted or clamped, for undo
+}
+
 // --- Scene Operations ---
 
 export interface UpdateSceneOp extends BaseOperation {
@@ -149,12 +369,14 @@ export interface UpdateSceneOp extends BaseOperation {
     width?: number;
     height?: number;
     background?: string;
+    safeAreaInsets?: SafeAreaInsets | null; // null restores the standard areas
   };
   previous?: {
     name?: string;
     width?: number;
     height?: number;
     background?: string;
+    safeAreaInsets?: SafeAreaInsets | null;
   };
 }
 
@@ -182,6 +404,57 @@ export interface RenameProjectOp extends BaseOperation {
   previous?: string; // For undo
 }
 
+export interface UpdateProjectOp extends BaseOperation {
+  type: "project.update";
+  changes: {
+    units?: Units;
+    direction?: "ltr" | "rtl";
+    linkedAssets?: boolean;
+  };
+  previous?: {
+    units?: Units;
+    direction?: "ltr" | "rtl";
+    linkedAssets?: boolean;
+  };
+}
+
+export interface SetRootTimelineOp extends BaseOperation {
+  type: "project.setRootTimeline";
+  timelineId: string;
+  previousTimelineId?: string; // For undo
+}
+
+// --- Asset Operations ---
+
+// Sent by the server when an asset's content is replaced in place
+export interface UpdateAssetOp extends BaseOperation {
+  type: "asset.update";
+  asset: Asset; // New record; its URL names the new revision
+}
+
+// --- Easing Preset Operations ---
+
+export interface CreateEasingPresetOp extends BaseOperation {
+  type: "easingPreset.create";
+  presetName: string;
+  preset: EasingPreset;
+}
+
+export interface UpdateEasingPresetOp extends BaseOperation {
+  type: "easingPreset.update";
+  presetName: string;
+  preset: EasingPreset;
+  previousPreset?: EasingPreset; // For undo
+}
+
+// Keyframes that reference a deleted preset evaluate as linear until a
+// preset with the same name is created again.
+export interface DeleteEasingPresetOp extends BaseOperation {
+  type: "easingPreset.delete";
+  presetName: string;
+  previousPreset?: EasingPreset; // For undo
+}
+
 // Union type of all operations
 export type Operation =
   | TransformObjectOp
@@ -189,19 +462,43 @@ export type Operation =
   | DeleteObjectOp
   | CreateObjectOp
   | ReparentObjectOp
+  | ReorderObjectOp
   | SetVisibilityOp
   | SetLockedOp
+  | SoloVisibilityOp
   | UpdateDataOp
+  | SetMetaOp
+  | ResetTransformOp
+  | NudgeObjectsOp
+  | DetachSymbolOp
+  | RestoreSymbolOp
+  | DuplicateObjectOp
+  | InsertPathPointOp
+  | MovePathPointOp
+  | DeletePathPointOp
+  | SetPathClosedOp
   | CreateTrackOp
   | DeleteTrackOp
+  | ReverseTrackOp
   | AddKeyframeOp
   | UpdateKeyframeOp
+  | ConvertEasingToBezierOp
   | DeleteKeyframeOp
   | UpdateTimelineOp
+  | InsertTimeOp
+  | RemoveTimeOp
   | UpdateSceneOp
   | CreateSceneOp
   | DeleteSceneOp
-  | RenameProjectOp;
+  | RenameProjectOp
+  | UpdateProjectOp
+  | SetRootTimelineOp
+  | UpdateAssetOp
+  | CopyAnimationOp
+  | RestoreAnimationOp
+  | CreateEasingPresetOp
+  | UpdateEasingPresetOp
+  | DeleteEasingPresetOp;
 
 // --- Server Response Types ---
 
@@ -209,12 +506,16 @@ export interface OperationAck {
   operationId: string;
   serverSeq: number; // Authoritative sequence number
   serverTimestamp: number;
+  idMap?: Record<string, Record<string, string>>; // IDs the server assigned (animation.copy, object.detachSymbol, object.duplicate)
+  transform?: Partial<Transform>; // What a rebased or rounded object.transform set instead
+  objectId?: string; // The copy an object.duplicate created
 }
 
 export interface OperationNack {
   operationId: string;
   reason: string;
   conflictingOp?: Operation; // For conflict resolution
+  field?: string; // Offending field when an ID failed validation
 }
 
 export interface OperationBroadcast {
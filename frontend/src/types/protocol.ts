@@ -7,10 +7,37 @@ export interface Message {
   payload: unknown;
 }
 
+// Cursors are in scene space: scene pixels before the viewport's pan and
+// zoom, so they land in the same place for every collaborator. The server
+// marks cursors from clients that don't declare a space as legacy
+export const CURSOR_SPACE_SCENE = "scene";
+
+export type CursorSpace = typeof CURSOR_SPACE_SCENE | "legacy";
+
+export interface CursorPos {
+  x: number;
+  y: number;
+  space?: CursorSpace;
+}
+
 export interface PresencePayload {
-  cursor?: { x: number; y: number };
+  cursor?: CursorPos;
   selection?: string[];
+  frame?: number; // Playhead the selection is seen at
   displayName?: string;
+  sceneId?: string; // Scene being edited; cursors are only drawn over it
+  // Objects being edited (e.g. dragged). The server locks them against
+  // others' transform and style edits and echoes the ones it granted
+  editingObjects?: string[];
+  // Union of the selected objects' world bounds, resolved by the server
+  selectionBounds?: SelectionBounds;
+}
+
+export interface SelectionBounds {
+  x: number;
+  y: number;
+  width: number;
+  height: number;
 }
 
 export interface PresenceStatePayload {
@@ -34,12 +61,27 @@ import type {
   OperationNack,
   OperationBroadcast,
 } from "./operations";
+import type { Asset } from "./document";
 
 // Client → Server: Submit an operation
 export interface OperationSubmitPayload {
   operation: Operation;
 }
 
+// Client → Server: Undo an acknowledged operation. The server applies its
+// inverse as a new operation with id, answers with op.ack or op.nack, and
+// broadcasts it to everyone, the sender included
+export interface OperationUndoPayload {
+  id: string; // ID for the inverse operation
+  operationId?: string; // The operation to undo; omit for the sender's latest
+}
+
+// Client → Server: Redo what the sender's latest undo undid, answered and
+// broadcast as op.undo is. Nothing can be redone once the sender edits again
+export interface OperationRedoPayload {
+  id: string; // ID for the redoing operation
+}
+
 // Server → Client: Operation acknowledged
 export interface OperationAckPayload extends OperationAck {}
 
@@ -49,6 +91,63 @@ export interface OperationNackPayload extends OperationNack {}
 // Server → Client: Operation from another user
 export interface OperationBroadcastPayload extends OperationBroadcast {}
 
+// Client → Server: Operations applied in order, all or nothing, e.g. the
+// transforms of a drag. Answered with op.batchAck or op.batchNack
+export interface OperationBatchPayload {
+  id: string;
+  operations: Operation[];
+}
+
+// Server → Client: Batch applied. The operations that changed the document
+// were sequenced firstSeq through serverSeq; firstSeq is absent if none did
+export interface OperationBatchAckPayload {
+  batchId: string;
+  operationIds: string[];
+  firstSeq?: number;
+  serverSeq: number;
+  serverTimestamp: number;
+  idMaps?: Record<string, Record<string, Record<string, string>>>;
+}
+
+// Server → Client: Batch rejected because the operation at index failed;
+// none of it was applied
+export interface OperationBatchNackPayload extends OperationNack {
+  batchId: string;
+  index: number;
+}
+
+// Server → Client: The operations of another user's batch that changed the
+// document, sequenced firstSeq through serverSeq
+export interface OperationBatchBroadcastPayload {
+  batchId: string;
+  operations: Operation[];
+  userId: string;
+  firstSeq: number;
+  serverSeq: number;
+}
+
+// Client → Server: Catch up after reconnecting. The last server sequence
+// seen can also be passed as the seq query parameter when connecting
+export interface ResyncRequestPayload {
+  lastSeq: number;
+}
+
+// Server → Client: Every operation sequenced after lastSeq, in order, which
+// brings the client to serverSeq. Sent instead of doc.sync when the server
+// still has them all
+export interface ResyncOpsPayload {
+  lastSeq: number;
+  serverSeq: number;
+  operations: OperationBroadcastPayload[];
+}
+
+// Server → Client: An asset's content was replaced
+export interface AssetUpdatedPayload {
+  asset: Asset;
+  userId: string;
+  serverSeq: number;
+}
+
 // Error payload from server
 export interface ErrorPayload {
   code: string;
@@ -67,12 +166,28 @@ export const MessageTypes = {
   WELCOME: "welcome",
   ERROR: "error",
 
-  // Document sync
+  // Document sync. Send DOC_REQUEST_SYNC to be sent DOC_SYNC again, e.g.
+  // after missing an operation; msg.seq is the server sequence it is at.
   DOC_SYNC: "doc.sync",
+  DOC_REQUEST_SYNC: "doc.requestSync",
+  DOC_RESYNC: "doc.resync",
+  DOC_RESYNC_OPS: "doc.resyncOps",
 
   // Operations
   OP_SUBMIT: "op.submit",
   OP_ACK: "op.ack",
   OP_NACK: "op.nack",
   OP_BROADCAST: "op.broadcast",
+  OP_UNDO: "op.undo",
+  OP_REDO: "op.redo",
+  OP_BATCH: "op.batch",
+  OP_BATCH_ACK: "op.batchAck",
+  OP_BATCH_NACK: "op.batchNack",
+  OP_BATCH_BROADCAST: "op.batchBroadcast",
+
+  // Assets
+  ASSET_UPDATED: "asset.updated",
+
+  // Sent on every connection of the recipient, whatever the room
+  NOTIFICATION_NEW: "notification.new",
 } as const;
@@ -0,0 +1,140 @@
+/**
+ * Color parsing for fills and strokes. Mirrors the server's color package:
+ * accepts #rgb, #rgba, #rrggbb, #rrggbbaa, rgb()/rgba(), hsl()/hsla() (comma
+ * or space syntax, optional "/ alpha") and "transparent", and canonicalizes
+ * to lowercase "#rrggbbaa". The server rejects anything else.
+ */
+
+export interface RGBA {
+  r: number; // 0-255
+  g: number;
+  b: number;
+  a: number; // 0-255
+}
+
+const clamp01 = (v: number) => Math.max(0, Math.min(1, v));
+const toByte = (v: number) => Math.round(clamp01(v) * 255);
+const hex2 = (v: number) => v.toString(16).padStart(2, "0");
+
+/**
+ * Canonical "#rrggbbaa" form of a color.
+ */
+export function toHex(c: RGBA): string {
+  return `#${hex2(c.r)}${hex2(c.g)}${hex2(c.b)}${hex2(c.a)}`;
+}
+
+/**
+ * Normalize a paint value to canonical hex. "" and "none" pass through;
+ * returns null if the value isn't a supported color.
+ */
+export function normalizeColor(value: string): string | null {
+  if (value === "" || value === "none") return value;
+  const c = parseColor(value);
+  return c ? toHex(c) : null;
+}
+
+/**
+ * Parse a color in any supported notation, or null if unsupported.
+ */
+export function parseColor(input: string): RGBA | null {
+  const v = input.trim().toLowerCase();
+  if (v === "transparent") return { r: 0, g: 0, b: 0, a: 0 };
+  if (v.startsWith("#")) return parseHex(v.slice(1));
+
+  const match = /^([a-z]+)\s*\((.*)\)$/.exec(v);
+  if (!match) return null;
+  const args = splitArgs(match[2]);
+  if (!args) return null;
+  switch (match[1]) {
+    case "rgb":
+    case "rgba":
+      return parseRGB(args);
+    case "hsl":
+    case "hsla":
+      return parseHSL(args);
+    default:
+      return null;
+  }
+}
+
+function parseHex(digits: string): RGBA | null {
+  if (digits.length === 3 || digits.length === 4) {
+    digits = [...digits].map((d) => d + d).join("");
+  }
+  if (digits.length === 6) digits += "ff";
+  if (!/^[0-9a-f]{8}$/.test(digits)) return null;
+  const n = (i: number) => parseInt(digits.slice(i, i + 2), 16);
+  return { r: n(0), g: n(2), b: n(4), a: n(6) };
+}
+
+function splitArgs(s: string): string[] | null {
+  let alpha = "";
+  const slash = s.indexOf("/");
+  if (slash >= 0) {
+    alpha = s.slice(slash + 1).trim();
+    s = s.slice(0, slash);
+    if (!alpha) return null;
+  }
+  const args = s.split(/[,\s]+/).filter(Boolean);
+  if (alpha) args.push(alpha);
+  return args.length === 3 || args.length === 4 ? args : null;
+}
+
+// A plain number, or a percentage where 100% equals full
+function numberOrPercent(s: string, full: number): number | null {
+  const percent = s.endsWith("%");
+  const text = percent ? s.slice(0, -1) : s;
+  if (!/^[+-]?(\d+\.?\d*|\.\d+)(e[+-]?\d+)?$/.test(text)) return null;
+  const v = parseFloat(text);
+  return percent ? (v / 100) * full : v;
+}
+
+function parseAlpha(args: string[]): number | null {
+  if (args.length < 4) return 255;
+  const v = numberOrPercent(args[3], 1);
+  return v === null ? null : toByte(v);
+}
+
+function parseRGB(args: string[]): RGBA | null {
+  const channels = args.slice(0, 3).map((a) => numberOrPercent(a, 255));
+  const a = parseAlpha(args);
+  if (channels.some((c) => c === null) || a === null) return null;
+  const [r, g, b] = channels.map((c) => toByte((c as number) / 255));
+  return { r, g, b, a };
+}
+
+const HUE_UNITS: [string, number][] = [
+  ["deg", 1],
+  ["grad", 0.9],
+  ["rad", 180 / Math.PI],
+  ["turn", 360],
+];
+
+function parseHue(s: string): number | null {
+  for (const [suffix, scale] of HUE_UNITS) {
+    if (s.endsWith(suffix)) {
+      const v = numberOrPercent(s.slice(0, -suffix.length), 1);
+      return v === null ? null : v * scale;
+    }
+  }
+  return s.endsWith("%") ? null : numberOrPercent(s, 1);
+}
+
+function parseHSL(args: string[]): RGBA | null {
+  const h = parseHue(args[0]);
+  const s = numberOrPercent(args[1], 100);
+  const l = numberOrPercent(args[2], 100);
+  const a = parseAlpha(args);
+  if (h === null || s === null || l === null || a === null) return null;
+
+  // CSS Color 4 hsl-to-rgb
+  const hue = ((h % 360) + 360) % 360;
+  const sat = clamp01(s / 100);
+  const light = clamp01(l / 100);
+  const f = (n: number) => {
+    const k = (n + hue / 30) % 12;
+    const amp = sat * Math.min(light, 1 - light);
+    return light - amp * Math.max(-1, Math.min(k - 3, 9 - k, 1));
+  };
+  return { r: toByte(f(0)), g: toByte(f(8)), b: toByte(f(4)), a };
+}
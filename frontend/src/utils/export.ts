@@ -7,6 +7,7 @@ import type { Stage } from "../engine/Stage";
 import type { InDocument } from "../types/document";
 import { RUNTIME_JS } from "../engine/runtime";
 import { API_BASE } from "../api/client";
+import { newId } from "./typeid";
 
 export interface ExportProgress {
   current: number;
@@ -98,6 +99,10 @@ export async function exportPngSequence(
 
 /**
  * Export animation as a video or GIF via the backend ffmpeg endpoint.
+ *
+ * Frames are sampled by time at outputFps (the document's fps by default), so
+ * a higher rate interpolates between keyframes instead of repeating frames.
+ * Aborting signal stops rendering, or cancels the server job while encoding.
  */
 export async function exportVideo(
   stage: Stage,
@@ -107,26 +112,33 @@ export async function exportVideo(
   format: "mp4" | "gif" | "webm",
   fps: number,
   onProgress?: (progress: ExportProgress) => void,
+  outputFps: number = fps,
+  signal?: AbortSignal,
 ): Promise<void> {
+  const jobId = newId("exp");
+
   const safeName =
     projectName
       .toLowerCase()
       .replace(/[^a-z0-9]+/g, "-")
       .replace(/^-|-$/g, "") || "animation";
 
+  const outputFrames = Math.max(1, Math.round((totalFrames * outputFps) / fps));
+
   // Phase 1: Render frames to blobs
   const blobs: Blob[] = [];
 
-  for (let frame = 0; frame < totalFrames; frame++) {
+  for (let frame = 0; frame < outputFrames; frame++) {
+    signal?.throwIfAborted();
     onProgress?.({
       current: frame + 1,
-      total: totalFrames,
+      total: outputFrames,
       phase: "rendering",
     });
 
-    stage.seek(frame);
-    stage.invalidate();
+    stage.renderAtTime(frame / outputFps);
 
+    // Yield so progress updates can paint
     await new Promise((resolve) => requestAnimationFrame(resolve));
 
     const blob = await new Promise<Blob>((resolve, reject) => {
@@ -141,30 +153,45 @@ export async function exportVideo(
 
   // Phase 2: Upload to backend
   onProgress?.({
-    current: totalFrames,
-    total: totalFrames,
+    current: outputFrames,
+    total: outputFrames,
     phase: "encoding",
   });
 
   const formData = new FormData();
   formData.append("format", format);
-  formData.append("fps", fps.toString());
+  formData.append("fps", outputFps.toString());
   formData.append("width", canvas.width.toString());
   formData.append("height", canvas.height.toString());
   formData.append("name", safeName);
-  formData.append("frameCount", totalFrames.toString());
+  formData.append("frameCount", outputFrames.toString());
+  formData.append("jobId", jobId);
 
-  const padLength = String(totalFrames - 1).length;
+  const padLength = String(outputFrames - 1).length;
   const pad = Math.max(padLength, 4);
   for (let i = 0; i < blobs.length; i++) {
     const key = `frame_${i.toString().padStart(pad, "0")}`;
     formData.append(key, blobs[i], `${key}.png`);
   }
 
-  const response = await fetch(`${API_BASE}/export/video`, {
-    method: "POST",
-    body: formData,
-  });
+  signal?.throwIfAborted();
+  const cancelJob = () => {
+    fetch(`${API_BASE}/export/jobs/${jobId}`, { method: "DELETE" }).catch(
+      () => {},
+    );
+  };
+  signal?.addEventListener("abort", cancelJob, { once: true });
+
+  let response: Response;
+  try {
+    response = await fetch(`${API_BASE}/export/video`, {
+      method: "POST",
+      body: formData,
+      signal,
+    });
+  } finally {
+    signal?.removeEventListener("abort", cancelJob);
+  }
 
   if (!response.ok) {
     const text = await response.text();
@@ -173,8 +200,8 @@ export async function exportVideo(
 
   // Phase 3: Download
   onProgress?.({
-    current: totalFrames,
-    total: totalFrames,
+    current: outputFrames,
+    total: outputFrames,
     phase: "downloading",
   });
 
@@ -186,6 +213,147 @@ export async function exportVideo(
   URL.revokeObjectURL(link.href);
 }
 
+export type ExportJobState =
+  | "queued"
+  | "running"
+  | "completed"
+  | "failed"
+  | "cancelled";
+
+export interface ExportSceneStatus {
+  sceneId: string;
+  file: string;
+  state: ExportJobState;
+  frames: number;
+  encoded: number;
+  size?: number;
+  error?: string;
+}
+
+export interface ExportJobStatus {
+  id: string;
+  projectId?: string;
+  format: string;
+  state: ExportJobState;
+  scenes: ExportSceneStatus[];
+  download?: string;
+  error?: string;
+  createdAt: string;
+  finishedAt?: string;
+}
+
+const JOB_POLL_INTERVAL_MS = 500;
+
+/**
+ * Export every scene as its own video in one background job on the server.
+ *
+ * Each scene's frames are rendered and uploaded together; the server encodes
+ * them one at a time and zips the results when more than one succeeds. A
+ * scene that fails doesn't stop the others, so check the returned status for
+ * per-scene errors. Leaves the stage on the last scene; callers restore it.
+ */
+export async function exportAllScenes(
+  stage: Stage,
+  canvas: HTMLCanvasElement,
+  doc: InDocument,
+  totalFrames: number,
+  format: "mp4" | "gif" | "webm",
+  onProgress?: (progress: ExportProgress) => void,
+  signal?: AbortSignal,
+): Promise<ExportJobStatus> {
+  const fps = doc.project.fps || 24;
+  const safeName =
+    doc.project.name
+      .toLowerCase()
+      .replace(/[^a-z0-9]+/g, "-")
+      .replace(/^-|-$/g, "") || "animation";
+  const sceneIds = doc.project.scenes;
+  const total = sceneIds.length * totalFrames;
+
+  const formData = new FormData();
+  formData.append("format", format);
+  formData.append("fps", fps.toString());
+  formData.append("name", safeName);
+  formData.append("scenes", sceneIds.join(","));
+  formData.append("jobId", newId("exp"));
+
+  // Phase 1: Render every scene's frames
+  const pad = Math.max(String(totalFrames - 1).length, 4);
+  let rendered = 0;
+  for (const sceneId of sceneIds) {
+    stage.setScene(sceneId);
+    formData.append(`${sceneId}/name`, doc.scenes[sceneId]?.name ?? sceneId);
+
+    for (let frame = 0; frame < totalFrames; frame++) {
+      signal?.throwIfAborted();
+      onProgress?.({ current: ++rendered, total, phase: "rendering" });
+
+      stage.renderAtTime(frame / fps);
+      await new Promise((resolve) => requestAnimationFrame(resolve));
+
+      const blob = await new Promise<Blob>((resolve, reject) => {
+        canvas.toBlob((b) => {
+          if (b) resolve(b);
+          else reject(new Error(`Failed to capture frame ${frame}`));
+        }, "image/png");
+      });
+      const key = `frame_${frame.toString().padStart(pad, "0")}`;
+      formData.append(`${sceneId}/${key}`, blob, `${key}.png`);
+    }
+  }
+
+  // Phase 2: Upload and wait for the server to encode each scene
+  onProgress?.({ current: 0, total, phase: "encoding" });
+  const response = await fetch(`${API_BASE}/export/jobs`, {
+    method: "POST",
+    body: formData,
+    signal,
+  });
+  if (!response.ok) {
+    const text = await response.text();
+    throw new Error(`Export failed: ${text}`);
+  }
+  let status: ExportJobStatus = await response.json();
+
+  const cancelJob = () => {
+    fetch(`${API_BASE}/export/jobs/${status.id}`, { method: "DELETE" }).catch(
+      () => {},
+    );
+  };
+  signal?.addEventListener("abort", cancelJob, { once: true });
+  try {
+    while (!status.finishedAt) {
+      await new Promise((resolve) =>
+        setTimeout(resolve, JOB_POLL_INTERVAL_MS),
+      );
+      signal?.throwIfAborted();
+      const poll = await fetch(`${API_BASE}/export/jobs/${status.id}`, {
+        signal,
+      });
+      if (!poll.ok) {
+        throw new Error(`Export status failed: ${await poll.text()}`);
+      }
+      status = await poll.json();
+      onProgress?.({
+        current: status.scenes.reduce((n, s) => n + s.encoded, 0),
+        total,
+        phase: "encoding",
+      });
+    }
+  } finally {
+    signal?.removeEventListener("abort", cancelJob);
+  }
+  if (status.state !== "completed" || !status.download) return status;
+
+  // Phase 3: Download the single video or the zip of all of them
+  onProgress?.({ current: total, total, phase: "downloading" });
+  const link = document.createElement("a");
+  link.href = `${API_BASE}/export/jobs/${status.id}/download`;
+  link.download = status.download;
+  link.click();
+  return status;
+}
+
 /**
  * Prepare document for standalone export: convert asset URLs to base64 data URLs.
  */
@@ -0,0 +1,50 @@
+/**
+ * TypeID generation for document entities. The server rejects object, scene,
+ * timeline, track, keyframe and asset IDs that aren't "<prefix>_<suffix>",
+ * where the suffix is a UUIDv7 in 26-character Crockford base32.
+ */
+
+export type IdPrefix =
+  | "obj"
+  | "scene"
+  | "tl"
+  | "track"
+  | "kf"
+  | "asset"
+  | "exp";
+
+const ALPHABET = "0123456789abcdefghjkmnpqrstvwxyz";
+
+/**
+ * A new TypeID with the given prefix, e.g. newId("obj").
+ */
+export function newId(prefix: IdPrefix): string {
+  return `${prefix}_${encodeBase32(uuidv7())}`;
+}
+
+// UUIDv7: 48-bit millisecond timestamp, then random bits with the version
+// and variant set
+function uuidv7(): Uint8Array {
+  const bytes = crypto.getRandomValues(new Uint8Array(16));
+  let ms = Date.now();
+  for (let i = 5; i >= 0; i--) {
+    bytes[i] = ms % 256;
+    ms = Math.floor(ms / 256);
+  }
+  bytes[6] = (bytes[6] & 0x0f) | 0x70;
+  bytes[8] = (bytes[8] & 0x3f) | 0x80;
+  return bytes;
+}
+
+// 128 bits as 26 base32 digits, most significant first (the top digit only
+// carries two bits, so it's always 0-7)
+function encodeBase32(bytes: Uint8Array): string {
+  let n = 0n;
+  for (const b of bytes) n = (n << 8n) | BigInt(b);
+  let out = "";
+  for (let i = 0; i < 26; i++) {
+    out = ALPHABET[Number(n & 31n)] + out;
+    n >>= 5n;
+  }
+  return out;
+}